@@ -0,0 +1,374 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package balance withholds the unhealthy addresses of the load balanced A/AAAA records from the answers and orders
+// the weighted addresses in smooth weighted round-robin
+package balance
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/datastore"
+	"dns-server/util"
+)
+
+// TargetHealth health state of a load balanced address
+type TargetHealth struct {
+	Name                string    `json:"name"`
+	Type                string    `json:"type"`
+	Address             string    `json:"address"`
+	Weight              uint32    `json:"weight"`
+	Healthy             bool      `json:"healthy"`
+	ConsecutiveFailures uint32    `json:"consecutiveFailures"`
+	LastCheck           time.Time `json:"lastCheck"`
+	LastError           string    `json:"lastError,omitempty"`
+}
+
+type rrSetKey struct {
+	name   string
+	rrType uint16
+}
+
+type target struct {
+	address     string
+	weight      int64
+	current     int64 // smooth weighted round-robin state
+	healthy     bool
+	consecutive uint32
+	lastCheck   time.Time
+	lastError   string
+	nextCheck   time.Time
+	probing     bool
+}
+
+type rrSet struct {
+	weighted bool
+	check    *datastore.HealthCheck
+	targets  map[string]*target
+}
+
+// Balancer balances the answers of the records having weights or health check
+type Balancer struct {
+	store datastore.DataStore
+
+	mutex      sync.Mutex
+	sets       map[rrSetKey]*rrSet
+	lastReload time.Time
+	stop       chan struct{}
+	probes     sync.WaitGroup
+}
+
+// NewBalancer creates the balancer of the records in the data store
+func NewBalancer(store datastore.DataStore) *Balancer {
+	return &Balancer{store: store, sets: make(map[rrSetKey]*rrSet)}
+}
+
+// Start loads the records, they are reloaded and the addresses are probed in the background
+func (b *Balancer) Start() {
+	if err := b.Reload(); err != nil {
+		log.Errorf("Failed to load the load balanced records(%s).", err.Error())
+	}
+	b.lastReload = time.Now()
+	b.stop = make(chan struct{})
+	go b.run(b.stop)
+}
+
+// Stop stops the background reload and probes
+func (b *Balancer) Stop() {
+	if b.stop != nil {
+		close(b.stop)
+		b.stop = nil
+	}
+	b.probes.Wait()
+}
+
+func (b *Balancer) run(stop chan struct{}) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if now.Sub(b.lastReload) >= util.BalancerReloadInterval {
+				if err := b.Reload(); err != nil {
+					log.Errorf("Failed to reload the load balanced records(%s).", err.Error())
+				}
+				b.lastReload = now
+			}
+			b.probeDue(now)
+		}
+	}
+}
+
+// Reload loads the records having weights or health check, the health of the unchanged addresses is kept
+func (b *Balancer) Reload() error {
+	zones, err := b.store.ListZones()
+	if err != nil {
+		return err
+	}
+	sets := make(map[rrSetKey]*rrSet)
+	for _, zone := range zones {
+		records, err := b.store.ListResourceRecords(zone)
+		if err != nil {
+			return err
+		}
+		for i := range records {
+			rr := &records[i]
+			if len(rr.Weights) == 0 && rr.HealthCheck == nil {
+				continue
+			}
+			// The records of the views share the balancing state of the name
+			key := rrSetKey{name: strings.ToLower(rr.Name), rrType: dns.StringToType[rr.Type]}
+			set, ok := sets[key]
+			if !ok {
+				set = &rrSet{check: rr.HealthCheck, targets: make(map[string]*target)}
+				sets[key] = set
+			}
+			set.weighted = set.weighted || len(rr.Weights) != 0
+			if set.check == nil {
+				set.check = rr.HealthCheck
+			}
+			for j, rData := range rr.RData {
+				ip := net.ParseIP(rData)
+				if ip == nil {
+					continue
+				}
+				weight := int64(1)
+				if j < len(rr.Weights) {
+					weight = int64(rr.Weights[j])
+				}
+				set.targets[ip.String()] = &target{address: ip.String(), weight: weight, healthy: true}
+			}
+		}
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for key, set := range sets {
+		old, ok := b.sets[key]
+		if !ok || !sameCheck(old.check, set.check) {
+			continue
+		}
+		for address, t := range set.targets {
+			if oldTarget, ok := old.targets[address]; ok {
+				t.healthy, t.consecutive, t.lastCheck, t.lastError = oldTarget.healthy, oldTarget.consecutive,
+					oldTarget.lastCheck, oldTarget.lastError
+				t.nextCheck, t.probing, t.current = oldTarget.nextCheck, oldTarget.probing, oldTarget.current
+			}
+		}
+	}
+	b.sets = sets
+	return nil
+}
+
+func sameCheck(a *datastore.HealthCheck, b *datastore.HealthCheck) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// probeDue probes the addresses of which the check interval elapsed
+func (b *Balancer) probeDue(now time.Time) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	for _, set := range b.sets {
+		if set.check == nil {
+			continue
+		}
+		for _, t := range set.targets {
+			if t.probing || now.Before(t.nextCheck) {
+				continue
+			}
+			t.probing = true
+			t.nextCheck = now.Add(time.Duration(set.check.Interval) * time.Second)
+			b.probes.Add(1)
+			go func(t *target, check datastore.HealthCheck) {
+				defer b.probes.Done()
+				err := probe(t.address, &check)
+				b.report(t, err)
+			}(t, *set.check)
+		}
+	}
+}
+
+func (b *Balancer) report(t *target, err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	t.probing = false
+	t.lastCheck = time.Now()
+	if err == nil {
+		if !t.healthy {
+			log.Infof("Load balanced address %s is healthy.", t.address)
+		}
+		t.healthy, t.consecutive, t.lastError = true, 0, ""
+		return
+	}
+	t.consecutive++
+	t.lastError = err.Error()
+	if t.healthy && t.consecutive >= util.HealthCheckFailures {
+		t.healthy = false
+		log.Warnf("Load balanced address %s is unhealthy(%s).", t.address, err.Error())
+	}
+}
+
+// probe checks the address with a tcp connection or a http get
+func probe(address string, check *datastore.HealthCheck) error {
+	timeout := time.Duration(check.Timeout) * time.Second
+	hostPort := net.JoinHostPort(address, strconv.Itoa(int(check.Port)))
+	if check.Protocol == datastore.HealthCheckHTTP {
+		client := &http.Client{Timeout: timeout, CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		rsp, err := client.Get("http://" + hostPort + check.Path)
+		if err != nil {
+			return err
+		}
+		_ = rsp.Body.Close()
+		if rsp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("http status %d", rsp.StatusCode)
+		}
+		return nil
+	}
+
+	conn, err := net.DialTimeout("tcp", hostPort, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func address(rr dns.RR) string {
+	switch record := rr.(type) {
+	case *dns.A:
+		return record.A.String()
+	case *dns.AAAA:
+		return record.AAAA.String()
+	}
+	return ""
+}
+
+// Balance withholds the unhealthy addresses, all the addresses are answered if none is healthy, and orders the
+// weighted addresses, the other answers are shuffled if requested
+func (b *Balancer) Balance(answer []dns.RR, shuffle bool) []dns.RR {
+	if len(answer) == 0 {
+		return answer
+	}
+	key := rrSetKey{name: strings.ToLower(answer[0].Header().Name), rrType: answer[0].Header().Rrtype}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	set, ok := b.sets[key]
+	if !ok {
+		if shuffle {
+			rand.Shuffle(len(answer), func(i, j int) {
+				answer[i], answer[j] = answer[j], answer[i]
+			})
+		}
+		return answer
+	}
+
+	healthy := make([]dns.RR, 0, len(answer))
+	for _, rr := range answer {
+		if t, ok := set.targets[address(rr)]; !ok || t.healthy {
+			healthy = append(healthy, rr)
+		}
+	}
+	if len(healthy) == 0 {
+		healthy = answer
+	}
+
+	if set.weighted {
+		set.order(healthy)
+	} else if shuffle {
+		rand.Shuffle(len(healthy), func(i, j int) {
+			healthy[i], healthy[j] = healthy[j], healthy[i]
+		})
+	}
+	return healthy
+}
+
+// order runs a smooth weighted round-robin selection over the answer, the selected address is answered first and the
+// others follow in the selection order of the next queries
+func (s *rrSet) order(answer []dns.RR) {
+	var total int64
+	var selected *target
+	for _, rr := range answer {
+		t, ok := s.targets[address(rr)]
+		if !ok {
+			continue
+		}
+		t.current += t.weight
+		total += t.weight
+		if selected == nil || t.current > selected.current {
+			selected = t
+		}
+	}
+	if selected == nil {
+		return
+	}
+	selected.current -= total
+
+	current := func(rr dns.RR) int64 {
+		t, ok := s.targets[address(rr)]
+		if !ok {
+			return -total
+		}
+		if t == selected {
+			return total + 1
+		}
+		return t.current
+	}
+	sort.SliceStable(answer, func(i, j int) bool {
+		return current(answer[i]) > current(answer[j])
+	})
+}
+
+// Health returns the health state of the load balanced addresses sorted by the name, type and address
+func (b *Balancer) Health() []TargetHealth {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	health := make([]TargetHealth, 0)
+	for key, set := range b.sets {
+		for _, t := range set.targets {
+			health = append(health, TargetHealth{Name: key.name, Type: dns.TypeToString[key.rrType],
+				Address: t.address, Weight: uint32(t.weight), Healthy: t.healthy, ConsecutiveFailures: t.consecutive,
+				LastCheck: t.lastCheck, LastError: t.lastError})
+		}
+	}
+	sort.Slice(health, func(i, j int) bool {
+		if health[i].Name != health[j].Name {
+			return health[i].Name < health[j].Name
+		}
+		if health[i].Type != health[j].Type {
+			return health[i].Type < health[j].Type
+		}
+		return health[i].Address < health[j].Address
+	})
+	return health
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package balance
+
+import (
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/datastore"
+)
+
+const exampleDomain = "app.example.com."
+
+func newAnswer(addresses ...string) []dns.RR {
+	answer := make([]dns.RR, 0, len(addresses))
+	for _, address := range addresses {
+		answer = append(answer, &dns.A{Hdr: dns.RR_Header{Name: exampleDomain, Rrtype: dns.TypeA,
+			Class: dns.ClassINET, Ttl: 30}, A: net.ParseIP(address)})
+	}
+	return answer
+}
+
+func openStore(t *testing.T, name string) *datastore.BoltDB {
+	store := &datastore.BoltDB{FileName: name, TTL: 30}
+	assert.Nil(t, store.Open())
+	return store
+}
+
+func TestWeightedRoundRobin(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+	store := openStore(t, "testweightdb")
+	defer store.Close()
+	assert.Nil(t, store.SetResourceRecord("example.com.", &datastore.ResourceRecord{Name: exampleDomain, Type: "A",
+		Class: "IN", TTL: 30, RData: []string{"10.10.0.1", "10.10.0.2"}, Weights: []uint32{3, 1}}))
+
+	b := NewBalancer(store)
+	assert.Nil(t, b.Reload())
+	first := map[string]int{}
+	for i := 0; i < 8; i++ {
+		answer := b.Balance(newAnswer("10.10.0.1", "10.10.0.2"), false)
+		assert.Equal(t, 2, len(answer))
+		first[address(answer[0])]++
+	}
+	assert.Equal(t, map[string]int{"10.10.0.1": 6, "10.10.0.2": 2}, first)
+
+	// The records without load balancing are kept as is
+	other := newAnswer("10.10.0.3", "10.10.0.4")
+	other[0].Header().Name = "www.example.com."
+	other[1].Header().Name = "www.example.com."
+	answer := b.Balance(other, false)
+	assert.Equal(t, "10.10.0.3", address(answer[0]))
+}
+
+func TestHealthCheck(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+	port := listener.Addr().(*net.TCPAddr).Port
+
+	store := openStore(t, "testhealthdb")
+	defer store.Close()
+	check := &datastore.HealthCheck{Protocol: datastore.HealthCheckTCP, Port: uint16(port), Interval: 1, Timeout: 1}
+	assert.Nil(t, store.SetResourceRecord("example.com.", &datastore.ResourceRecord{Name: exampleDomain, Type: "A",
+		Class: "IN", TTL: 30, RData: []string{"127.0.0.1", "127.0.0.2"}, HealthCheck: check}))
+
+	b := NewBalancer(store)
+	assert.Nil(t, b.Reload())
+	now := time.Now()
+	for i := 0; i < 2; i++ {
+		b.probeDue(now.Add(time.Duration(i) * time.Second))
+		b.probes.Wait()
+	}
+
+	health := b.Health()
+	assert.Equal(t, 2, len(health))
+	assert.True(t, health[0].Healthy)
+	assert.Equal(t, "127.0.0.1", health[0].Address)
+	assert.False(t, health[1].Healthy)
+	assert.Equal(t, uint32(2), health[1].ConsecutiveFailures)
+	assert.NotEmpty(t, health[1].LastError)
+
+	// The unhealthy address is withheld
+	answer := b.Balance(newAnswer("127.0.0.1", "127.0.0.2"), true)
+	assert.Equal(t, 1, len(answer))
+	assert.Equal(t, "127.0.0.1", address(answer[0]))
+
+	// The health is kept on reload
+	assert.Nil(t, b.Reload())
+	assert.Equal(t, health, b.Health())
+
+	// All the addresses are answered if none is healthy
+	_ = listener.Close()
+	for i := 2; i < 4; i++ {
+		b.probeDue(now.Add(time.Duration(i) * time.Second))
+		b.probes.Wait()
+	}
+	answer = b.Balance(newAnswer("127.0.0.1", "127.0.0.2"), false)
+	assert.Equal(t, 2, len(answer))
+}
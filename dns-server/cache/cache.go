@@ -0,0 +1,222 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package cache caches the forwarded dns answers, the positive answers for the lowest ttl of the records and the
+// negative answers for the SOA minimum ttl (RFC 2308)
+package cache
+
+import (
+	"container/list"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Stats statistics of the answer cache
+type Stats struct {
+	Capacity     int     `json:"capacity"`
+	Size         int     `json:"size"`
+	Hits         uint64  `json:"hits"`
+	NegativeHits uint64  `json:"negativeHits"`
+	Misses       uint64  `json:"misses"`
+	Evictions    uint64  `json:"evictions"`
+	HitRatio     float64 `json:"hitRatio"`
+}
+
+type key struct {
+	name   string
+	qtype  uint16
+	qclass uint16
+}
+
+type entry struct {
+	key      key
+	msg      *dns.Msg
+	stored   time.Time
+	expire   time.Time
+	negative bool
+}
+
+// Cache LRU cache of the dns answers
+type Cache struct {
+	capacity int
+	maxTTL   uint32
+	now      func() time.Time
+
+	mutex   sync.Mutex
+	entries map[key]*list.Element
+	lru     *list.List
+	stats   Stats
+}
+
+// NewCache creates the cache holding up to capacity answers, the ttl of the answers is limited to maxTTL seconds
+func NewCache(capacity int, maxTTL uint32) *Cache {
+	return &Cache{capacity: capacity, maxTTL: maxTTL, now: time.Now, entries: make(map[key]*list.Element),
+		lru: list.New()}
+}
+
+func newKey(q *dns.Question) key {
+	return key{name: strings.ToLower(q.Name), qtype: q.Qtype, qclass: q.Qclass}
+}
+
+// Get returns the cached answer of the request with the ttl reduced by the time spent in the cache
+func (c *Cache) Get(req *dns.Msg) (*dns.Msg, bool) {
+	if c == nil || len(req.Question) == 0 {
+		return nil, false
+	}
+	k := newKey(&req.Question[0])
+	now := c.now()
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	element, ok := c.entries[k]
+	if ok && !now.Before(element.Value.(*entry).expire) {
+		c.remove(element)
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.lru.MoveToFront(element)
+	cached := element.Value.(*entry)
+	c.stats.Hits++
+	if cached.negative {
+		c.stats.NegativeHits++
+	}
+
+	rsp := cached.msg.Copy()
+	rsp.Id = req.Id
+	rsp.Question = req.Question
+	elapsed := uint32(now.Sub(cached.stored) / time.Second)
+	for _, section := range [][]dns.RR{rsp.Answer, rsp.Ns, rsp.Extra} {
+		for _, rr := range section {
+			rr.Header().Ttl -= elapsed
+		}
+	}
+	return rsp, true
+}
+
+// Set caches the answer, the truncated, failed and zero ttl answers are not cached
+func (c *Cache) Set(rsp *dns.Msg) {
+	if c == nil || c.capacity <= 0 || len(rsp.Question) == 0 || rsp.Truncated {
+		return
+	}
+	ttl, negative, ok := c.ttl(rsp)
+	if !ok {
+		return
+	}
+
+	msg := rsp.Copy()
+	// The OPT record is per request, it is added by the response writer
+	extra := msg.Extra[:0]
+	for _, rr := range msg.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			extra = append(extra, rr)
+		}
+	}
+	msg.Extra = extra
+	// The records are not served longer than cached
+	for _, section := range [][]dns.RR{msg.Answer, msg.Ns, msg.Extra} {
+		for _, rr := range section {
+			if rr.Header().Ttl > ttl {
+				rr.Header().Ttl = ttl
+			}
+		}
+	}
+
+	now := c.now()
+	cached := &entry{key: newKey(&rsp.Question[0]), msg: msg, stored: now,
+		expire: now.Add(time.Duration(ttl) * time.Second), negative: negative}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if element, exists := c.entries[cached.key]; exists {
+		c.remove(element)
+	}
+	for c.lru.Len() >= c.capacity {
+		c.remove(c.lru.Back())
+		c.stats.Evictions++
+	}
+	c.entries[cached.key] = c.lru.PushFront(cached)
+}
+
+// ttl returns the cache ttl of the answer and whether it is a negative answer
+func (c *Cache) ttl(rsp *dns.Msg) (uint32, bool, bool) {
+	var ttl uint32
+	var negative bool
+	switch {
+	case rsp.Rcode == dns.RcodeSuccess && len(rsp.Answer) != 0:
+		ttl = c.maxTTL
+		for _, section := range [][]dns.RR{rsp.Answer, rsp.Ns, rsp.Extra} {
+			for _, rr := range section {
+				if rr.Header().Rrtype != dns.TypeOPT && rr.Header().Ttl < ttl {
+					ttl = rr.Header().Ttl
+				}
+			}
+		}
+	case rsp.Rcode == dns.RcodeSuccess || rsp.Rcode == dns.RcodeNameError:
+		// Negative answers without SOA are not cached
+		negative = true
+		for _, rr := range rsp.Ns {
+			if soa, ok := rr.(*dns.SOA); ok {
+				ttl = soa.Hdr.Ttl
+				if soa.Minttl < ttl {
+					ttl = soa.Minttl
+				}
+				break
+			}
+		}
+		if ttl > c.maxTTL {
+			ttl = c.maxTTL
+		}
+	}
+	return ttl, negative, ttl != 0
+}
+
+// Flush removes all the cached answers
+func (c *Cache) Flush() {
+	if c == nil {
+		return
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries = make(map[key]*list.Element)
+	c.lru.Init()
+}
+
+// Stats returns the cache statistics
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats := c.stats
+	stats.Capacity = c.capacity
+	stats.Size = c.lru.Len()
+	if lookups := stats.Hits + stats.Misses; lookups != 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+func (c *Cache) remove(element *list.Element) {
+	delete(c.entries, element.Value.(*entry).key)
+	c.lru.Remove(element)
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package cache
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+const exampleDomain = "www.example.com."
+
+func newResponse(name string, rcode int, ttl uint32) *dns.Msg {
+	req := new(dns.Msg)
+	req.SetQuestion(name, dns.TypeA)
+	rsp := new(dns.Msg)
+	rsp.SetRcode(req, rcode)
+	if rcode == dns.RcodeSuccess {
+		rsp.Answer = []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: name, Rrtype: dns.TypeA, Class: dns.ClassINET,
+			Ttl: ttl}, A: net.ParseIP("10.10.0.1")}}
+	}
+	return rsp
+}
+
+func TestCachePositiveAnswer(t *testing.T) {
+	now := time.Now()
+	c := NewCache(10, 3600)
+	c.now = func() time.Time { return now }
+
+	c.Set(newResponse(exampleDomain, dns.RcodeSuccess, 30))
+	req := new(dns.Msg)
+	req.SetQuestion("WWW.Example.com.", dns.TypeA)
+	rsp, ok := c.Get(req)
+	assert.True(t, ok)
+	assert.Equal(t, req.Id, rsp.Id)
+	assert.Equal(t, "WWW.Example.com.", rsp.Question[0].Name)
+	assert.Equal(t, uint32(30), rsp.Answer[0].Header().Ttl)
+
+	// The ttl is reduced by the time in the cache
+	now = now.Add(10 * time.Second)
+	rsp, ok = c.Get(req)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(20), rsp.Answer[0].Header().Ttl)
+
+	// Expired
+	now = now.Add(20 * time.Second)
+	_, ok = c.Get(req)
+	assert.False(t, ok)
+
+	stats := c.Stats()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, 0, stats.Size)
+}
+
+func TestCacheNegativeAnswer(t *testing.T) {
+	c := NewCache(10, 3600)
+
+	// Without SOA the negative answer is not cached
+	nxdomain := newResponse(exampleDomain, dns.RcodeNameError, 0)
+	c.Set(nxdomain)
+	_, ok := c.Get(nxdomain)
+	assert.False(t, ok)
+
+	nxdomain.Ns = []dns.RR{&dns.SOA{Hdr: dns.RR_Header{Name: "example.com.", Rrtype: dns.TypeSOA,
+		Class: dns.ClassINET, Ttl: 300}, Ns: "ns.example.com.", Mbox: "hostmaster.example.com.", Minttl: 60}}
+	c.Set(nxdomain)
+	rsp, ok := c.Get(nxdomain)
+	assert.True(t, ok)
+	assert.Equal(t, dns.RcodeNameError, rsp.Rcode)
+	assert.Equal(t, uint32(60), rsp.Ns[0].Header().Ttl)
+	assert.Equal(t, uint64(1), c.Stats().NegativeHits)
+
+	// Failures are not cached
+	servfail := newResponse("www.example.org.", dns.RcodeServerFailure, 0)
+	c.Set(servfail)
+	_, ok = c.Get(servfail)
+	assert.False(t, ok)
+}
+
+func TestCacheEviction(t *testing.T) {
+	c := NewCache(2, 10)
+	first := newResponse("a.example.com.", dns.RcodeSuccess, 30)
+	second := newResponse("b.example.com.", dns.RcodeSuccess, 30)
+	third := newResponse("c.example.com.", dns.RcodeSuccess, 30)
+	c.Set(first)
+	c.Set(second)
+	// The least recently used is evicted
+	_, _ = c.Get(first)
+	c.Set(third)
+
+	_, ok := c.Get(second)
+	assert.False(t, ok)
+	rsp, ok := c.Get(first)
+	assert.True(t, ok)
+	// Limited to the maximum ttl
+	assert.Equal(t, uint32(10), rsp.Answer[0].Header().Ttl)
+	assert.Equal(t, uint64(1), c.Stats().Evictions)
+
+	c.Flush()
+	assert.Equal(t, 0, c.Stats().Size)
+
+	disabled := NewCache(0, 10)
+	disabled.Set(first)
+	_, ok = disabled.Get(first)
+	assert.False(t, ok)
+}
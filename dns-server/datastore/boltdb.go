@@ -27,6 +27,7 @@ import (
 	"net"
 	"os"
 	"path"
+	"sort"
 	"strings"
 	"time"
 )
@@ -34,6 +35,8 @@ import (
 const (
 	// ZoneConfig Zone constant.
 	ZoneConfig = "zone"
+	// ViewConfig View constant.
+	ViewConfig = "view"
 	// DefaultZone default zone constant.
 	DefaultZone = "."
 	// DBPath DataBase Path.
@@ -44,17 +47,24 @@ const (
 type DNSConfigRRKey struct {
 	Host   string `json:"host"`
 	RRType uint16 `json:"rrType"`
+	View   string `json:"view,omitempty"`
 }
 
 // DNSConfigRRValue RR config value.
 type DNSConfigRRValue struct {
-	RRClass uint16   `json:"rrClass"`
-	PointTo []string `json:"pointTo"`
-	TTL     uint32   `json:"ttl"`
+	RRClass     uint16       `json:"rrClass"`
+	PointTo     []string     `json:"pointTo"`
+	TTL         uint32       `json:"ttl"`
+	Weights     []uint32     `json:"weights,omitempty"`
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
 }
 
 // rrTypeMap rr Type Map.
-var rrTypeMap = map[string]uint16{"A": dns.TypeA, "AAAA": dns.TypeAAAA}
+var rrTypeMap = map[string]uint16{"A": dns.TypeA, "AAAA": dns.TypeAAAA, "CNAME": dns.TypeCNAME, "SRV": dns.TypeSRV,
+	"TXT": dns.TypeTXT, "PTR": dns.TypePTR, "MX": dns.TypeMX, "SOA": dns.TypeSOA, "NS": dns.TypeNS}
+
+// maxCNAMEChain maximum number of the local cname records followed in a query.
+const maxCNAMEChain = 8
 
 // rrClassMap rr Class Map.
 var rrClassMap = map[string]uint16{"IN": dns.ClassINET, "CS": dns.ClassCSNET, "CH": dns.ClassCHAOS,
@@ -94,6 +104,18 @@ func (b *BoltDB) Open() error {
 			log.Error("Failed to create the default(.) zone bucket.", nil)
 			return fmt.Errorf("error creating default zone(.) bucket: %s", err)
 		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(ViewConfig)); err != nil {
+			log.Error("Failed to create the view bucket.", nil)
+			return fmt.Errorf("error creating view bucket: %s", err)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(MetaConfig)); err != nil {
+			log.Error("Failed to create the meta bucket.", nil)
+			return fmt.Errorf("error creating meta bucket: %s", err)
+		}
+		if _, err = tx.CreateBucketIfNotExists([]byte(ChangeLogConfig)); err != nil {
+			log.Error("Failed to create the change log bucket.", nil)
+			return fmt.Errorf("error creating change log bucket: %s", err)
+		}
 
 		return nil
 	})
@@ -117,7 +139,7 @@ func (b *BoltDB) Close() error {
 	return nil
 }
 
-func (b *BoltDB) setOrCreateDBEntryGeneration(confValueBytes []byte, rr *ResourceRecord) ([]byte, error) {
+func setOrCreateDBEntryGeneration(confValueBytes []byte, rr *ResourceRecord) (*DNSConfigRRValue, error) {
 	var err error
 	dnsCfgValue := &DNSConfigRRValue{}
 
@@ -143,13 +165,12 @@ func (b *BoltDB) setOrCreateDBEntryGeneration(confValueBytes []byte, rr *Resourc
 	}
 	dnsCfgValue.TTL = rr.TTL
 	if len(rr.RData) != 0 {
+		// The load balancing settings belong to the addresses
 		dnsCfgValue.PointTo = rr.RData
+		dnsCfgValue.Weights = rr.Weights
+		dnsCfgValue.HealthCheck = rr.HealthCheck
 	}
-	updatedConfValueBytes, err := json.Marshal(dnsCfgValue)
-	if err != nil {
-		return nil, fmt.Errorf("data store could not marshal dns config json")
-	}
-	return updatedConfValueBytes, nil
+	return dnsCfgValue, nil
 }
 
 func (b *BoltDB) SetResourceRecord(zone string, rr *ResourceRecord) error {
@@ -164,7 +185,7 @@ func (b *BoltDB) SetResourceRecord(zone string, rr *ResourceRecord) error {
 
 	host := strings.ToLower(rr.Name)
 
-	dnsCfgKey := DNSConfigRRKey{Host: host, RRType: rrType}
+	dnsCfgKey := DNSConfigRRKey{Host: host, RRType: rrType, View: rr.View}
 	confKeyBytes, err := json.Marshal(dnsCfgKey)
 	if err != nil {
 		return fmt.Errorf("internal error, could not parse dns config json")
@@ -172,24 +193,61 @@ func (b *BoltDB) SetResourceRecord(zone string, rr *ResourceRecord) error {
 
 	// Add new entry to the db
 	return b.db.Update(func(tx *bolt.Tx) error {
+		if len(rr.View) != 0 && tx.Bucket([]byte(ViewConfig)).Get([]byte(rr.View)) == nil {
+			return fmt.Errorf("view(%s) not found", rr.View)
+		}
 		zoneBkt, err := tx.Bucket([]byte(ZoneConfig)).CreateBucketIfNotExists([]byte(zone))
 		if err != nil {
 			return fmt.Errorf("zone(%s) retrieval failed", zone)
 		}
+		if hasCNAMEConflict(zoneBkt, host, rrType, rr.View) {
+			return fmt.Errorf("cname record can not coexist with other records of %s", host)
+		}
 		confValueBytes := zoneBkt.Get(confKeyBytes)
-		updatedConfValueBytes, err := b.setOrCreateDBEntryGeneration(confValueBytes, rr)
+		dnsCfgValue, err := setOrCreateDBEntryGeneration(confValueBytes, rr)
 		if err != nil {
 			return err
 		}
-		if err = zoneBkt.Put(confKeyBytes, updatedConfValueBytes); err != nil {
-			return fmt.Errorf("saving dns entry to data store failed")
-		}
 
-		return nil
+		return putRecord(tx, zone, zoneBkt, confKeyBytes, dnsCfgValue)
 	})
 }
 
-func (b *BoltDB) getRRFromZoneBucket(zoneBkt *bolt.Bucket, dnsCfgKeyBytes []byte, question *dns.Question) []dns.RR {
+// recordBucket reads the records of a zone or the views, implemented by the bolt buckets and the memory buckets
+type recordBucket interface {
+	Get(key []byte) []byte
+	ForEach(fn func(key, value []byte) error) error
+}
+
+// zoneLookup returns the bucket of the zone, nil if the zone is not available
+type zoneLookup func(zone string) recordBucket
+
+func boltZoneLookup(tx *bolt.Tx) zoneLookup {
+	zonesBkt := tx.Bucket([]byte(ZoneConfig))
+	return func(zone string) recordBucket {
+		if zoneBkt := zonesBkt.Bucket([]byte(zone)); zoneBkt != nil {
+			return zoneBkt
+		}
+		return nil
+	}
+}
+
+// hasCNAMEConflict checks the cname record would coexist with the other records of the host in the zone and view
+func hasCNAMEConflict(zoneBkt recordBucket, host string, rrType uint16, view string) bool {
+	for _, otherType := range rrTypeMap {
+		if otherType == rrType || (rrType != dns.TypeCNAME && otherType != dns.TypeCNAME) {
+			continue
+		}
+		keyBytes, err := json.Marshal(DNSConfigRRKey{Host: host, RRType: otherType, View: view})
+		if err == nil && zoneBkt.Get(keyBytes) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func getRRFromZoneBucket(zoneBkt recordBucket, dnsCfgKeyBytes []byte, owner string, rrType uint16,
+	rrClass uint16) []dns.RR {
 	var records []dns.RR
 	dnsCfgBytes := zoneBkt.Get(dnsCfgKeyBytes)
 	if dnsCfgBytes == nil {
@@ -200,63 +258,73 @@ func (b *BoltDB) getRRFromZoneBucket(zoneBkt *bolt.Bucket, dnsCfgKeyBytes []byte
 		return records
 	}
 	// rrClass filtering
-	if dnsCfg.RRClass != question.Qclass {
+	if dnsCfg.RRClass != rrClass {
 		return records
 	}
-	for _, pointToIP := range dnsCfg.PointTo {
-		if question.Qtype == dns.TypeA {
-			records = append(records, &dns.A{Hdr: dns.RR_Header{Name: question.Name, Rrtype: question.Qtype,
-				Class: dns.ClassINET, Ttl: dnsCfg.TTL}, A: net.ParseIP(pointToIP)})
-		} else if question.Qtype == dns.TypeAAAA {
-			records = append(records, &dns.AAAA{Hdr: dns.RR_Header{Name: question.Name, Rrtype: question.Qtype,
-				Class: dns.ClassINET, Ttl: dnsCfg.TTL}, AAAA: net.ParseIP(pointToIP)})
+	for _, rData := range dnsCfg.PointTo {
+		rr, err := newRR(owner, rrType, dnsCfg.RRClass, dnsCfg.TTL, rData)
+		if err != nil {
+			log.Errorf("Invalid %s record data of %s in the data store.", dns.TypeToString[rrType], owner)
+			continue
 		}
+		records = append(records, rr)
 	}
 
 	return records
 }
 
-func (b *BoltDB) GetResourceRecord(question *dns.Question) (*[]dns.RR, error) {
-	q := strings.ToLower(question.Name)
+// lookup finds the records of the owner name in the most specific zone having them, the records of the client views
+// are preferred in the given order over the records without view
+func lookup(zoneBkts zoneLookup, owner string, rrType uint16, rrClass uint16, views []string) ([]dns.RR, error) {
+	q := strings.ToLower(owner)
 	var (
 		off int
 		end bool
 	)
 
-	dnsCfgKey := DNSConfigRRKey{Host: q, RRType: question.Qtype}
-	dnsCfgKeyBytes, err := json.Marshal(dnsCfgKey)
-	if err != nil {
-		return nil, fmt.Errorf("parsing dns query failed")
-	}
-
-	zones := make(map[string]bool)
+	var zones []string
 	// Get a  zone entries from the input question
 	for {
-		zones[q[off:]] = true
+		zones = append(zones, q[off:])
 		off, end = dns.NextLabel(q, off)
 		if end {
 			break
 		}
 	}
-	zones["."] = true // Add the default zone at end to process
-
-	var records []dns.RR
+	if q != DefaultZone {
+		zones = append(zones, DefaultZone) // Add the default zone at end to process
+	}
 
-	err = b.db.View(func(tx *bolt.Tx) error {
-		var zoneBkt *bolt.Bucket
-		for zone := range zones {
-			zoneBkt = tx.Bucket([]byte(ZoneConfig)).Bucket([]byte(zone))
+	for _, view := range append(views, "") {
+		dnsCfgKey := DNSConfigRRKey{Host: q, RRType: rrType, View: view}
+		dnsCfgKeyBytes, err := json.Marshal(dnsCfgKey)
+		if err != nil {
+			return nil, fmt.Errorf("parsing dns query failed")
+		}
+		for _, zone := range zones {
+			zoneBkt := zoneBkts(zone)
 			if zoneBkt == nil {
 				// Zone not available in the db
 				continue
 			}
-			records = b.getRRFromZoneBucket(zoneBkt, dnsCfgKeyBytes, question)
+			records := getRRFromZoneBucket(zoneBkt, dnsCfgKeyBytes, owner, rrType, rrClass)
 			if len(records) != 0 {
-				break
+				return records, nil
 			}
 		}
+	}
+	return nil, nil
+}
 
-		return nil
+// GetResourceRecord answers the question from the local zones, the cname records are followed within the local
+// zones and the chain is returned in the answer, the records of the views matching the client are preferred
+func (b *BoltDB) GetResourceRecord(question *dns.Question, client net.IP) (*[]dns.RR, error) {
+	var records []dns.RR
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		records, err = resolve(boltZoneLookup(tx), matchViews(tx.Bucket([]byte(ViewConfig)), client), question)
+		return err
 	})
 	if err != nil {
 		return nil, fmt.Errorf("reading dns entry from data store failed")
@@ -268,7 +336,42 @@ func (b *BoltDB) GetResourceRecord(question *dns.Question) (*[]dns.RR, error) {
 	return &records, nil
 }
 
-func (b *BoltDB) DelResourceRecord(zone string, host string, rrtypestr string) error {
+// resolve answers the question from the zones with the records of the views, the cname chain is followed
+func resolve(zoneBkts zoneLookup, views []string, question *dns.Question) ([]dns.RR, error) {
+	var records []dns.RR
+	owner := question.Name
+	visited := make(map[string]bool)
+	for i := 0; i <= maxCNAMEChain; i++ {
+		answer, err := lookup(zoneBkts, owner, question.Qtype, question.Qclass, views)
+		if err != nil {
+			return nil, err
+		}
+		if len(answer) != 0 {
+			return append(records, answer...), nil
+		}
+		if question.Qtype == dns.TypeCNAME {
+			return records, nil
+		}
+
+		cname, err := lookup(zoneBkts, owner, dns.TypeCNAME, question.Qclass, views)
+		if err != nil {
+			return nil, err
+		}
+		if len(cname) == 0 {
+			return records, nil
+		}
+		records = append(records, cname[0])
+		visited[strings.ToLower(owner)] = true
+		owner = cname[0].(*dns.CNAME).Target
+		if visited[strings.ToLower(owner)] {
+			log.Errorf("CNAME loop found on %s.", question.Name)
+			return records, nil
+		}
+	}
+	return records, nil
+}
+
+func (b *BoltDB) DelResourceRecord(zone string, host string, rrtypestr string, view string) error {
 	// panic("implement me")
 	var found bool
 	rrType, ok := rrTypeMap[rrtypestr]
@@ -276,7 +379,7 @@ func (b *BoltDB) DelResourceRecord(zone string, host string, rrtypestr string) e
 		return fmt.Errorf("unsupported rrtype(%s) entry", rrtypestr)
 	}
 
-	dnsCfgKey := &DNSConfigRRKey{Host: strings.ToLower(host), RRType: rrType}
+	dnsCfgKey := &DNSConfigRRKey{Host: strings.ToLower(host), RRType: rrType, View: view}
 	dnsCfgKeyBytes, err := json.Marshal(dnsCfgKey)
 	if err != nil {
 		return fmt.Errorf("failed to parse input request")
@@ -296,7 +399,7 @@ func (b *BoltDB) DelResourceRecord(zone string, host string, rrtypestr string) e
 			if zoneBkt.Get(dnsCfgKeyBytes) != nil {
 				found = true
 
-				return zoneBkt.Delete(dnsCfgKeyBytes)
+				return deleteRecord(tx, string(zone), zoneBkt, dnsCfgKeyBytes)
 			}
 			return nil
 		})
@@ -326,7 +429,7 @@ func (b *BoltDB) IsResourceRecordExists(zone string, rr *ResourceRecord) bool {
 
 	host := strings.ToLower(rr.Name)
 
-	dnsCfgKey := DNSConfigRRKey{Host: host, RRType: rrType}
+	dnsCfgKey := DNSConfigRRKey{Host: host, RRType: rrType, View: rr.View}
 	confKeyBytes, err := json.Marshal(dnsCfgKey)
 	if err != nil {
 		log.Error("Internal error, could not parse dns config json")
@@ -361,3 +464,268 @@ func (b *BoltDB) IsResourceRecordExists(zone string, rr *ResourceRecord) bool {
 
 	return true
 }
+
+// toResourceRecord converts the stored entry to the resource record
+func toResourceRecord(keyBytes []byte, valueBytes []byte) (*ResourceRecord, error) {
+	dnsCfgKey := &DNSConfigRRKey{}
+	if err := json.Unmarshal(keyBytes, dnsCfgKey); err != nil {
+		return nil, fmt.Errorf("parsing failed on data retrieval")
+	}
+	dnsCfgValue := &DNSConfigRRValue{}
+	if err := json.Unmarshal(valueBytes, dnsCfgValue); err != nil {
+		return nil, fmt.Errorf("parsing failed on data retrieval")
+	}
+	return &ResourceRecord{Name: dnsCfgKey.Host, Type: dns.TypeToString[dnsCfgKey.RRType],
+		Class: dns.ClassToString[dnsCfgValue.RRClass], TTL: dnsCfgValue.TTL, RData: dnsCfgValue.PointTo,
+		Weights: dnsCfgValue.Weights, HealthCheck: dnsCfgValue.HealthCheck, View: dnsCfgKey.View}, nil
+}
+
+func (b *BoltDB) GetZoneResourceRecord(zone string, host string, rrtypestr string, view string) (*ResourceRecord,
+	error) {
+	rrType, ok := rrTypeMap[rrtypestr]
+	if !ok {
+		return nil, fmt.Errorf("unsupported rrtype(%s) entry", rrtypestr)
+	}
+	dnsCfgKeyBytes, err := json.Marshal(&DNSConfigRRKey{Host: strings.ToLower(host), RRType: rrType, View: view})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input request")
+	}
+
+	var rr *ResourceRecord
+	err = b.db.View(func(tx *bolt.Tx) error {
+		zoneBkt := tx.Bucket([]byte(ZoneConfig)).Bucket([]byte(zone))
+		if zoneBkt == nil {
+			return ErrNotFound
+		}
+		valueBytes := zoneBkt.Get(dnsCfgKeyBytes)
+		if valueBytes == nil {
+			return ErrNotFound
+		}
+		rr, err = toResourceRecord(dnsCfgKeyBytes, valueBytes)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rr, nil
+}
+
+func (b *BoltDB) ListResourceRecords(zone string) ([]ResourceRecord, error) {
+	records := make([]ResourceRecord, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		zoneBkt := tx.Bucket([]byte(ZoneConfig)).Bucket([]byte(zone))
+		if zoneBkt == nil {
+			return ErrNotFound
+		}
+		return zoneBkt.ForEach(func(key, value []byte) error {
+			rr, err := toResourceRecord(key, value)
+			if err != nil {
+				log.Errorf("Skipped the invalid record in the zone %s.", zone)
+				return nil
+			}
+			records = append(records, *rr)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		return records[i].Type < records[j].Type
+	})
+	return records, nil
+}
+
+func (b *BoltDB) ListZones() ([]string, error) {
+	zones := make([]string, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(ZoneConfig)).ForEach(func(zone, _ []byte) error {
+			zones = append(zones, string(zone))
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading zones from data store failed")
+	}
+	sort.Strings(zones)
+	return zones, nil
+}
+
+func (b *BoltDB) ImportZone(zone string, records []ResourceRecord, replace bool) error {
+	zone = dns.Fqdn(strings.ToLower(zone))
+	return b.db.Update(func(tx *bolt.Tx) error {
+		zoneBkt, err := tx.Bucket([]byte(ZoneConfig)).CreateBucketIfNotExists([]byte(zone))
+		if err != nil {
+			return fmt.Errorf("zone(%s) retrieval failed", zone)
+		}
+
+		// The records of the replaced zone missing in the import are deleted
+		keys := make(map[string]bool, len(records))
+		for i := range records {
+			rr := &records[i]
+			rrType, ok := rrTypeMap[rr.Type]
+			if !ok {
+				return fmt.Errorf("unsupported rrtype(%s) entry", rr.Type)
+			}
+			confKeyBytes, err := json.Marshal(DNSConfigRRKey{Host: strings.ToLower(rr.Name), RRType: rrType,
+				View: rr.View})
+			if err != nil {
+				return fmt.Errorf("internal error, could not parse dns config json")
+			}
+			keys[string(confKeyBytes)] = true
+		}
+		if replace {
+			if err = deleteZoneRecords(tx, zone, zoneBkt, keys); err != nil {
+				return fmt.Errorf("zone(%s) replace failed", zone)
+			}
+		}
+
+		for i := range records {
+			rr := &records[i]
+			rrType := rrTypeMap[rr.Type]
+			if rr.TTL == 0 {
+				return fmt.Errorf("unsupported/missing ttl value")
+			}
+			if len(rr.View) != 0 && tx.Bucket([]byte(ViewConfig)).Get([]byte(rr.View)) == nil {
+				return fmt.Errorf("view(%s) not found", rr.View)
+			}
+			host := strings.ToLower(rr.Name)
+			if hasCNAMEConflict(zoneBkt, host, rrType, rr.View) {
+				return fmt.Errorf("cname record can not coexist with other records of %s", host)
+			}
+			confKeyBytes, err := json.Marshal(DNSConfigRRKey{Host: host, RRType: rrType, View: rr.View})
+			if err != nil {
+				return fmt.Errorf("internal error, could not parse dns config json")
+			}
+			// Merge replaces the records of the same name and type
+			dnsCfgValue, err := setOrCreateDBEntryGeneration(nil, rr)
+			if err != nil {
+				return err
+			}
+			if err = putRecord(tx, zone, zoneBkt, confKeyBytes, dnsCfgValue); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// deleteZoneRecords deletes the records of the zone except the kept ones
+func deleteZoneRecords(tx *bolt.Tx, zone string, zoneBkt *bolt.Bucket, kept map[string]bool) error {
+	var deleted [][]byte
+	err := zoneBkt.ForEach(func(key, _ []byte) error {
+		if !kept[string(key)] {
+			deleted = append(deleted, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range deleted {
+		if err = deleteRecord(tx, zone, zoneBkt, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *BoltDB) SetZoneAuthoritative(zone string, authoritative bool) error {
+	zone = dns.Fqdn(strings.ToLower(zone))
+	if zone == DefaultZone {
+		return fmt.Errorf("default zone can not be authoritative")
+	}
+	soaKeyBytes, _ := json.Marshal(DNSConfigRRKey{Host: zone, RRType: dns.TypeSOA})
+	nsKeyBytes, _ := json.Marshal(DNSConfigRRKey{Host: zone, RRType: dns.TypeNS})
+
+	return b.db.Update(func(tx *bolt.Tx) error {
+		zoneBkt, err := tx.Bucket([]byte(ZoneConfig)).CreateBucketIfNotExists([]byte(zone))
+		if err != nil {
+			return fmt.Errorf("zone(%s) retrieval failed", zone)
+		}
+		if !authoritative {
+			return deleteRecord(tx, zone, zoneBkt, soaKeyBytes)
+		}
+
+		// Generate the SOA and NS records if not available
+		soa := NewSOA(zone, uint32(time.Now().Unix()))
+		generated := map[string]dns.RR{string(soaKeyBytes): soa,
+			string(nsKeyBytes): &dns.NS{Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeNS, Class: dns.ClassINET,
+				Ttl: util.DefaultTTL}, Ns: soa.Ns}}
+		for key, rr := range generated {
+			if zoneBkt.Get([]byte(key)) != nil {
+				continue
+			}
+			err = putRecord(tx, zone, zoneBkt, []byte(key), &DNSConfigRRValue{RRClass: dns.ClassINET,
+				TTL: rr.Header().Ttl, PointTo: []string{recordData(rr)}})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (b *BoltDB) GetAuthority(name string) (dns.RR, bool, error) {
+	var soa dns.RR
+	var exists bool
+
+	err := b.db.View(func(tx *bolt.Tx) error {
+		soa, exists = authority(boltZoneLookup(tx), name)
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("reading zone authority from data store failed")
+	}
+	return soa, exists, nil
+}
+
+// authority finds the SOA record of the most specific authoritative zone of the name and whether the name exists
+func authority(zoneBkts zoneLookup, name string) (dns.RR, bool) {
+	name = strings.ToLower(name)
+	var soa dns.RR
+	var zoneBkt recordBucket
+	for off, end := 0, false; !end; off, end = dns.NextLabel(name, off) {
+		zone := name[off:]
+		if zone == DefaultZone {
+			break
+		}
+		zoneBkt = zoneBkts(zone)
+		if zoneBkt == nil {
+			continue
+		}
+		soaKeyBytes, _ := json.Marshal(DNSConfigRRKey{Host: zone, RRType: dns.TypeSOA})
+		records := getRRFromZoneBucket(zoneBkt, soaKeyBytes, zone, dns.TypeSOA, dns.ClassINET)
+		if len(records) != 0 {
+			soa = records[0]
+			break
+		}
+	}
+	if soa == nil {
+		return nil, false
+	}
+
+	// The name exists if it owns any record or it is an empty non-terminal of a record owner
+	for _, bkt := range []recordBucket{zoneBkt, zoneBkts(DefaultZone)} {
+		if bkt != nil && nameExists(bkt, name) {
+			return soa, true
+		}
+	}
+	return soa, false
+}
+
+func nameExists(zoneBkt recordBucket, name string) bool {
+	found := false
+	_ = zoneBkt.ForEach(func(key, _ []byte) error {
+		dnsCfgKey := &DNSConfigRRKey{}
+		if found || json.Unmarshal(key, dnsCfgKey) != nil {
+			return nil
+		}
+		found = dnsCfgKey.Host == name || strings.HasSuffix(dnsCfgKey.Host, "."+name)
+		return nil
+	})
+	return found
+}
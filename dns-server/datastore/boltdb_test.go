@@ -67,18 +67,18 @@ func TestBasicDataStoreOperations(t *testing.T) {
 	assert.Equal(t, nil, err, "Error in setting the record")
 
 	question := dns.Question{Name: exampleDomain, Qtype: dns.TypeA, Qclass: dns.ClassINET}
-	rrResponse, err := store.GetResourceRecord(&question)
+	rrResponse, err := store.GetResourceRecord(&question, nil)
 	assert.Equal(t, nil, err, "Error in reading the record")
 	assert.NotEqual(t, 0, len(*rrResponse), "Not found")
 	assert.Equal(t, fmt.Sprintf(exampleRspFormatter, dnsConfigTestIP1),
 		(*rrResponse)[0].String(), "Error")
 
-	err = store.DelResourceRecord("", exampleDomain, "A")
+	err = store.DelResourceRecord("", exampleDomain, "A", "")
 	assert.Equal(t, nil, err, errorDeleteMessage)
 
 	t.Run("QueryNonExistingRecord", func(t *testing.T) {
 		question := dns.Question{Name: example1Domain, Qtype: dns.TypeA, Qclass: dns.ClassINET}
-		_, err = store.GetResourceRecord(&question)
+		_, err = store.GetResourceRecord(&question, nil)
 		assert.EqualError(t, err, "could not process/retrieve the query", "Error in reading the db")
 	})
 
@@ -107,20 +107,20 @@ func TestBasicDataStoreOperations(t *testing.T) {
 		_ = store.SetResourceRecord(".", &ResourceRecord{Name: "WWW.EXAMPLE.COM.", Type: "A",
 			Class: "IN", TTL: 30, RData: []string{dnsConfigTestIP1}})
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: exampleDomain,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, fmt.Sprintf(exampleRspFormatter, dnsConfigTestIP1), (*rrResponse)[0].String(),
 			"Error")
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: "WWW.EXAMPLE.COM.",
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, fmt.Sprintf("WWW.EXAMPLE.COM.\t30\tIN\tA\t%s", dnsConfigTestIP1), (*rrResponse)[0].String(),
 			"Error")
 
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: "WWW.example.COM.",
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, fmt.Sprintf("WWW.example.COM.\t30\tIN\tA\t%s", dnsConfigTestIP1), (*rrResponse)[0].String(),
 			"Error")
 
-		err = store.DelResourceRecord("", exampleDomain, "A")
+		err = store.DelResourceRecord("", exampleDomain, "A", "")
 		assert.Equal(t, nil, err, errorDeleteMessage)
 	})
 
@@ -131,16 +131,16 @@ func TestBasicDataStoreOperations(t *testing.T) {
 			Class: "IN", TTL: 30, RData: []string{dnsConfigTestIP2}})
 		assert.Equal(t, nil, err, "Error in setting the db")
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: exampleDomain,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, fmt.Sprintf(exampleRspFormatter, dnsConfigTestIP2), (*rrResponse)[0].String(),
 			"Error")
 
-		err = store.DelResourceRecord("", exampleDomain, "A")
+		err = store.DelResourceRecord("", exampleDomain, "A", "")
 		assert.Equal(t, nil, err, errorDeleteMessage)
 	})
 
 	t.Run("DeleteNonExistingRecord", func(t *testing.T) {
-		err = store.DelResourceRecord("cloud", exampleDomain, "A")
+		err = store.DelResourceRecord("cloud", exampleDomain, "A", "")
 		assert.NotEqual(t, nil, err, "Error in deleting the db")
 		assert.EqualError(t, err, "not found for the zone cloud", errorSettingMessage)
 	})
@@ -149,11 +149,11 @@ func TestBasicDataStoreOperations(t *testing.T) {
 		_ = store.SetResourceRecord(".", &ResourceRecord{Name: exampleDomain, Type: "A",
 			Class: "IN", TTL: 30, RData: []string{dnsConfigTestIP1}})
 
-		err = store.DelResourceRecord("", exampleDomain, "None")
+		err = store.DelResourceRecord("", exampleDomain, "None", "")
 		assert.NotEqual(t, nil, err, "Error in deleting the db")
 		assert.EqualError(t, err, "unsupported rrtype(None) entry", "Error in deleting record")
 
-		err = store.DelResourceRecord("", exampleDomain, "A")
+		err = store.DelResourceRecord("", exampleDomain, "A", "")
 		assert.Equal(t, nil, err, errorDeleteMessage)
 	})
 
@@ -162,11 +162,11 @@ func TestBasicDataStoreOperations(t *testing.T) {
 			Class: "IN", TTL: 30, RData: []string{dnsConfigTestIP3}})
 
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: exampleAbcDomain,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, fmt.Sprintf(abcExampleRspFormatter, dnsConfigTestIP3), (*rrResponse)[0].String(),
 			"Error")
 
-		err = store.DelResourceRecord("", exampleAbcDomain, "A")
+		err = store.DelResourceRecord("", exampleAbcDomain, "A", "")
 		assert.Equal(t, nil, err, errorDeleteMessage)
 	})
 
@@ -175,7 +175,7 @@ func TestBasicDataStoreOperations(t *testing.T) {
 			Class: "IN", TTL: 30, RData: []string{dnsConfigTestIP3, dnsConfigTestIP4, dnsConfigTestIP5}})
 
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: exampleAbcDomain,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, 3, len(*rrResponse), "Not found all records")
 		assert.Equal(t, fmt.Sprintf(abcExampleRspFormatter, dnsConfigTestIP3), (*rrResponse)[0].String(),
 			"Error")
@@ -184,10 +184,163 @@ func TestBasicDataStoreOperations(t *testing.T) {
 		assert.Equal(t, fmt.Sprintf(abcExampleRspFormatter, dnsConfigTestIP5), (*rrResponse)[2].String(),
 			"Error")
 
-		err = store.DelResourceRecord("", exampleAbcDomain, "A")
+		err = store.DelResourceRecord("", exampleAbcDomain, "A", "")
 		assert.Equal(t, nil, err, errorDeleteMessage)
 	})
 
 	err = store.Close()
 	assert.Equal(t, nil, err, "Error in closing the db")
 }
+
+func TestRecordTypes(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(DBPath)
+	}()
+
+	store := &BoltDB{FileName: "testtypesdb", TTL: 30}
+	err := store.Open()
+	assert.Equal(t, nil, err, "Error in opening the db")
+	defer store.Close()
+
+	query := func(name string, qtype uint16) []string {
+		rrResponse, err := store.GetResourceRecord(&dns.Question{Name: name, Qtype: qtype, Qclass: dns.ClassINET}, nil)
+		if err != nil {
+			return nil
+		}
+		var answer []string
+		for _, rr := range *rrResponse {
+			answer = append(answer, rr.String())
+		}
+		return answer
+	}
+
+	t.Run("SRV", func(t *testing.T) {
+		err = store.SetResourceRecord(".", &ResourceRecord{Name: "_http._tcp.app.example.com.", Type: "SRV",
+			Class: "IN", TTL: 30, RData: []string{"10 60 8080 app.example.com."}})
+		assert.Equal(t, nil, err, errorSettingMessage)
+		assert.Equal(t, []string{"_http._tcp.app.example.com.\t30\tIN\tSRV\t10 60 8080 app.example.com."},
+			query("_http._tcp.app.example.com.", dns.TypeSRV))
+	})
+
+	t.Run("TXT", func(t *testing.T) {
+		err = store.SetResourceRecord(".", &ResourceRecord{Name: "app.example.com.", Type: "TXT",
+			Class: "IN", TTL: 30, RData: []string{"version=1"}})
+		assert.Equal(t, nil, err, errorSettingMessage)
+		assert.Equal(t, []string{"app.example.com.\t30\tIN\tTXT\t\"version=1\""},
+			query("app.example.com.", dns.TypeTXT))
+	})
+
+	t.Run("PTR", func(t *testing.T) {
+		err = store.SetResourceRecord("in-addr.arpa.", &ResourceRecord{Name: "1.0.10.10.in-addr.arpa.",
+			Type: "PTR", Class: "IN", TTL: 30, RData: []string{"app.example.com."}})
+		assert.Equal(t, nil, err, errorSettingMessage)
+		assert.Equal(t, []string{"1.0.10.10.in-addr.arpa.\t30\tIN\tPTR\tapp.example.com."},
+			query("1.0.10.10.in-addr.arpa.", dns.TypePTR))
+	})
+
+	t.Run("MX", func(t *testing.T) {
+		err = store.SetResourceRecord(".", &ResourceRecord{Name: "example.com.", Type: "MX",
+			Class: "IN", TTL: 30, RData: []string{"10 mail.example.com."}})
+		assert.Equal(t, nil, err, errorSettingMessage)
+		assert.Equal(t, []string{"example.com.\t30\tIN\tMX\t10 mail.example.com."},
+			query("example.com.", dns.TypeMX))
+	})
+
+	t.Run("CNAME", func(t *testing.T) {
+		err = store.SetResourceRecord(".", &ResourceRecord{Name: "app.example.com.", Type: "A",
+			Class: "IN", TTL: 30, RData: []string{dnsConfigTestIP1}})
+		assert.Equal(t, nil, err, errorSettingMessage)
+		err = store.SetResourceRecord(".", &ResourceRecord{Name: "alias.example.com.", Type: "CNAME",
+			Class: "IN", TTL: 30, RData: []string{"www.example.com."}})
+		assert.Equal(t, nil, err, errorSettingMessage)
+		err = store.SetResourceRecord("example.com.", &ResourceRecord{Name: "www.example.com.", Type: "CNAME",
+			Class: "IN", TTL: 30, RData: []string{"app.example.com."}})
+		assert.Equal(t, nil, err, errorSettingMessage)
+
+		// The chain is followed within the local zones
+		assert.Equal(t, []string{"alias.example.com.\t30\tIN\tCNAME\twww.example.com.",
+			"www.example.com.\t30\tIN\tCNAME\tapp.example.com.",
+			fmt.Sprintf("app.example.com.\t30\tIN\tA\t%s", dnsConfigTestIP1)},
+			query("alias.example.com.", dns.TypeA))
+		assert.Equal(t, []string{"alias.example.com.\t30\tIN\tCNAME\twww.example.com."},
+			query("alias.example.com.", dns.TypeCNAME))
+
+		// The cname can not coexist with the other records
+		err = store.SetResourceRecord(".", &ResourceRecord{Name: "alias.example.com.", Type: "A",
+			Class: "IN", TTL: 30, RData: []string{dnsConfigTestIP2}})
+		assert.NotEqual(t, nil, err, errorSettingMessage)
+		err = store.SetResourceRecord(".", &ResourceRecord{Name: "app.example.com.", Type: "CNAME",
+			Class: "IN", TTL: 30, RData: []string{"www.example.com."}})
+		assert.NotEqual(t, nil, err, errorSettingMessage)
+
+		// Chain ends outside the local zones
+		err = store.SetResourceRecord(".", &ResourceRecord{Name: "ext.example.com.", Type: "CNAME",
+			Class: "IN", TTL: 30, RData: []string{"www.edgegallery.org."}})
+		assert.Equal(t, nil, err, errorSettingMessage)
+		assert.Equal(t, []string{"ext.example.com.\t30\tIN\tCNAME\twww.edgegallery.org."},
+			query("ext.example.com.", dns.TypeA))
+
+		// Loop
+		err = store.SetResourceRecord(".", &ResourceRecord{Name: "loop1.example.com.", Type: "CNAME",
+			Class: "IN", TTL: 30, RData: []string{"loop2.example.com."}})
+		assert.Equal(t, nil, err, errorSettingMessage)
+		err = store.SetResourceRecord(".", &ResourceRecord{Name: "loop2.example.com.", Type: "CNAME",
+			Class: "IN", TTL: 30, RData: []string{"loop1.example.com."}})
+		assert.Equal(t, nil, err, errorSettingMessage)
+		assert.Equal(t, 2, len(query("loop1.example.com.", dns.TypeA)))
+	})
+
+	t.Run("ValidateRecordData", func(t *testing.T) {
+		assert.Nil(t, ValidateRecordData("SRV", []string{"10 60 8080 app.example.com."}))
+		assert.NotNil(t, ValidateRecordData("SRV", []string{"10 60 app.example.com."}))
+		assert.Nil(t, ValidateRecordData("MX", []string{"10 mail.example.com."}))
+		assert.NotNil(t, ValidateRecordData("MX", []string{"mail.example.com."}))
+		assert.NotNil(t, ValidateRecordData("CNAME", []string{"a.example.com.", "b.example.com."}))
+		assert.NotNil(t, ValidateRecordData("PTR", []string{"app.example.com.\nexample.com. 30 IN A 1.1.1.1"}))
+		assert.Nil(t, ValidateRecordData("TXT", []string{"any text"}))
+		assert.NotNil(t, ValidateRecordData("HINFO", []string{"cpu os"}))
+	})
+}
+
+func TestZoneAuthority(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(DBPath)
+	}()
+
+	store := &BoltDB{FileName: "testauthoritydb", TTL: 30}
+	assert.Nil(t, store.Open())
+	defer store.Close()
+
+	assert.Nil(t, store.SetResourceRecord("example.com.", &ResourceRecord{Name: "_http._tcp.app.example.com.",
+		Type: "SRV", Class: "IN", TTL: 30, RData: []string{"10 60 8080 app.example.com."}}))
+
+	// Not authoritative until marked
+	soa, _, err := store.GetAuthority("www.example.com.")
+	assert.Nil(t, err)
+	assert.Nil(t, soa)
+	assert.NotNil(t, store.SetZoneAuthoritative(DefaultZone, true))
+
+	assert.Nil(t, store.SetZoneAuthoritative("Example.com", true))
+	soa, exists, err := store.GetAuthority("WWW.example.com.")
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com.", soa.Header().Name)
+	assert.False(t, exists)
+	_, exists, _ = store.GetAuthority("_http._tcp.app.example.com.")
+	assert.True(t, exists)
+	// Empty non-terminal
+	_, exists, _ = store.GetAuthority("app.example.com.")
+	assert.True(t, exists)
+	ns, err := store.GetZoneResourceRecord("example.com.", "example.com.", "NS", "")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ns.example.com."}, ns.RData)
+
+	// The stored SOA is kept on marking again
+	assert.Nil(t, store.SetZoneAuthoritative("example.com.", true))
+	again, _, _ := store.GetAuthority("www.example.com.")
+	assert.Equal(t, soa.String(), again.String())
+
+	assert.Nil(t, store.SetZoneAuthoritative("example.com.", false))
+	soa, _, err = store.GetAuthority("www.example.com.")
+	assert.Nil(t, err)
+	assert.Nil(t, soa)
+}
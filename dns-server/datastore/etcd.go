@@ -0,0 +1,524 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/util"
+)
+
+// Keys of the data store under the prefix
+const (
+	// etcdZonesKey the records of a zone in one key, zones/<zone>
+	etcdZonesKey = "zones/"
+	// etcdViewsKey a view, views/<name>
+	etcdViewsKey = "views/"
+	// etcdChangesKey the changes of an update, changes/<generation of the last change>
+	etcdChangesKey = "changes/"
+	// etcdGenerationKey the generation of the last change, every update writes it to detect the concurrent updates
+	etcdGenerationKey = "generation"
+)
+
+// EtcdDB the data store in etcd shared by the dns server replicas. The data store is cached in memory and the cache is
+// updated by watching the prefix, so the replicas answer the queries without etcd requests. An update is one etcd
+// transaction conditional on the generation and it is retried on the latest data when another replica updated the
+// data store. The records of a zone are kept in one key so the zone size is limited by the etcd request size.
+type EtcdDB struct {
+	Endpoints []string
+	// Prefix key prefix of the data store in etcd
+	Prefix string
+	// TLS optional tls config of the etcd client
+	TLS *tls.Config
+	TTL uint32
+
+	client   *clientv3.Client
+	kv       clientv3.KV
+	watcher  clientv3.Watcher
+	cancel   context.CancelFunc
+	done     sync.WaitGroup
+	updating sync.Mutex
+
+	mutex sync.Mutex
+	cache etcdCache
+}
+
+// etcdCache the cached state and the etcd revisions it reflects
+type etcdCache struct {
+	state *memState
+	// revision etcd revision of the state
+	revision int64
+	// genRevision modification revision of the generation key
+	genRevision int64
+	// batches generations of the last changes of the change keys in etcd
+	batches []uint64
+}
+
+func (e *EtcdDB) Open() error {
+	if e.kv == nil {
+		client, err := clientv3.New(clientv3.Config{Endpoints: e.Endpoints, DialTimeout: util.EtcdDialTimeout,
+			TLS: e.TLS})
+		if err != nil {
+			log.Errorf("Failed to connect to the etcd(%s).", strings.Join(e.Endpoints, ","))
+			return fmt.Errorf("connecting to etcd failed(%s)", err.Error())
+		}
+		e.client, e.kv, e.watcher = client, client.KV, client.Watcher
+	}
+	if err := e.reload(); err != nil {
+		return err
+	}
+
+	// Create the default zone if not exists one
+	err := e.update(func(tx *memTx) error {
+		if _, ok := tx.zones[DefaultZone]; !ok {
+			tx.writableZone(DefaultZone)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	e.cancel = cancel
+	e.done.Add(1)
+	go e.watch(ctx)
+
+	log.Debugf("Initialize etcd db(%s) success.", e.Prefix)
+	return nil
+}
+
+func (e *EtcdDB) Close() error {
+	if e.cancel != nil {
+		e.cancel()
+		e.done.Wait()
+		e.cancel = nil
+	}
+	if e.client != nil {
+		if err := e.client.Close(); err != nil {
+			log.Errorf("Failed to close the etcd db(%s).", e.Prefix)
+			return err
+		}
+		e.client = nil
+	}
+	log.Debugf("Closed etcd db(%s) as part of shutdown service.", e.Prefix)
+	return nil
+}
+
+// current returns the cached state
+func (e *EtcdDB) current() etcdCache {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	cache := e.cache
+	if cache.state == nil {
+		cache.state = newMemState()
+	}
+	return cache
+}
+
+// reload replaces the cache with the data store read from etcd, unless the cache is already newer
+func (e *EtcdDB) reload() error {
+	ctx, cancel := context.WithTimeout(context.Background(), util.EtcdRequestTimeout)
+	defer cancel()
+	resp, err := e.kv.Get(ctx, e.Prefix, clientv3.WithPrefix())
+	if err != nil {
+		return fmt.Errorf("reading data store from etcd failed(%s)", err.Error())
+	}
+	// The keys are returned in order, so are the change keys by the zero padded generation
+	cache := etcdCache{state: newMemState(), revision: resp.Header.Revision}
+	for _, kv := range resp.Kvs {
+		cache.apply(e.Prefix, kv, false)
+	}
+
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.cache.state != nil && e.cache.revision >= cache.revision {
+		return nil
+	}
+	e.cache = cache
+	return nil
+}
+
+// watch applies the changes of the data store in etcd to the cache, the cache is reloaded when the watch fails
+func (e *EtcdDB) watch(ctx context.Context) {
+	defer e.done.Done()
+	for {
+		e.follow(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(util.EtcdRetryInterval):
+		}
+		if err := e.reload(); err != nil {
+			log.Errorf("Failed to reload the data store from etcd(%s).", err.Error())
+		}
+	}
+}
+
+// follow applies the watched events until the watch fails or the context is done
+func (e *EtcdDB) follow(ctx context.Context) {
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watchChan := e.watcher.Watch(watchCtx, e.Prefix, clientv3.WithPrefix(),
+		clientv3.WithRev(e.current().revision+1))
+	for resp := range watchChan {
+		if err := resp.Err(); err != nil {
+			log.Warnf("Watching the data store in etcd failed(%s), reloading.", err.Error())
+			return
+		}
+		e.applyEvents(resp.Events)
+	}
+}
+
+// applyEvents applies the events newer than the cache, the events of the local updates are already applied
+func (e *EtcdDB) applyEvents(events []*clientv3.Event) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.cache.state == nil {
+		return
+	}
+	cache := etcdCache{state: e.cache.state.begin().memState, revision: e.cache.revision,
+		genRevision: e.cache.genRevision, batches: e.cache.batches}
+	applied := false
+	for _, event := range events {
+		if event.Kv.ModRevision <= e.cache.revision {
+			continue
+		}
+		cache.apply(e.Prefix, event.Kv, event.Type == clientv3.EventTypeDelete)
+		cache.revision = event.Kv.ModRevision
+		applied = true
+	}
+	if applied {
+		e.cache = cache
+	}
+}
+
+// apply applies the key of the data store to the state, the state must be a copy
+func (c *etcdCache) apply(prefix string, kv *mvccpb.KeyValue, deleted bool) {
+	key := strings.TrimPrefix(string(kv.Key), prefix)
+	switch {
+	case strings.HasPrefix(key, etcdZonesKey):
+		zone := strings.TrimPrefix(key, etcdZonesKey)
+		if deleted {
+			delete(c.state.zones, zone)
+			return
+		}
+		zoneBkt, err := decodeZone(kv.Value)
+		if err != nil {
+			log.Errorf("Skipped the invalid zone %s in etcd.", zone)
+			return
+		}
+		c.state.zones[zone] = zoneBkt
+	case strings.HasPrefix(key, etcdViewsKey):
+		name := strings.TrimPrefix(key, etcdViewsKey)
+		if deleted {
+			delete(c.state.views, name)
+			return
+		}
+		c.state.views[name] = kv.Value
+	case strings.HasPrefix(key, etcdChangesKey):
+		last, err := strconv.ParseUint(strings.TrimPrefix(key, etcdChangesKey), 10, 64)
+		if err != nil {
+			return
+		}
+		batches := make([]uint64, 0, len(c.batches)+1)
+		for _, batch := range c.batches {
+			if batch != last {
+				batches = append(batches, batch)
+			}
+		}
+		if !deleted {
+			batches = append(batches, last)
+			c.appendChanges(kv.Value)
+		}
+		c.batches = batches
+	case key == etcdGenerationKey:
+		if deleted {
+			return
+		}
+		if generation, err := strconv.ParseUint(string(kv.Value), 10, 64); err == nil {
+			c.state.generation = generation
+		}
+		c.genRevision = kv.ModRevision
+	}
+}
+
+// appendChanges appends the changes after the last cached change
+func (c *etcdCache) appendChanges(value []byte) {
+	var changes []Change
+	if err := json.Unmarshal(value, &changes); err != nil {
+		log.Error("Skipped the invalid changes in etcd.")
+		return
+	}
+	for _, change := range changes {
+		if n := len(c.state.changes); n != 0 && change.Generation <= c.state.changes[n-1].Generation {
+			continue
+		}
+		c.state.changes = append(c.state.changes, change)
+	}
+	if len(c.state.changes) > util.MaxChangeLogEntries {
+		c.state.changes = c.state.changes[len(c.state.changes)-util.MaxChangeLogEntries:]
+	}
+}
+
+func encodeZone(zoneBkt memBucket) ([]byte, error) {
+	records := make(map[string]json.RawMessage, len(zoneBkt))
+	for key, value := range zoneBkt {
+		records[key] = value
+	}
+	return json.Marshal(records)
+}
+
+func decodeZone(value []byte) (memBucket, error) {
+	records := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(value, &records); err != nil {
+		return nil, err
+	}
+	zoneBkt := make(memBucket, len(records))
+	for key, record := range records {
+		zoneBkt[key] = record
+	}
+	return zoneBkt, nil
+}
+
+func sameBucket(a, b memBucket) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || string(other) != string(value) {
+			return false
+		}
+	}
+	return true
+}
+
+// update applies the function on the cached state and writes the changed keys in etcd, the update is retried on
+// the latest data store if another replica updated it meanwhile
+func (e *EtcdDB) update(fn func(tx *memTx) error) error {
+	e.updating.Lock()
+	defer e.updating.Unlock()
+	for attempt := 0; ; attempt++ {
+		cache := e.current()
+		tx := cache.state.begin()
+		if err := fn(tx); err != nil {
+			return err
+		}
+		ops, batches, err := e.txnOps(&cache, tx)
+		if err != nil {
+			return err
+		}
+		if len(ops) == 0 {
+			return nil
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), util.EtcdRequestTimeout)
+		resp, err := e.kv.Txn(ctx).
+			If(clientv3.Compare(clientv3.ModRevision(e.Prefix+etcdGenerationKey), "=", cache.genRevision)).
+			Then(ops...).Commit()
+		cancel()
+		if err != nil {
+			return fmt.Errorf("saving to the data store in etcd failed(%s)", err.Error())
+		}
+		if resp.Succeeded {
+			e.commit(&etcdCache{state: tx.memState, revision: resp.Header.Revision,
+				genRevision: resp.Header.Revision, batches: batches})
+			return nil
+		}
+		if attempt >= util.EtcdUpdateRetries {
+			return fmt.Errorf("data store in etcd is busy, update failed")
+		}
+		log.Debugf("Data store in etcd was updated by another dns server, retrying.")
+		if err = e.reload(); err != nil {
+			return err
+		}
+	}
+}
+
+// commit publishes the state written in etcd, unless the watch already applied a newer state
+func (e *EtcdDB) commit(cache *etcdCache) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.cache.state != nil && e.cache.revision >= cache.revision {
+		return
+	}
+	e.cache = *cache
+}
+
+// txnOps returns the etcd operations writing the zones, views and changes updated in the transaction and the change
+// keys after the update, the oldest change keys beyond the change log size are deleted
+func (e *EtcdDB) txnOps(cache *etcdCache, tx *memTx) ([]clientv3.Op, []uint64, error) {
+	var ops []clientv3.Op
+	zones := make([]string, 0, len(tx.written))
+	for zone := range tx.written {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	for _, zone := range zones {
+		if zoneBkt, ok := cache.state.zones[zone]; ok && sameBucket(zoneBkt, tx.zones[zone]) {
+			continue
+		}
+		value, err := encodeZone(tx.zones[zone])
+		if err != nil {
+			return nil, nil, fmt.Errorf("data store could not marshal zone json")
+		}
+		ops = append(ops, clientv3.OpPut(e.Prefix+etcdZonesKey+zone, string(value)))
+	}
+
+	var changes []Change
+	views := make(map[string]bool)
+	for _, change := range tx.changes {
+		if change.Generation <= cache.state.generation {
+			continue
+		}
+		changes = append(changes, change)
+		if change.View == nil || views[change.View.Name] {
+			continue
+		}
+		views[change.View.Name] = true
+		if value := tx.views.Get([]byte(change.View.Name)); value != nil {
+			ops = append(ops, clientv3.OpPut(e.Prefix+etcdViewsKey+change.View.Name, string(value)))
+		} else {
+			ops = append(ops, clientv3.OpDelete(e.Prefix+etcdViewsKey+change.View.Name))
+		}
+	}
+	if len(ops) == 0 && len(changes) == 0 {
+		return nil, cache.batches, nil
+	}
+
+	batches := make([]uint64, 0, len(cache.batches)+1)
+	for _, batch := range cache.batches {
+		if batch+util.MaxChangeLogEntries <= tx.generation {
+			ops = append(ops, clientv3.OpDelete(e.Prefix+etcdChangesKey+changesKey(batch)))
+			continue
+		}
+		batches = append(batches, batch)
+	}
+	if len(changes) != 0 {
+		value, err := json.Marshal(changes)
+		if err != nil {
+			return nil, nil, fmt.Errorf("data store could not marshal change json")
+		}
+		ops = append(ops, clientv3.OpPut(e.Prefix+etcdChangesKey+changesKey(tx.generation), string(value)))
+		batches = append(batches, tx.generation)
+	}
+	ops = append(ops, clientv3.OpPut(e.Prefix+etcdGenerationKey, strconv.FormatUint(tx.generation, 10)))
+	return ops, batches, nil
+}
+
+// changesKey zero pads the generation to order the change keys
+func changesKey(generation uint64) string {
+	return fmt.Sprintf("%020d", generation)
+}
+
+func (e *EtcdDB) SetResourceRecord(zone string, rr *ResourceRecord) error {
+	return e.update(func(tx *memTx) error {
+		return tx.setResourceRecord(zone, rr)
+	})
+}
+
+func (e *EtcdDB) GetResourceRecord(question *dns.Question, client net.IP) (*[]dns.RR, error) {
+	return e.current().state.getResourceRecord(question, client)
+}
+
+func (e *EtcdDB) DelResourceRecord(zone string, host string, rrtypestr string, view string) error {
+	return e.update(func(tx *memTx) error {
+		return tx.delResourceRecord(zone, host, rrtypestr, view)
+	})
+}
+
+func (e *EtcdDB) IsResourceRecordExists(zone string, rr *ResourceRecord) bool {
+	return e.current().state.isResourceRecordExists(zone, rr)
+}
+
+func (e *EtcdDB) GetZoneResourceRecord(zone string, host string, rrtypestr string, view string) (*ResourceRecord,
+	error) {
+	return e.current().state.getZoneResourceRecord(zone, host, rrtypestr, view)
+}
+
+func (e *EtcdDB) ListResourceRecords(zone string) ([]ResourceRecord, error) {
+	return e.current().state.listResourceRecords(zone)
+}
+
+func (e *EtcdDB) ListZones() ([]string, error) {
+	return e.current().state.listZones(), nil
+}
+
+func (e *EtcdDB) ImportZone(zone string, records []ResourceRecord, replace bool) error {
+	return e.update(func(tx *memTx) error {
+		return tx.importZone(zone, records, replace)
+	})
+}
+
+func (e *EtcdDB) SetZoneAuthoritative(zone string, authoritative bool) error {
+	return e.update(func(tx *memTx) error {
+		return tx.setZoneAuthoritative(zone, authoritative)
+	})
+}
+
+func (e *EtcdDB) GetAuthority(name string) (dns.RR, bool, error) {
+	soa, exists := authority(e.current().state.zoneBucket, name)
+	return soa, exists, nil
+}
+
+func (e *EtcdDB) SetView(view *View) error {
+	return e.update(func(tx *memTx) error {
+		return tx.setView(view)
+	})
+}
+
+func (e *EtcdDB) GetView(name string) (*View, error) {
+	return e.current().state.getView(name)
+}
+
+func (e *EtcdDB) ListViews() ([]View, error) {
+	return e.current().state.listViews()
+}
+
+func (e *EtcdDB) DelView(name string) error {
+	return e.update(func(tx *memTx) error {
+		return tx.delView(name)
+	})
+}
+
+func (e *EtcdDB) DelUnusedView(name string) error {
+	return e.update(func(tx *memTx) error {
+		return tx.delUnusedView(name)
+	})
+}
+
+func (e *EtcdDB) Generation() (uint64, error) {
+	return e.current().state.generation, nil
+}
+
+func (e *EtcdDB) Changes(since uint64, limit int) ([]Change, error) {
+	return e.current().state.listChanges(since, limit)
+}
@@ -0,0 +1,320 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/coreos/etcd/clientv3"
+	pb "github.com/coreos/etcd/etcdserver/etcdserverpb"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+	"github.com/stretchr/testify/assert"
+)
+
+var errNotSupported = errors.New("not supported by the fake etcd")
+
+// fakeEtcd the key value store and watches of etcd used by the etcd data store
+type fakeEtcd struct {
+	mutex    sync.Mutex
+	revision int64
+	kvs      map[string]*mvccpb.KeyValue
+	history  []*clientv3.Event
+	watches  map[*fakeWatch]bool
+	// held the events are not delivered to the watches until released
+	held    bool
+	pending [][]*clientv3.Event
+}
+
+type fakeWatch struct {
+	prefix    string
+	responses chan clientv3.WatchResponse
+}
+
+type fakeTxn struct {
+	etcd *fakeEtcd
+	cmps []clientv3.Cmp
+	ops  []clientv3.Op
+}
+
+func newFakeEtcd() *fakeEtcd {
+	return &fakeEtcd{revision: 1, kvs: make(map[string]*mvccpb.KeyValue), watches: make(map[*fakeWatch]bool)}
+}
+
+func (f *fakeEtcd) Put(context.Context, string, string, ...clientv3.OpOption) (*clientv3.PutResponse, error) {
+	return nil, errNotSupported
+}
+
+func (f *fakeEtcd) Get(_ context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	op := clientv3.OpGet(key, opts...)
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	resp := &clientv3.GetResponse{Header: &pb.ResponseHeader{Revision: f.revision}}
+	for k, kv := range f.kvs {
+		if k == key || (k > key && k < string(op.RangeBytes())) {
+			resp.Kvs = append(resp.Kvs, kv)
+		}
+	}
+	sort.Slice(resp.Kvs, func(i, j int) bool {
+		return string(resp.Kvs[i].Key) < string(resp.Kvs[j].Key)
+	})
+	return resp, nil
+}
+
+func (f *fakeEtcd) Delete(context.Context, string, ...clientv3.OpOption) (*clientv3.DeleteResponse, error) {
+	return nil, errNotSupported
+}
+
+func (f *fakeEtcd) Compact(context.Context, int64, ...clientv3.CompactOption) (*clientv3.CompactResponse, error) {
+	return nil, errNotSupported
+}
+
+func (f *fakeEtcd) Do(context.Context, clientv3.Op) (clientv3.OpResponse, error) {
+	return clientv3.OpResponse{}, errNotSupported
+}
+
+func (f *fakeEtcd) Txn(context.Context) clientv3.Txn {
+	return &fakeTxn{etcd: f}
+}
+
+func (t *fakeTxn) If(cmps ...clientv3.Cmp) clientv3.Txn {
+	t.cmps = append(t.cmps, cmps...)
+	return t
+}
+
+func (t *fakeTxn) Then(ops ...clientv3.Op) clientv3.Txn {
+	t.ops = append(t.ops, ops...)
+	return t
+}
+
+func (t *fakeTxn) Else(...clientv3.Op) clientv3.Txn {
+	return t
+}
+
+// Commit supports the modification revision comparisons and the single key puts and deletes
+func (t *fakeTxn) Commit() (*clientv3.TxnResponse, error) {
+	f := t.etcd
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, cmp := range t.cmps {
+		var modRevision int64
+		if kv, ok := f.kvs[string(cmp.KeyBytes())]; ok {
+			modRevision = kv.ModRevision
+		}
+		target, ok := cmp.TargetUnion.(*pb.Compare_ModRevision)
+		if !ok || cmp.Result != pb.Compare_EQUAL {
+			return nil, errNotSupported
+		}
+		if target.ModRevision != modRevision {
+			return &clientv3.TxnResponse{Header: &pb.ResponseHeader{Revision: f.revision}}, nil
+		}
+	}
+
+	f.revision++
+	var events []*clientv3.Event
+	for _, op := range t.ops {
+		key := string(op.KeyBytes())
+		switch {
+		case op.IsPut():
+			kv := &mvccpb.KeyValue{Key: op.KeyBytes(), Value: op.ValueBytes(), ModRevision: f.revision}
+			f.kvs[key] = kv
+			events = append(events, &clientv3.Event{Type: mvccpb.PUT, Kv: kv})
+		case op.IsDelete():
+			if _, ok := f.kvs[key]; ok {
+				delete(f.kvs, key)
+				events = append(events, &clientv3.Event{Type: mvccpb.DELETE,
+					Kv: &mvccpb.KeyValue{Key: op.KeyBytes(), ModRevision: f.revision}})
+			}
+		}
+	}
+	f.history = append(f.history, events...)
+	if f.held {
+		f.pending = append(f.pending, events)
+	} else {
+		f.publish(events)
+	}
+	return &clientv3.TxnResponse{Header: &pb.ResponseHeader{Revision: f.revision}, Succeeded: true}, nil
+}
+
+func (f *fakeEtcd) publish(events []*clientv3.Event) {
+	for watch := range f.watches {
+		f.publishTo(watch, events)
+	}
+}
+
+func (f *fakeEtcd) publishTo(watch *fakeWatch, events []*clientv3.Event) {
+	var matched []*clientv3.Event
+	for _, event := range events {
+		if strings.HasPrefix(string(event.Kv.Key), watch.prefix) {
+			matched = append(matched, event)
+		}
+	}
+	if len(matched) != 0 {
+		watch.responses <- clientv3.WatchResponse{Events: matched}
+	}
+}
+
+// Watch supports the prefix watches from a revision
+func (f *fakeEtcd) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	op := clientv3.OpGet(key, opts...)
+	watch := &fakeWatch{prefix: key, responses: make(chan clientv3.WatchResponse, 100)}
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if len(f.history) != 0 && op.Rev() < f.history[0].Kv.ModRevision {
+		watch.responses <- clientv3.WatchResponse{CompactRevision: f.history[0].Kv.ModRevision}
+		close(watch.responses)
+		return watch.responses
+	}
+	// The events of a revision are delivered in one response
+	f.watches[watch] = true
+	for i, event := range f.history {
+		if event.Kv.ModRevision >= op.Rev() && (i == 0 || f.history[i-1].Kv.ModRevision != event.Kv.ModRevision) {
+			var events []*clientv3.Event
+			for _, next := range f.history[i:] {
+				if next.Kv.ModRevision == event.Kv.ModRevision {
+					events = append(events, next)
+				}
+			}
+			f.publishTo(watch, events)
+		}
+	}
+	go func() {
+		<-ctx.Done()
+		f.mutex.Lock()
+		defer f.mutex.Unlock()
+		if f.watches[watch] {
+			delete(f.watches, watch)
+			close(watch.responses)
+		}
+	}()
+	return watch.responses
+}
+
+func (f *fakeEtcd) Close() error {
+	return nil
+}
+
+// hold stops delivering the events to the watches
+func (f *fakeEtcd) hold() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.held = true
+}
+
+// release delivers the held events
+func (f *fakeEtcd) release() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.held = false
+	for _, events := range f.pending {
+		f.publish(events)
+	}
+	f.pending = nil
+}
+
+// compact drops the history and fails the watches
+func (f *fakeEtcd) compact() {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	f.history = nil
+	for watch := range f.watches {
+		watch.responses <- clientv3.WatchResponse{CompactRevision: f.revision}
+		close(watch.responses)
+		delete(f.watches, watch)
+	}
+}
+
+func newTestEtcdDB(etcd *fakeEtcd) *EtcdDB {
+	return &EtcdDB{Prefix: "/edgegallery/dns/test/", TTL: 30, kv: etcd, watcher: etcd}
+}
+
+func TestEtcdDB(t *testing.T) {
+	t.Run("DataStore", func(t *testing.T) {
+		testDataStore(t, newTestEtcdDB(newFakeEtcd()))
+	})
+
+	etcd := newFakeEtcd()
+	replica1, replica2 := newTestEtcdDB(etcd), newTestEtcdDB(etcd)
+	assert.Nil(t, replica1.Open())
+	defer replica1.Close()
+	assert.Nil(t, replica2.Open())
+	defer replica2.Close()
+	record := func(address string) *ResourceRecord {
+		return &ResourceRecord{Name: exampleDomain, Type: "A", Class: "IN", TTL: 30, RData: []string{address}}
+	}
+	recordData := func(store DataStore) string {
+		rr, err := store.GetZoneResourceRecord(DefaultZone, exampleDomain, "A", "")
+		if err != nil {
+			return ""
+		}
+		return rr.RData[0]
+	}
+
+	t.Run("Watch", func(t *testing.T) {
+		assert.Nil(t, replica1.SetResourceRecord(DefaultZone, record("10.10.0.1")))
+		assert.Equal(t, "10.10.0.1", recordData(replica1))
+		assert.Eventually(t, func() bool {
+			return recordData(replica2) == "10.10.0.1"
+		}, 2*time.Second, 10*time.Millisecond)
+		assert.Nil(t, replica2.SetView(&View{Name: "cell", Subnets: []string{"10.100.0.0/16"}}))
+		assert.Eventually(t, func() bool {
+			_, err := replica1.GetView("cell")
+			return err == nil
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("ConcurrentUpdate", func(t *testing.T) {
+		// The update on the stale cache conflicts and is retried on the latest data
+		etcd.hold()
+		assert.Nil(t, replica1.SetResourceRecord(DefaultZone, record("10.10.0.2")))
+		assert.Nil(t, replica2.SetResourceRecord("example.com.", record("10.10.0.3")))
+		etcd.release()
+
+		assert.Equal(t, "10.10.0.2", recordData(replica2))
+		assert.Eventually(t, func() bool {
+			generation, _ := replica1.Generation()
+			return generation == 4
+		}, 2*time.Second, 10*time.Millisecond)
+		for _, replica := range []*EtcdDB{replica1, replica2} {
+			changes, err := replica.Changes(0, 10)
+			assert.Nil(t, err)
+			if assert.Equal(t, 4, len(changes)) {
+				assert.Equal(t, "example.com.", changes[3].Zone)
+			}
+			zones, err := replica.ListZones()
+			assert.Nil(t, err)
+			assert.Equal(t, []string{DefaultZone, "example.com."}, zones)
+		}
+	})
+
+	t.Run("Compaction", func(t *testing.T) {
+		// The cache is reloaded when the watched revisions are compacted
+		etcd.compact()
+		assert.Nil(t, replica1.DelResourceRecord(DefaultZone, exampleDomain, "A", ""))
+		assert.Eventually(t, func() bool {
+			return recordData(replica2) == ""
+		}, 3*time.Second, 10*time.Millisecond)
+		generation, err := replica2.Generation()
+		assert.Nil(t, err)
+		assert.Equal(t, uint64(5), generation)
+	})
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"fmt"
+	"strings"
+
+	"dns-server/util"
+)
+
+const (
+	// HealthCheckTCP probes the address with a tcp connection
+	HealthCheckTCP = "tcp"
+	// HealthCheckHTTP probes the address with a http get, the 2xx and 3xx status codes are healthy
+	HealthCheckHTTP = "http"
+)
+
+// HealthCheck active health check of the record addresses
+type HealthCheck struct {
+	Protocol string `json:"protocol"`
+	Port     uint16 `json:"port"`
+	Path     string `json:"path,omitempty"`
+	// Interval between the probes in seconds
+	Interval uint32 `json:"interval,omitempty"`
+	// Timeout of a probe in seconds
+	Timeout uint32 `json:"timeout,omitempty"`
+}
+
+// ValidateLoadBalancing validates the weights and the health check of the record, the missing interval and timeout
+// of the health check are set to the defaults
+func ValidateLoadBalancing(rr *ResourceRecord) error {
+	if len(rr.Weights) == 0 && rr.HealthCheck == nil {
+		return nil
+	}
+	if rr.Type != "A" && rr.Type != "AAAA" {
+		return fmt.Errorf("load balancing is supported only on A/AAAA records")
+	}
+	if len(rr.Weights) != 0 && len(rr.Weights) != len(rr.RData) {
+		return fmt.Errorf("weights should be given for each rData")
+	}
+	for _, weight := range rr.Weights {
+		if weight == 0 || weight > util.MaxRecordWeight {
+			return fmt.Errorf("weight not in valid range(1~%d)", util.MaxRecordWeight)
+		}
+	}
+
+	check := rr.HealthCheck
+	if check == nil {
+		return nil
+	}
+	if check.Protocol != HealthCheckTCP && check.Protocol != HealthCheckHTTP {
+		return fmt.Errorf("unsupported health check protocol(%s)", check.Protocol)
+	}
+	if check.Port == 0 {
+		return fmt.Errorf("health check port not in valid range")
+	}
+	if check.Protocol == HealthCheckHTTP && len(check.Path) == 0 {
+		check.Path = "/"
+	}
+	if len(check.Path) > util.MaxHealthCheckPathLength || (len(check.Path) != 0 && !strings.HasPrefix(check.Path, "/")) {
+		return fmt.Errorf("invalid health check path")
+	}
+	if check.Interval == 0 {
+		check.Interval = util.DefaultHealthCheckInterval
+	}
+	if check.Timeout == 0 {
+		check.Timeout = util.DefaultHealthCheckTimeout
+	}
+	if check.Interval > util.MaxHealthCheckInterval || check.Timeout > check.Interval {
+		return fmt.Errorf("health check interval(1~%d) or timeout not in valid range", util.MaxHealthCheckInterval)
+	}
+	return nil
+}
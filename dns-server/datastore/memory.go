@@ -0,0 +1,624 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/util"
+)
+
+// MemDB the data store keeping the zones, views and changes in memory, for the tests and the ephemeral deployments,
+// the data is lost on restart
+type MemDB struct {
+	TTL   uint32
+	mutex sync.Mutex
+	state *memState
+}
+
+// memBucket the records of a zone or the views in the json encoding of the bolt buckets
+type memBucket map[string][]byte
+
+func (m memBucket) Get(key []byte) []byte {
+	return m[string(key)]
+}
+
+// ForEach calls the function on the entries in the key order like the bolt buckets
+func (m memBucket) ForEach(fn func(key, value []byte) error) error {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if err := fn([]byte(key), m[key]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m memBucket) clone() memBucket {
+	bkt := make(memBucket, len(m))
+	for key, value := range m {
+		bkt[key] = value
+	}
+	return bkt
+}
+
+// memState the zones, views and changes of the data store, a published state is never modified so that it is read
+// without lock and the updates work on a copy
+type memState struct {
+	zones      map[string]memBucket
+	views      memBucket
+	generation uint64
+	changes    []Change
+}
+
+func newMemState() *memState {
+	return &memState{zones: make(map[string]memBucket), views: make(memBucket)}
+}
+
+// memTx an update of the state, the zone buckets are copied on the first write
+type memTx struct {
+	*memState
+	written map[string]bool
+}
+
+// begin starts an update on the copy of the state
+func (s *memState) begin() *memTx {
+	state := &memState{zones: make(map[string]memBucket, len(s.zones)), views: s.views.clone(),
+		generation: s.generation, changes: s.changes[:len(s.changes):len(s.changes)]}
+	for zone, zoneBkt := range s.zones {
+		state.zones[zone] = zoneBkt
+	}
+	return &memTx{memState: state, written: make(map[string]bool)}
+}
+
+func (s *memState) zoneBucket(zone string) recordBucket {
+	if zoneBkt, ok := s.zones[zone]; ok {
+		return zoneBkt
+	}
+	return nil
+}
+
+// writableZone returns the bucket of the zone to write, the zone is created if not exists
+func (tx *memTx) writableZone(zone string) memBucket {
+	if !tx.written[zone] {
+		tx.zones[zone] = tx.zones[zone].clone()
+		tx.written[zone] = true
+	}
+	return tx.zones[zone]
+}
+
+// logChange assigns the next generation to the change and appends it to the changes, the oldest changes beyond the
+// change log size are dropped
+func (tx *memTx) logChange(change *Change) {
+	tx.generation++
+	change.Generation = tx.generation
+	tx.changes = append(tx.changes, *change)
+	if len(tx.changes) > util.MaxChangeLogEntries {
+		tx.changes = tx.changes[len(tx.changes)-util.MaxChangeLogEntries:]
+	}
+}
+
+// putRecord stores the record and logs the change
+func (tx *memTx) putRecord(zone string, keyBytes []byte, value *DNSConfigRRValue) error {
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("data store could not marshal dns config json")
+	}
+	rr, err := toResourceRecord(keyBytes, valueBytes)
+	if err != nil {
+		return err
+	}
+	tx.writableZone(zone)[string(keyBytes)] = valueBytes
+	tx.logChange(&Change{Zone: zone, Record: rr})
+	return nil
+}
+
+// deleteRecord deletes the record if exists and logs the change
+func (tx *memTx) deleteRecord(zone string, keyBytes []byte) error {
+	if zoneBkt := tx.zoneBucket(zone); zoneBkt == nil || zoneBkt.Get(keyBytes) == nil {
+		return nil
+	}
+	dnsCfgKey := &DNSConfigRRKey{}
+	if err := json.Unmarshal(keyBytes, dnsCfgKey); err != nil {
+		return fmt.Errorf("parsing failed on data retrieval")
+	}
+	delete(tx.writableZone(zone), string(keyBytes))
+	tx.logChange(&Change{Zone: zone, Record: &ResourceRecord{Name: dnsCfgKey.Host,
+		Type: dns.TypeToString[dnsCfgKey.RRType], View: dnsCfgKey.View}, Deleted: true})
+	return nil
+}
+
+func (m *MemDB) Open() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.state == nil {
+		m.state = newMemState()
+	}
+	if _, ok := m.state.zones[DefaultZone]; !ok {
+		tx := m.state.begin()
+		tx.writableZone(DefaultZone)
+		m.state = tx.memState
+	}
+	log.Debugf("Initialize memory db success.")
+	return nil
+}
+
+func (m *MemDB) Close() error {
+	log.Debugf("Closed memory db as part of shutdown service.")
+	return nil
+}
+
+// current returns the published state
+func (m *MemDB) current() *memState {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.state == nil {
+		return newMemState()
+	}
+	return m.state
+}
+
+// update applies the function on a copy of the state and publishes the copy if the function succeeds
+func (m *MemDB) update(fn func(tx *memTx) error) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.state == nil {
+		m.state = newMemState()
+	}
+	tx := m.state.begin()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	m.state = tx.memState
+	return nil
+}
+
+func (m *MemDB) SetResourceRecord(zone string, rr *ResourceRecord) error {
+	return m.update(func(tx *memTx) error {
+		return tx.setResourceRecord(zone, rr)
+	})
+}
+
+func (tx *memTx) setResourceRecord(zone string, rr *ResourceRecord) error {
+	rrType, ok := rrTypeMap[rr.Type]
+	if !ok {
+		return fmt.Errorf("unsupported rrtype(%s) entry", rr.Type)
+	}
+	if rr.TTL == 0 {
+		log.Error("DNS TTL value 0 is not supported.", nil)
+		return fmt.Errorf("unsupported/missing ttl value")
+	}
+
+	host := strings.ToLower(rr.Name)
+	confKeyBytes, err := json.Marshal(DNSConfigRRKey{Host: host, RRType: rrType, View: rr.View})
+	if err != nil {
+		return fmt.Errorf("internal error, could not parse dns config json")
+	}
+	if len(rr.View) != 0 && tx.views.Get([]byte(rr.View)) == nil {
+		return fmt.Errorf("view(%s) not found", rr.View)
+	}
+	zoneBkt := tx.writableZone(zone)
+	if hasCNAMEConflict(zoneBkt, host, rrType, rr.View) {
+		return fmt.Errorf("cname record can not coexist with other records of %s", host)
+	}
+	dnsCfgValue, err := setOrCreateDBEntryGeneration(zoneBkt.Get(confKeyBytes), rr)
+	if err != nil {
+		return err
+	}
+	return tx.putRecord(zone, confKeyBytes, dnsCfgValue)
+}
+
+func (m *MemDB) GetResourceRecord(question *dns.Question, client net.IP) (*[]dns.RR, error) {
+	return m.current().getResourceRecord(question, client)
+}
+
+func (s *memState) getResourceRecord(question *dns.Question, client net.IP) (*[]dns.RR, error) {
+	records, err := resolve(s.zoneBucket, matchViews(s.views, client), question)
+	if err != nil {
+		return nil, fmt.Errorf("reading dns entry from data store failed")
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("could not process/retrieve the query")
+	}
+	return &records, nil
+}
+
+func (m *MemDB) DelResourceRecord(zone string, host string, rrtypestr string, view string) error {
+	return m.update(func(tx *memTx) error {
+		return tx.delResourceRecord(zone, host, rrtypestr, view)
+	})
+}
+
+// findRecordZone returns the first zone having the record in the zone order, empty if not found
+func (s *memState) findRecordZone(keyBytes []byte) string {
+	zones := s.listZones()
+	for _, zone := range zones {
+		if s.zones[zone].Get(keyBytes) != nil {
+			return zone
+		}
+	}
+	return ""
+}
+
+func (tx *memTx) delResourceRecord(zone string, host string, rrtypestr string, view string) error {
+	rrType, ok := rrTypeMap[rrtypestr]
+	if !ok {
+		return fmt.Errorf("unsupported rrtype(%s) entry", rrtypestr)
+	}
+	dnsCfgKeyBytes, err := json.Marshal(&DNSConfigRRKey{Host: strings.ToLower(host), RRType: rrType, View: view})
+	if err != nil {
+		return fmt.Errorf("failed to parse input request")
+	}
+	recordZone := tx.findRecordZone(dnsCfgKeyBytes)
+	if len(recordZone) == 0 {
+		return fmt.Errorf("not found for the zone %v", zone)
+	}
+	if err = tx.deleteRecord(recordZone, dnsCfgKeyBytes); err != nil {
+		return fmt.Errorf("failed to delete dns entry")
+	}
+	return nil
+}
+
+func (m *MemDB) IsResourceRecordExists(zone string, rr *ResourceRecord) bool {
+	return m.current().isResourceRecordExists(zone, rr)
+}
+
+func (s *memState) isResourceRecordExists(zone string, rr *ResourceRecord) bool {
+	rrType, ok := rrTypeMap[rr.Type]
+	if !ok {
+		log.Error("Unsupported rrtype entry", nil)
+		return false
+	}
+	if rr.TTL == 0 {
+		log.Error("DNS TTL value 0 is not supported.", nil)
+		return false
+	}
+	confKeyBytes, err := json.Marshal(DNSConfigRRKey{Host: strings.ToLower(rr.Name), RRType: rrType, View: rr.View})
+	if err != nil {
+		log.Error("Internal error, could not parse dns config json")
+		return false
+	}
+	if len(s.findRecordZone(confKeyBytes)) == 0 {
+		log.Infof("Record not found for the zone %s", zone)
+		return false
+	}
+	return true
+}
+
+func (m *MemDB) GetZoneResourceRecord(zone string, host string, rrtypestr string, view string) (*ResourceRecord,
+	error) {
+	return m.current().getZoneResourceRecord(zone, host, rrtypestr, view)
+}
+
+func (s *memState) getZoneResourceRecord(zone string, host string, rrtypestr string, view string) (*ResourceRecord,
+	error) {
+	rrType, ok := rrTypeMap[rrtypestr]
+	if !ok {
+		return nil, fmt.Errorf("unsupported rrtype(%s) entry", rrtypestr)
+	}
+	dnsCfgKeyBytes, err := json.Marshal(&DNSConfigRRKey{Host: strings.ToLower(host), RRType: rrType, View: view})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse input request")
+	}
+	zoneBkt, ok := s.zones[zone]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	valueBytes := zoneBkt.Get(dnsCfgKeyBytes)
+	if valueBytes == nil {
+		return nil, ErrNotFound
+	}
+	return toResourceRecord(dnsCfgKeyBytes, valueBytes)
+}
+
+func (m *MemDB) ListResourceRecords(zone string) ([]ResourceRecord, error) {
+	return m.current().listResourceRecords(zone)
+}
+
+func (s *memState) listResourceRecords(zone string) ([]ResourceRecord, error) {
+	zoneBkt, ok := s.zones[zone]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	records := make([]ResourceRecord, 0, len(zoneBkt))
+	_ = zoneBkt.ForEach(func(key, value []byte) error {
+		rr, err := toResourceRecord(key, value)
+		if err != nil {
+			log.Errorf("Skipped the invalid record in the zone %s.", zone)
+			return nil
+		}
+		records = append(records, *rr)
+		return nil
+	})
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Name != records[j].Name {
+			return records[i].Name < records[j].Name
+		}
+		return records[i].Type < records[j].Type
+	})
+	return records, nil
+}
+
+func (m *MemDB) ListZones() ([]string, error) {
+	return m.current().listZones(), nil
+}
+
+func (s *memState) listZones() []string {
+	zones := make([]string, 0, len(s.zones))
+	for zone := range s.zones {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	return zones
+}
+
+func (m *MemDB) ImportZone(zone string, records []ResourceRecord, replace bool) error {
+	return m.update(func(tx *memTx) error {
+		return tx.importZone(zone, records, replace)
+	})
+}
+
+func (tx *memTx) importZone(zone string, records []ResourceRecord, replace bool) error {
+	zone = dns.Fqdn(strings.ToLower(zone))
+	zoneBkt := tx.writableZone(zone)
+
+	// The records of the replaced zone missing in the import are deleted
+	keys := make(map[string]bool, len(records))
+	for i := range records {
+		rr := &records[i]
+		rrType, ok := rrTypeMap[rr.Type]
+		if !ok {
+			return fmt.Errorf("unsupported rrtype(%s) entry", rr.Type)
+		}
+		confKeyBytes, err := json.Marshal(DNSConfigRRKey{Host: strings.ToLower(rr.Name), RRType: rrType,
+			View: rr.View})
+		if err != nil {
+			return fmt.Errorf("internal error, could not parse dns config json")
+		}
+		keys[string(confKeyBytes)] = true
+	}
+	if replace {
+		var deleted [][]byte
+		_ = zoneBkt.ForEach(func(key, _ []byte) error {
+			if !keys[string(key)] {
+				deleted = append(deleted, key)
+			}
+			return nil
+		})
+		for _, key := range deleted {
+			if err := tx.deleteRecord(zone, key); err != nil {
+				return fmt.Errorf("zone(%s) replace failed", zone)
+			}
+		}
+	}
+
+	for i := range records {
+		rr := &records[i]
+		rrType := rrTypeMap[rr.Type]
+		if rr.TTL == 0 {
+			return fmt.Errorf("unsupported/missing ttl value")
+		}
+		if len(rr.View) != 0 && tx.views.Get([]byte(rr.View)) == nil {
+			return fmt.Errorf("view(%s) not found", rr.View)
+		}
+		host := strings.ToLower(rr.Name)
+		if hasCNAMEConflict(zoneBkt, host, rrType, rr.View) {
+			return fmt.Errorf("cname record can not coexist with other records of %s", host)
+		}
+		confKeyBytes, err := json.Marshal(DNSConfigRRKey{Host: host, RRType: rrType, View: rr.View})
+		if err != nil {
+			return fmt.Errorf("internal error, could not parse dns config json")
+		}
+		// Merge replaces the records of the same name and type
+		dnsCfgValue, err := setOrCreateDBEntryGeneration(nil, rr)
+		if err != nil {
+			return err
+		}
+		if err = tx.putRecord(zone, confKeyBytes, dnsCfgValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemDB) SetZoneAuthoritative(zone string, authoritative bool) error {
+	return m.update(func(tx *memTx) error {
+		return tx.setZoneAuthoritative(zone, authoritative)
+	})
+}
+
+func (tx *memTx) setZoneAuthoritative(zone string, authoritative bool) error {
+	zone = dns.Fqdn(strings.ToLower(zone))
+	if zone == DefaultZone {
+		return fmt.Errorf("default zone can not be authoritative")
+	}
+	soaKeyBytes, _ := json.Marshal(DNSConfigRRKey{Host: zone, RRType: dns.TypeSOA})
+	nsKeyBytes, _ := json.Marshal(DNSConfigRRKey{Host: zone, RRType: dns.TypeNS})
+
+	zoneBkt := tx.writableZone(zone)
+	if !authoritative {
+		return tx.deleteRecord(zone, soaKeyBytes)
+	}
+
+	// Generate the SOA and NS records if not available
+	soa := NewSOA(zone, uint32(time.Now().Unix()))
+	generated := []struct {
+		key []byte
+		rr  dns.RR
+	}{{soaKeyBytes, soa}, {nsKeyBytes, &dns.NS{Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeNS,
+		Class: dns.ClassINET, Ttl: util.DefaultTTL}, Ns: soa.Ns}}}
+	for _, record := range generated {
+		if zoneBkt.Get(record.key) != nil {
+			continue
+		}
+		err := tx.putRecord(zone, record.key, &DNSConfigRRValue{RRClass: dns.ClassINET, TTL: record.rr.Header().Ttl,
+			PointTo: []string{recordData(record.rr)}})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemDB) GetAuthority(name string) (dns.RR, bool, error) {
+	soa, exists := authority(m.current().zoneBucket, name)
+	return soa, exists, nil
+}
+
+func (m *MemDB) SetView(view *View) error {
+	return m.update(func(tx *memTx) error {
+		return tx.setView(view)
+	})
+}
+
+func (tx *memTx) setView(view *View) error {
+	if err := ValidateView(view); err != nil {
+		return err
+	}
+	valueBytes, err := json.Marshal(view)
+	if err != nil {
+		return fmt.Errorf("data store could not marshal view json")
+	}
+	tx.views[view.Name] = valueBytes
+	tx.logChange(&Change{View: view})
+	return nil
+}
+
+func (m *MemDB) GetView(name string) (*View, error) {
+	return m.current().getView(name)
+}
+
+func (s *memState) getView(name string) (*View, error) {
+	valueBytes := s.views.Get([]byte(name))
+	if valueBytes == nil {
+		return nil, ErrNotFound
+	}
+	view := &View{}
+	if err := json.Unmarshal(valueBytes, view); err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+func (m *MemDB) ListViews() ([]View, error) {
+	return m.current().listViews()
+}
+
+func (s *memState) listViews() ([]View, error) {
+	views := make([]View, 0, len(s.views))
+	err := s.views.ForEach(func(_, valueBytes []byte) error {
+		view := View{}
+		if err := json.Unmarshal(valueBytes, &view); err != nil {
+			return err
+		}
+		views = append(views, view)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading views from data store failed")
+	}
+	return views, nil
+}
+
+func (m *MemDB) DelView(name string) error {
+	return m.update(func(tx *memTx) error {
+		return tx.delView(name)
+	})
+}
+
+func (tx *memTx) delView(name string) error {
+	if tx.views.Get([]byte(name)) == nil {
+		return ErrNotFound
+	}
+	// The records of the view are never answered without the view
+	for _, zone := range tx.listZones() {
+		var viewKeys [][]byte
+		_ = tx.zones[zone].ForEach(func(key, _ []byte) error {
+			dnsCfgKey := &DNSConfigRRKey{}
+			if json.Unmarshal(key, dnsCfgKey) == nil && dnsCfgKey.View == name {
+				viewKeys = append(viewKeys, key)
+			}
+			return nil
+		})
+		for _, key := range viewKeys {
+			if err := tx.deleteRecord(zone, key); err != nil {
+				return fmt.Errorf("deleting the records of the view(%s) failed", name)
+			}
+		}
+	}
+	delete(tx.views, name)
+	tx.logChange(&Change{View: &View{Name: name}, Deleted: true})
+	return nil
+}
+
+func (m *MemDB) DelUnusedView(name string) error {
+	return m.update(func(tx *memTx) error {
+		return tx.delUnusedView(name)
+	})
+}
+
+func (tx *memTx) delUnusedView(name string) error {
+	if tx.views.Get([]byte(name)) == nil {
+		return ErrNotFound
+	}
+	for _, zone := range tx.listZones() {
+		if viewInUse(tx.zones[zone], name) {
+			return ErrViewInUse
+		}
+	}
+	delete(tx.views, name)
+	tx.logChange(&Change{View: &View{Name: name}, Deleted: true})
+	return nil
+}
+
+func (m *MemDB) Generation() (uint64, error) {
+	return m.current().generation, nil
+}
+
+func (m *MemDB) Changes(since uint64, limit int) ([]Change, error) {
+	return m.current().listChanges(since, limit)
+}
+
+func (s *memState) listChanges(since uint64, limit int) ([]Change, error) {
+	changes := make([]Change, 0)
+	if since >= s.generation {
+		return changes, nil
+	}
+	if len(s.changes) == 0 || since+1 < s.changes[0].Generation {
+		return nil, ErrChangeLogTruncated
+	}
+	for _, change := range s.changes[since+1-s.changes[0].Generation:] {
+		if len(changes) >= limit {
+			break
+		}
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
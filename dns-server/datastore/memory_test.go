@@ -0,0 +1,188 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+// testDataStore checks the behaviour every data store shares with the bolt data store
+func testDataStore(t *testing.T, store DataStore) {
+	assert.Nil(t, store.Open())
+	defer store.Close()
+
+	zones, err := store.ListZones()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{DefaultZone}, zones)
+
+	// Records of the views are preferred and the cname chain is followed
+	assert.NotNil(t, store.SetResourceRecord(DefaultZone, &ResourceRecord{Name: exampleDomain, Type: "A",
+		Class: "IN", TTL: 30, RData: []string{"10.10.0.2"}, View: "cell"}))
+	assert.Nil(t, store.SetView(&View{Name: "cell", Subnets: []string{"10.100.0.0/16"}}))
+	assert.Nil(t, store.SetResourceRecord(DefaultZone, &ResourceRecord{Name: exampleDomain, Type: "A",
+		Class: "IN", TTL: 30, RData: []string{"10.10.0.1"}}))
+	assert.Nil(t, store.SetResourceRecord(DefaultZone, &ResourceRecord{Name: exampleDomain, Type: "A",
+		Class: "IN", TTL: 30, RData: []string{"10.10.0.2"}, View: "cell"}))
+	assert.Nil(t, store.SetResourceRecord(DefaultZone, &ResourceRecord{Name: exampleAbcDomain, Type: "CNAME",
+		Class: "IN", TTL: 30, RData: []string{exampleDomain}}))
+	assert.NotNil(t, store.SetResourceRecord(DefaultZone, &ResourceRecord{Name: exampleAbcDomain, Type: "A",
+		Class: "IN", TTL: 30, RData: []string{"10.10.0.3"}}))
+
+	question := &dns.Question{Name: exampleAbcDomain, Qtype: dns.TypeA, Qclass: dns.ClassINET}
+	records, err := store.GetResourceRecord(question, net.ParseIP("10.100.1.1"))
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(*records)) {
+		assert.Equal(t, exampleDomain, (*records)[0].(*dns.CNAME).Target)
+		assert.Equal(t, "10.10.0.2", (*records)[1].(*dns.A).A.String())
+	}
+	records, err = store.GetResourceRecord(question, net.ParseIP("192.168.1.1"))
+	if assert.Nil(t, err) && assert.Equal(t, 2, len(*records)) {
+		assert.Equal(t, "10.10.0.1", (*records)[1].(*dns.A).A.String())
+	}
+	_, err = store.GetResourceRecord(&dns.Question{Name: example1Domain, Qtype: dns.TypeA,
+		Qclass: dns.ClassINET}, nil)
+	assert.NotNil(t, err)
+
+	// A failed import leaves the zone unchanged
+	record := func(name string, ttl uint32) ResourceRecord {
+		return ResourceRecord{Name: name, Type: "A", Class: "IN", TTL: ttl, RData: []string{"10.20.0.1"}}
+	}
+	assert.Nil(t, store.ImportZone("example.org", []ResourceRecord{record("a.example.org.", 30),
+		record("b.example.org.", 30)}, false))
+	assert.NotNil(t, store.ImportZone("example.org.", []ResourceRecord{record("a.example.org.", 30),
+		record("c.example.org.", 0)}, true))
+	zoneRecords, err := store.ListResourceRecords("example.org.")
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(zoneRecords))
+	assert.Nil(t, store.ImportZone("example.org.", []ResourceRecord{record("a.example.org.", 60)}, true))
+	zoneRecords, err = store.ListResourceRecords("example.org.")
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(zoneRecords)) {
+		assert.Equal(t, uint32(60), zoneRecords[0].TTL)
+	}
+	_, err = store.ListResourceRecords("example.net.")
+	assert.Equal(t, ErrNotFound, err)
+
+	rr, err := store.GetZoneResourceRecord("example.org.", "A.example.org.", "A", "")
+	if assert.Nil(t, err) {
+		assert.Equal(t, []string{"10.20.0.1"}, rr.RData)
+	}
+	_, err = store.GetZoneResourceRecord("example.org.", "b.example.org.", "A", "")
+	assert.Equal(t, ErrNotFound, err)
+	_, err = store.GetZoneResourceRecord("example.net.", "a.example.net.", "A", "")
+	assert.Equal(t, ErrNotFound, err)
+	existing, missing := record("a.example.org.", 30), record("b.example.org.", 30)
+	assert.True(t, store.IsResourceRecordExists("example.org.", &existing))
+	assert.False(t, store.IsResourceRecordExists("example.org.", &missing))
+
+	assert.NotNil(t, store.SetZoneAuthoritative(DefaultZone, true))
+	assert.Nil(t, store.SetZoneAuthoritative("example.org.", true))
+	soa, exists, err := store.GetAuthority("a.example.org.")
+	assert.Nil(t, err)
+	assert.NotNil(t, soa)
+	assert.True(t, exists)
+	soa, exists, err = store.GetAuthority("c.example.org.")
+	assert.Nil(t, err)
+	assert.NotNil(t, soa)
+	assert.False(t, exists)
+	soa, _, err = store.GetAuthority(exampleDomain)
+	assert.Nil(t, err)
+	assert.Nil(t, soa)
+
+	// Deleting the view deletes its records
+	assert.Equal(t, ErrViewInUse, store.DelUnusedView("cell"))
+	assert.Nil(t, store.DelView("cell"))
+	assert.Equal(t, ErrNotFound, store.DelView("cell"))
+	_, err = store.GetZoneResourceRecord(DefaultZone, exampleDomain, "A", "cell")
+	assert.Equal(t, ErrNotFound, err)
+	views, err := store.ListViews()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(views))
+	assert.Nil(t, store.DelResourceRecord(DefaultZone, exampleAbcDomain, "CNAME", ""))
+	assert.NotNil(t, store.DelResourceRecord(DefaultZone, exampleAbcDomain, "CNAME", ""))
+
+	zones, err = store.ListZones()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{DefaultZone, "example.org."}, zones)
+
+	// View, 3 records, 2 imported, 1 replaced and 1 deleted, SOA and NS, view record and view, cname
+	generation, err := store.Generation()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(13), generation)
+	changes, err := store.Changes(0, 100)
+	assert.Nil(t, err)
+	if assert.Equal(t, 13, len(changes)) {
+		for i := range changes {
+			assert.Equal(t, uint64(i+1), changes[i].Generation)
+		}
+		assert.Equal(t, "cell", changes[0].View.Name)
+		assert.True(t, changes[12].Deleted)
+		assert.Equal(t, exampleAbcDomain, changes[12].Record.Name)
+	}
+	changes, err = store.Changes(13, 100)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(changes))
+}
+
+func TestDataStores(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(DBPath)
+	}()
+
+	t.Run("BoltDB", func(t *testing.T) {
+		testDataStore(t, &BoltDB{FileName: "testdatastoredb", TTL: 30})
+	})
+	t.Run("MemDB", func(t *testing.T) {
+		testDataStore(t, &MemDB{TTL: 30})
+	})
+}
+
+func TestMemDBChangeLog(t *testing.T) {
+	store := &MemDB{TTL: 30}
+	assert.Nil(t, store.Open())
+	defer store.Close()
+
+	record := &ResourceRecord{Name: exampleDomain, Type: "A", Class: "IN", TTL: 30, RData: []string{"10.10.0.1"}}
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, store.SetResourceRecord(DefaultZone, record))
+	}
+	changes, err := store.Changes(3, 1)
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(changes)) {
+		assert.Equal(t, uint64(4), changes[0].Generation)
+	}
+
+	// The published state is not changed by the later updates
+	state := store.current()
+	assert.Nil(t, store.DelResourceRecord(DefaultZone, exampleDomain, "A", ""))
+	_, err = state.getZoneResourceRecord(DefaultZone, exampleDomain, "A", "")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(5), state.generation)
+
+	// The changes dropped from the change log require a snapshot
+	state = store.current()
+	state.changes = state.changes[2:]
+	_, err = state.listChanges(1, 10)
+	assert.Equal(t, ErrChangeLogTruncated, err)
+	changes, err = state.listChanges(2, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(changes))
+}
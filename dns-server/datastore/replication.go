@@ -0,0 +1,195 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/miekg/dns"
+	bolt "go.etcd.io/bbolt"
+
+	"dns-server/util"
+)
+
+const (
+	// MetaConfig Meta data constant.
+	MetaConfig = "meta"
+	// ChangeLogConfig Change log constant.
+	ChangeLogConfig = "changelog"
+	// generationKey key of the generation of the last change in the meta bucket
+	generationKey = "generation"
+)
+
+// ErrChangeLogTruncated the changes since the generation are no longer in the change log, a snapshot is required
+var ErrChangeLogTruncated = errors.New("change log truncated")
+
+// Change a change of a record or a view, the deleted records and views have only their keys
+type Change struct {
+	Generation uint64          `json:"generation"`
+	Zone       string          `json:"zone,omitempty"`
+	Record     *ResourceRecord `json:"record,omitempty"`
+	View       *View           `json:"view,omitempty"`
+	Deleted    bool            `json:"deleted,omitempty"`
+}
+
+// Snapshot the records and views of the data store, the changes after the generation may be included
+type Snapshot struct {
+	Generation uint64      `json:"generation"`
+	Views      []View      `json:"views"`
+	Zones      []ZoneEntry `json:"zones"`
+}
+
+// TakeSnapshot reads the records and views of the data store, the generation is read first so that replaying the
+// changes after the generation on the snapshot gives the latest data
+func TakeSnapshot(store DataStore) (*Snapshot, error) {
+	generation, err := store.Generation()
+	if err != nil {
+		return nil, err
+	}
+	views, err := store.ListViews()
+	if err != nil {
+		return nil, err
+	}
+	zones, err := store.ListZones()
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{Generation: generation, Views: views, Zones: make([]ZoneEntry, 0, len(zones))}
+	for _, zone := range zones {
+		records, err := store.ListResourceRecords(zone)
+		if err == ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Zones = append(snapshot.Zones, ZoneEntry{Zone: zone, RR: &records})
+	}
+	return snapshot, nil
+}
+
+func generationBytes(generation uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, generation)
+	return key
+}
+
+func readGeneration(tx *bolt.Tx) uint64 {
+	value := tx.Bucket([]byte(MetaConfig)).Get([]byte(generationKey))
+	if len(value) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(value)
+}
+
+// logChange assigns the next generation to the change and appends it to the change log, the oldest changes beyond
+// the change log size are dropped
+func logChange(tx *bolt.Tx, change *Change) error {
+	change.Generation = readGeneration(tx) + 1
+	if err := tx.Bucket([]byte(MetaConfig)).Put([]byte(generationKey), generationBytes(change.Generation)); err != nil {
+		return fmt.Errorf("saving generation to data store failed")
+	}
+	changeBytes, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("data store could not marshal change json")
+	}
+	logBkt := tx.Bucket([]byte(ChangeLogConfig))
+	if err = logBkt.Put(generationBytes(change.Generation), changeBytes); err != nil {
+		return fmt.Errorf("saving change to data store failed")
+	}
+	if change.Generation <= util.MaxChangeLogEntries {
+		return nil
+	}
+	oldest := generationBytes(change.Generation - util.MaxChangeLogEntries)
+	cursor := logBkt.Cursor()
+	for key, _ := cursor.First(); key != nil && string(key) <= string(oldest); key, _ = cursor.First() {
+		if err = cursor.Delete(); err != nil {
+			return fmt.Errorf("trimming change log failed")
+		}
+	}
+	return nil
+}
+
+// putRecord stores the record and logs the change
+func putRecord(tx *bolt.Tx, zone string, zoneBkt *bolt.Bucket, keyBytes []byte, value *DNSConfigRRValue) error {
+	valueBytes, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("data store could not marshal dns config json")
+	}
+	if err = zoneBkt.Put(keyBytes, valueBytes); err != nil {
+		return fmt.Errorf("saving dns entry to data store failed")
+	}
+	rr, err := toResourceRecord(keyBytes, valueBytes)
+	if err != nil {
+		return err
+	}
+	return logChange(tx, &Change{Zone: zone, Record: rr})
+}
+
+// deleteRecord deletes the record if exists and logs the change
+func deleteRecord(tx *bolt.Tx, zone string, zoneBkt *bolt.Bucket, keyBytes []byte) error {
+	if zoneBkt.Get(keyBytes) == nil {
+		return nil
+	}
+	dnsCfgKey := &DNSConfigRRKey{}
+	if err := json.Unmarshal(keyBytes, dnsCfgKey); err != nil {
+		return fmt.Errorf("parsing failed on data retrieval")
+	}
+	if err := zoneBkt.Delete(keyBytes); err != nil {
+		return fmt.Errorf("deleting dns entry from data store failed")
+	}
+	return logChange(tx, &Change{Zone: zone, Record: &ResourceRecord{Name: dnsCfgKey.Host,
+		Type: dns.TypeToString[dnsCfgKey.RRType], View: dnsCfgKey.View}, Deleted: true})
+}
+
+func (b *BoltDB) Generation() (uint64, error) {
+	var generation uint64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		generation = readGeneration(tx)
+		return nil
+	})
+	return generation, err
+}
+
+func (b *BoltDB) Changes(since uint64, limit int) ([]Change, error) {
+	changes := make([]Change, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		if since >= readGeneration(tx) {
+			return nil
+		}
+		cursor := tx.Bucket([]byte(ChangeLogConfig)).Cursor()
+		key, value := cursor.Seek(generationBytes(since + 1))
+		if key == nil || binary.BigEndian.Uint64(key) != since+1 {
+			return ErrChangeLogTruncated
+		}
+		for ; key != nil && len(changes) < limit; key, value = cursor.Next() {
+			change := Change{}
+			if err := json.Unmarshal(value, &change); err != nil {
+				return fmt.Errorf("parsing failed on data retrieval")
+			}
+			changes = append(changes, change)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestChangeLog(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(DBPath)
+	}()
+
+	store := &BoltDB{FileName: "testchangelogdb", TTL: 30}
+	assert.Nil(t, store.Open())
+	defer store.Close()
+
+	generation, err := store.Generation()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(0), generation)
+
+	assert.Nil(t, store.SetView(&View{Name: "cell", Subnets: []string{"10.100.0.0/16"}}))
+	assert.Nil(t, store.SetResourceRecord("example.com.", &ResourceRecord{Name: exampleDomain, Type: "A",
+		Class: "IN", TTL: 30, RData: []string{"10.10.0.1"}}))
+	assert.Nil(t, store.SetResourceRecord("example.com.", &ResourceRecord{Name: exampleDomain, Type: "A",
+		Class: "IN", TTL: 30, RData: []string{"10.10.0.2"}, View: "cell"}))
+	assert.Nil(t, store.DelResourceRecord("example.com.", exampleDomain, "A", ""))
+	assert.Nil(t, store.DelView("cell"))
+
+	generation, err = store.Generation()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(6), generation)
+
+	changes, err := store.Changes(0, 10)
+	assert.Nil(t, err)
+	if assert.Equal(t, 6, len(changes)) {
+		assert.Equal(t, "cell", changes[0].View.Name)
+		assert.Equal(t, []string{"10.10.0.1"}, changes[1].Record.RData)
+		assert.Equal(t, "example.com.", changes[1].Zone)
+		assert.Equal(t, "cell", changes[2].Record.View)
+		// The record of the view is deleted with the view
+		assert.True(t, changes[3].Deleted)
+		assert.Equal(t, "", changes[3].Record.View)
+		assert.True(t, changes[4].Deleted)
+		assert.Equal(t, "cell", changes[4].Record.View)
+		assert.True(t, changes[5].Deleted)
+		assert.Equal(t, "cell", changes[5].View.Name)
+		for i := range changes {
+			assert.Equal(t, uint64(i+1), changes[i].Generation)
+		}
+	}
+
+	changes, err = store.Changes(4, 1)
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(changes)) {
+		assert.Equal(t, uint64(5), changes[0].Generation)
+	}
+	changes, err = store.Changes(6, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(changes))
+
+	// The changes dropped from the change log require a snapshot
+	assert.Nil(t, store.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(ChangeLogConfig)).Delete(generationBytes(1))
+	}))
+	_, err = store.Changes(0, 10)
+	assert.Equal(t, ErrChangeLogTruncated, err)
+	_, err = store.Changes(1, 10)
+	assert.Nil(t, err)
+}
+
+func TestTakeSnapshot(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(DBPath)
+	}()
+
+	store := &BoltDB{FileName: "testsnapshotdb", TTL: 30}
+	assert.Nil(t, store.Open())
+	defer store.Close()
+
+	assert.Nil(t, store.SetView(&View{Name: "cell", Subnets: []string{"10.100.0.0/16"}}))
+	assert.Nil(t, store.SetResourceRecord("example.com.", &ResourceRecord{Name: exampleDomain, Type: "A",
+		Class: "IN", TTL: 30, RData: []string{"10.10.0.1"}, View: "cell"}))
+
+	snapshot, err := TakeSnapshot(store)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), snapshot.Generation)
+	assert.Equal(t, []View{{Name: "cell", Subnets: []string{"10.100.0.0/16"}}}, snapshot.Views)
+	// The default zone and the example.com zone
+	assert.Equal(t, 2, len(snapshot.Zones))
+
+	// The snapshot replaces the zone of another store with the view records
+	replica := &BoltDB{FileName: "testreplicadb", TTL: 30}
+	assert.Nil(t, replica.Open())
+	defer replica.Close()
+	assert.Nil(t, replica.SetResourceRecord("example.com.", &ResourceRecord{Name: "old.example.com.", Type: "A",
+		Class: "IN", TTL: 30, RData: []string{"10.10.0.9"}}))
+	assert.Nil(t, replica.SetView(&snapshot.Views[0]))
+	for _, zone := range snapshot.Zones {
+		assert.Nil(t, replica.ImportZone(zone.Zone, *zone.RR, true))
+	}
+	records, err := replica.ListResourceRecords("example.com.")
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(records)) {
+		assert.Equal(t, exampleDomain, records[0].Name)
+		assert.Equal(t, "cell", records[0].View)
+	}
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// maxTXTStringLength maximum length of a character string in the TXT record
+const maxTXTStringLength = 255
+
+// The record data(rData) of the types in the presentation format:
+//  A, AAAA: ip address, "172.168.15.101"
+//  CNAME, PTR: domain name, "app.example.com."
+//  TXT: text, "v=app1"
+//  MX: preference and exchange, "10 mail.example.com."
+//  SRV: priority, weight, port and target, "10 60 8080 app.example.com."
+
+// newRR builds the resource record of the record data
+func newRR(owner string, rrType uint16, rrClass uint16, ttl uint32, rData string) (dns.RR, error) {
+	hdr := dns.RR_Header{Name: owner, Rrtype: rrType, Class: rrClass, Ttl: ttl}
+	switch rrType {
+	case dns.TypeA:
+		return &dns.A{Hdr: hdr, A: net.ParseIP(rData)}, nil
+	case dns.TypeAAAA:
+		return &dns.AAAA{Hdr: hdr, AAAA: net.ParseIP(rData)}, nil
+	case dns.TypeTXT:
+		return &dns.TXT{Hdr: hdr, Txt: splitTXT(rData)}, nil
+	}
+	rr, err := dns.NewRR(fmt.Sprintf("%s %d %s %s %s", owner, ttl, dns.ClassToString[rrClass],
+		dns.TypeToString[rrType], rData))
+	if err != nil {
+		return nil, err
+	}
+	if rr == nil || rr.Header().Rrtype != rrType {
+		return nil, fmt.Errorf("invalid %s record data", dns.TypeToString[rrType])
+	}
+	return rr, nil
+}
+
+// splitTXT splits the text in to the character strings of the TXT record
+func splitTXT(text string) []string {
+	var txt []string
+	for len(text) > maxTXTStringLength {
+		txt = append(txt, text[:maxTXTStringLength])
+		text = text[maxTXTStringLength:]
+	}
+	return append(txt, text)
+}
+
+// ValidateRecordData checks the record data of the resource record type, the ip addresses of the A and AAAA
+// records are validated by the caller
+func ValidateRecordData(rrTypeStr string, rData []string) error {
+	rrType, ok := rrTypeMap[rrTypeStr]
+	if !ok {
+		return fmt.Errorf("unsupported rrtype(%s) entry", rrTypeStr)
+	}
+	if rrType == dns.TypeCNAME && len(rData) != 1 {
+		return fmt.Errorf("cname record must have one target")
+	}
+	for _, data := range rData {
+		if len(strings.TrimSpace(data)) == 0 || strings.ContainsAny(data, "\r\n") {
+			return fmt.Errorf("invalid %s record data", rrTypeStr)
+		}
+		if rrType == dns.TypeA || rrType == dns.TypeAAAA || rrType == dns.TypeTXT {
+			continue
+		}
+		if _, err := newRR(DefaultZone, rrType, dns.ClassINET, 1, data); err != nil {
+			return fmt.Errorf("invalid %s record data(%s)", rrTypeStr, data)
+		}
+	}
+	return nil
+}
@@ -17,7 +17,28 @@
 // Package Data Store
 package datastore
 
-import "github.com/miekg/dns"
+import (
+	"errors"
+	"net"
+
+	"github.com/miekg/dns"
+)
+
+// Types of the data stores
+const (
+	// TypeBolt the bolt db file of the dns server
+	TypeBolt = "bolt"
+	// TypeMemory the memory of the dns server, lost on restart
+	TypeMemory = "memory"
+	// TypeEtcd the etcd shared by the dns servers
+	TypeEtcd = "etcd"
+)
+
+// ErrNotFound the zone or the record does not exist
+var ErrNotFound = errors.New("not found")
+
+// ErrViewInUse the view is referenced by the records
+var ErrViewInUse = errors.New("view in use")
 
 type ResourceRecord struct {
 	Name  string   `json:"name"`
@@ -25,6 +46,18 @@ type ResourceRecord struct {
 	Class string   `json:"class"`
 	TTL   uint32   `json:"ttl"`
 	RData []string `json:"rData"`
+	// Weights load balancing weights of the A/AAAA record addresses, in the order of the rData
+	Weights []uint32 `json:"weights,omitempty"`
+	// HealthCheck active health check of the A/AAAA record addresses, the unhealthy addresses are withheld
+	HealthCheck *HealthCheck `json:"healthCheck,omitempty"`
+	// View the record is answered only to the clients of the view, the records without view are answered to all
+	View string `json:"view,omitempty"`
+}
+
+// View the clients of the source subnets
+type View struct {
+	Name    string   `json:"name"`
+	Subnets []string `json:"subnets"`
 }
 
 type ZoneEntry struct {
@@ -42,11 +75,54 @@ type DataStore interface {
 	// SetResourceRecord - Add or modify a A type record
 	SetResourceRecord(zone string, rr *ResourceRecord) error
 
-	// GetResourceRecord - Get A type record
-	GetResourceRecord(question *dns.Question) (*[]dns.RR, error)
+	// GetResourceRecord - Get the records answering the question to the client, nil client matches no view
+	GetResourceRecord(question *dns.Question, client net.IP) (*[]dns.RR, error)
 
-	// DelResourceRecord - Delete A type record
-	DelResourceRecord(zone string, host string, rrtype string) error
+	// DelResourceRecord - Delete the record of the view, empty view for the record without view
+	DelResourceRecord(zone string, host string, rrtype string, view string) error
 	// IsResourceRecordExists - check the record exists
 	IsResourceRecordExists(zone string, rr *ResourceRecord) bool
+
+	// GetZoneResourceRecord - Get the record of the host, type and view in the zone, ErrNotFound if not exists
+	GetZoneResourceRecord(zone string, host string, rrtype string, view string) (*ResourceRecord, error)
+
+	// ListResourceRecords - List the records of the zone sorted by the name and type, ErrNotFound if no zone
+	ListResourceRecords(zone string) ([]ResourceRecord, error)
+
+	// ListZones - List the zone names in sorted order
+	ListZones() ([]string, error)
+
+	// ImportZone - Add the records to the zone atomically, the zone is emptied first on replace
+	ImportZone(zone string, records []ResourceRecord, replace bool) error
+
+	// SetZoneAuthoritative - Mark the zone authoritative by generating its SOA and NS records if not exists, the SOA
+	// record is removed otherwise
+	SetZoneAuthoritative(zone string, authoritative bool) error
+
+	// GetAuthority - Get the SOA record of the most specific authoritative zone of the name, nil if the name is not in
+	// an authoritative zone, and whether the name exists in the zone
+	GetAuthority(name string) (dns.RR, bool, error)
+
+	// SetView - Add or modify the view
+	SetView(view *View) error
+
+	// GetView - Get the view, ErrNotFound if not exists
+	GetView(name string) (*View, error)
+
+	// ListViews - List the views sorted by the name
+	ListViews() ([]View, error)
+
+	// DelView - Delete the view and its records, ErrNotFound if not exists
+	DelView(name string) error
+
+	// DelUnusedView - Delete the view if no record references it, ErrNotFound if not exists and ErrViewInUse if
+	// referenced
+	DelUnusedView(name string) error
+
+	// Generation - Get the generation of the last change, 0 if never changed
+	Generation() (uint64, error)
+
+	// Changes - List at most limit changes after the generation in order, ErrChangeLogTruncated if the changes are no
+	// longer available
+	Changes(since uint64, limit int) ([]Change, error)
 }
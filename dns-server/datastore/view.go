@@ -0,0 +1,209 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+
+	bolt "go.etcd.io/bbolt"
+
+	"dns-server/util"
+)
+
+// ValidateView validates the view name and the source subnets
+func ValidateView(view *View) error {
+	if len(view.Name) == 0 || len(view.Name) > util.MaxViewNameLength {
+		return fmt.Errorf("invalid view name")
+	}
+	if len(view.Subnets) == 0 {
+		return fmt.Errorf("view should have source subnets")
+	}
+	for _, subnet := range view.Subnets {
+		if _, _, err := net.ParseCIDR(subnet); err != nil {
+			return fmt.Errorf("invalid view subnet(%s)", subnet)
+		}
+	}
+	return nil
+}
+
+func (b *BoltDB) SetView(view *View) error {
+	if err := ValidateView(view); err != nil {
+		return err
+	}
+	valueBytes, err := json.Marshal(view)
+	if err != nil {
+		return fmt.Errorf("data store could not marshal view json")
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		if err := tx.Bucket([]byte(ViewConfig)).Put([]byte(view.Name), valueBytes); err != nil {
+			return err
+		}
+		return logChange(tx, &Change{View: view})
+	})
+}
+
+func (b *BoltDB) GetView(name string) (*View, error) {
+	view := &View{}
+	err := b.db.View(func(tx *bolt.Tx) error {
+		valueBytes := tx.Bucket([]byte(ViewConfig)).Get([]byte(name))
+		if valueBytes == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(valueBytes, view)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return view, nil
+}
+
+func (b *BoltDB) ListViews() ([]View, error) {
+	views := make([]View, 0)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(ViewConfig)).ForEach(func(_, valueBytes []byte) error {
+			view := View{}
+			if err := json.Unmarshal(valueBytes, &view); err != nil {
+				return err
+			}
+			views = append(views, view)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reading views from data store failed")
+	}
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].Name < views[j].Name
+	})
+	return views, nil
+}
+
+func (b *BoltDB) DelView(name string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		viewBkt := tx.Bucket([]byte(ViewConfig))
+		if viewBkt.Get([]byte(name)) == nil {
+			return ErrNotFound
+		}
+		// The records of the view are never answered without the view
+		zonesBkt := tx.Bucket([]byte(ZoneConfig))
+		err := zonesBkt.ForEach(func(zone, _ []byte) error {
+			zoneBkt := zonesBkt.Bucket(zone)
+			var viewKeys [][]byte
+			err := zoneBkt.ForEach(func(key, _ []byte) error {
+				dnsCfgKey := &DNSConfigRRKey{}
+				if json.Unmarshal(key, dnsCfgKey) == nil && dnsCfgKey.View == name {
+					viewKeys = append(viewKeys, key)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, key := range viewKeys {
+				if err = deleteRecord(tx, string(zone), zoneBkt, key); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("deleting the records of the view(%s) failed", name)
+		}
+		if err = viewBkt.Delete([]byte(name)); err != nil {
+			return err
+		}
+		return logChange(tx, &Change{View: &View{Name: name}, Deleted: true})
+	})
+}
+
+func (b *BoltDB) DelUnusedView(name string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		viewBkt := tx.Bucket([]byte(ViewConfig))
+		if viewBkt.Get([]byte(name)) == nil {
+			return ErrNotFound
+		}
+		zonesBkt := tx.Bucket([]byte(ZoneConfig))
+		inUse := false
+		_ = zonesBkt.ForEach(func(zone, _ []byte) error {
+			inUse = inUse || viewInUse(zonesBkt.Bucket(zone), name)
+			return nil
+		})
+		if inUse {
+			return ErrViewInUse
+		}
+		if err := viewBkt.Delete([]byte(name)); err != nil {
+			return err
+		}
+		return logChange(tx, &Change{View: &View{Name: name}, Deleted: true})
+	})
+}
+
+// viewInUse checks whether any record of the zone references the view
+func viewInUse(zoneBkt recordBucket, name string) bool {
+	inUse := false
+	_ = zoneBkt.ForEach(func(key, _ []byte) error {
+		dnsCfgKey := &DNSConfigRRKey{}
+		if json.Unmarshal(key, dnsCfgKey) == nil && dnsCfgKey.View == name {
+			inUse = true
+		}
+		return nil
+	})
+	return inUse
+}
+
+// matchViews returns the views of the client ordered by the longest matching subnet
+func matchViews(viewBkt recordBucket, client net.IP) []string {
+	if client == nil {
+		return nil
+	}
+	type match struct {
+		view   string
+		prefix int
+	}
+	var matches []match
+	_ = viewBkt.ForEach(func(_, valueBytes []byte) error {
+		view := View{}
+		if json.Unmarshal(valueBytes, &view) != nil {
+			return nil
+		}
+		longest := -1
+		for _, subnet := range view.Subnets {
+			_, ipNet, err := net.ParseCIDR(subnet)
+			if err != nil || !ipNet.Contains(client) {
+				continue
+			}
+			if prefix, _ := ipNet.Mask.Size(); prefix > longest {
+				longest = prefix
+			}
+		}
+		if longest >= 0 {
+			matches = append(matches, match{view: view.Name, prefix: longest})
+		}
+		return nil
+	})
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].prefix > matches[j].prefix
+	})
+	views := make([]string, 0, len(matches))
+	for _, m := range matches {
+		views = append(views, m.view)
+	}
+	return views
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViews(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(DBPath)
+	}()
+
+	store := &BoltDB{FileName: "testviewdb", TTL: 30}
+	assert.Nil(t, store.Open())
+	defer store.Close()
+
+	assert.NotNil(t, store.SetView(&View{Name: "cell", Subnets: []string{"10.100.0"}}))
+	assert.Nil(t, store.SetView(&View{Name: "cell", Subnets: []string{"10.100.0.0/16"}}))
+	assert.Nil(t, store.SetView(&View{Name: "enterprise", Subnets: []string{"10.100.8.0/24", "2001:db8::/32"}}))
+	views, err := store.ListViews()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(views))
+	_, err = store.GetView("missing")
+	assert.Equal(t, ErrNotFound, err)
+
+	record := func(view string, address string) *ResourceRecord {
+		return &ResourceRecord{Name: exampleDomain, Type: "A", Class: "IN", TTL: 30, RData: []string{address},
+			View: view}
+	}
+	assert.Nil(t, store.SetResourceRecord(DefaultZone, record("", "10.10.0.1")))
+	assert.Nil(t, store.SetResourceRecord(DefaultZone, record("cell", "10.10.0.2")))
+	assert.Nil(t, store.SetResourceRecord("example.com.", record("enterprise", "10.10.0.3")))
+	assert.NotNil(t, store.SetResourceRecord(DefaultZone, record("missing", "10.10.0.4")))
+
+	answer := func(client string) string {
+		rrs, err := store.GetResourceRecord(&dns.Question{Name: exampleDomain, Qtype: dns.TypeA,
+			Qclass: dns.ClassINET}, net.ParseIP(client))
+		assert.Nil(t, err)
+		return (*rrs)[0].(*dns.A).A.String()
+	}
+	assert.Equal(t, "10.10.0.1", answer("192.168.1.1"))
+	assert.Equal(t, "10.10.0.2", answer("10.100.1.1"))
+	// The longest matching subnet is preferred
+	assert.Equal(t, "10.10.0.3", answer("10.100.8.1"))
+	assert.Equal(t, "10.10.0.3", answer("2001:db8::1"))
+	rrs, err := store.GetResourceRecord(&dns.Question{Name: exampleDomain, Qtype: dns.TypeA, Qclass: dns.ClassINET},
+		nil)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.10.0.1", (*rrs)[0].(*dns.A).A.String())
+
+	rr, err := store.GetZoneResourceRecord(DefaultZone, exampleDomain, "A", "cell")
+	assert.Nil(t, err)
+	assert.Equal(t, "cell", rr.View)
+	records, _ := store.ListResourceRecords(DefaultZone)
+	assert.Equal(t, 2, len(records))
+
+	// The records of the view are deleted with the view
+	assert.Nil(t, store.DelView("cell"))
+	assert.Equal(t, ErrNotFound, store.DelView("cell"))
+	assert.Equal(t, "10.10.0.1", answer("10.100.1.1"))
+	records, _ = store.ListResourceRecords(DefaultZone)
+	assert.Equal(t, 1, len(records))
+
+	// The view is kept while its records exist in any zone
+	assert.Equal(t, ErrViewInUse, store.DelUnusedView("enterprise"))
+	assert.Nil(t, store.DelResourceRecord("example.com.", exampleDomain, "A", "enterprise"))
+	assert.Equal(t, "10.10.0.1", answer("10.100.8.1"))
+	assert.Nil(t, store.DelUnusedView("enterprise"))
+	assert.Equal(t, ErrNotFound, store.DelUnusedView("enterprise"))
+}
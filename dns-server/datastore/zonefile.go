@@ -0,0 +1,157 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/miekg/dns"
+
+	"dns-server/util"
+)
+
+const (
+	// ZoneImportMerge the imported records replace the records of the same name and type in the zone
+	ZoneImportMerge = "merge"
+	// ZoneImportReplace the zone is replaced by the imported records
+	ZoneImportReplace = "replace"
+)
+
+// Timers of the generated SOA record in seconds
+const (
+	soaRefresh = 3600
+	soaRetry   = 600
+	soaExpire  = 86400
+)
+
+// ParseZoneFile parses the master format(RFC 1035) zone file of the zone, the records of the same name and type are
+// merged in to one resource record
+func ParseZoneFile(r io.Reader, zone string) ([]ResourceRecord, error) {
+	zone = dns.Fqdn(strings.ToLower(zone))
+	parser := dns.NewZoneParser(r, zone, "")
+	parser.SetDefaultTTL(util.DefaultTTL)
+
+	records := make([]ResourceRecord, 0)
+	index := make(map[DNSConfigRRKey]int)
+	for rr, ok := parser.Next(); ok; rr, ok = parser.Next() {
+		hdr := rr.Header()
+		name := strings.ToLower(hdr.Name)
+		if !dns.IsSubDomain(zone, name) {
+			return nil, fmt.Errorf("record %s is out of the zone %s", hdr.Name, zone)
+		}
+		rrType := dns.TypeToString[hdr.Rrtype]
+		if _, ok := rrTypeMap[rrType]; !ok {
+			return nil, fmt.Errorf("unsupported rrtype(%s) of the record %s", rrType, hdr.Name)
+		}
+		if hdr.Ttl == 0 {
+			return nil, fmt.Errorf("unsupported ttl value 0 of the record %s", hdr.Name)
+		}
+
+		key := DNSConfigRRKey{Host: name, RRType: hdr.Rrtype}
+		if i, found := index[key]; found {
+			if records[i].Class != dns.ClassToString[hdr.Class] {
+				return nil, fmt.Errorf("class mismatch in the records of %s %s", hdr.Name, rrType)
+			}
+			records[i].RData = append(records[i].RData, recordData(rr))
+			if hdr.Ttl < records[i].TTL {
+				records[i].TTL = hdr.Ttl
+			}
+			continue
+		}
+		index[key] = len(records)
+		records = append(records, ResourceRecord{Name: name, Type: rrType, Class: dns.ClassToString[hdr.Class],
+			TTL: hdr.Ttl, RData: []string{recordData(rr)}})
+	}
+	if err := parser.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, rr := range records {
+		if err := ValidateRecordData(rr.Type, rr.RData); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// recordData returns the record data of the record in the presentation format of the data store
+func recordData(rr dns.RR) string {
+	if txt, ok := rr.(*dns.TXT); ok {
+		return strings.Join(txt.Txt, "")
+	}
+	return strings.TrimPrefix(rr.String(), rr.Header().String())
+}
+
+// WriteZoneFile writes the records of the zone in the master format(RFC 1035), the SOA and NS records are generated
+// for the zones other than the root zone if not available
+func WriteZoneFile(w io.Writer, zone string, records []ResourceRecord, serial uint32) error {
+	zone = dns.Fqdn(strings.ToLower(zone))
+	var soa, ns, others []dns.RR
+	for _, rr := range records {
+		rrType, ok := rrTypeMap[rr.Type]
+		// The master format has no views
+		if !ok || len(rr.View) != 0 {
+			continue
+		}
+		rrClass, ok := rrClassMap[rr.Class]
+		if !ok {
+			continue
+		}
+		for _, rData := range rr.RData {
+			record, err := newRR(rr.Name, rrType, rrClass, rr.TTL, rData)
+			if err != nil {
+				return fmt.Errorf("invalid %s record data of %s", rr.Type, rr.Name)
+			}
+			switch rrType {
+			case dns.TypeSOA:
+				soa = append(soa, record)
+			case dns.TypeNS:
+				ns = append(ns, record)
+			default:
+				others = append(others, record)
+			}
+		}
+	}
+	if zone != DefaultZone {
+		if len(soa) == 0 {
+			soa = append(soa, NewSOA(zone, serial))
+		}
+		if len(ns) == 0 {
+			ns = append(ns, &dns.NS{Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeNS, Class: dns.ClassINET,
+				Ttl: util.DefaultTTL}, Ns: "ns." + zone})
+		}
+	}
+
+	writer := bufio.NewWriter(w)
+	_, _ = fmt.Fprintf(writer, "$ORIGIN %s\n$TTL %d\n", zone, util.DefaultTTL)
+	for _, group := range [][]dns.RR{soa, ns, others} {
+		for _, record := range group {
+			_, _ = fmt.Fprintln(writer, record.String())
+		}
+	}
+	return writer.Flush()
+}
+
+// NewSOA generates the SOA record of the zone served by this server
+func NewSOA(zone string, serial uint32) *dns.SOA {
+	return &dns.SOA{Hdr: dns.RR_Header{Name: zone, Rrtype: dns.TypeSOA, Class: dns.ClassINET, Ttl: util.DefaultTTL},
+		Ns: "ns." + zone, Mbox: "hostmaster." + zone, Serial: serial, Refresh: soaRefresh, Retry: soaRetry,
+		Expire: soaExpire, Minttl: util.DefaultTTL}
+}
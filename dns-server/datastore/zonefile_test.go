@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package datastore
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+const exampleZoneFile = `$ORIGIN example.com.
+$TTL 60
+@       IN SOA ns1 admin 2021010101 3600 600 86400 30
+@       IN NS  ns1
+ns1     IN A   10.10.0.53
+app     IN A   10.10.0.1
+app     IN A   10.10.0.2
+alias   IN CNAME app
+_http._tcp IN SRV 10 60 8080 app
+app     IN TXT "version=1"
+@       IN MX  10 mail
+`
+
+func TestParseZoneFile(t *testing.T) {
+	records, err := ParseZoneFile(strings.NewReader(exampleZoneFile), "example.com")
+	assert.Nil(t, err)
+	assert.Equal(t, 8, len(records))
+	assert.Equal(t, ResourceRecord{Name: "app.example.com.", Type: "A", Class: "IN", TTL: 60,
+		RData: []string{"10.10.0.1", "10.10.0.2"}}, records[3])
+	assert.Equal(t, ResourceRecord{Name: "alias.example.com.", Type: "CNAME", Class: "IN", TTL: 60,
+		RData: []string{"app.example.com."}}, records[4])
+	assert.Equal(t, []string{"10 60 8080 app.example.com."}, records[5].RData)
+	assert.Equal(t, []string{"version=1"}, records[6].RData)
+
+	_, err = ParseZoneFile(strings.NewReader("www.example.org. 60 IN A 10.10.0.1\n"), "example.com.")
+	assert.NotNil(t, err)
+	_, err = ParseZoneFile(strings.NewReader("www 60 IN HINFO cpu os\n"), "example.com.")
+	assert.NotNil(t, err)
+	_, err = ParseZoneFile(strings.NewReader("www 60 IN A 10.10.0\n"), "example.com.")
+	assert.NotNil(t, err)
+}
+
+func TestWriteZoneFile(t *testing.T) {
+	records := []ResourceRecord{
+		{Name: "app.example.com.", Type: "A", Class: "IN", TTL: 30, RData: []string{"10.10.0.1"}},
+		{Name: "app.example.com.", Type: "TXT", Class: "IN", TTL: 30, RData: []string{"version=1"}},
+	}
+	var zoneFile bytes.Buffer
+	assert.Nil(t, WriteZoneFile(&zoneFile, "example.com.", records, 7))
+	assert.Equal(t, "$ORIGIN example.com.\n$TTL 30\n"+
+		"example.com.\t30\tIN\tSOA\tns.example.com. hostmaster.example.com. 7 3600 600 86400 30\n"+
+		"example.com.\t30\tIN\tNS\tns.example.com.\n"+
+		"app.example.com.\t30\tIN\tA\t10.10.0.1\n"+
+		"app.example.com.\t30\tIN\tTXT\t\"version=1\"\n", zoneFile.String())
+
+	// The exported zone file is imported back as is
+	imported, err := ParseZoneFile(&zoneFile, "example.com.")
+	assert.Nil(t, err)
+	assert.Equal(t, records, imported[2:])
+
+	// No SOA and NS on the root zone
+	zoneFile.Reset()
+	assert.Nil(t, WriteZoneFile(&zoneFile, ".", records[:1], 7))
+	assert.Equal(t, "$ORIGIN .\n$TTL 30\napp.example.com.\t30\tIN\tA\t10.10.0.1\n", zoneFile.String())
+}
+
+func TestImportZone(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(DBPath)
+	}()
+
+	store := &BoltDB{FileName: "testimportdb", TTL: 30}
+	assert.Nil(t, store.Open())
+	defer store.Close()
+
+	assert.Nil(t, store.SetResourceRecord("example.com.", &ResourceRecord{Name: "old.example.com.", Type: "A",
+		Class: "IN", TTL: 30, RData: []string{"10.10.0.9"}}))
+	records, err := ParseZoneFile(strings.NewReader(exampleZoneFile), "example.com.")
+	assert.Nil(t, err)
+
+	// Merge keeps the other records
+	assert.Nil(t, store.ImportZone("example.com.", records, false))
+	zoneRecords, _ := store.ListResourceRecords("example.com.")
+	assert.Equal(t, 9, len(zoneRecords))
+	rrResponse, err := store.GetResourceRecord(&dns.Question{Name: "alias.example.com.", Qtype: dns.TypeA,
+		Qclass: dns.ClassINET}, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(*rrResponse))
+
+	// Replace drops the other records
+	assert.Nil(t, store.ImportZone("example.com.", records, true))
+	zoneRecords, _ = store.ListResourceRecords("example.com.")
+	assert.Equal(t, 8, len(zoneRecords))
+
+	// The failed import is not applied
+	invalid := append(records, ResourceRecord{Name: "alias.example.com.", Type: "A", Class: "IN", TTL: 30,
+		RData: []string{"10.10.0.3"}})
+	assert.NotNil(t, store.ImportZone("example.com.", invalid, true))
+	zoneRecords, _ = store.ListResourceRecords("example.com.")
+	assert.Equal(t, 8, len(zoneRecords))
+}
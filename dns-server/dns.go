@@ -18,29 +18,53 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
-	"math/rand"
 	"net"
+	"os"
 	"time"
 
 	"github.com/miekg/dns"
 	log "github.com/sirupsen/logrus"
 
+	"dns-server/balance"
+	"dns-server/cache"
 	"dns-server/datastore"
+	"dns-server/forward"
 	"dns-server/mgmt"
+	"dns-server/querylog"
+	"dns-server/replication"
 	"dns-server/util"
 )
 
 // Config DNS server configuration.
 type Config struct {
-	dbName            string // Database name, default zone
-	port              uint   // Port to listen to, default 53
-	mgmtPort          uint   // Http port to listen to, default 80
-	ipAdd             net.IP // IP address to listen to, default 0.0.0.0
-	ipMgmtAdd         net.IP // IP address to listen to, default 0.0.0.0
-	forwarder         net.IP // Forwarder dns address , default 8.8.8.8
-	connectionTimeout uint   // Connection time out value, both read, and write, default 2s
-	loadBalance       bool   // load balancing using random shuffle
+	dbName            string             // Database name, default zone
+	dbType            string             // bolt, memory or etcd data store
+	etcdEndpoints     []string           // endpoints of the etcd data store
+	etcdTLS           *tls.Config        // tls config of the etcd client, nil connects over http
+	port              uint               // Port to listen to, default 53
+	mgmtPort          uint               // Http port to listen to, default 80
+	ipAdd             net.IP             // IP address to listen to, default 0.0.0.0
+	ipMgmtAdd         net.IP             // IP address to listen to, default 0.0.0.0
+	forwarders        []forward.Upstream // Upstream dns servers, no forwarding by default
+	forwardPolicy     string             // sequential or parallel forwarding to the upstreams
+	cacheSize         uint               // number of the cached answers, 0 disables the cache
+	cacheMaxTTL       uint               // maximum ttl of the cached answers in seconds
+	clientSubnet      bool               // select the views by the EDNS client subnet of the queries
+	querySampleRate   float64            // fraction of the logged queries, 0 disables the query log
+	queryStatsFile    string             // file persisting the query statistics, empty keeps them in memory
+	primary           string             // management endpoint of the primary, replicated as secondary if set
+	secondaries       []string           // dns servers of the secondaries notified of the changes
+	primaryTLS        *tls.Config        // verifies the management api of the primary, nil pulls over http
+	mgmtTLS           *tls.Config        // serves the management api over https, nil serves plain http
+	mgmtToken         string             // bearer token of the management api, also sent to the primary
+	connectionTimeout uint               // Connection time out value, both read, and write, default 2s
+	loadBalance       bool               // load balancing using random shuffle
+	tracingEndpoint   string             // otlp/http traces endpoint of the collector, empty disables the tracing
+	zoneFile          string             // master format zone file imported on start, empty skips the import
+	zone              string             // zone of the imported zone file
+	zoneFileMode      string             // merge or replace the zone with the imported zone file
 }
 
 type Server struct {
@@ -49,23 +73,48 @@ type Server struct {
 	mgmtCtl   mgmt.ManagementCtrl
 	tcpServer *dns.Server
 	udpServer *dns.Server
+	forwarder *forward.Forwarder
+	// answerCache caches the forwarded answers
+	answerCache *cache.Cache
+	balancer    *balance.Balancer
+	queryLog    *querylog.Recorder
+	notifier    *replication.Notifier
+	// replicator replicates the data store of the primary, nil on the primary
+	replicator *replication.Replicator
 }
 
 func NewServer(config *Config, dataStore datastore.DataStore, mgmtCtl mgmt.ManagementCtrl) *Server {
-	return &Server{config: config, dataStore: dataStore, mgmtCtl: mgmtCtl}
+	server := &Server{config: config, dataStore: dataStore, mgmtCtl: mgmtCtl,
+		forwarder: forward.NewForwarder(config.forwarders, config.forwardPolicy,
+			time.Duration(config.connectionTimeout)*time.Second),
+		answerCache: cache.NewCache(int(config.cacheSize), uint32(config.cacheMaxTTL)),
+		balancer:    balance.NewBalancer(dataStore),
+		queryLog:    querylog.NewRecorder(dataStore, config.querySampleRate, config.queryStatsFile),
+		notifier:    replication.NewNotifier(dataStore, config.secondaries)}
+	if len(config.primary) != 0 {
+		server.replicator = replication.NewReplicator(dataStore, config.primary, config.primaryTLS, config.mgmtToken)
+	}
+	return server
 }
 
-func (s *Server) Run() error { // Set dns query handler
-	dns.HandleFunc(".", s.handleDNS)
-
+func (s *Server) Run() error {
 	address := fmt.Sprintf("%s:%d", s.config.ipAdd.String(), s.config.port)
 
+	// The udp responses are truncated to the client buffer size and retried by the client over tcp
 	s.udpServer = &dns.Server{
 		Addr:         address,
 		Net:          "udp",
 		UDPSize:      util.DNSUDPPacketSize,
 		ReadTimeout:  time.Duration(s.config.connectionTimeout) * time.Second,
 		WriteTimeout: time.Duration(s.config.connectionTimeout) * time.Second,
+		Handler:      s.handler(true),
+	}
+	s.tcpServer = &dns.Server{
+		Addr:         address,
+		Net:          "tcp",
+		ReadTimeout:  time.Duration(s.config.connectionTimeout) * time.Second,
+		WriteTimeout: time.Duration(s.config.connectionTimeout) * time.Second,
+		Handler:      s.handler(false),
 	}
 
 	err := s.dataStore.Open()
@@ -75,12 +124,46 @@ func (s *Server) Run() error { // Set dns query handler
 		return err
 	}
 
+	if len(s.config.zoneFile) != 0 {
+		err = s.importZoneFile()
+		if err != nil {
+			log.Errorf("Failed to import the zone file %s(%s).", s.config.zoneFile, err.Error())
+
+			return err
+		}
+	}
+
+	s.balancer.Start()
+	s.queryLog.Start()
+	s.notifier.Start()
+	s.replicator.Start()
 	go s.mgmtCtl.StartController(&s.dataStore, s.config.ipMgmtAdd, s.config.mgmtPort)
 	go s.start(s.udpServer)
+	go s.start(s.tcpServer)
 
 	return nil
 }
 
+// importZoneFile imports the configured zone file to the data store
+func (s *Server) importZoneFile() error {
+	zoneFile, err := os.Open(s.config.zoneFile)
+	if err != nil {
+		return err
+	}
+	defer zoneFile.Close()
+
+	records, err := datastore.ParseZoneFile(zoneFile, s.config.zone)
+	if err != nil {
+		return err
+	}
+	err = s.dataStore.ImportZone(s.config.zone, records, s.config.zoneFileMode == datastore.ZoneImportReplace)
+	if err != nil {
+		return err
+	}
+	log.Infof("Imported %d resource records to the zone %s.", len(records), s.config.zone)
+	return nil
+}
+
 func (s *Server) start(dns *dns.Server) {
 	err := dns.ListenAndServe()
 	if err != nil {
@@ -90,6 +173,10 @@ func (s *Server) start(dns *dns.Server) {
 }
 
 func (s *Server) Stop() {
+	s.replicator.Stop()
+	s.notifier.Stop()
+	s.balancer.Stop()
+	s.queryLog.Stop()
 	err := s.dataStore.Close()
 	if err != nil {
 		log.Error("Failed to close the data store.", nil)
@@ -101,6 +188,12 @@ func (s *Server) Stop() {
 			log.Error("Failed to stop the dns udp server.", nil)
 		}
 	}
+	if s.tcpServer != nil {
+		err = s.tcpServer.Shutdown()
+		if err != nil {
+			log.Error("Failed to stop the dns tcp server.", nil)
+		}
+	}
 
 	err = s.mgmtCtl.StopController()
 	if err != nil {
@@ -110,65 +203,159 @@ func (s *Server) Stop() {
 	log.Info("Edge-Gallery DNS-Server stopped now.")
 }
 
-// forward request to external server.
-func (s *Server) forward(req *dns.Msg) (*dns.Msg, error) {
-	c := new(dns.Client)
-	forwarder := s.config.forwarder.String()
-	if forwarder == util.DefaultIP {
-		return nil, fmt.Errorf("could not resolve the request %q and no forwarder is configured",
-			req.Question[0].Name)
+// forward request to external server, the answers are cached and whether the answer is from the cache is returned
+func (s *Server) forward(req *dns.Msg) (*dns.Msg, bool, error) {
+	if rsp, ok := s.answerCache.Get(req); ok {
+		return rsp, true, nil
 	}
 
-	// Retry 3 times on failure. exchange will not retry on failure.
-	for i := 0; i < util.ForwardRetryCount; i++ {
-		ret, _, err := c.Exchange(req, forwarder+":53")
-		if err != nil {
-			continue
-		}
-		if ret.Rcode == dns.RcodeSuccess {
-			return ret, nil
-		}
+	rsp, err := s.forwarder.Forward(req)
+	if err != nil {
+		return nil, false, err
+	}
+	s.answerCache.Set(rsp)
+	return rsp, false, nil
+}
+
+// CacheStats returns the answer cache statistics
+func (s *Server) CacheStats() cache.Stats {
+	return s.answerCache.Stats()
+}
+
+// FlushCache removes all the cached answers
+func (s *Server) FlushCache() {
+	s.answerCache.Flush()
+	log.Info("Flushed the answer cache.")
+}
+
+// ForwarderStats returns the health and latency of the forwarders
+func (s *Server) ForwarderStats() []forward.UpstreamStats {
+	return s.forwarder.Stats()
+}
+
+// TargetHealth returns the health state of the load balanced addresses
+func (s *Server) TargetHealth() []balance.TargetHealth {
+	return s.balancer.Health()
+}
+
+// QuerySummary returns the query counters by the answer source, response code and zone
+func (s *Server) QuerySummary() querylog.Summary {
+	return s.queryLog.Summary()
+}
+
+// TopNames returns the most queried names, at most limit entries
+func (s *Server) TopNames(limit int) []querylog.NameHits {
+	return s.queryLog.TopNames(limit)
+}
+
+// ReplicationStatus returns the role and the replication state of the server
+func (s *Server) ReplicationStatus() replication.Status {
+	if s.replicator != nil {
+		return s.replicator.Status()
 	}
+	generation, _ := s.dataStore.Generation()
+	return replication.Status{Role: replication.RolePrimary, Generation: generation,
+		Secondaries: s.notifier.Secondaries()}
+}
 
-	return nil, fmt.Errorf("forward of request %q was not accepted", req.Question[0].Name)
+// ResetQueryStats clears the query statistics
+func (s *Server) ResetQueryStats() {
+	s.queryLog.Reset()
+	log.Info("Reset the query statistics.")
 }
 
-// Handle DNS Query matching.
+// Handle DNS Query matching, the queries are recorded in the query log and statistics.
 func (s *Server) handleDNS(w dns.ResponseWriter, req *dns.Msg) {
+	start := time.Now()
+	client := s.clientAddress(w, req)
+	rw := &rcodeResponseWriter{ResponseWriter: w, rcode: dns.RcodeServerFailure}
+	source := s.answer(rw, req, client)
+	if len(req.Question) == 0 {
+		return
+	}
+	s.queryLog.Record(&querylog.Query{Client: client, Name: req.Question[0].Name, Type: req.Question[0].Qtype,
+		Rcode: rw.rcode, Source: source, Latency: time.Since(start)})
+}
+
+// answer answers the query and returns the source of the answer
+func (s *Server) answer(w dns.ResponseWriter, req *dns.Msg, client net.IP) string {
 	if !s.validateQuestion(req) {
 		s.writeErrorResponse(w, req, dns.RcodeFormatError)
 
-		return
+		return querylog.SourceNone
+	}
+
+	// The notify of the primary only triggers pulling the changes from the configured primary
+	if req.Opcode == dns.OpcodeNotify && s.replicator != nil {
+		s.replicator.Notify()
+		response := new(dns.Msg)
+		response.SetReply(req)
+		response.Authoritative = true
+		if err := w.WriteMsg(response); err != nil {
+			log.Errorf("Failed to send notify response")
+		}
+		return querylog.SourceLocal
 	}
 
 	if req.Opcode == dns.OpcodeQuery {
-		// log.Debugf("Query lookup (%s)", req.Question[0].String())
 		// Match data from db
-		rrs, err := s.dataStore.GetResourceRecord(&req.Question[0])
+		rrs, err := s.dataStore.GetResourceRecord(&req.Question[0], client)
 		if err != nil {
-			respMsg, err := s.forward(req)
+			// Names inside an authoritative zone are answered locally and never forwarded
+			soa, exists, authErr := s.dataStore.GetAuthority(req.Question[0].Name)
+			if authErr == nil && soa != nil {
+				s.writeNegativeResponse(soa, exists, w, req)
+				return querylog.SourceLocal
+			}
+			respMsg, cached, err := s.forward(req)
 			if err != nil {
 				s.writeErrorResponse(w, req, dns.RcodeServerFailure)
-				// log.Debugf("Failed to find entry: %v", err)
-				return
+				return querylog.SourceNone
 			}
 			err = w.WriteMsg(respMsg)
 			if err != nil {
 				log.Errorf("Failed to send a response for query")
 			}
 
-			return
-		}
-		// Shuffle the response if load balancing is enabled
-		if s.config.loadBalance && len(*rrs) > 1 {
-			rand.Shuffle(len(*rrs), func(i, j int) {
-				(*rrs)[i], (*rrs)[j] = (*rrs)[j], (*rrs)[i]
-			})
+			if cached {
+				return querylog.SourceCache
+			}
+			return querylog.SourceForward
 		}
+		// Withhold the unhealthy addresses and order the weighted addresses, the other answers are shuffled if load
+		// balancing is enabled, the cname chain is kept in order
+		chainLength := cnameChainLength(*rrs, req.Question[0].Qtype)
+		answer := s.balancer.Balance((*rrs)[chainLength:], s.config.loadBalance)
+		*rrs = append((*rrs)[:chainLength], answer...)
 		s.writeSuccessResponse(rrs, w, req)
-	} else {
-		s.writeErrorResponse(w, req, dns.RcodeRefused)
+		return querylog.SourceLocal
 	}
+	s.writeErrorResponse(w, req, dns.RcodeRefused)
+	return querylog.SourceNone
+}
+
+// rcodeResponseWriter keeps the response code of the written response
+type rcodeResponseWriter struct {
+	dns.ResponseWriter
+	rcode int
+}
+
+// WriteMsg writes the response and keeps its response code
+func (w *rcodeResponseWriter) WriteMsg(msg *dns.Msg) error {
+	w.rcode = msg.Rcode
+	return w.ResponseWriter.WriteMsg(msg)
+}
+
+// cnameChainLength returns the number of the cname records preceding the answer of the query type
+func cnameChainLength(rrs []dns.RR, qtype uint16) int {
+	if qtype == dns.TypeCNAME {
+		return 0
+	}
+	length := 0
+	for length < len(rrs) && rrs[length].Header().Rrtype == dns.TypeCNAME {
+		length++
+	}
+	return length
 }
 
 // Validate the input question.
@@ -205,3 +392,25 @@ func (s *Server) writeSuccessResponse(answer *[]dns.RR, w dns.ResponseWriter, re
 		log.Errorf("Failed to send success response for query")
 	}
 }
+
+// writeNegativeResponse answers NODATA when the name exists in the authoritative zone and NXDOMAIN otherwise, with
+// the zone SOA in the authority section for negative caching (RFC 2308)
+func (s *Server) writeNegativeResponse(soa dns.RR, exists bool, w dns.ResponseWriter, req *dns.Msg) {
+	response := new(dns.Msg)
+	response.SetReply(req)
+	response.Authoritative = true
+	if !exists {
+		response.Rcode = dns.RcodeNameError
+	}
+
+	soa = dns.Copy(soa)
+	if soaRR, ok := soa.(*dns.SOA); ok && soaRR.Minttl < soaRR.Hdr.Ttl {
+		soaRR.Hdr.Ttl = soaRR.Minttl
+	}
+	response.Ns = []dns.RR{soa}
+
+	err := w.WriteMsg(response)
+	if err != nil {
+		log.Errorf("Failed to send negative response for query")
+	}
+}
@@ -17,9 +17,11 @@ package main
 
 import (
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"net"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 	"time"
@@ -29,7 +31,10 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"dns-server/datastore"
+	"dns-server/forward"
 	"dns-server/mgmt"
+	"dns-server/querylog"
+	"dns-server/replication"
 	"dns-server/util"
 )
 
@@ -68,8 +73,33 @@ func TestForward(t *testing.T) {
 	var ipMgmtAddString = util.DefaultIP
 	var forwarder = defaultTestForwarder
 	var loadBalance = false
+	var tracingEndpoint = ""
+	var zoneFile = ""
+	var zone = datastore.DefaultZone
+	var zoneFileMode = datastore.ZoneImportMerge
+	var forwardPolicy = forward.PolicySequential
+	var cacheSize uint = util.DefaultCacheSize
+	var cacheMaxTTL uint = util.DefaultCacheMaxTTL
+	var clientSubnet = false
+	var querySampleRate float64 = 0
+	var queryStatsFile = ""
+	var primary = ""
+	var notify = ""
+	var primaryCA = ""
+	var mgmtCert = ""
+	var mgmtKey = ""
+	var mgmtClientCA = ""
+	var mgmtTokenFile = ""
+	var dbType = datastore.TypeBolt
+	var etcdEndpoints = ""
+	var etcdCA = ""
+	var etcdCert = ""
+	var etcdKey = ""
 	parameters := &InputParameters{&dbName, &port, &mgmtPort, &connTimeOut,
-		&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance}
+		&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+		&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+		&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+		&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 	config := validateInputAndGenerateConfig(parameters)
 
 	store := &datastore.BoltDB{FileName: config.dbName, TTL: util.DefaultTTL}
@@ -94,7 +124,7 @@ func TestForward(t *testing.T) {
 		})
 		defer patch1.Reset()
 
-		rsp, err := dnsServer.forward(dnsMsg)
+		rsp, _, err := dnsServer.forward(dnsMsg)
 		assert.Equal(t, nil, err, errorForwarding)
 		assert.Contains(t, rsp.Answer[0].String(), testDomainServer, errorForwarding)
 	})
@@ -116,15 +146,58 @@ func TestForward(t *testing.T) {
 		})
 		defer patch1.Reset()
 
-		_, err := dnsServer.forward(dnsMsg)
+		_, _, err := dnsServer.forward(dnsMsg)
 		assert.NotEqual(t, nil, err, errorForwarding)
 		assert.EqualError(t, err, "forward of request \"www.edgegallery0000111.org.\" was not "+
 			"accepted", errorForwarding)
 	})
 
+	t.Run("CachedAnswer", func(t *testing.T) {
+		dnsMsg := new(dns.Msg)
+		dnsMsg.SetQuestion("cached.edgegallery.org.", dns.TypeA)
+
+		var c *dns.Client
+		patch1 := gomonkey.ApplyMethod(reflect.TypeOf(c), "Exchange", func(client *dns.Client, m *dns.Msg,
+			address string) (r *dns.Msg, rtt time.Duration, err error) {
+			rsp := new(dns.Msg)
+			rsp.SetReply(m)
+			rsp.Answer = []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: m.Question[0].Name, Rrtype: m.Question[0].Qtype,
+				Class: dns.ClassINET, Ttl: 30}, A: net.ParseIP(dnsConfigTestIP1)}}
+			return rsp, 10, nil
+		})
+		defer patch1.Reset()
+
+		// the requests sent to the forwarders are counted in their stats
+		forwarded := func() uint64 {
+			var requests uint64
+			for _, upstreamStats := range dnsServer.ForwarderStats() {
+				requests += upstreamStats.Requests
+			}
+			return requests
+		}
+		assert.Equal(t, 1, len(dnsServer.ForwarderStats()), errorForwarding)
+		requests := forwarded()
+		hits := dnsServer.CacheStats().Hits
+
+		for i := 0; i < 2; i++ {
+			rsp, cached, err := dnsServer.forward(dnsMsg)
+			assert.Equal(t, nil, err, errorForwarding)
+			assert.Equal(t, 1, len(rsp.Answer), errorForwarding)
+			assert.Equal(t, i == 1, cached, errorForwarding)
+		}
+		assert.Equal(t, requests+1, forwarded(), errorForwarding)
+		assert.Equal(t, hits+1, dnsServer.CacheStats().Hits, errorForwarding)
+
+		dnsServer.FlushCache()
+		_, cached, err := dnsServer.forward(dnsMsg)
+		assert.Equal(t, nil, err, errorForwarding)
+		assert.False(t, cached, errorForwarding)
+		assert.Equal(t, requests+2, forwarded(), errorForwarding)
+		assert.Equal(t, hits+1, dnsServer.CacheStats().Hits, errorForwarding)
+	})
+
 	t.Run("WrongForwardAddress", func(t *testing.T) {
-		config.forwarder = net.ParseIP("0.0.0.0")
-		defer func() { config.forwarder = net.ParseIP(defaultTestForwarder) }()
+		noForwarderServer := NewServer(&Config{dbName: config.dbName, cacheSize: util.DefaultCacheSize}, store, mgmtCtl)
 
 		dnsMsg := new(dns.Msg)
 		dnsMsg.Id = dns.Id()
@@ -132,7 +205,7 @@ func TestForward(t *testing.T) {
 		dnsMsg.Question = make([]dns.Question, 1)
 		dnsMsg.Question[0] = dns.Question{Name: testDomainServer, Qtype: dns.TypeA, Qclass: dns.ClassINET}
 
-		_, err := dnsServer.forward(dnsMsg)
+		_, _, err := noForwarderServer.forward(dnsMsg)
 		assert.NotEqual(t, nil, err, errorForwarding)
 		assert.EqualError(t, err, "could not resolve the request \"www.edgegallery.org.\" and no forwarder is "+
 			"configured", errorForwarding)
@@ -151,7 +224,7 @@ func (m *mockDnsRespWriter) LocalAddr() net.Addr {
 }
 
 func (m *mockDnsRespWriter) RemoteAddr() net.Addr {
-	panic(panicImplement)
+	return &net.UDPAddr{IP: net.ParseIP("127.0.0.1"), Port: util.DefaultDNSPort}
 }
 
 func (m *mockDnsRespWriter) WriteMsg(msg *dns.Msg) error {
@@ -197,8 +270,33 @@ func TestHandleDNS(t *testing.T) {
 	var ipMgmtAddString = util.DefaultIP
 	var forwarder = defaultTestForwarder
 	var loadBalance = false
+	var tracingEndpoint = ""
+	var zoneFile = ""
+	var zone = datastore.DefaultZone
+	var zoneFileMode = datastore.ZoneImportMerge
+	var forwardPolicy = forward.PolicySequential
+	var cacheSize uint = util.DefaultCacheSize
+	var cacheMaxTTL uint = util.DefaultCacheMaxTTL
+	var clientSubnet = false
+	var querySampleRate float64 = 0
+	var queryStatsFile = ""
+	var primary = ""
+	var notify = ""
+	var primaryCA = ""
+	var mgmtCert = ""
+	var mgmtKey = ""
+	var mgmtClientCA = ""
+	var mgmtTokenFile = ""
+	var dbType = datastore.TypeBolt
+	var etcdEndpoints = ""
+	var etcdCA = ""
+	var etcdCert = ""
+	var etcdKey = ""
 	parameters := &InputParameters{&dbName, &port, &mgmtPort, &connTimeOut,
-		&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance}
+		&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+		&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+		&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+		&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 	config := validateInputAndGenerateConfig(parameters)
 
 	store := &datastore.BoltDB{FileName: config.dbName, TTL: util.DefaultTTL}
@@ -280,4 +378,108 @@ func TestHandleDNS(t *testing.T) {
 		assert.Contains(t, mockDnsWriter.rspMsg.Answer[0].String(), testDomainServer, errorInResponse)
 	})
 
+	t.Run("AuthoritativeZone", func(t *testing.T) {
+		err := store.SetZoneAuthoritative("example.com", true)
+		assert.Equal(t, nil, err, "Error in setting the zone authority")
+		defer func() {
+			_ = store.SetZoneAuthoritative("example.com", false)
+		}()
+
+		// NXDOMAIN with the zone SOA instead of forwarding
+		req := &dns.Msg{Question: []dns.Question{{Name: "missing.example.com.", Qtype: dns.TypeA,
+			Qclass: dns.ClassINET}}}
+		mockDnsWriter := &mockDnsRespWriter{}
+		dnsServer.handleDNS(mockDnsWriter, req)
+		assert.Equal(t, dns.RcodeNameError, mockDnsWriter.rspMsg.Rcode, errorInResponse)
+		assert.True(t, mockDnsWriter.rspMsg.Authoritative, errorInResponse)
+		assert.Equal(t, 0, len(mockDnsWriter.rspMsg.Answer), errorInResponse)
+		assert.Equal(t, 1, len(mockDnsWriter.rspMsg.Ns), errorInResponse)
+		soa, ok := mockDnsWriter.rspMsg.Ns[0].(*dns.SOA)
+		assert.True(t, ok, errorInResponse)
+		assert.Equal(t, "example.com.", soa.Hdr.Name, errorInResponse)
+		assert.Equal(t, soa.Minttl, soa.Hdr.Ttl, errorInResponse)
+
+		// NODATA for the existing name without the requested type
+		req = &dns.Msg{Question: []dns.Question{{Name: exampleDomain, Qtype: dns.TypeAAAA, Qclass: dns.ClassINET}}}
+		mockDnsWriter = &mockDnsRespWriter{}
+		dnsServer.handleDNS(mockDnsWriter, req)
+		assert.Equal(t, dns.RcodeSuccess, mockDnsWriter.rspMsg.Rcode, errorInResponse)
+		assert.Equal(t, 0, len(mockDnsWriter.rspMsg.Answer), errorInResponse)
+		assert.Equal(t, 1, len(mockDnsWriter.rspMsg.Ns), errorInResponse)
+
+		// Names outside the local zones are still forwarded
+		req = &dns.Msg{Question: []dns.Question{{Name: "www.example.org.", Qtype: dns.TypeA, Qclass: dns.ClassINET}}}
+		mockDnsWriter = &mockDnsRespWriter{}
+		dnsServer.handleDNS(mockDnsWriter, req)
+		assert.Equal(t, dns.RcodeSuccess, mockDnsWriter.rspMsg.Rcode, errorInResponse)
+		assert.Equal(t, 1, len(mockDnsWriter.rspMsg.Answer), errorInResponse)
+	})
+
+	t.Run("QueryStats", func(t *testing.T) {
+		dnsServer.FlushCache()
+		dnsServer.ResetQueryStats()
+		for _, name := range []string{exampleDomain, exampleDomain, testDomainServer} {
+			req := &dns.Msg{Question: []dns.Question{{Name: name, Qtype: dns.TypeA, Qclass: dns.ClassINET}}}
+			dnsServer.handleDNS(&mockDnsRespWriter{}, req)
+		}
+		dnsServer.handleDNS(&mockDnsRespWriter{}, &dns.Msg{Question: []dns.Question{{Name: testInvalidDomain,
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}}})
+
+		summary := dnsServer.QuerySummary()
+		assert.Equal(t, uint64(4), summary.Total, errorInResponse)
+		assert.Equal(t, map[string]uint64{querylog.SourceLocal: 2, querylog.SourceForward: 1,
+			querylog.SourceNone: 1}, summary.Sources, errorInResponse)
+		assert.Equal(t, map[string]uint64{"NOERROR": 3, "SERVFAIL": 1}, summary.Rcodes, errorInResponse)
+		topNames := dnsServer.TopNames(1)
+		assert.Equal(t, []querylog.NameHits{{Name: exampleDomain, Type: "A", Hits: 2}}, topNames, errorInResponse)
+	})
+
+	t.Run("Notify", func(t *testing.T) {
+		// The primary refuses the notify
+		req := &dns.Msg{Question: []dns.Question{{Name: ".", Qtype: dns.TypeSOA, Qclass: dns.ClassINET}}}
+		req.Opcode = dns.OpcodeNotify
+		mockDnsWriter := &mockDnsRespWriter{}
+		dnsServer.handleDNS(mockDnsWriter, req)
+		assert.Equal(t, dns.RcodeRefused, mockDnsWriter.rspMsg.Rcode, errorInResponse)
+		assert.Equal(t, replication.RolePrimary, dnsServer.ReplicationStatus().Role, errorInResponse)
+
+		// The secondary acknowledges the notify and pulls the changes in the background
+		dnsServer.replicator = replication.NewReplicator(store, "127.0.0.1:8080", nil, "")
+		defer func() {
+			dnsServer.replicator = nil
+		}()
+		mockDnsWriter = &mockDnsRespWriter{}
+		dnsServer.handleDNS(mockDnsWriter, req)
+		assert.Equal(t, dns.RcodeSuccess, mockDnsWriter.rspMsg.Rcode, errorInResponse)
+		assert.True(t, mockDnsWriter.rspMsg.Authoritative, errorInResponse)
+		assert.Equal(t, replication.RoleSecondary, dnsServer.ReplicationStatus().Role, errorInResponse)
+	})
+}
+
+func TestImportZoneFileOnStart(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+
+	dir, err := ioutil.TempDir("", "zonefile")
+	assert.Equal(t, nil, err, "Error in creating the temp dir")
+	defer os.RemoveAll(dir)
+	zoneFile := filepath.Join(dir, "example.com.zone")
+	err = ioutil.WriteFile(zoneFile, []byte("$ORIGIN example.com.\nwww 30 IN A "+dnsConfigTestIP1+"\n"), 0600)
+	assert.Equal(t, nil, err, "Error in writing the zone file")
+
+	config := &Config{dbName: "test_zonefile_db", zoneFile: zoneFile, zone: "example.com.",
+		zoneFileMode: datastore.ZoneImportReplace}
+	store := &datastore.BoltDB{FileName: config.dbName, TTL: util.DefaultTTL}
+	assert.Equal(t, nil, store.Open(), "Error in opening the db")
+	defer store.Close()
+
+	dnsServer := NewServer(config, store, &stubMgmtCtrl{})
+	assert.Equal(t, nil, dnsServer.importZoneFile(), "Error in importing the zone file")
+	rrs, err := store.GetResourceRecord(&dns.Question{Name: exampleDomain, Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
+	assert.Equal(t, nil, err, errorInResponse)
+	assert.Equal(t, fmt.Sprintf("www.example.com.\t30\tIN\tA\t%s", dnsConfigTestIP1), (*rrs)[0].String())
+
+	config.zoneFile = filepath.Join(dir, "missing.zone")
+	assert.NotEqual(t, nil, dnsServer.importZoneFile(), "Error in importing the zone file")
 }
@@ -0,0 +1,107 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"net"
+
+	"github.com/miekg/dns"
+
+	"dns-server/util"
+)
+
+// handler returns the dns query handler of the udp or tcp server
+func (s *Server) handler(udp bool) dns.Handler {
+	return dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		s.handleDNS(&ednsResponseWriter{ResponseWriter: w, req: req, udp: udp,
+			clientSubnet: s.config.clientSubnet}, req)
+	})
+}
+
+// ednsResponseWriter answers the EDNS0 queries with an OPT record and truncates the udp responses to the buffer size
+// advertised by the client, the TC bit is set on the truncated responses
+type ednsResponseWriter struct {
+	dns.ResponseWriter
+	req *dns.Msg
+	udp bool
+	// clientSubnet echoes the client subnet option of the query
+	clientSubnet bool
+}
+
+// WriteMsg writes the response fitting in the client buffer
+func (w *ednsResponseWriter) WriteMsg(msg *dns.Msg) error {
+	reqOpt := w.req.IsEdns0()
+	if reqOpt != nil && msg.IsEdns0() == nil {
+		msg.SetEdns0(util.MaxEDNS0UDPSize, reqOpt.Do())
+		// The answer is scoped to the whole source prefix of the client subnet
+		if subnet := clientSubnetOption(w.req); subnet != nil && w.clientSubnet {
+			echo := *subnet
+			echo.SourceScope = subnet.SourceNetmask
+			opt := msg.IsEdns0()
+			opt.Option = append(opt.Option, &echo)
+		}
+	}
+	if w.udp {
+		msg.Truncate(udpResponseSize(reqOpt))
+	}
+	return w.ResponseWriter.WriteMsg(msg)
+}
+
+// udpResponseSize returns the udp response size of the client, 512 bytes without EDNS0 and limited to the server
+// buffer size with EDNS0
+func udpResponseSize(reqOpt *dns.OPT) int {
+	if reqOpt == nil {
+		return dns.MinMsgSize
+	}
+	size := int(reqOpt.UDPSize())
+	if size < dns.MinMsgSize {
+		return dns.MinMsgSize
+	}
+	if size > util.MaxEDNS0UDPSize {
+		return util.MaxEDNS0UDPSize
+	}
+	return size
+}
+
+// clientSubnetOption returns the EDNS Client Subnet(RFC 7871) option of the query, nil if not available
+func clientSubnetOption(req *dns.Msg) *dns.EDNS0_SUBNET {
+	opt := req.IsEdns0()
+	if opt == nil {
+		return nil
+	}
+	for _, option := range opt.Option {
+		if subnet, ok := option.(*dns.EDNS0_SUBNET); ok {
+			return subnet
+		}
+	}
+	return nil
+}
+
+// clientAddress returns the address selecting the views of the client, the address of the client subnet option is
+// used if enabled, the source address of the query otherwise
+func (s *Server) clientAddress(w dns.ResponseWriter, req *dns.Msg) net.IP {
+	if subnet := clientSubnetOption(req); subnet != nil && s.config.clientSubnet {
+		return subnet.Address
+	}
+	switch addr := w.RemoteAddr().(type) {
+	case *net.UDPAddr:
+		return addr.IP
+	case *net.TCPAddr:
+		return addr.IP
+	}
+	return nil
+}
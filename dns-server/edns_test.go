@@ -0,0 +1,180 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/datastore"
+	"dns-server/util"
+)
+
+const largeDomain = "large.example.com."
+
+// stubMgmtCtrl skips the management controller in the dns integration tests
+type stubMgmtCtrl struct{}
+
+func (c *stubMgmtCtrl) StartController(store *datastore.DataStore, ipAddr net.IP, port uint) {}
+
+func (c *stubMgmtCtrl) StopController() error {
+	return nil
+}
+
+func freeDNSPort(t *testing.T) uint {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer conn.Close()
+	return uint(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func exchangeWithRetry(client *dns.Client, req *dns.Msg, address string) (*dns.Msg, error) {
+	var rsp *dns.Msg
+	var err error
+	for i := 0; i < 20; i++ {
+		rsp, _, err = client.Exchange(req, address)
+		if err == nil {
+			return rsp, nil
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return rsp, err
+}
+
+func TestDNSOverUDPAndTCP(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+
+	config := &Config{dbName: "test_transport_db", port: freeDNSPort(t), ipAdd: net.ParseIP("127.0.0.1"),
+		connectionTimeout: util.DefaultConnTimeout}
+	store := &datastore.BoltDB{FileName: config.dbName, TTL: util.DefaultTTL}
+	dnsServer := NewServer(config, store, &stubMgmtCtrl{})
+	assert.Nil(t, dnsServer.Run())
+	defer dnsServer.Stop()
+
+	// 100 A records do not fit in a 512 bytes response
+	var pointTo []string
+	for i := 0; i < 100; i++ {
+		pointTo = append(pointTo, fmt.Sprintf("10.10.0.%d", i+1))
+	}
+	err := store.SetResourceRecord(".", &datastore.ResourceRecord{Name: largeDomain, Type: "A", Class: "IN",
+		TTL: 30, RData: pointTo})
+	assert.Nil(t, err)
+	address := fmt.Sprintf("127.0.0.1:%d", config.port)
+
+	t.Run("UDPTruncated", func(t *testing.T) {
+		req := new(dns.Msg).SetQuestion(largeDomain, dns.TypeA)
+		rsp, err := exchangeWithRetry(&dns.Client{Net: "udp"}, req, address)
+		if assert.Nil(t, err) {
+			assert.True(t, rsp.Truncated)
+			assert.True(t, len(rsp.Answer) < len(pointTo))
+			assert.Nil(t, rsp.IsEdns0())
+		}
+	})
+
+	t.Run("UDPWithEDNS0", func(t *testing.T) {
+		req := new(dns.Msg).SetQuestion(largeDomain, dns.TypeA)
+		req.SetEdns0(4096, false)
+		rsp, err := exchangeWithRetry(&dns.Client{Net: "udp", UDPSize: 4096}, req, address)
+		if assert.Nil(t, err) {
+			assert.False(t, rsp.Truncated)
+			assert.Equal(t, len(pointTo), len(rsp.Answer))
+			if assert.NotNil(t, rsp.IsEdns0()) {
+				assert.Equal(t, uint16(util.MaxEDNS0UDPSize), rsp.IsEdns0().UDPSize())
+			}
+		}
+	})
+
+	t.Run("UDPWithSmallEDNS0", func(t *testing.T) {
+		req := new(dns.Msg).SetQuestion(largeDomain, dns.TypeA)
+		req.SetEdns0(1024, false)
+		rsp, err := exchangeWithRetry(&dns.Client{Net: "udp", UDPSize: 1024}, req, address)
+		if assert.Nil(t, err) {
+			assert.True(t, rsp.Truncated)
+			assert.True(t, len(rsp.Answer) < len(pointTo))
+		}
+	})
+
+	t.Run("TCP", func(t *testing.T) {
+		req := new(dns.Msg).SetQuestion(largeDomain, dns.TypeA)
+		rsp, err := exchangeWithRetry(&dns.Client{Net: "tcp"}, req, address)
+		if assert.Nil(t, err) {
+			assert.False(t, rsp.Truncated)
+			assert.Equal(t, len(pointTo), len(rsp.Answer))
+		}
+	})
+}
+
+func TestUDPResponseSize(t *testing.T) {
+	assert.Equal(t, dns.MinMsgSize, udpResponseSize(nil))
+
+	opt := &dns.OPT{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeOPT}}
+	opt.SetUDPSize(256)
+	assert.Equal(t, dns.MinMsgSize, udpResponseSize(opt))
+	opt.SetUDPSize(1232)
+	assert.Equal(t, 1232, udpResponseSize(opt))
+	opt.SetUDPSize(65535)
+	assert.Equal(t, util.MaxEDNS0UDPSize, udpResponseSize(opt))
+}
+
+func TestClientSubnetViews(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+
+	config := &Config{dbName: "test_view_db"}
+	store := &datastore.BoltDB{FileName: config.dbName, TTL: util.DefaultTTL}
+	assert.Nil(t, store.Open())
+	defer store.Close()
+	assert.Nil(t, store.SetView(&datastore.View{Name: "cell", Subnets: []string{"10.100.0.0/16"}}))
+	assert.Nil(t, store.SetView(&datastore.View{Name: "local", Subnets: []string{"127.0.0.0/8"}}))
+	for view, address := range map[string]string{"": "10.10.0.1", "cell": "10.10.0.2", "local": "10.10.0.3"} {
+		assert.Nil(t, store.SetResourceRecord(".", &datastore.ResourceRecord{Name: exampleDomain, Type: "A",
+			Class: "IN", TTL: 30, RData: []string{address}, View: view}))
+	}
+	dnsServer := NewServer(config, store, &stubMgmtCtrl{})
+
+	query := func() *dns.Msg {
+		req := new(dns.Msg)
+		req.SetQuestion(exampleDomain, dns.TypeA)
+		req.SetEdns0(dns.MinMsgSize, false)
+		req.IsEdns0().Option = append(req.IsEdns0().Option, &dns.EDNS0_SUBNET{Code: dns.EDNS0SUBNET, Family: 1,
+			SourceNetmask: 24, Address: net.ParseIP("10.100.1.0").To4()})
+		mockDnsWriter := &mockDnsRespWriter{}
+		dnsServer.handler(true).ServeDNS(mockDnsWriter, req)
+		return mockDnsWriter.rspMsg
+	}
+
+	// The source address selects the view without the client subnet
+	rsp := query()
+	assert.Equal(t, "10.10.0.3", rsp.Answer[0].(*dns.A).A.String())
+	assert.Equal(t, 0, len(rsp.IsEdns0().Option))
+
+	config.clientSubnet = true
+	rsp = query()
+	assert.Equal(t, "10.10.0.2", rsp.Answer[0].(*dns.A).A.String())
+	subnet, ok := rsp.IsEdns0().Option[0].(*dns.EDNS0_SUBNET)
+	assert.True(t, ok)
+	assert.Equal(t, uint8(24), subnet.SourceScope)
+}
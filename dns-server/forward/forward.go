@@ -0,0 +1,256 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package forward forwards the dns requests to the upstream servers over udp, tcp or tls, the upstream health and
+// latency are tracked from the forwarded requests
+package forward
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+
+	"dns-server/util"
+)
+
+const (
+	// PolicySequential tries the upstreams one by one in the configured order, healthy upstreams first
+	PolicySequential = "sequential"
+	// PolicyParallel queries all the healthy upstreams in parallel and answers with the first response
+	PolicyParallel = "parallel"
+
+	tlsScheme = "tls://"
+	// rttWeight weight of the latest round trip time in the average
+	rttWeight = 0.2
+)
+
+// Upstream an upstream dns server, tls://host[:port][#server name] for DNS-over-TLS and host[:port] otherwise
+type Upstream struct {
+	Address    string // host:port of the server
+	TLS        bool   // DNS-over-TLS(RFC 7858)
+	ServerName string // tls server name, the host by default
+}
+
+// ParseUpstream parses an upstream dns server
+func ParseUpstream(upstream string) (Upstream, error) {
+	result := Upstream{}
+	address := upstream
+	port := strconv.Itoa(util.DefaultDNSPort)
+	if strings.HasPrefix(address, tlsScheme) {
+		result.TLS = true
+		address = strings.TrimPrefix(address, tlsScheme)
+		port = strconv.Itoa(util.DefaultDNSOverTLSPort)
+		if index := strings.Index(address, "#"); index >= 0 {
+			result.ServerName = address[index+1:]
+			address = address[:index]
+		}
+	}
+
+	host := address
+	if h, p, err := net.SplitHostPort(address); err == nil {
+		host, port = h, p
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return result, fmt.Errorf("upstream %q is not an ipv4/ipv6 address", upstream)
+	}
+	if ip.IsMulticast() || ip.Equal(net.IPv4bcast) || ip.IsUnspecified() {
+		return result, fmt.Errorf("upstream %q is a multicast, broadcast or unspecified address", upstream)
+	}
+	if portNo, err := strconv.Atoi(port); err != nil || portNo <= 0 || portNo > util.MaxPortNumber {
+		return result, fmt.Errorf("upstream %q port not in valid range", upstream)
+	}
+	result.Address = net.JoinHostPort(ip.String(), port)
+	if result.TLS && len(result.ServerName) == 0 {
+		result.ServerName = ip.String()
+	}
+	return result, nil
+}
+
+// String returns the upstream in the parsed format
+func (u Upstream) String() string {
+	if u.TLS {
+		return tlsScheme + u.Address + "#" + u.ServerName
+	}
+	return u.Address
+}
+
+// UpstreamStats health and latency of an upstream
+type UpstreamStats struct {
+	Upstream            string  `json:"upstream"`
+	Healthy             bool    `json:"healthy"`
+	Requests            uint64  `json:"requests"`
+	Failures            uint64  `json:"failures"`
+	ConsecutiveFailures uint32  `json:"consecutiveFailures"`
+	AvgRTTMillis        float64 `json:"avgRttMillis"`
+}
+
+type upstreamState struct {
+	upstream Upstream
+	client   *dns.Client
+
+	mutex       sync.Mutex
+	requests    uint64
+	failures    uint64
+	consecutive uint32
+	lastFailure time.Time
+	avgRTT      time.Duration
+}
+
+// healthy reports the upstream healthy until the consecutive failures reach the limit, the unhealthy upstream is
+// tried again after the retry interval
+func (u *upstreamState) healthy(now time.Time) bool {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	return u.consecutive < util.MaxForwarderFailures || now.Sub(u.lastFailure) >= util.ForwarderRetryInterval
+}
+
+func (u *upstreamState) report(rtt time.Duration, err error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.requests++
+	if err != nil {
+		u.failures++
+		u.consecutive++
+		u.lastFailure = time.Now()
+		return
+	}
+	u.consecutive = 0
+	if u.avgRTT == 0 {
+		u.avgRTT = rtt
+	} else {
+		u.avgRTT = time.Duration(rttWeight*float64(rtt) + (1-rttWeight)*float64(u.avgRTT))
+	}
+}
+
+// Forwarder forwards the requests to the upstreams in the configured policy
+type Forwarder struct {
+	upstreams []*upstreamState
+	parallel  bool
+}
+
+// NewForwarder creates the forwarder of the upstreams, the timeout applies to each exchange with an upstream
+func NewForwarder(upstreams []Upstream, policy string, timeout time.Duration) *Forwarder {
+	f := &Forwarder{parallel: policy == PolicyParallel}
+	for _, upstream := range upstreams {
+		client := &dns.Client{Net: "udp", Timeout: timeout}
+		if upstream.TLS {
+			client = &dns.Client{Net: "tcp-tls", Timeout: timeout,
+				TLSConfig: &tls.Config{ServerName: upstream.ServerName, MinVersion: tls.VersionTLS12}}
+		}
+		f.upstreams = append(f.upstreams, &upstreamState{upstream: upstream, client: client})
+	}
+	return f
+}
+
+// Forward forwards the request and returns the first successful or NXDOMAIN response of the upstreams
+func (f *Forwarder) Forward(req *dns.Msg) (*dns.Msg, error) {
+	if f == nil || len(f.upstreams) == 0 {
+		return nil, fmt.Errorf("could not resolve the request %q and no forwarder is configured",
+			req.Question[0].Name)
+	}
+
+	candidates := f.candidates()
+	if f.parallel {
+		if rsp := f.forwardParallel(req, candidates); rsp != nil {
+			return rsp, nil
+		}
+	} else {
+		// Retry on failure, exchange will not retry on failure
+		attempts := len(candidates)
+		if attempts < util.ForwardRetryCount {
+			attempts = util.ForwardRetryCount
+		}
+		for i := 0; i < attempts; i++ {
+			if rsp, err := exchange(candidates[i%len(candidates)], req); err == nil {
+				return rsp, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("forward of request %q was not accepted", req.Question[0].Name)
+}
+
+// candidates returns the healthy upstreams in the configured order, all the upstreams if none is healthy
+func (f *Forwarder) candidates() []*upstreamState {
+	now := time.Now()
+	healthy := make([]*upstreamState, 0, len(f.upstreams))
+	for _, upstream := range f.upstreams {
+		if upstream.healthy(now) {
+			healthy = append(healthy, upstream)
+		}
+	}
+	if len(healthy) == 0 {
+		return f.upstreams
+	}
+	return healthy
+}
+
+func (f *Forwarder) forwardParallel(req *dns.Msg, candidates []*upstreamState) *dns.Msg {
+	responses := make(chan *dns.Msg, len(candidates))
+	for _, upstream := range candidates {
+		go func(upstream *upstreamState, req *dns.Msg) {
+			rsp, err := exchange(upstream, req)
+			if err != nil {
+				rsp = nil
+			}
+			responses <- rsp
+		}(upstream, req.Copy())
+	}
+	for range candidates {
+		if rsp := <-responses; rsp != nil {
+			return rsp
+		}
+	}
+	return nil
+}
+
+// exchange sends the request to the upstream, the truncated udp response is retried over tcp
+func exchange(upstream *upstreamState, req *dns.Msg) (*dns.Msg, error) {
+	rsp, rtt, err := upstream.client.Exchange(req, upstream.upstream.Address)
+	if err == nil && rsp.Truncated && upstream.client.Net == "udp" {
+		tcpClient := &dns.Client{Net: "tcp", Timeout: upstream.client.Timeout}
+		rsp, rtt, err = tcpClient.Exchange(req, upstream.upstream.Address)
+	}
+	if err == nil && rsp.Rcode != dns.RcodeSuccess && rsp.Rcode != dns.RcodeNameError {
+		err = fmt.Errorf("upstream %s answered %s", upstream.upstream, dns.RcodeToString[rsp.Rcode])
+	}
+	upstream.report(rtt, err)
+	return rsp, err
+}
+
+// Stats returns the health and latency of the upstreams
+func (f *Forwarder) Stats() []UpstreamStats {
+	if f == nil {
+		return []UpstreamStats{}
+	}
+	now := time.Now()
+	stats := make([]UpstreamStats, 0, len(f.upstreams))
+	for _, upstream := range f.upstreams {
+		healthy := upstream.healthy(now)
+		upstream.mutex.Lock()
+		stats = append(stats, UpstreamStats{Upstream: upstream.upstream.String(), Healthy: healthy,
+			Requests: upstream.requests, Failures: upstream.failures, ConsecutiveFailures: upstream.consecutive,
+			AvgRTTMillis: float64(upstream.avgRTT) / float64(time.Millisecond)})
+		upstream.mutex.Unlock()
+	}
+	return stats
+}
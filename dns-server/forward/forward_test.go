@@ -0,0 +1,127 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package forward
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+)
+
+const exampleDomain = "www.example.com."
+
+// startUpstream starts a local udp dns server answering with the rcode
+func startUpstream(t *testing.T, rcode int) (Upstream, func()) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	started := &sync.WaitGroup{}
+	started.Add(1)
+	server := &dns.Server{PacketConn: conn, NotifyStartedFunc: started.Done,
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+			rsp := new(dns.Msg)
+			rsp.SetRcode(req, rcode)
+			if rcode == dns.RcodeSuccess {
+				rsp.Answer = []dns.RR{&dns.A{Hdr: dns.RR_Header{Name: req.Question[0].Name, Rrtype: dns.TypeA,
+					Class: dns.ClassINET, Ttl: 30}, A: net.ParseIP("10.10.0.1")}}
+			}
+			_ = w.WriteMsg(rsp)
+		})}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	started.Wait()
+
+	upstream, err := ParseUpstream(conn.LocalAddr().String())
+	assert.Nil(t, err)
+	return upstream, func() { _ = server.Shutdown() }
+}
+
+func TestParseUpstream(t *testing.T) {
+	upstream, err := ParseUpstream("8.8.8.8")
+	assert.Nil(t, err)
+	assert.Equal(t, Upstream{Address: "8.8.8.8:53"}, upstream)
+
+	upstream, err = ParseUpstream("[2001:db8::53]:5353")
+	assert.Nil(t, err)
+	assert.Equal(t, "[2001:db8::53]:5353", upstream.String())
+
+	upstream, err = ParseUpstream("tls://1.1.1.1#cloudflare-dns.com")
+	assert.Nil(t, err)
+	assert.Equal(t, Upstream{Address: "1.1.1.1:853", TLS: true, ServerName: "cloudflare-dns.com"}, upstream)
+
+	upstream, err = ParseUpstream("tls://9.9.9.9:8853")
+	assert.Nil(t, err)
+	assert.Equal(t, "tls://9.9.9.9:8853#9.9.9.9", upstream.String())
+
+	for _, invalid := range []string{"", "dns.google", "224.0.0.1", "255.255.255.255", "0.0.0.0", "8.8.8.8:0",
+		"8.8.8.8:65536", "tls://"} {
+		_, err = ParseUpstream(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func TestForwardSequential(t *testing.T) {
+	failing, stopFailing := startUpstream(t, dns.RcodeServerFailure)
+	defer stopFailing()
+	working, stopWorking := startUpstream(t, dns.RcodeSuccess)
+	defer stopWorking()
+
+	f := NewForwarder([]Upstream{failing, working}, PolicySequential, time.Second)
+	req := new(dns.Msg)
+	req.SetQuestion(exampleDomain, dns.TypeA)
+	for i := 0; i < 4; i++ {
+		rsp, err := f.Forward(req)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(rsp.Answer))
+	}
+
+	// The failing upstream is skipped once unhealthy
+	stats := f.Stats()
+	assert.Equal(t, 2, len(stats))
+	assert.False(t, stats[0].Healthy)
+	assert.Equal(t, uint64(3), stats[0].Requests)
+	assert.Equal(t, uint32(3), stats[0].ConsecutiveFailures)
+	assert.True(t, stats[1].Healthy)
+	assert.Equal(t, uint64(4), stats[1].Requests)
+	assert.True(t, stats[1].AvgRTTMillis > 0)
+}
+
+func TestForwardParallel(t *testing.T) {
+	nxdomain, stopNXDomain := startUpstream(t, dns.RcodeNameError)
+	defer stopNXDomain()
+	refused, stopRefused := startUpstream(t, dns.RcodeRefused)
+	defer stopRefused()
+
+	f := NewForwarder([]Upstream{refused, nxdomain}, PolicyParallel, time.Second)
+	req := new(dns.Msg)
+	req.SetQuestion(exampleDomain, dns.TypeA)
+	rsp, err := f.Forward(req)
+	assert.Nil(t, err)
+	assert.Equal(t, dns.RcodeNameError, rsp.Rcode)
+
+	f = NewForwarder([]Upstream{refused}, PolicyParallel, time.Second)
+	_, err = f.Forward(req)
+	assert.EqualError(t, err, "forward of request \"www.example.com.\" was not accepted")
+
+	f = NewForwarder(nil, PolicyParallel, time.Second)
+	_, err = f.Forward(req)
+	assert.EqualError(t, err, "could not resolve the request \"www.example.com.\" and no forwarder is configured")
+}
@@ -2,14 +2,25 @@ module dns-server
 
 go 1.14
 
-replace k8s.io/client-go v2.0.0-alpha.0.0.20180817174322-745ca8300397+incompatible => github.com/kubernetes/client-go v0.0.0-20180817174322-745ca8300397
+replace (
+	k8s.io/client-go v2.0.0-alpha.0.0.20180817174322-745ca8300397+incompatible => github.com/kubernetes/client-go v0.0.0-20180817174322-745ca8300397
+	tracing => ../tracing
+)
 
 require (
 	github.com/agiledragon/gomonkey v2.0.1+incompatible
+	github.com/coreos/etcd v3.3.13+incompatible
+	github.com/gogo/protobuf v1.2.1 // indirect
+	github.com/golang/protobuf v1.3.2 // indirect
 	github.com/labstack/echo/v4 v4.1.16
 	github.com/miekg/dns v1.1.29
 	github.com/sirupsen/logrus v1.6.0
 	github.com/stretchr/testify v1.6.1
 	go.etcd.io/bbolt v1.3.4
+	golang.org/x/net v0.0.0-20200301022130-244492dfa37a // indirect
 	golang.org/x/sys v0.0.0-20200515095857-1151b9dac4a9 // indirect
+	golang.org/x/text v0.3.2 // indirect
+	google.golang.org/genproto v0.0.0-20190819201941-24fa4b261c55 // indirect
+	google.golang.org/grpc v1.20.1 // indirect
+	tracing v0.0.0
 )
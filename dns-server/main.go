@@ -18,31 +18,63 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"flag"
 	"fmt"
 	"net"
+	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"dns-server/datastore"
+	"dns-server/forward"
 	"dns-server/mgmt"
 	"dns-server/util"
+	"tracing"
 )
 
+// timeout of the export of the traced spans on the shutdown
+const tracingShutdownTimeout = 5 * time.Second
+
 // Input placeholder.
 type InputParameters struct {
-	dbName          *string // DB name placeholder
-	port            *uint   // dns port number
-	mgmtPort        *uint   // management interface port number
-	connTimeOut     *uint   // connection time out value
-	ipAddString     *string // dns listening ip
-	ipMgmtAddString *string // management interface listening ip
-	forwarder       *string // forwarder ip address
-	loadBalance     *bool   // need load balancing?
+	dbName          *string  // DB name placeholder
+	port            *uint    // dns port number
+	mgmtPort        *uint    // management interface port number
+	connTimeOut     *uint    // connection time out value
+	ipAddString     *string  // dns listening ip
+	ipMgmtAddString *string  // management interface listening ip
+	forwarder       *string  // comma separated upstream dns servers
+	loadBalance     *bool    // need load balancing?
+	tracingEndpoint *string  // otlp/http traces endpoint of the collector
+	zoneFile        *string  // master format zone file imported on start
+	zone            *string  // zone of the imported zone file
+	zoneFileMode    *string  // merge or replace the zone with the imported zone file
+	forwardPolicy   *string  // sequential or parallel forwarding to the upstreams
+	cacheSize       *uint    // number of the cached answers
+	cacheMaxTTL     *uint    // maximum ttl of the cached answers
+	clientSubnet    *bool    // select the views by the EDNS client subnet
+	querySampleRate *float64 // fraction of the logged queries
+	queryStatsFile  *string  // file persisting the query statistics
+	primary         *string  // management endpoint of the primary
+	notify          *string  // comma separated secondaries notified of the changes
+	primaryCA       *string  // ca certificate verifying the management api of the primary
+	mgmtCert        *string  // certificate serving the management api over https
+	mgmtKey         *string  // key of the management certificate
+	mgmtClientCA    *string  // ca certificate verifying the management clients
+	mgmtTokenFile   *string  // file of the management api bearer token
+	dbType          *string  // bolt, memory or etcd data store
+	etcdEndpoints   *string  // comma separated etcd endpoints of the etcd data store
+	etcdCA          *string  // ca certificate verifying the etcd endpoints
+	etcdCert        *string  // client certificate presented to etcd
+	etcdKey         *string  // key of the etcd client certificate
 }
 
 // Input flag parameters registration.
@@ -52,6 +84,13 @@ func registerInputParameters(inParam *InputParameters) {
 		return
 	}
 	inParam.dbName = flag.String("db", "dbEgDns", "Database name")
+	inParam.dbType = flag.String("dbType", datastore.TypeBolt,
+		"Data store, bolt file, memory or etcd shared by the dns servers under the key prefix of the database name")
+	inParam.etcdEndpoints = flag.String("etcdEndpoints", "",
+		"Comma separated etcd endpoints of the etcd data store, http(s)://host:port")
+	inParam.etcdCA = flag.String("etcdCA", "", "CA certificate file verifying the etcd endpoints over https")
+	inParam.etcdCert = flag.String("etcdCert", "", "Client certificate file presented to etcd")
+	inParam.etcdKey = flag.String("etcdKey", "", "Key file of the etcd client certificate")
 	inParam.port = flag.Uint("port", util.DefaultDNSPort, "Port number to listens to")
 	inParam.mgmtPort = flag.Uint("managementPort", util.DefaultManagementPort,
 		"Management interface port number to listens to")
@@ -60,8 +99,41 @@ func registerInputParameters(inParam *InputParameters) {
 	inParam.ipAddString = flag.String("ipAdd", util.DefaultIP, "Ipv4/Ipv6 address to listens to")
 	inParam.ipMgmtAddString = flag.String("managementIpAdd", util.DefaultIP,
 		"Management Ipv4/Ipv6 address to listens to")
-	inParam.forwarder = flag.String("forwarder", util.DefaultIP, "Forwarder")
-	inParam.loadBalance = flag.Bool("loadBalance", false, "Load balance using random shuffle")
+	inParam.forwarder = flag.String("forwarder", util.DefaultIP,
+		"Comma separated upstream dns servers, ip[:port] or tls://ip[:port][#server name] for DNS-over-TLS")
+	inParam.loadBalance = flag.Bool("loadBalance", false,
+		"Load balance the answers without weights using random shuffle")
+	inParam.tracingEndpoint = flag.String("tracingEndpoint", "",
+		"OTLP/HTTP traces endpoint of the collector, empty disables the tracing")
+	inParam.zoneFile = flag.String("zoneFile", "", "Master format(RFC 1035) zone file imported on start")
+	inParam.zone = flag.String("zone", datastore.DefaultZone, "Zone of the imported zone file")
+	inParam.zoneFileMode = flag.String("zoneFileMode", datastore.ZoneImportMerge,
+		"Import mode of the zone file, merge or replace the zone")
+	inParam.forwardPolicy = flag.String("forwardPolicy", forward.PolicySequential,
+		"Forwarding to the upstreams, sequential in the configured order or parallel")
+	inParam.cacheSize = flag.Uint("cacheSize", util.DefaultCacheSize,
+		"Number of the cached forwarded answers, 0 disables the cache")
+	inParam.cacheMaxTTL = flag.Uint("cacheMaxTTL", util.DefaultCacheMaxTTL,
+		"Maximum ttl of the cached answers in seconds")
+	inParam.clientSubnet = flag.Bool("clientSubnet", false,
+		"Select the views by the EDNS Client Subnet of the queries, enable only behind trusted resolvers")
+	inParam.querySampleRate = flag.Float64("querySampleRate", 0,
+		"Fraction(0~1) of the queries logged, 0 disables the query log")
+	inParam.queryStatsFile = flag.String("queryStatsFile", "",
+		"File persisting the query statistics periodically, empty keeps the statistics in memory")
+	inParam.primary = flag.String("primary", "",
+		"Management host:port of the primary, the data store is replicated from the primary as read only secondary")
+	inParam.notify = flag.String("notify", "",
+		"Comma separated secondary dns servers, ip[:port], notified of the changes")
+	inParam.primaryCA = flag.String("primaryCA", "",
+		"CA certificate file verifying the management api of the primary, the changes are pulled over https")
+	inParam.mgmtCert = flag.String("managementCert", "",
+		"Certificate file serving the management api over https, also presented to the primary")
+	inParam.mgmtKey = flag.String("managementKey", "", "Key file of the management certificate")
+	inParam.mgmtClientCA = flag.String("managementClientCA", "",
+		"CA certificate file verifying the client certificates of the management api(mutual TLS)")
+	inParam.mgmtTokenFile = flag.String("managementTokenFile", "",
+		"File of the bearer token required by the management api, also sent to the primary")
 
 	flag.Parse()
 }
@@ -79,6 +151,36 @@ func validateInputAndGenerateConfig(inParam *InputParameters) *Config {
 		log.Fatalf("Failed to parse db name(%s). %s", *inParam.dbName, err.Error())
 	}
 
+	// Validate data store
+	var etcdEndpoints []string
+	var etcdTLS *tls.Config
+	switch *inParam.dbType {
+	case datastore.TypeBolt, datastore.TypeMemory:
+		if len(*inParam.etcdEndpoints) != 0 || len(*inParam.etcdCA) != 0 {
+			log.Fatalf("Etcd settings require the etcd data store.")
+		}
+	case datastore.TypeEtcd:
+		for _, endpoint := range strings.Split(*inParam.etcdEndpoints, ",") {
+			endpoint = strings.TrimSpace(endpoint)
+			endpointURL, err := url.Parse(endpoint)
+			if err != nil || (endpointURL.Scheme != "http" && endpointURL.Scheme != "https") ||
+				len(endpointURL.Host) == 0 {
+				log.Fatalf("Failed to parse etcd endpoint(%s).", endpoint)
+			}
+			etcdEndpoints = append(etcdEndpoints, endpoint)
+		}
+		if len(*inParam.etcdCA) != 0 {
+			var err error
+			if etcdTLS, err = mgmt.ClientTLSConfig(*inParam.etcdCA, *inParam.etcdCert, *inParam.etcdKey); err != nil {
+				log.Fatalf("Failed to load the etcd ca(%s).", err.Error())
+			}
+		} else if len(*inParam.etcdCert) != 0 {
+			log.Fatalf("Etcd client certificate requires the etcd ca.")
+		}
+	default:
+		log.Fatalf("Invalid data store type(%s).", *inParam.dbType)
+	}
+
 	// Validate DNS port range
 	if *inParam.port > util.MaxPortNumber || *inParam.port == 0 {
 		err := fmt.Errorf("error: port number not in valid range")
@@ -125,26 +227,134 @@ func validateInputAndGenerateConfig(inParam *InputParameters) *Config {
 		log.Fatalf("Multicast or broadcast ip address(%s). %s", *inParam.ipMgmtAddString, err.Error())
 	}
 
-	// Validate forwarder
-	forwarderAdd := net.ParseIP(*inParam.forwarder)
-	if forwarderAdd == nil {
-		err := fmt.Errorf("error: parsing forwarder failed, not in ipv4/ipv6 format")
-		log.Fatalf("Failed to parse forwarder address(%s). %s", *inParam.forwarder, err.Error())
+	// Validate forwarders, the default ip disables the forwarding
+	var forwarders []forward.Upstream
+	if *inParam.forwarder != util.DefaultIP {
+		for _, upstream := range strings.Split(*inParam.forwarder, ",") {
+			forwarder, err := forward.ParseUpstream(strings.TrimSpace(upstream))
+			if err != nil {
+				log.Fatalf("Failed to parse forwarder address(%s). %s", upstream, err.Error())
+			}
+			forwarders = append(forwarders, forwarder)
+		}
+	}
+	if *inParam.forwardPolicy != forward.PolicySequential && *inParam.forwardPolicy != forward.PolicyParallel {
+		log.Fatalf("Invalid forward policy(%s).", *inParam.forwardPolicy)
 	}
 
-	if forwarderAdd != nil && (forwarderAdd.IsMulticast() || forwarderAdd.Equal(net.IPv4bcast)) {
-		err := fmt.Errorf("error: multicast or broadcast ip address ")
-		log.Fatalf("Multicast or broadcast ip address(%s). %s", *inParam.forwarder, err.Error())
+	// Validate query log sampling
+	if *inParam.querySampleRate < 0 || *inParam.querySampleRate > 1 {
+		log.Fatalf("Query sample rate not in valid range(0~1).")
+	}
+
+	// Validate replication
+	if len(*inParam.primary) != 0 {
+		host, portStr, err := net.SplitHostPort(*inParam.primary)
+		port, portErr := strconv.Atoi(portStr)
+		if err != nil || len(host) == 0 || portErr != nil || port <= 0 || port > util.MaxPortNumber {
+			log.Fatalf("Failed to parse primary management endpoint(%s).", *inParam.primary)
+		}
+	}
+	var secondaries []string
+	if len(*inParam.notify) != 0 {
+		for _, secondary := range strings.Split(*inParam.notify, ",") {
+			upstream, err := forward.ParseUpstream(strings.TrimSpace(secondary))
+			if err != nil || upstream.TLS {
+				log.Fatalf("Failed to parse secondary address(%s).", secondary)
+			}
+			secondaries = append(secondaries, upstream.Address)
+		}
+	}
+
+	// Validate management api security
+	var mgmtTLS *tls.Config
+	if len(*inParam.mgmtCert) != 0 || len(*inParam.mgmtKey) != 0 {
+		var err error
+		if mgmtTLS, err = mgmt.ServerTLSConfig(*inParam.mgmtCert, *inParam.mgmtKey, *inParam.mgmtClientCA); err != nil {
+			log.Fatalf("Failed to load the management certificate(%s).", err.Error())
+		}
+	} else if len(*inParam.mgmtClientCA) != 0 {
+		log.Fatalf("Management client ca requires the management certificate.")
+	}
+	var mgmtToken string
+	if len(*inParam.mgmtTokenFile) != 0 {
+		var err error
+		if mgmtToken, err = mgmt.ReadToken(*inParam.mgmtTokenFile); err != nil {
+			log.Fatalf("Failed to read the management token(%s).", err.Error())
+		}
+	}
+	var primaryTLS *tls.Config
+	if len(*inParam.primaryCA) != 0 {
+		if len(*inParam.primary) == 0 {
+			log.Fatalf("Primary ca requires the primary management endpoint.")
+		}
+		var err error
+		if primaryTLS, err = mgmt.ClientTLSConfig(*inParam.primaryCA, *inParam.mgmtCert, *inParam.mgmtKey); err != nil {
+			log.Fatalf("Failed to load the primary ca(%s).", err.Error())
+		}
+	}
+
+	// Validate tracing endpoint
+	tracingEndpoint := *inParam.tracingEndpoint
+	if len(tracingEndpoint) != 0 {
+		tracingURL, err := url.Parse(tracingEndpoint)
+		if err != nil || (tracingURL.Scheme != "http" && tracingURL.Scheme != "https") || len(tracingURL.Host) == 0 {
+			log.Fatalf("Failed to parse tracing endpoint(%s).", tracingEndpoint)
+		}
+	}
+
+	// Validate zone file import
+	if len(*inParam.zoneFile) != 0 {
+		if _, err := os.Stat(*inParam.zoneFile); err != nil {
+			log.Fatalf("Failed to read the zone file(%s).", *inParam.zoneFile)
+		}
+		if len(*inParam.zone) == 0 || len(*inParam.zone) >= util.MaxDNSFQDNLength {
+			log.Fatalf("Invalid zone of the zone file(%s).", *inParam.zone)
+		}
+		if *inParam.zoneFileMode != datastore.ZoneImportMerge && *inParam.zoneFileMode != datastore.ZoneImportReplace {
+			log.Fatalf("Invalid zone file import mode(%s).", *inParam.zoneFileMode)
+		}
 	}
 
 	return &Config{dbName: *inParam.dbName,
+		dbType:            *inParam.dbType,
+		etcdEndpoints:     etcdEndpoints,
+		etcdTLS:           etcdTLS,
 		port:              *inParam.port,
 		mgmtPort:          *inParam.mgmtPort,
 		ipAdd:             ipAdd,
 		ipMgmtAdd:         ipMgmtAdd,
 		connectionTimeout: *inParam.connTimeOut,
-		forwarder:         forwarderAdd,
+		forwarders:        forwarders,
+		forwardPolicy:     *inParam.forwardPolicy,
+		cacheSize:         *inParam.cacheSize,
+		cacheMaxTTL:       *inParam.cacheMaxTTL,
+		clientSubnet:      *inParam.clientSubnet,
+		querySampleRate:   *inParam.querySampleRate,
+		queryStatsFile:    *inParam.queryStatsFile,
+		primary:           *inParam.primary,
+		secondaries:       secondaries,
+		primaryTLS:        primaryTLS,
+		mgmtTLS:           mgmtTLS,
+		mgmtToken:         mgmtToken,
 		loadBalance:       *inParam.loadBalance,
+		tracingEndpoint:   tracingEndpoint,
+		zoneFile:          *inParam.zoneFile,
+		zone:              *inParam.zone,
+		zoneFileMode:      *inParam.zoneFileMode,
+	}
+}
+
+// newDataStore creates the data store of the type, the etcd keys are prefixed by the database name
+func newDataStore(config *Config) datastore.DataStore {
+	switch config.dbType {
+	case datastore.TypeMemory:
+		return &datastore.MemDB{TTL: util.DefaultTTL}
+	case datastore.TypeEtcd:
+		return &datastore.EtcdDB{Endpoints: config.etcdEndpoints, Prefix: util.EtcdKeyPrefix + config.dbName + "/",
+			TLS: config.etcdTLS, TTL: util.DefaultTTL}
+	default:
+		return &datastore.BoltDB{FileName: config.dbName, TTL: util.DefaultTTL}
 	}
 }
 
@@ -155,6 +365,11 @@ func waitForSignal() {
 		select {
 		case s := <-sig:
 			log.Infof("Signal(%d) received, stopping dns server\n", s)
+			ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+			if err := tracing.Shutdown(ctx); err != nil {
+				log.Warn("Failed to export the queued spans on the shutdown.")
+			}
+			cancel()
 			os.Exit(0)
 		}
 	}
@@ -169,9 +384,20 @@ func main() {
 
 	config := validateInputAndGenerateConfig(inputParam)
 
-	store := &datastore.BoltDB{FileName: config.dbName, TTL: util.DefaultTTL}
+	store := newDataStore(config)
+	if len(config.tracingEndpoint) != 0 {
+		tracing.SetLogger(log.Warnf)
+		tracing.Start(tracing.Options{ServiceName: "dns-server", Endpoint: config.tracingEndpoint})
+	}
 	mgmtCtl := &mgmt.Controller{}
 	dnsServer := NewServer(config, store, mgmtCtl)
+	mgmtCtl.Resolver = dnsServer
+	mgmtCtl.Balancer = dnsServer
+	mgmtCtl.QueryStats = dnsServer
+	mgmtCtl.Replication = dnsServer
+	mgmtCtl.ReadOnly = len(config.primary) != 0
+	mgmtCtl.TLSConfig = config.mgmtTLS
+	mgmtCtl.Token = config.mgmtToken
 
 	defer dnsServer.Stop()
 	if err := dnsServer.Run(); err != nil {
@@ -26,6 +26,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"dns-server/datastore"
+	"dns-server/forward"
 	"dns-server/mgmt"
 	"dns-server/util"
 )
@@ -38,6 +39,28 @@ var ipAddString = util.DefaultIP
 var ipMgmtAddString = util.DefaultIP
 var forwarder = util.DefaultIP
 var loadBalance = false
+var tracingEndpoint = ""
+var zoneFile = ""
+var zone = datastore.DefaultZone
+var zoneFileMode = datastore.ZoneImportMerge
+var forwardPolicy = forward.PolicySequential
+var cacheSize uint = util.DefaultCacheSize
+var cacheMaxTTL uint = util.DefaultCacheMaxTTL
+var clientSubnet = false
+var querySampleRate float64 = 0
+var queryStatsFile = ""
+var primary = ""
+var notify = ""
+var primaryCA = ""
+var mgmtCert = ""
+var mgmtKey = ""
+var mgmtClientCA = ""
+var mgmtTokenFile = ""
+var dbType = datastore.TypeBolt
+var etcdEndpoints = ""
+var etcdCA = ""
+var etcdCert = ""
+var etcdKey = ""
 var ePanic = "Panic expected"
 var eError = "Error expected"
 var panicProblem = "a problem"
@@ -93,7 +116,10 @@ func TestMainDnsServer(t *testing.T) {
 		}()
 		var invalidPortNo uint = 0
 		parameters := InputParameters{&dbName, &port, &invalidPortNo, &connTimeOut,
-			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance}
+			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -104,6 +130,28 @@ func TestMainDnsServer(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -122,7 +170,10 @@ func TestMainDnsServer(t *testing.T) {
 		}()
 		var invalidPortNo uint = 65536
 		parameters := InputParameters{&dbName, &port, &invalidPortNo, &connTimeOut,
-			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance}
+			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -133,6 +184,28 @@ func TestMainDnsServer(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -191,7 +264,10 @@ func TestMainDnsServer1(t *testing.T) {
 		}()
 		var invalidIpAdd = "127.0.0.256"
 		parameters := InputParameters{&dbName, &port, &mgmtPort, &connTimeOut,
-			&ipAddString, &ipMgmtAddString, &invalidIpAdd, &loadBalance}
+			&ipAddString, &ipMgmtAddString, &invalidIpAdd, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -202,6 +278,28 @@ func TestMainDnsServer1(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -220,7 +318,10 @@ func TestMainDnsServer1(t *testing.T) {
 			}
 		}()
 		parameters := InputParameters{&dbName, &port, &port, &connTimeOut,
-			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance}
+			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -231,6 +332,28 @@ func TestMainDnsServer1(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -290,7 +413,10 @@ func TestMainDnsServer2(t *testing.T) {
 
 		var invalidDbName = "test.db"
 		parameters := InputParameters{&invalidDbName, &port, &mgmtPort, &connTimeOut,
-			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance}
+			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -301,6 +427,28 @@ func TestMainDnsServer2(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -320,7 +468,10 @@ func TestMainDnsServer2(t *testing.T) {
 		}()
 		var invalidIpAdd = "127.0.0.256"
 		parameters := InputParameters{&dbName, &port, &mgmtPort, &connTimeOut,
-			&ipAddString, &invalidIpAdd, &forwarder, &loadBalance}
+			&ipAddString, &invalidIpAdd, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -331,6 +482,28 @@ func TestMainDnsServer2(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -389,7 +562,10 @@ func TestMainDnsServer3(t *testing.T) {
 		}()
 		var invalidIpAdd = "128.15.47.299"
 		parameters := InputParameters{&dbName, &port, &mgmtPort, &connTimeOut,
-			&invalidIpAdd, &ipMgmtAddString, &forwarder, &loadBalance}
+			&invalidIpAdd, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -400,6 +576,28 @@ func TestMainDnsServer3(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -418,7 +616,10 @@ func TestMainDnsServer3(t *testing.T) {
 		}()
 		var invalidIpAdd = "1::2lkh"
 		parameters := InputParameters{&dbName, &port, &mgmtPort, &connTimeOut,
-			&invalidIpAdd, &ipMgmtAddString, &forwarder, &loadBalance}
+			&invalidIpAdd, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -429,6 +630,28 @@ func TestMainDnsServer3(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -487,7 +710,10 @@ func TestMainDnsServer4(t *testing.T) {
 		}()
 		var invalidIpAdd = ""
 		parameters := InputParameters{&dbName, &port, &mgmtPort, &connTimeOut,
-			&invalidIpAdd, &ipMgmtAddString, &forwarder, &loadBalance}
+			&invalidIpAdd, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -498,6 +724,28 @@ func TestMainDnsServer4(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -516,7 +764,10 @@ func TestMainDnsServer4(t *testing.T) {
 		}()
 		var invalidIpAdd = "a"
 		parameters := InputParameters{&dbName, &port, &mgmtPort, &connTimeOut,
-			&invalidIpAdd, &ipMgmtAddString, &forwarder, &loadBalance}
+			&invalidIpAdd, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -527,6 +778,28 @@ func TestMainDnsServer4(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -581,7 +854,10 @@ func TestMainDnsServer5(t *testing.T) {
 			}
 		}()
 		parameters := InputParameters{&dbName, &port, &mgmtPort, &connTimeOut,
-			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance}
+			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -592,6 +868,28 @@ func TestMainDnsServer5(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -615,7 +913,10 @@ func TestMainDnsServer5(t *testing.T) {
 			"qwertyuiopqwertyuiopqwertyuiopqwertyuiopqwertyuiopqwertyuiopqwertyuiop"
 
 		parameters := InputParameters{&invalidDbName, &port, &mgmtPort, &connTimeOut,
-			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance}
+			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -626,6 +927,28 @@ func TestMainDnsServer5(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -684,7 +1007,10 @@ func TestMainDnsServer6(t *testing.T) {
 		}()
 		var invalidPortNo uint = 0
 		parameters := InputParameters{&dbName, &invalidPortNo, &mgmtPort, &connTimeOut,
-			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance}
+			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -695,6 +1021,28 @@ func TestMainDnsServer6(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -714,7 +1062,10 @@ func TestMainDnsServer6(t *testing.T) {
 		}()
 		var invalidPortNo uint = 65536
 		parameters := InputParameters{&dbName, &invalidPortNo, &mgmtPort, &connTimeOut,
-			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance}
+			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -725,6 +1076,28 @@ func TestMainDnsServer6(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
@@ -784,7 +1157,10 @@ func TestMainDnsServer7(t *testing.T) {
 		}()
 		var invalidConnT uint = 0
 		parameters := InputParameters{&dbName, &port, &mgmtPort, &invalidConnT,
-			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance}
+			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &dbType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
 
 		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
 			inParam.dbName = parameters.dbName
@@ -795,6 +1171,83 @@ func TestMainDnsServer7(t *testing.T) {
 			inParam.ipMgmtAddString = parameters.ipMgmtAddString
 			inParam.forwarder = parameters.forwarder
 			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
+			return
+		})
+		defer patch5.Reset()
+
+		main()
+	})
+
+	t.Run("InvalidDataStoreType", func(t *testing.T) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				t.Errorf("%s", ePanic)
+			}
+			if r != panicProblem {
+				t.Errorf("%s %v", panicSting, r)
+			}
+		}()
+		var invalidDBType = "mysql"
+		parameters := InputParameters{&dbName, &port, &mgmtPort, &connTimeOut,
+			&ipAddString, &ipMgmtAddString, &forwarder, &loadBalance, &tracingEndpoint,
+			&zoneFile, &zone, &zoneFileMode, &forwardPolicy, &cacheSize, &cacheMaxTTL, &clientSubnet,
+			&querySampleRate, &queryStatsFile, &primary, &notify, &primaryCA, &mgmtCert, &mgmtKey, &mgmtClientCA,
+			&mgmtTokenFile, &invalidDBType, &etcdEndpoints, &etcdCA, &etcdCert, &etcdKey}
+
+		patch5 := gomonkey.ApplyFunc(registerInputParameters, func(inParam *InputParameters) {
+			inParam.dbName = parameters.dbName
+			inParam.port = parameters.port
+			inParam.mgmtPort = parameters.mgmtPort
+			inParam.connTimeOut = parameters.connTimeOut
+			inParam.ipAddString = parameters.ipAddString
+			inParam.ipMgmtAddString = parameters.ipMgmtAddString
+			inParam.forwarder = parameters.forwarder
+			inParam.loadBalance = parameters.loadBalance
+			inParam.tracingEndpoint = parameters.tracingEndpoint
+			inParam.zoneFile = parameters.zoneFile
+			inParam.zone = parameters.zone
+			inParam.zoneFileMode = parameters.zoneFileMode
+			inParam.forwardPolicy = parameters.forwardPolicy
+			inParam.cacheSize = parameters.cacheSize
+			inParam.cacheMaxTTL = parameters.cacheMaxTTL
+			inParam.clientSubnet = parameters.clientSubnet
+			inParam.querySampleRate = parameters.querySampleRate
+			inParam.queryStatsFile = parameters.queryStatsFile
+			inParam.primary = parameters.primary
+			inParam.notify = parameters.notify
+			inParam.primaryCA = parameters.primaryCA
+			inParam.mgmtCert = parameters.mgmtCert
+			inParam.mgmtKey = parameters.mgmtKey
+			inParam.mgmtClientCA = parameters.mgmtClientCA
+			inParam.mgmtTokenFile = parameters.mgmtTokenFile
+			inParam.dbType = parameters.dbType
+			inParam.etcdEndpoints = parameters.etcdEndpoints
+			inParam.etcdCA = parameters.etcdCA
+			inParam.etcdCert = parameters.etcdCert
+			inParam.etcdKey = parameters.etcdKey
 			return
 		})
 		defer patch5.Reset()
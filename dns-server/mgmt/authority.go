@@ -0,0 +1,53 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/util"
+)
+
+// ZoneAuthority the authority setting of a zone
+type ZoneAuthority struct {
+	Authoritative *bool `json:"authoritative"`
+}
+
+// handleSetZoneAuthority marks the zone authoritative, the names inside an authoritative zone are answered locally
+// with NXDOMAIN/NODATA instead of being forwarded
+func (e *Controller) handleSetZoneAuthority(c echo.Context) error {
+	zone := c.Param("zone")
+	if len(zone) == 0 || len(zone) >= util.MaxDNSFQDNLength {
+		return c.String(http.StatusBadRequest, "invalid input parameters!")
+	}
+
+	authority := new(ZoneAuthority)
+	if err := c.Bind(authority); err != nil || authority.Authoritative == nil {
+		return c.String(http.StatusBadRequest, "invalid input parameters!")
+	}
+
+	if err := e.dataStore.SetZoneAuthoritative(zone, *authority.Authoritative); err != nil {
+		log.Errorf("Failed to set the authority of the zone %s(%s).", zone, err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	log.Infof("Zone %s authoritative: %t.", zone, *authority.Authoritative)
+
+	return c.String(http.StatusOK, "success in setting the zone authority.")
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/datastore"
+)
+
+func TestSetZoneAuthority(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+
+	store := &datastore.BoltDB{FileName: "testauthoritydb", TTL: 30}
+	err := store.Open()
+	assert.Equal(t, nil, err, "Error in opening the db")
+	defer store.Close()
+	mgmtCtl := &Controller{dataStore: store}
+
+	request := func(zone string, body string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPut, "/mep/dns_server_mgmt/v1/zones/"+zone, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		recorder := httptest.NewRecorder()
+		c := e.NewContext(req, recorder)
+		c.SetParamNames("zone")
+		c.SetParamValues(zone)
+		assert.Equal(t, nil, mgmtCtl.handleSetZoneAuthority(c), "Error")
+		return recorder
+	}
+
+	recorder := request("example.com.", `{"authoritative": true}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	soa, _, err := store.GetAuthority("www.example.com.")
+	assert.Equal(t, nil, err, "Error in reading the authority")
+	assert.NotEqual(t, nil, soa, "Zone is not authoritative")
+
+	assert.Equal(t, http.StatusBadRequest, request("example.com.", `{}`).Code)
+	assert.Equal(t, http.StatusBadRequest, request(".", `{"authoritative": true}`).Code)
+
+	recorder = request("example.com.", `{"authoritative": false}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	soa, _, _ = store.GetAuthority("www.example.com.")
+	assert.Equal(t, nil, soa, "Zone is still authoritative")
+}
@@ -18,6 +18,7 @@
 package mgmt
 
 import (
+	"crypto/tls"
 	"fmt"
 	"net"
 	"net/http"
@@ -27,10 +28,31 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"dns-server/datastore"
+	"dns-server/replication"
 	"dns-server/util"
 )
 
+// zoneFilePath the path of the master format zone file import and export
+const zoneFilePath = "/mep/dns_server_mgmt/v1/zones/:zone/file"
+
+// healthPath the path of the health check
+const healthPath = "/health"
+
 type Controller struct {
+	// Resolver exposes the answer cache and forwarder statistics, nil disables the statistics
+	Resolver Resolver
+	// Balancer exposes the health state of the load balanced addresses, nil disables the health state
+	Balancer LoadBalancer
+	// QueryStats exposes the query statistics, nil disables the statistics
+	QueryStats QueryStats
+	// Replication exposes the replication state, nil disables the state
+	Replication Replication
+	// ReadOnly rejects the updates of the replicated records, zones and views on the secondaries
+	ReadOnly bool
+	// TLSConfig serves the management api over https, nil serves plain http
+	TLSConfig *tls.Config
+	// Token bearer token required on the management requests, empty disables the token authentication
+	Token     string
 	dataStore datastore.DataStore
 	echo      *echo.Echo
 }
@@ -42,18 +64,55 @@ func (e *Controller) StartController(store *datastore.DataStore, ipAddr net.IP,
 	// Middleware
 	e.echo.Use(middleware.Logger())
 	e.echo.Use(middleware.Recover())
-	e.echo.Use(middleware.BodyLimit(util.MaxPacketSize))
+	e.echo.Use(tracingMiddleware)
+	e.echo.Use(e.authMiddleware)
+	e.echo.Use(e.readOnlyMiddleware)
+	e.echo.Use(middleware.BodyLimitWithConfig(middleware.BodyLimitConfig{Limit: util.MaxPacketSize,
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == zoneFilePath
+		}}))
 
 	// Routes
 	e.echo.POST("/mep/dns_server_mgmt/v1/rrecord", e.handleAddResourceRecords)
 	e.echo.PUT("/mep/dns_server_mgmt/v1/rrecord/:fqdn/:rrtype", e.handleSetResourceRecords)
 	e.echo.DELETE("/mep/dns_server_mgmt/v1/rrecord/:fqdn/:rrtype", e.handleDeleteResourceRecord)
-	e.echo.GET("/health", e.handleHealthResult)
+	e.echo.GET("/mep/dns_server_mgmt/v1/rrecord", e.handleListResourceRecords)
+	e.echo.GET("/mep/dns_server_mgmt/v1/rrecord/:fqdn/:rrtype", e.handleGetResourceRecord)
+	e.echo.GET("/mep/dns_server_mgmt/v1/zones", e.handleListZones)
+	e.echo.GET("/mep/dns_server_mgmt/v1/zones/:zone", e.handleExportZone)
+	e.echo.PUT("/mep/dns_server_mgmt/v1/zones/:zone", e.handleSetZoneAuthority)
+	e.echo.GET(zoneFilePath, e.handleExportZoneFile)
+	e.echo.PUT(zoneFilePath, e.handleImportZoneFile, middleware.BodyLimit(util.MaxZoneFileSize))
+	e.echo.POST("/mep/dns_server_mgmt/v1/views", e.handleAddView)
+	e.echo.GET("/mep/dns_server_mgmt/v1/views", e.handleListViews)
+	e.echo.GET("/mep/dns_server_mgmt/v1/views/:view", e.handleGetView)
+	e.echo.PUT("/mep/dns_server_mgmt/v1/views/:view", e.handleSetView)
+	e.echo.DELETE("/mep/dns_server_mgmt/v1/views/:view", e.handleDeleteView)
+	e.echo.GET("/mep/dns_server_mgmt/v1/cache", e.handleGetCacheStats)
+	e.echo.DELETE("/mep/dns_server_mgmt/v1/cache", e.handleFlushCache)
+	e.echo.GET("/mep/dns_server_mgmt/v1/forwarders", e.handleGetForwarderStats)
+	e.echo.GET("/mep/dns_server_mgmt/v1/healthchecks", e.handleGetTargetHealth)
+	e.echo.GET("/mep/dns_server_mgmt/v1/stats/queries", e.handleGetQuerySummary)
+	e.echo.DELETE("/mep/dns_server_mgmt/v1/stats/queries", e.handleResetQueryStats)
+	e.echo.GET("/mep/dns_server_mgmt/v1/stats/names", e.handleGetTopNames)
+	e.echo.GET("/mep/dns_server_mgmt/v1/replication", e.handleGetReplicationStatus)
+	e.echo.GET(replication.ChangesPath, e.handleGetChanges)
+	e.echo.GET(replication.SnapshotPath, e.handleGetSnapshot)
+	e.echo.GET(healthPath, e.handleHealthResult)
 
 	e.dataStore = *store
 
 	// Start server
-	e.echo.Logger.Fatal(e.echo.Start(fmt.Sprintf("%s:%d", ipAddr.String(), port)))
+	address := fmt.Sprintf("%s:%d", ipAddr.String(), port)
+	var err error
+	if e.TLSConfig != nil {
+		e.echo.TLSServer.Addr = address
+		e.echo.TLSServer.TLSConfig = e.TLSConfig
+		err = e.echo.StartServer(e.echo.TLSServer)
+	} else {
+		err = e.echo.Start(address)
+	}
+	e.echo.Logger.Fatal(err)
 }
 
 func (e *Controller) StopController() error {
@@ -190,6 +249,12 @@ func (e *Controller) validateSetRecordInput(zone string, rr *datastore.ResourceR
 		return err
 	}
 
+	if len(rr.View) != 0 {
+		if _, err := e.dataStore.GetView(rr.View); err != nil {
+			return fmt.Errorf("view(%s) not found", rr.View)
+		}
+	}
+
 	return nil
 }
 
@@ -200,6 +265,12 @@ func (e *Controller) validateResourceRecords(rr *datastore.ResourceRecord) error
 		len(rr.Class) == 0 || len(rr.Class) > util.MaxDNSFQDNLength {
 		return fmt.Errorf("invalid resource record value")
 	}
+	if rr.Type != "A" && rr.Type != "AAAA" {
+		if len(rr.Weights) != 0 || rr.HealthCheck != nil {
+			return fmt.Errorf("load balancing is supported only on A/AAAA records")
+		}
+		return datastore.ValidateRecordData(rr.Type, rr.RData)
+	}
 	for _, rData := range rr.RData {
 		mgmtIP := net.ParseIP(rData)
 		if len(rData) == 0 ||
@@ -209,15 +280,17 @@ func (e *Controller) validateResourceRecords(rr *datastore.ResourceRecord) error
 		}
 	}
 
-	return nil
+	return datastore.ValidateLoadBalancing(rr)
 }
 
 func (e *Controller) handleDeleteResourceRecord(c echo.Context) error {
 	zone := c.QueryParam("zone")
+	view := c.QueryParam("view")
 	fqdn := c.Param("fqdn")
 	rrtype := c.Param("rrtype")
 
-	if len(fqdn) == 0 || len(rrtype) == 0 || len(zone) >= util.MaxDNSFQDNLength {
+	if len(fqdn) == 0 || len(rrtype) == 0 || len(zone) >= util.MaxDNSFQDNLength ||
+		len(view) > util.MaxViewNameLength {
 		return c.String(http.StatusBadRequest, "invalid input parameters!")
 	}
 
@@ -225,7 +298,7 @@ func (e *Controller) handleDeleteResourceRecord(c echo.Context) error {
 		zone = "."
 	}
 
-	err := e.dataStore.DelResourceRecord(zone, fqdn, rrtype)
+	err := e.dataStore.DelResourceRecord(zone, fqdn, rrtype, view)
 	if err != nil {
 		log.Error("Failed to Delete Resource.", nil)
 		return c.String(http.StatusInternalServerError, "Error in retrieving the data.")
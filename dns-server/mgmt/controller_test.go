@@ -31,6 +31,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"dns-server/datastore"
+	"dns-server/util"
 )
 
 // Query dns rules request in mp1 interface
@@ -92,13 +93,13 @@ func TestRestControllerOperations(t *testing.T) {
 		assert.Equal(t, nil, err, "Error")
 
 		rrResponse, _ := store.GetResourceRecord(&dns.Question{Name: eg,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, rr_eg100, (*rrResponse)[0].String(),
 			"Error")
 
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
-		err = store.DelResourceRecord("", eg1, "A")
+		err = store.DelResourceRecord("", eg1, "A", "")
 		assert.NotEqual(t, nil, err, errRecord)
 	})
 
@@ -121,12 +122,12 @@ func TestRestControllerOperations(t *testing.T) {
 		assert.Equal(t, nil, err, "Error")
 
 		// zone name given as record
-		err = store.DelResourceRecord("", "www.example.org.", "A")
+		err = store.DelResourceRecord("", "www.example.org.", "A", "")
 		assert.NotEqual(t, nil, err, errRecord)
 
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
-		err = store.DelResourceRecord("", eg1, "A")
+		err = store.DelResourceRecord("", eg1, "A", "")
 		assert.Equal(t, nil, err, errRecord)
 	})
 
@@ -149,18 +150,18 @@ func TestRestControllerOperations(t *testing.T) {
 		assert.Equal(t, nil, err, "Error")
 
 		rrResponse, _ := store.GetResourceRecord(&dns.Question{Name: eg,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, rr_eg100, (*rrResponse)[0].String(),
 			"Error")
 
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: eg1,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, rr_eg101, (*rrResponse)[0].String(),
 			"Error")
 
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
-		err = store.DelResourceRecord("", eg1, "A")
+		err = store.DelResourceRecord("", eg1, "A", "")
 		assert.Equal(t, nil, err, errRecord)
 	})
 
@@ -183,24 +184,24 @@ func TestRestControllerOperations(t *testing.T) {
 		assert.Equal(t, nil, err, "Error")
 
 		rrResponse, _ := store.GetResourceRecord(&dns.Question{Name: eg,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, "www.example.com.\t30\tIN\tA\t172.168.15.100", (*rrResponse)[0].String(),
 			"Error")
 
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: eg1,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, "www.example1.com.\t30\tIN\tA\t172.168.15.101", (*rrResponse)[0].String(),
 			"Error")
 
 		// zone name given as record
-		err = store.DelResourceRecord("", ".", "A")
+		err = store.DelResourceRecord("", ".", "A", "")
 		assert.NotEqual(t, nil, err, errRecord)
-		err = store.DelResourceRecord("", "com.", "A")
+		err = store.DelResourceRecord("", "com.", "A", "")
 		assert.NotEqual(t, nil, err, errRecord)
 
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
-		err = store.DelResourceRecord("", eg1, "A")
+		err = store.DelResourceRecord("", eg1, "A", "")
 		assert.Equal(t, nil, err, errRecord)
 	})
 
@@ -227,29 +228,29 @@ func TestRestControllerOperations(t *testing.T) {
 		assert.Equal(t, nil, err, "Error")
 
 		rrResponse, _ := store.GetResourceRecord(&dns.Question{Name: eg,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, eg172, (*rrResponse)[0].String(),
 			"Error")
 
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: egOrg,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, rr_eg102, (*rrResponse)[0].String(),
 			"Error")
 
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: eg,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, "www.example.com.\t30\tIN\tA\t172.168.15.100", (*rrResponse)[0].String(),
 			"Error")
 
 		// zone name given as record
-		err = store.DelResourceRecord("invalid", ".", "A")
+		err = store.DelResourceRecord("invalid", ".", "A", "")
 		assert.NotEqual(t, nil, err, errRecord)
-		err = store.DelResourceRecord("", "org.", "A")
+		err = store.DelResourceRecord("", "org.", "A", "")
 		assert.NotEqual(t, nil, err, errRecord)
 
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
-		err = store.DelResourceRecord("org", egOrg, "A")
+		err = store.DelResourceRecord("org", egOrg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
 	})
 
@@ -286,12 +287,12 @@ func TestRestControllerOperations(t *testing.T) {
 		assert.Equal(t, nil, err, "Error")
 
 		rrResponse, _ := store.GetResourceRecord(&dns.Question{Name: eg,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, rr_eg100, (*rrResponse)[0].String(),
 			"Error")
 
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: eg1,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, rr_eg101, (*rrResponse)[0].String(),
 			"Error")
 
@@ -306,7 +307,7 @@ func TestRestControllerOperations(t *testing.T) {
 		err = mgmtCtl.handleDeleteResourceRecord(delContext1)
 		assert.Equal(t, nil, err, "Error")
 		assert.Equal(t, http.StatusOK, delContext1.Response().Status, "Error")
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.NotEqual(t, nil, err, errRecord)
 
 		deleteUrl2 := "/mep/dns_server_mgmt/v1/rrecord/www.example.org./A"
@@ -320,7 +321,7 @@ func TestRestControllerOperations(t *testing.T) {
 		err = mgmtCtl.handleDeleteResourceRecord(delContext2)
 		assert.Equal(t, nil, err, "Error")
 		assert.Equal(t, http.StatusOK, delContext2.Response().Status, "Error")
-		err = store.DelResourceRecord("", egOrg, "A")
+		err = store.DelResourceRecord("", egOrg, "A", "")
 		assert.NotEqual(t, nil, err, errRecord)
 
 		deleteUrl4 := "/mep/dns_server_mgmt/v1/rrecord/www.example1.com./A"
@@ -334,7 +335,7 @@ func TestRestControllerOperations(t *testing.T) {
 		err = mgmtCtl.handleDeleteResourceRecord(delContext4)
 		assert.Equal(t, nil, err, "Error")
 		assert.Equal(t, http.StatusOK, delContext4.Response().Status, "Error")
-		err = store.DelResourceRecord("", eg1, "A")
+		err = store.DelResourceRecord("", eg1, "A", "")
 		assert.NotEqual(t, nil, err, errRecord)
 	})
 	t.Run("DeleteRequestEmptyFqdn", func(t *testing.T) {
@@ -379,7 +380,7 @@ func TestRestControllerOperations(t *testing.T) {
 		assert.Equal(t, nil, err, "Error")
 
 		rrResponse, _ := store.GetResourceRecord(&dns.Question{Name: eg,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, rr_eg100, (*rrResponse)[0].String(),
 			"Error")
 
@@ -393,14 +394,14 @@ func TestRestControllerOperations(t *testing.T) {
 		err = mgmtCtl.handleSetResourceRecords(c)
 		assert.Equal(t, nil, err, "Error")
 		rrResponse, _ = store.GetResourceRecord(&dns.Question{Name: eg,
-			Qtype: dns.TypeA, Qclass: dns.ClassINET})
+			Qtype: dns.TypeA, Qclass: dns.ClassINET}, nil)
 		assert.Equal(t, "www.example.com.\t32\tIN\tA\t172.168.15.100", (*rrResponse)[0].String(),
 			"Error")
 		assert.Equal(t, "www.example.com.\t32\tIN\tA\t152.168.15.102", (*rrResponse)[1].String(),
 			"Error")
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
-		err = store.DelResourceRecord("", eg1, "A")
+		err = store.DelResourceRecord("", eg1, "A", "")
 		assert.NotEqual(t, nil, err, errRecord)
 	})
 	t.Run("BasicOperationsOnAddRecordBindErr", func(t *testing.T) {
@@ -473,7 +474,7 @@ func TestRestControllerOperations(t *testing.T) {
 		c.SetParamValues("org")
 		err = mgmtCtl.handleAddResourceRecords(c)
 		assert.Equal(t, nil, err, "Error")
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
 	})
 
@@ -521,7 +522,7 @@ func TestRestControllerOperations(t *testing.T) {
 		c.SetParamValues(eg, "A")
 		err = mgmtCtl.handleSetResourceRecords(c)
 		assert.Equal(t, nil, err, "Error")
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
 	})
 	t.Run("BasicOperationsOnSetRecordInvalidInput", func(t *testing.T) {
@@ -554,7 +555,7 @@ func TestRestControllerOperations(t *testing.T) {
 		c.SetParamValues("A")
 		err = mgmtCtl.handleSetResourceRecords(c)
 		assert.Equal(t, nil, err, "Error")
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
 	})
 
@@ -588,7 +589,7 @@ func TestRestControllerOperations(t *testing.T) {
 		c.SetParamValues(eg+".in", "A")
 		err = mgmtCtl.handleSetResourceRecords(c)
 		assert.Equal(t, nil, err, "Error")
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
 	})
 	t.Run("BasicOperationsOnSetRecordInvalidRdata", func(t *testing.T) {
@@ -612,7 +613,7 @@ func TestRestControllerOperations(t *testing.T) {
 		c.SetParamValues("www.e.com.", "A")
 		err = mgmtCtl.handleSetResourceRecords(c)
 		assert.Equal(t, nil, err, "Error")
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
 	})
 	t.Run("BasicOperationsOnSetRecordRecordNotExists", func(t *testing.T) {
@@ -659,9 +660,103 @@ func TestRestControllerOperations(t *testing.T) {
 		err = mgmtCtl.handleSetResourceRecords(c)
 		assert.Equal(t, nil, err, "Error")
 		patch.Reset()
-		err = store.DelResourceRecord("", eg, "A")
+		err = store.DelResourceRecord("", eg, "A", "")
 		assert.Equal(t, nil, err, errRecord)
 	})
 	//Cleanup Db
 	_ = os.RemoveAll(datastore.DBPath)
 }
+
+func TestRecordTypesOnAddRecord(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+
+	store := &datastore.BoltDB{FileName: "testtypesdb", TTL: 30}
+	err := store.Open()
+	assert.Equal(t, nil, err, "Error in opening the db")
+	defer store.Close()
+
+	mgmtCtl := &Controller{dataStore: store}
+	records := map[string]string{
+		"SRV":   "{\"name\": \"_http._tcp.app.example.com.\",\"type\": \"SRV\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"10 60 8080 app.example.com.\"]}",
+		"CNAME": "{\"name\": \"alias.example.com.\",\"type\": \"CNAME\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"app.example.com.\"]}",
+		"TXT":   "{\"name\": \"app.example.com.\",\"type\": \"TXT\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"version=1\"]}",
+		"PTR":   "{\"name\": \"1.0.10.10.in-addr.arpa.\",\"type\": \"PTR\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"app.example.com.\"]}",
+		"MX":    "{\"name\": \"example.com.\",\"type\": \"MX\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"10 mail.example.com.\"]}",
+	}
+	for rrType, record := range records {
+		e := echo.New()
+		newRequest, err := http.NewRequest(http.MethodPost, url, strings.NewReader(record))
+		assert.Equal(t, nil, err, "Error")
+		newRequest.Header.Set(cont, appj)
+		recorder := httptest.NewRecorder()
+		err = mgmtCtl.handleAddResourceRecords(e.NewContext(newRequest, recorder))
+		assert.Equal(t, nil, err, "Error")
+		assert.Equal(t, http.StatusOK, recorder.Code, rrType)
+	}
+
+	invalidRecords := []string{
+		"{\"name\": \"_sip._udp.example.com.\",\"type\": \"SRV\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"10 60 app.example.com.\"]}",
+		"{\"name\": \"alias2.example.com.\",\"type\": \"CNAME\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"a.example.com.\", \"b.example.com.\"]}",
+		"{\"name\": \"example.org.\",\"type\": \"MX\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"mail.example.org.\"]}",
+	}
+	for _, record := range invalidRecords {
+		e := echo.New()
+		newRequest, err := http.NewRequest(http.MethodPost, url, strings.NewReader(record))
+		assert.Equal(t, nil, err, "Error")
+		newRequest.Header.Set(cont, appj)
+		recorder := httptest.NewRecorder()
+		err = mgmtCtl.handleAddResourceRecords(e.NewContext(newRequest, recorder))
+		assert.Equal(t, nil, err, "Error")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, record)
+	}
+
+	rrResponse, err := store.GetResourceRecord(&dns.Question{Name: "alias.example.com.", Qtype: dns.TypeTXT,
+		Qclass: dns.ClassINET}, nil)
+	assert.Equal(t, nil, err, "Error")
+	assert.Equal(t, 2, len(*rrResponse))
+}
+
+func TestLoadBalancingOnAddRecord(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+
+	store := &datastore.BoltDB{FileName: "testbalancingdb", TTL: 30}
+	err := store.Open()
+	assert.Equal(t, nil, err, "Error in opening the db")
+	defer store.Close()
+
+	mgmtCtl := &Controller{dataStore: store}
+	addRecord := func(record string) int {
+		e := echo.New()
+		newRequest, err := http.NewRequest(http.MethodPost, url, strings.NewReader(record))
+		assert.Equal(t, nil, err, "Error")
+		newRequest.Header.Set(cont, appj)
+		recorder := httptest.NewRecorder()
+		err = mgmtCtl.handleAddResourceRecords(e.NewContext(newRequest, recorder))
+		assert.Equal(t, nil, err, "Error")
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, addRecord("{\"name\": \"app.example.com.\",\"type\": \"A\",\"class\": \"IN\","+
+		"\"ttl\": 30,\"rData\": [\"10.10.0.1\", \"10.10.0.2\"],\"weights\": [3, 1],"+
+		"\"healthCheck\": {\"protocol\": \"http\",\"port\": 8080}}"))
+	rr, err := store.GetZoneResourceRecord(".", "app.example.com.", "A", "")
+	assert.Equal(t, nil, err, "Error")
+	assert.Equal(t, []uint32{3, 1}, rr.Weights)
+	assert.Equal(t, &datastore.HealthCheck{Protocol: datastore.HealthCheckHTTP, Port: 8080, Path: "/",
+		Interval: util.DefaultHealthCheckInterval, Timeout: util.DefaultHealthCheckTimeout}, rr.HealthCheck)
+
+	invalidRecords := []string{
+		"{\"name\": \"app1.example.com.\",\"type\": \"A\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"10.10.0.1\"],\"weights\": [3, 1]}",
+		"{\"name\": \"app2.example.com.\",\"type\": \"A\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"10.10.0.1\"],\"weights\": [0]}",
+		"{\"name\": \"app3.example.com.\",\"type\": \"A\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"10.10.0.1\"],\"healthCheck\": {\"protocol\": \"udp\",\"port\": 53}}",
+		"{\"name\": \"app4.example.com.\",\"type\": \"A\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"10.10.0.1\"],\"healthCheck\": {\"protocol\": \"tcp\",\"port\": 80,\"interval\": 1,\"timeout\": 5}}",
+		"{\"name\": \"app5.example.com.\",\"type\": \"TXT\",\"class\": \"IN\",\"ttl\": 30,\"rData\": [\"version=1\"],\"weights\": [1]}",
+	}
+	for _, record := range invalidRecords {
+		assert.Equal(t, http.StatusBadRequest, addRecord(record), record)
+	}
+}
@@ -0,0 +1,31 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// handleGetTargetHealth returns the health state of the load balanced addresses
+func (e *Controller) handleGetTargetHealth(c echo.Context) error {
+	if e.Balancer == nil {
+		return c.String(http.StatusServiceUnavailable, "load balancer health not available!")
+	}
+	return c.JSON(http.StatusOK, e.Balancer.TargetHealth())
+}
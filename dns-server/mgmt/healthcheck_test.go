@@ -0,0 +1,55 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/balance"
+)
+
+type stubBalancer struct{}
+
+func (b *stubBalancer) TargetHealth() []balance.TargetHealth {
+	return []balance.TargetHealth{{Name: "app.example.com.", Type: "A", Address: "10.10.0.1", Weight: 1,
+		Healthy: false, ConsecutiveFailures: 2, LastError: "connection refused"}}
+}
+
+func TestGetTargetHealth(t *testing.T) {
+	mgmtCtl := &Controller{}
+	request := func() *httptest.ResponseRecorder {
+		e := echo.New()
+		recorder := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/mep/dns_server_mgmt/v1/healthchecks", nil), recorder)
+		assert.Equal(t, nil, mgmtCtl.handleGetTargetHealth(c), "Error")
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, request().Code)
+
+	mgmtCtl.Balancer = &stubBalancer{}
+	recorder := request()
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "\"address\":\"10.10.0.1\"")
+	assert.Contains(t, recorder.Body.String(), "\"healthy\":false")
+	assert.Contains(t, recorder.Body.String(), "\"lastError\":\"connection refused\"")
+}
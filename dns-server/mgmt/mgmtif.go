@@ -20,7 +20,12 @@ package mgmt
 import (
 	"net"
 
+	"dns-server/balance"
+	"dns-server/cache"
 	"dns-server/datastore"
+	"dns-server/forward"
+	"dns-server/querylog"
+	"dns-server/replication"
 )
 
 // ManagementCtrl Management ctrl interface.
@@ -31,3 +36,39 @@ type ManagementCtrl interface {
 	// Stop and cleanup controller module
 	StopController() error
 }
+
+// Resolver statistics of the answer cache and the forwarders of the dns server.
+type Resolver interface {
+	// CacheStats returns the answer cache statistics
+	CacheStats() cache.Stats
+
+	// FlushCache removes all the cached answers
+	FlushCache()
+
+	// ForwarderStats returns the health and latency of the forwarders
+	ForwarderStats() []forward.UpstreamStats
+}
+
+// LoadBalancer health state of the load balanced addresses of the dns server.
+type LoadBalancer interface {
+	// TargetHealth returns the health state of the load balanced addresses
+	TargetHealth() []balance.TargetHealth
+}
+
+// QueryStats query statistics of the dns server.
+type QueryStats interface {
+	// QuerySummary returns the query counters by the answer source, response code and zone
+	QuerySummary() querylog.Summary
+
+	// TopNames returns the most queried names, at most limit entries
+	TopNames(limit int) []querylog.NameHits
+
+	// ResetQueryStats clears the query statistics
+	ResetQueryStats()
+}
+
+// Replication replication state of the dns server.
+type Replication interface {
+	// ReplicationStatus returns the role and the replication state
+	ReplicationStatus() replication.Status
+}
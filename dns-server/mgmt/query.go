@@ -0,0 +1,157 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/datastore"
+	"dns-server/util"
+)
+
+// RecordList a page of the resource records in the zone
+type RecordList struct {
+	Zone   string                     `json:"zone"`
+	Total  int                        `json:"total"`
+	Offset int                        `json:"offset"`
+	Limit  int                        `json:"limit"`
+	RR     []datastore.ResourceRecord `json:"rr"`
+}
+
+func (e *Controller) handleGetResourceRecord(c echo.Context) error {
+	zone := c.QueryParam("zone")
+	view := c.QueryParam("view")
+	fqdn := c.Param("fqdn")
+	rrtype := c.Param("rrtype")
+
+	if len(fqdn) == 0 || len(rrtype) == 0 || len(zone) >= util.MaxDNSFQDNLength ||
+		len(view) > util.MaxViewNameLength {
+		return c.String(http.StatusBadRequest, "invalid input parameters!")
+	}
+	if len(zone) == 0 {
+		zone = "."
+	}
+
+	rr, err := e.dataStore.GetZoneResourceRecord(zone, fqdn, rrtype, view)
+	if err == datastore.ErrNotFound {
+		return c.String(http.StatusNotFound, "record not found!")
+	}
+	if err != nil {
+		log.Error("Failed to get the resource record.", nil)
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, rr)
+}
+
+// handleListResourceRecords lists the records of the zone filtered by the type, the name prefix and the view, the
+// records are paginated by the offset and limit query parameters
+func (e *Controller) handleListResourceRecords(c echo.Context) error {
+	zone := c.QueryParam("zone")
+	rrtype := c.QueryParam("type")
+	prefix := strings.ToLower(c.QueryParam("prefix"))
+	view, filterView := c.QueryParams()["view"]
+
+	if len(zone) >= util.MaxDNSFQDNLength || len(prefix) > util.MaxDNSFQDNLength {
+		return c.String(http.StatusBadRequest, "invalid input parameters!")
+	}
+	if len(zone) == 0 {
+		zone = "."
+	}
+	offset, limit, ok := pagination(c)
+	if !ok {
+		return c.String(http.StatusBadRequest, "invalid pagination parameters!")
+	}
+
+	records, err := e.dataStore.ListResourceRecords(zone)
+	if err == datastore.ErrNotFound {
+		return c.String(http.StatusNotFound, "zone not found!")
+	}
+	if err != nil {
+		log.Error("Failed to list the resource records.", nil)
+		return c.String(http.StatusInternalServerError, "Error in retrieving the data.")
+	}
+
+	filtered := make([]datastore.ResourceRecord, 0, len(records))
+	for _, rr := range records {
+		if (len(rrtype) == 0 || rr.Type == rrtype) && strings.HasPrefix(rr.Name, prefix) &&
+			(!filterView || rr.View == view[0]) {
+			filtered = append(filtered, rr)
+		}
+	}
+	page := RecordList{Zone: zone, Total: len(filtered), Offset: offset, Limit: limit,
+		RR: make([]datastore.ResourceRecord, 0)}
+	if offset < len(filtered) {
+		end := offset + limit
+		if end > len(filtered) {
+			end = len(filtered)
+		}
+		page.RR = filtered[offset:end]
+	}
+
+	return c.JSON(http.StatusOK, page)
+}
+
+func pagination(c echo.Context) (offset int, limit int, ok bool) {
+	limit = util.DefaultListLimit
+	var err error
+	if offsetStr := c.QueryParam("offset"); len(offsetStr) != 0 {
+		if offset, err = strconv.Atoi(offsetStr); err != nil || offset < 0 {
+			return 0, 0, false
+		}
+	}
+	if limitStr := c.QueryParam("limit"); len(limitStr) != 0 {
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 1 || limit > util.MaxListLimit {
+			return 0, 0, false
+		}
+	}
+	return offset, limit, true
+}
+
+func (e *Controller) handleListZones(c echo.Context) error {
+	zones, err := e.dataStore.ListZones()
+	if err != nil {
+		log.Error("Failed to list the zones.", nil)
+		return c.String(http.StatusInternalServerError, "Error in retrieving the data.")
+	}
+
+	return c.JSON(http.StatusOK, zones)
+}
+
+// handleExportZone exports all the records of the zone
+func (e *Controller) handleExportZone(c echo.Context) error {
+	zone := c.Param("zone")
+	if len(zone) == 0 || len(zone) >= util.MaxDNSFQDNLength {
+		return c.String(http.StatusBadRequest, "invalid input parameters!")
+	}
+
+	records, err := e.dataStore.ListResourceRecords(zone)
+	if err == datastore.ErrNotFound {
+		return c.String(http.StatusNotFound, "zone not found!")
+	}
+	if err != nil {
+		log.Error("Failed to export the zone.", nil)
+		return c.String(http.StatusInternalServerError, "Error in retrieving the data.")
+	}
+
+	return c.JSON(http.StatusOK, datastore.ZoneEntry{Zone: zone, RR: &records})
+}
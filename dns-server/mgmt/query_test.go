@@ -0,0 +1,120 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/datastore"
+)
+
+func TestQueryOperations(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+
+	store := &datastore.BoltDB{FileName: "testquerydb", TTL: 30}
+	err := store.Open()
+	assert.Equal(t, nil, err, "Error in opening the db")
+	defer store.Close()
+	mgmtCtl := &Controller{dataStore: store}
+
+	records := []datastore.ResourceRecord{
+		{Name: "app1.example.com.", Type: "A", Class: "IN", TTL: 30, RData: []string{"172.168.15.101"}},
+		{Name: "app1.example.com.", Type: "TXT", Class: "IN", TTL: 30, RData: []string{"version=1"}},
+		{Name: "app2.example.com.", Type: "A", Class: "IN", TTL: 30, RData: []string{"172.168.15.102"}},
+		{Name: "web.example.com.", Type: "AAAA", Class: "IN", TTL: 30, RData: []string{"2001:db8::1"}},
+	}
+	for i := range records {
+		assert.Equal(t, nil, store.SetResourceRecord("example.com.", &records[i]), "Error")
+	}
+
+	get := func(target string, handler echo.HandlerFunc, names []string, values []string) *httptest.ResponseRecorder {
+		e := echo.New()
+		recorder := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, target, nil), recorder)
+		c.SetParamNames(names...)
+		c.SetParamValues(values...)
+		assert.Equal(t, nil, handler(c), "Error")
+		return recorder
+	}
+
+	t.Run("GetRecord", func(t *testing.T) {
+		recorder := get(url+"/app1.example.com./A?zone=example.com.", mgmtCtl.handleGetResourceRecord,
+			[]string{"fqdn", "rrtype"}, []string{"APP1.example.com.", "A"})
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		rr := datastore.ResourceRecord{}
+		assert.Equal(t, nil, json.Unmarshal(recorder.Body.Bytes(), &rr))
+		assert.Equal(t, records[0], rr)
+
+		recorder = get(url+"/app1.example.com./A", mgmtCtl.handleGetResourceRecord,
+			[]string{"fqdn", "rrtype"}, []string{"app1.example.com.", "A"})
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+
+		recorder = get(url+"/app1.example.com./HINFO?zone=example.com.", mgmtCtl.handleGetResourceRecord,
+			[]string{"fqdn", "rrtype"}, []string{"app1.example.com.", "HINFO"})
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("ListRecords", func(t *testing.T) {
+		recorder := get(url+"?zone=example.com.&prefix=app&offset=1&limit=2", mgmtCtl.handleListResourceRecords,
+			nil, nil)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		page := RecordList{}
+		assert.Equal(t, nil, json.Unmarshal(recorder.Body.Bytes(), &page))
+		assert.Equal(t, 3, page.Total)
+		assert.Equal(t, []datastore.ResourceRecord{records[1], records[2]}, page.RR)
+
+		recorder = get(url+"?zone=example.com.&type=A", mgmtCtl.handleListResourceRecords, nil, nil)
+		assert.Equal(t, nil, json.Unmarshal(recorder.Body.Bytes(), &page))
+		assert.Equal(t, []datastore.ResourceRecord{records[0], records[2]}, page.RR)
+
+		recorder = get(url+"?zone=example.com.&offset=10", mgmtCtl.handleListResourceRecords, nil, nil)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, nil, json.Unmarshal(recorder.Body.Bytes(), &page))
+		assert.Equal(t, 4, page.Total)
+		assert.Equal(t, 0, len(page.RR))
+
+		recorder = get(url+"?zone=example.com.&limit=0", mgmtCtl.handleListResourceRecords, nil, nil)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+		recorder = get(url+"?zone=example.org.", mgmtCtl.handleListResourceRecords, nil, nil)
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("Zones", func(t *testing.T) {
+		recorder := get("/mep/dns_server_mgmt/v1/zones", mgmtCtl.handleListZones, nil, nil)
+		var zones []string
+		assert.Equal(t, nil, json.Unmarshal(recorder.Body.Bytes(), &zones))
+		assert.Equal(t, []string{".", "example.com."}, zones)
+
+		recorder = get("/mep/dns_server_mgmt/v1/zones/example.com.", mgmtCtl.handleExportZone,
+			[]string{"zone"}, []string{"example.com."})
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		zoneEntry := datastore.ZoneEntry{}
+		assert.Equal(t, nil, json.Unmarshal(recorder.Body.Bytes(), &zoneEntry))
+		assert.Equal(t, "example.com.", zoneEntry.Zone)
+		assert.Equal(t, records, *zoneEntry.RR)
+	})
+}
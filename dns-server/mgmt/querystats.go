@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/util"
+)
+
+// handleGetQuerySummary returns the query counters by the answer source, response code and zone
+func (e *Controller) handleGetQuerySummary(c echo.Context) error {
+	if e.QueryStats == nil {
+		return c.String(http.StatusServiceUnavailable, "query statistics not available!")
+	}
+	return c.JSON(http.StatusOK, e.QueryStats.QuerySummary())
+}
+
+// handleResetQueryStats clears the query statistics
+func (e *Controller) handleResetQueryStats(c echo.Context) error {
+	if e.QueryStats == nil {
+		return c.String(http.StatusServiceUnavailable, "query statistics not available!")
+	}
+	e.QueryStats.ResetQueryStats()
+	log.Debugf("Query statistics reset on management request.")
+
+	return c.String(http.StatusOK, "success in resetting the query statistics.")
+}
+
+// handleGetTopNames returns the most queried names, the number of the names is limited by the limit query parameter
+func (e *Controller) handleGetTopNames(c echo.Context) error {
+	if e.QueryStats == nil {
+		return c.String(http.StatusServiceUnavailable, "query statistics not available!")
+	}
+	limit := util.DefaultTopNamesLimit
+	if limitStr := c.QueryParam("limit"); len(limitStr) != 0 {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 1 || limit > util.MaxListLimit {
+			return c.String(http.StatusBadRequest, "invalid limit!")
+		}
+	}
+	return c.JSON(http.StatusOK, e.QueryStats.TopNames(limit))
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/querylog"
+)
+
+type stubQueryStats struct {
+	limit int
+	reset bool
+}
+
+func (q *stubQueryStats) QuerySummary() querylog.Summary {
+	return querylog.Summary{Total: 3, Sources: map[string]uint64{querylog.SourceLocal: 2, querylog.SourceForward: 1},
+		Rcodes: map[string]uint64{"NOERROR": 2, "NXDOMAIN": 1},
+		Zones:  []querylog.ZoneHits{{Zone: "example.com.", Hits: 2}}}
+}
+
+func (q *stubQueryStats) TopNames(limit int) []querylog.NameHits {
+	q.limit = limit
+	return []querylog.NameHits{{Name: "app.example.com.", Type: "A", Hits: 2}}
+}
+
+func (q *stubQueryStats) ResetQueryStats() {
+	q.reset = true
+}
+
+func TestQueryStats(t *testing.T) {
+	mgmtCtl := &Controller{}
+	request := func(method, target string, handler func(c echo.Context) error) *httptest.ResponseRecorder {
+		e := echo.New()
+		recorder := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(method, target, nil), recorder)
+		assert.Equal(t, nil, handler(c), "Error")
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable,
+		request(http.MethodGet, "/mep/dns_server_mgmt/v1/stats/queries", mgmtCtl.handleGetQuerySummary).Code)
+	assert.Equal(t, http.StatusServiceUnavailable,
+		request(http.MethodGet, "/mep/dns_server_mgmt/v1/stats/names", mgmtCtl.handleGetTopNames).Code)
+
+	stats := &stubQueryStats{}
+	mgmtCtl.QueryStats = stats
+	recorder := request(http.MethodGet, "/mep/dns_server_mgmt/v1/stats/queries", mgmtCtl.handleGetQuerySummary)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "\"NXDOMAIN\":1")
+	assert.Contains(t, recorder.Body.String(), "\"zone\":\"example.com.\"")
+
+	recorder = request(http.MethodGet, "/mep/dns_server_mgmt/v1/stats/names", mgmtCtl.handleGetTopNames)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "\"name\":\"app.example.com.\"")
+	assert.Equal(t, 10, stats.limit)
+
+	recorder = request(http.MethodGet, "/mep/dns_server_mgmt/v1/stats/names?limit=5", mgmtCtl.handleGetTopNames)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, 5, stats.limit)
+	assert.Equal(t, http.StatusBadRequest,
+		request(http.MethodGet, "/mep/dns_server_mgmt/v1/stats/names?limit=0", mgmtCtl.handleGetTopNames).Code)
+
+	recorder = request(http.MethodDelete, "/mep/dns_server_mgmt/v1/stats/queries", mgmtCtl.handleResetQueryStats)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, stats.reset)
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/datastore"
+	"dns-server/replication"
+	"dns-server/util"
+)
+
+// replicatedPaths the management paths updating the replicated data
+var replicatedPaths = []string{"/mep/dns_server_mgmt/v1/rrecord", "/mep/dns_server_mgmt/v1/zones",
+	"/mep/dns_server_mgmt/v1/views"}
+
+// readOnlyMiddleware rejects the updates of the replicated data on the secondary, they are done on the primary
+func (e *Controller) readOnlyMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !e.ReadOnly || c.Request().Method == http.MethodGet || c.Request().Method == http.MethodHead {
+			return next(c)
+		}
+		for _, path := range replicatedPaths {
+			if strings.HasPrefix(c.Request().URL.Path, path) {
+				return c.String(http.StatusForbidden, "read only secondary, update the primary!")
+			}
+		}
+		return next(c)
+	}
+}
+
+// handleGetReplicationStatus returns the role and the replication state
+func (e *Controller) handleGetReplicationStatus(c echo.Context) error {
+	if e.Replication == nil {
+		return c.String(http.StatusServiceUnavailable, "replication status not available!")
+	}
+	return c.JSON(http.StatusOK, e.Replication.ReplicationStatus())
+}
+
+// handleGetChanges returns the changes after the since query parameter, gone if the changes are no longer available
+func (e *Controller) handleGetChanges(c echo.Context) error {
+	since, err := strconv.ParseUint(c.QueryParam("since"), 10, 64)
+	if err != nil {
+		return c.String(http.StatusBadRequest, "invalid since!")
+	}
+	limit := util.MaxReplicationChanges
+	if limitStr := c.QueryParam("limit"); len(limitStr) != 0 {
+		if limit, err = strconv.Atoi(limitStr); err != nil || limit < 1 || limit > util.MaxReplicationChanges {
+			return c.String(http.StatusBadRequest, "invalid limit!")
+		}
+	}
+
+	// The generation is read first, the changes after it are returned on the next request
+	generation, err := e.dataStore.Generation()
+	if err != nil {
+		log.Error("Failed to read the generation.", nil)
+		return c.String(http.StatusInternalServerError, "Error in retrieving the data.")
+	}
+	changes, err := e.dataStore.Changes(since, limit)
+	if err == datastore.ErrChangeLogTruncated {
+		return c.String(http.StatusGone, "changes not available, snapshot required!")
+	}
+	if err != nil {
+		log.Error("Failed to read the changes.", nil)
+		return c.String(http.StatusInternalServerError, "Error in retrieving the data.")
+	}
+	if last := len(changes); last != 0 && changes[last-1].Generation > generation {
+		generation = changes[last-1].Generation
+	}
+
+	return c.JSON(http.StatusOK, replication.ChangeList{Generation: generation, Changes: changes})
+}
+
+// handleGetSnapshot returns the records and views of the data store
+func (e *Controller) handleGetSnapshot(c echo.Context) error {
+	snapshot, err := datastore.TakeSnapshot(e.dataStore)
+	if err != nil {
+		log.Errorf("Failed to take the snapshot(%s).", err.Error())
+		return c.String(http.StatusInternalServerError, "Error in retrieving the data.")
+	}
+	return c.JSON(http.StatusOK, snapshot)
+}
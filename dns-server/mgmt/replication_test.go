@@ -0,0 +1,93 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/datastore"
+	"dns-server/replication"
+)
+
+type stubReplication struct{}
+
+func (r *stubReplication) ReplicationStatus() replication.Status {
+	return replication.Status{Role: replication.RolePrimary, Generation: 4, Secondaries: []string{"10.10.0.2:53"}}
+}
+
+func TestReplication(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+
+	store := &datastore.BoltDB{FileName: "testreplicationdb", TTL: 30}
+	err := store.Open()
+	assert.Equal(t, nil, err, "Error in opening the db")
+	defer store.Close()
+	assert.Nil(t, store.SetView(&datastore.View{Name: "cell", Subnets: []string{"10.100.0.0/16"}}))
+	assert.Nil(t, store.SetResourceRecord("example.com.", &datastore.ResourceRecord{Name: "www.example.com.",
+		Type: "A", Class: "IN", TTL: 30, RData: []string{"10.10.0.1"}}))
+	mgmtCtl := &Controller{dataStore: store}
+
+	request := func(method, target string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		e := echo.New()
+		recorder := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(method, target, nil), recorder)
+		assert.Equal(t, nil, handler(c), "Error")
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable,
+		request(http.MethodGet, "/mep/dns_server_mgmt/v1/replication", mgmtCtl.handleGetReplicationStatus).Code)
+	mgmtCtl.Replication = &stubReplication{}
+	recorder := request(http.MethodGet, "/mep/dns_server_mgmt/v1/replication", mgmtCtl.handleGetReplicationStatus)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "\"role\":\"primary\"")
+
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, replication.ChangesPath,
+		mgmtCtl.handleGetChanges).Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodGet, replication.ChangesPath+"?since=0&limit=0",
+		mgmtCtl.handleGetChanges).Code)
+	recorder = request(http.MethodGet, replication.ChangesPath+"?since=0", mgmtCtl.handleGetChanges)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "\"name\":\"cell\"")
+	assert.Contains(t, recorder.Body.String(), "\"name\":\"www.example.com.\"")
+
+	recorder = request(http.MethodGet, replication.SnapshotPath, mgmtCtl.handleGetSnapshot)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "\"zone\":\"example.com.\"")
+
+	// The replicated data is updated only on the primary
+	mgmtCtl.ReadOnly = true
+	next := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	assert.Equal(t, http.StatusForbidden, request(http.MethodPost, "/mep/dns_server_mgmt/v1/rrecord",
+		mgmtCtl.readOnlyMiddleware(next)).Code)
+	assert.Equal(t, http.StatusForbidden, request(http.MethodDelete, "/mep/dns_server_mgmt/v1/views/cell",
+		mgmtCtl.readOnlyMiddleware(next)).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodGet, "/mep/dns_server_mgmt/v1/views/cell",
+		mgmtCtl.readOnlyMiddleware(next)).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodDelete, "/mep/dns_server_mgmt/v1/cache",
+		mgmtCtl.readOnlyMiddleware(next)).Code)
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+)
+
+// handleGetCacheStats returns the answer cache statistics
+func (e *Controller) handleGetCacheStats(c echo.Context) error {
+	if e.Resolver == nil {
+		return c.String(http.StatusServiceUnavailable, "resolver statistics not available!")
+	}
+	return c.JSON(http.StatusOK, e.Resolver.CacheStats())
+}
+
+// handleFlushCache removes all the cached answers
+func (e *Controller) handleFlushCache(c echo.Context) error {
+	if e.Resolver == nil {
+		return c.String(http.StatusServiceUnavailable, "resolver statistics not available!")
+	}
+	e.Resolver.FlushCache()
+	log.Debugf("Answer cache flushed on management request.")
+
+	return c.String(http.StatusOK, "success in flushing the cache.")
+}
+
+// handleGetForwarderStats returns the health and latency of the forwarders
+func (e *Controller) handleGetForwarderStats(c echo.Context) error {
+	if e.Resolver == nil {
+		return c.String(http.StatusServiceUnavailable, "resolver statistics not available!")
+	}
+	return c.JSON(http.StatusOK, e.Resolver.ForwarderStats())
+}
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/cache"
+	"dns-server/forward"
+)
+
+type stubResolver struct {
+	flushed bool
+}
+
+func (r *stubResolver) CacheStats() cache.Stats {
+	return cache.Stats{Capacity: 100, Size: 1, Hits: 3, Misses: 1, HitRatio: 0.75}
+}
+
+func (r *stubResolver) FlushCache() {
+	r.flushed = true
+}
+
+func (r *stubResolver) ForwarderStats() []forward.UpstreamStats {
+	return []forward.UpstreamStats{{Upstream: "8.8.8.8:53", Healthy: true, Requests: 4}}
+}
+
+func TestResolverStats(t *testing.T) {
+	resolver := &stubResolver{}
+	mgmtCtl := &Controller{}
+
+	request := func(method string, target string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		e := echo.New()
+		recorder := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(method, target, nil), recorder)
+		assert.Equal(t, nil, handler(c), "Error")
+		return recorder
+	}
+
+	recorder := request(http.MethodGet, "/mep/dns_server_mgmt/v1/cache", mgmtCtl.handleGetCacheStats)
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	mgmtCtl.Resolver = resolver
+	recorder = request(http.MethodGet, "/mep/dns_server_mgmt/v1/cache", mgmtCtl.handleGetCacheStats)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"capacity":100,"size":1,"hits":3,"negativeHits":0,"misses":1,"evictions":0,"hitRatio":0.75}`,
+		recorder.Body.String())
+
+	recorder = request(http.MethodDelete, "/mep/dns_server_mgmt/v1/cache", mgmtCtl.handleFlushCache)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.True(t, resolver.flushed)
+
+	recorder = request(http.MethodGet, "/mep/dns_server_mgmt/v1/forwarders", mgmtCtl.handleGetForwarderStats)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `[{"upstream":"8.8.8.8:53","healthy":true,"requests":4,"failures":0,"consecutiveFailures":0,`+
+		`"avgRttMillis":0}]`, recorder.Body.String())
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"dns-server/util"
+)
+
+// bearerPrefix the authorization scheme of the management api token
+const bearerPrefix = "Bearer "
+
+// ServerTLSConfig loads the certificate serving the management api, the client certificates are required and
+// verified with the client ca if given
+func ServerTLSConfig(certFile, keyFile, clientCAFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading the management certificate failed: %s", err.Error())
+	}
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	if len(clientCAFile) != 0 {
+		if tlsConfig.ClientCAs, err = loadCertPool(clientCAFile); err != nil {
+			return nil, err
+		}
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// ClientTLSConfig verifies the management api of the other dns server with the ca, the client certificate is
+// presented for the mutual tls if given
+func ClientTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	rootCAs, err := loadCertPool(caFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}
+	if len(certFile) != 0 {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading the client certificate failed: %s", err.Error())
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	caBytes, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading the ca certificate failed: %s", err.Error())
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caBytes) {
+		return nil, fmt.Errorf("no ca certificate in %s", caFile)
+	}
+	return pool, nil
+}
+
+// ReadToken reads the bearer token of the management api from the file, the surrounding spaces are ignored
+func ReadToken(tokenFile string) (string, error) {
+	tokenBytes, err := ioutil.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("reading the token file failed: %s", err.Error())
+	}
+	token := strings.TrimSpace(string(tokenBytes))
+	if len(token) < util.MinTokenLength || len(token) > util.MaxTokenLength {
+		return "", fmt.Errorf("token length not in valid range(%d~%d)", util.MinTokenLength, util.MaxTokenLength)
+	}
+	return token, nil
+}
+
+// authMiddleware requires the bearer token on the management requests if configured, the health check is open to
+// the liveness probes
+func (e *Controller) authMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if len(e.Token) == 0 || c.Request().URL.Path == healthPath {
+			return next(c)
+		}
+		authorization := c.Request().Header.Get(echo.HeaderAuthorization)
+		if !strings.HasPrefix(authorization, bearerPrefix) || subtle.ConstantTimeCompare(
+			[]byte(strings.TrimPrefix(authorization, bearerPrefix)), []byte(e.Token)) != 1 {
+			c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+			return c.String(http.StatusUnauthorized, "invalid or missing token!")
+		}
+		return next(c)
+	}
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+const testToken = "0123456789abcdef0123"
+
+// writeCert writes the certificate and key signed by the parent, self signed ca without parent
+func writeCert(t *testing.T, dir string, name string, template *x509.Certificate, parent *x509.Certificate,
+	parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	certBytes, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.Nil(t, err)
+	keyBytes, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name+".crt"),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certBytes}), 0600))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, name+".key"),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyBytes}), 0600))
+	cert, err := x509.ParseCertificate(certBytes)
+	assert.Nil(t, err)
+	return cert, key
+}
+
+func TestReadToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "token")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	tokenFile := filepath.Join(dir, "token")
+	assert.Nil(t, ioutil.WriteFile(tokenFile, []byte(testToken+"\n"), 0600))
+	token, err := ReadToken(tokenFile)
+	assert.Nil(t, err)
+	assert.Equal(t, testToken, token)
+
+	assert.Nil(t, ioutil.WriteFile(tokenFile, []byte("short"), 0600))
+	_, err = ReadToken(tokenFile)
+	assert.NotNil(t, err)
+	_, err = ReadToken(filepath.Join(dir, "missing"))
+	assert.NotNil(t, err)
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	mgmtCtl := &Controller{}
+	next := func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	}
+	request := func(target string, authorization string) int {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		if len(authorization) != 0 {
+			req.Header.Set(echo.HeaderAuthorization, authorization)
+		}
+		recorder := httptest.NewRecorder()
+		assert.Equal(t, nil, mgmtCtl.authMiddleware(next)(e.NewContext(req, recorder)), "Error")
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request(url, ""))
+	mgmtCtl.Token = testToken
+	assert.Equal(t, http.StatusUnauthorized, request(url, ""))
+	assert.Equal(t, http.StatusUnauthorized, request(url, "Bearer wrong"))
+	assert.Equal(t, http.StatusUnauthorized, request(url, "Basic "+testToken))
+	assert.Equal(t, http.StatusOK, request(url, "Bearer "+testToken))
+	assert.Equal(t, http.StatusOK, request(healthPath, ""))
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	notAfter := time.Now().Add(time.Hour)
+	ca, caKey := writeCert(t, dir, "ca", &x509.Certificate{SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{CommonName: "dns ca"}, NotBefore: time.Now().Add(-time.Hour), NotAfter: notAfter,
+		IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, nil, nil)
+	writeCert(t, dir, "server", &x509.Certificate{SerialNumber: big.NewInt(2),
+		Subject: pkix.Name{CommonName: "dns server"}, NotBefore: time.Now().Add(-time.Hour), NotAfter: notAfter,
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}, KeyUsage: x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}, ca, caKey)
+	writeCert(t, dir, "client", &x509.Certificate{SerialNumber: big.NewInt(3),
+		Subject: pkix.Name{CommonName: "mep server"}, NotBefore: time.Now().Add(-time.Hour), NotAfter: notAfter,
+		KeyUsage: x509.KeyUsageDigitalSignature, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}},
+		ca, caKey)
+	file := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	serverTLS, err := ServerTLSConfig(file("server.crt"), file("server.key"), file("ca.crt"))
+	assert.Nil(t, err)
+	_, err = ServerTLSConfig(file("server.crt"), file("client.key"), "")
+	assert.NotNil(t, err)
+	_, err = ClientTLSConfig(file("server.key"), "", "")
+	assert.NotNil(t, err)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	server.TLS = serverTLS
+	server.StartTLS()
+	defer server.Close()
+
+	// The client certificate is required
+	clientTLS, err := ClientTLSConfig(file("ca.crt"), file("client.crt"), file("client.key"))
+	assert.Nil(t, err)
+	client := http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+	resp, err := client.Get(server.URL)
+	if assert.Nil(t, err) {
+		_ = resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	clientTLS, err = ClientTLSConfig(file("ca.crt"), "", "")
+	assert.Nil(t, err)
+	client = http.Client{Transport: &http.Transport{TLSClientConfig: clientTLS}}
+	_, err = client.Get(server.URL)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"tracing"
+)
+
+// tracingMiddleware traces the management requests in the server spans named after the matched route, the
+// requests are not traced while the tracing is disabled
+func tracingMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if !tracing.Enabled() {
+			return next(c)
+		}
+		req := c.Request()
+		ctx, span := tracing.StartSpan(tracing.Extract(req.Context(), req.Header), req.Method+" "+c.Path(),
+			tracing.SpanKindServer)
+		span.SetAttribute("http.method", req.Method)
+		span.SetAttribute("http.target", req.URL.Path)
+		c.SetRequest(req.WithContext(ctx))
+
+		err := next(c)
+		if err != nil {
+			c.Error(err)
+		}
+		statusCode := c.Response().Status
+		span.SetAttribute("http.status_code", statusCode)
+		if statusCode >= http.StatusInternalServerError {
+			span.SetError(http.StatusText(statusCode))
+		}
+		span.End(nil)
+		// the error is already handled
+		return nil
+	}
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"tracing"
+)
+
+func serveTraced(header string) {
+	e := echo.New()
+	e.Use(tracingMiddleware)
+	e.PUT("/rrecord/:fqdn", func(c echo.Context) error {
+		return c.NoContent(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodPut, "/rrecord/www.example.com.", nil)
+	if len(header) != 0 {
+		req.Header.Set(tracing.TraceParentHeader, header)
+	}
+	e.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	exported := make(chan string, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		exported <- string(body)
+	}))
+	defer collector.Close()
+
+	// disabled tracing does not export
+	serveTraced("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	tracing.Start(tracing.Options{ServiceName: "dns-server", Endpoint: collector.URL})
+	serveTraced("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	// unsampled trace is not exported
+	serveTraced("00-5bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	assert.Nil(t, tracing.Shutdown(context.Background()))
+
+	assert.Equal(t, 1, len(exported))
+	body := <-exported
+	assert.True(t, strings.Contains(body, `"name":"PUT /rrecord/:fqdn"`))
+	assert.True(t, strings.Contains(body, `"traceId":"4bf92f3577b34da6a3ce929d0e0e4736"`))
+	assert.True(t, strings.Contains(body, `"parentSpanId":"00f067aa0ba902b7"`))
+	assert.False(t, strings.Contains(body, "5bf92f3577b34da6a3ce929d0e0e4736"))
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/datastore"
+)
+
+func (e *Controller) handleAddView(c echo.Context) error {
+	// Input Example:
+	//{
+	//	"name": "cell-1",
+	//	"subnets": [
+	//      "10.100.0.0/16"
+	//     ]
+	//}
+	view := datastore.View{}
+	if nil != c.Bind(&view) {
+		log.Error("Error in parsing the view post request body.", nil)
+		return c.String(http.StatusBadRequest, "invalid input!")
+	}
+	if err := datastore.ValidateView(&view); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if _, err := e.dataStore.GetView(view.Name); err == nil {
+		return c.String(http.StatusBadRequest, "view already exists!")
+	}
+
+	if err := e.dataStore.SetView(&view); err != nil {
+		log.Errorf("Failed to add the view %s(%s).", view.Name, err.Error())
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	log.Debugf("Added new view(name: %s, subnets: %v).", view.Name, view.Subnets)
+
+	return c.String(http.StatusOK, "success in adding view.")
+}
+
+func (e *Controller) handleSetView(c echo.Context) error {
+	view := datastore.View{}
+	if nil != c.Bind(&view) {
+		log.Error("Error in parsing the view put request body.", nil)
+		return c.String(http.StatusBadRequest, "invalid input!")
+	}
+	if view.Name != c.Param("view") {
+		return c.String(http.StatusBadRequest, "input not match with view resource")
+	}
+	if err := datastore.ValidateView(&view); err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if _, err := e.dataStore.GetView(view.Name); err != nil {
+		return c.String(http.StatusNotFound, "view not found!")
+	}
+
+	if err := e.dataStore.SetView(&view); err != nil {
+		log.Errorf("Failed to update the view %s(%s).", view.Name, err.Error())
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	log.Debugf("Updated view(name: %s, subnets: %v).", view.Name, view.Subnets)
+
+	return c.String(http.StatusOK, "success in updating view.")
+}
+
+func (e *Controller) handleGetView(c echo.Context) error {
+	view, err := e.dataStore.GetView(c.Param("view"))
+	if err == datastore.ErrNotFound {
+		return c.String(http.StatusNotFound, "view not found!")
+	}
+	if err != nil {
+		log.Error("Failed to get the view.", nil)
+		return c.String(http.StatusInternalServerError, "Error in retrieving the data.")
+	}
+
+	return c.JSON(http.StatusOK, view)
+}
+
+func (e *Controller) handleListViews(c echo.Context) error {
+	views, err := e.dataStore.ListViews()
+	if err != nil {
+		log.Error("Failed to list the views.", nil)
+		return c.String(http.StatusInternalServerError, "Error in retrieving the data.")
+	}
+
+	return c.JSON(http.StatusOK, views)
+}
+
+// handleDeleteView deletes the view and its records, with the unused query parameter the view is deleted only if no
+// record references it
+func (e *Controller) handleDeleteView(c echo.Context) error {
+	var err error
+	if c.QueryParam("unused") == "true" {
+		err = e.dataStore.DelUnusedView(c.Param("view"))
+	} else {
+		err = e.dataStore.DelView(c.Param("view"))
+	}
+	if err == datastore.ErrNotFound {
+		return c.String(http.StatusNotFound, "view not found!")
+	}
+	if err == datastore.ErrViewInUse {
+		return c.String(http.StatusConflict, "view in use!")
+	}
+	if err != nil {
+		log.Errorf("Failed to delete the view(%s).", err.Error())
+		return c.String(http.StatusInternalServerError, "Error in deleting the data.")
+	}
+
+	return c.String(http.StatusOK, "Success")
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/datastore"
+)
+
+func TestViewOperations(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+
+	store := &datastore.BoltDB{FileName: "testviewdb", TTL: 30}
+	err := store.Open()
+	assert.Equal(t, nil, err, "Error in opening the db")
+	defer store.Close()
+	mgmtCtl := &Controller{dataStore: store}
+
+	request := func(method string, view string, body string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(method, "/mep/dns_server_mgmt/v1/views/"+view, strings.NewReader(body))
+		req.Header.Set(cont, appj)
+		recorder := httptest.NewRecorder()
+		c := e.NewContext(req, recorder)
+		c.SetParamNames("view")
+		c.SetParamValues(view)
+		assert.Equal(t, nil, handler(c), "Error")
+		return recorder
+	}
+
+	cell := `{"name": "cell", "subnets": ["10.100.0.0/16"]}`
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "", cell, mgmtCtl.handleAddView).Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "", cell, mgmtCtl.handleAddView).Code)
+	assert.Equal(t, http.StatusBadRequest, request(http.MethodPost, "",
+		`{"name": "invalid", "subnets": ["10.100.0.0/33"]}`, mgmtCtl.handleAddView).Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPut, "cell",
+		`{"name": "cell", "subnets": ["10.100.0.0/16", "10.101.0.0/16"]}`, mgmtCtl.handleSetView).Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodPut, "edge",
+		`{"name": "edge", "subnets": ["10.102.0.0/16"]}`, mgmtCtl.handleSetView).Code)
+
+	recorder := request(http.MethodGet, "cell", "", mgmtCtl.handleGetView)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"name": "cell", "subnets": ["10.100.0.0/16", "10.101.0.0/16"]}`, recorder.Body.String())
+	recorder = request(http.MethodGet, "", "", mgmtCtl.handleListViews)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "\"name\":\"cell\"")
+
+	// The records are scoped to the existing views
+	addRecord := func(record string) int {
+		e := echo.New()
+		newRequest := httptest.NewRequest(http.MethodPost, url, strings.NewReader(record))
+		newRequest.Header.Set(cont, appj)
+		recorder := httptest.NewRecorder()
+		assert.Equal(t, nil, mgmtCtl.handleAddResourceRecords(e.NewContext(newRequest, recorder)), "Error")
+		return recorder.Code
+	}
+	assert.Equal(t, http.StatusOK, addRecord(`{"name": "www.example.com.", "type": "A", "class": "IN", "ttl": 30,`+
+		` "rData": ["10.10.0.2"], "view": "cell"}`))
+	assert.Equal(t, http.StatusBadRequest, addRecord(`{"name": "www.example.com.", "type": "A", "class": "IN",`+
+		` "ttl": 30, "rData": ["10.10.0.3"], "view": "edge"}`))
+	rr, err := store.GetZoneResourceRecord(".", "www.example.com.", "A", "cell")
+	assert.Equal(t, nil, err, "Error")
+	assert.Equal(t, []string{"10.10.0.2"}, rr.RData)
+
+	// The unused views only are deleted on request
+	deleteUnused := func(view string) int {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodDelete, "/mep/dns_server_mgmt/v1/views/"+view+"?unused=true", nil)
+		recorder := httptest.NewRecorder()
+		c := e.NewContext(req, recorder)
+		c.SetParamNames("view")
+		c.SetParamValues(view)
+		assert.Equal(t, nil, mgmtCtl.handleDeleteView(c), "Error")
+		return recorder.Code
+	}
+	assert.Equal(t, http.StatusConflict, deleteUnused("cell"))
+	_, err = store.GetView("cell")
+	assert.Equal(t, nil, err, "Error")
+
+	assert.Equal(t, http.StatusOK, request(http.MethodDelete, "cell", "", mgmtCtl.handleDeleteView).Code)
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "cell", "", mgmtCtl.handleGetView).Code)
+	_, err = store.GetZoneResourceRecord(".", "www.example.com.", "A", "cell")
+	assert.Equal(t, datastore.ErrNotFound, err, "Error")
+	assert.Equal(t, http.StatusNotFound, deleteUnused("cell"))
+
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "", cell, mgmtCtl.handleAddView).Code)
+	assert.Equal(t, http.StatusOK, deleteUnused("cell"))
+	assert.Equal(t, http.StatusNotFound, request(http.MethodGet, "cell", "", mgmtCtl.handleGetView).Code)
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/datastore"
+	"dns-server/util"
+)
+
+// handleImportZoneFile imports the master format zone file in the request body, the mode query parameter selects
+// merge(default) or replace of the zone
+func (e *Controller) handleImportZoneFile(c echo.Context) error {
+	zone := c.Param("zone")
+	mode := c.QueryParam("mode")
+	if len(zone) == 0 || len(zone) >= util.MaxDNSFQDNLength {
+		return c.String(http.StatusBadRequest, "invalid input parameters!")
+	}
+	if len(mode) == 0 {
+		mode = datastore.ZoneImportMerge
+	}
+	if mode != datastore.ZoneImportMerge && mode != datastore.ZoneImportReplace {
+		return c.String(http.StatusBadRequest, "invalid import mode!")
+	}
+
+	records, err := datastore.ParseZoneFile(c.Request().Body, zone)
+	if err != nil {
+		log.Errorf("Failed to parse the zone file of %s(%s).", zone, err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	err = e.dataStore.ImportZone(zone, records, mode == datastore.ZoneImportReplace)
+	if err != nil {
+		log.Errorf("Failed to import the zone file of %s(%s).", zone, err.Error())
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	log.Infof("Imported %d resource records to the zone %s(mode: %s).", len(records), zone, mode)
+
+	return c.String(http.StatusOK, fmt.Sprintf("success in importing %d rr entries.", len(records)))
+}
+
+// handleExportZoneFile exports the zone in the master format
+func (e *Controller) handleExportZoneFile(c echo.Context) error {
+	zone := c.Param("zone")
+	if len(zone) == 0 || len(zone) >= util.MaxDNSFQDNLength {
+		return c.String(http.StatusBadRequest, "invalid input parameters!")
+	}
+
+	records, err := e.dataStore.ListResourceRecords(zone)
+	if err == datastore.ErrNotFound {
+		return c.String(http.StatusNotFound, "zone not found!")
+	}
+	if err != nil {
+		log.Error("Failed to export the zone.", nil)
+		return c.String(http.StatusInternalServerError, "Error in retrieving the data.")
+	}
+
+	var zoneFile bytes.Buffer
+	if err = datastore.WriteZoneFile(&zoneFile, zone, records, uint32(time.Now().Unix())); err != nil {
+		log.Errorf("Failed to write the zone file of %s(%s).", zone, err.Error())
+		return c.String(http.StatusInternalServerError, "Error in exporting the zone.")
+	}
+
+	return c.Blob(http.StatusOK, util.ZoneFileContentType, zoneFile.Bytes())
+}
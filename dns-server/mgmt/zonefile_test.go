@@ -0,0 +1,78 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mgmt
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/datastore"
+)
+
+const zoneFileEntry = `$ORIGIN example.com.
+app     30 IN A   10.10.0.1
+alias   30 IN CNAME app
+`
+
+func TestZoneFileOperations(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+
+	store := &datastore.BoltDB{FileName: "testzonefiledb", TTL: 30}
+	err := store.Open()
+	assert.Equal(t, nil, err, "Error in opening the db")
+	defer store.Close()
+	mgmtCtl := &Controller{dataStore: store}
+
+	request := func(method string, target string, body string, handler echo.HandlerFunc) *httptest.ResponseRecorder {
+		e := echo.New()
+		recorder := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(method, target, strings.NewReader(body)), recorder)
+		c.SetParamNames("zone")
+		c.SetParamValues("example.com.")
+		assert.Equal(t, nil, handler(c), "Error")
+		return recorder
+	}
+
+	recorder := request(http.MethodPut, "/mep/dns_server_mgmt/v1/zones/example.com./file?mode=replace",
+		zoneFileEntry, mgmtCtl.handleImportZoneFile)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "success in importing 2 rr entries.", recorder.Body.String())
+
+	recorder = request(http.MethodPut, "/mep/dns_server_mgmt/v1/zones/example.com./file?mode=append",
+		zoneFileEntry, mgmtCtl.handleImportZoneFile)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = request(http.MethodPut, "/mep/dns_server_mgmt/v1/zones/example.com./file",
+		"www.example.org. 30 IN A 10.10.0.1\n", mgmtCtl.handleImportZoneFile)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+	recorder = request(http.MethodGet, "/mep/dns_server_mgmt/v1/zones/example.com./file", "",
+		mgmtCtl.handleExportZoneFile)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "text/dns", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "example.com.\t30\tIN\tSOA\tns.example.com. hostmaster.example.com.")
+	assert.Contains(t, recorder.Body.String(), "alias.example.com.\t30\tIN\tCNAME\tapp.example.com.\n")
+	assert.Contains(t, recorder.Body.String(), "app.example.com.\t30\tIN\tA\t10.10.0.1\n")
+}
@@ -0,0 +1,400 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package querylog logs the sampled dns queries and counts the hits of the names, zones and response codes, the
+// counters are persisted periodically
+package querylog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/datastore"
+	"dns-server/util"
+)
+
+// Sources of the answers
+const (
+	// SourceLocal answered from the data store
+	SourceLocal = "local"
+	// SourceCache answered from the answer cache
+	SourceCache = "cache"
+	// SourceForward answered by a forwarder
+	SourceForward = "forward"
+	// SourceNone answered with an error
+	SourceNone = "none"
+)
+
+// Query a dns query and its answer
+type Query struct {
+	Client  net.IP
+	Name    string
+	Type    uint16
+	Rcode   int
+	Source  string
+	Latency time.Duration
+}
+
+// NameHits number of the queries of a name and type
+type NameHits struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Hits uint64 `json:"hits"`
+}
+
+// ZoneHits number of the locally answered queries of a zone
+type ZoneHits struct {
+	Zone string `json:"zone"`
+	Hits uint64 `json:"hits"`
+}
+
+// Summary query counters by the answer source, response code and zone
+type Summary struct {
+	Total   uint64            `json:"total"`
+	Sources map[string]uint64 `json:"sources"`
+	Rcodes  map[string]uint64 `json:"rcodes"`
+	Zones   []ZoneHits        `json:"zones"`
+	// UntrackedHits queries of the names beyond the tracked names limit
+	UntrackedHits uint64    `json:"untrackedHits"`
+	Since         time.Time `json:"since"`
+}
+
+// counters persisted form of the counters
+type counters struct {
+	Total         uint64            `json:"total"`
+	Sources       map[string]uint64 `json:"sources"`
+	Rcodes        map[string]uint64 `json:"rcodes"`
+	Zones         map[string]uint64 `json:"zones"`
+	Names         []NameHits        `json:"names"`
+	UntrackedHits uint64            `json:"untrackedHits"`
+	Since         time.Time         `json:"since"`
+}
+
+type nameKey struct {
+	name   string
+	rrType uint16
+}
+
+// Recorder records the dns queries
+type Recorder struct {
+	store      datastore.DataStore
+	sampleRate float64
+	fileName   string
+	random     func() float64
+
+	mutex     sync.Mutex
+	zones     []string
+	names     map[nameKey]uint64
+	counters  counters
+	dirty     bool
+	stop      chan struct{}
+	done      sync.WaitGroup
+	lastLoad  time.Time
+	lastStore time.Time
+}
+
+// NewRecorder creates the recorder logging the sampleRate fraction of the queries, the counters are persisted to the
+// file if not empty
+func NewRecorder(store datastore.DataStore, sampleRate float64, fileName string) *Recorder {
+	r := &Recorder{store: store, sampleRate: sampleRate, fileName: fileName, random: rand.Float64}
+	r.reset(time.Now())
+	return r
+}
+
+func (r *Recorder) reset(now time.Time) {
+	r.names = make(map[nameKey]uint64)
+	r.counters = counters{Sources: make(map[string]uint64), Rcodes: make(map[string]uint64),
+		Zones: make(map[string]uint64), Since: now}
+	r.dirty = true
+}
+
+// Start loads the persisted counters, the zones are reloaded and the counters are persisted in the background
+func (r *Recorder) Start() {
+	if err := r.load(); err != nil {
+		log.Errorf("Failed to load the query statistics(%s).", err.Error())
+	}
+	if err := r.ReloadZones(); err != nil {
+		log.Errorf("Failed to load the zones of the query statistics(%s).", err.Error())
+	}
+	now := time.Now()
+	r.lastLoad = now
+	r.lastStore = now
+	r.stop = make(chan struct{})
+	r.done.Add(1)
+	go r.run(r.stop)
+}
+
+// Stop stops the background reload and persists the counters
+func (r *Recorder) Stop() {
+	if r.stop == nil {
+		return
+	}
+	close(r.stop)
+	r.stop = nil
+	r.done.Wait()
+	if err := r.Persist(); err != nil {
+		log.Errorf("Failed to persist the query statistics(%s).", err.Error())
+	}
+}
+
+func (r *Recorder) run(stop chan struct{}) {
+	defer r.done.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			if now.Sub(r.lastLoad) >= util.QueryStatsZoneReloadInterval {
+				if err := r.ReloadZones(); err != nil {
+					log.Errorf("Failed to reload the zones of the query statistics(%s).", err.Error())
+				}
+				r.lastLoad = now
+			}
+			if now.Sub(r.lastStore) >= util.QueryStatsPersistInterval {
+				if err := r.Persist(); err != nil {
+					log.Errorf("Failed to persist the query statistics(%s).", err.Error())
+				}
+				r.lastStore = now
+			}
+		}
+	}
+}
+
+// ReloadZones loads the zones of the data store, the locally answered queries are counted in their most specific zone
+func (r *Recorder) ReloadZones() error {
+	zones, err := r.store.ListZones()
+	if err != nil {
+		return err
+	}
+	// The most specific zone first
+	sort.Slice(zones, func(i, j int) bool {
+		return dns.CountLabel(zones[i]) > dns.CountLabel(zones[j])
+	})
+	r.mutex.Lock()
+	r.zones = zones
+	r.mutex.Unlock()
+	return nil
+}
+
+// Record counts the query and logs it if sampled
+func (r *Recorder) Record(query *Query) {
+	if r == nil {
+		return
+	}
+	name := strings.ToLower(dns.Fqdn(query.Name))
+	rcode := dns.RcodeToString[query.Rcode]
+
+	r.mutex.Lock()
+	r.counters.Total++
+	r.counters.Sources[query.Source]++
+	r.counters.Rcodes[rcode]++
+	if query.Source == SourceLocal {
+		if zone, ok := r.zoneOf(name); ok {
+			r.counters.Zones[zone]++
+		}
+	}
+	key := nameKey{name: name, rrType: query.Type}
+	if _, found := r.names[key]; found || len(r.names) < util.MaxQueryStatsNames {
+		r.names[key]++
+	} else {
+		r.counters.UntrackedHits++
+	}
+	r.dirty = true
+	sampled := r.sampleRate > 0 && r.random() < r.sampleRate
+	r.mutex.Unlock()
+
+	if sampled {
+		log.WithFields(log.Fields{
+			"client":    query.Client.String(),
+			"qname":     name,
+			"qtype":     dns.TypeToString[query.Type],
+			"rcode":     rcode,
+			"source":    query.Source,
+			"latencyMs": float64(query.Latency.Microseconds()) / 1000,
+		}).Info("DNS query.")
+	}
+}
+
+func (r *Recorder) zoneOf(name string) (string, bool) {
+	for _, zone := range r.zones {
+		if dns.IsSubDomain(zone, name) {
+			return zone, true
+		}
+	}
+	return "", false
+}
+
+// TopNames returns the most queried names and types, at most limit entries
+func (r *Recorder) TopNames(limit int) []NameHits {
+	if r == nil {
+		return []NameHits{}
+	}
+	r.mutex.Lock()
+	names := r.nameHits()
+	r.mutex.Unlock()
+
+	if limit >= 0 && len(names) > limit {
+		names = names[:limit]
+	}
+	return names
+}
+
+// nameHits returns the hits of the names in descending order
+func (r *Recorder) nameHits() []NameHits {
+	names := make([]NameHits, 0, len(r.names))
+	for key, hits := range r.names {
+		names = append(names, NameHits{Name: key.name, Type: dns.TypeToString[key.rrType], Hits: hits})
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if names[i].Hits != names[j].Hits {
+			return names[i].Hits > names[j].Hits
+		}
+		if names[i].Name != names[j].Name {
+			return names[i].Name < names[j].Name
+		}
+		return names[i].Type < names[j].Type
+	})
+	return names
+}
+
+// Summary returns the query counters by the answer source, response code and zone
+func (r *Recorder) Summary() Summary {
+	summary := Summary{Sources: make(map[string]uint64), Rcodes: make(map[string]uint64), Zones: []ZoneHits{}}
+	if r == nil {
+		return summary
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	summary.Total = r.counters.Total
+	summary.UntrackedHits = r.counters.UntrackedHits
+	summary.Since = r.counters.Since
+	for source, hits := range r.counters.Sources {
+		summary.Sources[source] = hits
+	}
+	for rcode, hits := range r.counters.Rcodes {
+		summary.Rcodes[rcode] = hits
+	}
+	for zone, hits := range r.counters.Zones {
+		summary.Zones = append(summary.Zones, ZoneHits{Zone: zone, Hits: hits})
+	}
+	sort.Slice(summary.Zones, func(i, j int) bool {
+		if summary.Zones[i].Hits != summary.Zones[j].Hits {
+			return summary.Zones[i].Hits > summary.Zones[j].Hits
+		}
+		return summary.Zones[i].Zone < summary.Zones[j].Zone
+	})
+	return summary
+}
+
+// Reset clears the counters
+func (r *Recorder) Reset() {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	r.reset(time.Now())
+	r.mutex.Unlock()
+}
+
+// Persist writes the changed counters to the file, nothing is written without a file
+func (r *Recorder) Persist() error {
+	if r == nil || len(r.fileName) == 0 {
+		return nil
+	}
+	r.mutex.Lock()
+	if !r.dirty {
+		r.mutex.Unlock()
+		return nil
+	}
+	persisted := r.counters
+	persisted.Names = r.nameHits()
+	data, err := json.Marshal(&persisted)
+	r.dirty = false
+	r.mutex.Unlock()
+	if err == nil {
+		err = r.write(data)
+	}
+	if err != nil {
+		r.mutex.Lock()
+		r.dirty = true
+		r.mutex.Unlock()
+	}
+	return err
+}
+
+// write replaces the file atomically to keep the previous counters on failures
+func (r *Recorder) write(data []byte) error {
+	tmpFileName := r.fileName + ".tmp"
+	if err := ioutil.WriteFile(tmpFileName, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmpFileName, r.fileName)
+}
+
+// load reads the persisted counters, the counters start from zero if the file does not exist
+func (r *Recorder) load() error {
+	if len(r.fileName) == 0 {
+		return nil
+	}
+	data, err := ioutil.ReadFile(r.fileName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	persisted := counters{}
+	if err = json.Unmarshal(data, &persisted); err != nil {
+		return err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.reset(persisted.Since)
+	r.counters.Total = persisted.Total
+	r.counters.UntrackedHits = persisted.UntrackedHits
+	for source, hits := range persisted.Sources {
+		r.counters.Sources[source] = hits
+	}
+	for rcode, hits := range persisted.Rcodes {
+		r.counters.Rcodes[rcode] = hits
+	}
+	for zone, hits := range persisted.Zones {
+		r.counters.Zones[zone] = hits
+	}
+	for _, name := range persisted.Names {
+		rrType, ok := dns.StringToType[name.Type]
+		if !ok || len(r.names) >= util.MaxQueryStatsNames {
+			r.counters.UntrackedHits += name.Hits
+			continue
+		}
+		r.names[nameKey{name: name.Name, rrType: rrType}] += name.Hits
+	}
+	r.dirty = false
+	return nil
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package querylog
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/datastore"
+)
+
+func openStore(t *testing.T, name string) *datastore.BoltDB {
+	store := &datastore.BoltDB{FileName: name, TTL: 30}
+	assert.Nil(t, store.Open())
+	return store
+}
+
+func newQuery(name string, rrType uint16, rcode int, source string) *Query {
+	return &Query{Client: net.ParseIP("10.10.0.1"), Name: name, Type: rrType, Rcode: rcode, Source: source,
+		Latency: time.Millisecond}
+}
+
+func TestRecord(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+	store := openStore(t, "testquerylogdb")
+	defer store.Close()
+	assert.Nil(t, store.SetResourceRecord("example.com.", &datastore.ResourceRecord{Name: "app.example.com.",
+		Type: "A", Class: "IN", TTL: 30, RData: []string{"10.10.0.1"}}))
+	assert.Nil(t, store.SetResourceRecord("edge.example.com.", &datastore.ResourceRecord{
+		Name: "app.edge.example.com.", Type: "A", Class: "IN", TTL: 30, RData: []string{"10.10.0.2"}}))
+
+	r := NewRecorder(store, 0, "")
+	assert.Nil(t, r.ReloadZones())
+	r.Record(newQuery("App.Example.com", dns.TypeA, dns.RcodeSuccess, SourceLocal))
+	r.Record(newQuery("app.example.com.", dns.TypeA, dns.RcodeSuccess, SourceLocal))
+	r.Record(newQuery("app.example.com.", dns.TypeAAAA, dns.RcodeSuccess, SourceLocal))
+	r.Record(newQuery("app.edge.example.com.", dns.TypeA, dns.RcodeSuccess, SourceLocal))
+	r.Record(newQuery("www.example.org.", dns.TypeA, dns.RcodeNameError, SourceForward))
+
+	summary := r.Summary()
+	assert.Equal(t, uint64(5), summary.Total)
+	assert.Equal(t, map[string]uint64{SourceLocal: 4, SourceForward: 1}, summary.Sources)
+	assert.Equal(t, map[string]uint64{"NOERROR": 4, "NXDOMAIN": 1}, summary.Rcodes)
+	// The most specific zone is counted, the forwarded names are not in the zones
+	assert.Equal(t, []ZoneHits{{Zone: "example.com.", Hits: 3}, {Zone: "edge.example.com.", Hits: 1}}, summary.Zones)
+
+	assert.Equal(t, []NameHits{{Name: "app.example.com.", Type: "A", Hits: 2},
+		{Name: "app.edge.example.com.", Type: "A", Hits: 1}}, r.TopNames(2))
+	assert.Equal(t, 4, len(r.TopNames(10)))
+
+	r.Reset()
+	assert.Equal(t, uint64(0), r.Summary().Total)
+	assert.Equal(t, 0, len(r.TopNames(10)))
+}
+
+func TestSampling(t *testing.T) {
+	r := NewRecorder(nil, 0.5, "")
+	samples := 0
+	r.random = func() float64 {
+		samples++
+		return 0.4
+	}
+	r.Record(newQuery("app.example.com.", dns.TypeA, dns.RcodeSuccess, SourceLocal))
+	assert.Equal(t, 1, samples)
+
+	// The sampling is skipped when the query log is disabled
+	r = NewRecorder(nil, 0, "")
+	r.random = func() float64 {
+		samples++
+		return 0
+	}
+	r.Record(newQuery("app.example.com.", dns.TypeA, dns.RcodeSuccess, SourceLocal))
+	assert.Equal(t, 1, samples)
+}
+
+func TestPersist(t *testing.T) {
+	dir, err := ioutil.TempDir("", "querylog")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	fileName := filepath.Join(dir, "stats.json")
+
+	r := NewRecorder(nil, 0, fileName)
+	assert.Nil(t, r.load())
+	r.Record(newQuery("app.example.com.", dns.TypeA, dns.RcodeSuccess, SourceCache))
+	r.Record(newQuery("app.example.com.", dns.TypeA, dns.RcodeSuccess, SourceCache))
+	assert.Nil(t, r.Persist())
+
+	loaded := NewRecorder(nil, 0, fileName)
+	assert.Nil(t, loaded.load())
+	summary, loadedSummary := r.Summary(), loaded.Summary()
+	assert.True(t, summary.Since.Equal(loadedSummary.Since))
+	summary.Since, loadedSummary.Since = time.Time{}, time.Time{}
+	assert.Equal(t, summary, loadedSummary)
+	assert.Equal(t, []NameHits{{Name: "app.example.com.", Type: "A", Hits: 2}}, loaded.TopNames(10))
+
+	// The counters continue from the persisted values
+	loaded.Record(newQuery("app.example.com.", dns.TypeA, dns.RcodeSuccess, SourceCache))
+	assert.Equal(t, uint64(3), loaded.Summary().Sources[SourceCache])
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replication
+
+import (
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/datastore"
+	"dns-server/util"
+)
+
+// Notifier sends DNS NOTIFY to the secondaries on the changes of the data store
+type Notifier struct {
+	store       datastore.DataStore
+	secondaries []string
+	client      *dns.Client
+	stop        chan struct{}
+	done        sync.WaitGroup
+	generation  uint64
+}
+
+// NewNotifier creates the notifier of the secondaries, the secondaries are the ip:port of their dns servers
+func NewNotifier(store datastore.DataStore, secondaries []string) *Notifier {
+	return &Notifier{store: store, secondaries: secondaries,
+		client: &dns.Client{Net: "udp", Timeout: util.ReplicationTimeout}}
+}
+
+// Start checks the changes in the background, nothing is done without secondaries
+func (n *Notifier) Start() {
+	if n == nil || len(n.secondaries) == 0 {
+		return
+	}
+	n.generation, _ = n.store.Generation()
+	n.stop = make(chan struct{})
+	n.done.Add(1)
+	go n.run(n.stop)
+}
+
+// Stop stops the background checks
+func (n *Notifier) Stop() {
+	if n == nil || n.stop == nil {
+		return
+	}
+	close(n.stop)
+	n.stop = nil
+	n.done.Wait()
+}
+
+func (n *Notifier) run(stop chan struct{}) {
+	defer n.done.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			generation, err := n.store.Generation()
+			if err != nil || generation == n.generation {
+				continue
+			}
+			n.generation = generation
+			n.NotifyAll()
+		}
+	}
+}
+
+// NotifyAll sends NOTIFY to all the secondaries in parallel, the failed notifies are covered by the periodic pulls
+func (n *Notifier) NotifyAll() {
+	if n == nil {
+		return
+	}
+	var wg sync.WaitGroup
+	for _, secondary := range n.secondaries {
+		wg.Add(1)
+		go func(secondary string) {
+			defer wg.Done()
+			msg := new(dns.Msg)
+			msg.SetNotify(datastore.DefaultZone)
+			rsp, _, err := n.client.Exchange(msg, secondary)
+			if err != nil || rsp.Rcode != dns.RcodeSuccess {
+				log.Warnf("Failed to notify the secondary %s of the changes.", secondary)
+			}
+		}(secondary)
+	}
+	wg.Wait()
+}
+
+// Secondaries returns the notified secondaries
+func (n *Notifier) Secondaries() []string {
+	if n == nil {
+		return nil
+	}
+	return n.secondaries
+}
@@ -0,0 +1,189 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package replication
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/assert"
+
+	"dns-server/datastore"
+)
+
+const exampleDomain = "www.example.com."
+
+func openStore(t *testing.T, name string) *datastore.BoltDB {
+	store := &datastore.BoltDB{FileName: name, TTL: 30}
+	assert.Nil(t, store.Open())
+	return store
+}
+
+func newRecord(name string, address string, view string) *datastore.ResourceRecord {
+	return &datastore.ResourceRecord{Name: name, Type: "A", Class: "IN", TTL: 30, RData: []string{address}, View: view}
+}
+
+// newPrimary serves the replication endpoints of the store, the changes are answered gone while gone is set
+func newPrimary(store datastore.DataStore, gone *bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var response interface{}
+		switch r.URL.Path {
+		case SnapshotPath:
+			snapshot, err := datastore.TakeSnapshot(store)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			response = snapshot
+		case ChangesPath:
+			if *gone {
+				w.WriteHeader(http.StatusGone)
+				return
+			}
+			since, _ := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+			generation, _ := store.Generation()
+			changes, err := store.Changes(since, 1000)
+			if err != nil {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			response = ChangeList{Generation: generation, Changes: changes}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+}
+
+func address(t *testing.T, store datastore.DataStore, name string, view string) string {
+	rr, err := store.GetZoneResourceRecord("example.com.", name, "A", view)
+	if err != nil {
+		return ""
+	}
+	return rr.RData[0]
+}
+
+func TestReplicator(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+	primaryStore := openStore(t, "testprimarydb")
+	defer primaryStore.Close()
+	secondaryStore := openStore(t, "testsecondarydb")
+	defer secondaryStore.Close()
+
+	assert.Nil(t, primaryStore.SetView(&datastore.View{Name: "cell", Subnets: []string{"10.100.0.0/16"}}))
+	assert.Nil(t, primaryStore.SetResourceRecord("example.com.", newRecord(exampleDomain, "10.10.0.1", "")))
+	assert.Nil(t, primaryStore.SetResourceRecord("example.com.", newRecord(exampleDomain, "10.10.0.2", "cell")))
+	// The stale records of the secondary are replaced on the first sync
+	assert.Nil(t, secondaryStore.SetResourceRecord("example.com.", newRecord("old.example.com.", "10.10.0.9", "")))
+
+	gone := false
+	primary := newPrimary(primaryStore, &gone)
+	defer primary.Close()
+	u, _ := url.Parse(primary.URL)
+	r := NewReplicator(secondaryStore, u.Host, nil, "")
+
+	assert.Nil(t, r.Sync())
+	assert.Equal(t, "10.10.0.1", address(t, secondaryStore, exampleDomain, ""))
+	assert.Equal(t, "10.10.0.2", address(t, secondaryStore, exampleDomain, "cell"))
+	assert.Equal(t, "", address(t, secondaryStore, "old.example.com.", ""))
+	generation, _ := primaryStore.Generation()
+	assert.Equal(t, generation, r.Status().PrimaryGeneration)
+
+	// The changes are applied in order
+	assert.Nil(t, primaryStore.SetResourceRecord("example.com.", newRecord("app.example.com.", "10.10.0.3", "")))
+	assert.Nil(t, primaryStore.DelResourceRecord("example.com.", exampleDomain, "A", ""))
+	assert.Nil(t, primaryStore.DelView("cell"))
+	assert.Nil(t, r.Sync())
+	assert.Equal(t, "10.10.0.3", address(t, secondaryStore, "app.example.com.", ""))
+	assert.Equal(t, "", address(t, secondaryStore, exampleDomain, ""))
+	assert.Equal(t, "", address(t, secondaryStore, exampleDomain, "cell"))
+	_, err := secondaryStore.GetView("cell")
+	assert.Equal(t, datastore.ErrNotFound, err)
+	status := r.Status()
+	assert.Equal(t, RoleSecondary, status.Role)
+	generation, _ = primaryStore.Generation()
+	assert.Equal(t, generation, status.PrimaryGeneration)
+	assert.Equal(t, "", status.LastError)
+
+	// The snapshot is pulled again when the changes are gone
+	gone = true
+	assert.Nil(t, primaryStore.SetResourceRecord("example.com.", newRecord("api.example.com.", "10.10.0.4", "")))
+	assert.NotNil(t, r.Sync())
+	assert.Equal(t, "10.10.0.4", address(t, secondaryStore, "api.example.com.", ""))
+	assert.NotEqual(t, "", r.Status().LastError)
+	gone = false
+	assert.Nil(t, r.Sync())
+	generation, _ = primaryStore.Generation()
+	assert.Equal(t, generation, r.Status().PrimaryGeneration)
+}
+
+func TestReplicatorNotify(t *testing.T) {
+	defer func() {
+		_ = os.RemoveAll(datastore.DBPath)
+	}()
+	primaryStore := openStore(t, "testnotifyprimarydb")
+	defer primaryStore.Close()
+	secondaryStore := openStore(t, "testnotifysecondarydb")
+	defer secondaryStore.Close()
+
+	gone := false
+	primary := newPrimary(primaryStore, &gone)
+	defer primary.Close()
+	u, _ := url.Parse(primary.URL)
+	r := NewReplicator(secondaryStore, u.Host, nil, "")
+	r.Start()
+	defer r.Stop()
+
+	// The secondary dns server pulls the changes on notify
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := &dns.Server{PacketConn: conn, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, req *dns.Msg) {
+		if req.Opcode == dns.OpcodeNotify {
+			r.Notify()
+		}
+		response := new(dns.Msg)
+		response.SetReply(req)
+		_ = w.WriteMsg(response)
+	})}
+	go func() {
+		_ = server.ActivateAndServe()
+	}()
+	defer server.Shutdown()
+
+	n := NewNotifier(primaryStore, []string{conn.LocalAddr().String()})
+	assert.Equal(t, []string{conn.LocalAddr().String()}, n.Secondaries())
+	n.Start()
+	defer n.Stop()
+
+	assert.Nil(t, primaryStore.SetResourceRecord("example.com.", newRecord(exampleDomain, "10.10.0.1", "")))
+	deadline := time.Now().Add(5 * time.Second)
+	for address(t, secondaryStore, exampleDomain, "") == "" && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+	}
+	assert.Equal(t, "10.10.0.1", address(t, secondaryStore, exampleDomain, ""))
+}
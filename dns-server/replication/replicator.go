@@ -0,0 +1,341 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package replication replicates the data store of the primary dns server to the secondary dns servers, the
+// secondaries pull the changes from the management api of the primary and the primary notifies the secondaries of
+// the changes with DNS NOTIFY(RFC 1996)
+package replication
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"dns-server/datastore"
+	"dns-server/util"
+)
+
+// Roles of the dns server
+const (
+	// RolePrimary the data store is updated by the management api, the standalone servers are primaries
+	RolePrimary = "primary"
+	// RoleSecondary the data store is replicated from the primary
+	RoleSecondary = "secondary"
+)
+
+// Replication endpoints of the management api
+const (
+	ChangesPath  = "/mep/dns_server_mgmt/v1/replication/changes"
+	SnapshotPath = "/mep/dns_server_mgmt/v1/replication/snapshot"
+)
+
+// ChangeList the changes after the requested generation and the generation of the last change
+type ChangeList struct {
+	Generation uint64             `json:"generation"`
+	Changes    []datastore.Change `json:"changes"`
+}
+
+// Status replication state of the dns server
+type Status struct {
+	Role       string `json:"role"`
+	Generation uint64 `json:"generation"`
+	// Primary management endpoint of the primary, secondary only
+	Primary string `json:"primary,omitempty"`
+	// PrimaryGeneration generation of the primary replicated, secondary only
+	PrimaryGeneration uint64    `json:"primaryGeneration,omitempty"`
+	LastSync          time.Time `json:"lastSync"`
+	LastError         string    `json:"lastError,omitempty"`
+	// Secondaries notified of the changes, primary only
+	Secondaries []string `json:"secondaries,omitempty"`
+}
+
+// Replicator pulls the changes of the primary in to the data store of the secondary
+type Replicator struct {
+	store   datastore.DataStore
+	primary string
+	scheme  string
+	token   string
+	client  http.Client
+	notify  chan struct{}
+	stop    chan struct{}
+	done    sync.WaitGroup
+	syncing sync.Mutex
+
+	mutex      sync.Mutex
+	synced     bool
+	generation uint64
+	lastSync   time.Time
+	lastError  string
+}
+
+// NewReplicator creates the replicator of the primary, the primary is the host:port of its management api. The
+// changes are pulled over https if the tls config is given and the token is sent as the bearer token if not empty
+func NewReplicator(store datastore.DataStore, primary string, tlsConfig *tls.Config, token string) *Replicator {
+	r := &Replicator{store: store, primary: primary, scheme: "http", token: token,
+		client: http.Client{Timeout: util.ReplicationTimeout}, notify: make(chan struct{}, 1)}
+	if tlsConfig != nil {
+		r.scheme = "https"
+		r.client.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	return r
+}
+
+// Start pulls the changes in the background, on notify and periodically
+func (r *Replicator) Start() {
+	if r == nil {
+		return
+	}
+	r.stop = make(chan struct{})
+	r.done.Add(1)
+	go r.run(r.stop)
+}
+
+// Stop stops the background pulling
+func (r *Replicator) Stop() {
+	if r == nil || r.stop == nil {
+		return
+	}
+	close(r.stop)
+	r.stop = nil
+	r.done.Wait()
+}
+
+// Notify triggers pulling the changes, the notifies received during a pull are coalesced
+func (r *Replicator) Notify() {
+	if r == nil {
+		return
+	}
+	select {
+	case r.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Replicator) run(stop chan struct{}) {
+	defer r.done.Done()
+	ticker := time.NewTicker(util.ReplicationSyncInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.Sync(); err != nil {
+			log.Errorf("Failed to replicate from the primary %s(%s).", r.primary, err.Error())
+		}
+		select {
+		case <-stop:
+			return
+		case <-r.notify:
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync pulls the changes of the primary since the last sync, the whole data store is replaced by the snapshot of the
+// primary on the first sync and when the changes are no longer available on the primary
+func (r *Replicator) Sync() error {
+	r.syncing.Lock()
+	defer r.syncing.Unlock()
+
+	err := r.sync()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if err != nil {
+		r.lastError = err.Error()
+		return err
+	}
+	r.lastError = ""
+	r.lastSync = time.Now()
+	return nil
+}
+
+func (r *Replicator) sync() error {
+	snapshotted := false
+	if !r.isSynced() {
+		if err := r.applySnapshot(); err != nil {
+			return err
+		}
+		snapshotted = true
+	}
+	for {
+		generation := r.primaryGeneration()
+		list := &ChangeList{}
+		err := r.get(ChangesPath, url.Values{"since": {strconv.FormatUint(generation, 10)},
+			"limit": {strconv.Itoa(util.MaxReplicationChanges)}}, list)
+		// The primary lost the changes or restarted with another data store, the snapshot is pulled once per sync
+		if err == datastore.ErrChangeLogTruncated || (err == nil && list.Generation < generation) {
+			if snapshotted {
+				return fmt.Errorf("changes since generation %d are not available on the primary", generation)
+			}
+			log.Warnf("Changes since generation %d are not available on the primary, replacing with snapshot.",
+				generation)
+			if err = r.applySnapshot(); err != nil {
+				return err
+			}
+			snapshotted = true
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		for i := range list.Changes {
+			if err = r.apply(&list.Changes[i]); err != nil {
+				return err
+			}
+			r.setGeneration(list.Changes[i].Generation)
+		}
+		if len(list.Changes) < util.MaxReplicationChanges {
+			return nil
+		}
+	}
+}
+
+// apply applies the change of the primary, the deletes of the missing records and views are ignored
+func (r *Replicator) apply(change *datastore.Change) error {
+	switch {
+	case change.View != nil && change.Deleted:
+		if err := r.store.DelView(change.View.Name); err != nil && err != datastore.ErrNotFound {
+			return err
+		}
+	case change.View != nil:
+		return r.store.SetView(change.View)
+	case change.Record != nil && change.Deleted:
+		rr := change.Record
+		_, err := r.store.GetZoneResourceRecord(change.Zone, rr.Name, rr.Type, rr.View)
+		if err == datastore.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return r.store.DelResourceRecord(change.Zone, rr.Name, rr.Type, rr.View)
+	case change.Record != nil:
+		return r.store.SetResourceRecord(change.Zone, change.Record)
+	}
+	return nil
+}
+
+// applySnapshot replaces the views and zones with the snapshot of the primary
+func (r *Replicator) applySnapshot() error {
+	snapshot := &datastore.Snapshot{}
+	if err := r.get(SnapshotPath, nil, snapshot); err != nil {
+		return err
+	}
+	views, err := r.store.ListViews()
+	if err != nil {
+		return err
+	}
+	zones, err := r.store.ListZones()
+	if err != nil {
+		return err
+	}
+
+	// The views are added before their records and deleted after
+	replicatedViews := make(map[string]bool, len(snapshot.Views))
+	for i := range snapshot.Views {
+		if err = r.store.SetView(&snapshot.Views[i]); err != nil {
+			return err
+		}
+		replicatedViews[snapshot.Views[i].Name] = true
+	}
+	replicatedZones := make(map[string]bool, len(snapshot.Zones))
+	for _, zone := range snapshot.Zones {
+		records := []datastore.ResourceRecord{}
+		if zone.RR != nil {
+			records = *zone.RR
+		}
+		if err = r.store.ImportZone(zone.Zone, records, true); err != nil {
+			return fmt.Errorf("replacing zone %s failed(%s)", zone.Zone, err.Error())
+		}
+		replicatedZones[zone.Zone] = true
+	}
+	for _, zone := range zones {
+		if !replicatedZones[zone] {
+			if err = r.store.ImportZone(zone, nil, true); err != nil {
+				return fmt.Errorf("emptying zone %s failed(%s)", zone, err.Error())
+			}
+		}
+	}
+	for _, view := range views {
+		if !replicatedViews[view.Name] {
+			if err = r.store.DelView(view.Name); err != nil && err != datastore.ErrNotFound {
+				return err
+			}
+		}
+	}
+
+	r.mutex.Lock()
+	r.synced = true
+	r.generation = snapshot.Generation
+	r.mutex.Unlock()
+	log.Infof("Replicated the snapshot of the primary at generation %d.", snapshot.Generation)
+	return nil
+}
+
+// get reads the json response of the primary management api, ErrChangeLogTruncated if the primary answers gone
+func (r *Replicator) get(path string, query url.Values, response interface{}) error {
+	endpoint := url.URL{Scheme: r.scheme, Host: r.primary, Path: path, RawQuery: query.Encode()}
+	httpReq, err := http.NewRequest(http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return err
+	}
+	if len(r.token) != 0 {
+		httpReq.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	httpResp, err := r.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode == http.StatusGone {
+		return datastore.ErrChangeLogTruncated
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("replication request to the primary failed(%s)", httpResp.Status)
+	}
+	return json.NewDecoder(httpResp.Body).Decode(response)
+}
+
+func (r *Replicator) isSynced() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.synced
+}
+
+func (r *Replicator) primaryGeneration() uint64 {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.generation
+}
+
+func (r *Replicator) setGeneration(generation uint64) {
+	r.mutex.Lock()
+	r.generation = generation
+	r.mutex.Unlock()
+}
+
+// Status returns the replication state of the secondary
+func (r *Replicator) Status() Status {
+	generation, _ := r.store.Generation()
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return Status{Role: RoleSecondary, Generation: generation, Primary: r.primary, PrimaryGeneration: r.generation,
+		LastSync: r.lastSync, LastError: r.lastError}
+}
@@ -17,6 +17,8 @@
 // Package util utility package
 package util
 
+import "time"
+
 const (
 	// DBStringExceptions .
 	DBStringExceptions = "/.\\"
@@ -38,12 +40,78 @@ const (
 	DefaultTTL = 30
 	// DNSUDPPacketSize  DNS UDP packet size.
 	DNSUDPPacketSize = 65535
+	// MaxEDNS0UDPSize  Maximum EDNS0 UDP response size, advertised in the responses.
+	MaxEDNS0UDPSize = 4096
 	// ForwardRetryCount  Max Forward retry count.
 	ForwardRetryCount = 3
+	// DefaultDNSOverTLSPort  Default DNS-over-TLS port of the upstreams.
+	DefaultDNSOverTLSPort = 853
+	// MaxForwarderFailures  Consecutive failures marking a forwarder unhealthy.
+	MaxForwarderFailures = 3
+	// ForwarderRetryInterval  Interval before an unhealthy forwarder is tried again.
+	ForwarderRetryInterval = 10 * time.Second
+	// DefaultCacheSize  Default number of the cached answers.
+	DefaultCacheSize = 10000
+	// DefaultCacheMaxTTL  Default maximum ttl of the cached answers in seconds.
+	DefaultCacheMaxTTL = 3600
+	// MaxRecordWeight  Maximum load balancing weight of a record address.
+	MaxRecordWeight = 1000
+	// DefaultHealthCheckInterval  Default interval between the health probes in seconds.
+	DefaultHealthCheckInterval = 10
+	// DefaultHealthCheckTimeout  Default timeout of a health probe in seconds.
+	DefaultHealthCheckTimeout = 2
+	// MaxHealthCheckInterval  Maximum interval between the health probes in seconds.
+	MaxHealthCheckInterval = 3600
+	// MaxHealthCheckPathLength  Maximum length of the http health check path.
+	MaxHealthCheckPathLength = 256
+	// HealthCheckFailures  Consecutive probe failures marking an address unhealthy.
+	HealthCheckFailures = 2
+	// BalancerReloadInterval  Interval of reloading the load balanced records from the data store.
+	BalancerReloadInterval = 5 * time.Second
+	// MaxViewNameLength  Maximum length of a view name.
+	MaxViewNameLength = 64
+	// MaxQueryStatsNames  Maximum number of the names and types counted in the query statistics.
+	MaxQueryStatsNames = 10000
+	// QueryStatsZoneReloadInterval  Interval of reloading the zones of the query statistics.
+	QueryStatsZoneReloadInterval = 5 * time.Second
+	// QueryStatsPersistInterval  Interval of persisting the query statistics.
+	QueryStatsPersistInterval = time.Minute
+	// DefaultTopNamesLimit  Default number of the most queried names.
+	DefaultTopNamesLimit = 10
+	// MaxChangeLogEntries  Number of the latest changes kept for the replication.
+	MaxChangeLogEntries = 10000
+	// MaxReplicationChanges  Maximum number of the changes pulled in one request.
+	MaxReplicationChanges = 1000
+	// ReplicationSyncInterval  Interval of pulling the changes from the primary without notify.
+	ReplicationSyncInterval = 30 * time.Second
+	// ReplicationTimeout  Timeout of the replication requests and notifies.
+	ReplicationTimeout = 5 * time.Second
+	// MinTokenLength  Minimum length of the management api token.
+	MinTokenLength = 16
+	// MaxTokenLength  Maximum length of the management api token.
+	MaxTokenLength = 4096
+	// EtcdDialTimeout  Timeout of connecting to the etcd endpoints.
+	EtcdDialTimeout = 5 * time.Second
+	// EtcdRequestTimeout  Timeout of the etcd requests.
+	EtcdRequestTimeout = 5 * time.Second
+	// EtcdRetryInterval  Interval of reloading the data store after the etcd watch failed.
+	EtcdRetryInterval = time.Second
+	// EtcdUpdateRetries  Number of retries of an update conflicting with the other dns servers.
+	EtcdUpdateRetries = 5
+	// EtcdKeyPrefix  Key prefix of the data stores in etcd, followed by the db name.
+	EtcdKeyPrefix = "/edgegallery/dns/"
 	// DefaultIP  default ip.
 	DefaultIP = "0.0.0.0"
 	// MaxPacketSize  Maximum packet size.
 	MaxPacketSize = "4K"
+	// MaxZoneFileSize  Maximum size of the imported zone file.
+	MaxZoneFileSize = "4M"
+	// ZoneFileContentType  Content type of the exported zone file.
+	ZoneFileContentType = "text/dns"
+	// DefaultListLimit  Default page size of the record listing.
+	DefaultListLimit = 100
+	// MaxListLimit  Maximum page size of the record listing.
+	MaxListLimit = 1000
 )
 
 const MaxDNSFQDNLength = 253
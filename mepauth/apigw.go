@@ -18,6 +18,7 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"errors"
@@ -29,6 +30,8 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"sync"
+	"tracing"
 
 	log "github.com/sirupsen/logrus"
 
@@ -41,15 +44,24 @@ const configFormat string = `{ "name": "%s", "config": %s }`
 // API gateway initializer
 type apiGwInitializer struct {
 	tlsConfig *tls.Config
+	// context of the traced operation, the api gateway administration calls are traced as its children
+	ctx context.Context
+	// app instances whose consumers are available on the api gateway
+	appConsumers *sync.Map
 }
 
-func (i *apiGwInitializer) InitAPIGateway(trustedNetworks *[]byte) error {
+func (i *apiGwInitializer) InitAPIGateway(trustedNetworks *[]byte) (err error) {
+	ctx, span := tracing.StartSpan(context.Background(), "InitAPIGateway", tracing.SpanKindInternal)
+	i.ctx = ctx
+	defer func() {
+		span.End(err)
+	}()
 	apiGwUrl, getApiGwUrlErr := util.GetAPIGwURL()
 	if getApiGwUrlErr != nil {
 		log.Error("Failed to get API gateway URL")
 		return getApiGwUrlErr
 	}
-	err := i.SetApiGwConsumer(apiGwUrl)
+	err = i.SetApiGwConsumer(apiGwUrl)
 	if err != nil {
 		return err
 	}
@@ -68,6 +80,11 @@ func (i *apiGwInitializer) InitAPIGateway(trustedNetworks *[]byte) error {
 		return err
 	}
 
+	err = i.SetupTracingPlugin(apiGwUrl)
+	if err != nil {
+		return err
+	}
+
 	log.Info("Initialization of consumer is successful")
 	return nil
 }
@@ -88,6 +105,34 @@ func (i *apiGwInitializer) SetupHttpLogPlugin(apiGwUrl string) error {
 	return nil
 }
 
+// SetupTracingPlugin enables the global zipkin plugin tracing the api gateway requests, the w3c trace context is
+// propagated to the upstream services. The plugin is not enabled if apigw_tracing_endpoint is not configured.
+func (i *apiGwInitializer) SetupTracingPlugin(apiGwUrl string) error {
+	endpoint := util.GetAppConfig("apigw_tracing_endpoint")
+	if len(endpoint) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(&models.TracingPluginInfo{
+		Name: util.TracingPlugin,
+		Config: models.TracingConfigInfo{
+			HTTPEndpoint:      endpoint,
+			SampleRatio:       1,
+			HeaderType:        util.TracingHeaderType,
+			DefaultHeaderType: util.TracingHeaderType,
+		},
+	})
+	if err != nil {
+		log.Error("Failed to marshal tracing plugin data")
+		return err
+	}
+	err = i.SendPostRequest(apiGwUrl+util.PluginPath, data)
+	if err != nil {
+		log.Error("Enable tracing plugin failed")
+		return err
+	}
+	return nil
+}
+
 func (i *apiGwInitializer) SetApiGwConsumer(apiGwUrl string) error {
 	// add mepauth consumer to ApiGw
 	consumerUrl := apiGwUrl + "/consumers"
@@ -120,6 +165,79 @@ func (i *apiGwInitializer) SetApiGwConsumer(apiGwUrl string) error {
 	return nil
 }
 
+// EnsureAppConsumer creates the api gateway consumer of the app instance with the jwt credential keyed by the app
+// instance id, the tokens issued by the app instance id are attributed to the consumer
+func (i *apiGwInitializer) EnsureAppConsumer(appInsId string) (err error) {
+	if i.appConsumers != nil {
+		if _, ok := i.appConsumers.Load(appInsId); ok {
+			return nil
+		}
+	}
+	operation, span := i.startOperation("EnsureAppConsumer")
+	defer func() {
+		span.End(err)
+	}()
+	apiGwUrl, err := util.GetAPIGwURL()
+	if err != nil {
+		log.Error("Failed to get API gateway URL")
+		return err
+	}
+	consumerUrl := apiGwUrl + util.ConsumerPath + "/" + appInsId
+	consumer, err := json.Marshal(&models.ConsumerInfo{Username: appInsId})
+	if err != nil {
+		return err
+	}
+	if err = operation.SendPutRequest(consumerUrl, consumer); err != nil {
+		log.Error("Failed to add the consumer of app instance " + appInsId + ".")
+		return err
+	}
+
+	jwtPublicKey, err := util.GetPublicKey()
+	if err != nil {
+		return err
+	}
+	credential, err := json.Marshal(&models.JwtCredentialInfo{Algorithm: util.JwtAlgorithm, Key: appInsId,
+		RsaPublicKey: string(jwtPublicKey)})
+	if err != nil {
+		return err
+	}
+	// conflict is returned if the credential already exists
+	if err = operation.SendPostRequest(consumerUrl+"/jwt", credential); err != nil {
+		log.Error("Failed to add the jwt credential of app instance " + appInsId + ".")
+		return err
+	}
+	if i.appConsumers != nil {
+		i.appConsumers.Store(appInsId, true)
+	}
+	return nil
+}
+
+// DeleteAppConsumer deletes the api gateway consumer of the app instance, the jwt credential and the plugins scoped
+// to the consumer are deleted with it
+func (i *apiGwInitializer) DeleteAppConsumer(appInsId string) (err error) {
+	if i.appConsumers != nil {
+		i.appConsumers.Delete(appInsId)
+	}
+	operation, span := i.startOperation("DeleteAppConsumer")
+	defer func() {
+		span.End(err)
+	}()
+	apiGwUrl, err := util.GetAPIGwURL()
+	if err != nil {
+		log.Error("Failed to get API gateway URL")
+		return err
+	}
+	return operation.SendDeleteRequest(apiGwUrl + util.ConsumerPath + "/" + appInsId)
+}
+
+// startOperation returns the initializer sending the api gateway administration calls in the span of the operation
+func (i *apiGwInitializer) startOperation(name string) (*apiGwInitializer, *tracing.Span) {
+	operation := *i
+	ctx, span := tracing.StartSpan(context.Background(), name, tracing.SpanKindInternal)
+	operation.ctx = ctx
+	return &operation, span
+}
+
 func (i *apiGwInitializer) SetupApiGwMepServer(apiGwUrl string) error {
 	// add mep server service and route to apiGw.
 	// since mep is also in the same pos, same ip address will work
@@ -135,7 +253,8 @@ func (i *apiGwInitializer) SetupApiGwMepServer(apiGwUrl string) error {
 		log.Error(msg)
 		return errors.New(msg)
 	}
-	err := i.AddServiceRoute(util.MepserverName, []string{util.MepServerServiceMgmt, util.MepServerAppSupport},
+	err := i.AddServiceRoute(util.MepserverName, []string{util.MepServerServiceMgmt, util.MepServerAppSupport,
+		util.MepServerRni, util.MepServerLocation},
 		"https://"+mepServerHost+":"+mepServerPort, false)
 	if err != nil {
 		log.Error("Add mep server route to apiGw failed")
@@ -291,33 +410,35 @@ func (i *apiGwInitializer) AddServiceRoute(serviceName string, servicePaths []st
 
 // Send post request
 func (i *apiGwInitializer) SendPostRequest(consumerURL string, jsonStr []byte) error {
+	return i.sendRequest(httplib.Post(consumerURL), http.MethodPost, jsonStr)
+}
 
-	req := httplib.Post(consumerURL)
-	req.Header(util.ContentType, util.JsonUtf8)
-	req.SetTLSClientConfig(i.tlsConfig)
-	req.Body(jsonStr)
-	resp, err := req.Response()
-	if err != nil {
-		log.Error("Request sending is having error")
-		return err
-	}
-	defer resp.Body.Close()
-	_, err2 := ioutil.ReadAll(resp.Body)
-	if err2 != nil {
-		log.Error("Request's response not received")
-	}
+// Send put request
+func (i *apiGwInitializer) SendPutRequest(consumerURL string, jsonStr []byte) error {
+	return i.sendRequest(httplib.Put(consumerURL), http.MethodPut, jsonStr)
+}
 
-	if !(resp.StatusCode >= 200 && resp.StatusCode <= 299) && resp.StatusCode != http.StatusConflict {
-		log.Error("Request sending returned failure response with status code " + strconv.Itoa(resp.StatusCode))
-		return errors.New("request sending returned failure response, status is " + strconv.Itoa(resp.StatusCode))
-	}
-	return nil
+// Send delete request
+func (i *apiGwInitializer) SendDeleteRequest(url string) error {
+	return i.sendRequest(httplib.Delete(url), http.MethodDelete, nil)
 }
 
-// Send post request
-func (i *apiGwInitializer) SendPutRequest(consumerURL string, jsonStr []byte) error {
+// sendRequest sends the api gateway administration request in a client span of the initialization
+func (i *apiGwInitializer) sendRequest(req *httplib.BeegoHTTPRequest, method string, jsonStr []byte) (err error) {
+	ctx, span := tracing.StartSpan(i.ctx, method+" "+req.GetRequest().URL.Host, tracing.SpanKindClient)
+	defer func() {
+		span.End(err)
+	}()
+	if span != nil {
+		span.SetAttribute("http.method", method)
+		span.SetAttribute("http.url", req.GetRequest().URL.String())
+		header := http.Header{}
+		tracing.Inject(ctx, header)
+		for key := range header {
+			req.Header(key, header.Get(key))
+		}
+	}
 
-	req := httplib.Put(consumerURL)
 	req.Header(util.ContentType, util.JsonUtf8)
 	req.SetTLSClientConfig(i.tlsConfig)
 	req.Body(jsonStr)
@@ -327,12 +448,15 @@ func (i *apiGwInitializer) SendPutRequest(consumerURL string, jsonStr []byte) er
 		return err
 	}
 	defer resp.Body.Close()
+	span.SetAttribute("http.status_code", resp.StatusCode)
 	_, err2 := ioutil.ReadAll(resp.Body)
 	if err2 != nil {
 		log.Error("Request's response not received")
 	}
 
-	if !(resp.StatusCode >= 200 && resp.StatusCode <= 299) && resp.StatusCode != http.StatusConflict {
+	// conflict is returned for the existing entities and not found for the deleted entities
+	if !(resp.StatusCode >= 200 && resp.StatusCode <= 299) && resp.StatusCode != http.StatusConflict &&
+		!(method == http.MethodDelete && resp.StatusCode == http.StatusNotFound) {
 		log.Error("Request sending returned failure response with status code " + strconv.Itoa(resp.StatusCode))
 		return errors.New("request sending returned failure response, status is " + strconv.Itoa(resp.StatusCode))
 	}
@@ -17,13 +17,22 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	. "github.com/agiledragon/gomonkey"
 	"github.com/astaxie/beego"
 	log "github.com/sirupsen/logrus"
+	"io/ioutil"
+	"mepauth/models"
 	"mepauth/util"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"tracing"
 
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -141,6 +150,37 @@ func TestSetupHttpLogPlugin(t *testing.T) {
 	})
 }
 
+func TestSetupTracingPlugin(t *testing.T) {
+	Convey("Setup Tracing Plugin", t, func() {
+		var initializer *apiGwInitializer
+		var pluginData []byte
+		patch1 := ApplyMethod(reflect.TypeOf(initializer), "SendPostRequest", func(_ *apiGwInitializer, _ string,
+			data []byte) error {
+			pluginData = data
+			return nil
+		})
+		defer patch1.Reset()
+		Convey("for not configured", func() {
+			i := &apiGwInitializer{}
+			err := i.SetupTracingPlugin("")
+			So(err, ShouldBeNil)
+			So(pluginData, ShouldBeNil)
+		})
+		Convey("for success", func() {
+			_ = beego.AppConfig.Set("apigw_tracing_endpoint", "http://localhost:9411/api/v2/spans")
+			defer beego.AppConfig.Set("apigw_tracing_endpoint", "")
+			i := &apiGwInitializer{}
+			err := i.SetupTracingPlugin("")
+			So(err, ShouldBeNil)
+			plugin := &models.TracingPluginInfo{}
+			So(json.Unmarshal(pluginData, plugin), ShouldBeNil)
+			So(plugin.Name, ShouldEqual, util.TracingPlugin)
+			So(plugin.Config.HTTPEndpoint, ShouldEqual, "http://localhost:9411/api/v2/spans")
+			So(plugin.Config.HeaderType, ShouldEqual, util.TracingHeaderType)
+		})
+	})
+}
+
 func TestSetApiGwConsumer(t *testing.T) {
 	err := beego.LoadAppConfig("ini", "../conf/app.conf")
 	if err != nil {
@@ -331,3 +371,87 @@ func TestAddServiceRoute(t *testing.T) {
 		})
 	})
 }
+
+func TestSendRequestTraceParent(t *testing.T) {
+	Convey("send request in the initialization trace", t, func() {
+		var header string
+		apiGw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header.Get(tracing.TraceParentHeader)
+			w.WriteHeader(http.StatusConflict)
+		}))
+		defer apiGw.Close()
+
+		Convey("for tracing enabled", func() {
+			tracing.Start(tracing.Options{ServiceName: "mepauth", Endpoint: "http://127.0.0.1:1/v1/traces"})
+			defer tracing.Shutdown(context.Background())
+			ctx, span := tracing.StartSpan(context.Background(), "InitAPIGateway", tracing.SpanKindInternal)
+			i := &apiGwInitializer{ctx: ctx}
+			err := i.SendPostRequest(apiGw.URL+"/consumers", []byte("{}"))
+			So(err, ShouldBeNil)
+			traceParent := span.SpanContext().TraceParent()
+			traceId := strings.Split(traceParent, "-")[1]
+			So(header, ShouldStartWith, "00-"+traceId+"-")
+			So(header, ShouldNotEqual, traceParent)
+		})
+		Convey("for tracing disabled", func() {
+			i := &apiGwInitializer{}
+			err := i.SendPutRequest(apiGw.URL+"/routes/mepauth", []byte("{}"))
+			So(err, ShouldBeNil)
+			So(header, ShouldBeEmpty)
+		})
+	})
+}
+
+// appConsumerApiGwUrl is the address of the api gateway stub, the patched function must not capture the test locals
+var appConsumerApiGwUrl string
+
+func TestEnsureAppConsumer(t *testing.T) {
+	Convey("ensure app instance consumer", t, func() {
+		appInsId := "5abe4782-2c70-4e47-9a4e-0ee3a1a0fd1f"
+		var requests []string
+		var bodies []string
+		status := http.StatusCreated
+		apiGw := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests = append(requests, r.Method+" "+r.URL.Path)
+			bodies = append(bodies, string(body))
+			w.WriteHeader(status)
+		}))
+		defer apiGw.Close()
+		appConsumerApiGwUrl = apiGw.URL
+		patches := ApplyFunc(util.GetAPIGwURL, func() (string, error) {
+			return appConsumerApiGwUrl, nil
+		})
+		patches.ApplyFunc(util.GetPublicKey, func() ([]byte, error) {
+			return []byte("public_key"), nil
+		})
+		defer patches.Reset()
+
+		Convey("for success", func() {
+			i := &apiGwInitializer{appConsumers: &sync.Map{}}
+			So(i.EnsureAppConsumer(appInsId), ShouldBeNil)
+			// the available consumer is not added again
+			So(i.EnsureAppConsumer(appInsId), ShouldBeNil)
+			So(requests, ShouldResemble, []string{
+				"PUT /consumers/" + appInsId,
+				"POST /consumers/" + appInsId + "/jwt",
+			})
+			So(bodies[0], ShouldEqual, `{"username":"`+appInsId+`"}`)
+			So(bodies[1], ShouldEqual, `{"algorithm":"RS512","key":"`+appInsId+`","rsa_public_key":"public_key"}`)
+
+			status = http.StatusNotFound
+			So(i.DeleteAppConsumer(appInsId), ShouldBeNil)
+			So(requests[2], ShouldEqual, "DELETE /consumers/"+appInsId)
+			status = http.StatusConflict
+			So(i.EnsureAppConsumer(appInsId), ShouldBeNil)
+			So(len(requests), ShouldEqual, 5)
+		})
+		Convey("for fail - api gateway error", func() {
+			status = http.StatusBadRequest
+			i := &apiGwInitializer{appConsumers: &sync.Map{}}
+			So(i.EnsureAppConsumer(appInsId), ShouldNotBeNil)
+			So(i.EnsureAppConsumer(appInsId), ShouldNotBeNil)
+			So(len(requests), ShouldEqual, 2)
+		})
+	})
+}
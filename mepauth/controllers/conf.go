@@ -133,6 +133,11 @@ func (c *ConfController) Delete() {
 		c.handleLoggingForError(clientIp, http.StatusBadRequest, err.Error())
 		return
 	}
+	if AppConsumers != nil {
+		if err = AppConsumers.DeleteAppConsumer(appInsId); err != nil {
+			log.Warn("Failed to delete the API gateway consumer of app instance " + appInsId + ".")
+		}
+	}
 	c.Data["json"] = "Delete success."
 	c.handleLoggingForSuccess(clientIp, "Delete success.")
 }
@@ -0,0 +1,47 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package controllers
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+// AppConsumerManager manages the api gateway consumers of the app instances
+type AppConsumerManager interface {
+	// EnsureAppConsumer creates the consumer and the jwt credential of the app instance if not exist
+	EnsureAppConsumer(appInsId string) error
+	// DeleteAppConsumer deletes the consumer of the app instance with its credentials and plugins
+	DeleteAppConsumer(appInsId string) error
+}
+
+// AppConsumers manages the api gateway consumers of the app instances, set once the api gateway is initialized
+var AppConsumers AppConsumerManager
+
+// tokenIssuer returns the issuer of the app instance tokens. The api gateway attributes the requests to the consumer
+// whose jwt credential key is the token issuer, hence the app instance id is the issuer if its consumer is available
+// and the tokens are attributed to the shared mep app consumer otherwise
+func tokenIssuer(appInsId string, mepAuthKey string) string {
+	if AppConsumers == nil {
+		return mepAuthKey
+	}
+	if err := AppConsumers.EnsureAppConsumer(appInsId); err != nil {
+		log.Warn("API gateway consumer of app instance " + appInsId + " is not available, the token is issued " +
+			"for the shared consumer.")
+		return mepAuthKey
+	}
+	return appInsId
+}
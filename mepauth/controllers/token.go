@@ -130,7 +130,7 @@ func generateJwtToken(appInsId string, clientIp string) (*string, error) {
 	claims := jwtClaims{
 		StandardClaims: jwt.StandardClaims{
 			ExpiresAt: jwt.At(time.Now().Add(time.Hour * 1)),
-			Issuer:    mepAuthKey,
+			Issuer:    tokenIssuer(appInsId, mepAuthKey),
 			Subject:   appInsId,
 		},
 		ClientIp: clientIp,
@@ -353,3 +353,34 @@ func TestGenerateJwtToken(t *testing.T) {
 		})
 	})
 }
+
+type fakeAppConsumers struct {
+	err error
+}
+
+func (f *fakeAppConsumers) EnsureAppConsumer(string) error {
+	return f.err
+}
+
+func (f *fakeAppConsumers) DeleteAppConsumer(string) error {
+	return f.err
+}
+
+func TestTokenIssuer(t *testing.T) {
+	Convey("token issuer", t, func() {
+		appInsId := "5abe4782-2c70-4e47-9a4e-0ee3a1a0fd1f"
+		defer func() {
+			AppConsumers = nil
+		}()
+		Convey("for the app instance consumer", func() {
+			AppConsumers = &fakeAppConsumers{}
+			So(tokenIssuer(appInsId, "mepauth"), ShouldEqual, appInsId)
+		})
+		Convey("for the shared consumer", func() {
+			AppConsumers = &fakeAppConsumers{err: errors.New("api gateway error")}
+			So(tokenIssuer(appInsId, "mepauth"), ShouldEqual, "mepauth")
+			AppConsumers = nil
+			So(tokenIssuer(appInsId, "mepauth"), ShouldEqual, "mepauth")
+		})
+	})
+}
@@ -14,4 +14,4 @@
 # limitations under the License.
 
 MEPAUTH_VERSION=latest
-docker build --no-cache -t edgegallery/mepauth:${MEPAUTH_VERSION} -f docker/Dockerfile .
+docker build --no-cache -t edgegallery/mepauth:${MEPAUTH_VERSION} -f docker/Dockerfile ..
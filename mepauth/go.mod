@@ -30,4 +30,7 @@ require (
 	golang.org/x/crypto v0.0.0-20201221181555-eec23a3978ad
 	google.golang.org/appengine v1.6.5 // indirect
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0 // indirect
+	tracing v0.0.0
 )
+
+replace tracing => ../tracing
@@ -19,6 +19,8 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"errors"
 	"github.com/astaxie/beego"
 	_ "github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
@@ -29,10 +31,19 @@ import (
 	_ "mepauth/models"
 	_ "mepauth/routers"
 	"mepauth/util"
+	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+	"tracing"
 )
 
+// timeout of the export of the traced spans on the shutdown
+const tracingShutdownTimeout = 5 * time.Second
+
 func scanConfig(r io.Reader) (util.AppConfigProperties, error) {
 	config := util.AppConfigProperties{}
 	scanner := bufio.NewScanner(r)
@@ -92,6 +103,12 @@ func main() {
 	}
 	util.KeyComponentFromUserStr = keyComponentUserStr
 
+	err = startTracing()
+	if err != nil {
+		log.Error("Failed to start the tracing.")
+		return
+	}
+	go shutdownOnSignal()
 	if !doInitialization(appConfig["TRUSTED_LIST"]) {
 		return
 	}
@@ -121,7 +138,36 @@ func main() {
 	beego.BeeApp.Server.TLSConfig = tlsConf
 	setSwaggerConfig()
 	beego.ErrorController(&controllers.ErrorController{})
-	beego.Run()
+	beego.RunWithMiddleWares("", tracing.Middleware)
+}
+
+// startTracing starts exporting the spans to the otlp/http traces endpoint of tracing_endpoint, the tracing is
+// disabled if the endpoint is not configured
+func startTracing() error {
+	endpoint := util.GetAppConfig("tracing_endpoint")
+	if len(endpoint) == 0 {
+		return nil
+	}
+	tracingURL, err := url.Parse(endpoint)
+	if err != nil || (tracingURL.Scheme != "http" && tracingURL.Scheme != "https") || len(tracingURL.Host) == 0 {
+		return errors.New("invalid tracing endpoint")
+	}
+	tracing.SetLogger(log.Warnf)
+	tracing.Start(tracing.Options{ServiceName: "mepauth", Endpoint: endpoint})
+	return nil
+}
+
+// shutdownOnSignal exports the queued spans before exiting on the termination signal
+func shutdownOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	<-signals
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	if err := tracing.Shutdown(ctx); err != nil {
+		log.Warn("Failed to export the queued spans on the shutdown.")
+	}
+	cancel()
+	os.Exit(0)
 }
 
 func setSwaggerConfig() {
@@ -145,13 +191,14 @@ func doInitialization(trustedNetworks *[]byte) bool {
 		return false
 	}
 
-	initializer := &apiGwInitializer{tlsConfig: config}
+	initializer := &apiGwInitializer{tlsConfig: config, appConsumers: &sync.Map{}}
 
 	err = initializer.InitAPIGateway(trustedNetworks)
 	if err != nil {
 		log.Error("Failed to initialize API gateway.")
 		return false
 	}
+	controllers.AppConsumers = initializer
 	err = util.InitRootKeyAndWorkKey()
 	if err != nil {
 		log.Error("Failed to initialize root key and work key.")
@@ -30,3 +30,29 @@ type ConfigInfo struct {
 	Timeout      int    `json:"timeout"`
 	Keepalive    int    `json:"keepalive"`
 }
+
+// TracingPluginInfo zipkin tracing plugin information
+type TracingPluginInfo struct {
+	Name   string            `json:"name"`
+	Config TracingConfigInfo `json:"config"`
+}
+
+// TracingConfigInfo zipkin tracing plugin configurations
+type TracingConfigInfo struct {
+	HTTPEndpoint      string  `json:"http_endpoint"`
+	SampleRatio       float64 `json:"sample_ratio"`
+	HeaderType        string  `json:"header_type"`
+	DefaultHeaderType string  `json:"default_header_type"`
+}
+
+// ConsumerInfo api gateway consumer information
+type ConsumerInfo struct {
+	Username string `json:"username"`
+}
+
+// JwtCredentialInfo jwt credential of the api gateway consumer, the key is matched against the token issuer
+type JwtCredentialInfo struct {
+	Algorithm    string `json:"algorithm"`
+	Key          string `json:"key"`
+	RsaPublicKey string `json:"rsa_public_key"`
+}
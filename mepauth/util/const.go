@@ -49,6 +49,8 @@ const (
 	MepserverName               = "mepserver"
 	MepServerServiceMgmt        = "/mep/mec_service_mgmt"
 	MepServerAppSupport         = "/mep/mec_app_support"
+	MepServerRni                = "/mep/rni"
+	MepServerLocation           = "/mep/location"
 	MepauthName                 = "mepauth"
 	ApigwHost            string = "apigw_host"
 	ApigwPort            string = "apigw_port"
@@ -67,7 +69,11 @@ const (
 	IpRestrictPlugin                = "ip-restriction"
 	PluginPath               string = "/plugins"
 	MepAppJwtName            string = "mepauth.jwt"
+	ConsumerPath             string = "/consumers"
+	JwtAlgorithm             string = "RS512"
 	JwtPlugin                       = "jwt"
+	TracingPlugin                   = "zipkin"
+	TracingHeaderType               = "w3c"
 )
 
 // Other
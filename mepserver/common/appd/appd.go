@@ -18,13 +18,18 @@ package appd
 
 import (
 	"encoding/json"
+	"fmt"
 	"mepserver/common/arch/workspace"
+	"mepserver/common/config"
 	"mepserver/common/extif/backend"
 	"mepserver/common/extif/dataplane"
+	"mepserver/common/extif/dns"
 	"mepserver/common/models"
 	meputil "mepserver/common/util"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
 
 	"github.com/apache/servicecomb-service-center/pkg/log"
 )
@@ -90,6 +95,96 @@ func (a *AppDCommon) GenerateTaskResponse(taskId string, appInstanceId string, r
 	}
 }
 
+// GetTaskProgress reads the task status from the data-store and builds the task progress response
+func (a *AppDCommon) GetTaskProgress(taskId string) (progress *models.TaskProgress, code workspace.ErrCode,
+	msg string) {
+	taskEntry, errCode := backend.GetRecord(meputil.AppDLCMTasksPath + taskId)
+	if errCode != 0 {
+		log.Errorf(nil, "Get task rule from data-store failed.")
+		return nil, workspace.ErrCode(errCode), "task rule retrieval failed"
+	}
+
+	appInstInStore := string(taskEntry)
+
+	taskStatus, errCode := backend.GetRecord(meputil.AppDLCMTaskStatusPath + appInstInStore + "/" + taskId)
+	if errCode != 0 {
+		log.Errorf(nil, "Get task status rule from data-store failed.")
+		return nil, workspace.ErrCode(errCode), "task status rule retrieval failed"
+	}
+
+	taskStatusInStore := &models.TaskStatus{}
+	jsonErr := json.Unmarshal(taskStatus, taskStatusInStore)
+	if jsonErr != nil {
+		log.Errorf(nil, "Failed to parse the task status from data-store.")
+		return nil, meputil.OperateDataWithEtcdErr, "parse task status from data-store failed"
+	}
+
+	totalRules := len(taskStatusInStore.TrafficRuleStatusLst) + len(taskStatusInStore.DNSRuleStatusLst)
+	if totalRules == 0 {
+		log.Errorf(nil, "Task status without any rules found in data-store.")
+		return nil, meputil.OperateDataWithEtcdErr, "invalid task status in data-store"
+	}
+	percent := (taskStatusInStore.Progress * 100) / totalRules
+
+	var state string
+	if taskStatusInStore.Progress == totalRules {
+		state = meputil.TaskStateSuccess
+	} else if taskStatusInStore.Progress >= 0 {
+		state = meputil.TaskStateProcessing
+	} else {
+		state = meputil.TaskStateFailure
+		percent = 0
+	}
+
+	details := taskStatusInStore.Details
+	if state == meputil.TaskStateSuccess && len(details) == 0 {
+		details = partialFailureDetails(taskStatusInStore)
+	}
+	taskProgress := a.GenerateTaskResponse(taskId, appInstInStore, state, strconv.Itoa(percent), details)
+	return &taskProgress, 0, ""
+}
+
+// partialFailureDetails reports the dns rules failed on the secondary dns servers
+func partialFailureDetails(taskStatus *models.TaskStatus) string {
+	var failures []string
+	for _, ruleStatus := range taskStatus.DNSRuleStatusLst {
+		if len(ruleStatus.FailedServers) != 0 {
+			failures = append(failures, fmt.Sprintf("%s(%s)", ruleStatus.Id,
+				strings.Join(ruleStatus.FailedServers, ", ")))
+		}
+	}
+	if len(failures) == 0 {
+		return ""
+	}
+	return "dns rule(s) failed on the secondary dns server(s): " + strings.Join(failures, ", ")
+}
+
+// defaultDNSZone returns the configured zone of the dns rules without a zone
+func defaultDNSZone() string {
+	if effective := config.GetEffective(); effective != nil {
+		return dns.DefaultZone(&effective.Config)
+	}
+	return dns.RootZone
+}
+
+// fillDNSRuleZones sets the default zone on the dns rules without a zone and checks the domain names belong to
+// their zones
+func (a *AppDCommon) fillDNSRuleZones(appDConfigInput *models.AppDConfig) error {
+	defaultZone := defaultDNSZone()
+	for i := range appDConfigInput.AppDNSRule {
+		rule := &appDConfigInput.AppDNSRule[i]
+		if len(rule.Zone) == 0 {
+			rule.Zone = defaultZone
+		}
+		rule.Zone = dns.FQDN(rule.Zone)
+		if !dns.InZone(rule.DomainName, rule.Zone) {
+			return fmt.Errorf("domain name %s of the dns rule %s is not in the zone %s", rule.DomainName,
+				rule.DNSRuleID, rule.Zone)
+		}
+	}
+	return nil
+}
+
 // StageNewTask stages new tasks for operation
 func (a *AppDCommon) StageNewTask(appInstanceId string, taskId string,
 	appDConfigInput *models.AppDConfig) (code workspace.ErrCode, msg string) {
@@ -111,6 +206,12 @@ func (a *AppDCommon) StageNewTask(appInstanceId string, taskId string,
 		log.Errorf(nil, "App-name miss-match.")
 		return meputil.OperateDataWithEtcdErr, "app-name doesn't match"
 	}
+	if appDConfigInput.Operation != http.MethodDelete {
+		if err := a.fillDNSRuleZones(appDConfigInput); err != nil {
+			log.Errorf(nil, "Invalid dns rule zone.")
+			return meputil.RequestParamErr, err.Error()
+		}
+	}
 
 	var err error
 	var appDConfigBytes []byte
@@ -158,6 +259,13 @@ func (a *AppDCommon) StageNewTask(appInstanceId string, taskId string,
 		log.Errorf(nil, "Duplicate dns entry found in the request.")
 		return meputil.DuplicateOperation, "duplicate dns entry"
 	}
+
+	// Check any traffic rule of other app instances conflicts with the input
+	if conflict := a.findTrafficRuleConflict(appInstanceId, appDConfigInput, taskStatus); conflict != nil {
+		_ = backend.DeletePaths([]string{meputil.AppDLCMJobsPath + appInstanceId, meputil.AppDLCMTasksPath + taskId},
+			true)
+		return meputil.TrafficRuleConflict, conflict.Error()
+	}
 	return a.writeStatusToStore(taskStatus, appInstanceId, taskId)
 }
 
@@ -201,7 +309,18 @@ func (a *AppDCommon) buildTaskStatus(appDConfigInput *models.AppDConfig,
 	return &taskStatus
 }
 
-func (a *AppDCommon) fillDnsDomainNameMap(appInstanceId string, path string, dnsRuleMap *map[string]bool) {
+// dnsRuleKey identifies the domain name of the dns rule in its zone and source filter view, the rules stored
+// without a zone are in the default zone
+func dnsRuleKey(rule *dataplane.DNSRule, defaultZone string) string {
+	zone := rule.Zone
+	if len(zone) == 0 {
+		zone = defaultZone
+	}
+	return dns.FQDN(zone) + " " + dns.FQDN(rule.DomainName) + " " + dns.ViewName(rule.SourceFilter)
+}
+
+func (a *AppDCommon) fillDnsDomainNameMap(appInstanceId string, path string, dnsRuleMap *map[string]bool,
+	defaultZone string) {
 	records, errCode := backend.GetRecords(path)
 	if errCode == 0 && len(records) != 0 {
 		for appId, record := range records {
@@ -214,7 +333,7 @@ func (a *AppDCommon) fillDnsDomainNameMap(appInstanceId string, path string, dns
 				continue
 			}
 			for i := 0; i < len(appDInStore.AppDNSRule); i++ {
-				(*dnsRuleMap)[appDInStore.AppDNSRule[i].DomainName] = true
+				(*dnsRuleMap)[dnsRuleKey(&appDInStore.AppDNSRule[i], defaultZone)] = true
 			}
 		}
 	}
@@ -225,24 +344,27 @@ func (a *AppDCommon) isDNSDomainNameExists(appInstanceId string, appDConfigInput
 
 	dnsInStoreDomainNameMap := make(map[string]bool)
 
-	a.fillDnsDomainNameMap(appInstanceId, meputil.AppDConfigKeyPath, &dnsInStoreDomainNameMap)
-	a.fillDnsDomainNameMap(appInstanceId, meputil.AppDLCMJobsPath, &dnsInStoreDomainNameMap)
+	defaultZone := defaultDNSZone()
+	a.fillDnsDomainNameMap(appInstanceId, meputil.AppDConfigKeyPath, &dnsInStoreDomainNameMap, defaultZone)
+	a.fillDnsDomainNameMap(appInstanceId, meputil.AppDLCMJobsPath, &dnsInStoreDomainNameMap, defaultZone)
 
 	dnsInputRuleMap := make(map[string]*dataplane.DNSRule)
 	dnsInputDomainNameMap := make(map[string]bool)
-	for i, rule := range appDConfigInput.AppDNSRule {
-		dnsInputRuleMap[rule.DNSRuleID] = &appDConfigInput.AppDNSRule[i]
+	for i := range appDConfigInput.AppDNSRule {
+		rule := &appDConfigInput.AppDNSRule[i]
+		dnsInputRuleMap[rule.DNSRuleID] = rule
 
 		// Duplicate entry in the input request
-		if _, found := dnsInputDomainNameMap[rule.DomainName]; found {
+		key := dnsRuleKey(rule, defaultZone)
+		if _, found := dnsInputDomainNameMap[key]; found {
 			return true
 		}
-		dnsInputDomainNameMap[rule.DomainName] = true
+		dnsInputDomainNameMap[key] = true
 	}
 
 	for _, ruleStatus := range taskStatus.DNSRuleStatusLst {
 		if ruleStatus.Method == meputil.OperCreate {
-			if _, found := dnsInStoreDomainNameMap[dnsInputRuleMap[ruleStatus.Id].DomainName]; found {
+			if _, found := dnsInStoreDomainNameMap[dnsRuleKey(dnsInputRuleMap[ruleStatus.Id], defaultZone)]; found {
 				return true
 			}
 		}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"encoding/json"
+	"mepserver/common/extif/backend"
+	"mepserver/common/extif/dataplane"
+	"mepserver/common/models"
+	meputil "mepserver/common/util"
+	"testing"
+
+	"github.com/agiledragon/gomonkey"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDNSDomainNameExistsInZone(t *testing.T) {
+	existingRule := dataplane.DNSRule{
+		DNSRuleID:     "DNS1",
+		DomainName:    "www.example.com",
+		IPAddressType: "IP_V4",
+		IPAddress:     "10.10.0.1",
+	}
+	patches := gomonkey.ApplyFunc(backend.GetRecords, func(path string) (map[string][]byte, int) {
+		if path != meputil.AppDConfigKeyPath {
+			return nil, 0
+		}
+		appDConfig := models.AppDConfig{AppName: "other", AppDNSRule: []dataplane.DNSRule{existingRule}}
+		record, _ := json.Marshal(&appDConfig)
+		return map[string][]byte{otherAppInstanceId: record}, 0
+	})
+	defer patches.Reset()
+
+	inputRule := dataplane.DNSRule{
+		DNSRuleID:     "DNS2",
+		DomainName:    "WWW.example.com.",
+		IPAddressType: "IP_V4",
+		IPAddress:     "10.10.0.2",
+	}
+	input := &models.AppDConfig{AppName: "app", AppDNSRule: []dataplane.DNSRule{inputRule}}
+	taskStatus := &models.TaskStatus{
+		DNSRuleStatusLst: []models.RuleStatus{{Id: "DNS2", State: meputil.WaitMp2, Method: meputil.OperCreate}},
+	}
+
+	// The rule stored without a zone is in the default zone
+	appDCommon := AppDCommon{}
+	assert.Nil(t, appDCommon.fillDNSRuleZones(input))
+	assert.Equal(t, ".", input.AppDNSRule[0].Zone)
+	assert.True(t, appDCommon.isDNSDomainNameExists(appInstanceId, input, taskStatus))
+
+	// The same domain name in another zone is not a duplicate
+	input.AppDNSRule[0].Zone = "example.com"
+	assert.Nil(t, appDCommon.fillDNSRuleZones(input))
+	assert.Equal(t, "example.com.", input.AppDNSRule[0].Zone)
+	assert.False(t, appDCommon.isDNSDomainNameExists(appInstanceId, input, taskStatus))
+
+	// Duplicate in the input request of the same zone
+	input.AppDNSRule = append(input.AppDNSRule, input.AppDNSRule[0])
+	input.AppDNSRule[1].DNSRuleID = "DNS3"
+	assert.True(t, appDCommon.isDNSDomainNameExists(appInstanceId, input, taskStatus))
+
+	// The domain name must be in the zone of the rule
+	input.AppDNSRule = input.AppDNSRule[:1]
+	input.AppDNSRule[0].Zone = "example.org"
+	assert.NotNil(t, appDCommon.fillDNSRuleZones(input))
+}
+
+func TestPartialFailureDetails(t *testing.T) {
+	taskStatus := &models.TaskStatus{
+		DNSRuleStatusLst: []models.RuleStatus{
+			{Id: "DNS1", State: meputil.WaitConfigDBWrite},
+			{Id: "DNS2", State: meputil.WaitConfigDBWrite, FailedServers: []string{"dns2:8080", "dns3:8080"}},
+		},
+	}
+	assert.Equal(t, "dns rule(s) failed on the secondary dns server(s): DNS2(dns2:8080, dns3:8080)",
+		partialFailureDetails(taskStatus))
+
+	taskStatus.DNSRuleStatusLst[1].FailedServers = nil
+	assert.Equal(t, "", partialFailureDetails(taskStatus))
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"encoding/json"
+	"fmt"
+	"mepserver/common/extif/backend"
+	"mepserver/common/extif/dataplane"
+	"mepserver/common/models"
+	meputil "mepserver/common/util"
+	"net/http"
+	"strings"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+)
+
+// TrafficRuleConflict holds the details of an existing rule which conflicts with the input rule
+type TrafficRuleConflict struct {
+	RuleId                string
+	ConflictAppInstanceId string
+	ConflictRuleId        string
+}
+
+func (c *TrafficRuleConflict) Error() string {
+	return fmt.Sprintf("traffic rule(%s) conflicts with traffic rule(%s) of app instance(%s)", c.RuleId,
+		c.ConflictRuleId, c.ConflictAppInstanceId)
+}
+
+// fillTrafficRuleMap collects the active traffic rules of all the other app instances from the given path
+func (a *AppDCommon) fillTrafficRuleMap(appInstanceId string, path string,
+	trafficRuleMap map[string][]dataplane.TrafficRule) {
+	records, errCode := backend.GetRecords(path)
+	if errCode != 0 || len(records) == 0 {
+		return
+	}
+	for appId, record := range records {
+		// This check is to exclude the current processing entry
+		if appId == appInstanceId {
+			continue
+		}
+		appDInStore := &models.AppDConfig{}
+		if err := json.Unmarshal(record, appDInStore); err != nil {
+			continue
+		}
+		// Rules of an on-going delete operation are going to be removed
+		if appDInStore.Operation == http.MethodDelete {
+			delete(trafficRuleMap, appId)
+			continue
+		}
+		var rules []dataplane.TrafficRule
+		for _, rule := range appDInStore.AppTrafficRule {
+			if isTrafficRuleActive(&rule) {
+				rules = append(rules, rule)
+			}
+		}
+		// Jobs are read last and holds the latest configuration of the app instance
+		trafficRuleMap[appId] = rules
+	}
+}
+
+// findTrafficRuleConflict checks the created or modified traffic rules in the input against the traffic rules of
+// the other app instances. Overlapping rules with the same priority and a different action can not be resolved by
+// the data-plane, hence it is reported as conflict. Other overlaps are only logged.
+func (a *AppDCommon) findTrafficRuleConflict(appInstanceId string, appDConfigInput *models.AppDConfig,
+	taskStatus *models.TaskStatus) *TrafficRuleConflict {
+
+	trafficRuleMap := make(map[string][]dataplane.TrafficRule)
+	a.fillTrafficRuleMap(appInstanceId, meputil.AppDConfigKeyPath, trafficRuleMap)
+	a.fillTrafficRuleMap(appInstanceId, meputil.AppDLCMJobsPath, trafficRuleMap)
+	if len(trafficRuleMap) == 0 {
+		return nil
+	}
+
+	trfInputRuleMap := make(map[string]*dataplane.TrafficRule)
+	for i, rule := range appDConfigInput.AppTrafficRule {
+		trfInputRuleMap[rule.TrafficRuleID] = &appDConfigInput.AppTrafficRule[i]
+	}
+
+	for _, ruleStatus := range taskStatus.TrafficRuleStatusLst {
+		if ruleStatus.Method != meputil.OperCreate && ruleStatus.Method != meputil.OperModify {
+			continue
+		}
+		inputRule, found := trfInputRuleMap[ruleStatus.Id]
+		if !found || !isTrafficRuleActive(inputRule) {
+			continue
+		}
+		for appId, rules := range trafficRuleMap {
+			for i := range rules {
+				if !TrafficFiltersOverlap(inputRule.TrafficFilter, rules[i].TrafficFilter) {
+					continue
+				}
+				if inputRule.Priority == rules[i].Priority && inputRule.Action != rules[i].Action {
+					log.Errorf(nil, "Traffic rule(%s) conflicts with traffic rule(%s) of app instance(%s).",
+						inputRule.TrafficRuleID, rules[i].TrafficRuleID, appId)
+					return &TrafficRuleConflict{
+						RuleId:                inputRule.TrafficRuleID,
+						ConflictAppInstanceId: appId,
+						ConflictRuleId:        rules[i].TrafficRuleID,
+					}
+				}
+				log.Warnf("Traffic rule(%s) overlaps with traffic rule(%s) of app instance(%s).",
+					inputRule.TrafficRuleID, rules[i].TrafficRuleID, appId)
+			}
+		}
+	}
+	return nil
+}
+
+func isTrafficRuleActive(rule *dataplane.TrafficRule) bool {
+	return rule.State == "" || rule.State == meputil.ActiveState
+}
+
+// TrafficFiltersOverlap checks whether any packet can be matched by both the filter sets. An empty filter set
+// matches all the traffic.
+func TrafficFiltersOverlap(filters1, filters2 []dataplane.TrafficFilter) bool {
+	if len(filters1) == 0 || len(filters2) == 0 {
+		return true
+	}
+	for i := range filters1 {
+		for j := range filters2 {
+			if trafficFilterOverlap(&filters1[i], &filters2[j]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func trafficFilterOverlap(filter1, filter2 *dataplane.TrafficFilter) bool {
+	return valuesOverlap(filter1.SrcAddress, filter2.SrcAddress, addressOverlap) &&
+		valuesOverlap(filter1.DstAddress, filter2.DstAddress, addressOverlap) &&
+		valuesOverlap(filter1.SrcPort, filter2.SrcPort, portOverlap) &&
+		valuesOverlap(filter1.DstPort, filter2.DstPort, portOverlap) &&
+		valuesOverlap(filter1.Protocol, filter2.Protocol, protocolOverlap) &&
+		valuesOverlap(filter1.Tag, filter2.Tag, valueEqual) &&
+		valuesOverlap(filter1.SrcTunnelAddress, filter2.SrcTunnelAddress, addressOverlap) &&
+		valuesOverlap(filter1.TgtTunnelAddress, filter2.TgtTunnelAddress, addressOverlap) &&
+		valuesOverlap(filter1.SrcTunnelPort, filter2.SrcTunnelPort, portOverlap) &&
+		valuesOverlap(filter1.DstTunnelPort, filter2.DstTunnelPort, portOverlap) &&
+		classOverlap(filter1.QCI, filter2.QCI) &&
+		classOverlap(filter1.DSCP, filter2.DSCP) &&
+		classOverlap(filter1.TC, filter2.TC)
+}
+
+// valuesOverlap checks whether any value of the first list overlaps with any value of the second list, an empty
+// list is a wildcard
+func valuesOverlap(values1, values2 []string, overlap func(value1, value2 string) bool) bool {
+	if len(values1) == 0 || len(values2) == 0 {
+		return true
+	}
+	for _, value1 := range values1 {
+		for _, value2 := range values2 {
+			if overlap(value1, value2) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// classOverlap checks the QCI, DSCP or TC values, zero is not configured and matches any value
+func classOverlap(value1, value2 int) bool {
+	return value1 == 0 || value2 == 0 || value1 == value2
+}
+
+func valueEqual(value1, value2 string) bool {
+	return strings.EqualFold(strings.TrimSpace(value1), strings.TrimSpace(value2))
+}
+
+// addressOverlap checks whether two ip addresses or CIDR blocks have any address in common
+func addressOverlap(address1, address2 string) bool {
+	network1, err1 := dataplane.ParseAddress(address1)
+	network2, err2 := dataplane.ParseAddress(address2)
+	if err1 != nil || err2 != nil {
+		// Not possible to compute, so compare as it is
+		return valueEqual(address1, address2)
+	}
+	return network1.Contains(network2.IP) || network2.Contains(network1.IP)
+}
+
+// portOverlap checks whether two ports or port ranges have any port in common
+func portOverlap(port1, port2 string) bool {
+	low1, high1, err1 := dataplane.ParsePortRange(port1)
+	low2, high2, err2 := dataplane.ParsePortRange(port2)
+	if err1 != nil || err2 != nil {
+		// Not possible to compute, so compare as it is
+		return valueEqual(port1, port2)
+	}
+	return low1 <= high2 && low2 <= high1
+}
+
+// protocolOverlap compares the protocols after converting the names to the protocol numbers
+func protocolOverlap(protocol1, protocol2 string) bool {
+	number1, err1 := dataplane.ParseProtocol(protocol1)
+	number2, err2 := dataplane.ParseProtocol(protocol2)
+	if err1 != nil || err2 != nil {
+		// Not possible to compute, so compare as it is
+		return valueEqual(protocol1, protocol2)
+	}
+	return number1 == number2
+}
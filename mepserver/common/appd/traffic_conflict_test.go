@@ -0,0 +1,123 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"encoding/json"
+	"mepserver/common/extif/backend"
+	"mepserver/common/extif/dataplane"
+	"mepserver/common/models"
+	meputil "mepserver/common/util"
+	"testing"
+
+	"github.com/agiledragon/gomonkey"
+	"github.com/stretchr/testify/assert"
+)
+
+const otherAppInstanceId = "5abe4782-2c70-4e47-9a4e-0ee3a1a0fd1f"
+const appInstanceId = "71ea862b-5806-4196-bce3-434bf9c95b18"
+
+func TestTrafficFiltersOverlap(t *testing.T) {
+	filter := dataplane.TrafficFilter{
+		SrcAddress: []string{"192.168.1.0/24"},
+		DstPort:    []string{"1000-2000"},
+		Protocol:   []string{"TCP"},
+	}
+
+	overlapping := dataplane.TrafficFilter{
+		SrcAddress: []string{"192.168.1.10"},
+		DstPort:    []string{"1500"},
+		Protocol:   []string{"6"},
+	}
+	assert.True(t, TrafficFiltersOverlap([]dataplane.TrafficFilter{filter},
+		[]dataplane.TrafficFilter{overlapping}))
+
+	// Wildcard filter matches everything
+	assert.True(t, TrafficFiltersOverlap([]dataplane.TrafficFilter{filter}, []dataplane.TrafficFilter{{}}))
+
+	otherNetwork := overlapping
+	otherNetwork.SrcAddress = []string{"192.168.2.0/24"}
+	assert.False(t, TrafficFiltersOverlap([]dataplane.TrafficFilter{filter},
+		[]dataplane.TrafficFilter{otherNetwork}))
+
+	otherPort := overlapping
+	otherPort.DstPort = []string{"2001-3000"}
+	assert.False(t, TrafficFiltersOverlap([]dataplane.TrafficFilter{filter},
+		[]dataplane.TrafficFilter{otherPort}))
+
+	otherProtocol := overlapping
+	otherProtocol.Protocol = []string{"udp"}
+	assert.False(t, TrafficFiltersOverlap([]dataplane.TrafficFilter{filter},
+		[]dataplane.TrafficFilter{otherProtocol}))
+
+	otherDscp := overlapping
+	otherDscp.DSCP = 10
+	dscpFilter := filter
+	dscpFilter.DSCP = 12
+	assert.False(t, TrafficFiltersOverlap([]dataplane.TrafficFilter{dscpFilter},
+		[]dataplane.TrafficFilter{otherDscp}))
+}
+
+func TestFindTrafficRuleConflict(t *testing.T) {
+	existingRule := dataplane.TrafficRule{
+		TrafficRuleID: "TR1",
+		FilterType:    "FLOW",
+		Priority:      5,
+		TrafficFilter: []dataplane.TrafficFilter{{DstAddress: []string{"10.10.0.0/16"}}},
+		Action:        "DROP",
+		State:         meputil.ActiveState,
+	}
+	patches := gomonkey.ApplyFunc(backend.GetRecords, func(path string) (map[string][]byte, int) {
+		if path != meputil.AppDConfigKeyPath {
+			return nil, 0
+		}
+		appDConfig := models.AppDConfig{AppName: "other", AppTrafficRule: []dataplane.TrafficRule{existingRule}}
+		record, _ := json.Marshal(&appDConfig)
+		return map[string][]byte{otherAppInstanceId: record}, 0
+	})
+	defer patches.Reset()
+
+	inputRule := dataplane.TrafficRule{
+		TrafficRuleID: "TR2",
+		FilterType:    "FLOW",
+		Priority:      5,
+		TrafficFilter: []dataplane.TrafficFilter{{DstAddress: []string{"10.10.1.1"}}},
+		Action:        "PASSTHROUGH",
+	}
+	input := &models.AppDConfig{AppName: "app", AppTrafficRule: []dataplane.TrafficRule{inputRule}}
+	taskStatus := &models.TaskStatus{
+		TrafficRuleStatusLst: []models.RuleStatus{{Id: "TR2", State: meputil.WaitMp2, Method: meputil.OperCreate}},
+	}
+
+	appDCommon := AppDCommon{}
+	conflict := appDCommon.findTrafficRuleConflict(appInstanceId, input, taskStatus)
+	if assert.NotNil(t, conflict) {
+		assert.Equal(t, otherAppInstanceId, conflict.ConflictAppInstanceId)
+		assert.Equal(t, "TR1", conflict.ConflictRuleId)
+		assert.Equal(t, "traffic rule(TR2) conflicts with traffic rule(TR1) of app instance("+
+			otherAppInstanceId+")", conflict.Error())
+	}
+
+	// Different priority is resolved by the data-plane
+	input.AppTrafficRule[0].Priority = 6
+	assert.Nil(t, appDCommon.findTrafficRuleConflict(appInstanceId, input, taskStatus))
+
+	// Inactive rules are not configured on the data-plane
+	input.AppTrafficRule[0].Priority = 5
+	input.AppTrafficRule[0].State = meputil.InactiveState
+	assert.Nil(t, appDCommon.findTrafficRuleConflict(appInstanceId, input, taskStatus))
+}
@@ -0,0 +1,35 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package appd
+
+import (
+	"mepserver/common/arch/workspace"
+	"mepserver/common/extif/dataplane"
+)
+
+// InvalidTrafficFilter error message on traffic filter validation failure
+const InvalidTrafficFilter = "invalid traffic filter"
+
+// NormalizeTrafficRule validates and normalizes the traffic filters of the rule, fields failed in the validation are
+// returned as invalid params
+func NormalizeTrafficRule(rule *dataplane.TrafficRule, prefix string) []workspace.InvalidParam {
+	var invalidParams []workspace.InvalidParam
+	for _, fieldErr := range dataplane.NormalizeTrafficRule(rule, prefix) {
+		invalidParams = append(invalidParams, workspace.InvalidParam{Param: fieldErr.Field, Reason: fieldErr.Reason})
+	}
+	return invalidParams
+}
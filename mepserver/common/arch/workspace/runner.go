@@ -18,6 +18,7 @@
 package workspace
 
 import (
+	"context"
 	"sync"
 
 	"mepserver/common/arch/bus"
@@ -35,6 +36,21 @@ const (
 const DataIn string = "in"
 const DataOut string = "out"
 
+// StepHook is called before the step runs, the returned function if any is called after the step finishes
+type StepHook func(wkSpace interface{}, stepIf TaskBaseIf) func()
+
+var stepHook StepHook
+
+// SetStepHook sets the hook called around each step run, it must be set before the work spaces run
+func SetStepHook(hook StepHook) {
+	stepHook = hook
+}
+
+// TraceContextIf is implemented by the work spaces carrying the trace context of the request
+type TraceContextIf interface {
+	TraceContext() context.Context
+}
+
 // WkRun run plans in workspace
 func WkRun(plan SpaceIf) ErrCode {
 	curPlan := plan.getPlan()
@@ -59,6 +75,11 @@ func WkRun(plan SpaceIf) ErrCode {
 
 // workspace task runner
 func taskRunner(wkSpace interface{}, stepIf TaskBaseIf) int {
+	if stepHook != nil {
+		if done := stepHook(wkSpace, stepIf); done != nil {
+			defer done()
+		}
+	}
 	for {
 		bus.LoadObjByInd(stepIf, wkSpace, DataIn)
 		retCode := stepIf.OnRequest("")
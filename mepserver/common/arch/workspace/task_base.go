@@ -17,6 +17,8 @@
 // Package workspace implements architecture work space
 package workspace
 
+import "context"
+
 type TaskBaseIf interface {
 	OnRequest(data string) TaskCode
 	Parse(params string)
@@ -27,12 +29,19 @@ type TaskBaseIf interface {
 	SetSerErrInfo(serErr *SerErrInfo)
 }
 
+// InvalidParamsIf is implemented by the tasks which report the invalid request parameters
+type InvalidParamsIf interface {
+	GetInvalidParams() []InvalidParam
+}
+
 type TaskBase struct {
-	serErr     *SerErrInfo
-	errMsg     string
-	Name       string
-	Param      []string
-	resultCode ErrCode
+	serErr        *SerErrInfo
+	errMsg        string
+	Name          string
+	Param         []string
+	resultCode    ErrCode
+	invalidParams []InvalidParam
+	traceCtx      context.Context
 }
 
 // WithName set task base name
@@ -75,6 +84,29 @@ func (t *TaskBase) SetFirstErrorCode(code ErrCode, msg string) {
 	t.errMsg = msg
 }
 
+// SetInvalidParams set the invalid request parameters of the failure
+func (t *TaskBase) SetInvalidParams(params []InvalidParam) {
+	t.invalidParams = params
+}
+
+// GetInvalidParams get the invalid request parameters of the failure
+func (t *TaskBase) GetInvalidParams() []InvalidParam {
+	return t.invalidParams
+}
+
+// SetTraceContext set the trace context of the task span
+func (t *TaskBase) SetTraceContext(ctx context.Context) {
+	t.traceCtx = ctx
+}
+
+// TraceContext get the trace context of the task span, outbound calls of the task are traced as its children
+func (t *TaskBase) TraceContext() context.Context {
+	if t.traceCtx == nil {
+		return context.Background()
+	}
+	return t.traceCtx
+}
+
 // GetErrCode get error code
 func (t *TaskBase) GetErrCode() (ErrCode, string) {
 	return t.resultCode, t.errMsg
@@ -60,11 +60,18 @@ func (s *SubGrp) install(task []interface{}) bool {
 	return true
 }
 
+// InvalidParam holds the failure reason of an invalid request parameter
+type InvalidParam struct {
+	Param  string
+	Reason string
+}
+
 type SerErrInfo struct {
-	ErrCode int
-	Message string
-	GrpIdx  int
-	TaskIdx int
+	ErrCode       int
+	Message       string
+	GrpIdx        int
+	TaskIdx       int
+	InvalidParams []InvalidParam
 }
 
 type PlanBase struct {
@@ -203,6 +210,9 @@ func RecordErrInfo(curPlan *PlanBase, stepIdx int) {
 	errCode, msg := stepIf.GetErrCode()
 	curPlan.SerError.ErrCode = int(errCode)
 	curPlan.SerError.Message = msg
+	if paramsIf, ok := curStep.(InvalidParamsIf); ok {
+		curPlan.SerError.InvalidParams = paramsIf.GetInvalidParams()
+	}
 }
 
 // GotoErrorProc go to error handling process
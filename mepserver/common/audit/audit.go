@@ -0,0 +1,279 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package audit implements the audit trail of the mp1 and mm5 modifications
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"mepserver/common/config"
+	meputil "mepserver/common/util"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+)
+
+const (
+	StoreNone = "none"
+	StoreEtcd = "etcd"
+	StoreFile = "file"
+
+	ResultSuccess = "SUCCESS"
+	ResultFailure = "FAILURE"
+)
+
+type contextKey struct{}
+
+// Record holds a single entry of the audit trail
+type Record struct {
+	Id            string    `json:"id"`
+	Timestamp     time.Time `json:"timestamp"`
+	AppInstanceId string    `json:"appInstanceId,omitempty"`
+	ClientIp      string    `json:"clientIp"`
+	Subject       string    `json:"subject,omitempty"`
+	Method        string    `json:"method"`
+	Resource      string    `json:"resource"`
+	BeforeHash    string    `json:"beforeHash,omitempty"`
+	AfterHash     string    `json:"afterHash,omitempty"`
+	Result        string    `json:"result"`
+	StatusCode    int       `json:"statusCode"`
+	Detail        string    `json:"detail,omitempty"`
+}
+
+// Filter holds the audit record query conditions, empty values match all the records
+type Filter struct {
+	AppInstanceId string
+	Resource      string
+	StartTime     time.Time
+	EndTime       time.Time
+	Limit         int
+}
+
+// Match checks the record satisfies the filter conditions
+func (f *Filter) Match(record *Record) bool {
+	if len(f.AppInstanceId) != 0 && record.AppInstanceId != f.AppInstanceId {
+		return false
+	}
+	if len(f.Resource) != 0 && !strings.Contains(record.Resource, f.Resource) {
+		return false
+	}
+	if !f.StartTime.IsZero() && record.Timestamp.Before(f.StartTime) {
+		return false
+	}
+	if !f.EndTime.IsZero() && record.Timestamp.After(f.EndTime) {
+		return false
+	}
+	return true
+}
+
+// Store interface of the audit record storage
+type Store interface {
+	// Save stores the audit record
+	Save(record *Record) error
+	// Query returns the audit records matching the filter, sorted by time with the latest first
+	Query(filter *Filter) ([]Record, error)
+}
+
+var (
+	store     Store = &noneStore{}
+	storeOnce sync.Once
+)
+
+// Init selects the audit record store as per configuration, only the first call is effective
+func Init(mepConfig *config.MepServerConfig) {
+	storeOnce.Do(func() {
+		auditConfig := mepConfig.Audit
+		switch auditConfig.Store {
+		case StoreEtcd:
+			store = newEtcdStore(auditConfig.MaxRecords)
+		case StoreFile:
+			store = newFileStore(auditConfig.FilePath, auditConfig.MaxFileSize, auditConfig.MaxBackups)
+		default:
+			store = &noneStore{}
+		}
+		log.Infof("Audit store initialized to %s.", auditConfig.Store)
+	})
+}
+
+// UseStore makes the audit use the store and returns the function restoring the previous one, the tests use it to
+// read the saved records
+func UseStore(s Store) func() {
+	previous := store
+	store = s
+	return func() {
+		store = previous
+	}
+}
+
+// GetStore returns the selected audit record store
+func GetStore() Store {
+	return store
+}
+
+// entry holds the audit data collected during the request processing
+type entry struct {
+	mutex      sync.Mutex
+	beforeHash string
+}
+
+// Begin attaches an audit entry to the modification requests, other requests are returned as it is
+func Begin(r *http.Request) *http.Request {
+	if r == nil || !isModification(r) {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), contextKey{}, &entry{}))
+}
+
+// SetBefore records the hash of the resource before the modification, raw bytes are hashed as it is. The
+// context must be derived from the request returned by Begin
+func SetBefore(ctx context.Context, before interface{}) {
+	auditEntry := getEntryFromContext(ctx)
+	if auditEntry == nil {
+		return
+	}
+	auditEntry.mutex.Lock()
+	auditEntry.beforeHash = hashPayload(before)
+	auditEntry.mutex.Unlock()
+}
+
+// Finish builds the audit record of the modification request and saves it to the store
+func Finish(r *http.Request, statusCode int, detail string, after interface{}) {
+	auditEntry := getEntry(r)
+	if auditEntry == nil {
+		return
+	}
+	auditEntry.mutex.Lock()
+	beforeHash := auditEntry.beforeHash
+	auditEntry.mutex.Unlock()
+
+	record := &Record{
+		Id:            meputil.GenerateUniqueId(),
+		Timestamp:     time.Now().UTC(),
+		AppInstanceId: meputil.GetAppInstanceId(r),
+		ClientIp:      meputil.GetClientIp(r),
+		Subject:       getSubject(r),
+		Method:        meputil.GetMethodFromReq(r),
+		Resource:      r.URL.Path,
+		BeforeHash:    beforeHash,
+		StatusCode:    statusCode,
+		Detail:        detail,
+	}
+	if len(record.AppInstanceId) == 0 {
+		record.AppInstanceId = r.Header.Get("X-AppinstanceID")
+	}
+	if statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices {
+		record.Result = ResultSuccess
+		record.AfterHash = hashPayload(after)
+	} else {
+		record.Result = ResultFailure
+	}
+
+	if err := store.Save(record); err != nil {
+		log.Errorf(err, "Audit record(method: %s, resource: %s) save failed.", record.Method, record.Resource)
+	}
+}
+
+func getEntry(r *http.Request) *entry {
+	if r == nil {
+		return nil
+	}
+	return getEntryFromContext(r.Context())
+}
+
+func getEntryFromContext(ctx context.Context) *entry {
+	if ctx == nil {
+		return nil
+	}
+	auditEntry, ok := ctx.Value(contextKey{}).(*entry)
+	if !ok {
+		return nil
+	}
+	return auditEntry
+}
+
+func isModification(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return false
+	}
+	// Api gateway logs are not the modification of the platform
+	return r.URL == nil || r.URL.Path != meputil.KongHttpLogPath
+}
+
+// getSubject reads the subject of the mepauth token, the token is already verified by the api gateway
+func getSubject(r *http.Request) string {
+	authorization := r.Header.Get("Authorization")
+	const bearerPrefix = "Bearer "
+	if !strings.HasPrefix(authorization, bearerPrefix) {
+		return ""
+	}
+	parts := strings.Split(strings.TrimPrefix(authorization, bearerPrefix), ".")
+	if len(parts) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return ""
+	}
+	claims := struct {
+		Subject string `json:"sub"`
+	}{}
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	return claims.Subject
+}
+
+func hashPayload(payload interface{}) string {
+	if payload == nil {
+		return ""
+	}
+	data, ok := payload.([]byte)
+	if !ok {
+		var err error
+		data, err = json.Marshal(payload)
+		if err != nil {
+			return ""
+		}
+	}
+	if len(data) == 0 {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// noneStore drops the audit records
+type noneStore struct {
+}
+
+// Save drops the record
+func (n *noneStore) Save(record *Record) error {
+	return nil
+}
+
+// Query returns empty result
+func (n *noneStore) Query(filter *Filter) ([]Record, error) {
+	return []Record{}, nil
+}
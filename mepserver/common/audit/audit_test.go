@@ -0,0 +1,90 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const auditAppInstanceId = "5abe4782-2c70-4e47-9a4e-0ee3a1a0fd1f"
+
+func TestFilterMatch(t *testing.T) {
+	now := time.Now()
+	record := &Record{AppInstanceId: auditAppInstanceId, Resource: "/mep/mec_app_support/v1/applications/x/dns_rules/y",
+		Timestamp: now}
+
+	assert.True(t, (&Filter{}).Match(record))
+	assert.True(t, (&Filter{AppInstanceId: auditAppInstanceId, Resource: "dns_rules"}).Match(record))
+	assert.False(t, (&Filter{AppInstanceId: "other"}).Match(record))
+	assert.False(t, (&Filter{Resource: "traffic_rules"}).Match(record))
+	assert.False(t, (&Filter{StartTime: now.Add(time.Second)}).Match(record))
+	assert.False(t, (&Filter{EndTime: now.Add(-time.Second)}).Match(record))
+}
+
+func TestGetSubject(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/mep/mec_service_mgmt/v1/applications/x/services", nil)
+	assert.Equal(t, "", getSubject(r))
+
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"app-client"}`))
+	r.Header.Set("Authorization", "Bearer header."+payload+".signature")
+	assert.Equal(t, "app-client", getSubject(r))
+}
+
+func TestAuditFileStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	fileStore := newFileStore(filepath.Join(dir, "audit.log"), 1, 2)
+	// force the rotation on every record
+	fileStore.maxFileSize = 1
+	original := store
+	store = fileStore
+	defer func() {
+		store = original
+	}()
+
+	r := Begin(httptest.NewRequest(http.MethodPut, "/mep/mec_app_support/v1/applications/x/dns_rules/y", nil))
+	r.Header.Set("X-AppinstanceID", auditAppInstanceId)
+	SetBefore(r.Context(), []byte("before"))
+	Finish(r, http.StatusOK, "", map[string]string{"state": "ACTIVE"})
+	Finish(r, http.StatusBadRequest, "invalid", nil)
+
+	get := Begin(httptest.NewRequest(http.MethodGet, "/mep/mec_app_support/v1/applications/x/dns_rules/y", nil))
+	Finish(get, http.StatusOK, "", nil)
+
+	var records []Record
+	records, err = fileStore.Query(&Filter{AppInstanceId: auditAppInstanceId})
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, ResultFailure, records[0].Result)
+	assert.Equal(t, ResultSuccess, records[1].Result)
+	assert.Equal(t, hashPayload([]byte("before")), records[1].BeforeHash)
+	assert.NotEmpty(t, records[1].AfterHash)
+
+	records, err = fileStore.Query(&Filter{Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, records, 1)
+}
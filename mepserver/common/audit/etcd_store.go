@@ -0,0 +1,124 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"mepserver/common/extif/backend"
+	meputil "mepserver/common/util"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+)
+
+const (
+	defaultMaxRecords = 10000
+	cleanupInterval   = 10 * time.Minute
+)
+
+// etcdStore keeps the audit records in the data-store, keys are prefixed with the time for ordering
+type etcdStore struct {
+	maxRecords  int
+	mutex       sync.Mutex
+	lastCleanup time.Time
+}
+
+func newEtcdStore(maxRecords int) *etcdStore {
+	if maxRecords <= 0 {
+		maxRecords = defaultMaxRecords
+	}
+	return &etcdStore{maxRecords: maxRecords, lastCleanup: time.Now()}
+}
+
+// Save puts the record to the data-store and periodically removes the oldest records over the limit
+func (e *etcdStore) Save(record *Record) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%s%020d-%s", meputil.AuditLogKeyPath, record.Timestamp.UnixNano(), record.Id)
+	if errCode := backend.PutRecord(key, recordBytes); errCode != 0 {
+		return fmt.Errorf("put audit record to data-store failed(%d)", errCode)
+	}
+
+	e.mutex.Lock()
+	cleanupRequired := time.Since(e.lastCleanup) >= cleanupInterval
+	if cleanupRequired {
+		e.lastCleanup = time.Now()
+	}
+	e.mutex.Unlock()
+	if cleanupRequired {
+		go e.cleanup()
+	}
+	return nil
+}
+
+// Query reads all the records from the data-store and filters them
+func (e *etcdStore) Query(filter *Filter) ([]Record, error) {
+	records, errCode := backend.GetRecords(meputil.AuditLogKeyPath)
+	if errCode != 0 {
+		return nil, fmt.Errorf("get audit records from data-store failed(%d)", errCode)
+	}
+	result := make([]Record, 0)
+	for _, recordBytes := range records {
+		record := Record{}
+		if err := json.Unmarshal(recordBytes, &record); err != nil {
+			continue
+		}
+		if filter.Match(&record) {
+			result = append(result, record)
+		}
+	}
+	return limitRecords(sortRecords(result), filter.Limit), nil
+}
+
+func (e *etcdStore) cleanup() {
+	records, errCode := backend.GetRecordsWithCompleteKeyPath(meputil.AuditLogKeyPath)
+	if errCode != 0 || len(records) <= e.maxRecords {
+		return
+	}
+	keys := make([]string, 0, len(records))
+	for key := range records {
+		keys = append(keys, key)
+	}
+	// Keys start with the fixed length timestamp, so the oldest records are at the beginning
+	sort.Strings(keys)
+	expiredKeys := keys[:len(keys)-e.maxRecords]
+	if errCode = backend.DeletePaths(expiredKeys, false); errCode != 0 {
+		log.Errorf(nil, "Delete expired audit records from data-store failed(%d).", errCode)
+		return
+	}
+	log.Infof("Deleted %d expired audit records.", len(expiredKeys))
+}
+
+// sortRecords orders the records with the latest first
+func sortRecords(records []Record) []Record {
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Timestamp.After(records[j].Timestamp)
+	})
+	return records
+}
+
+func limitRecords(records []Record, limit int) []Record {
+	if limit > 0 && len(records) > limit {
+		return records[:limit]
+	}
+	return records
+}
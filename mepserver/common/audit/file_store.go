@@ -0,0 +1,155 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+)
+
+const (
+	defaultMaxFileSize = 20
+	defaultMaxBackups  = 5
+	megaByte           = 1024 * 1024
+	auditFileMode      = 0640
+	maxRecordLength    = 64 * 1024
+)
+
+// fileStore keeps the audit records as json lines in a local file, the file is rotated on reaching the size limit
+type fileStore struct {
+	filePath    string
+	maxFileSize int64
+	maxBackups  int
+	mutex       sync.Mutex
+}
+
+func newFileStore(filePath string, maxFileSize int, maxBackups int) *fileStore {
+	if maxFileSize <= 0 {
+		maxFileSize = defaultMaxFileSize
+	}
+	if maxBackups <= 0 {
+		maxBackups = defaultMaxBackups
+	}
+	return &fileStore{
+		filePath:    filepath.Clean(filePath),
+		maxFileSize: int64(maxFileSize) * megaByte,
+		maxBackups:  maxBackups,
+	}
+}
+
+// Save appends the record to the audit file
+func (f *fileStore) Save(record *Record) error {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	recordBytes = append(recordBytes, '\n')
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err = f.rotateIfRequired(int64(len(recordBytes))); err != nil {
+		log.Errorf(err, "Audit file rotation failed.")
+	}
+	file, err := os.OpenFile(f.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, auditFileMode)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(recordBytes)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+// Query reads the current and the rotated audit files and filters the records
+func (f *fileStore) Query(filter *Filter) ([]Record, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	result := make([]Record, 0)
+	for i := 0; i <= f.maxBackups; i++ {
+		records, err := readRecords(f.backupPath(i), filter)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		result = append(result, records...)
+	}
+	return limitRecords(sortRecords(result), filter.Limit), nil
+}
+
+func (f *fileStore) backupPath(index int) string {
+	if index == 0 {
+		return f.filePath
+	}
+	return fmt.Sprintf("%s.%d", f.filePath, index)
+}
+
+// rotateIfRequired shifts the audit files by one index when the new record exceeds the file size limit
+func (f *fileStore) rotateIfRequired(length int64) error {
+	info, err := os.Stat(f.filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if info.Size()+length <= f.maxFileSize {
+		return nil
+	}
+	if err = os.Remove(f.backupPath(f.maxBackups)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for i := f.maxBackups - 1; i >= 0; i-- {
+		if err = os.Rename(f.backupPath(i), f.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func readRecords(path string, filter *Filter) ([]Record, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxRecordLength)
+	for scanner.Scan() {
+		record := Record{}
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue
+		}
+		if filter.Match(&record) {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
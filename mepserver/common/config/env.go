@@ -0,0 +1,87 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// envOverride sets a configuration from the environment variable, the variables override the configuration file
+type envOverride struct {
+	name  string
+	apply func(c *MepServerConfig, value string) error
+}
+
+var envOverrides = []envOverride{
+	// dns server endpoint variables are kept as it is for the existing deployments
+	{"DNS_SERVER_HOST", func(c *MepServerConfig, value string) error {
+		c.DNSAgent.Endpoint.Address.Host = value
+		return nil
+	}},
+	{"DNS_SERVER_PORT", func(c *MepServerConfig, value string) error {
+		return parseIntEnv(value, &c.DNSAgent.Endpoint.Address.Port)
+	}},
+	{"MEP_DNS_AGENT_TYPE", func(c *MepServerConfig, value string) error {
+		c.DNSAgent.Type = value
+		return nil
+	}},
+	{"MEP_DATAPLANE_TYPE", func(c *MepServerConfig, value string) error {
+		c.DataPlane.Type = value
+		return nil
+	}},
+	{"MEP_API_GATEWAY_TYPE", func(c *MepServerConfig, value string) error {
+		c.APIGateway.Type = value
+		return nil
+	}},
+	{"MEP_HTTP_LOG_STORE", func(c *MepServerConfig, value string) error {
+		c.HttpLog.Store = value
+		return nil
+	}},
+	{"MEP_TRACING_ENDPOINT", func(c *MepServerConfig, value string) error {
+		c.Tracing.Enabled = true
+		c.Tracing.Endpoint = value
+		return nil
+	}},
+}
+
+func parseIntEnv(value string, target *int) error {
+	num, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("not a number")
+	}
+	*target = num
+	return nil
+}
+
+// applyEnvOverrides overrides the configurations by the non-empty environment variables, the names of the applied
+// variables are returned. The overridden configurations are validated along with the file configurations
+func applyEnvOverrides(c *MepServerConfig) ([]string, error) {
+	applied := make([]string, 0)
+	for _, override := range envOverrides {
+		value := os.Getenv(override.name)
+		if len(value) == 0 {
+			continue
+		}
+		if err := override.apply(c, value); err != nil {
+			return nil, fmt.Errorf("invalid environment variable %s(%s)", override.name, err.Error())
+		}
+		applied = append(applied, override.name)
+	}
+	return applied, nil
+}
@@ -28,52 +28,119 @@ import (
 
 // MepServerConfig holds mep server configurations
 type MepServerConfig struct {
-	DNSAgent  DNSAgent  `yaml:"dnsAgent"`
-	DataPlane DataPlane `yaml:"dataplane"`
+	DNSAgent         DNSAgent         `json:"dnsAgent" yaml:"dnsAgent"`
+	DataPlane        DataPlane        `json:"dataplane" yaml:"dataplane"`
+	PlatformServices PlatformServices `json:"platformServices" yaml:"platformServices"`
+	Audit            Audit            `json:"audit" yaml:"audit"`
+	APIGateway       APIGateway       `json:"apiGateway" yaml:"apiGateway"`
+	HttpLog          HttpLog          `json:"httpLog" yaml:"httpLog"`
+	Tracing          Tracing          `json:"tracing" yaml:"tracing"`
 }
 
 // Address endpoint in config
 type Address struct {
-	Host string `yaml:"host" validate:"omitempty,min=1,max=253"`
-	Port int    `yaml:"port" validate:"omitempty,min=1,max=65535"`
+	Host string `json:"host" yaml:"host" validate:"omitempty,min=1,max=253"`
+	Port int    `json:"port" yaml:"port" validate:"omitempty,min=1,max=65535"`
 }
 
 // EndPoint config field
 type EndPoint struct {
-	Address Address `yaml:"address"`
+	Address Address `json:"address" yaml:"address"`
 }
 
-// DNSAgent related configurations
+// DNSAgent related configurations, the records are written to the primary end point and fanned out to the
+// secondary end points
 type DNSAgent struct {
-	Type     string   `yaml:"type" validate:"oneof=local dataplane all"`
-	Endpoint EndPoint `yaml:"endPoint" validate:"required_unless=type dataplane"`
+	Type               string     `json:"type" yaml:"type" validate:"oneof=local dataplane all"`
+	Endpoint           EndPoint   `json:"endPoint" yaml:"endPoint" validate:"required_unless=type dataplane"`
+	SecondaryEndpoints []EndPoint `json:"secondaryEndPoints" yaml:"secondaryEndPoints" validate:"omitempty,max=8,dive"`
+	Zone               string     `json:"zone" yaml:"zone" validate:"omitempty,max=253"`
+	// TLS the management api of the dns servers is served over https
+	TLS bool `json:"tls" yaml:"tls"`
+	// TokenFile file of the bearer token of the dns server management api, empty sends no token
+	TokenFile string `json:"tokenFile" yaml:"tokenFile" validate:"omitempty,max=4096"`
 }
 
 // DataPlane related configurations
 type DataPlane struct {
-	Type string `yaml:"type" validate:"oneof=none"`
+	Type string `json:"type" yaml:"type" validate:"oneof=none"`
 }
 
-// LoadMepServerConfig read and load the mep server configurations
+// PlatformServices radio network information(MEC 012) and location(MEC 013) service configurations
+type PlatformServices struct {
+	Provider     string `json:"provider" yaml:"provider" validate:"omitempty,oneof=none simulator"`
+	ScenarioFile string `json:"scenarioFile" yaml:"scenarioFile" validate:"required_if=Provider simulator,omitempty,max=4096"`
+}
+
+// Audit configurations of the audit trail of the mp1 and mm5 modifications
+type Audit struct {
+	Store       string `json:"store" yaml:"store" validate:"omitempty,oneof=none etcd file"`
+	FilePath    string `json:"filePath" yaml:"filePath" validate:"required_if=Store file,omitempty,max=4096"`
+	MaxFileSize int    `json:"maxFileSize" yaml:"maxFileSize" validate:"omitempty,min=1,max=1024"`
+	MaxBackups  int    `json:"maxBackups" yaml:"maxBackups" validate:"omitempty,min=1,max=32"`
+	MaxRecords  int    `json:"maxRecords" yaml:"maxRecords" validate:"omitempty,min=1,max=1000000"`
+}
+
+// APIGateway api gateway configurations, reconcile interval is in seconds
+type APIGateway struct {
+	Type              string `json:"type" yaml:"type" validate:"omitempty,oneof=kong none"`
+	ReconcileInterval int    `json:"reconcileInterval" yaml:"reconcileInterval" validate:"omitempty,min=10,max=86400"`
+}
+
+// HttpLog store configurations of the api gateway http logs used by the service governance
+type HttpLog struct {
+	Store      string `json:"store" yaml:"store" validate:"omitempty,oneof=elasticsearch file memory"`
+	EsUrl      string `json:"esUrl" yaml:"esUrl" validate:"omitempty,url,max=2048"`
+	FilePath   string `json:"filePath" yaml:"filePath" validate:"required_if=Store file,omitempty,max=4096"`
+	MaxRecords int    `json:"maxRecords" yaml:"maxRecords" validate:"omitempty,min=1,max=10000000"`
+}
+
+// Tracing configurations of the distributed tracing spans exported to an otlp collector
+type Tracing struct {
+	Enabled     bool    `json:"enabled" yaml:"enabled"`
+	Endpoint    string  `json:"endpoint" yaml:"endpoint" validate:"required_if=Enabled true,omitempty,url,max=2048"`
+	ServiceName string  `json:"serviceName" yaml:"serviceName" validate:"omitempty,max=128"`
+	SampleRatio float64 `json:"sampleRatio" yaml:"sampleRatio" validate:"omitempty,min=0,max=1"`
+}
+
+// LoadMepServerConfig read and load the mep server configurations, the environment variables override the file
+// configurations
 func LoadMepServerConfig() (*MepServerConfig, error) {
-	configFilePath := filepath.FromSlash(util.MepServerConfigPath)
+	mepConfig, overrides, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	initEffective(mepConfig, overrides)
+	return mepConfig, nil
+}
+
+// configFilePath the path of the mep server configuration file
+var configFilePath = filepath.FromSlash(util.MepServerConfigPath)
+
+// loadConfig reads the configuration file, applies the environment overrides and validates the result
+func loadConfig() (*MepServerConfig, []string, error) {
 	configData, err := ioutil.ReadFile(configFilePath)
 	if err != nil {
 		log.Error("Reading MEP configuration file error.", nil)
-		return nil, err
+		return nil, nil, err
 	}
 	var mepConfig MepServerConfig
 	err = yaml.Unmarshal(configData, &mepConfig)
 	if err != nil {
 		log.Error("Parsing MEP configuration file error.", nil)
-		return nil, err
+		return nil, nil, err
+	}
+	overrides, err := applyEnvOverrides(&mepConfig)
+	if err != nil {
+		log.Error("MEP config environment override failed.", err)
+		return nil, nil, err
 	}
 	err = mepConfig.validateConfig()
 	if err != nil {
 		log.Error("MEP config validation failed.", err)
-		return nil, err
+		return nil, nil, err
 	}
-	return &mepConfig, nil
+	return &mepConfig, overrides, nil
 }
 
 func (c *MepServerConfig) validateConfig() error {
@@ -0,0 +1,175 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+)
+
+const reloadInterval = 10 * time.Second
+
+// hotReloadSections the configuration sections applied without restart, the changes of the other sections are
+// reported as restart required
+var hotReloadSections = map[string]bool{"DNSAgent": true, "DataPlane": true}
+
+// ReloadHandler applies the reloaded configurations, the reload is applied only if all the handlers validate it.
+// Applying can not fail, hence the handlers prepare the changes while validating. The handlers must not call
+// GetEffective
+type ReloadHandler interface {
+	// ValidateReload checks the reloaded configurations can be applied
+	ValidateReload(newConfig *MepServerConfig) error
+	// ApplyReload applies the reloaded configurations
+	ApplyReload(newConfig *MepServerConfig)
+}
+
+// Effective holds the configurations in effect
+type Effective struct {
+	Config          MepServerConfig `json:"config"`
+	EnvOverrides    []string        `json:"envOverrides"`
+	LoadedAt        time.Time       `json:"loadedAt"`
+	RestartRequired []string        `json:"restartRequired"`
+}
+
+var (
+	// reloadMutex serializes the reloads, effectiveMutex guards the effective configurations
+	reloadMutex    sync.Mutex
+	effectiveMutex sync.RWMutex
+	effective      *Effective
+	handlers       []ReloadHandler
+	lastReloadErr  string
+	watchOnce      sync.Once
+)
+
+func initEffective(mepConfig *MepServerConfig, overrides []string) {
+	effectiveMutex.Lock()
+	defer effectiveMutex.Unlock()
+	if effective != nil {
+		return
+	}
+	effective = &Effective{Config: *mepConfig, EnvOverrides: overrides, LoadedAt: time.Now().UTC(),
+		RestartRequired: []string{}}
+}
+
+// GetEffective returns a copy of the configurations in effect, nil is returned if the configurations are not loaded
+func GetEffective() *Effective {
+	effectiveMutex.RLock()
+	defer effectiveMutex.RUnlock()
+	if effective == nil {
+		return nil
+	}
+	effectiveCopy := *effective
+	return &effectiveCopy
+}
+
+// Watch registers the handler of the reloaded configurations, the configuration file is watched from the first
+// registration on
+func Watch(handler ReloadHandler) {
+	reloadMutex.Lock()
+	handlers = append(handlers, handler)
+	reloadMutex.Unlock()
+	watchOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(reloadInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				_ = Reload()
+			}
+		}()
+	})
+}
+
+// Reload reads the configurations again and applies the changes of the hot reloadable sections, the invalid
+// configurations are rejected and the configurations in effect are kept
+func Reload() error {
+	reloadMutex.Lock()
+	defer reloadMutex.Unlock()
+
+	loaded, overrides, err := loadConfig()
+	if err != nil {
+		reportReloadError(err)
+		return err
+	}
+	current := GetEffective()
+	if current == nil {
+		initEffective(loaded, overrides)
+		return nil
+	}
+	merged, restartRequired := mergeHotReloadSections(&current.Config, loaded)
+	if !reflect.DeepEqual(restartRequired, current.RestartRequired) && len(restartRequired) != 0 {
+		log.Warnf("MEP configuration changes of %v require restart.", restartRequired)
+	}
+	if reflect.DeepEqual(*merged, current.Config) {
+		lastReloadErr = ""
+		setEffective(current.Config, current.EnvOverrides, current.LoadedAt, restartRequired)
+		return nil
+	}
+
+	for _, handler := range handlers {
+		if err = handler.ValidateReload(merged); err != nil {
+			reportReloadError(err)
+			return err
+		}
+	}
+	for _, handler := range handlers {
+		handler.ApplyReload(merged)
+	}
+	lastReloadErr = ""
+	setEffective(*merged, overrides, time.Now().UTC(), restartRequired)
+	log.Info("MEP configuration reloaded.")
+	return nil
+}
+
+func setEffective(mepConfig MepServerConfig, overrides []string, loadedAt time.Time, restartRequired []string) {
+	effectiveMutex.Lock()
+	effective = &Effective{Config: mepConfig, EnvOverrides: overrides, LoadedAt: loadedAt,
+		RestartRequired: restartRequired}
+	effectiveMutex.Unlock()
+}
+
+// reportReloadError logs the reload failure once until the failure changes, the file is polled periodically
+func reportReloadError(err error) {
+	if err.Error() == lastReloadErr {
+		return
+	}
+	lastReloadErr = err.Error()
+	log.Errorf(err, "MEP configuration reload rejected, the configurations in effect are kept.")
+}
+
+// mergeHotReloadSections copies the hot reloadable sections of the loaded configurations over the current ones,
+// the names of the other changed sections are returned
+func mergeHotReloadSections(current *MepServerConfig, loaded *MepServerConfig) (*MepServerConfig, []string) {
+	merged := *current
+	mergedValue := reflect.ValueOf(&merged).Elem()
+	loadedValue := reflect.ValueOf(loaded).Elem()
+	restartRequired := make([]string, 0)
+	for i := 0; i < mergedValue.NumField(); i++ {
+		field := mergedValue.Type().Field(i)
+		if reflect.DeepEqual(mergedValue.Field(i).Interface(), loadedValue.Field(i).Interface()) {
+			continue
+		}
+		if hotReloadSections[field.Name] {
+			mergedValue.Field(i).Set(loadedValue.Field(i))
+			continue
+		}
+		restartRequired = append(restartRequired, field.Tag.Get("yaml"))
+	}
+	return &merged, restartRequired
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const reloadConfigYaml = `
+dnsAgent:
+  type: %s
+  endPoint:
+    address:
+      host: localhost
+      port: 8080
+dataplane:
+  type: none
+apiGateway:
+  type: %s
+`
+
+type testReloadHandler struct {
+	validateErr error
+	applied     *MepServerConfig
+}
+
+func (h *testReloadHandler) ValidateReload(newConfig *MepServerConfig) error {
+	return h.validateErr
+}
+
+func (h *testReloadHandler) ApplyReload(newConfig *MepServerConfig) {
+	h.applied = newConfig
+}
+
+// useTestConfigFile points the loader to a configuration file in a temporary directory, the returned function
+// restores the configuration file path
+func useTestConfigFile(t *testing.T) func() {
+	dir, err := ioutil.TempDir("", "mepconfig")
+	assert.NoError(t, err)
+	original := configFilePath
+	configFilePath = filepath.Join(dir, "config.yaml")
+	return func() {
+		configFilePath = original
+		_ = os.RemoveAll(dir)
+	}
+}
+
+func writeConfigFile(t *testing.T, dnsType string, apiGwType string) {
+	configData := []byte(fmt.Sprintf(reloadConfigYaml, dnsType, apiGwType))
+	assert.NoError(t, ioutil.WriteFile(configFilePath, configData, 0600))
+}
+
+func resetReloadState(handler ReloadHandler) {
+	effective = nil
+	lastReloadErr = ""
+	handlers = []ReloadHandler{handler}
+}
+
+func TestEnvOverrides(t *testing.T) {
+	defer useTestConfigFile(t)()
+	_ = os.Setenv("DNS_SERVER_HOST", "mep-dns-server")
+	_ = os.Setenv("DNS_SERVER_PORT", "8081")
+	defer os.Unsetenv("DNS_SERVER_HOST")
+	defer os.Unsetenv("DNS_SERVER_PORT")
+	writeConfigFile(t, "all", "kong")
+
+	mepConfig, overrides, err := loadConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, Address{Host: "mep-dns-server", Port: 8081}, mepConfig.DNSAgent.Endpoint.Address)
+	assert.Equal(t, []string{"DNS_SERVER_HOST", "DNS_SERVER_PORT"}, overrides)
+
+	_ = os.Setenv("DNS_SERVER_PORT", "port")
+	_, _, err = loadConfig()
+	assert.Error(t, err)
+	_ = os.Setenv("DNS_SERVER_PORT", "70000")
+	_, _, err = loadConfig()
+	assert.Error(t, err)
+}
+
+func TestReloadAppliesHotSections(t *testing.T) {
+	defer useTestConfigFile(t)()
+	handler := &testReloadHandler{}
+	resetReloadState(handler)
+	writeConfigFile(t, "all", "kong")
+	_, err := LoadMepServerConfig()
+	assert.NoError(t, err)
+
+	writeConfigFile(t, "local", "none")
+	assert.NoError(t, Reload())
+	assert.Equal(t, "local", handler.applied.DNSAgent.Type)
+	// api gateway change requires restart, the effective one is kept
+	assert.Equal(t, "kong", handler.applied.APIGateway.Type)
+	current := GetEffective()
+	assert.Equal(t, "local", current.Config.DNSAgent.Type)
+	assert.Equal(t, "kong", current.Config.APIGateway.Type)
+	assert.Equal(t, []string{"apiGateway"}, current.RestartRequired)
+
+	// unchanged configurations are not applied again
+	handler.applied = nil
+	assert.NoError(t, Reload())
+	assert.Nil(t, handler.applied)
+}
+
+func TestReloadRejected(t *testing.T) {
+	defer useTestConfigFile(t)()
+	handler := &testReloadHandler{}
+	resetReloadState(handler)
+	writeConfigFile(t, "all", "kong")
+	_, err := LoadMepServerConfig()
+	assert.NoError(t, err)
+
+	// invalid configurations
+	writeConfigFile(t, "unknown", "kong")
+	assert.Error(t, Reload())
+	assert.Equal(t, "all", GetEffective().Config.DNSAgent.Type)
+
+	// configurations rejected by the handler
+	handler.validateErr = errors.New("unsupported")
+	writeConfigFile(t, "local", "kong")
+	assert.Error(t, Reload())
+	assert.Nil(t, handler.applied)
+	assert.Equal(t, "all", GetEffective().Config.DNSAgent.Type)
+}
@@ -0,0 +1,142 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package apigw defines the api gateway interfaces
+package apigw
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	"mepserver/common/config"
+)
+
+// PropertyPrefix prefix of the service instance properties holding the api gateway service upstream urls, the
+// property key is the prefix followed by the api gateway service name
+const PropertyPrefix = "apiGw/"
+
+// Service represents an api gateway service with its upstream url
+type Service struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+// ServiceStatus holds the presence of the service entities on the api gateway
+type ServiceStatus struct {
+	ServiceExists bool
+	RouteExists   bool
+	JwtEnabled    bool
+}
+
+// APIGateway interface
+type APIGateway interface {
+	// InitAPIGateway initialize the api gateway
+	InitAPIGateway(config *config.MepServerConfig) error
+	// AddOrUpdateService add or update the service with its upstream url
+	AddOrUpdateService(service Service) error
+	// DeleteService deletes the service, missing service is not an error
+	DeleteService(name string) error
+	// AddOrUpdateRoute add or update the route of the service
+	AddOrUpdateRoute(service Service) error
+	// DeleteRoute deletes the route of the service, missing route is not an error
+	DeleteRoute(name string) error
+	// EnableJwtPlugin enables the jwt plugin on the service, already enabled plugin is not an error. The gateways
+	// identifying the app instance of the token pass it to the upstream service in the X-AppinstanceID header
+	EnableJwtPlugin(name string) error
+	// GetServiceStatus reads the presence of the service, route and the jwt plugin
+	GetServiceStatus(name string) (*ServiceStatus, error)
+	// SetRateLimit add or update the rate limit of the consumer on the route of the service
+	SetRateLimit(name string, limit *RateLimit) error
+	// DeleteRateLimit deletes the rate limit of the consumer on the route of the service, missing limit is not an error
+	DeleteRateLimit(name string, consumerAppInstanceId string) error
+}
+
+// ContextAPIGateway is implemented by the api gateways which send the requests in the context of the caller
+type ContextAPIGateway interface {
+	WithContext(ctx context.Context) APIGateway
+}
+
+// WithContext returns the gateway sending the requests in the context, the gateway itself is returned if it does
+// not support the context
+func WithContext(gateway APIGateway, ctx context.Context) APIGateway {
+	if contextGateway, ok := gateway.(ContextAPIGateway); ok {
+		return contextGateway.WithContext(ctx)
+	}
+	return gateway
+}
+
+// ServicesFromProperties reads the api gateway services recorded in the service instance properties
+func ServicesFromProperties(properties map[string]string) []Service {
+	services := make([]Service, 0)
+	for key, value := range properties {
+		if !strings.HasPrefix(key, PropertyPrefix) || len(value) == 0 {
+			continue
+		}
+		name := strings.TrimPrefix(key, PropertyPrefix)
+		if len(name) == 0 {
+			continue
+		}
+		services = append(services, Service{Name: name, Url: value})
+	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+	return services
+}
+
+// RegisterServices registers the services with their routes and jwt plugins on the api gateway. On failure the error
+// is returned and if requested the services registered by this call are removed.
+func RegisterServices(gateway APIGateway, services []Service, rollback bool) error {
+	for i, service := range services {
+		if err := registerService(gateway, service); err != nil {
+			log.Errorf(err, "API gateway registration failed for service(name: %s).", service.Name)
+			if rollback {
+				UnregisterServices(gateway, services[:i+1])
+			}
+			return fmt.Errorf("api gateway registration of service %s failed: %v", service.Name, err)
+		}
+	}
+	return nil
+}
+
+func registerService(gateway APIGateway, service Service) error {
+	log.Infof("API gateway registration for service(name: %s, uri: %s).", service.Name, service.Url)
+	if err := gateway.AddOrUpdateService(service); err != nil {
+		return err
+	}
+	if err := gateway.AddOrUpdateRoute(service); err != nil {
+		return err
+	}
+	return gateway.EnableJwtPlugin(service.Name)
+}
+
+// UnregisterServices removes the routes and the services from the api gateway, failures are logged and the remaining
+// services are continued
+func UnregisterServices(gateway APIGateway, services []Service) {
+	for _, service := range services {
+		if err := gateway.DeleteRoute(service.Name); err != nil {
+			log.Errorf(err, "Failed to delete API gateway route(name: %s).", service.Name)
+		}
+		// plugins are deleted along with the service
+		if err := gateway.DeleteService(service.Name); err != nil {
+			log.Errorf(err, "Failed to delete API gateway service(name: %s).", service.Name)
+		}
+	}
+}
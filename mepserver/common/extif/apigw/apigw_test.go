@@ -0,0 +1,103 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apigw_test
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/agiledragon/gomonkey"
+	"github.com/apache/servicecomb-service-center/server/core/proto"
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/extif/apigw"
+	"mepserver/common/extif/apigw/memory"
+	"mepserver/common/extif/backend"
+	meputil "mepserver/common/util"
+)
+
+const (
+	serviceOne = "serviceOne4c3a2bf8df4144d4b6d0"
+	serviceTwo = "serviceTwo1d2c7fbb6e0e4cd1a6e2"
+	upstream   = "http://10.1.1.1:8080/"
+)
+
+func TestServicesFromProperties(t *testing.T) {
+	properties := map[string]string{
+		"serName":                         "serviceOne",
+		apigw.PropertyPrefix + serviceTwo: upstream,
+		apigw.PropertyPrefix + serviceOne: upstream,
+		apigw.PropertyPrefix:              upstream,
+	}
+	services := apigw.ServicesFromProperties(properties)
+	assert.Equal(t, []apigw.Service{{Name: serviceOne, Url: upstream}, {Name: serviceTwo, Url: upstream}}, services)
+}
+
+func TestRegisterServicesRollback(t *testing.T) {
+	gateway := memory.NewGateway()
+	gateway.FailServices[serviceTwo] = true
+	services := []apigw.Service{{Name: serviceOne, Url: upstream}, {Name: serviceTwo, Url: upstream}}
+
+	err := apigw.RegisterServices(gateway, services, true)
+	assert.Error(t, err)
+	status, _ := gateway.GetServiceStatus(serviceOne)
+	assert.Equal(t, apigw.ServiceStatus{}, *status)
+
+	delete(gateway.FailServices, serviceTwo)
+	err = apigw.RegisterServices(gateway, services, true)
+	assert.NoError(t, err)
+	for _, service := range services {
+		status, _ = gateway.GetServiceStatus(service.Name)
+		assert.Equal(t, apigw.ServiceStatus{ServiceExists: true, RouteExists: true, JwtEnabled: true}, *status)
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	gateway := memory.NewGateway()
+	_ = gateway.AddOrUpdateService(apigw.Service{Name: serviceOne, Url: upstream})
+
+	patches := gomonkey.ApplyFunc(meputil.FindInstanceByKey, func(result url.Values) (*proto.FindInstancesResponse,
+		error) {
+		return &proto.FindInstancesResponse{Instances: []*proto.MicroServiceInstance{
+			{Properties: map[string]string{apigw.PropertyPrefix + serviceOne: upstream}},
+			{Properties: map[string]string{apigw.PropertyPrefix + serviceTwo: upstream}},
+			{Properties: map[string]string{"serName": "serviceWithoutGateway"}},
+		}}, nil
+	})
+	defer patches.Reset()
+	patches.ApplyFunc(backend.GetRecordsWithCompleteKeyPath, func(path string) (map[string][]byte, int) {
+		return map[string][]byte{}, 0
+	})
+
+	err := apigw.NewReconciler(gateway, 0).Reconcile()
+	assert.NoError(t, err)
+	for _, name := range []string{serviceOne, serviceTwo} {
+		status, _ := gateway.GetServiceStatus(name)
+		assert.Equal(t, apigw.ServiceStatus{ServiceExists: true, RouteExists: true, JwtEnabled: true}, *status)
+	}
+}
+
+func TestReconcileRegistryFailure(t *testing.T) {
+	patches := gomonkey.ApplyFunc(meputil.FindInstanceByKey, func(result url.Values) (*proto.FindInstancesResponse,
+		error) {
+		return nil, errors.New("query from etch error")
+	})
+	defer patches.Reset()
+
+	assert.Error(t, apigw.NewReconciler(memory.NewGateway(), 0).Reconcile())
+}
@@ -0,0 +1,37 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package common implements api gateway common functionalities
+package common
+
+import (
+	"mepserver/common/config"
+	"mepserver/common/extif/apigw"
+	"mepserver/common/extif/apigw/kong"
+	"mepserver/common/extif/apigw/none"
+	meputil "mepserver/common/util"
+)
+
+// CreateAPIGateway factory to create api gateway, kong is used if not configured
+func CreateAPIGateway(config *config.MepServerConfig) apigw.APIGateway {
+	switch config.APIGateway.Type {
+	case "", meputil.ApiGatewayKong:
+		return &kong.Gateway{}
+	case meputil.ApiGatewayNone:
+		return &none.NoneGateway{}
+	}
+	return nil
+}
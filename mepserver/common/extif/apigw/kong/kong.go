@@ -0,0 +1,331 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package kong implements the kong api gateway driver
+package kong
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	"mepserver/common/config"
+	"mepserver/common/extif/apigw"
+	meputil "mepserver/common/util"
+	"tracing"
+)
+
+const (
+	servicesPath    = "/services/"
+	routesPath      = "/routes/"
+	pluginsPath     = "/plugins"
+	consumersPath   = "/consumers/"
+	rateLimitPlugin = "rate-limiting"
+	tracingPlugin   = "opentelemetry"
+	requestTimeout  = 10 * time.Second
+)
+
+type kongService struct {
+	Name string `json:"name"`
+	Url  string `json:"url"`
+}
+
+type kongRoute struct {
+	Name  string   `json:"name"`
+	Paths []string `json:"paths"`
+}
+
+type kongReference struct {
+	Id string `json:"id"`
+}
+
+type kongPlugin struct {
+	Name     string         `json:"name"`
+	Consumer *kongReference `json:"consumer,omitempty"`
+	Config   interface{}    `json:"config,omitempty"`
+}
+
+type kongConsumer struct {
+	Id       string `json:"id,omitempty"`
+	Username string `json:"username"`
+}
+
+type kongRateLimitConfig struct {
+	Second  int    `json:"second,omitempty"`
+	Minute  int    `json:"minute,omitempty"`
+	Hour    int    `json:"hour,omitempty"`
+	Day     int    `json:"day,omitempty"`
+	Month   int    `json:"month,omitempty"`
+	LimitBy string `json:"limit_by"`
+	Policy  string `json:"policy"`
+}
+
+type kongTracingConfig struct {
+	Endpoint           string            `json:"endpoint"`
+	ResourceAttributes map[string]string `json:"resource_attributes"`
+}
+
+type kongJwtConfig struct {
+	ClaimsToVerify []string `json:"claims_to_verify"`
+}
+
+type kongPluginList struct {
+	Data []kongPlugin `json:"data"`
+}
+
+// Gateway implements the api gateway interface on the kong admin api
+type Gateway struct {
+	apigw.APIGateway
+	baseURL string
+	client  *http.Client
+	ctx     context.Context
+}
+
+// InitAPIGateway reads the kong admin endpoint and the ca certificate
+func (k *Gateway) InitAPIGateway(config *config.MepServerConfig) error {
+	appConfig, err := meputil.GetAppConfig()
+	if err != nil {
+		log.Error("Get app config failed.", err)
+		return err
+	}
+	tlsCfg, err := meputil.TLSConfig(meputil.ApiGwCaCertName, false)
+	if err != nil {
+		log.Error("API gateway tls configuration failed.", err)
+		return err
+	}
+	k.baseURL = fmt.Sprintf("https://%s:%s", appConfig["apigw_host"], appConfig["apigw_port"])
+	k.client = &http.Client{
+		Transport: tracing.NewTransport(&http.Transport{TLSClientConfig: tlsCfg}),
+		Timeout:   requestTimeout,
+	}
+	if config.Tracing.Enabled {
+		// the older kong versions have no opentelemetry plugin, the tracing continues without the kong spans
+		if err = k.enableTracingPlugin(config.Tracing.Endpoint); err != nil {
+			log.Warn("Kong opentelemetry plugin could not be enabled.")
+		}
+	}
+	return nil
+}
+
+// WithContext returns a copy of the gateway sending the admin requests in the context, the requests are traced as
+// the children of the span in the context
+func (k *Gateway) WithContext(ctx context.Context) apigw.APIGateway {
+	gateway := *k
+	gateway.ctx = ctx
+	return &gateway
+}
+
+// enableTracingPlugin enables the kong opentelemetry plugin globally, kong propagates the w3c trace context to the
+// upstream services and exports its own spans to the collector
+func (k *Gateway) enableTracingPlugin(endpoint string) error {
+	body := kongPlugin{
+		Name: tracingPlugin,
+		Config: kongTracingConfig{
+			Endpoint:           endpoint,
+			ResourceAttributes: map[string]string{"service.name": "kong"},
+		},
+	}
+	_, _, err := k.send(http.MethodPut, pluginsPath+"/"+namedPluginId(tracingPlugin), body, http.StatusOK,
+		http.StatusCreated)
+	return err
+}
+
+// AddOrUpdateService add or update the kong service
+func (k *Gateway) AddOrUpdateService(service apigw.Service) error {
+	body := kongService{Name: service.Name, Url: service.Url}
+	_, _, err := k.send(http.MethodPut, servicesPath+service.Name, body, http.StatusOK, http.StatusCreated)
+	return err
+}
+
+// DeleteService deletes the kong service
+func (k *Gateway) DeleteService(name string) error {
+	_, _, err := k.send(http.MethodDelete, servicesPath+name, nil, http.StatusNoContent, http.StatusNotFound)
+	return err
+}
+
+// AddOrUpdateRoute add or update the kong route of the service, the route path is the service name
+func (k *Gateway) AddOrUpdateRoute(service apigw.Service) error {
+	body := kongRoute{Name: service.Name, Paths: []string{"/" + service.Name}}
+	_, _, err := k.send(http.MethodPut, servicesPath+service.Name+routesPath+service.Name, body, http.StatusOK,
+		http.StatusCreated)
+	return err
+}
+
+// DeleteRoute deletes the kong route of the service
+func (k *Gateway) DeleteRoute(name string) error {
+	_, _, err := k.send(http.MethodDelete, servicesPath+name+routesPath+name, nil, http.StatusNoContent,
+		http.StatusNotFound)
+	return err
+}
+
+// EnableJwtPlugin enables the kong jwt plugin on the service with the appid-header plugin, which passes the app
+// instance id of the token to the upstream service in the X-AppinstanceID header
+func (k *Gateway) EnableJwtPlugin(name string) error {
+	body := kongPlugin{Name: meputil.JwtPlugin, Config: kongJwtConfig{ClaimsToVerify: []string{"exp"}}}
+	// conflict is returned if the plugin is already enabled
+	_, _, err := k.send(http.MethodPost, servicesPath+name+pluginsPath, body, http.StatusCreated, http.StatusConflict)
+	if err != nil {
+		return err
+	}
+	_, _, err = k.send(http.MethodPost, servicesPath+name+pluginsPath, kongPlugin{Name: meputil.AppidPlugin},
+		http.StatusCreated, http.StatusConflict)
+	return err
+}
+
+// GetServiceStatus reads the presence of the kong service, route and the jwt plugin
+func (k *Gateway) GetServiceStatus(name string) (*apigw.ServiceStatus, error) {
+	status := &apigw.ServiceStatus{}
+	exists, _, err := k.get(servicesPath + name)
+	if err != nil || !exists {
+		return status, err
+	}
+	status.ServiceExists = true
+
+	status.RouteExists, _, err = k.get(servicesPath + name + routesPath + name)
+	if err != nil {
+		return nil, err
+	}
+
+	_, pluginsBody, err := k.get(servicesPath + name + pluginsPath)
+	if err != nil {
+		return nil, err
+	}
+	plugins := kongPluginList{}
+	if err = json.Unmarshal(pluginsBody, &plugins); err != nil {
+		return nil, fmt.Errorf("kong plugin list parse failed")
+	}
+	for _, plugin := range plugins.Data {
+		if plugin.Name == meputil.JwtPlugin {
+			status.JwtEnabled = true
+			break
+		}
+	}
+	return status, nil
+}
+
+// SetRateLimit add or update the kong rate-limiting plugin scoped to the consumer and the route of the service, the
+// consumer is created with the app instance id as the username if not exists. It is the consumer mep auth issues the
+// tokens of the app instance for, hence the requests of the app instance are limited
+func (k *Gateway) SetRateLimit(name string, limit *apigw.RateLimit) error {
+	consumerPath := consumersPath + limit.ConsumerAppInstanceId
+	_, consumerBody, err := k.send(http.MethodPut, consumerPath, kongConsumer{Username: limit.ConsumerAppInstanceId},
+		http.StatusOK, http.StatusCreated)
+	if err != nil {
+		return err
+	}
+	consumer := kongConsumer{}
+	if err = json.Unmarshal(consumerBody, &consumer); err != nil || len(consumer.Id) == 0 {
+		return fmt.Errorf("kong consumer parse failed")
+	}
+
+	body := kongPlugin{
+		Name:     rateLimitPlugin,
+		Consumer: &kongReference{Id: consumer.Id},
+		Config: kongRateLimitConfig{
+			Second:  limit.Second,
+			Minute:  limit.Minute,
+			Hour:    limit.Hour,
+			Day:     limit.Day,
+			Month:   limit.Month,
+			LimitBy: "consumer",
+			Policy:  "local",
+		},
+	}
+	pluginPath := routesPath + name + pluginsPath + "/" + rateLimitPluginId(name, limit.ConsumerAppInstanceId)
+	_, _, err = k.send(http.MethodPut, pluginPath, body, http.StatusOK, http.StatusCreated)
+	return err
+}
+
+// DeleteRateLimit deletes the kong rate-limiting plugin of the consumer on the route of the service
+func (k *Gateway) DeleteRateLimit(name string, consumerAppInstanceId string) error {
+	pluginPath := pluginsPath + "/" + rateLimitPluginId(name, consumerAppInstanceId)
+	_, _, err := k.send(http.MethodDelete, pluginPath, nil, http.StatusNoContent, http.StatusNotFound)
+	return err
+}
+
+// rateLimitPluginId generates the name based uuid of the rate limit plugin, hence the plugin is updated in place
+func rateLimitPluginId(name string, consumerAppInstanceId string) string {
+	return namedPluginId(rateLimitPlugin + "/" + name + "/" + consumerAppInstanceId)
+}
+
+// namedPluginId generates the sha1 name based uuid of the plugin
+func namedPluginId(name string) string {
+	sum := sha1.Sum([]byte(name))
+	sum[6] = (sum[6] & 0x0f) | 0x50
+	sum[8] = (sum[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+func (k *Gateway) get(path string) (bool, []byte, error) {
+	status, respBody, err := k.send(http.MethodGet, path, nil, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return false, nil, err
+	}
+	return status == http.StatusOK, respBody, nil
+}
+
+// send sends the request to kong admin api, error is returned if the response status is not in the expected list
+func (k *Gateway) send(method string, path string, body interface{}, expectedStatus ...int) (int, []byte, error) {
+	if k.client == nil {
+		return 0, nil, fmt.Errorf("api gateway is not initialized")
+	}
+	var reqBody []byte
+	if body != nil {
+		var err error
+		reqBody, err = json.Marshal(body)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	ctx := k.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, k.baseURL+path, bytes.NewReader(reqBody))
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
+	httpReq.Header.Set(meputil.XRealIp, meputil.GetLocalIP())
+
+	httpResp, err := k.client.Do(httpReq)
+	if err != nil {
+		log.Errorf(nil, "Request to API gateway failed(method: %s, path: %s).", method, path)
+		return 0, nil, err
+	}
+	defer httpResp.Body.Close()
+	respBody, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	for _, status := range expectedStatus {
+		if httpResp.StatusCode == status {
+			return status, respBody, nil
+		}
+	}
+	log.Errorf(nil, "API gateway request failed(method: %s, path: %s, status: %d).", method, path,
+		httpResp.StatusCode)
+	return httpResp.StatusCode, nil, fmt.Errorf("api gateway returned status %d", httpResp.StatusCode)
+}
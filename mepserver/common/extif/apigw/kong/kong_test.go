@@ -0,0 +1,144 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package kong
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/extif/apigw"
+)
+
+const serviceName = "serviceOne4c3a2bf8df4144d4b6d0"
+
+func newTestGateway(handler http.HandlerFunc) (*Gateway, *httptest.Server) {
+	server := httptest.NewTLSServer(handler)
+	return &Gateway{baseURL: server.URL, client: server.Client()}, server
+}
+
+func TestKongRegistration(t *testing.T) {
+	requests := make([]string, 0)
+	gateway, server := newTestGateway(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch r.Method {
+		case http.MethodPut:
+			w.WriteHeader(http.StatusOK)
+		case http.MethodPost:
+			// plugin is already enabled
+			w.WriteHeader(http.StatusConflict)
+		case http.MethodDelete:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	service := apigw.Service{Name: serviceName, Url: "http://10.1.1.1:8080/"}
+	assert.NoError(t, apigw.RegisterServices(gateway, []apigw.Service{service}, true))
+	apigw.UnregisterServices(gateway, []apigw.Service{service})
+	assert.Equal(t, []string{
+		"PUT /services/" + serviceName,
+		"PUT /services/" + serviceName + "/routes/" + serviceName,
+		"POST /services/" + serviceName + "/plugins",
+		"POST /services/" + serviceName + "/plugins",
+		"DELETE /services/" + serviceName + "/routes/" + serviceName,
+		"DELETE /services/" + serviceName,
+	}, requests)
+}
+
+func TestKongRegistrationFailure(t *testing.T) {
+	gateway, server := newTestGateway(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	defer server.Close()
+
+	assert.Error(t, gateway.AddOrUpdateService(apigw.Service{Name: serviceName, Url: "http://10.1.1.1:8080/"}))
+	assert.Error(t, (&Gateway{}).AddOrUpdateService(apigw.Service{Name: serviceName}))
+}
+
+func TestKongServiceStatus(t *testing.T) {
+	gateway, server := newTestGateway(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/services/" + serviceName:
+			_, _ = w.Write([]byte(`{"name":"` + serviceName + `"}`))
+		case "/services/" + serviceName + "/plugins":
+			_, _ = w.Write([]byte(`{"data":[{"name":"jwt"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	defer server.Close()
+
+	status, err := gateway.GetServiceStatus(serviceName)
+	assert.NoError(t, err)
+	assert.Equal(t, apigw.ServiceStatus{ServiceExists: true, RouteExists: false, JwtEnabled: true}, *status)
+
+	status, err = gateway.GetServiceStatus("unknown")
+	assert.NoError(t, err)
+	assert.Equal(t, apigw.ServiceStatus{}, *status)
+}
+
+func TestKongTracingPlugin(t *testing.T) {
+	requests := make([]string, 0)
+	gateway, server := newTestGateway(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		w.WriteHeader(http.StatusOK)
+	})
+	defer server.Close()
+
+	assert.NoError(t, gateway.enableTracingPlugin("http://localhost:4318/v1/traces"))
+	assert.NoError(t, gateway.enableTracingPlugin("http://localhost:4318/v1/traces"))
+	// the plugin is updated in place
+	pluginPath := "PUT /plugins/" + namedPluginId(tracingPlugin)
+	assert.Equal(t, []string{pluginPath, pluginPath}, requests)
+}
+
+func TestKongRateLimit(t *testing.T) {
+	appInstanceId := "5abe4782-2c70-4e47-9a4e-0ee3a1a0fd1f"
+	requests := make([]string, 0)
+	bodies := make([]string, 0)
+	gateway, server := newTestGateway(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		bodies = append(bodies, string(body))
+		if r.URL.Path == "/consumers/"+appInstanceId {
+			_, _ = w.Write([]byte(`{"id":"7c4c9a5b-9f53-4d1e-8a8c-3a7f1c2d9e10","username":"` + appInstanceId + `"}`))
+			return
+		}
+		if r.Method == http.MethodDelete {
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+	defer server.Close()
+
+	limit := apigw.RateLimit{ConsumerAppInstanceId: appInstanceId, SerName: serviceName, Second: 10, Minute: 100}
+	assert.NoError(t, gateway.SetRateLimit(serviceName, &limit))
+	assert.Equal(t, []string{
+		"PUT /consumers/" + appInstanceId,
+		"PUT /routes/" + serviceName + "/plugins/" + rateLimitPluginId(serviceName, appInstanceId),
+	}, requests)
+	// the consumer is the one the app instance tokens are issued for by mep auth
+	assert.JSONEq(t, `{"username":"`+appInstanceId+`"}`, bodies[0])
+	assert.JSONEq(t, `{"name":"rate-limiting","consumer":{"id":"7c4c9a5b-9f53-4d1e-8a8c-3a7f1c2d9e10"},`+
+		`"config":{"second":10,"minute":100,"limit_by":"consumer","policy":"local"}}`, bodies[1])
+
+	assert.NoError(t, gateway.DeleteRateLimit(serviceName, appInstanceId))
+	assert.Equal(t, "DELETE /plugins/"+rateLimitPluginId(serviceName, appInstanceId), requests[2])
+}
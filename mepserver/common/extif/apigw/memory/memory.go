@@ -0,0 +1,156 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package memory implements an in-memory api gateway, used for testing
+package memory
+
+import (
+	"fmt"
+	"sync"
+
+	"mepserver/common/config"
+	"mepserver/common/extif/apigw"
+)
+
+// Gateway keeps the api gateway entities in memory, the operations on the services listed in FailServices fail
+type Gateway struct {
+	apigw.APIGateway
+	FailServices map[string]bool
+	services     map[string]apigw.Service
+	routes       map[string]bool
+	jwtPlugins   map[string]bool
+	rateLimits   map[string]apigw.RateLimit
+	mutex        sync.Mutex
+}
+
+// NewGateway creates an in-memory api gateway
+func NewGateway() *Gateway {
+	return &Gateway{
+		FailServices: make(map[string]bool),
+		services:     make(map[string]apigw.Service),
+		routes:       make(map[string]bool),
+		jwtPlugins:   make(map[string]bool),
+		rateLimits:   make(map[string]apigw.RateLimit),
+	}
+}
+
+// InitAPIGateway initialize the api gateway
+func (g *Gateway) InitAPIGateway(config *config.MepServerConfig) error {
+	return nil
+}
+
+// AddOrUpdateService add or update the service
+func (g *Gateway) AddOrUpdateService(service apigw.Service) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if g.FailServices[service.Name] {
+		return fmt.Errorf("service %s add failed", service.Name)
+	}
+	g.services[service.Name] = service
+	return nil
+}
+
+// DeleteService deletes the service along with its plugins
+func (g *Gateway) DeleteService(name string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.services, name)
+	delete(g.jwtPlugins, name)
+	for key, limit := range g.rateLimits {
+		if key == rateLimitKey(name, limit.ConsumerAppInstanceId) {
+			delete(g.rateLimits, key)
+		}
+	}
+	return nil
+}
+
+// AddOrUpdateRoute add or update the route of the service
+func (g *Gateway) AddOrUpdateRoute(service apigw.Service) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if _, ok := g.services[service.Name]; !ok {
+		return fmt.Errorf("service %s not found", service.Name)
+	}
+	g.routes[service.Name] = true
+	return nil
+}
+
+// DeleteRoute deletes the route of the service
+func (g *Gateway) DeleteRoute(name string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.routes, name)
+	return nil
+}
+
+// EnableJwtPlugin enables the jwt plugin on the service
+func (g *Gateway) EnableJwtPlugin(name string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if _, ok := g.services[name]; !ok {
+		return fmt.Errorf("service %s not found", name)
+	}
+	g.jwtPlugins[name] = true
+	return nil
+}
+
+// GetServiceStatus reads the presence of the service, route and the jwt plugin
+func (g *Gateway) GetServiceStatus(name string) (*apigw.ServiceStatus, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	_, exists := g.services[name]
+	return &apigw.ServiceStatus{ServiceExists: exists, RouteExists: g.routes[name], JwtEnabled: g.jwtPlugins[name]},
+		nil
+}
+
+// SetRateLimit add or update the rate limit of the consumer on the service
+func (g *Gateway) SetRateLimit(name string, limit *apigw.RateLimit) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if _, ok := g.services[name]; !ok {
+		return fmt.Errorf("service %s not found", name)
+	}
+	g.rateLimits[rateLimitKey(name, limit.ConsumerAppInstanceId)] = *limit
+	return nil
+}
+
+// DeleteRateLimit deletes the rate limit of the consumer on the service
+func (g *Gateway) DeleteRateLimit(name string, consumerAppInstanceId string) error {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.rateLimits, rateLimitKey(name, consumerAppInstanceId))
+	return nil
+}
+
+// GetService reads the service
+func (g *Gateway) GetService(name string) (apigw.Service, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	service, ok := g.services[name]
+	return service, ok
+}
+
+// GetRateLimit reads the rate limit of the consumer on the service
+func (g *Gateway) GetRateLimit(name string, consumerAppInstanceId string) (apigw.RateLimit, bool) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	limit, ok := g.rateLimits[rateLimitKey(name, consumerAppInstanceId)]
+	return limit, ok
+}
+
+func rateLimitKey(name string, consumerAppInstanceId string) string {
+	return name + "/" + consumerAppInstanceId
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package none for the api gateway implementation without any api gateway
+package none
+
+import (
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	"mepserver/common/config"
+	"mepserver/common/extif/apigw"
+)
+
+// NoneGateway implements the api gateway functionalities without any api gateway
+type NoneGateway struct {
+	apigw.APIGateway
+}
+
+// InitAPIGateway initialize the api gateway
+func (n *NoneGateway) InitAPIGateway(config *config.MepServerConfig) error {
+	return nil
+}
+
+// AddOrUpdateService add or update the service
+func (n *NoneGateway) AddOrUpdateService(service apigw.Service) error {
+	log.Infof("Added service(%s) successfully to api gateway.", service.Name)
+	return nil
+}
+
+// DeleteService deletes the service
+func (n *NoneGateway) DeleteService(name string) error {
+	log.Infof("Deleted service(%s) successfully from api gateway.", name)
+	return nil
+}
+
+// AddOrUpdateRoute add or update the route of the service
+func (n *NoneGateway) AddOrUpdateRoute(service apigw.Service) error {
+	log.Infof("Added route(%s) successfully to api gateway.", service.Name)
+	return nil
+}
+
+// DeleteRoute deletes the route of the service
+func (n *NoneGateway) DeleteRoute(name string) error {
+	log.Infof("Deleted route(%s) successfully from api gateway.", name)
+	return nil
+}
+
+// EnableJwtPlugin enables the jwt plugin on the service
+func (n *NoneGateway) EnableJwtPlugin(name string) error {
+	log.Infof("Enabled jwt plugin successfully on api gateway service(%s).", name)
+	return nil
+}
+
+// GetServiceStatus reports all the entities are present, hence nothing is reconciled
+func (n *NoneGateway) GetServiceStatus(name string) (*apigw.ServiceStatus, error) {
+	return &apigw.ServiceStatus{ServiceExists: true, RouteExists: true, JwtEnabled: true}, nil
+}
+
+// SetRateLimit add or update the rate limit of the consumer on the route of the service
+func (n *NoneGateway) SetRateLimit(name string, limit *apigw.RateLimit) error {
+	log.Infof("Set rate limit successfully on api gateway service(%s) for consumer %s.", name,
+		limit.ConsumerAppInstanceId)
+	return nil
+}
+
+// DeleteRateLimit deletes the rate limit of the consumer on the route of the service
+func (n *NoneGateway) DeleteRateLimit(name string, consumerAppInstanceId string) error {
+	log.Infof("Deleted rate limit successfully from api gateway service(%s) for consumer %s.", name,
+		consumerAppInstanceId)
+	return nil
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apigw
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	"mepserver/common/extif/backend"
+	meputil "mepserver/common/util"
+)
+
+// RateLimit holds the request rate limits(second, minute, hour) and the request quotas(day, month) of a consumer
+// application instance on a provider service, zero value means no limit on the window
+type RateLimit struct {
+	ConsumerAppInstanceId string `json:"consumerAppInstanceId"`
+	SerName               string `json:"serName" validate:"required,max=128,validateName"`
+	Second                int    `json:"second,omitempty" validate:"omitempty,min=1,max=1000000"`
+	Minute                int    `json:"minute,omitempty" validate:"omitempty,min=1,max=1000000"`
+	Hour                  int    `json:"hour,omitempty" validate:"omitempty,min=1,max=100000000"`
+	Day                   int    `json:"day,omitempty" validate:"omitempty,min=1,max=100000000"`
+	Month                 int    `json:"month,omitempty" validate:"omitempty,min=1,max=1000000000"`
+}
+
+// Validate checks at least one window is limited and the limits of the longer windows are not lower than the shorter
+// ones, the api gateway rejects such limits
+func (l *RateLimit) Validate() error {
+	if err := meputil.ValidateRestBody(l); err != nil {
+		return err
+	}
+	if err := meputil.ValidateUUID(l.ConsumerAppInstanceId); err != nil {
+		return fmt.Errorf("invalid consumer app instance id")
+	}
+	limits := []struct {
+		name  string
+		value int
+	}{{"second", l.Second}, {"minute", l.Minute}, {"hour", l.Hour}, {"day", l.Day}, {"month", l.Month}}
+	lower := ""
+	lowerValue := 0
+	for _, limit := range limits {
+		if limit.value == 0 {
+			continue
+		}
+		if limit.value < lowerValue {
+			return fmt.Errorf("%s limit is lower than the %s limit", limit.name, lower)
+		}
+		lower, lowerValue = limit.name, limit.value
+	}
+	if lowerValue == 0 {
+		return fmt.Errorf("no limit is set")
+	}
+	return nil
+}
+
+func rateLimitKey(consumerAppInstanceId string, serName string) string {
+	// the key is terminated to avoid the prefix match of the other service names
+	return meputil.RateLimitKeyPath + consumerAppInstanceId + "/" + serName + "/"
+}
+
+// SaveRateLimit persists the rate limit in the data-store
+func SaveRateLimit(limit *RateLimit) int {
+	limitBytes, err := json.Marshal(limit)
+	if err != nil {
+		log.Errorf(nil, "Rate limit encoding failed.")
+		return meputil.ParseInfoErr
+	}
+	return backend.PutRecord(rateLimitKey(limit.ConsumerAppInstanceId, limit.SerName), limitBytes)
+}
+
+// GetRateLimit reads the rate limit of the consumer on the service from the data-store
+func GetRateLimit(consumerAppInstanceId string, serName string) (*RateLimit, int) {
+	limitBytes, errCode := backend.GetRecord(rateLimitKey(consumerAppInstanceId, serName))
+	if errCode != 0 {
+		return nil, errCode
+	}
+	limit := &RateLimit{}
+	if err := json.Unmarshal(limitBytes, limit); err != nil {
+		log.Errorf(nil, "Rate limit decoding failed.")
+		return nil, meputil.ParseInfoErr
+	}
+	return limit, 0
+}
+
+// GetRateLimits reads all the rate limits from the data-store sorted by the consumer and the service, empty
+// consumer id reads the rate limits of all the consumers
+func GetRateLimits(consumerAppInstanceId string) ([]RateLimit, int) {
+	path := meputil.RateLimitKeyPath
+	if len(consumerAppInstanceId) != 0 {
+		path += consumerAppInstanceId + "/"
+	}
+	records, errCode := backend.GetRecordsWithCompleteKeyPath(path)
+	if errCode != 0 {
+		return nil, errCode
+	}
+	limits := make([]RateLimit, 0, len(records))
+	for _, record := range records {
+		limit := RateLimit{}
+		if err := json.Unmarshal(record, &limit); err != nil {
+			log.Warn("Could not read the rate limit properly from data-store.")
+			continue
+		}
+		limits = append(limits, limit)
+	}
+	sort.Slice(limits, func(i, j int) bool {
+		if limits[i].ConsumerAppInstanceId != limits[j].ConsumerAppInstanceId {
+			return limits[i].ConsumerAppInstanceId < limits[j].ConsumerAppInstanceId
+		}
+		return limits[i].SerName < limits[j].SerName
+	})
+	return limits, 0
+}
+
+// DeleteRateLimit removes the rate limit from the data-store
+func DeleteRateLimit(consumerAppInstanceId string, serName string) int {
+	return backend.DeleteRecord(rateLimitKey(consumerAppInstanceId, serName))
+}
+
+// ApplyRateLimit sets the rate limit on the api gateway services of all the instances of the provider service, the
+// limit is applied by the reconciler on the services registered later
+func ApplyRateLimit(gateway APIGateway, limit *RateLimit) error {
+	names, err := gatewayServiceNames(limit.SerName)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err = gateway.SetRateLimit(name, limit); err != nil {
+			log.Errorf(err, "Rate limit set failed on api gateway service(name: %s).", name)
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveRateLimit removes the rate limit from the api gateway services of all the instances of the provider service
+func RemoveRateLimit(gateway APIGateway, limit *RateLimit) error {
+	names, err := gatewayServiceNames(limit.SerName)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err = gateway.DeleteRateLimit(name, limit.ConsumerAppInstanceId); err != nil {
+			log.Errorf(err, "Rate limit delete failed on api gateway service(name: %s).", name)
+			return err
+		}
+	}
+	return nil
+}
+
+// gatewayServiceNames finds the api gateway service names of the instances of the provider service
+func gatewayServiceNames(serName string) ([]string, error) {
+	resp, err := meputil.FindInstanceByKey(url.Values{})
+	if err != nil {
+		if err.Error() == "null" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	names := make([]string, 0)
+	for _, instance := range resp.Instances {
+		if instance.Properties["serName"] != serName {
+			continue
+		}
+		for _, service := range ServicesFromProperties(instance.Properties) {
+			names = append(names, service.Name)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apigw_test
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/agiledragon/gomonkey"
+	"github.com/apache/servicecomb-service-center/server/core/proto"
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/extif/apigw"
+	"mepserver/common/extif/apigw/memory"
+	"mepserver/common/extif/backend"
+	meputil "mepserver/common/util"
+)
+
+const consumerAppInstanceId = "5abe4782-2c70-4e47-9a4e-0ee3a1a0fd1f"
+
+func patchProviderInstances() *gomonkey.Patches {
+	return gomonkey.ApplyFunc(meputil.FindInstanceByKey, func(result url.Values) (*proto.FindInstancesResponse,
+		error) {
+		return &proto.FindInstancesResponse{Instances: []*proto.MicroServiceInstance{
+			{Properties: map[string]string{"serName": "serviceOne", apigw.PropertyPrefix + serviceOne: upstream}},
+			{Properties: map[string]string{"serName": "serviceTwo", apigw.PropertyPrefix + serviceTwo: upstream}},
+		}}, nil
+	})
+}
+
+func TestRateLimitValidate(t *testing.T) {
+	limit := apigw.RateLimit{ConsumerAppInstanceId: consumerAppInstanceId, SerName: "serviceOne", Second: 10,
+		Minute: 100, Day: 10000}
+	assert.NoError(t, limit.Validate())
+
+	noLimit := apigw.RateLimit{ConsumerAppInstanceId: consumerAppInstanceId, SerName: "serviceOne"}
+	assert.Error(t, noLimit.Validate())
+
+	decreasing := apigw.RateLimit{ConsumerAppInstanceId: consumerAppInstanceId, SerName: "serviceOne", Second: 100,
+		Hour: 10}
+	assert.Error(t, decreasing.Validate())
+
+	invalidConsumer := apigw.RateLimit{ConsumerAppInstanceId: "consumer", SerName: "serviceOne", Second: 1}
+	assert.Error(t, invalidConsumer.Validate())
+
+	negative := apigw.RateLimit{ConsumerAppInstanceId: consumerAppInstanceId, SerName: "serviceOne", Minute: -1}
+	assert.Error(t, negative.Validate())
+}
+
+func TestApplyAndRemoveRateLimit(t *testing.T) {
+	patches := patchProviderInstances()
+	defer patches.Reset()
+
+	gateway := memory.NewGateway()
+	services := []apigw.Service{{Name: serviceOne, Url: upstream}, {Name: serviceTwo, Url: upstream}}
+	assert.NoError(t, apigw.RegisterServices(gateway, services, false))
+	limit := &apigw.RateLimit{ConsumerAppInstanceId: consumerAppInstanceId, SerName: "serviceOne", Minute: 60}
+	assert.NoError(t, apigw.ApplyRateLimit(gateway, limit))
+
+	applied, ok := gateway.GetRateLimit(serviceOne, consumerAppInstanceId)
+	assert.True(t, ok)
+	assert.Equal(t, *limit, applied)
+	_, ok = gateway.GetRateLimit(serviceTwo, consumerAppInstanceId)
+	assert.False(t, ok)
+
+	assert.NoError(t, apigw.RemoveRateLimit(gateway, limit))
+	_, ok = gateway.GetRateLimit(serviceOne, consumerAppInstanceId)
+	assert.False(t, ok)
+}
+
+func TestReconcileRateLimits(t *testing.T) {
+	patches := patchProviderInstances()
+	defer patches.Reset()
+	limit := apigw.RateLimit{ConsumerAppInstanceId: consumerAppInstanceId, SerName: "serviceTwo", Second: 5}
+	patches.ApplyFunc(backend.GetRecordsWithCompleteKeyPath, func(path string) (map[string][]byte, int) {
+		return map[string][]byte{
+			path + consumerAppInstanceId + "/serviceTwo/": []byte(`{"consumerAppInstanceId":"` +
+				consumerAppInstanceId + `","serName":"serviceTwo","second":5}`),
+		}, 0
+	})
+
+	gateway := memory.NewGateway()
+	assert.NoError(t, apigw.NewReconciler(gateway, 0).Reconcile())
+	applied, ok := gateway.GetRateLimit(serviceTwo, consumerAppInstanceId)
+	assert.True(t, ok)
+	assert.Equal(t, limit, applied)
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apigw
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	meputil "mepserver/common/util"
+)
+
+// Reconciler re-creates the api gateway services, routes and jwt plugins of the registered service instances which
+// are missing on the api gateway and re-applies the persisted rate limits
+type Reconciler struct {
+	gateway  APIGateway
+	interval time.Duration
+}
+
+// NewReconciler creates an api gateway reconciler
+func NewReconciler(gateway APIGateway, interval time.Duration) *Reconciler {
+	return &Reconciler{gateway: gateway, interval: interval}
+}
+
+// Start runs the reconciliation periodically till the process exits
+func (r *Reconciler) Start() {
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := r.Reconcile(); err != nil {
+				log.Warnf("API gateway reconciliation failed, will retry.")
+			}
+		}
+	}()
+}
+
+// Reconcile compares the api gateway services recorded on the service instances with the api gateway and re-creates
+// the missing entities, returns the error only if the service registry could not be read
+func (r *Reconciler) Reconcile() error {
+	resp, err := meputil.FindInstanceByKey(url.Values{})
+	if err != nil {
+		if err.Error() == "null" {
+			return nil
+		}
+		return err
+	}
+	serviceNames := make(map[string][]string)
+	for _, instance := range resp.Instances {
+		for _, service := range ServicesFromProperties(instance.Properties) {
+			r.reconcileService(service)
+			serName := instance.Properties["serName"]
+			serviceNames[serName] = append(serviceNames[serName], service.Name)
+		}
+	}
+	r.reconcileRateLimits(serviceNames)
+	return nil
+}
+
+// reconcileRateLimits re-applies the persisted rate limits, the update is idempotent on the api gateway
+func (r *Reconciler) reconcileRateLimits(serviceNames map[string][]string) {
+	limits, errCode := GetRateLimits("")
+	if errCode != 0 {
+		log.Errorf(nil, "Rate limits read failed on reconciliation(%d).", errCode)
+		return
+	}
+	for i := range limits {
+		for _, name := range serviceNames[limits[i].SerName] {
+			if err := r.gateway.SetRateLimit(name, &limits[i]); err != nil {
+				log.Errorf(err, "Rate limit re-apply failed on api gateway service(name: %s).", name)
+			}
+		}
+	}
+}
+
+func (r *Reconciler) reconcileService(service Service) {
+	status, err := r.gateway.GetServiceStatus(service.Name)
+	if err != nil {
+		log.Errorf(err, "API gateway status query failed for service(name: %s).", service.Name)
+		return
+	}
+	if !status.ServiceExists {
+		log.Warnf("API gateway service(name: %s) is missing, re-creating.", service.Name)
+		if err = r.gateway.AddOrUpdateService(service); err != nil {
+			log.Errorf(err, "API gateway service(name: %s) re-creation failed.", service.Name)
+			return
+		}
+	}
+	if !status.RouteExists {
+		log.Warnf("API gateway route(name: %s) is missing, re-creating.", service.Name)
+		if err = r.gateway.AddOrUpdateRoute(service); err != nil {
+			log.Errorf(err, "API gateway route(name: %s) re-creation failed.", service.Name)
+			return
+		}
+	}
+	if !status.JwtEnabled {
+		log.Warnf("API gateway jwt plugin of service(name: %s) is missing, re-creating.", service.Name)
+		if err = r.gateway.EnableJwtPlugin(service.Name); err != nil {
+			log.Errorf(err, "API gateway jwt plugin of service(name: %s) re-creation failed.", service.Name)
+		}
+	}
+}
@@ -29,13 +29,28 @@ import (
 	meputil "mepserver/common/util"
 )
 
+// dataStore returns the registry of the records, the service center registry by default
+var dataStore = backend.Registry
+
+// UseRegistry makes the record functions use the registry and returns the function restoring the previous one, the
+// tests use it to keep the records in memory
+func UseRegistry(r registry.Registry) func() {
+	previous := dataStore
+	dataStore = func() registry.Registry {
+		return r
+	}
+	return func() {
+		dataStore = previous
+	}
+}
+
 // GetRecord Read a single record from the data store on given path
 func GetRecord(path string) (record []byte, errorCode int) {
 	log.Debugf("DB: Read request: %v.", path)
 	opts := []registry.PluginOp{
 		registry.OpGet(registry.WithStrKey(path), registry.WithPrefix()),
 	}
-	resp, err := backend.Registry().TxnWithCmp(context.Background(), opts, nil, nil)
+	resp, err := dataStore().TxnWithCmp(context.Background(), opts, nil, nil)
 	if err != nil {
 		log.Errorf(nil, "Get single entry from data-store failed.")
 		return nil, meputil.OperateDataWithEtcdErr
@@ -53,7 +68,7 @@ func GetRecords(path string) (records map[string][]byte, errorCode int) {
 	opts := []registry.PluginOp{
 		registry.OpGet(registry.WithStrKey(path), registry.WithPrefix()),
 	}
-	resp, err := backend.Registry().TxnWithCmp(context.Background(), opts, nil, nil)
+	resp, err := dataStore().TxnWithCmp(context.Background(), opts, nil, nil)
 	if err != nil {
 		log.Errorf(nil, "Get entries from data-store failed.")
 		return nil, meputil.OperateDataWithEtcdErr
@@ -71,7 +86,7 @@ func GetRecordsWithCompleteKeyPath(path string) (records map[string][]byte, erro
 	opts := []registry.PluginOp{
 		registry.OpGet(registry.WithStrKey(path), registry.WithPrefix()),
 	}
-	resp, err := backend.Registry().TxnWithCmp(context.Background(), opts, nil, nil)
+	resp, err := dataStore().TxnWithCmp(context.Background(), opts, nil, nil)
 	if err != nil {
 		log.Errorf(nil, "Get entries with path from data-store failed.")
 		return nil, meputil.OperateDataWithEtcdErr
@@ -89,7 +104,7 @@ func PutRecord(path string, value []byte) int {
 	opts := []registry.PluginOp{
 		registry.OpPut(registry.WithStrKey(path), registry.WithValue(value)),
 	}
-	_, err := backend.Registry().TxnWithCmp(context.Background(), opts, nil, nil)
+	_, err := dataStore().TxnWithCmp(context.Background(), opts, nil, nil)
 	if err != nil {
 		log.Errorf(nil, "Write to data-store failed.")
 		return meputil.OperateDataWithEtcdErr
@@ -103,7 +118,7 @@ func DeleteRecord(path string) int {
 	opts := []registry.PluginOp{
 		registry.OpDel(registry.WithStrKey(path), registry.WithPrefix()),
 	}
-	_, err := backend.Registry().TxnWithCmp(context.Background(), opts, nil, nil)
+	_, err := dataStore().TxnWithCmp(context.Background(), opts, nil, nil)
 	if err != nil {
 		log.Errorf(nil, "Delete entries from data-store failed.")
 		return meputil.OperateDataWithEtcdErr
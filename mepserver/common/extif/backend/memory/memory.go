@@ -0,0 +1,76 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package memory implements an in-memory data-store registry, used for testing
+package memory
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/apache/servicecomb-service-center/server/plugin/pkg/registry"
+	"github.com/coreos/etcd/mvcc/mvccpb"
+)
+
+// Registry keeps the data-store records in memory, only the transactions of the record functions are supported
+type Registry struct {
+	registry.Registry
+	records map[string][]byte
+	mutex   sync.Mutex
+}
+
+// NewRegistry creates an in-memory data-store registry
+func NewRegistry() *Registry {
+	return &Registry{records: make(map[string][]byte)}
+}
+
+// TxnWithCmp runs the get, put and delete operations on the records, the compare operations are not supported
+func (r *Registry) TxnWithCmp(ctx context.Context, success []registry.PluginOp, cmp []registry.CompareOp,
+	fail []registry.PluginOp) (*registry.PluginResponse, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	resp := &registry.PluginResponse{Succeeded: true}
+	for _, op := range success {
+		switch op.Action {
+		case registry.Get:
+			for _, key := range r.match(op) {
+				resp.Kvs = append(resp.Kvs, &mvccpb.KeyValue{Key: []byte(key), Value: r.records[key]})
+			}
+		case registry.Put:
+			r.records[string(op.Key)] = append([]byte(nil), op.Value...)
+		case registry.Delete:
+			for _, key := range r.match(op) {
+				delete(r.records, key)
+			}
+		}
+	}
+	resp.Count = int64(len(resp.Kvs))
+	return resp, nil
+}
+
+// match returns the sorted keys of the records matched by the operation
+func (r *Registry) match(op registry.PluginOp) []string {
+	var keys []string
+	for key := range r.records {
+		if key == string(op.Key) || (op.Prefix && strings.HasPrefix(key, string(op.Key))) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -37,29 +37,31 @@ type DstInterface struct {
 	DstIPAddress  string     `json:"dstIpAddress" validate:"omitempty,ip"`
 }
 
-// TrafficFilter Keeps traffic filtering configurations
+// TrafficFilter Keeps traffic filtering configurations, addresses are ip or CIDR, ports are port or port range(low-high)
+// and protocols are protocol name or number. Use NormalizeTrafficRule to validate and normalize the values.
 type TrafficFilter struct {
 	SrcAddress       []string `json:"srcAddress" validate:"omitempty,dive,max=64"`
 	DstAddress       []string `json:"dstAddress" validate:"omitempty,dive,max=64"`
-	SrcPort          []string `json:"srcPort" validate:"omitempty,dive,number"`
-	DstPort          []string `json:"dstPort" validate:"omitempty,dive,number"`
+	SrcPort          []string `json:"srcPort" validate:"omitempty,dive,min=1,max=11"`
+	DstPort          []string `json:"dstPort" validate:"omitempty,dive,min=1,max=11"`
 	Protocol         []string `json:"protocol" validate:"omitempty,dive,min=1,max=8"`
 	Tag              []string `json:"tag" validate:"omitempty,dive,min=1,max=8"`
 	SrcTunnelAddress []string `json:"srcTunnelAddress" validate:"omitempty,dive,ip"`
 	TgtTunnelAddress []string `json:"tgtTunnelAddress" validate:"omitempty,dive,ip"`
-	SrcTunnelPort    []string `json:"srcTunnelPort" validate:"omitempty,dive,number"`
-	DstTunnelPort    []string `json:"dstTunnelPort" validate:"omitempty,dive,number"`
+	SrcTunnelPort    []string `json:"srcTunnelPort" validate:"omitempty,dive,min=1,max=11"`
+	DstTunnelPort    []string `json:"dstTunnelPort" validate:"omitempty,dive,min=1,max=11"`
 	QCI              int      `json:"qCI" validate:"omitempty"`
 	DSCP             int      `json:"dSCP" validate:"omitempty"`
 	TC               int      `json:"tC" validate:"omitempty"`
 }
 
-// TrafficRule Keeps traffic rule related configurations
+// TrafficRule Keeps traffic rule related configurations, a rule holds at most 16 traffic filters like the app traffic
+// rules of an app config
 type TrafficRule struct {
 	TrafficRuleID string          `json:"trafficRuleId" validate:"required,min=1,max=63"`
 	FilterType    string          `json:"filterType" validate:"required,oneof=FLOW PACKET"`
 	Priority      int             `json:"priority" validate:"required,min=1,max=255"`
-	TrafficFilter []TrafficFilter `json:"trafficFilter" validate:"required,dive,max=16"` //TBD to decide max number.
+	TrafficFilter []TrafficFilter `json:"trafficFilter" validate:"required,max=16,dive"`
 	Action        string          `json:"action" validate:"required,oneof=DROP FORWARD_DECAPSULATED FORWARD_AS_IS PASSTHROUGH DUPLICATE_DECAPSULATED DUPLICATE_AS_IS"`
 	DstInterface  []DstInterface  `json:"dstInterface" validate:"omitempty,dive,max=2"`
 	State         string          `json:"state" validate:"omitempty,oneof=ACTIVE INACTIVE"`
@@ -73,6 +75,9 @@ type DNSRule struct {
 	IPAddress     string `json:"ipAddress" validate:"required,ip"`
 	TTL           uint32 `json:"ttl" validate:"omitempty,min=0,max=4294967295"`
 	State         string `json:"state" validate:"omitempty,oneof=ACTIVE INACTIVE"`
+	Zone          string `json:"zone,omitempty" validate:"omitempty,max=253"`
+	// SourceFilter the rule is answered only to the clients in the source subnets, to all clients if empty
+	SourceFilter []string `json:"sourceFilter,omitempty" validate:"omitempty,max=16,dive,cidr"`
 }
 
 // ApplicationInfo Application info struct
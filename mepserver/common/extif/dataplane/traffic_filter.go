@@ -0,0 +1,207 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataplane
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	portRangeSeparator = "-"
+	maxPort            = 65535
+	maxProtocol        = 255
+	maxQCI             = 255
+	maxDSCP            = 63
+	maxTC              = 255
+)
+
+// protocolNumbers maps the well known protocol names to the IANA protocol numbers
+var protocolNumbers = map[string]int{
+	"ICMP":   1,
+	"TCP":    6,
+	"UDP":    17,
+	"ICMPV6": 58,
+	"SCTP":   132,
+}
+
+// FieldError holds the validation failure of a single traffic rule field
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+// ParseAddress parses an ip address or a CIDR block, a plain address is considered as a host network
+func ParseAddress(address string) (*net.IPNet, error) {
+	address = strings.TrimSpace(address)
+	if strings.Contains(address, "/") {
+		_, network, err := net.ParseCIDR(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR")
+		}
+		return network, nil
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid ip address")
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(net.IPv4len*8, net.IPv4len*8)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(net.IPv6len*8, net.IPv6len*8)}, nil
+}
+
+// ParsePortRange parses a port or a port range in low-high format
+func ParsePortRange(port string) (low int, high int, err error) {
+	bounds := strings.SplitN(strings.TrimSpace(port), portRangeSeparator, 2)
+	low, err = parsePort(bounds[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	high = low
+	if len(bounds) == 2 {
+		high, err = parsePort(bounds[1])
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if low > high {
+		return 0, 0, fmt.Errorf("port range lower bound is greater than upper bound")
+	}
+	return low, high, nil
+}
+
+func parsePort(port string) (int, error) {
+	value, err := strconv.Atoi(strings.TrimSpace(port))
+	if err != nil {
+		return 0, fmt.Errorf("port is not a number")
+	}
+	if value < 0 || value > maxPort {
+		return 0, fmt.Errorf("port out of range(0-%d)", maxPort)
+	}
+	return value, nil
+}
+
+// ParseProtocol parses a protocol name or number and returns the protocol number
+func ParseProtocol(protocol string) (int, error) {
+	protocol = strings.ToUpper(strings.TrimSpace(protocol))
+	if number, found := protocolNumbers[protocol]; found {
+		return number, nil
+	}
+	number, err := strconv.Atoi(protocol)
+	if err != nil {
+		return 0, fmt.Errorf("unknown protocol name")
+	}
+	if number < 0 || number > maxProtocol {
+		return 0, fmt.Errorf("protocol number out of range(0-%d)", maxProtocol)
+	}
+	return number, nil
+}
+
+// protocolName returns the well known name of the protocol number if exists
+func protocolName(number int) string {
+	for name, value := range protocolNumbers {
+		if value == number {
+			return name
+		}
+	}
+	return strconv.Itoa(number)
+}
+
+// NormalizeTrafficRule validates the traffic filters of the rule and rewrites them to the canonical form, addresses
+// to the network notation, port ranges without spaces and protocols to the well known names. All the field errors
+// are returned with the json path of the field.
+func NormalizeTrafficRule(rule *TrafficRule, prefix string) []FieldError {
+	var fieldErrors []FieldError
+	for i := range rule.TrafficFilter {
+		filterPrefix := fmt.Sprintf("%strafficFilter[%d].", prefix, i)
+		fieldErrors = append(fieldErrors, normalizeTrafficFilter(&rule.TrafficFilter[i], filterPrefix)...)
+	}
+	return fieldErrors
+}
+
+func normalizeTrafficFilter(filter *TrafficFilter, prefix string) []FieldError {
+	var fieldErrors []FieldError
+	fieldErrors = append(fieldErrors, normalizeValues(filter.SrcAddress, prefix+"srcAddress", normalizeAddress)...)
+	fieldErrors = append(fieldErrors, normalizeValues(filter.DstAddress, prefix+"dstAddress", normalizeAddress)...)
+	fieldErrors = append(fieldErrors, normalizeValues(filter.SrcPort, prefix+"srcPort", normalizePort)...)
+	fieldErrors = append(fieldErrors, normalizeValues(filter.DstPort, prefix+"dstPort", normalizePort)...)
+	fieldErrors = append(fieldErrors, normalizeValues(filter.Protocol, prefix+"protocol", normalizeProtocol)...)
+	fieldErrors = append(fieldErrors, normalizeValues(filter.SrcTunnelPort, prefix+"srcTunnelPort",
+		normalizePort)...)
+	fieldErrors = append(fieldErrors, normalizeValues(filter.DstTunnelPort, prefix+"dstTunnelPort",
+		normalizePort)...)
+	fieldErrors = append(fieldErrors, checkBounds(filter.QCI, maxQCI, prefix+"qCI")...)
+	fieldErrors = append(fieldErrors, checkBounds(filter.DSCP, maxDSCP, prefix+"dSCP")...)
+	fieldErrors = append(fieldErrors, checkBounds(filter.TC, maxTC, prefix+"tC")...)
+	return fieldErrors
+}
+
+func normalizeValues(values []string, field string, normalize func(value string) (string, error)) []FieldError {
+	var fieldErrors []FieldError
+	for i, value := range values {
+		normalized, err := normalize(value)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:  fmt.Sprintf("%s[%d]", field, i),
+				Reason: err.Error(),
+			})
+			continue
+		}
+		values[i] = normalized
+	}
+	return fieldErrors
+}
+
+func normalizeAddress(address string) (string, error) {
+	network, err := ParseAddress(address)
+	if err != nil {
+		return "", err
+	}
+	if ones, bits := network.Mask.Size(); ones == bits {
+		return network.IP.String(), nil
+	}
+	return network.String(), nil
+}
+
+func normalizePort(port string) (string, error) {
+	low, high, err := ParsePortRange(port)
+	if err != nil {
+		return "", err
+	}
+	if low == high {
+		return strconv.Itoa(low), nil
+	}
+	return strconv.Itoa(low) + portRangeSeparator + strconv.Itoa(high), nil
+}
+
+func normalizeProtocol(protocol string) (string, error) {
+	number, err := ParseProtocol(protocol)
+	if err != nil {
+		return "", err
+	}
+	return protocolName(number), nil
+}
+
+func checkBounds(value int, max int, field string) []FieldError {
+	if value < 0 || value > max {
+		return []FieldError{{Field: field, Reason: fmt.Sprintf("value out of range(0-%d)", max)}}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dataplane
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeTrafficRule(t *testing.T) {
+	rule := TrafficRule{
+		TrafficRuleID: "TR1",
+		TrafficFilter: []TrafficFilter{{
+			SrcAddress: []string{"192.168.1.10/24", " 10.0.0.1 "},
+			DstAddress: []string{"2001:db8::1/64", "::ffff:10.1.1.1"},
+			SrcPort:    []string{"1000 - 2000", "80-80"},
+			DstPort:    []string{"8080"},
+			Protocol:   []string{"tcp", "17", "50"},
+			QCI:        9,
+			DSCP:       46,
+			TC:         3,
+		}},
+	}
+
+	fieldErrors := NormalizeTrafficRule(&rule, "")
+	assert.Empty(t, fieldErrors)
+
+	filter := rule.TrafficFilter[0]
+	assert.Equal(t, []string{"192.168.1.0/24", "10.0.0.1"}, filter.SrcAddress)
+	assert.Equal(t, []string{"2001:db8::/64", "10.1.1.1"}, filter.DstAddress)
+	assert.Equal(t, []string{"1000-2000", "80"}, filter.SrcPort)
+	assert.Equal(t, []string{"8080"}, filter.DstPort)
+	assert.Equal(t, []string{"TCP", "UDP", "50"}, filter.Protocol)
+}
+
+func TestNormalizeTrafficRuleInvalidFields(t *testing.T) {
+	rule := TrafficRule{
+		TrafficRuleID: "TR1",
+		TrafficFilter: []TrafficFilter{{}, {
+			SrcAddress: []string{"192.168.1.300"},
+			DstAddress: []string{"10.0.0.0/33"},
+			SrcPort:    []string{"2000-1000"},
+			DstPort:    []string{"70000"},
+			Protocol:   []string{"xyz", "256"},
+			QCI:        256,
+			DSCP:       64,
+			TC:         -1,
+		}},
+	}
+
+	fieldErrors := NormalizeTrafficRule(&rule, "appTrafficRule[0].")
+	assert.Equal(t, []FieldError{
+		{Field: "appTrafficRule[0].trafficFilter[1].srcAddress[0]", Reason: "invalid ip address"},
+		{Field: "appTrafficRule[0].trafficFilter[1].dstAddress[0]", Reason: "invalid CIDR"},
+		{Field: "appTrafficRule[0].trafficFilter[1].srcPort[0]",
+			Reason: "port range lower bound is greater than upper bound"},
+		{Field: "appTrafficRule[0].trafficFilter[1].dstPort[0]", Reason: "port out of range(0-65535)"},
+		{Field: "appTrafficRule[0].trafficFilter[1].protocol[0]", Reason: "unknown protocol name"},
+		{Field: "appTrafficRule[0].trafficFilter[1].protocol[1]", Reason: "protocol number out of range(0-255)"},
+		{Field: "appTrafficRule[0].trafficFilter[1].qCI", Reason: "value out of range(0-255)"},
+		{Field: "appTrafficRule[0].trafficFilter[1].dSCP", Reason: "value out of range(0-63)"},
+		{Field: "appTrafficRule[0].trafficFilter[1].tC", Reason: "value out of range(0-255)"},
+	}, fieldErrors)
+}
@@ -17,6 +17,8 @@
 // Package dns defines dns client interfaces
 package dns
 
+import "context"
+
 // RuleEntry DNS rule record
 type RuleEntry struct {
 	DomainName    string `json:"domainName"`
@@ -45,3 +47,51 @@ type DNSAgent interface {
 	// DeleteResourceRecord  DNS entry
 	DeleteResourceRecord(host, rrType string) error
 }
+
+// ContextDNSAgent is implemented by the dns agents which send the requests in the context of the caller
+type ContextDNSAgent interface {
+	WithContext(ctx context.Context) DNSAgent
+}
+
+// WithContext returns the agent sending the requests in the context, the agent itself is returned if it does not
+// support the context
+func WithContext(agent DNSAgent, ctx context.Context) DNSAgent {
+	if contextAgent, ok := agent.(ContextDNSAgent); ok {
+		return contextAgent.WithContext(ctx)
+	}
+	return agent
+}
+
+// ZonedDNSAgent is implemented by the dns agents which manage the records in a specific zone
+type ZonedDNSAgent interface {
+	WithZone(zone string) DNSAgent
+}
+
+// WithZone returns the agent managing the records in the zone, the agent itself is returned if the zone is empty or
+// the agent does not support the zones
+func WithZone(agent DNSAgent, zone string) DNSAgent {
+	if zone == "" {
+		return agent
+	}
+	if zonedAgent, ok := agent.(ZonedDNSAgent); ok {
+		return zonedAgent.WithZone(zone)
+	}
+	return agent
+}
+
+// ViewDNSAgent is implemented by the dns agents which scope the records to the clients of the source subnets
+type ViewDNSAgent interface {
+	WithView(view string, subnets []string) DNSAgent
+}
+
+// WithSourceFilter returns the agent managing the records answered only to the clients of the source subnets, the
+// agent itself is returned if the source filter is empty or the agent does not support the views
+func WithSourceFilter(agent DNSAgent, subnets []string) DNSAgent {
+	if len(subnets) == 0 {
+		return agent
+	}
+	if viewAgent, ok := agent.(ViewDNSAgent); ok {
+		return viewAgent.WithView(ViewName(subnets), subnets)
+	}
+	return agent
+}
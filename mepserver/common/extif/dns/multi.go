@@ -0,0 +1,155 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	"mepserver/common/config"
+)
+
+// PartialFailureError is returned when the record is written to the primary dns server but failed on some of the
+// secondary dns servers
+type PartialFailureError struct {
+	FailedServers []string
+}
+
+// Error returns the error message listing the failed servers
+func (e *PartialFailureError) Error() string {
+	return fmt.Sprintf("dns record update failed on the secondary server(s) %s", strings.Join(e.FailedServers, ", "))
+}
+
+// MultiDNSAgent writes the records to the primary dns server and fans out the successful writes to the secondary
+// dns servers
+type MultiDNSAgent struct {
+	primary     DNSAgent
+	secondaries []DNSAgent
+	servers     []string
+}
+
+// NewDNSAgent creates the dns agent on the configured dns servers, the records are fanned out to the secondary
+// servers if any configured
+func NewDNSAgent(mepConfig *config.MepServerConfig) (DNSAgent, error) {
+	zone := DefaultZone(mepConfig)
+	primary := NewRestDNSAgent(&mepConfig.DNSAgent, mepConfig.DNSAgent.Endpoint.Address, zone)
+	if primary.ServerEndPoint == nil {
+		return nil, fmt.Errorf("invalid dns server endpoint")
+	}
+	if len(mepConfig.DNSAgent.SecondaryEndpoints) == 0 {
+		return primary, nil
+	}
+
+	agent := &MultiDNSAgent{primary: primary}
+	for _, endPoint := range mepConfig.DNSAgent.SecondaryEndpoints {
+		secondary := NewRestDNSAgent(&mepConfig.DNSAgent, endPoint.Address, zone)
+		if secondary.ServerEndPoint == nil {
+			return nil, fmt.Errorf("invalid secondary dns server endpoint")
+		}
+		agent.secondaries = append(agent.secondaries, secondary)
+		agent.servers = append(agent.servers, secondary.ServerEndPoint.Host)
+	}
+	return agent, nil
+}
+
+// WithContext returns a copy of the agent sending the requests to all the servers in the context
+func (m *MultiDNSAgent) WithContext(ctx context.Context) DNSAgent {
+	return m.each(func(agent DNSAgent) DNSAgent {
+		return WithContext(agent, ctx)
+	})
+}
+
+// WithZone returns a copy of the agent managing the records in the zone on all the servers
+func (m *MultiDNSAgent) WithZone(zone string) DNSAgent {
+	return m.each(func(agent DNSAgent) DNSAgent {
+		return WithZone(agent, zone)
+	})
+}
+
+// WithView returns a copy of the agent managing the records of the view on all the servers
+func (m *MultiDNSAgent) WithView(view string, subnets []string) DNSAgent {
+	return m.each(func(agent DNSAgent) DNSAgent {
+		if viewAgent, ok := agent.(ViewDNSAgent); ok {
+			return viewAgent.WithView(view, subnets)
+		}
+		return agent
+	})
+}
+
+func (m *MultiDNSAgent) each(wrap func(agent DNSAgent) DNSAgent) DNSAgent {
+	agent := &MultiDNSAgent{primary: wrap(m.primary), servers: m.servers}
+	for _, secondary := range m.secondaries {
+		agent.secondaries = append(agent.secondaries, wrap(secondary))
+	}
+	return agent
+}
+
+// fanOut runs the operation on the primary server and then on the secondary servers in parallel, the failure on
+// the primary is returned as is and the failures on the secondaries as a PartialFailureError
+func (m *MultiDNSAgent) fanOut(operation func(agent DNSAgent) error) error {
+	if err := operation(m.primary); err != nil {
+		return err
+	}
+
+	errs := make([]error, len(m.secondaries))
+	var wg sync.WaitGroup
+	for i, secondary := range m.secondaries {
+		wg.Add(1)
+		go func(i int, secondary DNSAgent) {
+			defer wg.Done()
+			errs[i] = operation(secondary)
+		}(i, secondary)
+	}
+	wg.Wait()
+
+	var failedServers []string
+	for i, err := range errs {
+		if err != nil {
+			log.Warnf("DNS record update failed on the secondary server %s.", m.servers[i])
+			failedServers = append(failedServers, m.servers[i])
+		}
+	}
+	if len(failedServers) != 0 {
+		return &PartialFailureError{FailedServers: failedServers}
+	}
+	return nil
+}
+
+// AddResourceRecord adds the record on all the dns servers
+func (m *MultiDNSAgent) AddResourceRecord(host, rrType, class string, pointTo []string, ttl uint32) error {
+	return m.fanOut(func(agent DNSAgent) error {
+		return agent.AddResourceRecord(host, rrType, class, pointTo, ttl)
+	})
+}
+
+// SetResourceRecord updates the record on all the dns servers
+func (m *MultiDNSAgent) SetResourceRecord(host, rrType, class string, pointTo []string, ttl uint32) error {
+	return m.fanOut(func(agent DNSAgent) error {
+		return agent.SetResourceRecord(host, rrType, class, pointTo, ttl)
+	})
+}
+
+// DeleteResourceRecord deletes the record from all the dns servers
+func (m *MultiDNSAgent) DeleteResourceRecord(host, rrType string) error {
+	return m.fanOut(func(agent DNSAgent) error {
+		return agent.DeleteResourceRecord(host, rrType)
+	})
+}
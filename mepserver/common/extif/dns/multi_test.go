@@ -0,0 +1,90 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dns
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/config"
+)
+
+func newTestServer(status int, zones *[]string) (*httptest.Server, config.EndPoint) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*zones = append(*zones, r.URL.Query().Get("zone"))
+		w.WriteHeader(status)
+	}))
+	u, _ := url.Parse(server.URL)
+	host, portStr, _ := net.SplitHostPort(u.Host)
+	port, _ := strconv.Atoi(portStr)
+	return server, config.EndPoint{Address: config.Address{Host: host, Port: port}}
+}
+
+func TestMultiDNSAgent(t *testing.T) {
+	var primaryZones, secondaryZones, failedZones []string
+	primary, primaryEndPoint := newTestServer(http.StatusOK, &primaryZones)
+	defer primary.Close()
+	secondary, secondaryEndPoint := newTestServer(http.StatusOK, &secondaryZones)
+	defer secondary.Close()
+	failed, failedEndPoint := newTestServer(http.StatusInternalServerError, &failedZones)
+	defer failed.Close()
+
+	mepConfig := &config.MepServerConfig{}
+	mepConfig.DNSAgent.Endpoint = primaryEndPoint
+	mepConfig.DNSAgent.SecondaryEndpoints = []config.EndPoint{secondaryEndPoint, failedEndPoint}
+	mepConfig.DNSAgent.Zone = "mep"
+	agent, err := NewDNSAgent(mepConfig)
+	assert.Nil(t, err)
+
+	err = agent.AddResourceRecord("app.mep", "A", "IN", []string{"10.10.0.1"}, 30)
+	var partialErr *PartialFailureError
+	if assert.True(t, errors.As(err, &partialErr)) {
+		assert.Equal(t, []string{failedEndPoint.Address.Host + ":" + strconv.Itoa(failedEndPoint.Address.Port)},
+			partialErr.FailedServers)
+	}
+	assert.Equal(t, []string{"mep."}, primaryZones)
+	assert.Equal(t, []string{"mep."}, secondaryZones)
+
+	err = WithZone(agent, "edge.mep").DeleteResourceRecord("app.edge.mep", "A")
+	assert.True(t, errors.As(err, &partialErr))
+	assert.Equal(t, []string{"mep.", "edge.mep."}, secondaryZones)
+
+	// The secondaries are not updated on primary failure
+	mepConfig.DNSAgent.Endpoint = failedEndPoint
+	mepConfig.DNSAgent.SecondaryEndpoints = []config.EndPoint{secondaryEndPoint}
+	agent, err = NewDNSAgent(mepConfig)
+	assert.Nil(t, err)
+	err = agent.SetResourceRecord("app.mep", "A", "IN", []string{"10.10.0.2"}, 30)
+	assert.NotNil(t, err)
+	assert.False(t, errors.As(err, &partialErr))
+	assert.Equal(t, 2, len(secondaryZones))
+}
+
+func TestInZone(t *testing.T) {
+	assert.True(t, InZone("app.mep", "."))
+	assert.True(t, InZone("App.Mep.", "mep"))
+	assert.True(t, InZone("mep", "mep."))
+	assert.False(t, InZone("appmep", "mep"))
+	assert.False(t, InZone("app.mep", "edge.mep"))
+}
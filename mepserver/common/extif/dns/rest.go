@@ -19,21 +19,23 @@ package dns
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"mepserver/common/config"
 	"net/http"
 	"net/url"
-	"os"
-	"strconv"
 	"strings"
 
 	"github.com/apache/servicecomb-service-center/pkg/log"
 
 	meputil "mepserver/common/util"
+	"tracing"
 )
 
-const ServerURLFormat = "http://%s:%d/mep/dns_server_mgmt/v1/"
+const ServerURLFormat = "%s://%s:%d/mep/dns_server_mgmt/v1/"
 
 // ResourceRecord represents the dns resource record
 type ResourceRecord struct {
@@ -42,6 +44,13 @@ type ResourceRecord struct {
 	Class string   `json:"class"`
 	TTL   uint32   `json:"ttl"`
 	RData []string `json:"rData"`
+	View  string   `json:"view,omitempty"`
+}
+
+// View represents the dns view answering the records only to the clients of the source subnets
+type View struct {
+	Name    string   `json:"name"`
+	Subnets []string `json:"subnets"`
 }
 
 // ZoneEntry represents the dns zone
@@ -55,42 +64,54 @@ type RestDNSAgent struct {
 	DNSAgent
 	ServerEndPoint *url.URL `json:"serverEndPoint"`
 	client         http.Client
+	ctx            context.Context
+	zone           string
+	view           View
+	token          string
 }
 
-// NewRestDNSAgent creates and initialize a dns agent
-func NewRestDNSAgent(*config.MepServerConfig) *RestDNSAgent {
+// NewRestDNSAgent creates and initialize a dns agent of the zone on the dns server address, the default dns host and
+// management port are used for the address fields not configured
+func NewRestDNSAgent(agentConfig *config.DNSAgent, address config.Address, zone string) *RestDNSAgent {
 	log.Info("New DNS agent initialization.")
-	agent := RestDNSAgent{}
-	err := agent.initDnsAgent()
+	agent := RestDNSAgent{client: http.Client{Transport: tracing.NewTransport(nil)}, zone: zone}
+	err := agent.initDnsAgent(agentConfig, address)
 	if err != nil {
 		return &agent
 	}
 	return &agent
 }
 
-func (d *RestDNSAgent) initDnsAgent() error {
+func (d *RestDNSAgent) initDnsAgent(agentConfig *config.DNSAgent, address config.Address) error {
+	var scheme = "http"
+	if agentConfig.TLS {
+		tlsCfg, err := dnsTLSConfig()
+		if err != nil {
+			log.Errorf(nil, "DNS server tls configuration failed.")
+			return err
+		}
+		d.client.Transport = tracing.NewTransport(&http.Transport{TLSClientConfig: tlsCfg})
+		scheme = "https"
+	}
+	if len(agentConfig.TokenFile) != 0 {
+		token, err := ioutil.ReadFile(agentConfig.TokenFile)
+		if err != nil {
+			log.Errorf(nil, "Could not read the DNS server token file.")
+			return err
+		}
+		d.token = strings.TrimSpace(string(token))
+	}
+
 	var remoteServerHost = meputil.DefaultDnsHost
 	var remoteServerPort = meputil.DefaultDnsManagementPort
-
-	host := os.Getenv("DNS_SERVER_HOST")
-	if len(host) > meputil.MaxFQDNLength {
-		log.Warn("invalid dns remote server host configured, reset back to default")
-	} else {
-		remoteServerHost = host
-	}
-
-	port := os.Getenv("DNS_SERVER_PORT")
-	if len(port) > meputil.MaxPortLength {
-		log.Warn("Invalid dns remote server port configured, reset back to default.")
-	} else if num, err := strconv.Atoi(port); err == nil {
-		if num <= 0 || num > meputil.MaxPortNumber {
-			log.Warn("Invalid dns remote server port range, reset back to default.")
-		} else {
-			remoteServerPort = num
-		}
+	if len(address.Host) != 0 {
+		remoteServerHost = address.Host
+	}
+	if address.Port != 0 {
+		remoteServerPort = address.Port
 	}
 
-	u, err := url.Parse(fmt.Sprintf(ServerURLFormat, remoteServerHost, remoteServerPort))
+	u, err := url.Parse(fmt.Sprintf(ServerURLFormat, scheme, remoteServerHost, remoteServerPort))
 	if err != nil {
 		log.Errorf(nil, "Could not parse the DNS server endpoint.")
 		return err
@@ -99,6 +120,139 @@ func (d *RestDNSAgent) initDnsAgent() error {
 	return nil
 }
 
+// dnsTLSConfig verifies the dns server with the dns_cacert of the app config, the dns_client_cert and dns_client_key
+// of the app config are presented to the dns servers requiring the mutual tls
+func dnsTLSConfig() (*tls.Config, error) {
+	tlsCfg, err := meputil.TLSConfig(meputil.DnsCaCertName, false)
+	if err != nil {
+		return nil, err
+	}
+	appConfig, err := meputil.GetAppConfig()
+	if err != nil {
+		return nil, err
+	}
+	certFile := appConfig[meputil.DnsClientCertName]
+	if len(certFile) == 0 {
+		return tlsCfg, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, appConfig[meputil.DnsClientKeyName])
+	if err != nil {
+		log.Errorf(nil, "Unable to load the DNS client certificate.")
+		return nil, err
+	}
+	tlsCfg.Certificates = []tls.Certificate{cert}
+	return tlsCfg, nil
+}
+
+// setHeaders sets the json content type and the bearer token of the dns server
+func (d *RestDNSAgent) setHeaders(httpReq *http.Request) {
+	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if len(d.token) != 0 {
+		httpReq.Header.Set("Authorization", "Bearer "+d.token)
+	}
+}
+
+// WithContext returns a copy of the agent sending the requests in the context, the requests are traced as the
+// children of the span in the context
+func (d *RestDNSAgent) WithContext(ctx context.Context) DNSAgent {
+	agent := *d
+	agent.ctx = ctx
+	return &agent
+}
+
+// WithZone returns a copy of the agent managing the records in the zone
+func (d *RestDNSAgent) WithZone(zone string) DNSAgent {
+	agent := *d
+	agent.zone = FQDN(zone)
+	return &agent
+}
+
+// WithView returns a copy of the agent managing the records answered only to the clients of the view subnets, the
+// view is created on the dns server with the first record and deleted with the last record
+func (d *RestDNSAgent) WithView(view string, subnets []string) DNSAgent {
+	agent := *d
+	agent.view = View{Name: view, Subnets: subnets}
+	return &agent
+}
+
+func (d *RestDNSAgent) zoneQuery() string {
+	query := "?zone=" + RootZone
+	if d.zone != "" {
+		query = "?zone=" + url.QueryEscape(d.zone)
+	}
+	if d.view.Name != "" {
+		query += "&view=" + url.QueryEscape(d.view.Name)
+	}
+	return query
+}
+
+// ensureView creates or updates the view of the agent on the dns server
+func (d *RestDNSAgent) ensureView() error {
+	if d.view.Name == "" {
+		return nil
+	}
+	viewJSON, err := json.Marshal(d.view)
+	if err != nil {
+		log.Errorf(nil, "Marshal DNS view failed.")
+		return err
+	}
+
+	httpResp, err := d.sendView(http.MethodPut, d.BuildDNSEndpoint("views", d.view.Name), viewJSON)
+	if err == nil && httpResp.StatusCode == http.StatusNotFound {
+		httpResp, err = d.sendView(http.MethodPost, d.BuildDNSEndpoint("views"), viewJSON)
+	}
+	if err != nil {
+		log.Errorf(nil, "Request to DNS server failed in view update.")
+		return err
+	}
+	if !meputil.IsHttpStatusOK(httpResp.StatusCode) {
+		log.Errorf(nil, "DNS view update failed on server(%d: %s).", httpResp.StatusCode, httpResp.Status)
+		return fmt.Errorf("view request to dns server failed")
+	}
+	return nil
+}
+
+// releaseView deletes the view of the agent from the dns server once no record references it, the views still used by
+// the other records are kept by the dns server
+func (d *RestDNSAgent) releaseView() {
+	if d.view.Name == "" {
+		return
+	}
+	httpResp, err := d.sendView(http.MethodDelete, d.BuildDNSEndpoint("views", d.view.Name)+"?unused=true", nil)
+	if err != nil {
+		log.Warnf("Request to DNS server failed in view(%s) release.", d.view.Name)
+		return
+	}
+	if !meputil.IsHttpStatusOK(httpResp.StatusCode) && httpResp.StatusCode != http.StatusNotFound &&
+		httpResp.StatusCode != http.StatusConflict {
+		log.Warnf("DNS view(%s) release failed on server(%d: %s).", d.view.Name, httpResp.StatusCode,
+			httpResp.Status)
+	}
+}
+
+func (d *RestDNSAgent) sendView(method, endPoint string, viewJSON []byte) (*http.Response, error) {
+	httpReq, err := http.NewRequestWithContext(d.context(), method, endPoint, bytes.NewBuffer(viewJSON))
+	if err != nil {
+		log.Errorf(nil, "Http request creation for DNS view failed.")
+		return nil, err
+	}
+	d.setHeaders(httpReq)
+
+	httpResp, err := d.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	_ = httpResp.Body.Close()
+	return httpResp, nil
+}
+
+func (d *RestDNSAgent) context() context.Context {
+	if d.ctx == nil {
+		return context.Background()
+	}
+	return d.ctx
+}
+
 // BuildDNSEndpoint generates the dns server endpoint
 func (d *RestDNSAgent) BuildDNSEndpoint(paths ...string) string {
 	return meputil.JoinURL(d.ServerEndPoint.String(), paths...)
@@ -116,20 +270,24 @@ func (d *RestDNSAgent) AddResourceRecord(host, rrType, class string, pointTo []s
 		hostName = host + "."
 	}
 
-	rr := ResourceRecord{Name: hostName, Type: rrType, Class: class, TTL: ttl, RData: pointTo}
+	if err := d.ensureView(); err != nil {
+		return err
+	}
+
+	rr := ResourceRecord{Name: hostName, Type: rrType, Class: class, TTL: ttl, RData: pointTo, View: d.view.Name}
 	rrJSON, err := json.Marshal(rr)
 	if err != nil {
 		log.Errorf(nil, "Marshal DNS info failed.")
 		return err
 	}
 
-	httpReq, err := http.NewRequest(http.MethodPost, d.BuildDNSEndpoint("rrecord")+"?zone=.",
+	httpReq, err := http.NewRequestWithContext(d.context(), http.MethodPost, d.BuildDNSEndpoint("rrecord")+d.zoneQuery(),
 		bytes.NewBuffer(rrJSON))
 	if err != nil {
 		log.Errorf(nil, "Http request creation for DNS add failed.")
 		return err
 	}
-	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	d.setHeaders(httpReq)
 
 	httpResp, err := d.client.Do(httpReq)
 	if err != nil {
@@ -155,20 +313,24 @@ func (d *RestDNSAgent) SetResourceRecord(host, rrType, class string, pointTo []s
 		hostName = host + "."
 	}
 
-	rr := ResourceRecord{Name: hostName, Type: rrType, Class: class, TTL: ttl, RData: pointTo}
+	if err := d.ensureView(); err != nil {
+		return err
+	}
+
+	rr := ResourceRecord{Name: hostName, Type: rrType, Class: class, TTL: ttl, RData: pointTo, View: d.view.Name}
 	rrJSON, err := json.Marshal(rr)
 	if err != nil {
 		log.Errorf(nil, "Marshal DNS info failed.")
 		return err
 	}
 
-	httpReq, err := http.NewRequest(http.MethodPut, d.BuildDNSEndpoint("rrecord", hostName, rrType)+"?zone=.",
+	httpReq, err := http.NewRequestWithContext(d.context(), http.MethodPut, d.BuildDNSEndpoint("rrecord", hostName, rrType)+d.zoneQuery(),
 		bytes.NewBuffer(rrJSON))
 	if err != nil {
 		log.Errorf(nil, "Http request creation for DNS update failed.")
 		return err
 	}
-	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	d.setHeaders(httpReq)
 
 	httpResp, err := d.client.Do(httpReq)
 	if err != nil {
@@ -194,13 +356,13 @@ func (d *RestDNSAgent) DeleteResourceRecord(host, rrtype string) error {
 		hostName = host + "."
 	}
 
-	httpReq, err := http.NewRequest(http.MethodDelete, d.BuildDNSEndpoint("rrecord", hostName, rrtype)+"?zone=.",
+	httpReq, err := http.NewRequestWithContext(d.context(), http.MethodDelete, d.BuildDNSEndpoint("rrecord", hostName, rrtype)+d.zoneQuery(),
 		bytes.NewBuffer([]byte("{}")))
 	if err != nil {
 		log.Errorf(nil, "Http request creation for DNS delete failed.")
 		return err
 	}
-	httpReq.Header.Set("Content-Type", "application/json; charset=utf-8")
+	d.setHeaders(httpReq)
 
 	httpResp, err := d.client.Do(httpReq)
 	if err != nil {
@@ -211,5 +373,6 @@ func (d *RestDNSAgent) DeleteResourceRecord(host, rrtype string) error {
 		log.Errorf(nil, "DNS rule delete failed on server(%d: %s).", httpResp.StatusCode, httpResp.Status)
 		return fmt.Errorf("delete request to dns server failed")
 	}
+	d.releaseView()
 	return nil
 }
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dns
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/agiledragon/gomonkey"
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/config"
+	meputil "mepserver/common/util"
+)
+
+func serverAddress(server *httptest.Server) config.Address {
+	u, _ := url.Parse(server.URL)
+	host, portStr, _ := net.SplitHostPort(u.Host)
+	port, _ := strconv.Atoi(portStr)
+	return config.Address{Host: host, Port: port}
+}
+
+func TestRestDNSAgentSecured(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnstoken")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	tokenFile := filepath.Join(dir, "token")
+	assert.Nil(t, ioutil.WriteFile(tokenFile, []byte("0123456789abcdef\n"), 0600))
+
+	var authorization string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// The dns server certificate is verified with the ca of the app config
+	rootCAs := x509.NewCertPool()
+	rootCAs.AddCert(server.Certificate())
+	patch1 := gomonkey.ApplyFunc(meputil.TLSConfig, func(crtName string, skipInsecureVerify bool) (*tls.Config,
+		error) {
+		assert.Equal(t, meputil.DnsCaCertName, crtName)
+		return &tls.Config{RootCAs: rootCAs, MinVersion: tls.VersionTLS12}, nil
+	})
+	defer patch1.Reset()
+	patch2 := gomonkey.ApplyFunc(meputil.GetAppConfig, func() (meputil.AppConfigProperties, error) {
+		return meputil.AppConfigProperties{}, nil
+	})
+	defer patch2.Reset()
+
+	agent := NewRestDNSAgent(&config.DNSAgent{TLS: true, TokenFile: tokenFile}, serverAddress(server), "mep.")
+	assert.Equal(t, "https", agent.ServerEndPoint.Scheme)
+	assert.Nil(t, agent.AddResourceRecord("app.mep", "A", "IN", []string{"10.10.0.1"}, 30))
+	assert.Equal(t, "Bearer 0123456789abcdef", authorization)
+
+	// The agent without the token file is not usable
+	agent = NewRestDNSAgent(&config.DNSAgent{TLS: true, TokenFile: filepath.Join(dir, "missing")},
+		serverAddress(server), "mep.")
+	assert.Nil(t, agent.ServerEndPoint)
+
+	// The plain http agent fails on the https dns server
+	agent = NewRestDNSAgent(&config.DNSAgent{}, serverAddress(server), "mep.")
+	assert.Equal(t, "http", agent.ServerEndPoint.Scheme)
+	assert.NotNil(t, agent.AddResourceRecord("app.mep", "A", "IN", []string{"10.10.0.1"}, 30))
+}
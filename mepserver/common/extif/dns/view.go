@@ -0,0 +1,50 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dns
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"sort"
+	"strings"
+)
+
+// viewNamePrefix prefixes the names of the views created for the source filters of the dns rules
+const viewNamePrefix = "mep-"
+
+// ViewName returns the name of the view of the source subnets, the rules with the same subnets share the view
+func ViewName(subnets []string) string {
+	if len(subnets) == 0 {
+		return ""
+	}
+	unique := make(map[string]bool, len(subnets))
+	canonical := make([]string, 0, len(subnets))
+	for _, subnet := range subnets {
+		subnet = strings.TrimSpace(subnet)
+		if _, ipNet, err := net.ParseCIDR(subnet); err == nil {
+			subnet = ipNet.String()
+		}
+		if !unique[subnet] {
+			unique[subnet] = true
+			canonical = append(canonical, subnet)
+		}
+	}
+	sort.Strings(canonical)
+	sum := sha256.Sum256([]byte(strings.Join(canonical, ",")))
+	return viewNamePrefix + hex.EncodeToString(sum[:8])
+}
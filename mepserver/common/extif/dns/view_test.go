@@ -0,0 +1,127 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dns
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/config"
+)
+
+func TestViewName(t *testing.T) {
+	assert.Equal(t, "", ViewName(nil))
+	name := ViewName([]string{"10.10.0.0/16", "192.168.1.1/24"})
+	assert.Equal(t, viewNamePrefix, name[:len(viewNamePrefix)])
+	assert.Equal(t, name, ViewName([]string{" 192.168.1.0/24", "10.10.1.1/16", "10.10.0.0/16"}))
+	assert.NotEqual(t, name, ViewName([]string{"10.10.0.0/16"}))
+}
+
+func TestRestDNSAgentWithSourceFilter(t *testing.T) {
+	var lock sync.Mutex
+	var requests []string
+	views := map[string]View{}
+	viewRecords := map[string]int{}
+	var records []ResourceRecord
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		defer lock.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		body, _ := ioutil.ReadAll(r.Body)
+		switch {
+		case strings.Contains(r.URL.Path, "/views") && r.Method == http.MethodDelete:
+			name := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+			if viewRecords[name] != 0 {
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			delete(views, name)
+		case strings.Contains(r.URL.Path, "/views"):
+			view := View{}
+			_ = json.Unmarshal(body, &view)
+			if _, found := views[view.Name]; !found && r.Method == http.MethodPut {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			views[view.Name] = view
+		case r.Method == http.MethodDelete:
+			viewRecords[r.URL.Query().Get("view")]--
+		default:
+			rr := ResourceRecord{}
+			_ = json.Unmarshal(body, &rr)
+			records = append(records, rr)
+			viewRecords[rr.View]++
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	host, portStr, _ := net.SplitHostPort(u.Host)
+	port, _ := strconv.Atoi(portStr)
+	agent := NewRestDNSAgent(&config.DNSAgent{}, config.Address{Host: host, Port: port}, "mep.")
+
+	subnets := []string{"10.10.0.0/16"}
+	viewName := ViewName(subnets)
+	err := WithSourceFilter(agent, subnets).AddResourceRecord("app.mep", "A", "IN", []string{"10.10.0.1"}, 30)
+	assert.Nil(t, err)
+	assert.Equal(t, View{Name: viewName, Subnets: subnets}, views[viewName])
+	if assert.Equal(t, 1, len(records)) {
+		assert.Equal(t, viewName, records[0].View)
+	}
+	err = WithSourceFilter(agent, subnets).AddResourceRecord("db.mep", "A", "IN", []string{"10.10.0.3"}, 30)
+	assert.Nil(t, err)
+
+	// The view is kept while the other records reference it and deleted with the last record
+	err = WithSourceFilter(agent, subnets).DeleteResourceRecord("app.mep", "A")
+	assert.Nil(t, err)
+	assert.Contains(t, views, viewName)
+	err = WithSourceFilter(agent, subnets).DeleteResourceRecord("db.mep", "A")
+	assert.Nil(t, err)
+	assert.NotContains(t, views, viewName)
+	assert.Equal(t, []string{
+		"PUT /mep/dns_server_mgmt/v1/views/" + viewName + "?",
+		"POST /mep/dns_server_mgmt/v1/views?",
+		"POST /mep/dns_server_mgmt/v1/rrecord?zone=mep.&view=" + viewName,
+		"PUT /mep/dns_server_mgmt/v1/views/" + viewName + "?",
+		"POST /mep/dns_server_mgmt/v1/rrecord?zone=mep.&view=" + viewName,
+		"DELETE /mep/dns_server_mgmt/v1/rrecord/app.mep./A?zone=mep.&view=" + viewName,
+		"DELETE /mep/dns_server_mgmt/v1/views/" + viewName + "?unused=true",
+		"DELETE /mep/dns_server_mgmt/v1/rrecord/db.mep./A?zone=mep.&view=" + viewName,
+		"DELETE /mep/dns_server_mgmt/v1/views/" + viewName + "?unused=true",
+	}, requests)
+
+	// The records without source filter are not scoped to a view
+	err = WithSourceFilter(agent, nil).AddResourceRecord("web.mep", "A", "IN", []string{"10.10.0.2"}, 30)
+	assert.Nil(t, err)
+	if assert.Equal(t, 3, len(records)) {
+		assert.Equal(t, "", records[2].View)
+	}
+	err = WithSourceFilter(agent, nil).DeleteResourceRecord("web.mep", "A")
+	assert.Nil(t, err)
+	assert.Equal(t, 11, len(requests))
+}
@@ -0,0 +1,53 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package dns
+
+import (
+	"strings"
+
+	"mepserver/common/config"
+)
+
+// RootZone is the zone of the dns records without a configured zone
+const RootZone = "."
+
+// FQDN returns the lower case fully qualified form of the domain name
+func FQDN(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+// DefaultZone returns the configured zone of the dns rules without a zone, the root zone if not configured
+func DefaultZone(mepConfig *config.MepServerConfig) string {
+	if mepConfig == nil || mepConfig.DNSAgent.Zone == "" {
+		return RootZone
+	}
+	return FQDN(mepConfig.DNSAgent.Zone)
+}
+
+// InZone checks the domain name belongs to the zone
+func InZone(domainName, zone string) bool {
+	zone = FQDN(zone)
+	if zone == RootZone {
+		return true
+	}
+	domainName = FQDN(domainName)
+	return domainName == zone || strings.HasSuffix(domainName, "."+zone)
+}
@@ -0,0 +1,285 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httplog
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Analytics granularity options
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+
+	AnonymousConsumer = "anonymous"
+
+	maxHourBuckets = 31 * 24
+	maxDayBuckets  = 366
+)
+
+// maxAnalyzedLogs the number of the http logs analyzed in a window, the later logs are skipped and the analytics are
+// reported as truncated
+var maxAnalyzedLogs = 1000000
+
+// Window analytics time window, split to the buckets of the granularity. The oldest retained time is set for the
+// stores keeping only the latest http logs, the statistics before it are incomplete. The truncated window has more
+// http logs than analyzed, the statistics cover the first ones only
+type Window struct {
+	Start          time.Time  `json:"start"`
+	End            time.Time  `json:"end"`
+	Granularity    string     `json:"granularity"`
+	OldestRetained *time.Time `json:"oldestRetained,omitempty"`
+	Truncated      bool       `json:"truncated,omitempty"`
+}
+
+// NewWindow aligns the window start to the granularity and checks the number of buckets, zero times select the
+// last day for the hour granularity and the last week for the day granularity
+func NewWindow(start time.Time, end time.Time, granularity string) (*Window, error) {
+	var step time.Duration
+	var maxBuckets int
+	switch granularity {
+	case GranularityHour:
+		step, maxBuckets = time.Hour, maxHourBuckets
+	case "", GranularityDay:
+		granularity = GranularityDay
+		step, maxBuckets = 24*time.Hour, maxDayBuckets
+	default:
+		return nil, fmt.Errorf("granularity must be hour or day")
+	}
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if start.IsZero() {
+		start = end.Add(-time.Duration(defaultBuckets(granularity)) * step)
+	}
+	end = end.UTC()
+	start = start.UTC().Truncate(step)
+	if !start.Before(end) {
+		return nil, fmt.Errorf("start must be before end")
+	}
+	window := &Window{Start: start, End: end, Granularity: granularity}
+	if window.buckets() > maxBuckets {
+		return nil, fmt.Errorf("window exceeds %d buckets of %s", maxBuckets, granularity)
+	}
+	return window, nil
+}
+
+func defaultBuckets(granularity string) int {
+	if granularity == GranularityHour {
+		return 24
+	}
+	return 7
+}
+
+func (w *Window) step() time.Duration {
+	if w.Granularity == GranularityHour {
+		return time.Hour
+	}
+	return 24 * time.Hour
+}
+
+func (w *Window) buckets() int {
+	step := w.step()
+	return int((w.End.Sub(w.Start) + step - 1) / step)
+}
+
+// LatencyStats request latency percentiles in milliseconds
+type LatencyStats struct {
+	P50 int `json:"p50"`
+	P95 int `json:"p95"`
+	P99 int `json:"p99"`
+}
+
+// CallStats call count, error rates and latencies of a set of calls
+type CallStats struct {
+	Calls           int          `json:"calls"`
+	ClientErrors    int          `json:"clientErrors"`
+	ServerErrors    int          `json:"serverErrors"`
+	ClientErrorRate float64      `json:"clientErrorRate"`
+	ServerErrorRate float64      `json:"serverErrorRate"`
+	Latency         LatencyStats `json:"latency"`
+}
+
+// UsageBucket call statistics of a bucket of the window
+type UsageBucket struct {
+	Start time.Time `json:"start"`
+	CallStats
+}
+
+// Usage call statistics of the window, in total and by bucket
+type Usage struct {
+	Window
+	Total   CallStats     `json:"total"`
+	Buckets []UsageBucket `json:"buckets"`
+}
+
+// ConsumerUsage call statistics of a consumer application
+type ConsumerUsage struct {
+	Consumer string `json:"consumer"`
+	CallStats
+}
+
+// CallMatrixEntry call statistics of a consumer application on a provider service
+type CallMatrixEntry struct {
+	Consumer string `json:"consumer"`
+	Provider string `json:"provider"`
+	CallStats
+}
+
+// Analytics usage analytics of the window
+type Analytics struct {
+	Usage
+	Consumers  []ConsumerUsage   `json:"consumers"`
+	CallMatrix []CallMatrixEntry `json:"callMatrix"`
+}
+
+// callCollector collects the status codes and the latency histogram of the calls
+type callCollector struct {
+	latencies    latencyHistogram
+	clientErrors int
+	serverErrors int
+}
+
+func (c *callCollector) add(log *HttpLog) {
+	c.latencies.add(log.Latencies.Request)
+	switch {
+	case log.Response.Status >= http.StatusInternalServerError:
+		c.serverErrors++
+	case log.Response.Status >= http.StatusBadRequest:
+		c.clientErrors++
+	}
+}
+
+func (c *callCollector) stats() CallStats {
+	calls := c.latencies.count
+	stats := CallStats{Calls: calls, ClientErrors: c.clientErrors, ServerErrors: c.serverErrors}
+	if calls == 0 {
+		return stats
+	}
+	stats.ClientErrorRate = float64(c.clientErrors) / float64(calls)
+	stats.ServerErrorRate = float64(c.serverErrors) / float64(calls)
+	stats.Latency = LatencyStats{
+		P50: c.latencies.percentile(50),
+		P95: c.latencies.percentile(95),
+		P99: c.latencies.percentile(99),
+	}
+	return stats
+}
+
+// ConsumerName identifies the consumer application of the call by the app instance id the appid-header plugin passes
+// to the upstream service, the api gateway consumer identifies the calls without it. The calls without both are
+// anonymous
+func ConsumerName(log *HttpLog) string {
+	switch {
+	case len(log.Request.Headers.AppInstanceId) != 0:
+		return string(log.Request.Headers.AppInstanceId)
+	case log.Consumer == nil:
+		return AnonymousConsumer
+	case len(log.Consumer.Username) != 0:
+		return log.Consumer.Username
+	case len(log.Consumer.Id) != 0:
+		return log.Consumer.Id
+	}
+	return AnonymousConsumer
+}
+
+// Analyze computes the usage analytics of the http logs matching the query in the window. The provider function maps
+// the api gateway service names to the provider service names, nil keeps the api gateway service names. The
+// latencies are counted in the histograms and at most maxAnalyzedLogs http logs are analyzed, hence the memory is
+// bounded regardless of the number of the calls
+func Analyze(store HttpLogStore, query *Query, window *Window, provider func(name string) string) (*Analytics,
+	error) {
+	oldestRetained := OldestRetained(store)
+	windowQuery := *query
+	windowQuery.Start = window.Start
+	windowQuery.End = window.End
+
+	step := window.step()
+	total := &callCollector{}
+	buckets := make([]callCollector, window.buckets())
+	consumers := make(map[string]*callCollector)
+	matrix := make(map[[2]string]*callCollector)
+	truncated := false
+	err := store.Each(&windowQuery, func(log *HttpLog) {
+		index := int(log.Time().Sub(window.Start) / step)
+		if index < 0 || index >= len(buckets) {
+			return
+		}
+		if total.latencies.count >= maxAnalyzedLogs {
+			truncated = true
+			return
+		}
+		total.add(log)
+		buckets[index].add(log)
+
+		consumer := ConsumerName(log)
+		if consumers[consumer] == nil {
+			consumers[consumer] = &callCollector{}
+		}
+		consumers[consumer].add(log)
+
+		providerName := log.Service.Name
+		if provider != nil {
+			providerName = provider(providerName)
+		}
+		key := [2]string{consumer, providerName}
+		if matrix[key] == nil {
+			matrix[key] = &callCollector{}
+		}
+		matrix[key].add(log)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	analytics := &Analytics{Usage: Usage{Window: *window, Total: total.stats()}}
+	analytics.OldestRetained = oldestRetained
+	analytics.Truncated = truncated
+	analytics.Buckets = make([]UsageBucket, 0, len(buckets))
+	for i := range buckets {
+		analytics.Buckets = append(analytics.Buckets, UsageBucket{
+			Start:     window.Start.Add(time.Duration(i) * step),
+			CallStats: buckets[i].stats(),
+		})
+	}
+	analytics.Consumers = make([]ConsumerUsage, 0, len(consumers))
+	for consumer, collector := range consumers {
+		analytics.Consumers = append(analytics.Consumers, ConsumerUsage{Consumer: consumer, CallStats: collector.stats()})
+	}
+	// top consumers first
+	sort.Slice(analytics.Consumers, func(i, j int) bool {
+		if analytics.Consumers[i].Calls != analytics.Consumers[j].Calls {
+			return analytics.Consumers[i].Calls > analytics.Consumers[j].Calls
+		}
+		return analytics.Consumers[i].Consumer < analytics.Consumers[j].Consumer
+	})
+	analytics.CallMatrix = make([]CallMatrixEntry, 0, len(matrix))
+	for key, collector := range matrix {
+		analytics.CallMatrix = append(analytics.CallMatrix, CallMatrixEntry{Consumer: key[0], Provider: key[1],
+			CallStats: collector.stats()})
+	}
+	sort.Slice(analytics.CallMatrix, func(i, j int) bool {
+		if analytics.CallMatrix[i].Consumer != analytics.CallMatrix[j].Consumer {
+			return analytics.CallMatrix[i].Consumer < analytics.CallMatrix[j].Consumer
+		}
+		return analytics.CallMatrix[i].Provider < analytics.CallMatrix[j].Provider
+	})
+	return analytics, nil
+}
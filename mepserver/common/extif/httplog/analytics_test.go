@@ -0,0 +1,119 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httplog_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/extif/httplog"
+	"mepserver/common/extif/httplog/memory"
+)
+
+// the apps authenticate with the tokens of the shared consumer, the app instance id header identifies the app
+const consumerLogFormat = `{"started_at":%d,"upstream_uri":"/","request":{"method":"GET",` +
+	`"headers":{"x-appinstanceid":"%s"}},"response":{"status":%d},"latencies":{"request":%d},` +
+	`"service":{"name":"%s"},"consumer":{"username":"mepauth.jwt"}}`
+
+func TestNewWindow(t *testing.T) {
+	end := time.Date(2021, 6, 10, 12, 30, 0, 0, time.UTC)
+	window, err := httplog.NewWindow(time.Time{}, end, "")
+	assert.NoError(t, err)
+	assert.Equal(t, httplog.GranularityDay, window.Granularity)
+	assert.Equal(t, time.Date(2021, 6, 3, 0, 0, 0, 0, time.UTC), window.Start)
+
+	window, err = httplog.NewWindow(time.Time{}, end, httplog.GranularityHour)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2021, 6, 9, 12, 0, 0, 0, time.UTC), window.Start)
+
+	_, err = httplog.NewWindow(end, end.Add(-time.Hour), httplog.GranularityHour)
+	assert.Error(t, err)
+	_, err = httplog.NewWindow(end.Add(-60*24*time.Hour), end, httplog.GranularityHour)
+	assert.Error(t, err)
+	_, err = httplog.NewWindow(time.Time{}, end, "week")
+	assert.Error(t, err)
+}
+
+func TestAnalyze(t *testing.T) {
+	start := time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC)
+	store := memory.NewStore(1000)
+	save := func(offset time.Duration, status int, latency int, service string, consumer string) {
+		log := fmt.Sprintf(consumerLogFormat, millis(start.Add(offset)), consumer, status, latency, service)
+		assert.NoError(t, store.Save([]byte(log)))
+	}
+	// 100 calls of app1 to serviceOne in the first hour with latencies 1..100, every 10th is a server error
+	for i := 1; i <= 100; i++ {
+		status := 200
+		if i%10 == 0 {
+			status = 500
+		}
+		save(time.Minute, status, i, "serviceOne4c3a2b", "app1")
+	}
+	// 2 calls of app2 to serviceTwo in the second hour, one is a client error
+	save(time.Hour+time.Minute, 200, 5, "serviceTwo1d2c7f", "app2")
+	save(time.Hour+time.Minute, 404, 7, "serviceTwo1d2c7f", "app2")
+	// out of the window
+	save(-time.Minute, 200, 1, "serviceOne4c3a2b", "app1")
+
+	window, err := httplog.NewWindow(start, start.Add(3*time.Hour), httplog.GranularityHour)
+	assert.NoError(t, err)
+	names := map[string]string{"serviceOne4c3a2b": "serviceOne", "serviceTwo1d2c7f": "serviceTwo"}
+	analytics, err := httplog.Analyze(store, &httplog.Query{}, window, func(name string) string {
+		return names[name]
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, start.Add(-time.Minute), *analytics.OldestRetained)
+	assert.Equal(t, 102, analytics.Total.Calls)
+	assert.Equal(t, 10, analytics.Total.ServerErrors)
+	assert.Equal(t, 1, analytics.Total.ClientErrors)
+	assert.Len(t, analytics.Buckets, 3)
+	assert.Equal(t, httplog.CallStats{Calls: 100, ServerErrors: 10, ServerErrorRate: 0.1,
+		Latency: httplog.LatencyStats{P50: 50, P95: 95, P99: 99}}, analytics.Buckets[0].CallStats)
+	assert.Equal(t, 0.5, analytics.Buckets[1].ClientErrorRate)
+	assert.Equal(t, 0, analytics.Buckets[2].Calls)
+	assert.Equal(t, start.Add(2*time.Hour), analytics.Buckets[2].Start)
+
+	assert.Equal(t, []string{"app1", "app2"}, []string{analytics.Consumers[0].Consumer, analytics.Consumers[1].Consumer})
+	assert.Len(t, analytics.CallMatrix, 2)
+	assert.Equal(t, "serviceTwo", analytics.CallMatrix[1].Provider)
+	assert.Equal(t, 2, analytics.CallMatrix[1].Calls)
+
+	analytics, err = httplog.Analyze(store, &httplog.Query{ServiceNamePrefix: "serviceTwo"}, window, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, analytics.Total.Calls)
+	assert.Equal(t, "serviceTwo1d2c7f", analytics.CallMatrix[0].Provider)
+}
+
+func TestConsumerName(t *testing.T) {
+	assert.Equal(t, httplog.AnonymousConsumer, httplog.ConsumerName(&httplog.HttpLog{}))
+	assert.Equal(t, "id", httplog.ConsumerName(&httplog.HttpLog{Consumer: &httplog.Consumer{Id: "id"}}))
+	assert.Equal(t, "app", httplog.ConsumerName(&httplog.HttpLog{Consumer: &httplog.Consumer{Id: "id",
+		Username: "app"}}))
+	assert.Equal(t, "app1", httplog.ConsumerName(&httplog.HttpLog{Consumer: &httplog.Consumer{Username: "app"},
+		Request: httplog.Request{Headers: httplog.RequestHeaders{AppInstanceId: "app1"}}}))
+
+	// kong logs the repeated headers as the array
+	log := httplog.HttpLog{}
+	assert.NoError(t, json.Unmarshal([]byte(`{"request":{"headers":{"x-appinstanceid":["app1","app2"]}}}`), &log))
+	assert.Equal(t, "app1", httplog.ConsumerName(&log))
+	assert.Error(t, json.Unmarshal([]byte(`{"request":{"headers":{"x-appinstanceid":1}}}`), &log))
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package common implements http log store common functionalities
+package common
+
+import (
+	"mepserver/common/config"
+	"mepserver/common/extif/httplog"
+	"mepserver/common/extif/httplog/elasticsearch"
+	"mepserver/common/extif/httplog/file"
+	"mepserver/common/extif/httplog/memory"
+	meputil "mepserver/common/util"
+)
+
+// CreateHttpLogStore factory to create the http log store
+func CreateHttpLogStore(config *config.MepServerConfig) httplog.HttpLogStore {
+	switch config.HttpLog.Store {
+	case "", meputil.HttpLogStoreElasticsearch:
+		return &elasticsearch.Store{}
+	case meputil.HttpLogStoreFile:
+		return &file.Store{}
+	case meputil.HttpLogStoreMemory:
+		return memory.NewStore(config.HttpLog.MaxRecords)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package elasticsearch implements the elasticsearch http log store
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+	es "github.com/olivere/elastic/v7"
+
+	"mepserver/common/config"
+	"mepserver/common/extif/httplog"
+	"mepserver/common/models"
+	meputil "mepserver/common/util"
+)
+
+const (
+	startedAt  = "started_at"
+	scrollSize = 1000
+)
+
+// Store keeps the http logs in the elasticsearch index
+type Store struct {
+	client *es.Client
+}
+
+// InitStore connects to the elasticsearch and creates the http log index if not exists
+func (s *Store) InitStore(config *config.MepServerConfig) error {
+	esUrl := config.HttpLog.EsUrl
+	if len(esUrl) == 0 {
+		esUrl = meputil.DefaultHttpLogEsUrl
+	}
+	log.Info("Create es client.")
+	client, err := es.NewClient(es.SetSniff(false), es.SetURL(esUrl))
+	if err != nil {
+		return err
+	}
+	s.client = client
+	log.Info("Connect to es success.")
+
+	exists, err := client.IndexExists(meputil.KongHttpLogIndex).Do(context.Background())
+	if err != nil {
+		return err
+	}
+	if exists {
+		log.Info("Index already exists in the es client.")
+		return nil
+	}
+	mapping := models.GetHttpLogMapping()
+	createIndex, err := client.CreateIndex(meputil.KongHttpLogIndex).BodyString(mapping).Do(context.Background())
+	if err != nil {
+		return err
+	}
+	if !createIndex.Acknowledged {
+		return fmt.Errorf("create index not acknowledged")
+	}
+	return nil
+}
+
+// Save indexes the http log payload as it is
+func (s *Store) Save(data []byte) error {
+	if s.client == nil {
+		return fmt.Errorf("es client is not connected")
+	}
+	_, err := s.client.Index().Index(meputil.KongHttpLogIndex).BodyString(string(data)).Do(context.Background())
+	return err
+}
+
+// Count counts the http logs matching the query in the index
+func (s *Store) Count(query *httplog.Query) (int, error) {
+	if s.client == nil {
+		return 0, fmt.Errorf("es client is not connected")
+	}
+	count, err := s.client.Count(meputil.KongHttpLogIndex).Query(buildQuery(query)).Do(context.Background())
+	if err != nil {
+		return 0, err
+	}
+	return int(count), nil
+}
+
+// Each scrolls through the http logs matching the query in the index
+func (s *Store) Each(query *httplog.Query, handler func(log *httplog.HttpLog)) error {
+	if s.client == nil {
+		return fmt.Errorf("es client is not connected")
+	}
+	scroll := s.client.Scroll(meputil.KongHttpLogIndex).Query(buildQuery(query)).Size(scrollSize)
+	defer func() {
+		if err := scroll.Clear(context.Background()); err != nil {
+			log.Warnf("Clear es scroll failed.")
+		}
+	}()
+	for {
+		result, err := scroll.Do(context.Background())
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		for _, hit := range result.Hits.Hits {
+			record := httplog.HttpLog{}
+			if err = json.Unmarshal(hit.Source, &record); err != nil {
+				continue
+			}
+			handler(&record)
+		}
+	}
+}
+
+func buildQuery(query *httplog.Query) *es.BoolQuery {
+	boolQuery := es.NewBoolQuery()
+	if len(query.ServiceName) != 0 {
+		boolQuery.Filter(es.NewTermsQuery("service.name", query.ServiceName))
+	}
+	if len(query.ServiceNamePrefix) != 0 {
+		boolQuery.Filter(es.NewPrefixQuery("service.name.keyword", query.ServiceNamePrefix))
+	}
+	if len(query.UriPrefix) != 0 {
+		boolQuery.Filter(es.NewPrefixQuery("upstream_uri.keyword", query.UriPrefix))
+	}
+	if len(query.UriPattern) != 0 {
+		boolQuery.Filter(es.NewRegexpQuery("upstream_uri.keyword", query.UriPattern))
+	}
+	if len(query.Method) != 0 {
+		boolQuery.Filter(es.NewMatchQuery("request.method", query.Method))
+	}
+	if !query.Start.IsZero() || !query.End.IsZero() {
+		timeRange := es.NewRangeQuery(startedAt).Format("epoch_millis")
+		if !query.Start.IsZero() {
+			timeRange.Gte(toMillis(query.Start))
+		}
+		if !query.End.IsZero() {
+			timeRange.Lt(toMillis(query.End))
+		}
+		boolQuery.Filter(timeRange)
+	}
+	return boolQuery
+}
+
+func toMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
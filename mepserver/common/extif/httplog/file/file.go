@@ -0,0 +1,173 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package file implements the local file http log store for the edge nodes without elasticsearch
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	"mepserver/common/config"
+	"mepserver/common/extif/httplog"
+	"mepserver/common/extif/httplog/memory"
+	meputil "mepserver/common/util"
+)
+
+const (
+	filePermission = 0600
+	maxLineSize    = 1024 * 1024
+)
+
+// Store appends the http logs to a local file, one json record per line, and serves the queries from the memory.
+// The file is compacted to the latest records once it grows to the double of the maximum records
+type Store struct {
+	*memory.Store
+	mutex      sync.Mutex
+	filePath   string
+	file       *os.File
+	lines      int
+	maxRecords int
+}
+
+// InitStore loads the existing http logs from the file and opens the file for appending
+func (s *Store) InitStore(config *config.MepServerConfig) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.filePath = config.HttpLog.FilePath
+	s.maxRecords = config.HttpLog.MaxRecords
+	if s.maxRecords <= 0 {
+		s.maxRecords = meputil.DefaultHttpLogMaxRecords
+	}
+	s.Store = memory.NewStore(s.maxRecords)
+	if len(s.filePath) == 0 {
+		return fmt.Errorf("http log file path is not configured")
+	}
+	if err := os.MkdirAll(filepath.Dir(s.filePath), 0750); err != nil {
+		return err
+	}
+	if err := s.load(); err != nil {
+		return err
+	}
+	return s.open()
+}
+
+func (s *Store) load() error {
+	file, err := os.Open(s.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+	for scanner.Scan() {
+		s.lines++
+		record := httplog.HttpLog{}
+		if err = json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			log.Warnf("Skipped the invalid http log record in %s.", s.filePath)
+			continue
+		}
+		s.Add(record)
+	}
+	return scanner.Err()
+}
+
+func (s *Store) open() error {
+	file, err := os.OpenFile(s.filePath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, filePermission)
+	if err != nil {
+		return err
+	}
+	s.file = file
+	return nil
+}
+
+// Save decodes the http log, keeps it in the memory and appends it to the file
+func (s *Store) Save(data []byte) error {
+	record := httplog.HttpLog{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return err
+	}
+	// only the fields used by the governance are kept in the file
+	recordBytes, err := json.Marshal(&record)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.file == nil {
+		return fmt.Errorf("http log file is not open")
+	}
+	if _, err = s.file.Write(append(recordBytes, '\n')); err != nil {
+		return err
+	}
+	s.Add(record)
+	s.lines++
+	if s.lines >= 2*s.maxRecords {
+		if err = s.compact(); err != nil {
+			log.Errorf(err, "Http log file compaction failed.")
+		}
+	}
+	return nil
+}
+
+// compact rewrites the file with the records in the memory
+func (s *Store) compact() error {
+	tempPath := s.filePath + ".tmp"
+	tempFile, err := os.OpenFile(tempPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, filePermission)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(tempFile)
+	lines := 0
+	err = s.Each(&httplog.Query{}, func(record *httplog.HttpLog) {
+		recordBytes, marshalErr := json.Marshal(record)
+		if marshalErr != nil {
+			return
+		}
+		_, _ = writer.Write(append(recordBytes, '\n'))
+		lines++
+	})
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := tempFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(tempPath)
+		return err
+	}
+
+	_ = s.file.Close()
+	s.file = nil
+	if err = os.Rename(tempPath, s.filePath); err != nil {
+		_ = s.open()
+		return err
+	}
+	s.lines = lines
+	return s.open()
+}
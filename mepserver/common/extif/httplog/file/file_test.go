@@ -0,0 +1,82 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/config"
+	"mepserver/common/extif/httplog"
+)
+
+const httpLogFormat = `{"started_at":%d,"upstream_uri":"/","request":{"method":"GET","headers":{"host":"mep"}},` +
+	`"response":{"status":200},"service":{"name":"mepserver"}}`
+
+func countLines(t *testing.T, path string) int {
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+	}
+	return lines
+}
+
+func TestFileStoreReloadAndCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "httplog")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	mepConfig := &config.MepServerConfig{}
+	mepConfig.HttpLog.FilePath = filepath.Join(dir, "http-log.json")
+	mepConfig.HttpLog.MaxRecords = 3
+
+	store := &Store{}
+	assert.NoError(t, store.InitStore(mepConfig))
+	for i := 1; i <= 5; i++ {
+		assert.NoError(t, store.Save([]byte(fmt.Sprintf(httpLogFormat, i))))
+	}
+	assert.Equal(t, 5, countLines(t, mepConfig.HttpLog.FilePath))
+
+	// the sixth record reaches the double of the maximum records and compacts the file
+	assert.NoError(t, store.Save([]byte(fmt.Sprintf(httpLogFormat, 6))))
+	assert.Equal(t, 3, countLines(t, mepConfig.HttpLog.FilePath))
+	assert.NoError(t, store.file.Close())
+
+	reloaded := &Store{}
+	assert.NoError(t, reloaded.InitStore(mepConfig))
+	defer reloaded.file.Close()
+	startedAt := make([]int64, 0)
+	_ = reloaded.Each(&httplog.Query{}, func(log *httplog.HttpLog) {
+		startedAt = append(startedAt, log.StartedAt)
+	})
+	assert.Equal(t, []int64{4, 5, 6}, startedAt)
+	assert.Equal(t, int64(4), httplog.OldestRetained(reloaded).UnixNano()/int64(time.Millisecond))
+
+	count, err := reloaded.Count(&httplog.Query{ServiceName: "mepserver", Method: "GET"})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
@@ -0,0 +1,91 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httplog
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// latencySubBins the bins of each power of two range of the latencies, the latencies below twice of it are counted
+// exactly and the larger ones within 1/latencySubBins relative error
+const (
+	latencySubBins     = 32
+	latencySubBinsBits = 5
+)
+
+// latencyHistogram counts the latencies in the log-linear bins, the memory is bounded by the number of the bins
+// regardless of the number of the calls
+type latencyHistogram struct {
+	bins  map[int]int
+	count int
+}
+
+func (h *latencyHistogram) add(latency int) {
+	if h.bins == nil {
+		h.bins = make(map[int]int)
+	}
+	h.bins[latencyBin(latency)]++
+	h.count++
+}
+
+// percentile nearest-rank percentile, the largest latency of the bin holding the rank is returned
+func (h *latencyHistogram) percentile(p float64) int {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+	bins := make([]int, 0, len(h.bins))
+	for bin := range h.bins {
+		bins = append(bins, bin)
+	}
+	sort.Ints(bins)
+	counted := 0
+	for _, bin := range bins {
+		counted += h.bins[bin]
+		if counted >= rank {
+			return latencyBinMax(bin)
+		}
+	}
+	return latencyBinMax(bins[len(bins)-1])
+}
+
+// latencyBin the bin of the latency, the bin keeps the top latencySubBinsBits+1 bits of the latency
+func latencyBin(latency int) int {
+	if latency < 2*latencySubBins {
+		if latency < 0 {
+			return 0
+		}
+		return latency
+	}
+	shift := bits.Len(uint(latency)) - 1 - latencySubBinsBits
+	return shift*latencySubBins + latency>>uint(shift)
+}
+
+// latencyBinMax the largest latency of the bin
+func latencyBinMax(bin int) int {
+	if bin < 2*latencySubBins {
+		return bin
+	}
+	shift := bin/latencySubBins - 1
+	mantissa := bin%latencySubBins + latencySubBins
+	return (mantissa+1)<<uint(shift) - 1
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httplog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sliceStore http log store on a slice, the stores of the sub packages import this package
+type sliceStore struct {
+	HttpLogStore
+	logs []HttpLog
+}
+
+func (s *sliceStore) Each(query *Query, handler func(log *HttpLog)) error {
+	for i := range s.logs {
+		handler(&s.logs[i])
+	}
+	return nil
+}
+
+func TestLatencyHistogram(t *testing.T) {
+	histogram := latencyHistogram{}
+	assert.Equal(t, 0, histogram.percentile(50))
+	for latency := 1; latency <= 100; latency++ {
+		histogram.add(latency)
+	}
+	// the latencies below 64 are counted exactly, the larger ones by the largest latency of the bin
+	assert.Equal(t, 50, histogram.percentile(50))
+	assert.Equal(t, 95, histogram.percentile(95))
+	assert.Equal(t, 101, histogram.percentile(100))
+
+	histogram = latencyHistogram{}
+	for i := 0; i < 1000; i++ {
+		histogram.add(120000)
+	}
+	histogram.add(-1)
+	assert.Equal(t, 0, histogram.percentile(0))
+	assert.InEpsilon(t, 120000, histogram.percentile(50), 1.0/latencySubBins)
+	assert.LessOrEqual(t, len(histogram.bins), 2)
+
+	for latency := 0; latency < 1<<20; latency += 7 {
+		bin := latencyBin(latency)
+		assert.GreaterOrEqual(t, latencyBinMax(bin), latency)
+		assert.Less(t, latencyBinMax(bin-1), latency)
+	}
+}
+
+func TestAnalyzeTruncated(t *testing.T) {
+	maxAnalyzedLogs = 10
+	defer func() {
+		maxAnalyzedLogs = 1000000
+	}()
+	start := time.Date(2021, 6, 10, 0, 0, 0, 0, time.UTC)
+	store := &sliceStore{}
+	for i := 0; i < 15; i++ {
+		store.logs = append(store.logs, HttpLog{StartedAt: start.Unix() * 1000, Response: Response{Status: 200},
+			Latencies: Latencies{Request: i}, Service: Service{Name: "serviceOne"}})
+	}
+	window, err := NewWindow(start, start.Add(time.Hour), GranularityHour)
+	assert.NoError(t, err)
+
+	analytics, err := Analyze(store, &Query{}, window, nil)
+	assert.NoError(t, err)
+	assert.True(t, analytics.Truncated)
+	assert.Equal(t, 10, analytics.Total.Calls)
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package httplog defines the api gateway http log store interfaces
+package httplog
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"mepserver/common/config"
+)
+
+// HttpLog api gateway http log, the subset of the kong http-log plugin payload used by the service governance
+type HttpLog struct {
+	StartedAt   int64     `json:"started_at"`
+	ClientIp    string    `json:"client_ip,omitempty"`
+	UpstreamUri string    `json:"upstream_uri"`
+	Request     Request   `json:"request"`
+	Response    Response  `json:"response"`
+	Latencies   Latencies `json:"latencies"`
+	Service     Service   `json:"service"`
+	Consumer    *Consumer `json:"consumer,omitempty"`
+}
+
+// Request http request information
+type Request struct {
+	Method  string         `json:"method"`
+	Uri     string         `json:"uri,omitempty"`
+	Headers RequestHeaders `json:"headers"`
+}
+
+// RequestHeaders the subset of the http request headers used by the service governance, kong logs the header names
+// in lower case
+type RequestHeaders struct {
+	AppInstanceId HeaderValue `json:"x-appinstanceid,omitempty"`
+}
+
+// HeaderValue http header value, kong logs the repeated header as the array of the values of which the first is kept
+type HeaderValue string
+
+// UnmarshalJSON reads the header value from the string or the array of the strings
+func (v *HeaderValue) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*v = HeaderValue(value)
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("invalid header value")
+	}
+	*v = ""
+	if len(values) != 0 {
+		*v = HeaderValue(values[0])
+	}
+	return nil
+}
+
+// Response http response information
+type Response struct {
+	Status int `json:"status"`
+}
+
+// Latencies request latencies in milliseconds
+type Latencies struct {
+	Request int `json:"request"`
+	Kong    int `json:"kong"`
+	Proxy   int `json:"proxy"`
+}
+
+// Service api gateway service of the request
+type Service struct {
+	Name string `json:"name"`
+}
+
+// Consumer api gateway consumer of the request
+type Consumer struct {
+	Id       string `json:"id,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// Time returns the request start time
+func (l *HttpLog) Time() time.Time {
+	return time.Unix(0, l.StartedAt*int64(time.Millisecond))
+}
+
+// Query http log query conditions, empty values match all the logs. The uri pattern must match the complete uri,
+// the time range includes the start and excludes the end
+type Query struct {
+	ServiceName       string
+	ServiceNamePrefix string
+	UriPrefix         string
+	UriPattern        string
+	Method            string
+	Start             time.Time
+	End               time.Time
+}
+
+// Matcher compiles the query to a function checking the logs satisfy the query conditions
+func (q *Query) Matcher() (func(log *HttpLog) bool, error) {
+	var uriRegexp *regexp.Regexp
+	if len(q.UriPattern) != 0 {
+		var err error
+		uriRegexp, err = regexp.Compile("^(?:" + q.UriPattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid uri pattern")
+		}
+	}
+	query := *q
+	return func(log *HttpLog) bool {
+		if len(query.ServiceName) != 0 && log.Service.Name != query.ServiceName {
+			return false
+		}
+		if len(query.ServiceNamePrefix) != 0 && !strings.HasPrefix(log.Service.Name, query.ServiceNamePrefix) {
+			return false
+		}
+		if len(query.UriPrefix) != 0 && !strings.HasPrefix(log.UpstreamUri, query.UriPrefix) {
+			return false
+		}
+		if uriRegexp != nil && !uriRegexp.MatchString(log.UpstreamUri) {
+			return false
+		}
+		if len(query.Method) != 0 && !strings.EqualFold(log.Request.Method, query.Method) {
+			return false
+		}
+		startedAt := log.Time()
+		if !query.Start.IsZero() && startedAt.Before(query.Start) {
+			return false
+		}
+		if !query.End.IsZero() && !startedAt.Before(query.End) {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// HttpLogStore api gateway http log store interface functions
+type HttpLogStore interface {
+
+	// InitStore Initialize the store
+	InitStore(config *config.MepServerConfig) (err error)
+
+	// Save Store the http log payload sent by the api gateway
+	Save(data []byte) (err error)
+
+	// Count Count the http logs matching the query
+	Count(query *Query) (count int, err error)
+
+	// Each Invoke the handler on the http logs matching the query
+	Each(query *Query, handler func(log *HttpLog)) (err error)
+}
+
+// BoundedStore is implemented by the stores keeping only the latest http logs, the statistics before the oldest
+// retained http log are incomplete
+type BoundedStore interface {
+
+	// OldestRetained Start time of the oldest retained http log, zero time if the store is empty
+	OldestRetained() time.Time
+}
+
+// OldestRetained returns the start time of the oldest http log retained by a bounded store, nil for the stores
+// keeping all the http logs and for the empty stores
+func OldestRetained(store HttpLogStore) *time.Time {
+	bounded, ok := store.(BoundedStore)
+	if !ok {
+		return nil
+	}
+	oldest := bounded.OldestRetained()
+	if oldest.IsZero() {
+		return nil
+	}
+	return &oldest
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package httplog_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/extif/httplog"
+	"mepserver/common/extif/httplog/memory"
+)
+
+const httpLogFormat = `{"started_at":%d,"upstream_uri":"%s","request":{"method":"%s"},"response":{"status":200},` +
+	`"latencies":{"request":12,"kong":2,"proxy":10},"service":{"name":"%s"}}`
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func TestMemoryStoreCount(t *testing.T) {
+	now := time.Now().UTC()
+	store := memory.NewStore(10)
+	logs := []string{
+		fmt.Sprintf(httpLogFormat, millis(now), "/mep/mec_service_mgmt/v1/applications/5abe4782/services", "POST",
+			"mepserver"),
+		fmt.Sprintf(httpLogFormat, millis(now), "/mep/mec_service_mgmt/v1/services?ser_name=a", "GET", "mepserver"),
+		fmt.Sprintf(httpLogFormat, millis(now.Add(-48*time.Hour)), "/mep/mec_service_mgmt/v1/services", "GET",
+			"mepserver"),
+		fmt.Sprintf(httpLogFormat, millis(now), "/face", "GET", "FaceRegService6c3a2bf8df4144d4"),
+	}
+	for _, log := range logs {
+		assert.NoError(t, store.Save([]byte(log)))
+	}
+	assert.Error(t, store.Save([]byte("{")))
+
+	count, err := store.Count(&httplog.Query{ServiceName: "mepserver",
+		UriPattern: "/mep/mec_service_mgmt/v1/applications/[-A-Za-z0-9]+/services", Method: "POST"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	count, _ = store.Count(&httplog.Query{UriPrefix: "/mep/mec_service_mgmt/v1/services", Method: "GET"})
+	assert.Equal(t, 2, count)
+
+	count, _ = store.Count(&httplog.Query{UriPrefix: "/mep/mec_service_mgmt/v1/services",
+		Start: now.Add(-time.Hour)})
+	assert.Equal(t, 1, count)
+
+	count, _ = store.Count(&httplog.Query{ServiceNamePrefix: "FaceRegService6"})
+	assert.Equal(t, 1, count)
+
+	_, err = store.Count(&httplog.Query{UriPattern: "["})
+	assert.Error(t, err)
+}
+
+func TestMemoryStoreMaxRecords(t *testing.T) {
+	store := memory.NewStore(2)
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, store.Save([]byte(fmt.Sprintf(httpLogFormat, i, "/", "GET", "mepserver"))))
+	}
+	startedAt := make([]int64, 0)
+	_ = store.Each(&httplog.Query{}, func(log *httplog.HttpLog) {
+		startedAt = append(startedAt, log.StartedAt)
+	})
+	assert.Equal(t, []int64{2, 3}, startedAt)
+	assert.Equal(t, time.Unix(0, 2*int64(time.Millisecond)).UTC(), *httplog.OldestRetained(store))
+	assert.Nil(t, httplog.OldestRetained(memory.NewStore(2)))
+}
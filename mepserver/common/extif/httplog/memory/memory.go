@@ -0,0 +1,121 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package memory implements the in-memory http log store, the logs are lost on restart
+package memory
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"mepserver/common/config"
+	"mepserver/common/extif/httplog"
+	meputil "mepserver/common/util"
+)
+
+// Store keeps the latest http logs in memory up to the maximum records
+type Store struct {
+	mutex      sync.RWMutex
+	logs       []httplog.HttpLog
+	next       int
+	maxRecords int
+}
+
+// NewStore creates the in-memory store with the maximum records, non-positive value selects the default
+func NewStore(maxRecords int) *Store {
+	if maxRecords <= 0 {
+		maxRecords = meputil.DefaultHttpLogMaxRecords
+	}
+	return &Store{maxRecords: maxRecords}
+}
+
+// InitStore sets the maximum records as per configuration
+func (s *Store) InitStore(config *config.MepServerConfig) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.maxRecords = config.HttpLog.MaxRecords
+	if s.maxRecords <= 0 {
+		s.maxRecords = meputil.DefaultHttpLogMaxRecords
+	}
+	s.logs = nil
+	s.next = 0
+	return nil
+}
+
+// Save decodes and stores the http log
+func (s *Store) Save(data []byte) error {
+	log := httplog.HttpLog{}
+	if err := json.Unmarshal(data, &log); err != nil {
+		return err
+	}
+	s.Add(log)
+	return nil
+}
+
+// Add stores the decoded http log, the oldest log is replaced once the maximum records reached
+func (s *Store) Add(log httplog.HttpLog) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.logs) < s.maxRecords {
+		s.logs = append(s.logs, log)
+		return
+	}
+	s.logs[s.next] = log
+	s.next = (s.next + 1) % s.maxRecords
+}
+
+// Count counts the http logs matching the query
+func (s *Store) Count(query *httplog.Query) (int, error) {
+	count := 0
+	err := s.Each(query, func(log *httplog.HttpLog) {
+		count++
+	})
+	return count, err
+}
+
+// Each invokes the handler on the http logs matching the query from the oldest to the latest
+func (s *Store) Each(query *httplog.Query, handler func(log *httplog.HttpLog)) error {
+	match, err := query.Matcher()
+	if err != nil {
+		return err
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for i := range s.logs {
+		log := &s.logs[(s.next+i)%len(s.logs)]
+		if match(log) {
+			handler(log)
+		}
+	}
+	return nil
+}
+
+// OldestRetained returns the start time of the oldest retained http log, zero time if the store is empty
+func (s *Store) OldestRetained() time.Time {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if len(s.logs) == 0 {
+		return time.Time{}
+	}
+	oldest := s.logs[0].StartedAt
+	for i := range s.logs {
+		if s.logs[i].StartedAt < oldest {
+			oldest = s.logs[i].StartedAt
+		}
+	}
+	return time.Unix(0, oldest*int64(time.Millisecond)).UTC()
+}
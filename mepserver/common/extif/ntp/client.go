@@ -19,7 +19,9 @@ package ntp
 import (
 	"github.com/apache/servicecomb-service-center/pkg/log"
 	"github.com/beevik/ntp"
+	"mepserver/common/metrics"
 	"mepserver/common/util"
+	"time"
 )
 
 // Package ntp provides an implementation of a Simple NTP (SNTP) client
@@ -35,7 +37,9 @@ type NtpCurrentTime struct {
 
 func GetTimeStamp() (timeStamp *NtpCurrentTime, errorCode int) {
 	var currentTime NtpCurrentTime
+	start := time.Now()
 	ntpRsp, err := ntp.QueryWithOptions(util.NtpHost, ntp.QueryOptions{Version: 4})
+	metrics.ObserveNtpQuery(start, err)
 	if ntpRsp == nil {
 		log.Errorf(err, "Failed to read server response")
 		return nil, util.NtpConnectionErr
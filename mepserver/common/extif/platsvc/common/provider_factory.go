@@ -0,0 +1,37 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package common implements platform service provider common functionalities
+package common
+
+import (
+	"mepserver/common/config"
+	"mepserver/common/extif/platsvc"
+	"mepserver/common/extif/platsvc/none"
+	"mepserver/common/extif/platsvc/simulator"
+	meputil "mepserver/common/util"
+)
+
+// CreateProvider factory to create radio network information and location provider
+func CreateProvider(config *config.MepServerConfig) platsvc.Provider {
+	switch config.PlatformServices.Provider {
+	case "", meputil.PlatformSvcProviderNone:
+		return &none.NoneProvider{}
+	case meputil.PlatformSvcProviderSimulator:
+		return &simulator.Provider{}
+	}
+	return nil
+}
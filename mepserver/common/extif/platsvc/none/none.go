@@ -0,0 +1,51 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package none for sample radio network information and location provider implementations
+package none
+
+import (
+	"mepserver/common/config"
+	"mepserver/common/extif/platsvc"
+)
+
+// NoneProvider implements a provider without any cell or user equipment
+type NoneProvider struct {
+}
+
+// InitProvider initialize the provider
+func (n *NoneProvider) InitProvider(config *config.MepServerConfig) (err error) {
+	return nil
+}
+
+// GetPlmn returns an empty plmn
+func (n *NoneProvider) GetPlmn() (plmn platsvc.Plmn, err error) {
+	return platsvc.Plmn{}, nil
+}
+
+// GetCells returns an empty cell list
+func (n *NoneProvider) GetCells() (cells []platsvc.Cell, err error) {
+	return make([]platsvc.Cell, 0), nil
+}
+
+// GetUserEquipments returns an empty user equipment list
+func (n *NoneProvider) GetUserEquipments() (ues []platsvc.UserEquipment, err error) {
+	return make([]platsvc.UserEquipment, 0), nil
+}
+
+// SetEventHandler ignores the handler since no events are generated
+func (n *NoneProvider) SetEventHandler(handler platsvc.EventHandler) {
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package platsvc defines the radio network information and location provider interfaces
+package platsvc
+
+import (
+	"time"
+
+	"mepserver/common/config"
+)
+
+// Plmn public land mobile network identity
+type Plmn struct {
+	Mcc string `json:"mcc" validate:"required,len=3,numeric"`
+	Mnc string `json:"mnc" validate:"required,min=2,max=3,numeric"`
+}
+
+// Cell radio cell served by the platform along with its location attributes
+type Cell struct {
+	CellId        string  `json:"cellId" validate:"required,len=7,hexadecimal"`
+	ZoneId        string  `json:"zoneId" validate:"required,min=1,max=64"`
+	AccessPointId string  `json:"accessPointId" validate:"required,min=1,max=64"`
+	Latitude      float32 `json:"latitude" validate:"min=-90,max=90"`
+	Longitude     float32 `json:"longitude" validate:"min=-180,max=180"`
+}
+
+// Erab E-UTRAN radio access bearer of an user equipment
+type Erab struct {
+	ErabId int `json:"erabId" validate:"min=1,max=15"`
+	Qci    int `json:"qci" validate:"min=1,max=255"`
+}
+
+// UserEquipment user equipment attached to one of the cells
+type UserEquipment struct {
+	Address   string  `json:"address" validate:"required,ip"`
+	CellId    string  `json:"cellId" validate:"required,len=7,hexadecimal"`
+	Latitude  float32 `json:"latitude" validate:"min=-90,max=90"`
+	Longitude float32 `json:"longitude" validate:"min=-180,max=180"`
+	Erabs     []Erab  `json:"erabs" validate:"omitempty,dive"`
+}
+
+// CellChangeEvent raised by the provider when an user equipment moved to another cell
+type CellChangeEvent struct {
+	Ue        UserEquipment
+	SrcCell   Cell
+	TrgCell   Cell
+	TimeStamp time.Time
+}
+
+// EventHandler callback invoked on every provider event
+type EventHandler func(event CellChangeEvent)
+
+// Provider radio network information and location provider interface functions
+type Provider interface {
+
+	// InitProvider Initialize the provider
+	InitProvider(config *config.MepServerConfig) (err error)
+
+	// GetPlmn Get the public land mobile network identity of the platform
+	GetPlmn() (plmn Plmn, err error)
+
+	// GetCells Get all the cells
+	GetCells() (cells []Cell, err error)
+
+	// GetUserEquipments Get all the attached user equipments
+	GetUserEquipments() (ues []UserEquipment, err error)
+
+	// SetEventHandler Set the handler which receives the cell change events
+	SetEventHandler(handler EventHandler)
+}
@@ -0,0 +1,248 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package simulator implements a scenario file driven radio network information and location provider
+package simulator
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+	"github.com/ghodss/yaml"
+	"github.com/go-playground/validator/v10"
+
+	"mepserver/common/config"
+	"mepserver/common/extif/platsvc"
+)
+
+const defaultStepInterval = 10
+
+// Step moves one user equipment to the given cell, location is taken from the cell unless specified
+type Step struct {
+	Address   string   `json:"address" validate:"required,ip"`
+	CellId    string   `json:"cellId" validate:"required,len=7,hexadecimal"`
+	Latitude  *float32 `json:"latitude,omitempty" validate:"omitempty,min=-90,max=90"`
+	Longitude *float32 `json:"longitude,omitempty" validate:"omitempty,min=-180,max=180"`
+}
+
+// Scenario describes the simulated radio network and the scripted movements of the user equipments
+type Scenario struct {
+	Interval int                     `json:"interval" validate:"omitempty,min=1,max=86400"`
+	Loop     bool                    `json:"loop"`
+	Plmn     platsvc.Plmn            `json:"plmn"`
+	Cells    []platsvc.Cell          `json:"cells" validate:"required,min=1,dive"`
+	Ues      []platsvc.UserEquipment `json:"ues" validate:"omitempty,dive"`
+	Steps    []Step                  `json:"steps" validate:"omitempty,dive"`
+}
+
+// Provider simulates the radio network by replaying the scenario steps periodically
+type Provider struct {
+	mu        sync.RWMutex
+	scenario  *Scenario
+	cells     map[string]platsvc.Cell
+	ues       map[string]*platsvc.UserEquipment
+	nextStep  int
+	handler   platsvc.EventHandler
+	stopTimer chan struct{}
+}
+
+// InitProvider loads the scenario file and starts replaying the steps
+func (p *Provider) InitProvider(config *config.MepServerConfig) (err error) {
+	scenario, err := loadScenario(config.PlatformServices.ScenarioFile)
+	if err != nil {
+		return err
+	}
+	if err = p.applyScenario(scenario); err != nil {
+		return err
+	}
+	if len(scenario.Steps) != 0 {
+		p.stopTimer = make(chan struct{})
+		go p.run()
+	}
+	log.Infof("Simulator loaded %d cells, %d user equipments and %d steps.", len(scenario.Cells),
+		len(scenario.Ues), len(scenario.Steps))
+	return nil
+}
+
+func loadScenario(scenarioFile string) (*Scenario, error) {
+	data, err := ioutil.ReadFile(filepath.FromSlash(scenarioFile))
+	if err != nil {
+		log.Error("Reading simulator scenario file error.", nil)
+		return nil, err
+	}
+	var scenario Scenario
+	if err = yaml.Unmarshal(data, &scenario); err != nil {
+		log.Error("Parsing simulator scenario file error.", nil)
+		return nil, err
+	}
+	return &scenario, nil
+}
+
+func (p *Provider) applyScenario(scenario *Scenario) error {
+	if err := validator.New().Struct(scenario); err != nil {
+		log.Error("Simulator scenario validation failed.", err)
+		return err
+	}
+	cells := make(map[string]platsvc.Cell, len(scenario.Cells))
+	for _, cell := range scenario.Cells {
+		if _, found := cells[cell.CellId]; found {
+			return fmt.Errorf("error: duplicate cell %s in scenario", cell.CellId)
+		}
+		cells[cell.CellId] = cell
+	}
+	ues := make(map[string]*platsvc.UserEquipment, len(scenario.Ues))
+	for i := range scenario.Ues {
+		ue := scenario.Ues[i]
+		if _, found := ues[ue.Address]; found {
+			return fmt.Errorf("error: duplicate user equipment %s in scenario", ue.Address)
+		}
+		if _, found := cells[ue.CellId]; !found {
+			return fmt.Errorf("error: user equipment %s refers unknown cell %s", ue.Address, ue.CellId)
+		}
+		ues[ue.Address] = &ue
+	}
+	for i, step := range scenario.Steps {
+		if _, found := ues[step.Address]; !found {
+			return fmt.Errorf("error: step %d refers unknown user equipment %s", i, step.Address)
+		}
+		if _, found := cells[step.CellId]; !found {
+			return fmt.Errorf("error: step %d refers unknown cell %s", i, step.CellId)
+		}
+	}
+	if scenario.Interval == 0 {
+		scenario.Interval = defaultStepInterval
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.scenario = scenario
+	p.cells = cells
+	p.ues = ues
+	p.nextStep = 0
+	return nil
+}
+
+func (p *Provider) run() {
+	ticker := time.NewTicker(time.Duration(p.scenario.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if !p.advance() {
+				log.Info("Simulator scenario steps completed.")
+				return
+			}
+		case <-p.stopTimer:
+			return
+		}
+	}
+}
+
+// advance applies the next scenario step, returns false once all the steps are completed
+func (p *Provider) advance() bool {
+	p.mu.Lock()
+	if p.nextStep >= len(p.scenario.Steps) {
+		if !p.scenario.Loop || len(p.scenario.Steps) == 0 {
+			p.mu.Unlock()
+			return false
+		}
+		p.nextStep = 0
+	}
+	step := p.scenario.Steps[p.nextStep]
+	p.nextStep++
+
+	ue := p.ues[step.Address]
+	srcCell := p.cells[ue.CellId]
+	trgCell := p.cells[step.CellId]
+	ue.CellId = trgCell.CellId
+	ue.Latitude, ue.Longitude = trgCell.Latitude, trgCell.Longitude
+	if step.Latitude != nil {
+		ue.Latitude = *step.Latitude
+	}
+	if step.Longitude != nil {
+		ue.Longitude = *step.Longitude
+	}
+	event := platsvc.CellChangeEvent{Ue: copyUe(ue), SrcCell: srcCell, TrgCell: trgCell, TimeStamp: time.Now()}
+	handler := p.handler
+	p.mu.Unlock()
+
+	if handler != nil && srcCell.CellId != trgCell.CellId {
+		handler(event)
+	}
+	return true
+}
+
+// Stop stops replaying the scenario steps
+func (p *Provider) Stop() {
+	if p.stopTimer != nil {
+		close(p.stopTimer)
+		p.stopTimer = nil
+	}
+}
+
+// GetPlmn returns the scenario plmn
+func (p *Provider) GetPlmn() (plmn platsvc.Plmn, err error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.scenario == nil {
+		return platsvc.Plmn{}, errors.New("scenario not loaded")
+	}
+	return p.scenario.Plmn, nil
+}
+
+// GetCells returns the scenario cells
+func (p *Provider) GetCells() (cells []platsvc.Cell, err error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.scenario == nil {
+		return nil, errors.New("scenario not loaded")
+	}
+	cells = make([]platsvc.Cell, 0, len(p.scenario.Cells))
+	cells = append(cells, p.scenario.Cells...)
+	return cells, nil
+}
+
+// GetUserEquipments returns the current state of all the user equipments
+func (p *Provider) GetUserEquipments() (ues []platsvc.UserEquipment, err error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.scenario == nil {
+		return nil, errors.New("scenario not loaded")
+	}
+	ues = make([]platsvc.UserEquipment, 0, len(p.ues))
+	for _, scenarioUe := range p.scenario.Ues {
+		ues = append(ues, copyUe(p.ues[scenarioUe.Address]))
+	}
+	return ues, nil
+}
+
+// SetEventHandler set the handler which receives the cell change events
+func (p *Provider) SetEventHandler(handler platsvc.EventHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.handler = handler
+}
+
+func copyUe(ue *platsvc.UserEquipment) platsvc.UserEquipment {
+	ueCopy := *ue
+	ueCopy.Erabs = append([]platsvc.Erab(nil), ue.Erabs...)
+	return ueCopy
+}
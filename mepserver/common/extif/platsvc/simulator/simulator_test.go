@@ -0,0 +1,104 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simulator
+
+import (
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/extif/platsvc"
+)
+
+const scenarioYaml = `
+interval: 1
+loop: true
+plmn:
+  mcc: "460"
+  mnc: "01"
+cells:
+  - cellId: "000a001"
+    zoneId: zone01
+    accessPointId: ap01
+  - cellId: "000b001"
+    zoneId: zone02
+    accessPointId: ap02
+    latitude: 30.5
+    longitude: 104.1
+ues:
+  - address: 10.100.0.1
+    cellId: "000a001"
+steps:
+  - address: 10.100.0.1
+    cellId: "000b001"
+`
+
+func loadTestScenario(t *testing.T, data string) (*Provider, error) {
+	var scenario Scenario
+	if err := yaml.Unmarshal([]byte(data), &scenario); err != nil {
+		assert.Fail(t, err.Error())
+	}
+	provider := &Provider{}
+	return provider, provider.applyScenario(&scenario)
+}
+
+func TestScenarioStepRaisesCellChange(t *testing.T) {
+	provider, err := loadTestScenario(t, scenarioYaml)
+	assert.NoError(t, err)
+
+	var events []platsvc.CellChangeEvent
+	provider.SetEventHandler(func(event platsvc.CellChangeEvent) {
+		events = append(events, event)
+	})
+
+	assert.True(t, provider.advance())
+	assert.Equal(t, 1, len(events))
+	assert.Equal(t, "000a001", events[0].SrcCell.CellId)
+	assert.Equal(t, "000b001", events[0].TrgCell.CellId)
+
+	ues, err := provider.GetUserEquipments()
+	assert.NoError(t, err)
+	assert.Equal(t, "000b001", ues[0].CellId)
+	assert.Equal(t, float32(30.5), ues[0].Latitude)
+
+	// loop restarts with the same step, the user equipment stays on the cell hence no event
+	assert.True(t, provider.advance())
+	assert.Equal(t, 1, len(events))
+}
+
+func TestScenarioWithoutLoopCompletes(t *testing.T) {
+	provider, err := loadTestScenario(t, scenarioYaml+"\n")
+	assert.NoError(t, err)
+	provider.scenario.Loop = false
+
+	assert.True(t, provider.advance())
+	assert.False(t, provider.advance())
+}
+
+func TestScenarioUnknownCell(t *testing.T) {
+	_, err := loadTestScenario(t, `
+cells:
+  - cellId: "000a001"
+    zoneId: zone01
+    accessPointId: ap01
+ues:
+  - address: 10.100.0.1
+    cellId: "000c001"
+`)
+	assert.Error(t, err)
+}
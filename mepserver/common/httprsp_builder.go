@@ -33,7 +33,9 @@ import (
 	"github.com/apache/servicecomb-service-center/server/rest/controller"
 
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
 	"mepserver/common/util"
+	"tracing"
 )
 
 // SendHttpRsp holds the http response building parameters
@@ -43,6 +45,7 @@ type SendHttpRsp struct {
 	workspace.TaskBase
 	W          http.ResponseWriter `json:"w,in"`
 	HttpRsp    interface{}         `json:"httpRsp,in"`
+	HttpStatus int                 `json:"httpStatus,in"`
 	StatusCode int
 }
 
@@ -66,11 +69,15 @@ func (t *SendHttpRsp) OnRequest(data string) workspace.TaskCode {
 		log.Infof(failureEventLogFormat, util.GetClientIp(t.R), util.GetAppInstanceId(t.R), util.GetMethodFromReq(t.R),
 			util.GetHttpResourceInfo(t.R), body.Detail)
 		util.HttpErrResponse(t.W, util.RemoteServerErr, body)
+		audit.Finish(t.R, util.RemoteServerErr, body.Detail, nil)
+		tracing.Finish(t.R, util.RemoteServerErr)
 		return workspace.TaskFinish
 	}
 
 	if errInfo.ErrCode == util.SerErrServiceNotFound && strings.EqualFold(errInfo.Message, "failed to find the instance") {
 		t.writeResponse(t.W, t.HttpErrInf, make([]*models.ServiceInfo, 0))
+		audit.Finish(t.R, t.StatusCode, errInfo.Message, nil)
+		tracing.Finish(t.R, t.StatusCode)
 		return workspace.TaskFinish
 	}
 
@@ -79,11 +86,15 @@ func (t *SendHttpRsp) OnRequest(data string) workspace.TaskCode {
 		util.HttpErrResponse(t.W, statusCode, httpBody)
 		log.Infof(failureEventLogFormat, util.GetClientIp(t.R), util.GetAppInstanceId(t.R), util.GetMethodFromReq(t.R),
 			util.GetHttpResourceInfo(t.R), errInfo.Message)
+		audit.Finish(t.R, statusCode, errInfo.Message, nil)
+		tracing.Finish(t.R, statusCode)
 		return workspace.TaskFinish
 	}
 	log.Infof(successEventLogFormat, util.GetClientIp(t.R), util.GetAppInstanceId(t.R), util.GetMethodFromReq(t.R),
 		util.GetHttpResourceInfo(t.R))
 	t.writeResponse(t.W, t.HttpErrInf, t.HttpRsp)
+	audit.Finish(t.R, t.StatusCode, "", t.HttpRsp)
+	tracing.Finish(t.R, t.StatusCode)
 	return workspace.TaskFinish
 }
 
@@ -93,6 +104,7 @@ func (t *SendHttpRsp) writeResponse(w http.ResponseWriter, resp *proto.Response,
 		return
 	}
 	if obj == nil {
+		t.StatusCode = http.StatusExpectationFailed
 		w.Header().Set(rest.HEADER_RESPONSE_STATUS, strconv.Itoa(http.StatusExpectationFailed))
 		w.Header().Set(rest.HEADER_CONTENT_TYPE, rest.CONTENT_TYPE_JSON)
 		w.WriteHeader(http.StatusExpectationFailed)
@@ -105,6 +117,10 @@ func (t *SendHttpRsp) writeResponse(w http.ResponseWriter, resp *proto.Response,
 		return
 	}
 	w.Header().Set(rest.HEADER_CONTENT_TYPE, rest.CONTENT_TYPE_JSON)
+	// the success status set by the steps overrides the one of the plan
+	if t.HttpStatus != 0 {
+		t.StatusCode = t.HttpStatus
+	}
 	if t.StatusCode == 0 {
 		t.StatusCode = http.StatusOK
 	}
@@ -124,6 +140,9 @@ func (t *SendHttpRsp) cvtHttpErrInfo(errInfo *workspace.SerErrInfo) (int, interf
 		Status: uint32(errInfo.ErrCode),
 		Detail: errInfo.Message,
 	}
+	for _, param := range errInfo.InvalidParams {
+		body.InvalidParams = append(body.InvalidParams, models.InvalidParam{Param: param.Param, Reason: param.Reason})
+	}
 	switch workspace.ErrCode(errInfo.ErrCode) {
 	case util.SerErrServiceNotFound:
 		body.Title = "Can not found resource"
@@ -162,6 +181,9 @@ func (t *SendHttpRsp) cvtHttpErrInfo(errInfo *workspace.SerErrInfo) (int, interf
 	case util.ForbiddenOperation:
 		statusCode = http.StatusForbidden
 		body.Title = "Operation Not Allowed"
+	case util.TrafficRuleConflict:
+		statusCode = http.StatusConflict
+		body.Title = "Traffic rule conflict"
 
 	default:
 		body.Title = "Bad Request"
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"context"
+
+	"mepserver/common/config"
+	"mepserver/common/extif/dataplane"
+	"mepserver/common/extif/dns"
+)
+
+// instrumentedDataPlane counts the failed calls of the data-plane
+type instrumentedDataPlane struct {
+	dataplane.DataPlane
+}
+
+// InstrumentDataPlane wraps the data-plane to count the failed calls
+func InstrumentDataPlane(dataPlane dataplane.DataPlane) dataplane.DataPlane {
+	if dataPlane == nil {
+		return nil
+	}
+	return &instrumentedDataPlane{DataPlane: dataPlane}
+}
+
+func countDataPlaneError(operation string, err error) error {
+	if err != nil {
+		dataPlaneErrors.WithLabelValues(operation).Inc()
+	}
+	return err
+}
+
+// InitDataPlane initializes the data-plane
+func (d *instrumentedDataPlane) InitDataPlane(config *config.MepServerConfig) error {
+	return countDataPlaneError("init", d.DataPlane.InitDataPlane(config))
+}
+
+// AddTrafficRule adds the traffic rule
+func (d *instrumentedDataPlane) AddTrafficRule(appInfo dataplane.ApplicationInfo, trafficRuleId, filterType,
+	action string, priority int, filter []dataplane.TrafficFilter) error {
+	return countDataPlaneError("add_traffic_rule",
+		d.DataPlane.AddTrafficRule(appInfo, trafficRuleId, filterType, action, priority, filter))
+}
+
+// SetTrafficRule updates the traffic rule
+func (d *instrumentedDataPlane) SetTrafficRule(appInfo dataplane.ApplicationInfo, trafficRuleId, filterType,
+	action string, priority int, filter []dataplane.TrafficFilter) error {
+	return countDataPlaneError("set_traffic_rule",
+		d.DataPlane.SetTrafficRule(appInfo, trafficRuleId, filterType, action, priority, filter))
+}
+
+// DeleteTrafficRule deletes the traffic rule
+func (d *instrumentedDataPlane) DeleteTrafficRule(appInfo dataplane.ApplicationInfo, trafficRuleId string) error {
+	return countDataPlaneError("delete_traffic_rule", d.DataPlane.DeleteTrafficRule(appInfo, trafficRuleId))
+}
+
+// AddDNSRule adds the dns rule
+func (d *instrumentedDataPlane) AddDNSRule(appInfo dataplane.ApplicationInfo, dnsRuleId, domainName, ipAddressType,
+	ipAddress string, ttl uint32) error {
+	return countDataPlaneError("add_dns_rule",
+		d.DataPlane.AddDNSRule(appInfo, dnsRuleId, domainName, ipAddressType, ipAddress, ttl))
+}
+
+// SetDNSRule updates the dns rule
+func (d *instrumentedDataPlane) SetDNSRule(appInfo dataplane.ApplicationInfo, dnsRuleId, domainName, ipAddressType,
+	ipAddress string, ttl uint32) error {
+	return countDataPlaneError("set_dns_rule",
+		d.DataPlane.SetDNSRule(appInfo, dnsRuleId, domainName, ipAddressType, ipAddress, ttl))
+}
+
+// DeleteDNSRule deletes the dns rule
+func (d *instrumentedDataPlane) DeleteDNSRule(appInfo dataplane.ApplicationInfo, dnsRuleId string) error {
+	return countDataPlaneError("delete_dns_rule", d.DataPlane.DeleteDNSRule(appInfo, dnsRuleId))
+}
+
+// instrumentedDNSAgent counts the failed calls of the dns agent
+type instrumentedDNSAgent struct {
+	dns.DNSAgent
+}
+
+// InstrumentDNSAgent wraps the dns agent to count the failed calls
+func InstrumentDNSAgent(dnsAgent dns.DNSAgent) dns.DNSAgent {
+	if dnsAgent == nil {
+		return nil
+	}
+	return &instrumentedDNSAgent{DNSAgent: dnsAgent}
+}
+
+// WithContext keeps the instrumentation on the agent sending the requests in the context
+func (d *instrumentedDNSAgent) WithContext(ctx context.Context) dns.DNSAgent {
+	return &instrumentedDNSAgent{DNSAgent: dns.WithContext(d.DNSAgent, ctx)}
+}
+
+// WithZone keeps the instrumentation on the agent managing the records in the zone
+func (d *instrumentedDNSAgent) WithZone(zone string) dns.DNSAgent {
+	return &instrumentedDNSAgent{DNSAgent: dns.WithZone(d.DNSAgent, zone)}
+}
+
+// WithView keeps the instrumentation on the agent managing the records of the view
+func (d *instrumentedDNSAgent) WithView(view string, subnets []string) dns.DNSAgent {
+	if viewAgent, ok := d.DNSAgent.(dns.ViewDNSAgent); ok {
+		return &instrumentedDNSAgent{DNSAgent: viewAgent.WithView(view, subnets)}
+	}
+	return d
+}
+
+func countDNSAgentError(operation string, err error) error {
+	if err != nil {
+		dnsAgentErrors.WithLabelValues(operation).Inc()
+	}
+	return err
+}
+
+// AddResourceRecord adds the resource record
+func (d *instrumentedDNSAgent) AddResourceRecord(host, rrType, class string, pointTo []string, ttl uint32) error {
+	return countDNSAgentError("add_resource_record", d.DNSAgent.AddResourceRecord(host, rrType, class, pointTo, ttl))
+}
+
+// SetResourceRecord updates the resource record
+func (d *instrumentedDNSAgent) SetResourceRecord(host, rrType, class string, pointTo []string, ttl uint32) error {
+	return countDNSAgentError("set_resource_record", d.DNSAgent.SetResourceRecord(host, rrType, class, pointTo, ttl))
+}
+
+// DeleteResourceRecord deletes the resource record
+func (d *instrumentedDNSAgent) DeleteResourceRecord(host, rrType string) error {
+	return countDNSAgentError("delete_resource_record", d.DNSAgent.DeleteResourceRecord(host, rrType))
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package metrics implements the prometheus metrics of the mep server subsystems. The metrics are registered to the
+// default prometheus registry which is exposed by the service center on the /metrics endpoint
+package metrics
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	meputil "mepserver/common/util"
+)
+
+const namespace = "mep"
+
+// Metric label values
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	NotificationServiceAvailability = "service_availability"
+	NotificationPlatformService     = "platform_service"
+
+	PhaseDNSRules     = "dns_rules"
+	PhaseTrafficRules = "traffic_rules"
+	PhaseConfigWrite  = "config_write"
+	PhaseInit         = "init"
+)
+
+var (
+	heartbeatMisses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "heartbeat",
+		Name:      "misses_total",
+		Help:      "Number of service instances suspended on missing the heartbeat",
+	})
+
+	notifications = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "notification",
+		Name:      "deliveries_total",
+		Help:      "Number of notification deliveries to the subscribers by result",
+	}, []string{"type", "result"})
+
+	notificationDurations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "notification",
+		Name:      "delivery_duration_seconds",
+		Help:      "Notification delivery latency to the subscribers",
+	}, []string{"type"})
+
+	appDSyncDurations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "appd_sync",
+		Name:      "duration_seconds",
+		Help:      "Duration of the appd configuration sync tasks by result",
+	}, []string{"result"})
+
+	appDSyncFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "appd_sync",
+		Name:      "failures_total",
+		Help:      "Number of failed appd configuration sync tasks by the failed phase",
+	}, []string{"phase"})
+
+	dataPlaneErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "dataplane",
+		Name:      "call_errors_total",
+		Help:      "Number of failed data-plane calls by operation",
+	}, []string{"operation"})
+
+	dnsAgentErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "dns_agent",
+		Name:      "call_errors_total",
+		Help:      "Number of failed dns agent calls by operation",
+	}, []string{"operation"})
+
+	ntpQueryDurations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "ntp",
+		Name:      "query_duration_seconds",
+		Help:      "NTP server query latency by result",
+	}, []string{"result"})
+)
+
+func init() {
+	prometheus.MustRegister(heartbeatMisses, notifications, notificationDurations, appDSyncDurations,
+		appDSyncFailures, dataPlaneErrors, dnsAgentErrors, ntpQueryDurations, &serviceStateCollector{})
+}
+
+func resultOf(err error) string {
+	if err != nil {
+		return ResultFailure
+	}
+	return ResultSuccess
+}
+
+// HeartbeatMissed counts a service instance suspended on missing the heartbeat
+func HeartbeatMissed() {
+	heartbeatMisses.Inc()
+}
+
+// ObserveNotification records the result and the latency of a notification delivery started at the start time
+func ObserveNotification(notificationType string, start time.Time, err error) {
+	notifications.WithLabelValues(notificationType, resultOf(err)).Inc()
+	notificationDurations.WithLabelValues(notificationType).Observe(time.Since(start).Seconds())
+}
+
+// ObserveAppDSync records the duration of an appd sync task started at the start time, empty failed phase means the
+// task succeeded
+func ObserveAppDSync(start time.Time, failedPhase string) {
+	if len(failedPhase) == 0 {
+		appDSyncDurations.WithLabelValues(ResultSuccess).Observe(time.Since(start).Seconds())
+		return
+	}
+	appDSyncDurations.WithLabelValues(ResultFailure).Observe(time.Since(start).Seconds())
+	appDSyncFailures.WithLabelValues(failedPhase).Inc()
+}
+
+// ObserveNtpQuery records the latency of a ntp query started at the start time
+func ObserveNtpQuery(start time.Time, err error) {
+	ntpQueryDurations.WithLabelValues(resultOf(err)).Observe(time.Since(start).Seconds())
+}
+
+// serviceStateCollector reports the number of the registered service instances by state on every scrape
+type serviceStateCollector struct {
+}
+
+var serviceStateDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "services", "registered"),
+	"Number of the registered service instances by state", []string{"state"}, nil)
+
+// Describe sends the descriptor of the service state metric
+func (c *serviceStateCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- serviceStateDesc
+}
+
+// Collect counts the service instances by state from the service registry
+func (c *serviceStateCollector) Collect(ch chan<- prometheus.Metric) {
+	counts := map[string]int{meputil.ActiveState: 0, meputil.InactiveState: 0, meputil.SuspendedState: 0}
+	if resp, err := meputil.FindInstanceByKey(url.Values{}); err == nil {
+		for _, instance := range resp.Instances {
+			if _, ok := counts[instance.Properties["mecState"]]; ok {
+				counts[instance.Properties["mecState"]]++
+			}
+		}
+	}
+	for state, count := range counts {
+		ch <- prometheus.MustNewConstMetric(serviceStateDesc, prometheus.GaugeValue, float64(count), state)
+	}
+}
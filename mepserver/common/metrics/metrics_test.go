@@ -0,0 +1,102 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package metrics
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey"
+	"github.com/apache/servicecomb-service-center/server/core/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/extif/dataplane"
+	"mepserver/common/extif/dataplane/none"
+	meputil "mepserver/common/util"
+)
+
+func readValue(t *testing.T, metric prometheus.Metric) float64 {
+	m := &dto.Metric{}
+	assert.NoError(t, metric.Write(m))
+	switch {
+	case m.Counter != nil:
+		return m.Counter.GetValue()
+	case m.Gauge != nil:
+		return m.Gauge.GetValue()
+	case m.Histogram != nil:
+		return float64(m.Histogram.GetSampleCount())
+	}
+	return 0
+}
+
+type failingDataPlane struct {
+	none.NoneDataPlane
+}
+
+func (f *failingDataPlane) DeleteDNSRule(appInfo dataplane.ApplicationInfo, dnsRuleId string) error {
+	return errors.New("data-plane unreachable")
+}
+
+func TestInstrumentDataPlane(t *testing.T) {
+	assert.Nil(t, InstrumentDataPlane(nil))
+	assert.Nil(t, InstrumentDNSAgent(nil))
+
+	counter := dataPlaneErrors.WithLabelValues("delete_dns_rule").(prometheus.Metric)
+	before := readValue(t, counter)
+	dataPlane := InstrumentDataPlane(&failingDataPlane{})
+	assert.NoError(t, dataPlane.AddDNSRule(dataplane.ApplicationInfo{}, "rule", "www.example.com", "A",
+		"10.1.1.1", 30))
+	assert.Error(t, dataPlane.DeleteDNSRule(dataplane.ApplicationInfo{}, "rule"))
+	assert.Equal(t, before+1, readValue(t, counter))
+}
+
+func TestObserveAppDSync(t *testing.T) {
+	failures := appDSyncFailures.WithLabelValues(PhaseTrafficRules).(prometheus.Metric)
+	before := readValue(t, failures)
+	ObserveAppDSync(time.Now(), "")
+	ObserveAppDSync(time.Now(), PhaseTrafficRules)
+	assert.Equal(t, before+1, readValue(t, failures))
+}
+
+func TestServiceStateCollector(t *testing.T) {
+	patches := gomonkey.ApplyFunc(meputil.FindInstanceByKey, func(result url.Values) (*proto.FindInstancesResponse,
+		error) {
+		return &proto.FindInstancesResponse{Instances: []*proto.MicroServiceInstance{
+			{Properties: map[string]string{"mecState": meputil.ActiveState}},
+			{Properties: map[string]string{"mecState": meputil.ActiveState}},
+			{Properties: map[string]string{"mecState": meputil.SuspendedState}},
+			{Properties: map[string]string{}},
+		}}, nil
+	})
+	defer patches.Reset()
+
+	ch := make(chan prometheus.Metric, 3)
+	(&serviceStateCollector{}).Collect(ch)
+	close(ch)
+	counts := make(map[string]float64)
+	for metric := range ch {
+		m := &dto.Metric{}
+		assert.NoError(t, metric.Write(m))
+		counts[m.Label[0].GetValue()] = m.Gauge.GetValue()
+	}
+	assert.Equal(t, map[string]float64{meputil.ActiveState: 2, meputil.InactiveState: 0,
+		meputil.SuspendedState: 1}, counts)
+}
@@ -45,6 +45,8 @@ type RuleStatus struct {
 	Id     string                 `json:"id"`
 	State  meputil.AppDRuleStatus `json:"state"`  //One of INIT, MP2_OK, LOCAL_OK, DB_OK
 	Method meputil.OperType       `json:"method"` // Outgoing request method
+	// FailedServers the secondary dns servers failed in applying the dns rule
+	FailedServers []string `json:"failedServers,omitempty"`
 }
 
 // TaskProgress response model
@@ -0,0 +1,110 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package models implements mep server object models
+package models
+
+// User tracking event types
+const (
+	UserEventEntering     = "Entering"
+	UserEventLeaving      = "Leaving"
+	UserEventTransferring = "Transferring"
+)
+
+// LocationInfo geographical location of an user
+type LocationInfo struct {
+	Latitude  []float32 `json:"latitude"`
+	Longitude []float32 `json:"longitude"`
+	Shape     int       `json:"shape"`
+}
+
+// UserInfo location of one user
+type UserInfo struct {
+	Address       string       `json:"address"`
+	AccessPointId string       `json:"accessPointId"`
+	ZoneId        string       `json:"zoneId"`
+	ResourceURL   string       `json:"resourceURL"`
+	LocationInfo  LocationInfo `json:"locationInfo"`
+	TimeStamp     NtpTimeStamp `json:"timeStamp"`
+}
+
+// UserList user location query response
+type UserList struct {
+	User        []UserInfo `json:"user"`
+	ResourceURL string     `json:"resourceURL"`
+}
+
+// UserListResponse wraps the user location query response
+type UserListResponse struct {
+	UserList UserList `json:"userList"`
+}
+
+// ZoneInfo information of one zone
+type ZoneInfo struct {
+	ZoneId                  string `json:"zoneId"`
+	NumberOfAccessPoints    int    `json:"numberOfAccessPoints"`
+	NumberOfUnserviceableAP int    `json:"numberOfUnserviceableAccessPoints"`
+	NumberOfUsers           int    `json:"numberOfUsers"`
+	ResourceURL             string `json:"resourceURL"`
+}
+
+// ZoneList zone query response
+type ZoneList struct {
+	Zone        []ZoneInfo `json:"zone"`
+	ResourceURL string     `json:"resourceURL"`
+}
+
+// ZoneListResponse wraps the zone query response
+type ZoneListResponse struct {
+	ZoneList ZoneList `json:"zoneList"`
+}
+
+// CallbackReference notification target of a location subscription
+type CallbackReference struct {
+	NotifyURL    string `json:"notifyURL" validate:"required,uri"`
+	CallbackData string `json:"callbackData,omitempty" validate:"omitempty,max=256"`
+}
+
+// UserTrackingSubscription represents a subscription to the zone changes of an user
+type UserTrackingSubscription struct {
+	ClientCorrelator  string            `json:"clientCorrelator,omitempty" validate:"omitempty,max=128"`
+	CallbackReference CallbackReference `json:"callbackReference" validate:"required"`
+	Address           string            `json:"address" validate:"required,ip"`
+	UserEventCriteria []string          `json:"userEventCriteria,omitempty" validate:"omitempty,dive,oneof=Entering Leaving Transferring"`
+	ResourceURL       string            `json:"resourceURL,omitempty"`
+}
+
+// UserTrackingSubscriptionBody wraps the user tracking subscription request and response
+type UserTrackingSubscriptionBody struct {
+	UserTrackingSubscription UserTrackingSubscription `json:"userTrackingSubscription" validate:"required"`
+}
+
+// ZonalPresenceNotification sent to the user tracking subscribers on zone changes
+type ZonalPresenceNotification struct {
+	CallbackData          string       `json:"callbackData,omitempty"`
+	ZoneId                string       `json:"zoneId"`
+	Address               string       `json:"address"`
+	UserEventType         string       `json:"userEventType"`
+	CurrentAccessPointId  string       `json:"currentAccessPointId"`
+	PreviousAccessPointId string       `json:"previousAccessPointId,omitempty"`
+	TimeStamp             NtpTimeStamp `json:"timestamp"`
+	Link                  SerLinkType  `json:"link"`
+}
+
+// ZonalPresenceNotificationBody wraps the zonal presence notification
+type ZonalPresenceNotificationBody struct {
+	ZonalPresenceNotification ZonalPresenceNotification `json:"zonalPresenceNotification"`
+}
@@ -29,6 +29,14 @@ type ProblemDetails struct {
 	Status   uint32 `json:"status,omitempty"`
 	Detail   string `json:"detail,omitempty"`
 	Instance string `json:"instance,omitempty"`
+	// InvalidParams holds the details of each invalid request parameter
+	InvalidParams []InvalidParam `json:"invalidParams,omitempty"`
+}
+
+// InvalidParam holds the name and the failure reason of an invalid request parameter
+type InvalidParam struct {
+	Param  string `json:"param"`
+	Reason string `json:"reason,omitempty"`
 }
 
 // String generates the failure response body string
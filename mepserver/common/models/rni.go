@@ -0,0 +1,110 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package models implements mep server object models
+package models
+
+// AssociateIdUeIpv4 associate id type for the user equipment ipv4 address
+const AssociateIdUeIpv4 = 1
+
+// AssociateIdUeIpv6 associate id type for the user equipment ipv6 address
+const AssociateIdUeIpv6 = 2
+
+// HoStatusCompleted handover status completed
+const HoStatusCompleted = 3
+
+// Plmn public land mobile network identity
+type Plmn struct {
+	Mcc string `json:"mcc"`
+	Mnc string `json:"mnc"`
+}
+
+// Ecgi E-UTRAN cell global identifier
+type Ecgi struct {
+	CellId string `json:"cellId" validate:"required,len=7,hexadecimal"`
+	Plmn   Plmn   `json:"plmn"`
+}
+
+// AssociateId identifies the user equipment
+type AssociateId struct {
+	Type  int    `json:"type" validate:"oneof=1 2"`
+	Value string `json:"value" validate:"required,ip"`
+}
+
+// ErabQosParameters E-UTRAN radio access bearer qos parameters
+type ErabQosParameters struct {
+	Qci int `json:"qci"`
+}
+
+// ErabInfo E-UTRAN radio access bearer information
+type ErabInfo struct {
+	ErabId            int               `json:"erabId"`
+	ErabQosParameters ErabQosParameters `json:"erabQosParameters"`
+}
+
+// UeInfo holds the bearers of one user equipment
+type UeInfo struct {
+	AssociateId []AssociateId `json:"associateId"`
+	ErabInfo    []ErabInfo    `json:"erabInfo"`
+}
+
+// CellUserInfo holds the user equipments of one cell
+type CellUserInfo struct {
+	Ecgi   Ecgi     `json:"ecgi"`
+	UeInfo []UeInfo `json:"ueInfo"`
+}
+
+// RabInfo radio access bearer query response
+type RabInfo struct {
+	AppInstanceId string         `json:"appInstanceId"`
+	RequestId     string         `json:"requestId,omitempty"`
+	CellUserInfo  []CellUserInfo `json:"cellUserInfo"`
+	TimeStamp     NtpTimeStamp   `json:"timeStamp"`
+}
+
+// PlmnInfo plmn query response
+type PlmnInfo struct {
+	AppInstanceId string       `json:"appInstanceId"`
+	Plmn          []Plmn       `json:"plmn"`
+	TimeStamp     NtpTimeStamp `json:"timeStamp"`
+}
+
+// FilterCriteriaAssocHo filtering criteria of the cell change subscription
+type FilterCriteriaAssocHo struct {
+	AppInstanceId string        `json:"appInstanceId,omitempty"`
+	AssociateId   []AssociateId `json:"associateId,omitempty" validate:"omitempty,dive"`
+	Ecgi          []Ecgi        `json:"ecgi,omitempty" validate:"omitempty,dive"`
+	HoStatus      []int         `json:"hoStatus,omitempty" validate:"omitempty,dive,min=1,max=3"`
+}
+
+// CellChangeSubscription represents a subscription to the cell change notifications of the user equipments
+type CellChangeSubscription struct {
+	SubscriptionType      string                `json:"subscriptionType" validate:"required,eq=CellChangeSubscription"`
+	CallbackReference     string                `json:"callbackReference" validate:"required,uri"`
+	Links                 Links                 `json:"_links,omitempty"`
+	FilterCriteriaAssocHo FilterCriteriaAssocHo `json:"filterCriteriaAssocHo"`
+}
+
+// CellChangeNotification sent to the subscribers when an user equipment is handed over to another cell
+type CellChangeNotification struct {
+	NotificationType string          `json:"notificationType"`
+	TimeStamp        NtpTimeStamp    `json:"timeStamp"`
+	HoStatus         int             `json:"hoStatus"`
+	SrcEcgi          Ecgi            `json:"srcEcgi"`
+	TrgEcgi          []Ecgi          `json:"trgEcgi"`
+	AssociateId      []AssociateId   `json:"associateId"`
+	Links            SerSubscription `json:"_links"`
+}
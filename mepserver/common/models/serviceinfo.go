@@ -29,6 +29,7 @@ import (
 	"github.com/apache/servicecomb-service-center/pkg/log"
 	"github.com/apache/servicecomb-service-center/server/core/proto"
 
+	"mepserver/common/extif/apigw"
 	meputil "mepserver/common/util"
 )
 
@@ -113,7 +114,7 @@ func (s *ServiceInfo) GenerateRegisterInstance(req *proto.RegisterInstanceReques
 		meputil.UpdatePropertiesMap(properties, "timestamp/nanoseconds", secNanoSec[len(secNanoSec)/2+1:])
 		req.Instance.HostName = "default"
 		var epType string
-		req.Instance.Endpoints, epType = s.registerEndpoints(isUpdateReq, apiGwSerName)
+		req.Instance.Endpoints, epType = s.registerEndpoints(isUpdateReq, apiGwSerName, properties)
 		req.Instance.Properties["endPointType"] = epType
 
 		healthCheck := &proto.HealthCheck{
@@ -130,7 +131,8 @@ func (s *ServiceInfo) GenerateRegisterInstance(req *proto.RegisterInstanceReques
 	}
 }
 
-func (s *ServiceInfo) registerEndpoints(isUpdateReq bool, apiGwSerName string) ([]string, string) {
+func (s *ServiceInfo) registerEndpoints(isUpdateReq bool, apiGwSerName string, properties map[string]string) ([]string,
+	string) {
 	if len(s.TransportInfo.Endpoint.Uris) != 0 {
 		var serviceUris []string
 		_, apiGwServiceName := s.generateServiceIdAndName()
@@ -140,7 +142,7 @@ func (s *ServiceInfo) registerEndpoints(isUpdateReq bool, apiGwSerName string) (
 
 		for _, uri := range s.TransportInfo.Endpoint.Uris {
 			serviceUris = append(serviceUris, fmt.Sprintf(serviceGatewayURIFormatString, apiGwServiceName))
-			s.registerToApiGw(uri, apiGwServiceName, isUpdateReq)
+			s.recordApiGwService(properties, apiGwServiceName, uri)
 		}
 		return serviceUris, meputil.Uris
 	}
@@ -154,7 +156,7 @@ func (s *ServiceInfo) registerEndpoints(isUpdateReq bool, apiGwSerName string) (
 				apiGwServiceName = apiGwSerName
 			}
 			serviceUris = append(serviceUris, fmt.Sprintf(serviceGatewayURIFormatString, apiGwServiceName))
-			s.registerToApiGw(gwUri, apiGwServiceName, isUpdateReq)
+			s.recordApiGwService(properties, apiGwServiceName, gwUri)
 		}
 		return serviceUris, meputil.Uris
 	}
@@ -170,6 +172,12 @@ func (s *ServiceInfo) registerEndpoints(isUpdateReq bool, apiGwSerName string) (
 	}
 	return nil, ""
 }
+
+// GatewayURI generates the api gateway uri of the given mep server path
+func GatewayURI(path string) string {
+	return fmt.Sprintf(serviceGatewayURIFormatString, strings.TrimPrefix(path, "/"))
+}
+
 func (s *ServiceInfo) generateServiceIdAndName() (string, string) {
 	serviceId := util.GenerateUuid()[0:20]
 	return serviceId, s.SerName + serviceId
@@ -239,17 +247,11 @@ func (s *ServiceInfo) FromServiceInstance(inst *proto.MicroServiceInstance) {
 	s.transportInfoFromProperties(inst.Properties)
 }
 
-func (s *ServiceInfo) registerToApiGw(uri string, serviceName string, isUpdateReq bool) {
-	log.Infof("API gateway registration for new service(name: %s, uri: %s).", serviceName, uri)
-	serInfo := meputil.SerInfo{
-		SerName: serviceName,
-		Uri:     uri,
-	}
-	meputil.ApiGWInterface.AddOrUpdateApiGwService(serInfo)
-	meputil.ApiGWInterface.AddOrUpdateApiGwRoute(serInfo)
-	if !isUpdateReq {
-		meputil.ApiGWInterface.EnableJwtPlugin(serInfo)
-	}
+// recordApiGwService records the api gateway service in the instance properties, the registration plans and the api
+// gateway reconciler register the recorded services on the api gateway
+func (s *ServiceInfo) recordApiGwService(properties map[string]string, serviceName string, uri string) {
+	log.Infof("API gateway service recorded for the instance(name: %s, uri: %s).", serviceName, uri)
+	meputil.UpdatePropertiesMap(properties, apigw.PropertyPrefix+serviceName, uri)
 }
 
 func (s *ServiceInfo) serCategoryFromProperties(properties map[string]string) {
@@ -0,0 +1,63 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"mepserver/common/arch/workspace"
+	trace "tracing"
+)
+
+// enableStepTracing traces each workspace step in a span, the steps of the work spaces without trace context are
+// not traced
+func enableStepTracing() {
+	workspace.SetStepHook(traceStep)
+}
+
+func traceStep(wkSpace interface{}, stepIf workspace.TaskBaseIf) func() {
+	space, ok := wkSpace.(workspace.TraceContextIf)
+	if !ok {
+		return nil
+	}
+	ctx, span := trace.StartSpan(space.TraceContext(), "step "+stepName(stepIf), trace.SpanKindInternal)
+	if span == nil {
+		return nil
+	}
+	if step, ok := stepIf.(interface{ SetTraceContext(ctx context.Context) }); ok {
+		step.SetTraceContext(ctx)
+	}
+	return func() {
+		errCode, msg := stepIf.GetErrCode()
+		if errCode > workspace.TaskOK {
+			span.SetAttribute("mep.error_code", int(errCode))
+			span.End(errors.New(msg))
+			return
+		}
+		span.End(nil)
+	}
+}
+
+func stepName(stepIf workspace.TaskBaseIf) string {
+	stepType := reflect.TypeOf(stepIf)
+	if stepType.Kind() == reflect.Ptr {
+		stepType = stepType.Elem()
+	}
+	return stepType.Name()
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tracing configures the shared tracing of the mep server and traces the workspace steps
+package tracing
+
+import (
+	"context"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	"mepserver/common/config"
+	trace "tracing"
+)
+
+// DefaultServiceName service name reported in the spans if not configured
+const DefaultServiceName = "mepserver"
+
+// Init enables the tracing based on the mep server configurations, the workspace steps are traced from then on
+func Init(mepConfig *config.MepServerConfig) {
+	tracingConfig := mepConfig.Tracing
+	if !tracingConfig.Enabled {
+		log.Info("Tracing is disabled.")
+		return
+	}
+	if trace.Enabled() {
+		return
+	}
+	serviceName := tracingConfig.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = DefaultServiceName
+	}
+	sampleRatio := tracingConfig.SampleRatio
+	if sampleRatio == 0 {
+		sampleRatio = trace.DefaultSampleRatio
+	}
+	trace.SetLogger(log.Warnf)
+	trace.Start(trace.Options{ServiceName: serviceName, Endpoint: tracingConfig.Endpoint, SampleRatio: sampleRatio})
+	enableStepTracing()
+	log.Infof("Tracing is enabled(endpoint: %s, sample ratio: %g).", tracingConfig.Endpoint, sampleRatio)
+}
+
+// Shutdown exports the spans of the mep server not exported yet
+func Shutdown(ctx context.Context) {
+	if err := trace.Shutdown(ctx); err != nil {
+		log.Warnf("Tracing shutdown failed(%s), spans are dropped.", err.Error())
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"mepserver/common/arch/workspace"
+	trace "tracing"
+)
+
+type testSpace struct {
+	ctx context.Context
+}
+
+func (s *testSpace) TraceContext() context.Context {
+	return s.ctx
+}
+
+type testStep struct {
+	workspace.TaskBase
+}
+
+func (t *testStep) OnRequest(data string) workspace.TaskCode {
+	return workspace.TaskFinish
+}
+
+func TestTraceStep(t *testing.T) {
+	// the spans are not exported, the collector is unreachable
+	trace.Start(trace.Options{Endpoint: "http://127.0.0.1:1/v1/traces"})
+	defer func() {
+		_ = trace.Shutdown(context.Background())
+	}()
+
+	spaceCtx, parent := trace.StartSpan(context.Background(), "request", trace.SpanKindServer)
+	step := &testStep{}
+	done := traceStep(&testSpace{ctx: spaceCtx}, step)
+	assert.NotNil(t, done)
+	stepSpanCtx, ok := trace.SpanContextFromContext(step.TraceContext())
+	assert.True(t, ok)
+	assert.Equal(t, parent.SpanContext().TraceId, stepSpanCtx.TraceId)
+	assert.NotEqual(t, parent.SpanContext().SpanId, stepSpanCtx.SpanId)
+	assert.Equal(t, "testStep", stepName(step))
+	done()
+
+	assert.Nil(t, traceStep(struct{}{}, step))
+}
@@ -45,6 +45,7 @@ const (
 	DuplicateOperation          = 19
 	ForbiddenOperation          = 20
 	NtpConnectionErr            = 21
+	TrafficRuleConflict         = 22
 )
 
 // Mep server api paths
@@ -57,6 +58,8 @@ const (
 	MecPlatformConfigPath = "/mec_platform_config/v1"
 	MecAppDConfigPath     = "/app_lcm/v1"
 	MecServiceGovernPath  = "/service_govern/v1"
+	MecRniPath            = "/rni/v2"
+	MecLocationPath       = "/location/v2"
 
 	AppServicesPath     = RootPath + MecServicePath + "/applications/:appInstanceId" + ServicePath
 	AppSubscribePath    = RootPath + MecServicePath + "/applications/:appInstanceId/subscriptions"
@@ -67,21 +70,38 @@ const (
 	TimingPath          = RootPath + MecAppSupportPath + "/timing"
 	TransportPath       = RootPath + MecServicePath + "/transports"
 	ConfirmReadyPath    = RootPath + MecAppSupportPath + "/applications/:appInstanceId/confirm_ready"
+	AppTasksPath        = RootPath + MecAppSupportPath + "/applications/:appInstanceId/tasks"
+
+	RabInfoPath              = RootPath + MecRniPath + "/queries/rab_info"
+	PlmnInfoPath             = RootPath + MecRniPath + "/queries/plmn_info"
+	RniSubscribePath         = RootPath + MecRniPath + "/subscriptions"
+	LocationUsersPath        = RootPath + MecLocationPath + "/queries/users"
+	LocationZonesPath        = RootPath + MecLocationPath + "/queries/zones"
+	LocationUserTrackingPath = RootPath + MecLocationPath + "/subscriptions/userTracking"
 
 	CapabilityPath        = Mm5RootPath + MecPlatformConfigPath + "/capabilities"
 	AppDConfigPath        = Mm5RootPath + MecAppDConfigPath + "/applications/:appInstanceId/appd_configuration"
 	AppDQueryResPath      = Mm5RootPath + MecAppDConfigPath + "/tasks/:taskId/appd_configuration"
 	AppInsTerminationPath = RootPath + MecAppSupportPath + "/applications/:appInstanceId/AppInstanceTermination"
 
-	KongHttpLogPath        = RootPath + MecServiceGovernPath + "/kong_log"
-	SubscribeStatisticPath = RootPath + MecServiceGovernPath + "/subscribe_statistic"
-	GovernServicesPath     = RootPath + MecServiceGovernPath + ServicePath
+	KongHttpLogPath         = RootPath + MecServiceGovernPath + "/kong_log"
+	SubscribeStatisticPath  = RootPath + MecServiceGovernPath + "/subscribe_statistic"
+	GovernServicesPath      = RootPath + MecServiceGovernPath + ServicePath
+	AuditLogsPath           = RootPath + MecServiceGovernPath + "/audit_logs"
+	RateLimitsPath          = RootPath + MecServiceGovernPath + "/rate_limits"
+	AppRateLimitsPath       = RootPath + MecServiceGovernPath + "/applications/:appInstanceId/rate_limits"
+	AnalyticsUsagePath      = RootPath + MecServiceGovernPath + "/analytics/usage"
+	AnalyticsConsumersPath  = RootPath + MecServiceGovernPath + "/analytics/consumers"
+	AnalyticsCallMatrixPath = RootPath + MecServiceGovernPath + "/analytics/call_matrix"
+	EffectiveConfigPath     = Mm5RootPath + MecPlatformConfigPath + "/configuration"
 
 	DNSRuleIdPath      = "/:dnsRuleId"
 	TrafficRuleIdPath  = "/:trafficRuleId"
 	SubscriptionIdPath = "/:subscriptionId"
 	ServiceIdPath      = "/:serviceId"
 	CapabilityIdPath   = "/:capabilityId"
+	TaskIdPath         = "/:taskId"
+	SerNamePath        = "/:serName"
 	Liveness           = "/liveness"
 	CurrentTIme        = "/current_time"
 	TimingCaps         = "/timing_caps"
@@ -109,6 +129,10 @@ const (
 	AppDLCMTasksPath      = DBRootPath + "mep/applcm/tasks/"
 	AppDLCMTaskStatusPath = DBRootPath + "mep/applcm/taskstatus/"
 	TransportInfoPath     = DBRootPath + "transports/"
+	RniSubKeyPath         = DBRootPath + "rni-subscribe/"
+	LocationSubKeyPath    = DBRootPath + "location-subscribe/"
+	AuditLogKeyPath       = DBRootPath + "audit/"
+	RateLimitKeyPath      = DBRootPath + "ratelimits/"
 )
 
 const (
@@ -131,12 +155,15 @@ const SuccessRetCode = 0
 
 const SerAvailabilityNotificationSubscription string = "SerAvailabilityNotificationSubscription"
 const AppTerminationNotificationSubscription string = "AppTerminationNotificationSubscription"
+const CellChangeSubscription string = "CellChangeSubscription"
+const UserTrackingSubscription string = "UserTrackingSubscription"
 
 const RequestBodyLength = 4096
 const ServicesMaxCount = 50
 const AppSubscriptionCount = 50
 const ServerHeader = "Server"
 const JwtPlugin = "jwt"
+const AppidPlugin = "appid-header"
 
 const specialCharRegex string = `^.*['~!@#$%^&*()-_=+\|[{}\];:'",<.>/?].*$`
 const singleDigitRegex string = `^.*\d.*$`
@@ -154,6 +181,7 @@ const ComponentContent = "j7k0UwOJSsIfi3dzainoBdkcpJJJOJlzd2oBwMQxXdaZ3oCswITWUy
 
 const ErrorRequestBodyMessage = "request body invalid"
 const XRealIp = "X-Real-Ip"
+const XTaskId = "X-Task-Id"
 
 // MaxFQDNLength As per RFC-1035 section-2.3.4, the maximum length of full FQDN name is 255 octets including
 // one length and one null terminating character. Hence it is limited as 253.
@@ -192,6 +220,37 @@ const (
 	DataPlaneNone = "none"
 )
 
+// Platform service provider options
+const (
+	PlatformSvcProviderNone      = "none"
+	PlatformSvcProviderSimulator = "simulator"
+)
+
+// Api gateway options
+const (
+	ApiGatewayKong = "kong"
+	ApiGatewayNone = "none"
+
+	DefaultApiGwReconcileInterval = 60
+)
+
+// Api gateway http log store options
+const (
+	HttpLogStoreElasticsearch = "elasticsearch"
+	HttpLogStoreFile          = "file"
+	HttpLogStoreMemory        = "memory"
+
+	DefaultHttpLogEsUrl      = "http://mep-elasticsearch:9200"
+	DefaultHttpLogMaxRecords = 100000
+)
+
+// Built-in platform service names registered on the service registry
+const (
+	RniServiceName      = "RNI"
+	LocationServiceName = "Location"
+	PlatformSvcVersion  = "2.1.1"
+)
+
 // Dns agent options
 const (
 	DnsAgentTypeLocal     = "local"
@@ -250,6 +309,13 @@ const KongHttpLogIndex = "http-log"
 const WeekDay = 7
 
 const ApiGwCaCertName = "apigw_cacert"
+
+// app config names of the dns server management api certificates
+const (
+	DnsCaCertName     = "dns_cacert"
+	DnsClientCertName = "dns_client_cert"
+	DnsClientKeyName  = "dns_client_key"
+)
 const ConfigFilePath = "/usr/mep/conf/app.conf"
 
 const (
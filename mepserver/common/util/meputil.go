@@ -281,9 +281,14 @@ func ValidateRegexp(strToCheck string, regexStr string, errMsg string) error {
 // GetSubscribeKeyPath get subscribe key path
 func GetSubscribeKeyPath(subscribeType string) string {
 	var subscribeKeyPath string
-	if subscribeType == SerAvailabilityNotificationSubscription {
+	switch subscribeType {
+	case SerAvailabilityNotificationSubscription:
 		subscribeKeyPath = AvailAppSubKeyPath
-	} else {
+	case CellChangeSubscription:
+		subscribeKeyPath = RniSubKeyPath
+	case UserTrackingSubscription:
+		subscribeKeyPath = LocationSubKeyPath
+	default:
 		subscribeKeyPath = EndAppSubKeyPath
 	}
 	return subscribeKeyPath
@@ -24,6 +24,7 @@ import (
 	"github.com/apache/servicecomb-service-center/pkg/log"
 	"io/ioutil"
 	"net"
+	"net/http"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -31,6 +32,11 @@ import (
 	"github.com/astaxie/beego/httplib"
 )
 
+var cipherSuiteMap = map[string]uint16{
+	"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256": tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	"TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384": tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+}
+
 // TLSConfig create tls configuration
 func TLSConfig(crtName string, skipInsecureVerify bool) (*tls.Config, error) {
 	appConfig, err := GetAppConfig()
@@ -139,6 +145,20 @@ func SendGetRequest(url string, tlsCfg *tls.Config) (string, error) {
 
 //SendRequest rest request
 func SendRequest(url string, method string, jsonStr []byte, tlsCfg *tls.Config) (string, error) {
+	req := newRequest(url, method, jsonStr)
+	req.SetTLSClientConfig(tlsCfg)
+	return doRequest(req)
+}
+
+// SendPostRequestWithTransport sends post request through the round tripper, the tls configuration is taken from
+// the round tripper
+func SendPostRequestWithTransport(url string, jsonStr []byte, transport http.RoundTripper) (string, error) {
+	req := newRequest(url, PostMethod, jsonStr)
+	req.SetTransport(transport)
+	return doRequest(req)
+}
+
+func newRequest(url string, method string, jsonStr []byte) *httplib.BeegoHTTPRequest {
 	log.Infof("New rest request url: %s, method: %s.", url, method)
 	log.Debugf("Rest body: %s.", string(jsonStr))
 	var req *httplib.BeegoHTTPRequest
@@ -156,8 +176,10 @@ func SendRequest(url string, method string, jsonStr []byte, tlsCfg *tls.Config)
 	default:
 		req = httplib.Get(url)
 	}
+	return req
+}
 
-	req.SetTLSClientConfig(tlsCfg)
+func doRequest(req *httplib.BeegoHTTPRequest) (string, error) {
 	req.Header(XRealIp, GetLocalIP())
 
 	res, err := req.String()
@@ -26,6 +26,7 @@ import (
 	"github.com/apache/servicecomb-service-center/server/core/backend"
 	"github.com/apache/servicecomb-service-center/server/core/proto"
 	"github.com/apache/servicecomb-service-center/server/plugin/pkg/registry"
+	"mepserver/common/metrics"
 	meputil "mepserver/common/util"
 	"strconv"
 	"time"
@@ -101,6 +102,7 @@ func heartbeatProcess() {
 					Properties: property,
 				}
 				_, err := core.InstanceAPI.UpdateInstanceProperties(context.Background(), req)
+				metrics.HeartbeatMissed()
 				log.Infof("Service(%s) send to suspended state.", svc.ServiceId)
 				if err != nil {
 					log.Error("Updating service properties for heartbeat failed.", nil)
@@ -18,10 +18,13 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"os"
+	"time"
 
 	_ "mepserver/common/tls"
+	"mepserver/common/tracing"
 	"mepserver/common/util"
 	_ "mepserver/mm5"
 	_ "mepserver/mm5/plans"
@@ -38,6 +41,8 @@ import (
 var rootKey = "ROOT_KEY"
 var tlsKey = "TLS_KEY"
 
+const tracingShutdownTimeout = 5 * time.Second
+
 func main() {
 
 	err := initialEncryptComponent()
@@ -54,8 +59,12 @@ func main() {
 
 	}
 	go heartbeatProcess()
-	util.ApiGWInterface = util.NewApiGwIf()
 	server.Run()
+
+	// the spans not exported yet are flushed on the server stop
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+	tracing.Shutdown(ctx)
 }
 
 func encryptCertPwd() error {
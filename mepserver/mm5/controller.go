@@ -23,14 +23,18 @@ import (
 	"github.com/apache/servicecomb-service-center/pkg/rest"
 	v4 "github.com/apache/servicecomb-service-center/server/rest/controller/v4"
 	"mepserver/common/config"
-	dpCommon "mepserver/common/extif/dataplane/common"
-	"mepserver/common/extif/dns"
+	"mepserver/common/extif/apigw"
+	apigwCommon "mepserver/common/extif/apigw/common"
+	"mepserver/common/extif/httplog"
+	httplogCommon "mepserver/common/extif/httplog/common"
 	"mepserver/common/models"
 	"mepserver/mm5/task"
 	"net/http"
 
 	"mepserver/common"
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
+	"mepserver/common/tracing"
 	meputil "mepserver/common/util"
 	"mepserver/mm5/plans"
 )
@@ -54,7 +58,8 @@ type Mm5Service struct {
 	v4.MicroServiceService
 	config         *config.MepServerConfig
 	mepAuthBaseUrl string
-	mp2Worker      task.Worker
+	apiGateway     apigw.APIGateway
+	httpLogStore   httplog.HttpLogStore
 }
 
 // Init initialize mm5 interface service
@@ -64,23 +69,34 @@ func (m *Mm5Service) Init() error {
 		return fmt.Errorf("error: reading configuration failed")
 	}
 	m.config = mepConfig
+	audit.Init(mepConfig)
+	tracing.Init(mepConfig)
 
-	// Checking if local or both is configured
-	var dnsAgent dns.DNSAgent
-	if m.config.DNSAgent.Type != meputil.DnsAgentTypeDataPlane {
-		dnsAgent = dns.NewRestDNSAgent(mepConfig)
+	// the appd sync worker is shared with the mp1 rule updates, its data-plane and dns agent are swapped on the
+	// configuration reload
+	if err := task.ConfigureAppDWorker(mepConfig); err != nil {
+		return err
 	}
 
-	// select data plane as per configuration
-	dataPlane := dpCommon.CreateDataPlane(mepConfig)
-	if dataPlane == nil {
-		return fmt.Errorf("error: unsupported data-plane")
+	// select api gateway as per configuration, used to remove the services of the terminated applications
+	apiGateway := apigwCommon.CreateAPIGateway(mepConfig)
+	if apiGateway == nil {
+		return fmt.Errorf("error: unsupported api gateway")
 	}
-	if err := dataPlane.InitDataPlane(mepConfig); err != nil {
-		return err
+	if err := apiGateway.InitAPIGateway(mepConfig); err != nil {
+		log.Errorf(err, "API gateway initialization failed.")
+	}
+	m.apiGateway = apiGateway
+
+	// select http log store as per configuration, used by the service governance
+	httpLogStore := httplogCommon.CreateHttpLogStore(mepConfig)
+	if httpLogStore == nil {
+		return fmt.Errorf("error: unsupported http log store")
 	}
-	log.Infof("Data-plane initialized to %s.", m.config.DataPlane.Type)
-	m.mp2Worker.InitializeWorker(dataPlane, dnsAgent, m.config.DNSAgent.Type)
+	if err := httpLogStore.InitStore(mepConfig); err != nil {
+		log.Errorf(err, "Http log store initialization failed.")
+	}
+	m.httpLogStore = httpLogStore
 
 	m.mepAuthBaseUrl, err = meputil.ReadMepAuthEndpoint()
 	if err != nil {
@@ -112,6 +128,25 @@ func (m *Mm5Service) URLPatterns() []rest.Route {
 		{Method: rest.HTTP_METHOD_GET, Path: meputil.KongHttpLogPath, Func: m.queryHttpLog},
 		{Method: rest.HTTP_METHOD_GET, Path: meputil.SubscribeStatisticPath, Func: m.querySubscribeStatistic},
 		{Method: rest.HTTP_METHOD_GET, Path: meputil.GovernServicesPath, Func: m.queryAllServices},
+
+		// Audit Interface
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.AuditLogsPath, Func: m.queryAuditLogs},
+
+		// Rate Limit Interface
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.RateLimitsPath, Func: m.queryRateLimits},
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.AppRateLimitsPath, Func: m.queryRateLimits},
+		{Method: rest.HTTP_METHOD_PUT, Path: meputil.AppRateLimitsPath + meputil.SerNamePath, Func: m.setRateLimit},
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.AppRateLimitsPath + meputil.SerNamePath, Func: m.getRateLimit},
+		{Method: rest.HTTP_METHOD_DELETE, Path: meputil.AppRateLimitsPath + meputil.SerNamePath,
+			Func: m.deleteRateLimit},
+
+		// Api Usage Analytics Interface
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.AnalyticsUsagePath, Func: m.queryUsageAnalytics},
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.AnalyticsConsumersPath, Func: m.queryConsumerAnalytics},
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.AnalyticsCallMatrixPath, Func: m.queryCallMatrixAnalytics},
+
+		// Configuration Interface
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.EffectiveConfigPath, Func: m.getEffectiveConfig},
 	}
 }
 
@@ -139,7 +174,7 @@ func (m *Mm5Service) appDCreate(w http.ResponseWriter, r *http.Request) {
 	workPlan := NewWorkSpace(w, r)
 	workPlan.Try(
 		(&plans.DecodeAppDRestReq{}).WithBody(&models.AppDConfig{}),
-		(&plans.CreateAppDConfig{}).WithWorker(&m.mp2Worker))
+		(&plans.CreateAppDConfig{}).WithWorker(task.AppDWorker()))
 	workPlan.Finally(&common.SendHttpRsp{})
 
 	workspace.WkRun(workPlan)
@@ -150,7 +185,7 @@ func (m *Mm5Service) appDUpdate(w http.ResponseWriter, r *http.Request) {
 	workPlan := NewWorkSpace(w, r)
 	workPlan.Try(
 		(&plans.DecodeAppDRestReq{}).WithBody(&models.AppDConfig{}),
-		(&plans.UpdateAppDConfig{}).WithWorker(&m.mp2Worker))
+		(&plans.UpdateAppDConfig{}).WithWorker(task.AppDWorker()))
 	workPlan.Finally(&common.SendHttpRsp{})
 
 	workspace.WkRun(workPlan)
@@ -161,7 +196,7 @@ func (m *Mm5Service) appDDelete(w http.ResponseWriter, r *http.Request) {
 	workPlan := NewWorkSpace(w, r)
 	workPlan.Try(
 		&plans.DecodeAppDRestReq{},
-		(&plans.DeleteAppDConfig{}).WithWorker(&m.mp2Worker))
+		(&plans.DeleteAppDConfig{}).WithWorker(task.AppDWorker()))
 	workPlan.Finally(&common.SendHttpRsp{})
 
 	workspace.WkRun(workPlan)
@@ -194,8 +229,8 @@ func (m *Mm5Service) terminateAppInstance(w http.ResponseWriter, r *http.Request
 	workPlan := NewWorkSpace(w, r)
 	workPlan.Try(
 		&plans.DecodeAppTerminationReq{},
-		(&plans.DeleteAppDConfigWithSync{}).WithWorker(&m.mp2Worker),
-		&plans.DeleteService{},
+		(&plans.DeleteAppDConfigWithSync{}).WithWorker(task.AppDWorker()),
+		(&plans.DeleteService{}).WithAPIGateway(m.apiGateway),
 		(&plans.DeleteFromMepauth{}).WithEndPoint(m.mepAuthBaseUrl))
 	workPlan.Finally(&common.SendHttpRsp{})
 
@@ -205,7 +240,7 @@ func (m *Mm5Service) terminateAppInstance(w http.ResponseWriter, r *http.Request
 func (m *Mm5Service) insertHttpLog(w http.ResponseWriter, r *http.Request) {
 	workPlan := NewWorkSpace(w, r)
 	workPlan.Try(
-		&plans.CreateKongHttpLog{})
+		(&plans.CreateKongHttpLog{}).WithHttpLogStore(m.httpLogStore))
 	workPlan.Finally(&common.SendHttpRsp{})
 
 	workspace.WkRun(workPlan)
@@ -214,7 +249,7 @@ func (m *Mm5Service) insertHttpLog(w http.ResponseWriter, r *http.Request) {
 func (m *Mm5Service) queryHttpLog(w http.ResponseWriter, r *http.Request) {
 	workPlan := NewWorkSpace(w, r)
 	workPlan.Try(
-		&plans.GetKongHttpLog{})
+		(&plans.GetKongHttpLog{}).WithHttpLogStore(m.httpLogStore))
 	workPlan.Finally(&common.SendHttpRsp{})
 
 	workspace.WkRun(workPlan)
@@ -237,3 +272,82 @@ func (m *Mm5Service) queryAllServices(w http.ResponseWriter, r *http.Request) {
 
 	workspace.WkRun(workPlan)
 }
+
+func (m *Mm5Service) queryAuditLogs(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.AuditLogsGet{})
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mm5Service) queryRateLimits(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodeRateLimitReq{},
+		&plans.RateLimitsGet{})
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mm5Service) setRateLimit(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		(&plans.DecodeRateLimitReq{}).WithBody(&apigw.RateLimit{}),
+		(&plans.RateLimitSet{}).WithAPIGateway(m.apiGateway))
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mm5Service) getRateLimit(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodeRateLimitReq{},
+		&plans.RateLimitGet{})
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mm5Service) deleteRateLimit(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodeRateLimitReq{},
+		(&plans.RateLimitDelete{}).WithAPIGateway(m.apiGateway))
+	workPlan.Finally(&common.SendHttpRsp{StatusCode: http.StatusNoContent})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mm5Service) queryUsageAnalytics(w http.ResponseWriter, r *http.Request) {
+	m.queryAnalytics(w, r, plans.AnalyticsUsage)
+}
+
+func (m *Mm5Service) queryConsumerAnalytics(w http.ResponseWriter, r *http.Request) {
+	m.queryAnalytics(w, r, plans.AnalyticsConsumers)
+}
+
+func (m *Mm5Service) queryCallMatrixAnalytics(w http.ResponseWriter, r *http.Request) {
+	m.queryAnalytics(w, r, plans.AnalyticsCallMatrix)
+}
+
+func (m *Mm5Service) queryAnalytics(w http.ResponseWriter, r *http.Request, report string) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		(&plans.HttpLogAnalyticsGet{}).WithHttpLogStore(m.httpLogStore).WithReport(report))
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mm5Service) getEffectiveConfig(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.EffectiveConfigGet{})
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
@@ -27,8 +27,12 @@ import (
 	"mepserver/common/appd"
 	"mepserver/common/arch/workspace"
 	"mepserver/common/config"
+	"mepserver/common/extif/apigw"
+	"mepserver/common/extif/apigw/memory"
+	backendMemory "mepserver/common/extif/backend/memory"
 	"mepserver/common/extif/dataplane"
 	"mepserver/common/extif/dns"
+	httplogMemory "mepserver/common/extif/httplog/memory"
 	"mepserver/common/models"
 	"mepserver/mm5/task"
 	"mepserver/mp1/event"
@@ -67,6 +71,8 @@ const defaultTaskId = "5abe4782-2c70-4e47-9a4e-0ee3a1a0fd1f"
 const taskQueryFormat = ":taskId=%s&;"
 
 const appInstanceQueryFormat = ":appInstanceId=%s&;"
+const rateLimitUrlFormat = "/mep/service_govern/v1/applications/%s/rate_limits/FaceRegService6"
+const rateLimitQueryFormat = ":appInstanceId=%s&:serName=FaceRegService6"
 const appInstanceIdHeader = "X-AppinstanceID"
 const responseStatusHeader = "X-Response-Status"
 const responseCheckFor200 = "Response status code must be 200"
@@ -3093,3 +3099,223 @@ func TestAppInstanceTerminationStatusDbErrhandle(t *testing.T) {
 
 	service.URLPatterns()[7].Func(mockWriterGet, getRequest)
 }
+
+// routeFunc finds the handler of the route with the method and the path
+func routeFunc(t *testing.T, service *Mm5Service, method string, path string) func(http.ResponseWriter,
+	*http.Request) {
+	for _, route := range service.URLPatterns() {
+		if route.Method == method && route.Path == path {
+			return route.Func
+		}
+	}
+	t.Fatalf("route %s %s not found", method, path)
+	return nil
+}
+
+func TestSetRateLimit(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf(panicFormatString, r)
+		}
+	}()
+
+	service := Mm5Service{apiGateway: memory.NewGateway()}
+	putRequest, _ := http.NewRequest("PUT", fmt.Sprintf(rateLimitUrlFormat, defaultAppInstanceId),
+		bytes.NewReader([]byte("{\"second\":10,\"minute\":60}")))
+	putRequest.URL.RawQuery = fmt.Sprintf(rateLimitQueryFormat, defaultAppInstanceId)
+
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{}
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write", []byte("{\"consumerAppInstanceId\":\""+defaultAppInstanceId+
+		"\",\"serName\":\"FaceRegService6\",\"second\":10,\"minute\":60}\n")).Return(0, nil)
+	mockWriter.On("WriteHeader", 200)
+
+	restore := backend.UseRegistry(backendMemory.NewRegistry())
+	defer restore()
+	patches := gomonkey.ApplyFunc(util.FindInstanceByKey, func(result url.Values) (*proto.FindInstancesResponse,
+		error) {
+		return &proto.FindInstancesResponse{}, nil
+	})
+	defer patches.Reset()
+
+	routeFunc(t, &service, http.MethodPut, util.AppRateLimitsPath+util.SerNamePath)(mockWriter, putRequest)
+
+	assert.Equal(t, "200", responseHeader.Get(responseStatusHeader), responseCheckFor200)
+	mockWriter.AssertExpectations(t)
+	saved, errCode := apigw.GetRateLimit(defaultAppInstanceId, "FaceRegService6")
+	assert.Equal(t, 0, errCode)
+	assert.Equal(t, &apigw.RateLimit{ConsumerAppInstanceId: defaultAppInstanceId, SerName: "FaceRegService6",
+		Second: 10, Minute: 60}, saved)
+}
+
+func TestSetRateLimitInvalidWindows(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf(panicFormatString, r)
+		}
+	}()
+
+	service := Mm5Service{apiGateway: memory.NewGateway()}
+	putRequest, _ := http.NewRequest("PUT", fmt.Sprintf(rateLimitUrlFormat, defaultAppInstanceId),
+		bytes.NewReader([]byte("{\"second\":100,\"minute\":60}")))
+	putRequest.URL.RawQuery = fmt.Sprintf(rateLimitQueryFormat, defaultAppInstanceId)
+
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{}
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write", mock.Anything).Return(0, nil)
+	mockWriter.On("WriteHeader", 400)
+
+	restore := backend.UseRegistry(backendMemory.NewRegistry())
+	defer restore()
+
+	routeFunc(t, &service, http.MethodPut, util.AppRateLimitsPath+util.SerNamePath)(mockWriter, putRequest)
+
+	assert.Equal(t, "400", responseHeader.Get(responseStatusHeader), "Response status code must be 400")
+	mockWriter.AssertExpectations(t)
+	_, errCode := apigw.GetRateLimit(defaultAppInstanceId, "FaceRegService6")
+	assert.Equal(t, util.SubscriptionNotFound, errCode)
+}
+
+func TestKongHttpLogMemoryStore(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf(panicFormatString, r)
+		}
+	}()
+
+	service := Mm5Service{httpLogStore: httplogMemory.NewStore(10)}
+	startedAt := time.Now().UnixNano() / int64(time.Millisecond)
+	httpLog := fmt.Sprintf("{\"started_at\":%d,\"upstream_uri\":\"/mep/mec_service_mgmt/v1/services\","+
+		"\"request\":{\"method\":\"GET\"},\"response\":{\"status\":200},\"service\":{\"name\":\"mepserver\"}}",
+		startedAt)
+	postRequest, _ := http.NewRequest("POST", util.KongHttpLogPath, bytes.NewReader([]byte(httpLog)))
+
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{}
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write", mock.Anything).Return(0, nil)
+	mockWriter.On("WriteHeader", 200)
+
+	// 8 is the order of the kong http log insert handler in the URLPattern
+	service.URLPatterns()[8].Func(mockWriter, postRequest)
+	assert.Equal(t, "200", responseHeader.Get(responseStatusHeader), responseCheckFor200)
+
+	patches := gomonkey.ApplyFunc(util.FindInstanceByKey, func(result url.Values) (*proto.FindInstancesResponse,
+		error) {
+		return &proto.FindInstancesResponse{}, nil
+	})
+	defer patches.Reset()
+
+	getRequest, _ := http.NewRequest("GET", util.KongHttpLogPath, bytes.NewReader([]byte("")))
+	mockWriterGet := &mockHttpWriter{}
+	responseHeaderGet := http.Header{}
+	mockWriterGet.On("Header").Return(responseHeaderGet)
+	mockWriterGet.On("Write", []byte("{\"data\":{\"appServices\":[],\"mepServices\":[{\"callTimes\":[0,0,0,0,0,0,0],"+
+		"\"desc\":\"\",\"name\":\"serviceRegister\"},{\"callTimes\":[1,0,0,0,0,0,0],\"desc\":\"\","+
+		"\"name\":\"serviceDiscovery\"}],\"oldestRetained\":\""+
+		time.Unix(0, startedAt*int64(time.Millisecond)).UTC().Format(time.RFC3339Nano)+"\"},\"retCode\":0,"+
+		"\"message\":\"\",\"params\":\"\"}\n")).Return(0, nil)
+	mockWriterGet.On("WriteHeader", 200)
+
+	// 9 is the order of the kong http log query handler in the URLPattern
+	service.URLPatterns()[9].Func(mockWriterGet, getRequest)
+	assert.Equal(t, "200", responseHeaderGet.Get(responseStatusHeader), responseCheckFor200)
+	mockWriterGet.AssertExpectations(t)
+}
+
+func TestCallMatrixAnalytics(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf(panicFormatString, r)
+		}
+	}()
+
+	store := httplogMemory.NewStore(10)
+	_ = store.Save([]byte("{\"started_at\":1623283200000,\"response\":{\"status\":200},\"latencies\":{\"request\":8}," +
+		"\"service\":{\"name\":\"FaceRegService6c3a2bf8df4144d4\"},\"consumer\":{\"username\":\"" +
+		defaultAppInstanceId + "\"}}"))
+	service := Mm5Service{httpLogStore: store}
+
+	patches := gomonkey.ApplyFunc(util.FindInstanceByKey, func(result url.Values) (*proto.FindInstancesResponse,
+		error) {
+		return &proto.FindInstancesResponse{Instances: []*proto.MicroServiceInstance{{Properties: map[string]string{
+			"serName": "FaceRegService6", "apiGw/FaceRegService6c3a2bf8df4144d4": "http://10.1.1.1:8080/"}}}}, nil
+	})
+	defer patches.Reset()
+
+	getRequest, _ := http.NewRequest("GET", util.AnalyticsCallMatrixPath, bytes.NewReader([]byte("")))
+	getRequest.URL.RawQuery = "startTime=2021-06-10T00:00:00Z&endTime=2021-06-11T00:00:00Z"
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{}
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write", []byte("{\"start\":\"2021-06-10T00:00:00Z\",\"end\":\"2021-06-11T00:00:00Z\","+
+		"\"granularity\":\"day\",\"oldestRetained\":\"2021-06-10T00:00:00Z\",\"callMatrix\":[{\"consumer\":\""+defaultAppInstanceId+"\","+
+		"\"provider\":\"FaceRegService6\",\"calls\":1,\"clientErrors\":0,\"serverErrors\":0,\"clientErrorRate\":0,"+
+		"\"serverErrorRate\":0,\"latency\":{\"p50\":8,\"p95\":8,\"p99\":8}}]}\n")).Return(0, nil)
+	mockWriter.On("WriteHeader", 200)
+
+	// 20 is the order of the call matrix analytics handler in the URLPattern
+	service.URLPatterns()[20].Func(mockWriter, getRequest)
+
+	assert.Equal(t, "200", responseHeader.Get(responseStatusHeader), responseCheckFor200)
+	mockWriter.AssertExpectations(t)
+}
+
+func TestUsageAnalyticsInvalidGranularity(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf(panicFormatString, r)
+		}
+	}()
+
+	service := Mm5Service{httpLogStore: httplogMemory.NewStore(10)}
+	getRequest, _ := http.NewRequest("GET", util.AnalyticsUsagePath, bytes.NewReader([]byte("")))
+	getRequest.URL.RawQuery = "granularity=week"
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{}
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write", mock.Anything).Return(0, nil)
+	mockWriter.On("WriteHeader", 400)
+
+	// 18 is the order of the usage analytics handler in the URLPattern
+	service.URLPatterns()[18].Func(mockWriter, getRequest)
+
+	assert.Equal(t, "400", responseHeader.Get(responseStatusHeader), "Response status code must be 400")
+	mockWriter.AssertExpectations(t)
+}
+
+func TestGetEffectiveConfig(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf(panicFormatString, r)
+		}
+	}()
+
+	effective := &config.Effective{
+		Config:          config.MepServerConfig{DNSAgent: config.DNSAgent{Type: util.DnsAgentTypeLocal}},
+		EnvOverrides:    []string{"DNS_SERVER_HOST"},
+		LoadedAt:        time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		RestartRequired: []string{},
+	}
+	patches := gomonkey.ApplyFunc(config.GetEffective, func() *config.Effective {
+		return effective
+	})
+	defer patches.Reset()
+
+	service := Mm5Service{}
+	getRequest, _ := http.NewRequest("GET", util.EffectiveConfigPath, bytes.NewReader([]byte("")))
+	expectedBody, _ := json.Marshal(effective)
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{}
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write", append(expectedBody, '\n')).Return(0, nil)
+	mockWriter.On("WriteHeader", 200)
+
+	// 21 is the order of the effective configuration handler in the URLPattern
+	service.URLPatterns()[21].Func(mockWriter, getRequest)
+
+	assert.Equal(t, "200", responseHeader.Get(responseStatusHeader), "Response status code must be 200")
+	mockWriter.AssertExpectations(t)
+}
@@ -25,6 +25,8 @@ import (
 	"golang.org/x/net/context"
 
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
+	"tracing"
 )
 
 type MepSpace struct {
@@ -38,6 +40,7 @@ type MepSpace struct {
 	DNSRuleId     string          `json:"dnsRuleId"`
 	CapabilityId  string          `json:"capabilityId"`
 	TaskId        string          `json:"taskId"`
+	SerName       string          `json:"serName"`
 	QueryParam    url.Values      `json:"queryParam"`
 	CoreRequest   interface{}     `json:"coreRequest"`
 	CoreRsp       interface{}     `json:"coreRsp"`
@@ -49,9 +52,17 @@ type MepSpace struct {
 func NewWorkSpace(w http.ResponseWriter, r *http.Request) *MepSpace {
 	var plan = MepSpace{
 		W: w,
-		R: r,
+		R: tracing.Begin(audit.Begin(r)),
 	}
 
 	plan.Init()
 	return &plan
 }
+
+// TraceContext returns the trace context of the request, the steps are traced as the children of the request span
+func (m *MepSpace) TraceContext() context.Context {
+	if m.R == nil {
+		return context.Background()
+	}
+	return m.R.Context()
+}
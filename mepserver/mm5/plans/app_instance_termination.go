@@ -32,12 +32,15 @@ import (
 	"io/ioutil"
 	"mepserver/common/appd"
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
+	"mepserver/common/extif/apigw"
 	"mepserver/common/extif/backend"
 	"mepserver/common/models"
 	meputil "mepserver/common/util"
 	"mepserver/mm5/task"
 	"net/http"
 	"os"
+	"tracing"
 )
 
 // DecodeAppTerminationReq decodes application termination request
@@ -58,10 +61,30 @@ func (t *DecodeAppTerminationReq) OnRequest(data string) workspace.TaskCode {
 		log.Error("Parameters validation failed.", err)
 		return workspace.TaskFinish
 	}
+	t.recordBefore()
 	log.Debugf("Query request arrived to fetch the app Id.")
 	return workspace.TaskFinish
 }
 
+// terminationBefore holds the application instance state removed by the termination
+type terminationBefore struct {
+	AppDConfig json.RawMessage               `json:"appDConfig,omitempty"`
+	Services   []*proto.MicroServiceInstance `json:"services,omitempty"`
+}
+
+// recordBefore records the appd config and the services of the application instance for audit before the
+// termination steps remove them
+func (t *DecodeAppTerminationReq) recordBefore() {
+	before := terminationBefore{}
+	if appDConfigEntry, errCode := backend.GetRecord(meputil.AppDConfigKeyPath + t.AppInstanceId); errCode == 0 {
+		before.AppDConfig = appDConfigEntry
+	}
+	if instances, errCode, _ := getAppInstanceServices(t.AppInstanceId); errCode == 0 {
+		before.Services = instances
+	}
+	audit.SetBefore(t.Ctx, before)
+}
+
 // GetFindParam get find param by request
 func (t *DecodeAppTerminationReq) GetFindParam(r *http.Request) (context.Context, error) {
 
@@ -84,16 +107,54 @@ type DeleteService struct {
 	HttpErrInf    *proto.Response `json:"httpErrInf,out"`
 	HttpRsp       interface{}     `json:"httpRsp,out"`
 	AppInstanceId string          `json:"appInstanceId,in"`
+	apiGateway    apigw.APIGateway
+}
+
+// WithAPIGateway inputs the api gateway instance
+func (t *DeleteService) WithAPIGateway(apiGateway apigw.APIGateway) *DeleteService {
+	t.apiGateway = apiGateway
+	return t
 }
 
 // OnRequest handles the service deletion
 func (t *DeleteService) OnRequest(data string) workspace.TaskCode {
 	log.Info("Application termination request.")
+	findResp, errCode, errMsg := getAppInstanceServices(t.AppInstanceId)
+	if errCode != 0 {
+		t.SetFirstErrorCode(errCode, errMsg)
+		return workspace.TaskFinish
+	}
+	for _, ins := range findResp {
+		unRegSvc := &proto.UnregisterInstanceRequest{
+			ServiceId:  ins.ServiceId,
+			InstanceId: ins.InstanceId,
+		}
+		resp, err := core.InstanceAPI.Unregister(t.Ctx, unRegSvc)
+
+		errorCode, errorString := checkErr(resp, err)
+		if errorCode != 0 {
+			t.SetFirstErrorCode(workspace.ErrCode(errorCode), errorString)
+			return workspace.TaskFinish
+		}
+
+		t.cleanUpApiGwEntry(ins)
+	}
+	if len(findResp) == 0 {
+		log.Infof("Requested application instances not found.")
+		t.HttpRsp = ""
+		return workspace.TaskFinish
+	}
+	log.Info("Successfully terminated application services.")
+	t.HttpRsp = ""
+	return workspace.TaskFinish
+}
+
+// getAppInstanceServices reads the service instances registered by the application instance
+func getAppInstanceServices(appInstanceId string) ([]*proto.MicroServiceInstance, workspace.ErrCode, string) {
 	resp, errInt := backend.GetRecords("/cse-sr/inst/files///")
 	if errInt != 0 {
 		log.Errorf(nil, "Data store read failed.")
-		t.SetFirstErrorCode(meputil.OperateDataWithEtcdErr, "query error from etcd")
-		return workspace.TaskFinish
+		return nil, meputil.OperateDataWithEtcdErr, "query error from etcd"
 	}
 	var findResp []*proto.MicroServiceInstance
 	for _, value := range resp {
@@ -101,64 +162,43 @@ func (t *DeleteService) OnRequest(data string) workspace.TaskCode {
 		err := json.Unmarshal(value, &instances)
 		if err != nil {
 			log.Errorf(nil, "Instance decode failed.")
-			t.SetFirstErrorCode(meputil.ParseInfoErr, err.Error())
-			return workspace.TaskFinish
+			return nil, meputil.ParseInfoErr, err.Error()
 		}
 		dci := &proto.DataCenterInfo{Name: "", Region: "", AvailableZone: ""}
 		instances[meputil.ServiceInfoDataCenter] = dci
 		message, err := json.Marshal(&instances)
 		if err != nil {
 			log.Errorf(nil, "Instance encode failed.")
-			t.SetFirstErrorCode(meputil.ParseInfoErr, err.Error())
-			return workspace.TaskFinish
+			return nil, meputil.ParseInfoErr, err.Error()
 		}
 		var ins *proto.MicroServiceInstance
 		err = json.Unmarshal(message, &ins)
 		if err != nil {
 			log.Errorf(nil, "Micro service instance decode failed.")
-			t.SetFirstErrorCode(meputil.ParseInfoErr, err.Error())
-			return workspace.TaskFinish
+			return nil, meputil.ParseInfoErr, err.Error()
 		}
-		instanceId := ins.InstanceId
-		serviceId := ins.ServiceId
-		property := ins.Properties
-		if t.AppInstanceId == property["appInstanceId"] {
+		if appInstanceId == ins.Properties["appInstanceId"] {
 			findResp = append(findResp, ins)
-			unRegSvc := &proto.UnregisterInstanceRequest{
-				ServiceId:  serviceId,
-				InstanceId: instanceId,
-			}
-			resp, err := core.InstanceAPI.Unregister(t.Ctx, unRegSvc)
-
-			errorCode, errorString := checkErr(resp, err)
-			if errorCode != 0 {
-				t.SetFirstErrorCode(workspace.ErrCode(errorCode), errorString)
-				return workspace.TaskFinish
-			}
-
-			apiGwSerName := meputil.GetApiGwSerName(ins)
-			if apiGwSerName != "" {
-				cleanUpApiGwEntry(apiGwSerName)
-			}
 		}
 	}
-	if len(findResp) == 0 {
-		log.Infof("Requested application instances not found.")
-		t.HttpRsp = ""
-		return workspace.TaskFinish
-	}
-	log.Info("Successfully terminated application services.")
-	t.HttpRsp = ""
-	return workspace.TaskFinish
+	return findResp, 0, ""
 }
 
-func cleanUpApiGwEntry(apiGwServiceName string) {
-	// delete service route from apiGw
-	meputil.ApiGWInterface.DeleteApiGwRoute(apiGwServiceName)
-	// delete service plugin from apiGw
-	meputil.ApiGWInterface.DeleteJwtPlugin(apiGwServiceName)
-	// delete service from apiGw
-	meputil.ApiGWInterface.DeleteApiGwService(apiGwServiceName)
+// cleanUpApiGwEntry removes the api gateway services of the instance, the instances registered without the api
+// gateway properties are identified by the endpoint
+func (t *DeleteService) cleanUpApiGwEntry(ins *proto.MicroServiceInstance) {
+	if t.apiGateway == nil {
+		return
+	}
+	services := apigw.ServicesFromProperties(ins.Properties)
+	if len(services) == 0 {
+		apiGwSerName := meputil.GetApiGwSerName(ins)
+		if apiGwSerName == "" {
+			return
+		}
+		services = append(services, apigw.Service{Name: apiGwSerName})
+	}
+	apigw.UnregisterServices(apigw.WithContext(t.apiGateway, t.TraceContext()), services)
 }
 
 func checkErr(response *proto.UnregisterInstanceResponse, err error) (int, string) {
@@ -191,7 +231,7 @@ func (t *DeleteFromMepauth) OnRequest(data string) workspace.TaskCode {
 	log.Info("Deleting authentication key entry.")
 	deleteUrl := fmt.Sprintf(t.authBaseUrl+"/%s/confs", t.AppInstanceId)
 	// Create request
-	req, err := http.NewRequest("DELETE", deleteUrl, nil)
+	req, err := http.NewRequestWithContext(t.TraceContext(), "DELETE", deleteUrl, nil)
 	if err != nil {
 		log.Errorf(nil, "Not able to send the request to mep-auth %s.", err.Error())
 		return workspace.TaskFinish
@@ -204,7 +244,7 @@ func (t *DeleteFromMepauth) OnRequest(data string) workspace.TaskCode {
 	tr := &http.Transport{
 		TLSClientConfig: config,
 	}
-	client := &http.Client{Transport: tr}
+	client := &http.Client{Transport: tracing.NewTransport(tr)}
 	req.Header.Add(meputil.XRealIp, meputil.GetLocalIP())
 	// Fetch Request
 	resp, err := client.Do(req)
@@ -275,8 +315,11 @@ func (t *DeleteAppDConfigWithSync) OnRequest(data string) workspace.TaskCode {
 		return workspace.TaskFinish
 	}
 
-	// Check if any other ongoing operation for this AppInstance Id in the system.
+	// Check if any other ongoing operation for this AppInstance Id in the system, the check and the staging are
+	// atomic across the mm5 and the mp1 requests
+	t.Worker.LockStaging()
 	if t.IsAnyOngoingOperationExist(t.AppInstanceId) {
+		t.Worker.UnlockStaging()
 		log.Errorf(nil, "App instance has other operation in progress.")
 		t.SetFirstErrorCode(meputil.ForbiddenOperation, "app instance has other operation in progress")
 		return workspace.TaskFinish
@@ -287,6 +330,7 @@ func (t *DeleteAppDConfigWithSync) OnRequest(data string) workspace.TaskCode {
 
 	taskId := meputil.GenerateUniqueId()
 	errCode, msg := t.StageNewTask(t.AppInstanceId, taskId, &appDConfig)
+	t.Worker.UnlockStaging()
 	if errCode != 0 {
 		t.SetFirstErrorCode(errCode, msg)
 		return workspace.TaskFinish
@@ -127,6 +127,18 @@ func (t *DecodeAppDRestReq) parseBody(r *http.Request) error {
 		t.SetFirstErrorCode(meputil.SerErrFailBase, errorString)
 		return verrs
 	}
+
+	var invalidParams []workspace.InvalidParam
+	for i := range appDConfigInput.AppTrafficRule {
+		invalidParams = append(invalidParams, appd.NormalizeTrafficRule(&appDConfigInput.AppTrafficRule[i],
+			fmt.Sprintf("appTrafficRule[%d].", i))...)
+	}
+	if len(invalidParams) != 0 {
+		log.Errorf(nil, "Traffic filter validation failed(%d invalid fields).", len(invalidParams))
+		t.SetInvalidParams(invalidParams)
+		t.SetFirstErrorCode(meputil.RequestParamErr, appd.InvalidTrafficFilter)
+		return fmt.Errorf(appd.InvalidTrafficFilter)
+	}
 	log.Infof("AppD config received(method: %s, body:%s).", r.Method, string(msg))
 	return nil
 }
@@ -200,7 +212,10 @@ func (t *CreateAppDConfig) OnRequest(data string) workspace.TaskCode {
 		return workspace.TaskFinish
 	}
 
-	// Check if any other ongoing operation for this AppInstance Id in the system.
+	// Check if any other ongoing operation for this AppInstance Id in the system, the check and the staging are
+	// atomic across the mm5 and the mp1 requests
+	t.worker.LockStaging()
+	defer t.worker.UnlockStaging()
 	if t.IsAnyOngoingOperationExist(t.AppInstanceId) {
 		log.Errorf(nil, "App instance has other operation in progress.")
 		t.SetFirstErrorCode(meputil.ForbiddenOperation, "app instance has other operation in progress")
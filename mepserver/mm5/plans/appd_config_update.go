@@ -20,12 +20,14 @@ package plans
 import (
 	"context"
 	"mepserver/common/appd"
+	"mepserver/common/extif/backend"
 	"mepserver/common/models"
 	meputil "mepserver/common/util"
 	"mepserver/mm5/task"
 	"net/http"
 
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
 
 	"github.com/apache/servicecomb-service-center/pkg/log"
 )
@@ -68,8 +70,14 @@ func (t *UpdateAppDConfig) OnRequest(data string) workspace.TaskCode {
 		t.SetFirstErrorCode(meputil.SerInstanceNotFound, "app instance not found")
 		return workspace.TaskFinish
 	}
+	if appDConfigEntry, errCode := backend.GetRecord(meputil.AppDConfigKeyPath + t.AppInstanceId); errCode == 0 {
+		audit.SetBefore(t.Ctx, appDConfigEntry)
+	}
 
-	// Check if any other ongoing operation for this AppInstance Id in the system.
+	// Check if any other ongoing operation for this AppInstance Id in the system, the check and the staging are
+	// atomic across the mm5 and the mp1 requests
+	t.worker.LockStaging()
+	defer t.worker.UnlockStaging()
 	if t.IsAnyOngoingOperationExist(t.AppInstanceId) {
 		log.Errorf(nil, "App instance has other operation in progress.")
 		t.SetFirstErrorCode(meputil.ForbiddenOperation, "app instance has other operation in progress")
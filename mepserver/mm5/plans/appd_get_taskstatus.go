@@ -19,16 +19,12 @@ package plans
 
 import (
 	"context"
-	"encoding/json"
 	"github.com/apache/servicecomb-service-center/pkg/log"
 	"github.com/apache/servicecomb-service-center/pkg/util"
 	"mepserver/common/appd"
 	"mepserver/common/arch/workspace"
-	"mepserver/common/extif/backend"
-	"mepserver/common/models"
 	meputil "mepserver/common/util"
 	"net/http"
-	"strconv"
 )
 
 // DecodeTaskRestReq step to decode status task request
@@ -78,44 +74,13 @@ type TaskStatusGet struct {
 func (t *TaskStatusGet) OnRequest(inputData string) workspace.TaskCode {
 	log.Debugf("Query request arrived to fetch task status for taskId %s.", t.TaskId)
 
-	taskEntry, err := backend.GetRecord(meputil.AppDLCMTasksPath + t.TaskId)
-	if err != 0 {
-		log.Errorf(nil, "Get task rule from data-store failed.")
-		t.SetFirstErrorCode(workspace.ErrCode(err), "task rule retrieval failed")
+	taskProgress, errCode, msg := t.GetTaskProgress(t.TaskId)
+	if errCode != 0 {
+		t.SetFirstErrorCode(errCode, msg)
 		return workspace.TaskFinish
 	}
 
-	appInstInStore := string(taskEntry)
-
-	taskStatus, err := backend.GetRecord(meputil.AppDLCMTaskStatusPath + appInstInStore + "/" + t.TaskId)
-	if err != 0 {
-		log.Errorf(nil, "Get task status rule from data-store failed.")
-		t.SetFirstErrorCode(workspace.ErrCode(err), "task status rule retrieval failed")
-		return workspace.TaskFinish
-	}
-
-	taskStatusInStore := &models.TaskStatus{}
-	jsonErr := json.Unmarshal(taskStatus, taskStatusInStore)
-	if jsonErr != nil {
-		log.Errorf(nil, "Failed to parse the task status from data-store.")
-		t.SetFirstErrorCode(meputil.OperateDataWithEtcdErr, "parse task status from data-store failed")
-		return workspace.TaskFinish
-	}
-
-	progress := (taskStatusInStore.Progress * 100) / (len(taskStatusInStore.TrafficRuleStatusLst) + len(taskStatusInStore.DNSRuleStatusLst))
-
-	var state string
-	if taskStatusInStore.Progress == (len(taskStatusInStore.TrafficRuleStatusLst) + len(taskStatusInStore.DNSRuleStatusLst)) {
-		state = meputil.TaskStateSuccess
-	} else if taskStatusInStore.Progress >= 0 {
-		state = meputil.TaskStateProcessing
-	} else {
-		state = meputil.TaskStateFailure
-		progress = 0
-	}
-
-	t.HttpRsp = t.GenerateTaskResponse(t.TaskId, appInstInStore, state,
-		strconv.Itoa(progress), taskStatusInStore.Details)
+	t.HttpRsp = *taskProgress
 
 	return workspace.TaskFinish
 }
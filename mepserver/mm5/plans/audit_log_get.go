@@ -0,0 +1,101 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plans
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
+	meputil "mepserver/common/util"
+)
+
+const (
+	auditQueryAppInstanceId = "appInstanceId"
+	auditQueryResource      = "resource"
+	auditQueryStartTime     = "startTime"
+	auditQueryEndTime       = "endTime"
+	auditQueryLimit         = "limit"
+	auditMaxQueryLimit      = 1000
+)
+
+// AuditLogsGet step to query the audit records
+type AuditLogsGet struct {
+	workspace.TaskBase
+	R       *http.Request `json:"r,in"`
+	HttpRsp interface{}   `json:"httpRsp,out"`
+}
+
+// OnRequest handles the audit record query
+func (t *AuditLogsGet) OnRequest(data string) workspace.TaskCode {
+	filter, err := parseAuditFilter(t.R)
+	if err != nil {
+		log.Errorf(nil, "Audit log query parameters validation failed.")
+		t.SetFirstErrorCode(meputil.RequestParamErr, err.Error())
+		return workspace.TaskFinish
+	}
+
+	records, err := audit.GetStore().Query(filter)
+	if err != nil {
+		log.Errorf(err, "Audit log query failed.")
+		t.SetFirstErrorCode(meputil.OperateDataWithEtcdErr, "audit log query failed")
+		return workspace.TaskFinish
+	}
+	t.HttpRsp = records
+	return workspace.TaskFinish
+}
+
+func parseAuditFilter(r *http.Request) (*audit.Filter, error) {
+	query := r.URL.Query()
+	filter := &audit.Filter{
+		AppInstanceId: query.Get(auditQueryAppInstanceId),
+		Resource:      query.Get(auditQueryResource),
+		Limit:         auditMaxQueryLimit,
+	}
+	if len(filter.AppInstanceId) != 0 {
+		if err := meputil.ValidateUUID(filter.AppInstanceId); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if startTime := query.Get(auditQueryStartTime); len(startTime) != 0 {
+		if filter.StartTime, err = time.Parse(time.RFC3339, startTime); err != nil {
+			return nil, fmt.Errorf("invalid start time")
+		}
+	}
+	if endTime := query.Get(auditQueryEndTime); len(endTime) != 0 {
+		if filter.EndTime, err = time.Parse(time.RFC3339, endTime); err != nil {
+			return nil, fmt.Errorf("invalid end time")
+		}
+	}
+	if !filter.StartTime.IsZero() && !filter.EndTime.IsZero() && filter.EndTime.Before(filter.StartTime) {
+		return nil, fmt.Errorf("end time is before start time")
+	}
+	if limit := query.Get(auditQueryLimit); len(limit) != 0 {
+		filter.Limit, err = strconv.Atoi(limit)
+		if err != nil || filter.Limit <= 0 || filter.Limit > auditMaxQueryLimit {
+			return nil, fmt.Errorf("invalid limit")
+		}
+	}
+	return filter, nil
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plans
+
+import (
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	"mepserver/common/arch/workspace"
+	"mepserver/common/config"
+	meputil "mepserver/common/util"
+)
+
+// EffectiveConfigGet step to read the mep server configurations in effect
+type EffectiveConfigGet struct {
+	workspace.TaskBase
+	HttpRsp interface{} `json:"httpRsp,out"`
+}
+
+// OnRequest handles the effective configuration query
+func (t *EffectiveConfigGet) OnRequest(data string) workspace.TaskCode {
+	effective := config.GetEffective()
+	if effective == nil {
+		log.Errorf(nil, "Configurations are not loaded.")
+		t.SetFirstErrorCode(meputil.RemoteServerErr, "configurations are not loaded")
+		return workspace.TaskFinish
+	}
+	t.HttpRsp = effective
+	return workspace.TaskFinish
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plans
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	"mepserver/common/arch/workspace"
+	"mepserver/common/extif/apigw"
+	"mepserver/common/extif/httplog"
+	meputil "mepserver/common/util"
+)
+
+// Usage analytics reports
+const (
+	AnalyticsUsage      = "usage"
+	AnalyticsConsumers  = "consumers"
+	AnalyticsCallMatrix = "callMatrix"
+)
+
+const (
+	analyticsQueryStartTime   = "startTime"
+	analyticsQueryEndTime     = "endTime"
+	analyticsQueryGranularity = "granularity"
+	analyticsQuerySerName     = "serName"
+	analyticsQueryTop         = "top"
+	analyticsDefaultTop       = 10
+	analyticsMaxTop           = 100
+)
+
+// HttpLogAnalyticsGet step to query the api usage analytics computed from the kong http logs
+type HttpLogAnalyticsGet struct {
+	workspace.TaskBase
+	R            *http.Request `json:"r,in"`
+	HttpRsp      interface{}   `json:"httpRsp,out"`
+	httpLogStore httplog.HttpLogStore
+	report       string
+}
+
+// WithHttpLogStore inputs the http log store
+func (t *HttpLogAnalyticsGet) WithHttpLogStore(httpLogStore httplog.HttpLogStore) *HttpLogAnalyticsGet {
+	t.httpLogStore = httpLogStore
+	return t
+}
+
+// WithReport selects the analytics report of the response
+func (t *HttpLogAnalyticsGet) WithReport(report string) *HttpLogAnalyticsGet {
+	t.report = report
+	return t
+}
+
+// OnRequest computes the analytics over the requested window and responds the selected report
+func (t *HttpLogAnalyticsGet) OnRequest(data string) workspace.TaskCode {
+	query, window, top, err := parseAnalyticsQuery(t.R)
+	if err != nil {
+		log.Errorf(nil, "Analytics query parameters validation failed.")
+		t.SetFirstErrorCode(meputil.RequestParamErr, err.Error())
+		return workspace.TaskFinish
+	}
+
+	analytics, err := httplog.Analyze(t.httpLogStore, query, window, providerNames())
+	if err != nil {
+		log.Errorf(err, "Analytics computation failed.")
+		t.SetFirstErrorCode(meputil.RemoteServerErr, "analytics computation failed")
+		return workspace.TaskFinish
+	}
+
+	switch t.report {
+	case AnalyticsConsumers:
+		if len(analytics.Consumers) > top {
+			analytics.Consumers = analytics.Consumers[:top]
+		}
+		t.HttpRsp = struct {
+			httplog.Window
+			Consumers []httplog.ConsumerUsage `json:"consumers"`
+		}{analytics.Window, analytics.Consumers}
+	case AnalyticsCallMatrix:
+		t.HttpRsp = struct {
+			httplog.Window
+			CallMatrix []httplog.CallMatrixEntry `json:"callMatrix"`
+		}{analytics.Window, analytics.CallMatrix}
+	default:
+		t.HttpRsp = analytics.Usage
+	}
+	return workspace.TaskFinish
+}
+
+func parseAnalyticsQuery(r *http.Request) (*httplog.Query, *httplog.Window, int, error) {
+	query := r.URL.Query()
+	var err error
+	var startTime, endTime time.Time
+	if start := query.Get(analyticsQueryStartTime); len(start) != 0 {
+		if startTime, err = time.Parse(time.RFC3339, start); err != nil {
+			return nil, nil, 0, fmt.Errorf("invalid start time")
+		}
+	}
+	if end := query.Get(analyticsQueryEndTime); len(end) != 0 {
+		if endTime, err = time.Parse(time.RFC3339, end); err != nil {
+			return nil, nil, 0, fmt.Errorf("invalid end time")
+		}
+	}
+	window, err := httplog.NewWindow(startTime, endTime, query.Get(analyticsQueryGranularity))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	top := analyticsDefaultTop
+	if topValue := query.Get(analyticsQueryTop); len(topValue) != 0 {
+		top, err = strconv.Atoi(topValue)
+		if err != nil || top <= 0 || top > analyticsMaxTop {
+			return nil, nil, 0, fmt.Errorf("invalid top")
+		}
+	}
+
+	// the api gateway service names of the provider services are prefixed with the service name
+	return &httplog.Query{ServiceNamePrefix: query.Get(analyticsQuerySerName)}, window, top, nil
+}
+
+// providerNames maps the api gateway service names to the service names of the registered instances
+func providerNames() func(name string) string {
+	serNames := make(map[string]string)
+	resp, err := meputil.FindInstanceByKey(url.Values{})
+	if err != nil {
+		log.Warn("Find service instances failed, api gateway service names are used in analytics.")
+		return nil
+	}
+	for _, instance := range resp.Instances {
+		serName := instance.Properties["serName"]
+		if len(serName) == 0 {
+			continue
+		}
+		for _, service := range apigw.ServicesFromProperties(instance.Properties) {
+			serNames[service.Name] = serName
+		}
+	}
+	return func(name string) string {
+		if serName, ok := serNames[name]; ok {
+			return serName
+		}
+		return name
+	}
+}
@@ -18,71 +18,37 @@
 package plans
 
 import (
-	"context"
 	"io/ioutil"
 	"mepserver/common/arch/workspace"
+	"mepserver/common/extif/httplog"
 	"mepserver/common/models"
 	meputil "mepserver/common/util"
 	"mepserver/mp1"
 	"net/http"
 	"net/url"
-	"strconv"
+	"time"
 
 	"github.com/apache/servicecomb-service-center/pkg/log"
-	es "github.com/olivere/elastic/v7"
 )
 
-const startedAt = "started_at"
-
-const esHost = "http://mep-elasticsearch:9200"
-
-var EsClient *es.Client
-
-func init() {
-	EsClient = createEsClient()
-}
-
-func createEsClient() *es.Client {
-	log.Info("Create es client.")
-	esClient, err := es.NewClient(es.SetSniff(false), es.SetURL(esHost))
-	if err != nil {
-		log.Error("Connect to es fail.", err)
-		return EsClient
-	}
-	log.Info("Connect to es success.")
-
-	exists, err := esClient.IndexExists(meputil.KongHttpLogIndex).Do(context.Background())
-	if err != nil {
-		log.Error("Failed to check the index existence on es client.", err)
-		return esClient
-	}
-
-	if exists {
-		log.Info("Index already exists in the es client.")
-	} else {
-		mapping := models.GetHttpLogMapping()
-		createIndex, err := esClient.CreateIndex(meputil.KongHttpLogIndex).BodyString(mapping).Do(context.Background())
-		if err != nil {
-			log.Error("Create index failed.", err)
-			return esClient
-		}
-		if !createIndex.Acknowledged {
-			log.Error("Create index fail, not acknowledged.", err)
-			return esClient
-		}
-	}
-	return esClient
-}
+const day = 24 * time.Hour
 
 // CreateKongHttpLog step to create kong http log request
 type CreateKongHttpLog struct {
 	workspace.TaskBase
-	R       *http.Request `json:"r,in"`
-	HttpRsp interface{}   `json:"httpRsp,out"`
+	R            *http.Request `json:"r,in"`
+	HttpRsp      interface{}   `json:"httpRsp,out"`
+	httpLogStore httplog.HttpLogStore
+}
+
+// WithHttpLogStore inputs the http log store
+func (t *CreateKongHttpLog) WithHttpLogStore(httpLogStore httplog.HttpLogStore) *CreateKongHttpLog {
+	t.httpLogStore = httpLogStore
+	return t
 }
 
 // OnRequest When call the api through kong api gateway, the kong http-log plugin will send message to this interface.
-// The interface will store the data to elasticsearch for search by other api.
+// The interface will store the data to the http log store for search by other api.
 func (t *CreateKongHttpLog) OnRequest(data string) workspace.TaskCode {
 	log.Info("Request to create api gw http log.")
 	msg, err := ioutil.ReadAll(t.R.Body)
@@ -92,35 +58,49 @@ func (t *CreateKongHttpLog) OnRequest(data string) workspace.TaskCode {
 		return workspace.TaskFinish
 	}
 
-	resp, err := EsClient.Index().Index(meputil.KongHttpLogIndex).BodyString(string(msg)).Do(context.Background())
-	if err != nil {
-		log.Error("Create doc fail in es.", err)
+	if err = t.httpLogStore.Save(msg); err != nil {
+		log.Error("Save api gw http log failed.", err)
+		t.SetFirstErrorCode(meputil.SerErrFailBase, "save http log error")
+		return workspace.TaskFinish
 	}
-	t.HttpRsp = resp
+	t.HttpRsp = ""
 
 	return workspace.TaskFinish
 }
 
+// GetKongHttpLog step to query the call statistics from the kong http logs
 type GetKongHttpLog struct {
 	workspace.TaskBase
-	R       *http.Request `json:"r,in"`
-	HttpRsp interface{}   `json:"httpRsp,out"`
+	R            *http.Request `json:"r,in"`
+	HttpRsp      interface{}   `json:"httpRsp,out"`
+	httpLogStore httplog.HttpLogStore
 }
 
-// OnRequest The interface is query called times of the 3rd app registered services and mep self capability from elasticsearch.
+// WithHttpLogStore inputs the http log store
+func (t *GetKongHttpLog) WithHttpLogStore(httpLogStore httplog.HttpLogStore) *GetKongHttpLog {
+	t.httpLogStore = httpLogStore
+	return t
+}
+
+// OnRequest The interface is query called times of the 3rd app registered services and mep self capability from the
+// http log store. The stores keeping only the latest http logs report the oldest retained time, the call times before
+// it are incomplete.
 func (t *GetKongHttpLog) OnRequest(data string) workspace.TaskCode {
 	log.Info("New request to get api gw http log.")
 	// 3rd app services list
 	// registered services name list
 	serviceNames := getAllServiceNames()
-	appList := statisticAppServices(serviceNames)
+	appList := statisticAppServices(t.httpLogStore, serviceNames)
 
 	// MEP self capability
-	mepList := statisticMepServices()
+	mepList := statisticMepServices(t.httpLogStore)
 
 	res := make(map[string]interface{})
 	res["appServices"] = appList
 	res["mepServices"] = mepList
+	if oldestRetained := httplog.OldestRetained(t.httpLogStore); oldestRetained != nil {
+		res["oldestRetained"] = oldestRetained
+	}
 
 	responseInfo := models.ResponseInfo{
 		Data:    res,
@@ -130,115 +110,62 @@ func (t *GetKongHttpLog) OnRequest(data string) workspace.TaskCode {
 	return workspace.TaskFinish
 }
 
-func statisticMepServices() []interface{} {
+func statisticMepServices(store httplog.HttpLogStore) []interface{} {
 	list := make([]interface{}, 0)
 
 	// service register data
 	registerMap := make(map[string]interface{})
 	registerMap["name"] = "serviceRegister"
 	registerMap["desc"] = ""
-	registerMap["callTimes"] = statisticRegisterServices()
+	registerMap["callTimes"] = countByDay(store, httplog.Query{
+		ServiceName: "mepserver",
+		UriPattern:  "/mep/mec_service_mgmt/v1/applications/[-A-Za-z0-9]+/services",
+		Method:      http.MethodPost,
+	})
 	list = append(list, registerMap)
-	//services["serviceRegister"] = statisticRegisterServices()
 
 	// service discovery data
 	discoveryMap := make(map[string]interface{})
 	discoveryMap["name"] = "serviceDiscovery"
 	discoveryMap["desc"] = ""
-	discoveryMap["callTimes"] = statisticDiscoveryServices()
+	discoveryMap["callTimes"] = countByDay(store, httplog.Query{
+		ServiceName: "mepserver",
+		UriPrefix:   "/mep/mec_service_mgmt/v1/services",
+		Method:      http.MethodGet,
+	})
 	list = append(list, discoveryMap)
-	//services["serviceDiscovery"] = statisticDiscoveryServices()
 
 	return list
 }
 
-func statisticDiscoveryServices() interface{} {
+// countByDay counts the matching http logs of the last week by day, the first entry is today
+func countByDay(store httplog.HttpLogStore, query httplog.Query) []int {
 	dayCount := make([]int, meputil.WeekDay)
+	today := time.Now().UTC().Truncate(day)
 	for i := 0; i < meputil.WeekDay; i++ {
-		boolQuery := es.NewBoolQuery()
-		serviceNameQuery := es.NewTermsQuery("service.name", "mepserver")
-		boolQuery.Filter(serviceNameQuery)
-
-		upstreamUriQuery := es.NewPrefixQuery("upstream_uri.keyword",
-			"/mep/mec_service_mgmt/v1/services")
-		boolQuery.Filter(upstreamUriQuery)
-
-		requestMethodQuery := es.NewMatchQuery("request.method", "GET")
-		boolQuery.Filter(requestMethodQuery)
-
-		timeRangeQuery := getTimeRange(i)
-		boolQuery.Filter(timeRangeQuery)
-
-		resp, err := EsClient.Count(meputil.KongHttpLogIndex).Query(boolQuery).Do(context.Background())
-		if err != nil {
-			dayCount[i] = 0
+		query.Start = today.Add(-time.Duration(i) * day)
+		if i == 0 {
+			query.End = time.Time{}
 		} else {
-			dayCount[i] = int(resp)
+			query.End = query.Start.Add(day)
 		}
-	}
-	return dayCount
-}
-
-func statisticRegisterServices() interface{} {
-	dayCount := make([]int, meputil.WeekDay)
-	for i := 0; i < meputil.WeekDay; i++ {
-		boolQuery := es.NewBoolQuery()
-		serviceNameQuery := es.NewTermsQuery("service.name", "mepserver")
-		boolQuery.Filter(serviceNameQuery)
-
-		upstreamUriQuery := es.NewRegexpQuery("upstream_uri.keyword",
-			"/mep/mec_service_mgmt/v1/applications/[-A-Za-z0-9]+/services")
-		boolQuery.Filter(upstreamUriQuery)
-
-		requestMethodQuery := es.NewMatchQuery("request.method", "POST")
-		boolQuery.Filter(requestMethodQuery)
-
-		timeRangeQuery := getTimeRange(i)
-		boolQuery.Filter(timeRangeQuery)
-
-		resp, err := EsClient.Count(meputil.KongHttpLogIndex).Query(boolQuery).Do(context.Background())
+		count, err := store.Count(&query)
 		if err != nil {
-			dayCount[i] = 0
-		} else {
-			dayCount[i] = int(resp)
+			log.Errorf(err, "Count api gw http logs failed.")
+			continue
 		}
+		dayCount[i] = count
 	}
 	return dayCount
 }
 
-func getTimeRange(i int) *es.RangeQuery {
-	// range by day
-	if i == 0 {
-		return es.NewRangeQuery(startedAt).Gte("now/d")
-	} else {
-		return es.NewRangeQuery(startedAt).Gte("now-" + strconv.Itoa(i) + "d/d").Lt("now-" + strconv.Itoa(
-			i-1) + "d/d")
-	}
-}
-
-func statisticAppServices(names []string) []interface{} {
+func statisticAppServices(store httplog.HttpLogStore, names []string) []interface{} {
 	list := make([]interface{}, 0)
 	for _, serviceName := range names {
 		serviceMap := make(map[string]interface{})
-		dayCount := make([]int, meputil.WeekDay)
-		for i := 0; i < meputil.WeekDay; i++ {
-			boolQuery := es.NewBoolQuery()
-			serviceNameQuery := es.NewPrefixQuery("service.name.keyword", serviceName)
-			boolQuery.Filter(serviceNameQuery)
-
-			timeRangeQuery := getTimeRange(i)
-			boolQuery.Filter(timeRangeQuery)
-
-			resp, err := EsClient.Count(meputil.KongHttpLogIndex).Query(boolQuery).Do(context.Background())
-			if err != nil {
-				dayCount[i] = 0
-			} else {
-				dayCount[i] = int(resp)
-			}
-		}
 		serviceMap["name"] = serviceName
 		serviceMap["desc"] = ""
-		serviceMap["callTimes"] = dayCount
+		serviceMap["callTimes"] = countByDay(store, httplog.Query{ServiceNamePrefix: serviceName})
 		list = append(list, serviceMap)
 	}
 	return list
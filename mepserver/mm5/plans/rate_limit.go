@@ -0,0 +1,216 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package plans
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+
+	"mepserver/common/arch/workspace"
+	"mepserver/common/extif/apigw"
+	meputil "mepserver/common/util"
+)
+
+const serNameStr = ":serName"
+
+// DecodeRateLimitReq step to decode the rate limit requests
+type DecodeRateLimitReq struct {
+	workspace.TaskBase
+	R             *http.Request `json:"r,in"`
+	AppInstanceId string        `json:"appInstanceId,out"`
+	SerName       string        `json:"serName,out"`
+	RestBody      interface{}   `json:"restBody,out"`
+}
+
+// WithBody handle input body initialization
+func (t *DecodeRateLimitReq) WithBody(body interface{}) *DecodeRateLimitReq {
+	t.RestBody = body
+	return t
+}
+
+// OnRequest handles the rate limit request decoding
+func (t *DecodeRateLimitReq) OnRequest(data string) workspace.TaskCode {
+	query := t.R.URL.Query()
+	t.AppInstanceId = query.Get(meputil.AppInstanceIdStr)
+	t.SerName = query.Get(serNameStr)
+	if len(t.AppInstanceId) != 0 {
+		if err := meputil.ValidateUUID(t.AppInstanceId); err != nil {
+			log.Error("Consumer app instance id validation failed.", err)
+			t.SetFirstErrorCode(meputil.RequestParamErr, "invalid app instance id")
+			return workspace.TaskFinish
+		}
+	}
+
+	limit, ok := t.RestBody.(*apigw.RateLimit)
+	if !ok {
+		return workspace.TaskFinish
+	}
+	msg, err := ioutil.ReadAll(t.R.Body)
+	if err != nil {
+		log.Error("Rate limit body read failed.", nil)
+		t.SetFirstErrorCode(meputil.SerErrFailBase, "read request body error")
+		return workspace.TaskFinish
+	}
+	if len(msg) > meputil.RequestBodyLength {
+		log.Errorf(nil, "Rate limit body too large %d.", len(msg))
+		t.SetFirstErrorCode(meputil.RequestParamErr, "request body too large")
+		return workspace.TaskFinish
+	}
+	if err = json.Unmarshal(msg, limit); err != nil {
+		log.Errorf(nil, "Rate limit body unmarshalling failed.")
+		t.SetFirstErrorCode(meputil.ParseInfoErr, "unmarshal request body error")
+		return workspace.TaskFinish
+	}
+	// the consumer and the service are identified by the path
+	limit.ConsumerAppInstanceId = t.AppInstanceId
+	limit.SerName = t.SerName
+	if err = limit.Validate(); err != nil {
+		log.Error("Rate limit validation failed.", err)
+		t.SetFirstErrorCode(meputil.RequestParamErr, err.Error())
+		return workspace.TaskFinish
+	}
+	return workspace.TaskFinish
+}
+
+// RateLimitSet step to set the rate limit of a consumer on a service
+type RateLimitSet struct {
+	workspace.TaskBase
+	RestBody   interface{} `json:"restBody,in"`
+	HttpRsp    interface{} `json:"httpRsp,out"`
+	apiGateway apigw.APIGateway
+}
+
+// WithAPIGateway inputs the api gateway instance
+func (t *RateLimitSet) WithAPIGateway(apiGateway apigw.APIGateway) *RateLimitSet {
+	t.apiGateway = apiGateway
+	return t
+}
+
+// OnRequest pushes the rate limit to the api gateway and persists it
+func (t *RateLimitSet) OnRequest(data string) workspace.TaskCode {
+	limit, ok := t.RestBody.(*apigw.RateLimit)
+	if !ok {
+		t.SetFirstErrorCode(meputil.ParseInfoErr, "rest-body failed")
+		return workspace.TaskFinish
+	}
+	previous, _ := apigw.GetRateLimit(limit.ConsumerAppInstanceId, limit.SerName)
+
+	if err := apigw.ApplyRateLimit(apigw.WithContext(t.apiGateway, t.TraceContext()), limit); err != nil {
+		log.Errorf(err, "Rate limit apply failed on api gateway.")
+		t.SetFirstErrorCode(meputil.RemoteServerErr, "rate limit apply failed on api gateway")
+		return workspace.TaskFinish
+	}
+	if errCode := apigw.SaveRateLimit(limit); errCode != 0 {
+		log.Errorf(nil, "Rate limit save failed, reverting the api gateway.")
+		t.revert(limit, previous)
+		t.SetFirstErrorCode(meputil.OperateDataWithEtcdErr, "rate limit save failed")
+		return workspace.TaskFinish
+	}
+	t.HttpRsp = limit
+	return workspace.TaskFinish
+}
+
+func (t *RateLimitSet) revert(limit *apigw.RateLimit, previous *apigw.RateLimit) {
+	var err error
+	if previous != nil {
+		err = apigw.ApplyRateLimit(apigw.WithContext(t.apiGateway, t.TraceContext()), previous)
+	} else {
+		err = apigw.RemoveRateLimit(apigw.WithContext(t.apiGateway, t.TraceContext()), limit)
+	}
+	if err != nil {
+		log.Errorf(err, "Rate limit revert failed on api gateway.")
+	}
+}
+
+// RateLimitGet step to read the rate limit of a consumer on a service
+type RateLimitGet struct {
+	workspace.TaskBase
+	AppInstanceId string      `json:"appInstanceId,in"`
+	SerName       string      `json:"serName,in"`
+	HttpRsp       interface{} `json:"httpRsp,out"`
+}
+
+// OnRequest reads the rate limit from the data-store
+func (t *RateLimitGet) OnRequest(data string) workspace.TaskCode {
+	limit, errCode := apigw.GetRateLimit(t.AppInstanceId, t.SerName)
+	if errCode != 0 {
+		log.Errorf(nil, "Rate limit retrieval failed.")
+		t.SetFirstErrorCode(workspace.ErrCode(errCode), "rate limit retrieval failed")
+		return workspace.TaskFinish
+	}
+	t.HttpRsp = limit
+	return workspace.TaskFinish
+}
+
+// RateLimitsGet step to read the rate limits of all the consumers or a consumer
+type RateLimitsGet struct {
+	workspace.TaskBase
+	AppInstanceId string      `json:"appInstanceId,in"`
+	HttpRsp       interface{} `json:"httpRsp,out"`
+}
+
+// OnRequest reads the rate limits from the data-store
+func (t *RateLimitsGet) OnRequest(data string) workspace.TaskCode {
+	limits, errCode := apigw.GetRateLimits(t.AppInstanceId)
+	if errCode != 0 {
+		log.Errorf(nil, "Rate limits retrieval failed.")
+		t.SetFirstErrorCode(workspace.ErrCode(errCode), "rate limits retrieval failed")
+		return workspace.TaskFinish
+	}
+	t.HttpRsp = limits
+	return workspace.TaskFinish
+}
+
+// RateLimitDelete step to delete the rate limit of a consumer on a service
+type RateLimitDelete struct {
+	workspace.TaskBase
+	AppInstanceId string      `json:"appInstanceId,in"`
+	SerName       string      `json:"serName,in"`
+	HttpRsp       interface{} `json:"httpRsp,out"`
+	apiGateway    apigw.APIGateway
+}
+
+// WithAPIGateway inputs the api gateway instance
+func (t *RateLimitDelete) WithAPIGateway(apiGateway apigw.APIGateway) *RateLimitDelete {
+	t.apiGateway = apiGateway
+	return t
+}
+
+// OnRequest removes the rate limit from the api gateway and the data-store
+func (t *RateLimitDelete) OnRequest(data string) workspace.TaskCode {
+	limit, errCode := apigw.GetRateLimit(t.AppInstanceId, t.SerName)
+	if errCode != 0 {
+		log.Errorf(nil, "Rate limit retrieval failed on delete.")
+		t.SetFirstErrorCode(workspace.ErrCode(errCode), "rate limit retrieval failed")
+		return workspace.TaskFinish
+	}
+	if err := apigw.RemoveRateLimit(apigw.WithContext(t.apiGateway, t.TraceContext()), limit); err != nil {
+		log.Errorf(err, "Rate limit remove failed on api gateway.")
+		t.SetFirstErrorCode(meputil.RemoteServerErr, "rate limit remove failed on api gateway")
+		return workspace.TaskFinish
+	}
+	if errCode = apigw.DeleteRateLimit(t.AppInstanceId, t.SerName); errCode != 0 {
+		log.Errorf(nil, "Rate limit delete failed on data-store.")
+		t.SetFirstErrorCode(workspace.ErrCode(errCode), "rate limit delete failed")
+		return workspace.TaskFinish
+	}
+	t.HttpRsp = ""
+	return workspace.TaskFinish
+}
@@ -20,6 +20,7 @@ package plans
 import (
 	"github.com/apache/servicecomb-service-center/pkg/log"
 	"mepserver/common/arch/workspace"
+	"mepserver/common/extif/apigw"
 	"mepserver/common/models"
 	meputil "mepserver/common/util"
 	"mepserver/mp1"
@@ -27,6 +28,12 @@ import (
 	"net/url"
 )
 
+// governServiceInfo service information with the rate limits of the consumers on the api gateway
+type governServiceInfo struct {
+	*models.ServiceInfo
+	RateLimits []apigw.RateLimit `json:"rateLimits,omitempty"`
+}
+
 // AllServicesReq steps to query all services registered in mep
 type AllServicesReq struct {
 	workspace.TaskBase
@@ -39,7 +46,7 @@ func (t *AllServicesReq) OnRequest(data string) workspace.TaskCode {
 	services := getAllServices()
 
 	responseInfo := models.ResponseInfo{
-		Data:    services,
+		Data:    withRateLimits(services),
 		RetCode: meputil.SuccessRetCode,
 	}
 	t.HttpRsp = responseInfo
@@ -62,3 +69,23 @@ func getAllServices() []*models.ServiceInfo {
 
 	return serviceInfos
 }
+
+// withRateLimits attaches the rate limits to the services, services are reported without the limits on failure
+func withRateLimits(services []*models.ServiceInfo) []governServiceInfo {
+	limitsBySerName := make(map[string][]apigw.RateLimit)
+	limits, errCode := apigw.GetRateLimits("")
+	if errCode != 0 {
+		log.Warn("Rate limits retrieval failed for the service governance.")
+	}
+	for _, limit := range limits {
+		limitsBySerName[limit.SerName] = append(limitsBySerName[limit.SerName], limit)
+	}
+	governServices := make([]governServiceInfo, 0, len(services))
+	for _, service := range services {
+		governServices = append(governServices, governServiceInfo{
+			ServiceInfo: service,
+			RateLimits:  limitsBySerName[service.SerName],
+		})
+	}
+	return governServices
+}
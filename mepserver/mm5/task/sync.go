@@ -17,39 +17,162 @@
 package task
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"mepserver/common/config"
 	"mepserver/common/extif/backend"
 	"mepserver/common/extif/dataplane"
+	dpCommon "mepserver/common/extif/dataplane/common"
 	"mepserver/common/extif/dns"
+	"mepserver/common/metrics"
 	"mepserver/common/models"
 	"mepserver/common/util"
 	"net/http"
 	"runtime/debug"
 	"sync"
+	"time"
+	"tracing"
 
 	"github.com/apache/servicecomb-service-center/pkg/log"
 )
 
-// Worker keeps the asynchronous task parameters
+// Worker keeps the asynchronous task parameters, the data-plane and the dns agent are swapped on the configuration
+// reload while the running tasks keep the ones they started with
 type Worker struct {
 	waitWorkerFinish sync.WaitGroup
+	mutex            sync.RWMutex
+	stageMutex       sync.Mutex
 	dnsTypeConfig    string
 	dataPlane        dataplane.DataPlane
 	dnsAgent         dns.DNSAgent
+	// reloaded the backends created by the reload validation, the reload apply swaps them in
+	reloaded *workerBackends
 }
 
+// workerBackends the data-plane and the dns agent created as per the configurations
+type workerBackends struct {
+	dataPlane dataplane.DataPlane
+	dnsAgent  dns.DNSAgent
+	dnsType   string
+}
+
+// appDWorker the appd sync worker of the mep server, the mm5 appd configurations and the mp1 rule updates stage their
+// tasks on it
+var appDWorker Worker
+var appDWorkerOnce sync.Once
+var appDWorkerErr error
+
 const dataInconsistentError = "Failed to revert the data, this will lead to data inconsistency."
 const ExistRuleError = "existing rule expected"
 
 // InitializeWorker initialize worker instance
 func (w *Worker) InitializeWorker(dataPlane dataplane.DataPlane, dnsAgent dns.DNSAgent, dnsType string) *Worker {
+	w.mutex.Lock()
 	w.dataPlane = dataPlane
 	w.dnsAgent = dnsAgent
 	w.dnsTypeConfig = dnsType
+	w.mutex.Unlock()
 	return w
 }
 
+// Configure creates the data-plane and the dns agent as per the configurations and swaps them in, the current ones
+// are kept on failure
+func (w *Worker) Configure(mepConfig *config.MepServerConfig) error {
+	backends, err := createBackends(mepConfig)
+	if err != nil {
+		return err
+	}
+	w.InitializeWorker(backends.dataPlane, backends.dnsAgent, backends.dnsType)
+	return nil
+}
+
+// createBackends creates and initializes the data-plane and the dns agent as per the configurations
+func createBackends(mepConfig *config.MepServerConfig) (*workerBackends, error) {
+	// Checking if local or both is configured
+	var dnsAgent dns.DNSAgent
+	if mepConfig.DNSAgent.Type != util.DnsAgentTypeDataPlane {
+		var err error
+		if dnsAgent, err = dns.NewDNSAgent(mepConfig); err != nil {
+			return nil, err
+		}
+	}
+
+	// select data plane as per configuration
+	dataPlane := dpCommon.CreateDataPlane(mepConfig)
+	if dataPlane == nil {
+		return nil, fmt.Errorf("unsupported data-plane %s", mepConfig.DataPlane.Type)
+	}
+	if err := dataPlane.InitDataPlane(mepConfig); err != nil {
+		return nil, err
+	}
+	log.Infof("Data-plane initialized to %s, dns agent to %s.", mepConfig.DataPlane.Type, mepConfig.DNSAgent.Type)
+	return &workerBackends{dataPlane: metrics.InstrumentDataPlane(dataPlane),
+		dnsAgent: metrics.InstrumentDNSAgent(dnsAgent), dnsType: mepConfig.DNSAgent.Type}, nil
+}
+
+// AppDWorker returns the appd sync worker shared by the mm5 and the mp1 interfaces
+func AppDWorker() *Worker {
+	return &appDWorker
+}
+
+// ConfigureAppDWorker configures the shared appd sync worker and registers it for the configuration reload, only the
+// first call does the configuration, the later ones return its result
+func ConfigureAppDWorker(mepConfig *config.MepServerConfig) error {
+	appDWorkerOnce.Do(func() {
+		if appDWorkerErr = appDWorker.Configure(mepConfig); appDWorkerErr != nil {
+			return
+		}
+		config.Watch(&appDWorker)
+	})
+	return appDWorkerErr
+}
+
+// LockStaging locks the staging of the appd tasks, the check of the on-going operation of an application instance
+// and the staging of its new task are done under this lock so that two requests can not stage tasks concurrently
+func (w *Worker) LockStaging() {
+	w.stageMutex.Lock()
+}
+
+// UnlockStaging unlocks the staging of the appd tasks
+func (w *Worker) UnlockStaging() {
+	w.stageMutex.Unlock()
+}
+
+// ValidateReload creates and initializes the data-plane and the dns agent of the reloaded configurations, the
+// reload is rejected if they fail
+func (w *Worker) ValidateReload(newConfig *config.MepServerConfig) error {
+	backends, err := createBackends(newConfig)
+	if err != nil {
+		return err
+	}
+	w.mutex.Lock()
+	w.reloaded = backends
+	w.mutex.Unlock()
+	return nil
+}
+
+// ApplyReload swaps in the data-plane and the dns agent created by the reload validation
+func (w *Worker) ApplyReload(newConfig *config.MepServerConfig) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.reloaded == nil {
+		log.Warn("Data-plane reconfiguration is not validated, the current data-plane is kept.")
+		return
+	}
+	w.dataPlane = w.reloaded.dataPlane
+	w.dnsAgent = w.reloaded.dnsAgent
+	w.dnsTypeConfig = w.reloaded.dnsType
+	w.reloaded = nil
+}
+
+func (w *Worker) backends() (dataplane.DataPlane, dns.DNSAgent, string) {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+	return w.dataPlane, w.dnsAgent, w.dnsTypeConfig
+}
+
 // StartNewTask start new task for sync
 func (w *Worker) StartNewTask(appName, appInstanceId, taskId string) {
 	log.Infof("New appd sync task created(app-name: %s, app-id: %s, task-id: %s).", appName, appInstanceId, taskId)
@@ -71,8 +194,20 @@ func (w *Worker) ProcessAppDConfigSync(appName, appInstanceId, taskId string) {
 
 // ProcessDataPlaneSync Go Routine function to handle the sync of traffic and dns to the data-plane over mp2
 func (w *Worker) ProcessDataPlaneSync(appName, appInstanceId, taskId string) {
-
-	syncJob := newTask(appName, appInstanceId, taskId, w.dataPlane, w.dnsAgent, w.dnsTypeConfig)
+	start := time.Now()
+	// the sync runs after the response of the request, it is traced in a trace of its own
+	ctx, span := tracing.StartSpan(context.Background(), "appd sync", tracing.SpanKindInternal)
+	span.SetAttribute("mep.app_instance_id", appInstanceId)
+	span.SetAttribute("mep.task_id", taskId)
+	finish := func(failedPhase string) {
+		metrics.ObserveAppDSync(start, failedPhase)
+		if len(failedPhase) != 0 {
+			span.SetError(failedPhase + " failed")
+		}
+		span.End(nil)
+	}
+	dataPlane, dnsAgent, dnsType := w.backends()
+	syncJob := newTask(appName, appInstanceId, taskId, dataPlane, dns.WithContext(dnsAgent, ctx), dnsType)
 	if syncJob == nil {
 		log.Error("Failed to process the task, something went wrong.", nil)
 		_ = backend.DeletePaths([]string{util.AppDLCMJobsPath + appInstanceId}, true)
@@ -82,6 +217,7 @@ func (w *Worker) ProcessDataPlaneSync(appName, appInstanceId, taskId string) {
 			taskStatus.setFailureReason("Unexpected error in processing.")
 			_ = taskStatus.pushDB()
 		}
+		finish(metrics.PhaseInit)
 		return
 	}
 	err := syncJob.handleDNSRules(util.ApplyFunc)
@@ -92,6 +228,7 @@ func (w *Worker) ProcessDataPlaneSync(appName, appInstanceId, taskId string) {
 		if err != nil {
 			log.Error(dataInconsistentError, err)
 		}
+		finish(metrics.PhaseDNSRules)
 		return
 	}
 	err = syncJob.handleTrafficRules(util.ApplyFunc)
@@ -102,6 +239,7 @@ func (w *Worker) ProcessDataPlaneSync(appName, appInstanceId, taskId string) {
 		if err != nil {
 			log.Error(dataInconsistentError, err)
 		}
+		finish(metrics.PhaseTrafficRules)
 		return
 	}
 	err = syncJob.handleConfigDBWriteOnSuccess()
@@ -112,11 +250,12 @@ func (w *Worker) ProcessDataPlaneSync(appName, appInstanceId, taskId string) {
 		if err != nil {
 			log.Error(dataInconsistentError, err)
 		}
+		finish(metrics.PhaseConfigWrite)
 		return
 	}
 
 	_ = syncJob.cleanProcessingCache()
-
+	finish("")
 }
 
 type ruleOperation struct {
@@ -408,7 +547,7 @@ func (t *task) processDNSEntryApply(dnsNewRule *dataplane.DNSRule, dnsOldRule *d
 		var err error
 		if operation != nil && operation.apply != nil {
 			log.Debugf("DNS apply(method:%v, state: %v).", ruleStatus.Method, state)
-			err = operation.apply(ruleStatus.Id, dnsNewRule, dnsOldRule)
+			err = t.recordPartialFailure(ruleStatus.Id, operation.apply(ruleStatus.Id, dnsNewRule, dnsOldRule))
 		}
 		if err != nil {
 			log.Errorf(err, "DNS apply(method:%v, state: %v) failed in configuration.", ruleStatus.Method, state)
@@ -440,7 +579,7 @@ func (t *task) processDNSEntryRevert(dnsNewRule *dataplane.DNSRule, dnsOldRule *
 		var err error
 		if operation != nil && operation.revert != nil {
 			log.Debugf("DNS revert(method:%v, state: %v).", ruleStatus.Method, state)
-			err = operation.revert(ruleStatus.Id, dnsNewRule, dnsOldRule)
+			err = t.recordPartialFailure(ruleStatus.Id, operation.revert(ruleStatus.Id, dnsNewRule, dnsOldRule))
 		}
 		if err != nil {
 			log.Errorf(err, "DNS revert(method:%v, state: %v) failed in configuration.", ruleStatus.Method, state)
@@ -465,6 +604,23 @@ func (t *task) processDNSEntryRevert(dnsNewRule *dataplane.DNSRule, dnsOldRule *
 	return nil
 }
 
+// recordPartialFailure records the secondary dns servers failed in applying the rule, the rule is considered as
+// applied once written to the primary dns server
+func (t *task) recordPartialFailure(ruleId string, err error) error {
+	var partialErr *dns.PartialFailureError
+	if !errors.As(err, &partialErr) {
+		return err
+	}
+	log.Warnf("DNS rule %s partially applied, failed on %v.", ruleId, partialErr.FailedServers)
+	t.statusDb.setFailedServers(ruleId, partialErr.FailedServers)
+	return nil
+}
+
+func isPartialFailure(err error) bool {
+	var partialErr *dns.PartialFailureError
+	return errors.As(err, &partialErr)
+}
+
 func (t *task) addDNSOnMp2(ruleId string, newRule interface{}, existingRule interface{}) error {
 	dnsRule := newRule.(*dataplane.DNSRule)
 	if dnsRule.State == "" {
@@ -534,6 +690,12 @@ func (t *task) deleteDNSOnMp2(ruleId string, newRule interface{}, existingRule i
 	return t.dataPlane.DeleteDNSRule(appInfo, ruleId)
 }
 
+// dnsRuleAgent returns the dns agent managing the record of the rule in its zone and source filter view, the view is
+// deleted from the dns server with its last record
+func (t *task) dnsRuleAgent(dnsRule *dataplane.DNSRule) dns.DNSAgent {
+	return dns.WithSourceFilter(dns.WithZone(t.dnsAgent, dnsRule.Zone), dnsRule.SourceFilter)
+}
+
 func (t *task) addDNSOnLocalDns(ruleId string, newRule interface{}, existingRule interface{}) error {
 	dnsRule := newRule.(*dataplane.DNSRule)
 	if dnsRule.State == "" {
@@ -548,7 +710,7 @@ func (t *task) addDNSOnLocalDns(ruleId string, newRule interface{}, existingRule
 	if dnsRule.IPAddressType == util.IPv6Type {
 		rrType = util.RRTypeAAAA
 	}
-	err := t.dnsAgent.AddResourceRecord(
+	err := t.dnsRuleAgent(dnsRule).AddResourceRecord(
 		dnsRule.DomainName, rrType, util.RRClassIN, []string{dnsRule.IPAddress},
 		dnsRule.TTL)
 	return err
@@ -573,15 +735,26 @@ func (t *task) setDNSOnLocalDns(ruleId string, newRule interface{}, existingRule
 
 	if dnsExistingRule.State == util.InactiveState && dnsRule.State == util.ActiveState {
 		// Add rule
-		return t.dnsAgent.AddResourceRecord(
+		return t.dnsRuleAgent(dnsRule).AddResourceRecord(
 			dnsRule.DomainName, rrType, util.RRClassIN, []string{dnsRule.IPAddress},
 			dnsRule.TTL)
 	} else if dnsExistingRule.State == util.ActiveState && dnsRule.State == util.InactiveState {
 		// Delete rule
-		return t.dnsAgent.DeleteResourceRecord(dnsRule.DomainName, rrType)
+		return t.dnsRuleAgent(dnsExistingRule).DeleteResourceRecord(dnsRule.DomainName, rrType)
+	} else if dnsExistingRule.State == util.ActiveState && (dns.FQDN(dnsRule.Zone) != dns.FQDN(dnsExistingRule.Zone) ||
+		dns.ViewName(dnsRule.SourceFilter) != dns.ViewName(dnsExistingRule.SourceFilter)) {
+		// Move the record to the new zone or view
+		err := t.dnsRuleAgent(dnsExistingRule).DeleteResourceRecord(dnsExistingRule.DomainName,
+			rrType)
+		if err != nil && !isPartialFailure(err) {
+			return err
+		}
+		return t.dnsRuleAgent(dnsRule).AddResourceRecord(
+			dnsRule.DomainName, rrType, util.RRClassIN, []string{dnsRule.IPAddress},
+			dnsRule.TTL)
 	}
 
-	return t.dnsAgent.SetResourceRecord(
+	return t.dnsRuleAgent(dnsRule).SetResourceRecord(
 		dnsRule.DomainName, rrType, util.RRClassIN, []string{dnsRule.IPAddress},
 		dnsRule.TTL)
 }
@@ -604,7 +777,7 @@ func (t *task) deleteDNSOnLocalDns(ruleId string, newRule interface{}, existingR
 	if dnsRule.IPAddressType == util.IPv6Type {
 		rrType = util.RRTypeAAAA
 	}
-	err := t.dnsAgent.DeleteResourceRecord(dnsRule.DomainName, rrType)
+	err := t.dnsRuleAgent(dnsExistingRule).DeleteResourceRecord(dnsRule.DomainName, rrType)
 	if err != nil {
 		return err
 	}
@@ -30,6 +30,7 @@ import (
 	"mepserver/common/models"
 	"mepserver/common/util"
 	"net/http"
+	"reflect"
 	"testing"
 )
 
@@ -72,7 +73,7 @@ func TestProcessDataPlaneSync(t *testing.T) {
 	defer patch3.Reset()
 	defer patch4.Reset()
 	noneDataPlane := &none.NoneDataPlane{}
-	dnsRules := dns.NewRestDNSAgent(&config.MepServerConfig{})
+	dnsRules := dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)
 	worker := Worker{dataPlane: noneDataPlane, dnsAgent: dnsRules}
 	worker.waitWorkerFinish.Add(1)
 	taskId := uuid.NewV4().String()
@@ -122,7 +123,7 @@ func TestHandleTrafficRules(t *testing.T) {
 		appDConfig: &models.AppDConfig{AppName: "AppName", AppTrafficRule: filters}},
 		statusDb: &statusDB{appInstanceId: defaultAppInstanceId,
 			status: &models.TaskStatus{Progress: 1, DNSRuleStatusLst: ruleList, TrafficRuleStatusLst: ruleList}},
-		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.MepServerConfig{})}
+		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)}
 
 	j.trfStateMachine = [][]*ruleOperation{
 		util.OperCreate: {
@@ -159,7 +160,7 @@ func TestHandleDNSRules(t *testing.T) {
 		appDConfig: &models.AppDConfig{AppName: "AppName", AppDNSRule: filters}},
 		statusDb: &statusDB{appInstanceId: defaultAppInstanceId,
 			status: &models.TaskStatus{Progress: 1, DNSRuleStatusLst: ruleList, TrafficRuleStatusLst: ruleList}},
-		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.MepServerConfig{})}
+		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)}
 
 	j.dnsStateMachine = [][]*ruleOperation{
 		util.OperModify: {
@@ -193,7 +194,7 @@ func TestHandleDNSRulesForDelete(t *testing.T) {
 		appDConfig: &models.AppDConfig{AppName: "AppName", AppDNSRule: filters}},
 		statusDb: &statusDB{appInstanceId: defaultAppInstanceId,
 			status: &models.TaskStatus{Progress: 1, DNSRuleStatusLst: ruleList, TrafficRuleStatusLst: ruleList}},
-		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.MepServerConfig{})}
+		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)}
 
 	j.dnsStateMachine = [][]*ruleOperation{
 		util.OperCreate: {
@@ -215,7 +216,7 @@ func TestHandleConfigDBWriteOnSuccess(t *testing.T) {
 		appDConfig: &models.AppDConfig{AppName: "AppName"}},
 		statusDb: &statusDB{appInstanceId: defaultAppInstanceId,
 			status: &models.TaskStatus{Progress: 1, DNSRuleStatusLst: ruleList, TrafficRuleStatusLst: ruleList}},
-		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.MepServerConfig{})}
+		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)}
 
 	patch1 := gomonkey.ApplyFunc(backend.PutRecord, func(path string, value []byte) int {
 		return 0
@@ -247,7 +248,7 @@ func TestNewTask(t *testing.T) {
 	defer patch4.Reset()
 
 	noneDataPlane := &none.NoneDataPlane{}
-	dnsRules := dns.NewRestDNSAgent(&config.MepServerConfig{})
+	dnsRules := dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)
 	worker := Worker{dataPlane: noneDataPlane, dnsAgent: dnsRules}
 	newTask("AppName", defaultAppInstanceId, ruleId, worker.dataPlane, worker.dnsAgent, worker.dnsTypeConfig)
 
@@ -257,8 +258,8 @@ func TestSetDNSOnLocalDns(t *testing.T) {
 
 	dnsRule := dataplane.DNSRule{DNSRuleID: ruleId, IPAddressType: "IP_V6", IPAddress: exampleIPAddress, State: "ACTIVE"}
 
-	j := &task{appInstanceId: defaultAppInstanceId, taskId: ruleId, dnsAgent: dns.NewRestDNSAgent(&config.MepServerConfig{})}
-	patch1 := gomonkey.ApplyFunc(dns.NewRestDNSAgent(&config.MepServerConfig{}).SetResourceRecord, func(host, rrtype, class string, pointTo []string, ttl uint32) error {
+	j := &task{appInstanceId: defaultAppInstanceId, taskId: ruleId, dnsAgent: dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)}
+	patch1 := gomonkey.ApplyFunc(dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone).SetResourceRecord, func(host, rrtype, class string, pointTo []string, ttl uint32) error {
 		return nil
 	})
 	defer patch1.Reset()
@@ -277,7 +278,7 @@ func TestProcessTrfEntryRevert(t *testing.T) {
 		appDConfig: &models.AppDConfig{AppName: "AppName", AppTrafficRule: filters}},
 		statusDb: &statusDB{appInstanceId: defaultAppInstanceId,
 			status: &models.TaskStatus{Progress: 1, DNSRuleStatusLst: ruleList, TrafficRuleStatusLst: ruleList}},
-		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.MepServerConfig{})}
+		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)}
 
 	j.trfStateMachine = [][]*ruleOperation{
 		util.OperCreate: {
@@ -314,7 +315,7 @@ func TestHandleConfigDBWriteOnError(t *testing.T) {
 		appDConfig: &models.AppDConfig{AppName: "AppName", Operation: http.MethodPost}},
 		statusDb: &statusDB{appInstanceId: defaultAppInstanceId,
 			status: &models.TaskStatus{Progress: 1, DNSRuleStatusLst: ruleList, TrafficRuleStatusLst: ruleList}},
-		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.MepServerConfig{})}
+		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)}
 
 	patch1 := gomonkey.ApplyFunc(backend.PutRecord, func(path string, value []byte) int {
 		return 1
@@ -334,7 +335,7 @@ func TestHandleTrafficRulesRevert(t *testing.T) {
 		appDConfig: &models.AppDConfig{AppName: "AppName", AppTrafficRule: filters}},
 		statusDb: &statusDB{appInstanceId: defaultAppInstanceId,
 			status: &models.TaskStatus{Progress: 1, DNSRuleStatusLst: ruleList, TrafficRuleStatusLst: ruleList}},
-		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.MepServerConfig{})}
+		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)}
 
 	j.trfStateMachine = [][]*ruleOperation{
 		util.OperCreate: {
@@ -376,7 +377,7 @@ func TestHandleTrafficRulesGetRule(t *testing.T) {
 		appDConfigDb: &appDConfigDB{appInstanceId: defaultAppInstanceId, appDConfig: &models.AppDConfig{AppName: "AppName", AppTrafficRule: filters}},
 		statusDb: &statusDB{appInstanceId: defaultAppInstanceId,
 			status: &models.TaskStatus{Progress: 1, DNSRuleStatusLst: ruleList, TrafficRuleStatusLst: ruleList}},
-		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.MepServerConfig{})}
+		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)}
 
 	j.trfStateMachine = [][]*ruleOperation{
 		util.OperCreate: {
@@ -419,7 +420,7 @@ func TestHandleTrafficRulesRevertRules(t *testing.T) {
 		appDConfig: &models.AppDConfig{AppName: "AppName", AppTrafficRule: filters}},
 		statusDb: &statusDB{appInstanceId: defaultAppInstanceId,
 			status: &models.TaskStatus{Progress: 1, DNSRuleStatusLst: ruleList, TrafficRuleStatusLst: ruleList}},
-		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.MepServerConfig{})}
+		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)}
 
 	j.trfStateMachine = [][]*ruleOperation{
 		util.OperCreate: {
@@ -459,7 +460,7 @@ func TestHandleTrafficRulesSet(t *testing.T) {
 		appDConfigDb: &appDConfigDB{appInstanceId: defaultAppInstanceId, appDConfig: &models.AppDConfig{AppName: "AppName", AppTrafficRule: filters}},
 		statusDb: &statusDB{appInstanceId: defaultAppInstanceId,
 			status: &models.TaskStatus{Progress: 1, DNSRuleStatusLst: ruleList, TrafficRuleStatusLst: ruleList}},
-		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.MepServerConfig{})}
+		dataPlane: &none.NoneDataPlane{}, dnsAgent: dns.NewRestDNSAgent(&config.DNSAgent{}, config.Address{}, dns.RootZone)}
 
 	j.trfStateMachine = [][]*ruleOperation{
 		util.OperCreate: {
@@ -483,3 +484,113 @@ func TestHandleTrafficRulesSet(t *testing.T) {
 	err := j.handleTrafficRules(0)
 	assert.Equal(t, nil, err)
 }
+
+func TestWorkerReload(t *testing.T) {
+	worker := &Worker{}
+	mepConfig := &config.MepServerConfig{
+		DNSAgent:  config.DNSAgent{Type: util.DnsAgentTypeDataPlane},
+		DataPlane: config.DataPlane{Type: util.DataPlaneNone},
+	}
+	assert.NoError(t, worker.Configure(mepConfig))
+	_, dnsAgent, dnsType := worker.backends()
+	assert.Nil(t, dnsAgent)
+	assert.Equal(t, util.DnsAgentTypeDataPlane, dnsType)
+
+	newConfig := *mepConfig
+	newConfig.DNSAgent.Type = util.DnsAgentTypeLocal
+	assert.NoError(t, worker.ValidateReload(&newConfig))
+	worker.ApplyReload(&newConfig)
+	dataPlane, dnsAgent, dnsType := worker.backends()
+	assert.NotNil(t, dataPlane)
+	assert.NotNil(t, dnsAgent)
+	assert.Equal(t, util.DnsAgentTypeLocal, dnsType)
+
+	newConfig.DataPlane.Type = "unknown"
+	assert.Error(t, worker.ValidateReload(&newConfig))
+}
+
+func TestWorkerReloadInitDataPlaneFailed(t *testing.T) {
+	worker := &Worker{}
+	mepConfig := &config.MepServerConfig{
+		DNSAgent:  config.DNSAgent{Type: util.DnsAgentTypeDataPlane},
+		DataPlane: config.DataPlane{Type: util.DataPlaneNone},
+	}
+	assert.NoError(t, worker.Configure(mepConfig))
+	dataPlane, _, _ := worker.backends()
+
+	patches := gomonkey.ApplyMethod(reflect.TypeOf(&none.NoneDataPlane{}), "InitDataPlane",
+		func(*none.NoneDataPlane, *config.MepServerConfig) error {
+			return fmt.Errorf("data-plane unreachable")
+		})
+	defer patches.Reset()
+	newConfig := *mepConfig
+	newConfig.DNSAgent.Type = util.DnsAgentTypeLocal
+	assert.Error(t, worker.ValidateReload(&newConfig))
+	// the reload is rejected, the current data-plane and dns agent are kept
+	worker.ApplyReload(&newConfig)
+	currentDataPlane, dnsAgent, dnsType := worker.backends()
+	assert.Same(t, dataPlane, currentDataPlane)
+	assert.Nil(t, dnsAgent)
+	assert.Equal(t, util.DnsAgentTypeDataPlane, dnsType)
+}
+
+// zoneDNSAgent records the zones of the dns records and fails on the secondary servers
+type zoneDNSAgent struct {
+	zones *[]string
+	zone  string
+}
+
+func (d *zoneDNSAgent) WithZone(zone string) dns.DNSAgent {
+	return &zoneDNSAgent{zones: d.zones, zone: zone}
+}
+
+func (d *zoneDNSAgent) AddResourceRecord(host, rrType, class string, pointTo []string, ttl uint32) error {
+	*d.zones = append(*d.zones, d.zone)
+	return &dns.PartialFailureError{FailedServers: []string{"dns2:8080"}}
+}
+
+func (d *zoneDNSAgent) SetResourceRecord(host, rrType, class string, pointTo []string, ttl uint32) error {
+	*d.zones = append(*d.zones, d.zone)
+	return nil
+}
+
+func (d *zoneDNSAgent) DeleteResourceRecord(host, rrType string) error {
+	*d.zones = append(*d.zones, d.zone)
+	return nil
+}
+
+func TestProcessDNSEntryPartialFailure(t *testing.T) {
+	var zones []string
+	ruleStatus := models.RuleStatus{Id: ruleId, State: util.WaitLocal, Method: util.OperCreate}
+	dnsRule := dataplane.DNSRule{DNSRuleID: ruleId, DomainName: "app.mep", IPAddressType: "IP_V4",
+		IPAddress: "10.10.0.1", Zone: "mep."}
+	j := &task{appInstanceId: defaultAppInstanceId, taskId: ruleId, dnsAgent: &zoneDNSAgent{zones: &zones},
+		statusDb: &statusDB{appInstanceId: defaultAppInstanceId,
+			status: &models.TaskStatus{DNSRuleStatusLst: []models.RuleStatus{ruleStatus}}}}
+	j.dnsStateMachine = [][]*ruleOperation{
+		util.OperCreate: {
+			util.WaitMp2:           &ruleOperation{nil, nil, util.WaitLocal},
+			util.WaitLocal:         &ruleOperation{j.addDNSOnLocalDns, j.deleteDNSOnLocalDns, util.WaitConfigDBWrite},
+			util.WaitConfigDBWrite: &ruleOperation{nil, nil, 0},
+		},
+	}
+
+	patches := gomonkey.ApplyFunc(backend.PutRecord, func(path string, value []byte) int {
+		return 0
+	})
+	defer patches.Reset()
+
+	// Partial failure on the secondary servers is applied and reported
+	assert.NoError(t, j.processDNSEntryApply(&dnsRule, nil, ruleStatus))
+	assert.Equal(t, []string{"mep."}, zones)
+	assert.Equal(t, util.WaitConfigDBWrite, j.statusDb.status.DNSRuleStatusLst[0].State)
+	assert.Equal(t, []string{"dns2:8080"}, j.statusDb.status.DNSRuleStatusLst[0].FailedServers)
+
+	// Zone change moves the record
+	newRule := dnsRule
+	newRule.Zone = "edge.mep."
+	newRule.DomainName = "app.edge.mep"
+	zones = nil
+	assert.NoError(t, j.recordPartialFailure(ruleId, j.setDNSOnLocalDns(ruleId, &newRule, &dnsRule)))
+	assert.Equal(t, []string{"mep.", "edge.mep."}, zones)
+}
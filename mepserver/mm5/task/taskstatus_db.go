@@ -100,6 +100,16 @@ func (s *statusDB) setStateAndProgress(ruleType util.AppDRuleType, ruleId string
 	return err
 }
 
+// setFailedServers records the secondary dns servers failed in applying the dns rule, stored on the next state
+// update
+func (s *statusDB) setFailedServers(ruleId string, servers []string) {
+	ruleIndex := s.searchRule(s.status.DNSRuleStatusLst, ruleId)
+	if ruleIndex == -1 {
+		return
+	}
+	s.status.DNSRuleStatusLst[ruleIndex].FailedServers = servers
+}
+
 func (s *statusDB) pushDB() error {
 	path := util.AppDLCMTaskStatusPath + s.appInstanceId + "/" + s.taskId
 
@@ -20,11 +20,14 @@ package mp1
 import (
 	"fmt"
 	"mepserver/common/config"
+	"mepserver/common/extif/apigw"
+	apigwCommon "mepserver/common/extif/apigw/common"
 	"mepserver/common/extif/dataplane"
-	dpCommon "mepserver/common/extif/dataplane/common"
-	"mepserver/common/extif/dns"
+	"mepserver/common/extif/platsvc"
+	psCommon "mepserver/common/extif/platsvc/common"
 	"mepserver/common/models"
 	"net/http"
+	"time"
 
 	"github.com/apache/servicecomb-service-center/pkg/log"
 	"github.com/apache/servicecomb-service-center/pkg/rest"
@@ -32,7 +35,10 @@ import (
 
 	"mepserver/common"
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
+	"mepserver/common/tracing"
 	meputil "mepserver/common/util"
+	"mepserver/mm5/task"
 	"mepserver/mp1/plans"
 )
 
@@ -65,9 +71,9 @@ func initRouter() {
 // Mp1Service represents the mp1 service object
 type Mp1Service struct {
 	v4.MicroServiceService
-	config    *config.MepServerConfig
-	dnsAgent  dns.DNSAgent
-	dataPlane dataplane.DataPlane
+	config      *config.MepServerConfig
+	platformSvc platsvc.Provider
+	apiGateway  apigw.APIGateway
 }
 
 // Init initialize mp1 service
@@ -77,24 +83,46 @@ func (m *Mp1Service) Init() error {
 		return fmt.Errorf("error: reading configuration failed")
 	}
 	m.config = mepConfig
+	audit.Init(mepConfig)
+	tracing.Init(mepConfig)
 
-	// Checking if local or both is configured
-	var dnsAgent dns.DNSAgent
-	if m.config.DNSAgent.Type != meputil.DnsAgentTypeDataPlane {
-		dnsAgent = dns.NewRestDNSAgent(mepConfig)
+	// the rule updates are synced by the appd sync worker of the mm5 interface
+	if err := task.ConfigureAppDWorker(mepConfig); err != nil {
+		return err
+	}
+
+	// select api gateway as per configuration, the registrations fail if the api gateway could not be initialized
+	apiGateway := apigwCommon.CreateAPIGateway(mepConfig)
+	if apiGateway == nil {
+		return fmt.Errorf("error: unsupported api gateway")
 	}
-	m.dnsAgent = dnsAgent
-	// select data plane as per configuration
-	dataPlane := dpCommon.CreateDataPlane(mepConfig)
-	if dataPlane == nil {
-		return fmt.Errorf("error: unsupported data-plane")
+	m.apiGateway = apiGateway
+	if err := apiGateway.InitAPIGateway(mepConfig); err != nil {
+		log.Errorf(err, "API gateway initialization failed.")
+	} else if m.config.APIGateway.Type != meputil.ApiGatewayNone {
+		interval := m.config.APIGateway.ReconcileInterval
+		if interval == 0 {
+			interval = meputil.DefaultApiGwReconcileInterval
+		}
+		apigw.NewReconciler(apiGateway, time.Duration(interval)*time.Second).Start()
 	}
+	log.Infof("API gateway initialized to %s.", m.config.APIGateway.Type)
 
-	if err := dataPlane.InitDataPlane(mepConfig); err != nil {
+	// select radio network information and location provider as per configuration
+	platformSvc := psCommon.CreateProvider(mepConfig)
+	if platformSvc == nil {
+		return fmt.Errorf("error: unsupported platform service provider")
+	}
+	if err := platformSvc.InitProvider(mepConfig); err != nil {
 		return err
 	}
-	m.dataPlane = dataPlane
-	log.Infof("Data plane initialized to %s.", m.config.DataPlane.Type)
+	platformSvc.SetEventHandler((&platformSvcNotifier{}).onCellChange)
+	m.platformSvc = platformSvc
+	if len(m.config.PlatformServices.Provider) != 0 &&
+		m.config.PlatformServices.Provider != meputil.PlatformSvcProviderNone {
+		go registerPlatformServices()
+	}
+	log.Infof("Platform service provider initialized to %s.", m.config.PlatformServices.Provider)
 
 	return nil
 }
@@ -148,6 +176,26 @@ func (m *Mp1Service) URLPatterns() []rest.Route {
 		{Method: rest.HTTP_METHOD_GET, Path: meputil.TimingPath + meputil.TimingCaps, Func: m.getTimingCaps},
 		{Method: rest.HTTP_METHOD_GET, Path: meputil.TransportPath, Func: m.getTransports},
 		{Method: rest.HTTP_METHOD_POST, Path: meputil.ConfirmReadyPath, Func: m.confirmReady},
+		// Radio network information
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.RabInfoPath, Func: m.getRabInfo},
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.PlmnInfoPath, Func: m.getPlmnInfo},
+		{Method: rest.HTTP_METHOD_POST, Path: meputil.RniSubscribePath, Func: m.rniSubscribe},
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.RniSubscribePath, Func: m.getRniSubscribes},
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.RniSubscribePath + meputil.SubscriptionIdPath,
+			Func: m.getRniSubscribe},
+		{Method: rest.HTTP_METHOD_DELETE, Path: meputil.RniSubscribePath + meputil.SubscriptionIdPath,
+			Func: m.delRniSubscribe},
+		// Location
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.LocationUsersPath, Func: m.getLocationUsers},
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.LocationZonesPath, Func: m.getLocationZones},
+		{Method: rest.HTTP_METHOD_POST, Path: meputil.LocationUserTrackingPath, Func: m.userTrackingSubscribe},
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.LocationUserTrackingPath, Func: m.getUserTrackingSubscribes},
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.LocationUserTrackingPath + meputil.SubscriptionIdPath,
+			Func: m.getUserTrackingSubscribe},
+		{Method: rest.HTTP_METHOD_DELETE, Path: meputil.LocationUserTrackingPath + meputil.SubscriptionIdPath,
+			Func: m.delUserTrackingSubscribe},
+		// Rule update task status
+		{Method: rest.HTTP_METHOD_GET, Path: meputil.AppTasksPath + meputil.TaskIdPath, Func: m.getAppTaskStatus},
 	}
 }
 
@@ -247,7 +295,7 @@ func (m *Mp1Service) serviceRegister(w http.ResponseWriter, r *http.Request) {
 		(&plans.DecodeRestReq{}).WithBody(&models.ServiceInfo{}),
 		&plans.RegisterLimit{},
 		&plans.RegisterServiceId{},
-		&plans.RegisterServiceInst{})
+		(&plans.RegisterServiceInst{}).WithAPIGateway(m.apiGateway))
 	workPlan.Finally(&common.SendHttpRsp{StatusCode: http.StatusCreated})
 
 	workspace.WkRun(workPlan)
@@ -271,7 +319,7 @@ func (m *Mp1Service) serviceUpdate(w http.ResponseWriter, r *http.Request) {
 	workPlan := NewWorkSpace(w, r)
 	workPlan.Try(
 		(&plans.DecodeRestReq{}).WithBody(&models.ServiceInfo{}),
-		&plans.UpdateInstance{})
+		(&plans.UpdateInstance{}).WithAPIGateway(m.apiGateway))
 	workPlan.Finally(&common.SendHttpRsp{})
 
 	workspace.WkRun(workPlan)
@@ -326,7 +374,7 @@ func (m *Mp1Service) dnsRuleUpdate(w http.ResponseWriter, r *http.Request) {
 	workPlan := NewWorkSpace(w, r)
 	workPlan.Try(
 		(&plans.DecodeDnsRestReq{}).WithBody(&dataplane.DNSRule{}),
-		(&plans.DNSRuleUpdate{}).WithDNSAgent(m.dnsAgent).WithDataPlane(m.dataPlane))
+		(&plans.DNSRuleUpdate{}).WithWorker(task.AppDWorker()))
 	workPlan.Finally(&common.SendHttpRsp{})
 
 	workspace.WkRun(workPlan)
@@ -378,7 +426,17 @@ func (m *Mp1Service) trafficRuleUpdate(w http.ResponseWriter, r *http.Request) {
 	workPlan := NewWorkSpace(w, r)
 	workPlan.Try(
 		(&plans.DecodeTrafficRestReq{}).WithBody(&dataplane.TrafficRule{}),
-		(&plans.TrafficRuleUpdate{}).WithDataPlane(m.dataPlane))
+		(&plans.TrafficRuleUpdate{}).WithWorker(task.AppDWorker()))
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) getAppTaskStatus(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodeAppTaskReq{},
+		&plans.AppTaskStatusGet{})
 	workPlan.Finally(&common.SendHttpRsp{})
 
 	workspace.WkRun(workPlan)
@@ -420,3 +478,125 @@ func (m *Mp1Service) confirmReady(w http.ResponseWriter, r *http.Request) {
 
 	workspace.WkRun(workPlan)
 }
+
+func (m *Mp1Service) getRabInfo(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodePlatformSvcReq{},
+		(&plans.RabInfoGet{}).WithProvider(m.platformSvc))
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) getPlmnInfo(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodePlatformSvcReq{},
+		(&plans.PlmnInfoGet{}).WithProvider(m.platformSvc))
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) rniSubscribe(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		(&plans.DecodePlatformSvcReq{}).WithBody(&models.CellChangeSubscription{}),
+		(&plans.AppSubscribeLimit{}).WithType(meputil.CellChangeSubscription),
+		(&plans.PlatformSvcSubscribe{}).WithType(meputil.CellChangeSubscription))
+	workPlan.Finally(&common.SendHttpRsp{StatusCode: http.StatusCreated})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) getRniSubscribes(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodePlatformSvcReq{},
+		(&plans.GetSubscribes{}).WithType(meputil.CellChangeSubscription))
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) getRniSubscribe(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodePlatformSvcReq{},
+		(&plans.GetOneSubscribe{}).WithType(meputil.CellChangeSubscription))
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) delRniSubscribe(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodePlatformSvcReq{},
+		(&plans.DelOneSubscribe{}).WithType(meputil.CellChangeSubscription))
+	workPlan.Finally(&common.SendHttpRsp{StatusCode: http.StatusNoContent})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) getLocationUsers(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodePlatformSvcReq{},
+		(&plans.LocationUsersGet{}).WithProvider(m.platformSvc))
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) getLocationZones(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodePlatformSvcReq{},
+		(&plans.LocationZonesGet{}).WithProvider(m.platformSvc))
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) userTrackingSubscribe(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		(&plans.DecodePlatformSvcReq{}).WithBody(&models.UserTrackingSubscriptionBody{}),
+		(&plans.AppSubscribeLimit{}).WithType(meputil.UserTrackingSubscription),
+		(&plans.PlatformSvcSubscribe{}).WithType(meputil.UserTrackingSubscription))
+	workPlan.Finally(&common.SendHttpRsp{StatusCode: http.StatusCreated})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) getUserTrackingSubscribes(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodePlatformSvcReq{},
+		(&plans.GetSubscribes{}).WithType(meputil.UserTrackingSubscription))
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) getUserTrackingSubscribe(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodePlatformSvcReq{},
+		(&plans.GetOneSubscribe{}).WithType(meputil.UserTrackingSubscription))
+	workPlan.Finally(&common.SendHttpRsp{})
+
+	workspace.WkRun(workPlan)
+}
+
+func (m *Mp1Service) delUserTrackingSubscribe(w http.ResponseWriter, r *http.Request) {
+	workPlan := NewWorkSpace(w, r)
+	workPlan.Try(
+		&plans.DecodePlatformSvcReq{},
+		(&plans.DelOneSubscribe{}).WithType(meputil.UserTrackingSubscription))
+	workPlan.Finally(&common.SendHttpRsp{StatusCode: http.StatusNoContent})
+
+	workspace.WkRun(workPlan)
+}
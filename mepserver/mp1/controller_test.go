@@ -19,16 +19,20 @@ package mp1
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/beevik/ntp"
-	"github.com/ghodss/yaml"
 	"io"
 	"io/ioutil"
 	"math/rand"
+	"mepserver/common/audit"
 	"mepserver/common/config"
+	"mepserver/common/extif/apigw"
+	"mepserver/common/extif/apigw/memory"
 	"mepserver/common/extif/dataplane"
 	"mepserver/common/extif/dataplane/none"
 	"mepserver/common/models"
@@ -36,7 +40,11 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -58,6 +66,7 @@ import (
 	"mepserver/common/extif/dns"
 	ntpc "mepserver/common/extif/ntp"
 	"mepserver/common/util"
+	"mepserver/mm5/task"
 )
 
 type mockHttpWriter struct {
@@ -73,9 +82,9 @@ const trafficRuleId = "8ft68t22-81f3-47bb-a2fc-56996er4tf37"
 const panicFormatString = "Panic: %v"
 const getDnsRulesUrlFormat = "/mep/mec_app_support/v1/applications/%s/dns_rules"
 const getDnsRuleUrlFormat = "/mep/mec_app_support/v1/applications/%s/dns_rules/%s"
-const appInstanceQueryFormat = ":appInstanceId=%s&;"
-const appIdAndDnsRuleIdQueryFormat = ":appInstanceId=%s&;:dnsRuleId=%s&;"
-const appIdAndTrafficRuleIdQueryFormat = ":appInstanceId=%s&;:trafficRuleId=%s&;"
+const appInstanceQueryFormat = ":appInstanceId=%s&"
+const appIdAndDnsRuleIdQueryFormat = ":appInstanceId=%s&:dnsRuleId=%s&"
+const appIdAndTrafficRuleIdQueryFormat = ":appInstanceId=%s&:trafficRuleId=%s&"
 const appInstanceIdHeader = "X-AppinstanceID"
 const responseStatusHeader = "X-Response-Status"
 const responseCheckFor200 = "Response status code must be 200"
@@ -125,11 +134,11 @@ const postAppTerminologiesUrl = "/mec_app_support/v1/applications/%s/services"
 const getAppTerminologiesUrl = "/mec_app_support/v1/applications/%s/services"
 const getOneAppTerminologiesUrl = "/mec_app_support/v1/applications/%s/services/%s"
 const delOneAppTerminologiesUrl = "/mec_app_support/v1/applications/%s/services/%s"
-const appIdAndServiceIdQueryFormat = ":appInstanceId=%s&;:serviceId=%s&;"
+const appIdAndServiceIdQueryFormat = ":appInstanceId=%s&:serviceId=%s&"
 const sampleServiceId = "f7e898d1c9ea9edd7496c761ddc92718"
 const sampleInstanceId = "f7e898d1c9ea9edd7496c761ddc92718"
 const serviceDiscoverUrlFormat = "/mep/mec_service_mgmt/v1/applications/%s/services"
-const serNameQueryFormat = ":appInstanceId=%s&;ser_name=%s&;"
+const serNameQueryFormat = ":appInstanceId=%s&ser_name=%s&"
 const getAllTrafficRuleUrl = "/mec_app_support/v1/applications/%s/traffic_rules"
 const getOneTrafficRuleUrl = "/mec_app_support/v1/applications/%s/traffic_rules/%s"
 const heartBeatUrl = "/mep/mec_service_mgmt/v1/applications/%s/services/%s/liveness"
@@ -309,7 +318,7 @@ func TestGetTrafficRules(t *testing.T) {
 
 }
 
-// Update a dns rule
+// Update a traffic rule, the update is applied by the appd sync worker
 func TestPutTrafficRule(t *testing.T) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -317,8 +326,16 @@ func TestPutTrafficRule(t *testing.T) {
 		}
 	}()
 
+	store := newMemDataStore()
+	patches := store.patch()
+	defer patches.Reset()
+	useAppDWorker("")
 	service := Mp1Service{}
 
+	storedRule := dataplane.TrafficRule{TrafficRuleID: trafficRuleId, FilterType: "FLOW", Priority: 5,
+		Action: "DROP", State: util.InactiveState}
+	store.putAppDConfig(t, &models.AppDConfig{AppTrafficRule: []dataplane.TrafficRule{storedRule}})
+
 	updateRule := dataplane.TrafficRule{
 		TrafficRuleID: trafficRuleId,
 		FilterType:    "FLOW",
@@ -327,42 +344,12 @@ func TestPutTrafficRule(t *testing.T) {
 		Action:        "DROP",
 		State:         util.InactiveState,
 	}
-	updateRuleBytes, _ := json.Marshal(updateRule)
-
-	// Create http get request
-	getRequest, _ := http.NewRequest("PUT",
-		fmt.Sprintf(getOneTrafficRuleUrl, defaultAppInstanceId, trafficRuleId),
-		bytes.NewReader(updateRuleBytes))
-	getRequest.URL.RawQuery = fmt.Sprintf(appIdAndTrafficRuleIdQueryFormat, defaultAppInstanceId, trafficRuleId)
-	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
-
-	// Mock the response writer
-	mockWriter := &mockHttpWriter{}
-	responseHeader := http.Header{} // Create http response header
-	mockWriter.On("Header").Return(responseHeader)
-	mockWriter.On("Write",
-		[]byte(fmt.Sprintf(writeTrafficPutObjectFormat, util.InactiveState))).
-		Return(0, nil)
-	mockWriter.On("WriteHeader", 200)
-
-	patches := gomonkey.ApplyFunc(backend.GetRecord, func(path string) ([]byte, int) {
-		TrafficRule := dataplane.TrafficRule{TrafficRuleID: trafficRuleId, FilterType: "FLOW", Priority: 5,
-			Action: "DROP", State: util.InactiveState}
-		var TrafficRules []dataplane.TrafficRule
-		TrafficRules = append(TrafficRules, TrafficRule)
-		entry := models.AppDConfig{AppTrafficRule: TrafficRules}
-		outBytes, _ := json.Marshal(&entry)
-		return outBytes, 0
-	})
-	defer patches.Reset()
-
-	// 23 is the order of the Traffic Rule put handler in the URLPattern
-	service.URLPatterns()[23].Func(mockWriter, getRequest)
-
-	assert.Equal(t, "200", responseHeader.Get(responseStatusHeader),
-		responseCheckFor200)
+	responseHeader, body := putTrafficRule(t, &service, &updateRule)
+	taskId := assertTaskAccepted(t, store, responseHeader, body, &updateRule)
 
-	mockWriter.AssertExpectations(t)
+	progress := waitTaskProgress(t, &service, taskId)
+	assert.Equal(t, util.TaskStateSuccess, progress.ConfigResult)
+	assert.Equal(t, util.InactiveState, store.getAppDConfig(t).AppTrafficRule[0].State)
 }
 
 // Query dns rules request in mp1 interface
@@ -696,7 +683,7 @@ func TestPutSingleDnsRule(t *testing.T) {
 	mockWriter.AssertExpectations(t)
 }
 
-// Update a dns rule from INACTIVE to ACTIVE
+// Update a dns rule from INACTIVE to ACTIVE, the update is applied by the appd sync worker
 func TestPutSingleDnsRuleActive(t *testing.T) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -704,34 +691,6 @@ func TestPutSingleDnsRuleActive(t *testing.T) {
 		}
 	}()
 
-	patchInit1 := gomonkey.ApplyFunc(config.LoadMepServerConfig, func() (*config.MepServerConfig, error) {
-		configData := `
-# dns agent configuration
-dnsAgent:
-  # values: local, dataplane, all
-  type: all
-  # local dns server end point
-  endPoint:
-    address:
-      host: localhost
-      port: 80
-
-
-# data plane option to use in Mp2 interface
-dataplane:
-  # values: none
-  type: none
-
-`
-		var mepConfig config.MepServerConfig
-		err := yaml.Unmarshal([]byte(configData), &mepConfig)
-		if err != nil {
-			assert.Fail(t, parseFail)
-		}
-		return &mepConfig, nil
-	})
-	defer patchInit1.Reset()
-
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, err2 := w.Write([]byte(""))
@@ -741,14 +700,15 @@ dataplane:
 	}))
 	defer ts.Close()
 
-	patchInit2 := gomonkey.ApplyFunc(dns.NewRestDNSAgent, func(config *config.MepServerConfig) *dns.RestDNSAgent {
-		parse, _ := url.Parse(ts.URL)
-		return &dns.RestDNSAgent{ServerEndPoint: parse}
-	})
-	defer patchInit2.Reset()
-
+	store := newMemDataStore()
+	patches := store.patch()
+	defer patches.Reset()
+	useAppDWorker(ts.URL)
 	service := Mp1Service{}
-	_ = service.Init()
+
+	storedRule := dataplane.DNSRule{DNSRuleID: dnsRuleId, DomainName: exampleDomainName, IPAddressType: "IP_V4",
+		IPAddress: exampleIPAddress, TTL: defaultTTL, State: util.InactiveState}
+	store.putAppDConfig(t, &models.AppDConfig{AppDNSRule: []dataplane.DNSRule{storedRule}})
 
 	updateRule := dataplane.DNSRule{
 		DNSRuleID:     dnsRuleId,
@@ -758,43 +718,14 @@ dataplane:
 		TTL:           defaultTTL,
 		State:         util.ActiveState,
 	}
-	updateRuleBytes, _ := json.Marshal(updateRule)
-
-	// Create http get request
-	getRequest, _ := http.NewRequest("PUT",
-		fmt.Sprintf(getDnsRuleUrlFormat, defaultAppInstanceId, dnsRuleId),
-		bytes.NewReader(updateRuleBytes))
-	getRequest.URL.RawQuery = fmt.Sprintf(appIdAndDnsRuleIdQueryFormat, defaultAppInstanceId, dnsRuleId)
-	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
-
-	// Mock the response writer
-	mockWriter := &mockHttpWriter{}
-	responseHeader := http.Header{} // Create http response header
-	mockWriter.On("Header").Return(responseHeader)
-	mockWriter.On("Write",
-		[]byte(fmt.Sprintf(writeObjectFormat, exampleIPAddress, util.ActiveState))).
-		Return(0, nil)
-
-	mockWriter.On("WriteHeader", 200)
-
-	patch1 := gomonkey.ApplyFunc(backend.GetRecord, func(path string) ([]byte, int) {
-		dnsRule := dataplane.DNSRule{DNSRuleID: dnsRuleId, DomainName: exampleDomainName, IPAddressType: "IP_V4", IPAddress: exampleIPAddress,
-			TTL: 30, State: util.InactiveState}
-		var dnsRules []dataplane.DNSRule
-		dnsRules = append(dnsRules, dnsRule)
-		entry := models.AppDConfig{AppDNSRule: dnsRules}
-		outBytes, _ := json.Marshal(&entry)
-		return outBytes, 0
-	})
-	defer patch1.Reset()
-
-	// 15 is the order of the DNS put handler in the URLPattern
-	service.URLPatterns()[15].Func(mockWriter, getRequest)
+	responseHeader, body := putDnsRule(t, &service, &updateRule)
 
-	assert.Equal(t, "200", responseHeader.Get(responseStatusHeader),
-		responseCheckFor200)
+	storedRule.State = util.ActiveState
+	taskId := assertTaskAccepted(t, store, responseHeader, body, &storedRule)
 
-	mockWriter.AssertExpectations(t)
+	progress := waitTaskProgress(t, &service, taskId)
+	assert.Equal(t, util.TaskStateSuccess, progress.ConfigResult)
+	assert.Equal(t, util.ActiveState, store.getAppDConfig(t).AppDNSRule[0].State)
 }
 
 // Update a dns rule from ACTIVE to ACTIVE
@@ -850,7 +781,7 @@ func TestPutSingleDnsRuleReActive(t *testing.T) {
 		outBytes, _ := json.Marshal(&entry)
 		return outBytes, 0
 	})
-	patch2 := gomonkey.ApplyFunc(dns.NewRestDNSAgent, func(config *config.MepServerConfig) *dns.RestDNSAgent {
+	patch2 := gomonkey.ApplyFunc(dns.NewRestDNSAgent, func(*config.DNSAgent, config.Address, string) *dns.RestDNSAgent {
 		parse, _ := url.Parse(ts.URL)
 		return &dns.RestDNSAgent{ServerEndPoint: parse}
 	})
@@ -867,7 +798,7 @@ func TestPutSingleDnsRuleReActive(t *testing.T) {
 	mockWriter.AssertExpectations(t)
 }
 
-// Update a dns rule from ACTIVE to INACTIVE
+// Update a dns rule from ACTIVE to INACTIVE, the update is applied by the appd sync worker
 func TestPutSingleDnsRuleInactive(t *testing.T) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -875,34 +806,6 @@ func TestPutSingleDnsRuleInactive(t *testing.T) {
 		}
 	}()
 
-	patchInit1 := gomonkey.ApplyFunc(config.LoadMepServerConfig, func() (*config.MepServerConfig, error) {
-		configData := `
-# dns agent configuration
-dnsAgent:
-  # values: local, dataplane, all
-  type: all
-  # local dns server end point
-  endPoint:
-    address:
-      host: localhost
-      port: 80
-
-
-# data plane option to use in Mp2 interface
-dataplane:
-  # values: none
-  type: none
-
-`
-		var mepConfig config.MepServerConfig
-		err := yaml.Unmarshal([]byte(configData), &mepConfig)
-		if err != nil {
-			assert.Fail(t, parseFail)
-		}
-		return &mepConfig, nil
-	})
-	defer patchInit1.Reset()
-
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, err2 := w.Write([]byte(""))
@@ -912,14 +815,15 @@ dataplane:
 	}))
 	defer ts.Close()
 
-	patchInit2 := gomonkey.ApplyFunc(dns.NewRestDNSAgent, func(config *config.MepServerConfig) *dns.RestDNSAgent {
-		parse, _ := url.Parse(ts.URL)
-		return &dns.RestDNSAgent{ServerEndPoint: parse}
-	})
-	defer patchInit2.Reset()
-
+	store := newMemDataStore()
+	patches := store.patch()
+	defer patches.Reset()
+	useAppDWorker(ts.URL)
 	service := Mp1Service{}
-	_ = service.Init()
+
+	storedRule := dataplane.DNSRule{DNSRuleID: dnsRuleId, DomainName: exampleDomainName, IPAddressType: "IP_V4",
+		IPAddress: exampleIPAddress, TTL: defaultTTL, State: util.ActiveState}
+	store.putAppDConfig(t, &models.AppDConfig{AppDNSRule: []dataplane.DNSRule{storedRule}})
 
 	updateRule := dataplane.DNSRule{
 		DNSRuleID:     dnsRuleId,
@@ -929,45 +833,17 @@ dataplane:
 		TTL:           defaultTTL,
 		State:         util.InactiveState,
 	}
-	updateRuleBytes, _ := json.Marshal(updateRule)
-
-	// Create http get request
-	getRequest, _ := http.NewRequest("PUT",
-		fmt.Sprintf(getDnsRuleUrlFormat, defaultAppInstanceId, dnsRuleId),
-		bytes.NewReader(updateRuleBytes))
-	getRequest.URL.RawQuery = fmt.Sprintf(appIdAndDnsRuleIdQueryFormat, defaultAppInstanceId, dnsRuleId)
-	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
-
-	// Mock the response writer
-	mockWriter := &mockHttpWriter{}
-	responseHeader := http.Header{} // Create http response header
-	mockWriter.On("Header").Return(responseHeader)
-	mockWriter.On("Write",
-		[]byte(fmt.Sprintf(writeObjectFormat, exampleIPAddress, util.InactiveState))).
-		Return(0, nil)
-	mockWriter.On("WriteHeader", 200)
-
-	patch1 := gomonkey.ApplyFunc(backend.GetRecord, func(path string) ([]byte, int) {
-		dnsRule := dataplane.DNSRule{DNSRuleID: dnsRuleId, DomainName: exampleDomainName, IPAddressType: "IP_V4", IPAddress: exampleIPAddress,
-			TTL: 30, State: util.ActiveState}
-		var dnsRules []dataplane.DNSRule
-		dnsRules = append(dnsRules, dnsRule)
-		entry := models.AppDConfig{AppDNSRule: dnsRules}
-		outBytes, _ := json.Marshal(&entry)
-		return outBytes, 0
-	})
-	defer patch1.Reset()
+	responseHeader, body := putDnsRule(t, &service, &updateRule)
 
-	// 15 is the order of the DNS put handler in the URLPattern
-	service.URLPatterns()[15].Func(mockWriter, getRequest)
-
-	assert.Equal(t, "200", responseHeader.Get(responseStatusHeader),
-		responseCheckFor200)
+	storedRule.State = util.InactiveState
+	taskId := assertTaskAccepted(t, store, responseHeader, body, &storedRule)
 
-	mockWriter.AssertExpectations(t)
+	progress := waitTaskProgress(t, &service, taskId)
+	assert.Equal(t, util.TaskStateSuccess, progress.ConfigResult)
+	assert.Equal(t, util.InactiveState, store.getAppDConfig(t).AppDNSRule[0].State)
 }
 
-// Update a dns rule from INACTIVE to ACTIVE when the server is not reachable
+// Update a dns rule from INACTIVE to ACTIVE when the server is not reachable, the task fails
 func TestPutSingleDnsRuleActiveWithServerNotReachable(t *testing.T) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -975,36 +851,18 @@ func TestPutSingleDnsRuleActiveWithServerNotReachable(t *testing.T) {
 		}
 	}()
 
-	patchInit1 := gomonkey.ApplyFunc(config.LoadMepServerConfig, func() (*config.MepServerConfig, error) {
-		configData := `
-# dns agent configuration
-dnsAgent:
-  # values: local, dataplane, all
-  type: all
-  # local dns server end point
-  endPoint:
-    address:
-      host: localhost
-      port: 80
-
-
-# data plane option to use in Mp2 interface
-dataplane:
-  # values: none
-  type: none
-
-`
-		var mepConfig config.MepServerConfig
-		err := yaml.Unmarshal([]byte(configData), &mepConfig)
-		if err != nil {
-			assert.Fail(t, parseFail)
-		}
-		return &mepConfig, nil
-	})
-	defer patchInit1.Reset()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Close()
 
+	store := newMemDataStore()
+	patches := store.patch()
+	defer patches.Reset()
+	useAppDWorker(ts.URL)
 	service := Mp1Service{}
-	_ = service.Init()
+
+	storedRule := dataplane.DNSRule{DNSRuleID: dnsRuleId, DomainName: exampleDomainName, IPAddressType: "IP_V4",
+		IPAddress: exampleIPAddress, TTL: defaultTTL, State: util.InactiveState}
+	store.putAppDConfig(t, &models.AppDConfig{AppDNSRule: []dataplane.DNSRule{storedRule}})
 
 	updateRule := dataplane.DNSRule{
 		DNSRuleID:     dnsRuleId,
@@ -1014,45 +872,20 @@ dataplane:
 		TTL:           defaultTTL,
 		State:         util.ActiveState,
 	}
-	updateRuleBytes, _ := json.Marshal(updateRule)
-
-	// Create http get request
-	getRequest, _ := http.NewRequest("PUT",
-		fmt.Sprintf(getDnsRuleUrlFormat, defaultAppInstanceId, dnsRuleId),
-		bytes.NewReader(updateRuleBytes))
-	getRequest.URL.RawQuery = fmt.Sprintf(appIdAndDnsRuleIdQueryFormat, defaultAppInstanceId, dnsRuleId)
-	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
-
-	// Mock the response writer
-	mockWriter := &mockHttpWriter{}
-	responseHeader := http.Header{} // Create http response header
-	mockWriter.On("Header").Return(responseHeader)
-	mockWriter.On("Write",
-		[]byte("{\"title\":\"Remote server error\",\"status\":9,\"detail\":\"failed to apply the dns modification\"}\n")).
-		Return(0, nil)
-	mockWriter.On("WriteHeader", 503)
-
-	patch1 := gomonkey.ApplyFunc(backend.GetRecord, func(path string) ([]byte, int) {
-		dnsRule := dataplane.DNSRule{DNSRuleID: dnsRuleId, DomainName: exampleDomainName, IPAddressType: "IP_V4", IPAddress: exampleIPAddress,
-			TTL: 30, State: util.InactiveState}
-		var dnsRules []dataplane.DNSRule
-		dnsRules = append(dnsRules, dnsRule)
-		entry := models.AppDConfig{AppDNSRule: dnsRules}
-		outBytes, _ := json.Marshal(&entry)
-		return outBytes, 0
-	})
-	defer patch1.Reset()
-
-	// 15 is the order of the DNS put handler in the URLPattern
-	service.URLPatterns()[15].Func(mockWriter, getRequest)
+	responseHeader, body := putDnsRule(t, &service, &updateRule)
 
-	assert.Equal(t, "503", responseHeader.Get(responseStatusHeader),
-		"Response status code miss-match")
+	storedRule.State = util.ActiveState
+	taskId := assertTaskAccepted(t, store, responseHeader, body, &storedRule)
 
-	mockWriter.AssertExpectations(t)
+	progress := waitTaskProgress(t, &service, taskId)
+	assert.Equal(t, util.TaskStateFailure, progress.ConfigResult)
+	assert.Equal(t, "Failed in configuring dns rule on remote dns-server/data-plane.", progress.Details)
+	// the failed modification is not applied
+	assert.Equal(t, util.InactiveState, store.getAppDConfig(t).AppDNSRule[0].State)
+	assert.Empty(t, store.find(util.AppDLCMJobsPath+defaultAppInstanceId))
 }
 
-// Update a dns rule from INACTIVE to ACTIVE with error in the dns server
+// Update a dns rule from INACTIVE to ACTIVE with error in the dns server, the task fails
 func TestPutSingleDnsRuleActiveWithServerError(t *testing.T) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -1060,34 +893,6 @@ func TestPutSingleDnsRuleActiveWithServerError(t *testing.T) {
 		}
 	}()
 
-	patchInit1 := gomonkey.ApplyFunc(config.LoadMepServerConfig, func() (*config.MepServerConfig, error) {
-		configData := `
-# dns agent configuration
-dnsAgent:
-  # values: local, dataplane, all
-  type: all
-  # local dns server end point
-  endPoint:
-    address:
-      host: localhost
-      port: 80
-
-
-# data plane option to use in Mp2 interface
-dataplane:
-  # values: none
-  type: none
-
-`
-		var mepConfig config.MepServerConfig
-		err := yaml.Unmarshal([]byte(configData), &mepConfig)
-		if err != nil {
-			assert.Fail(t, parseFail)
-		}
-		return &mepConfig, nil
-	})
-	defer patchInit1.Reset()
-
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		_, err2 := w.Write([]byte(""))
@@ -1097,14 +902,15 @@ dataplane:
 	}))
 	defer ts.Close()
 
-	patchInit2 := gomonkey.ApplyFunc(dns.NewRestDNSAgent, func(config *config.MepServerConfig) *dns.RestDNSAgent {
-		parse, _ := url.Parse(ts.URL)
-		return &dns.RestDNSAgent{ServerEndPoint: parse}
-	})
-	defer patchInit2.Reset()
-
+	store := newMemDataStore()
+	patches := store.patch()
+	defer patches.Reset()
+	useAppDWorker(ts.URL)
 	service := Mp1Service{}
-	_ = service.Init()
+
+	storedRule := dataplane.DNSRule{DNSRuleID: dnsRuleId, DomainName: exampleDomainName, IPAddressType: "IP_V4",
+		IPAddress: exampleIPAddress, TTL: defaultTTL, State: util.InactiveState}
+	store.putAppDConfig(t, &models.AppDConfig{AppDNSRule: []dataplane.DNSRule{storedRule}})
 
 	updateRule := dataplane.DNSRule{
 		DNSRuleID:     dnsRuleId,
@@ -1114,43 +920,17 @@ dataplane:
 		TTL:           defaultTTL,
 		State:         util.ActiveState,
 	}
-	updateRuleBytes, _ := json.Marshal(updateRule)
-
-	// Create http get request
-	getRequest, _ := http.NewRequest("PUT",
-		fmt.Sprintf(getDnsRuleUrlFormat, defaultAppInstanceId, dnsRuleId),
-		bytes.NewReader(updateRuleBytes))
-	getRequest.URL.RawQuery = fmt.Sprintf(appIdAndDnsRuleIdQueryFormat, defaultAppInstanceId, dnsRuleId)
-	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
-
-	// Mock the response writer
-	mockWriter := &mockHttpWriter{}
-	responseHeader := http.Header{} // Create http response header
-	mockWriter.On("Header").Return(responseHeader)
-	mockWriter.On("Write",
-		[]byte("{\"title\":\"Remote server error\",\"status\":9,\"detail\":\"failed to apply the dns modification\"}\n")).
-		Return(0, nil)
-	mockWriter.On("WriteHeader", 503)
-
-	patch1 := gomonkey.ApplyFunc(backend.GetRecord, func(path string) ([]byte, int) {
-		dnsRule := dataplane.DNSRule{DNSRuleID: dnsRuleId, DomainName: exampleDomainName, IPAddressType: "IP_V4", IPAddress: exampleIPAddress,
-			TTL: 30, State: util.InactiveState}
-		var dnsRules []dataplane.DNSRule
-		dnsRules = append(dnsRules, dnsRule)
-		entry := models.AppDConfig{AppDNSRule: dnsRules}
-		outBytes, _ := json.Marshal(&entry)
-		return outBytes, 0
-	})
-
-	defer patch1.Reset()
-
-	// 15 is the order of the DNS put handler in the URLPattern
-	service.URLPatterns()[15].Func(mockWriter, getRequest)
+	responseHeader, body := putDnsRule(t, &service, &updateRule)
 
-	assert.Equal(t, "503", responseHeader.Get(responseStatusHeader),
-		"Response status code miss-match")
+	storedRule.State = util.ActiveState
+	taskId := assertTaskAccepted(t, store, responseHeader, body, &storedRule)
 
-	mockWriter.AssertExpectations(t)
+	progress := waitTaskProgress(t, &service, taskId)
+	assert.Equal(t, util.TaskStateFailure, progress.ConfigResult)
+	assert.Equal(t, "Failed in configuring dns rule on remote dns-server/data-plane.", progress.Details)
+	// the failed modification is not applied
+	assert.Equal(t, util.InactiveState, store.getAppDConfig(t).AppDNSRule[0].State)
+	assert.Empty(t, store.find(util.AppDLCMJobsPath+defaultAppInstanceId))
 }
 
 // Put a dns rule which doesn't exists
@@ -2196,6 +1976,64 @@ func TestPutServiceUpdateFindInstanceFail(t *testing.T) {
 	service.URLPatterns()[6].Func(mockWriter, getRequest)
 }
 
+// Update the service uris but the registry update fails, the api gateway service is restored
+func TestPutServiceUpdateRestoresApiGateway(t *testing.T) {
+	apiGwSerName := "faceregservice5d7e1c"
+	previousService := apigw.Service{Name: apiGwSerName, Url: "http://10.1.1.1:8080/"}
+	gateway := memory.NewGateway()
+	assert.NoError(t, apigw.RegisterServices(gateway, []apigw.Service{previousService}, false))
+	service := Mp1Service{apiGateway: gateway}
+
+	serviceInf := serviceInfo{
+		SerName:     "FaceRegService5",
+		SerCategory: models.CategoryRef{Href: href, ID: "id12345", Name: "RNI", Version: "1.2.3"},
+		Version:     "4.5.8",
+		State:       "ACTIVE",
+		TransportInfo: models.TransportInfo{
+			ID:        "TransId12345",
+			Name:      "REST",
+			TransType: "REST_HTTP",
+			Protocol:  "HTTP",
+			Version:   "2.0",
+			Endpoint:  models.EndPointInfo{Uris: []string{"http://10.2.2.2:9090/"}},
+		},
+		Serializer:      "JSON",
+		ScopeOfLocality: "MEC_SYSTEM",
+		IsLocal:         true,
+	}
+	serviceInfBytes, _ := json.Marshal(serviceInf)
+	putRequest, _ := http.NewRequest("PUT",
+		fmt.Sprintf(getOrDelOneSubscribeOrSveUrl, defaultAppInstanceId, sampleServiceId),
+		bytes.NewReader(serviceInfBytes))
+	putRequest.URL.RawQuery = fmt.Sprintf(appIdAndServiceIdQueryFormat, defaultAppInstanceId, sampleServiceId)
+	putRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
+
+	patches := gomonkey.ApplyFunc(util.GetServiceInstance, func(ctx context.Context,
+		serviceId string) (*pb.MicroServiceInstance, error) {
+		return &pb.MicroServiceInstance{
+			InstanceId: sampleInstanceId,
+			ServiceId:  sampleServiceId,
+			Endpoints:  []string{"https://mep-api-gw.mep:8443/" + apiGwSerName},
+			Properties: map[string]string{apigw.PropertyPrefix + apiGwSerName: previousService.Url},
+		}, nil
+	})
+	defer patches.Reset()
+	patches.ApplyFunc(svcutil.UpdateInstance, func(context.Context, string, *pb.MicroServiceInstance) *scerr.Error {
+		return scerr.NewError(scerr.ErrInternal, "registry unavailable")
+	})
+
+	mockWriter := &mockHttpWriterWithoutWrite{}
+	mockWriter.On("Header").Return(http.Header{})
+	mockWriter.On("Write").Return(0, nil)
+	mockWriter.On("WriteHeader", 400)
+	routeFunc(t, &service, http.MethodPut, util.AppServicesPath+util.ServiceIdPath)(mockWriter, putRequest)
+
+	mockWriter.AssertExpectations(t)
+	restored, ok := gateway.GetService(apiGwSerName)
+	assert.True(t, ok)
+	assert.Equal(t, previousService, restored)
+}
+
 // Query a service with invalid service id
 func TestGetOneServiceWithInvalidId(t *testing.T) {
 	defer func() {
@@ -2465,21 +2303,125 @@ func TestHeartbeatService(t *testing.T) {
 		responseCheckFor204)
 	mockWriter.AssertExpectations(t)
 }
-func TestHeartbeatServiceInvalidServiceId(t *testing.T) {
-	defer func() {
-		if r := recover(); r != nil {
-			t.Errorf(panicFormatString, r)
-		}
-	}()
+// auditRecorder keeps the saved audit records
+type auditRecorder struct {
+	records []audit.Record
+}
+
+// Save keeps the record
+func (a *auditRecorder) Save(record *audit.Record) error {
+	a.records = append(a.records, *record)
+	return nil
+}
+
+// Query returns the kept records
+func (a *auditRecorder) Query(filter *audit.Filter) ([]audit.Record, error) {
+	return a.records, nil
+}
+
+func auditHash(t *testing.T, payload interface{}) string {
+	data, err := json.Marshal(payload)
+	assert.NoError(t, err)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Liveness update is audited with the instance before the update
+func TestHeartbeatServiceAuditBefore(t *testing.T) {
+	recorder := &auditRecorder{}
+	defer audit.UseStore(recorder)()
 	service := Mp1Service{}
-	heartbeatRequest := models.ServiceLivenessUpdate{State: "ACTIVE"}
-	heartbeatRequestBytes, _ := json.Marshal(heartbeatRequest)
-	//Patching
-	var updatePropertiesResp = &pb.UpdateInstancePropsResponse{
-		Response: &pb.Response{Code: pb.Response_SUCCESS},
+	heartbeatRequestBytes, _ := json.Marshal(models.ServiceLivenessUpdate{State: "ACTIVE"})
+	instance := &pb.MicroServiceInstance{
+		InstanceId: sampleInstanceId,
+		ServiceId:  sampleServiceId,
+		Properties: map[string]string{
+			"mecState":         "ACTIVE",
+			"livenessInterval": "60",
+			secString:          "1",
+			nanosecString:      "2",
+		},
 	}
-	n1 := &srv.InstanceService{}
-	var resp = &pb.GetOneInstanceResponse{
+	beforeHash := auditHash(t, instance)
+	n := &srv.InstanceService{}
+	patches := gomonkey.ApplyMethod(reflect.TypeOf(n), "GetOneInstance", func(*srv.InstanceService,
+		context.Context, *pb.GetOneInstanceRequest) (*pb.GetOneInstanceResponse, error) {
+		return &pb.GetOneInstanceResponse{Response: &pb.Response{Code: pb.Response_SUCCESS}, Instance: instance}, nil
+	})
+	defer patches.Reset()
+	patches.ApplyMethod(reflect.TypeOf(n), "UpdateInstanceProperties", func(*srv.InstanceService, context.Context,
+		*pb.UpdateInstancePropsRequest) (*pb.UpdateInstancePropsResponse, error) {
+		return &pb.UpdateInstancePropsResponse{Response: &pb.Response{Code: pb.Response_SUCCESS}}, nil
+	})
+	putRequest, _ := http.NewRequest(http.MethodPut, fmt.Sprintf(heartBeatUrl, defaultAppInstanceId, sampleServiceId),
+		bytes.NewReader(heartbeatRequestBytes))
+	putRequest.URL.RawQuery = fmt.Sprintf(appIdAndServiceIdQueryFormat, defaultAppInstanceId, sampleServiceId)
+	putRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{}
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write", []byte("\"\"\n")).Return(0, nil)
+	mockWriter.On("WriteHeader", http.StatusNoContent)
+
+	routeFunc(t, &service, http.MethodPut, util.AppServicesPath+util.ServiceIdPath+util.Liveness)(mockWriter,
+		putRequest)
+
+	assert.Equal(t, "204", responseHeader.Get(responseStatusHeader), responseCheckFor204)
+	assert.Len(t, recorder.records, 1)
+	assert.Equal(t, audit.ResultSuccess, recorder.records[0].Result)
+	assert.Equal(t, beforeHash, recorder.records[0].BeforeHash)
+	assert.NotEqual(t, "1", instance.Properties[secString])
+}
+
+// Service delete is audited with the instance before the delete
+func TestDelOneServiceAuditBefore(t *testing.T) {
+	recorder := &auditRecorder{}
+	defer audit.UseStore(recorder)()
+	service := Mp1Service{}
+	instance := &pb.MicroServiceInstance{InstanceId: sampleInstanceId, ServiceId: sampleServiceId,
+		Properties: map[string]string{"appInstanceId": defaultAppInstanceId, "serName": "FaceRegService6"}}
+	patches := gomonkey.ApplyFunc(svcutil.GetInstance, func(context.Context, string, string,
+		string) (*pb.MicroServiceInstance, error) {
+		return instance, nil
+	})
+	defer patches.Reset()
+	n := &srv.InstanceService{}
+	patches.ApplyMethod(reflect.TypeOf(n), "Unregister", func(*srv.InstanceService, context.Context,
+		*pb.UnregisterInstanceRequest) (*pb.UnregisterInstanceResponse, error) {
+		return &pb.UnregisterInstanceResponse{Response: &pb.Response{Code: pb.Response_SUCCESS}}, nil
+	})
+	delRequest, _ := http.NewRequest(http.MethodDelete,
+		fmt.Sprintf(getOrDelOneSubscribeOrSveUrl, defaultAppInstanceId, sampleServiceId), nil)
+	delRequest.URL.RawQuery = fmt.Sprintf(appIdAndServiceIdQueryFormat, defaultAppInstanceId, sampleServiceId)
+	delRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{}
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write", []byte("\"\"\n")).Return(0, nil)
+	mockWriter.On("WriteHeader", http.StatusNoContent)
+
+	routeFunc(t, &service, http.MethodDelete, util.AppServicesPath+util.ServiceIdPath)(mockWriter, delRequest)
+
+	assert.Equal(t, "204", responseHeader.Get(responseStatusHeader), responseCheckFor204)
+	assert.Len(t, recorder.records, 1)
+	assert.Equal(t, auditHash(t, instance), recorder.records[0].BeforeHash)
+}
+
+func TestHeartbeatServiceInvalidServiceId(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf(panicFormatString, r)
+		}
+	}()
+	service := Mp1Service{}
+	heartbeatRequest := models.ServiceLivenessUpdate{State: "ACTIVE"}
+	heartbeatRequestBytes, _ := json.Marshal(heartbeatRequest)
+	//Patching
+	var updatePropertiesResp = &pb.UpdateInstancePropsResponse{
+		Response: &pb.Response{Code: pb.Response_SUCCESS},
+	}
+	n1 := &srv.InstanceService{}
+	var resp = &pb.GetOneInstanceResponse{
 		Response: &pb.Response{Code: pb.Response_SUCCESS},
 		Instance: &pb.MicroServiceInstance{
 			InstanceId: sampleInstanceId,
@@ -2938,7 +2880,7 @@ func TestGetTransportInfoPutRecordFailed(t *testing.T) {
 	mockWriter.AssertExpectations(t)
 }
 
-// Update a dns rule
+// Update a traffic rule from INACTIVE to ACTIVE
 func TestPutTrafficRuleStateAddRule(t *testing.T) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -2946,10 +2888,16 @@ func TestPutTrafficRuleStateAddRule(t *testing.T) {
 		}
 	}()
 
+	store := newMemDataStore()
+	patches := store.patch()
+	defer patches.Reset()
+	useAppDWorker("")
 	service := Mp1Service{}
 
-	service.dataPlane = &none.NoneDataPlane{}
-	//service.dataPlane.AddTrafficRule()
+	storedRule := dataplane.TrafficRule{TrafficRuleID: trafficRuleId, FilterType: "FLOW", Priority: 5,
+		Action: "DROP", State: util.InactiveState}
+	store.putAppDConfig(t, &models.AppDConfig{AppTrafficRule: []dataplane.TrafficRule{storedRule}})
+
 	updateRule := dataplane.TrafficRule{
 		TrafficRuleID: trafficRuleId,
 		FilterType:    "FLOW",
@@ -2958,45 +2906,15 @@ func TestPutTrafficRuleStateAddRule(t *testing.T) {
 		Action:        "DROP",
 		State:         util.ActiveState,
 	}
-	updateRuleBytes, _ := json.Marshal(updateRule)
-
-	// Create http get request
-	getRequest, _ := http.NewRequest("PUT",
-		fmt.Sprintf(getOneTrafficRuleUrl, defaultAppInstanceId, trafficRuleId),
-		bytes.NewReader(updateRuleBytes))
-	getRequest.URL.RawQuery = fmt.Sprintf(appIdAndTrafficRuleIdQueryFormat, defaultAppInstanceId, trafficRuleId)
-	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
-
-	// Mock the response writer
-	mockWriter := &mockHttpWriter{}
-	responseHeader := http.Header{} // Create http response header
-	mockWriter.On("Header").Return(responseHeader)
-	mockWriter.On("Write",
-		[]byte(fmt.Sprintf(writeTrafficPutObjectFormat, util.ActiveState))).
-		Return(0, nil)
-	mockWriter.On("WriteHeader", 200)
-
-	patches := gomonkey.ApplyFunc(backend.GetRecord, func(path string) ([]byte, int) {
-		TrafficRule := dataplane.TrafficRule{TrafficRuleID: trafficRuleId, FilterType: "FLOW", Priority: 5,
-			Action: "DROP", State: util.InactiveState}
-		var TrafficRules []dataplane.TrafficRule
-		TrafficRules = append(TrafficRules, TrafficRule)
-		entry := models.AppDConfig{AppTrafficRule: TrafficRules}
-		outBytes, _ := json.Marshal(&entry)
-		return outBytes, 0
-	})
-	defer patches.Reset()
+	responseHeader, body := putTrafficRule(t, &service, &updateRule)
+	taskId := assertTaskAccepted(t, store, responseHeader, body, &updateRule)
 
-	// 23 is the order of the Traffic Rule put handler in the URLPattern
-	service.URLPatterns()[23].Func(mockWriter, getRequest)
-
-	assert.Equal(t, "200", responseHeader.Get(responseStatusHeader),
-		responseCheckFor200)
-
-	mockWriter.AssertExpectations(t)
+	progress := waitTaskProgress(t, &service, taskId)
+	assert.Equal(t, util.TaskStateSuccess, progress.ConfigResult)
+	assert.Equal(t, util.ActiveState, store.getAppDConfig(t).AppTrafficRule[0].State)
 }
 
-// Update a dns rule
+// Update a traffic rule from ACTIVE to INACTIVE
 func TestPutTrafficRuleStateDeleteRule(t *testing.T) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -3004,9 +2922,15 @@ func TestPutTrafficRuleStateDeleteRule(t *testing.T) {
 		}
 	}()
 
+	store := newMemDataStore()
+	patches := store.patch()
+	defer patches.Reset()
+	useAppDWorker("")
 	service := Mp1Service{}
 
-	service.dataPlane = &none.NoneDataPlane{}
+	storedRule := dataplane.TrafficRule{TrafficRuleID: trafficRuleId, FilterType: "FLOW", Priority: 5,
+		Action: "DROP", State: util.ActiveState}
+	store.putAppDConfig(t, &models.AppDConfig{AppTrafficRule: []dataplane.TrafficRule{storedRule}})
 
 	updateRule := dataplane.TrafficRule{
 		TrafficRuleID: trafficRuleId,
@@ -3016,49 +2940,15 @@ func TestPutTrafficRuleStateDeleteRule(t *testing.T) {
 		Action:        "DROP",
 		State:         util.InactiveState,
 	}
-	updateRuleBytes, _ := json.Marshal(updateRule)
-
-	// Create http get request
-	getRequest, _ := http.NewRequest("PUT",
-		fmt.Sprintf(getOneTrafficRuleUrl, defaultAppInstanceId, trafficRuleId),
-		bytes.NewReader(updateRuleBytes))
-	getRequest.URL.RawQuery = fmt.Sprintf(appIdAndTrafficRuleIdQueryFormat, defaultAppInstanceId, trafficRuleId)
-	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
+	responseHeader, body := putTrafficRule(t, &service, &updateRule)
+	taskId := assertTaskAccepted(t, store, responseHeader, body, &updateRule)
 
-	// Mock the response writer
-	mockWriter := &mockHttpWriter{}
-	responseHeader := http.Header{} // Create http response header
-	mockWriter.On("Header").Return(responseHeader)
-	mockWriter.On("Write",
-		[]byte(fmt.Sprintf(writeTrafficPutObjectFormat, util.InactiveState))).
-		Return(0, nil)
-	mockWriter.On("WriteHeader", 200)
-
-	patches := gomonkey.ApplyFunc(backend.GetRecord, func(path string) ([]byte, int) {
-		TrafficRule := dataplane.TrafficRule{TrafficRuleID: trafficRuleId, FilterType: "FLOW", Priority: 5,
-			Action: "DROP", State: util.ActiveState}
-		var TrafficRules []dataplane.TrafficRule
-		TrafficRules = append(TrafficRules, TrafficRule)
-		entry := models.AppDConfig{AppTrafficRule: TrafficRules}
-		outBytes, _ := json.Marshal(&entry)
-		return outBytes, 0
-	})
-	defer patches.Reset()
-	patches.ApplyFunc(service.dataPlane.AddTrafficRule, func(appInfo dataplane.ApplicationInfo, trafficRuleId, filterType, action string, priority int,
-		filter []dataplane.TrafficFilter) (err error) {
-		return errors.New("Error")
-	})
-
-	// 23 is the order of the Traffic Rule put handler in the URLPattern
-	service.URLPatterns()[23].Func(mockWriter, getRequest)
-
-	assert.Equal(t, "200", responseHeader.Get(responseStatusHeader),
-		responseCheckFor200)
-
-	mockWriter.AssertExpectations(t)
+	progress := waitTaskProgress(t, &service, taskId)
+	assert.Equal(t, util.TaskStateSuccess, progress.ConfigResult)
+	assert.Equal(t, util.InactiveState, store.getAppDConfig(t).AppTrafficRule[0].State)
 }
 
-// Update a dns rule
+// Update an ACTIVE traffic rule
 func TestPutTrafficRuleStateSetRule(t *testing.T) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -3066,9 +2956,15 @@ func TestPutTrafficRuleStateSetRule(t *testing.T) {
 		}
 	}()
 
+	store := newMemDataStore()
+	patches := store.patch()
+	defer patches.Reset()
+	useAppDWorker("")
 	service := Mp1Service{}
 
-	service.dataPlane = &none.NoneDataPlane{}
+	storedRule := dataplane.TrafficRule{TrafficRuleID: trafficRuleId, FilterType: "FLOW", Priority: 5,
+		Action: "DROP", State: util.ActiveState}
+	store.putAppDConfig(t, &models.AppDConfig{AppTrafficRule: []dataplane.TrafficRule{storedRule}})
 
 	updateRule := dataplane.TrafficRule{
 		TrafficRuleID: trafficRuleId,
@@ -3078,46 +2974,12 @@ func TestPutTrafficRuleStateSetRule(t *testing.T) {
 		Action:        "DROP",
 		State:         util.ActiveState,
 	}
-	updateRuleBytes, _ := json.Marshal(updateRule)
-
-	// Create http get request
-	getRequest, _ := http.NewRequest("PUT",
-		fmt.Sprintf(getOneTrafficRuleUrl, defaultAppInstanceId, trafficRuleId),
-		bytes.NewReader(updateRuleBytes))
-	getRequest.URL.RawQuery = fmt.Sprintf(appIdAndTrafficRuleIdQueryFormat, defaultAppInstanceId, trafficRuleId)
-	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
-
-	// Mock the response writer
-	mockWriter := &mockHttpWriter{}
-	responseHeader := http.Header{} // Create http response header
-	mockWriter.On("Header").Return(responseHeader)
-	mockWriter.On("Write",
-		[]byte(fmt.Sprintf(writeTrafficPutObjectFormat, util.ActiveState))).
-		Return(0, nil)
-	mockWriter.On("WriteHeader", 200)
+	responseHeader, body := putTrafficRule(t, &service, &updateRule)
+	taskId := assertTaskAccepted(t, store, responseHeader, body, &updateRule)
 
-	patches := gomonkey.ApplyFunc(backend.GetRecord, func(path string) ([]byte, int) {
-		TrafficRule := dataplane.TrafficRule{TrafficRuleID: trafficRuleId, FilterType: "FLOW", Priority: 5,
-			Action: "DROP", State: util.ActiveState}
-		var TrafficRules []dataplane.TrafficRule
-		TrafficRules = append(TrafficRules, TrafficRule)
-		entry := models.AppDConfig{AppTrafficRule: TrafficRules}
-		outBytes, _ := json.Marshal(&entry)
-		return outBytes, 0
-	})
-	defer patches.Reset()
-	patches.ApplyFunc(service.dataPlane.AddTrafficRule, func(appInfo dataplane.ApplicationInfo, trafficRuleId, filterType, action string, priority int,
-		filter []dataplane.TrafficFilter) (err error) {
-		return errors.New("Error")
-	})
-
-	// 23 is the order of the Traffic Rule put handler in the URLPattern
-	service.URLPatterns()[23].Func(mockWriter, getRequest)
-
-	assert.Equal(t, "200", responseHeader.Get(responseStatusHeader),
-		responseCheckFor200)
-
-	mockWriter.AssertExpectations(t)
+	progress := waitTaskProgress(t, &service, taskId)
+	assert.Equal(t, util.TaskStateSuccess, progress.ConfigResult)
+	assert.Equal(t, util.ActiveState, store.getAppDConfig(t).AppTrafficRule[0].State)
 }
 
 // Update a dns rule
@@ -3130,8 +2992,6 @@ func TestPutTrafficRuleStatePutRecordFailed(t *testing.T) {
 
 	service := Mp1Service{}
 
-	service.dataPlane = &none.NoneDataPlane{}
-
 	updateRule := dataplane.TrafficRule{
 		TrafficRuleID: trafficRuleId,
 		FilterType:    "FLOW",
@@ -3154,7 +3014,8 @@ func TestPutTrafficRuleStatePutRecordFailed(t *testing.T) {
 	responseHeader := http.Header{} // Create http response header
 	mockWriter.On("Header").Return(responseHeader)
 	mockWriter.On("Write",
-		[]byte("{\"title\":\"Bad Request\",\"status\":6,\"detail\":\"put traffic rule to etcd failed\"}"+"\n")).
+		[]byte("{\"title\":\"Bad Request\",\"status\":1,\"detail\":\"put app config rule to data-store failed\"}"+
+			"\n")).
 		Return(0, nil)
 	mockWriter.On("WriteHeader", 400)
 
@@ -3783,3 +3644,377 @@ func TestConfirmReadyInvalidAppInstaces(t *testing.T) {
 
 	mockWriter.AssertExpectations(t)
 }
+
+//============================= staged rule updates ============================
+const appTaskUrlFormat = "/mep/mec_app_support/v1/applications/%s/tasks/%s"
+const appTaskQueryFormat = ":appInstanceId=%s&:taskId=%s&"
+const exampleAppName = "exampleApp"
+const responseCheckFor202 = "Response status code must be 202"
+
+// memDataStore backs the data-store functions in the tests, it is read and written by the appd sync worker too
+type memDataStore struct {
+	mutex   sync.Mutex
+	records map[string][]byte
+}
+
+func newMemDataStore() *memDataStore {
+	return &memDataStore{records: make(map[string][]byte)}
+}
+
+// patch redirects the data-store functions to the in-memory records, the paths are prefixes as in the data-store
+func (s *memDataStore) patch() *gomonkey.Patches {
+	patches := gomonkey.ApplyFunc(backend.GetRecord, func(path string) ([]byte, int) {
+		records := s.find(path)
+		if len(records) == 0 {
+			return nil, util.SubscriptionNotFound
+		}
+		return records[s.firstKey(records)], 0
+	})
+	patches.ApplyFunc(backend.GetRecords, func(path string) (map[string][]byte, int) {
+		records := make(map[string][]byte)
+		for key, value := range s.find(path) {
+			records[filepath.Base(key)] = value
+		}
+		return records, 0
+	})
+	patches.ApplyFunc(backend.GetRecordsWithCompleteKeyPath, func(path string) (map[string][]byte, int) {
+		return s.find(path), 0
+	})
+	patches.ApplyFunc(backend.PutRecord, func(path string, value []byte) int {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		s.records[path] = value
+		return 0
+	})
+	patches.ApplyFunc(backend.DeleteRecord, func(path string) int {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		for key := range s.records {
+			if strings.HasPrefix(key, path) {
+				delete(s.records, key)
+			}
+		}
+		return 0
+	})
+	return patches
+}
+
+func (s *memDataStore) find(path string) map[string][]byte {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	records := make(map[string][]byte)
+	for key, value := range s.records {
+		if strings.HasPrefix(key, path) {
+			records[key] = value
+		}
+	}
+	return records
+}
+
+func (s *memDataStore) firstKey(records map[string][]byte) string {
+	keys := make([]string, 0, len(records))
+	for key := range records {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys[0]
+}
+
+func (s *memDataStore) putAppDConfig(t *testing.T, appDConfig *models.AppDConfig) {
+	appDConfig.AppName = exampleAppName
+	appDConfigBytes, err := json.Marshal(appDConfig)
+	assert.NoError(t, err)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.records[util.AppDConfigKeyPath+defaultAppInstanceId] = appDConfigBytes
+}
+
+func (s *memDataStore) getAppDConfig(t *testing.T) *models.AppDConfig {
+	records := s.find(util.AppDConfigKeyPath + defaultAppInstanceId)
+	appDConfig := &models.AppDConfig{}
+	assert.NoError(t, json.Unmarshal(records[util.AppDConfigKeyPath+defaultAppInstanceId], appDConfig))
+	return appDConfig
+}
+
+// routeFunc finds the handler of the route with the method and the path
+func routeFunc(t *testing.T, service *Mp1Service, method string, path string) func(http.ResponseWriter,
+	*http.Request) {
+	for _, route := range service.URLPatterns() {
+		if route.Method == method && route.Path == path {
+			return route.Func
+		}
+	}
+	t.Fatalf("route %s %s not found", method, path)
+	return nil
+}
+
+// useAppDWorker sets the data-plane and the dns agent of the appd sync worker, the dns rules are applied on the
+// dns server if given and on the data-plane otherwise
+func useAppDWorker(dnsServer string) {
+	if len(dnsServer) == 0 {
+		task.AppDWorker().InitializeWorker(&none.NoneDataPlane{}, nil, util.DnsAgentTypeDataPlane)
+		return
+	}
+	endPoint, _ := url.Parse(dnsServer)
+	task.AppDWorker().InitializeWorker(&none.NoneDataPlane{}, &dns.RestDNSAgent{ServerEndPoint: endPoint},
+		util.DnsAgentTypeLocal)
+}
+
+// putRule sends the rule update on the path and returns the response header and body
+func putRule(t *testing.T, service *Mp1Service, path string, ruleUrl string, query string,
+	rule interface{}) (http.Header, []byte) {
+	ruleBytes, _ := json.Marshal(rule)
+	putRequest, _ := http.NewRequest("PUT", ruleUrl, bytes.NewReader(ruleBytes))
+	putRequest.URL.RawQuery = query
+	putRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
+
+	// Mock the response writer
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{} // Create http response header
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write", mock.Anything).Return(0, nil)
+	mockWriter.On("WriteHeader", mock.Anything)
+
+	routeFunc(t, service, http.MethodPut, path)(mockWriter, putRequest)
+	return responseHeader, mockWriter.response
+}
+
+func putDnsRule(t *testing.T, service *Mp1Service, rule *dataplane.DNSRule) (http.Header, []byte) {
+	return putRule(t, service, util.DNSRulesPath+util.DNSRuleIdPath,
+		fmt.Sprintf(getDnsRuleUrlFormat, defaultAppInstanceId, dnsRuleId),
+		fmt.Sprintf(appIdAndDnsRuleIdQueryFormat, defaultAppInstanceId, dnsRuleId), rule)
+}
+
+func putTrafficRule(t *testing.T, service *Mp1Service, rule *dataplane.TrafficRule) (http.Header, []byte) {
+	return putRule(t, service, util.TrafficRulesPath+util.TrafficRuleIdPath,
+		fmt.Sprintf(getOneTrafficRuleUrl, defaultAppInstanceId, trafficRuleId),
+		fmt.Sprintf(appIdAndTrafficRuleIdQueryFormat, defaultAppInstanceId, trafficRuleId), rule)
+}
+
+// assertTaskAccepted checks the response of a staged rule update and returns the task id, the rule is not applied
+// yet hence the response refers to the task and the e-tag is of the staged rule
+func assertTaskAccepted(t *testing.T, store *memDataStore, responseHeader http.Header, body []byte,
+	stagedRule interface{}) string {
+	assert.Equal(t, "202", responseHeader.Get(responseStatusHeader), responseCheckFor202)
+	taskId := responseHeader.Get(util.XTaskId)
+	assert.NotEmpty(t, taskId)
+	assert.Equal(t, fmt.Sprintf(appTaskUrlFormat, defaultAppInstanceId, taskId), responseHeader.Get("Location"))
+	stagedRuleBytes, _ := json.Marshal(stagedRule)
+	assert.Equal(t, util.GenerateStrongETag(stagedRuleBytes), responseHeader.Get("ETag"))
+
+	progress := models.TaskProgress{}
+	assert.NoError(t, json.Unmarshal(body, &progress))
+	assert.Equal(t, models.TaskProgress{TaskId: taskId, AppInstanceId: defaultAppInstanceId,
+		ConfigResult: util.TaskStateProcessing, ConfigPhase: "0", Details: "Operation In progress"}, progress)
+
+	// the task is staged in the data-store
+	assert.NotEmpty(t, store.find(util.AppDLCMTasksPath+taskId))
+	assert.NotEmpty(t, store.find(util.AppDLCMTaskStatusPath+defaultAppInstanceId+"/"+taskId))
+	return taskId
+}
+
+// waitTaskProgress queries the app task status until the appd sync worker finishes the task
+func waitTaskProgress(t *testing.T, service *Mp1Service, taskId string) models.TaskProgress {
+	getTaskStatus := routeFunc(t, service, http.MethodGet, util.AppTasksPath+util.TaskIdPath)
+	for i := 0; i < 50; i++ {
+		getRequest, _ := http.NewRequest("GET", fmt.Sprintf(appTaskUrlFormat, defaultAppInstanceId, taskId), nil)
+		getRequest.URL.RawQuery = fmt.Sprintf(appTaskQueryFormat, defaultAppInstanceId, taskId)
+		getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
+		recorder := httptest.NewRecorder()
+		getTaskStatus(recorder, getRequest)
+
+		progress := models.TaskProgress{}
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &progress))
+		if progress.ConfigResult != util.TaskStateProcessing {
+			return progress
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Fatalf("task %s not finished", taskId)
+	return models.TaskProgress{}
+}
+
+// Query the status of a rule update task
+func TestGetAppTaskStatus(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf(panicFormatString, r)
+		}
+	}()
+
+	service := Mp1Service{}
+
+	taskId := "f1cf4c1e-21b4-4d7f-8e4d-c3a7c9d1f0a2"
+	getRequest, _ := http.NewRequest("GET",
+		fmt.Sprintf("/mep/mec_app_support/v1/applications/%s/tasks/%s", defaultAppInstanceId, taskId), nil)
+	getRequest.URL.RawQuery = fmt.Sprintf(":appInstanceId=%s&:taskId=%s", defaultAppInstanceId, taskId)
+	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
+
+	// Mock the response writer
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{} // Create http response header
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write", []byte(fmt.Sprintf("{\"taskId\":\"%s\",\"appInstanceId\":\"%s\","+
+		"\"configResult\":\"SUCCESS\",\"configPhase\":\"100\",\"Detailed\":\"\"}\n", taskId, defaultAppInstanceId))).
+		Return(0, nil)
+	mockWriter.On("WriteHeader", 200)
+
+	patches := gomonkey.ApplyFunc(backend.GetRecord, func(path string) ([]byte, int) {
+		if path == util.AppDLCMTasksPath+taskId {
+			return []byte(defaultAppInstanceId), 0
+		}
+		status := models.TaskStatus{
+			Progress:         1,
+			DNSRuleStatusLst: []models.RuleStatus{{Id: dnsRuleId, State: util.WaitConfigDBWrite}},
+		}
+		outBytes, _ := json.Marshal(&status)
+		return outBytes, 0
+	})
+	defer patches.Reset()
+
+	// 40 is the order of the app task status get handler in the URLPattern
+	service.URLPatterns()[40].Func(mockWriter, getRequest)
+
+	assert.Equal(t, "200", responseHeader.Get(responseStatusHeader),
+		responseCheckFor200)
+
+	mockWriter.AssertExpectations(t)
+}
+
+// Query the status of a task which belongs to another app instance
+func TestGetAppTaskStatusOtherApp(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf(panicFormatString, r)
+		}
+	}()
+
+	service := Mp1Service{}
+
+	taskId := "f1cf4c1e-21b4-4d7f-8e4d-c3a7c9d1f0a2"
+	getRequest, _ := http.NewRequest("GET",
+		fmt.Sprintf("/mep/mec_app_support/v1/applications/%s/tasks/%s", defaultAppInstanceId, taskId), nil)
+	getRequest.URL.RawQuery = fmt.Sprintf(":appInstanceId=%s&:taskId=%s", defaultAppInstanceId, taskId)
+	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
+
+	// Mock the response writer
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{} // Create http response header
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write",
+		[]byte("{\"title\":\"Can not found resource\",\"status\":5,\"detail\":\"task not found\"}\n")).
+		Return(0, nil)
+	mockWriter.On("WriteHeader", 404)
+
+	patches := gomonkey.ApplyFunc(backend.GetRecord, func(path string) ([]byte, int) {
+		if path == util.AppDLCMTasksPath+taskId {
+			return []byte("1f3c4f21-2a5c-4a5e-9b8e-0d2b0f5c7a11"), 0
+		}
+		status := models.TaskStatus{
+			Progress:         0,
+			DNSRuleStatusLst: []models.RuleStatus{{Id: dnsRuleId, State: util.WaitMp2}},
+		}
+		outBytes, _ := json.Marshal(&status)
+		return outBytes, 0
+	})
+	defer patches.Reset()
+
+	// 40 is the order of the app task status get handler in the URLPattern
+	service.URLPatterns()[40].Func(mockWriter, getRequest)
+
+	assert.Equal(t, "404", responseHeader.Get(responseStatusHeader),
+		responseCheckFor400)
+
+	mockWriter.AssertExpectations(t)
+}
+
+// Update a traffic rule with invalid traffic filter
+func TestPutTrafficRuleInvalidTrafficFilter(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf(panicFormatString, r)
+		}
+	}()
+
+	service := Mp1Service{}
+
+	updateRule := dataplane.TrafficRule{
+		TrafficRuleID: trafficRuleId,
+		FilterType:    "FLOW",
+		Priority:      5,
+		TrafficFilter: []dataplane.TrafficFilter{{SrcAddress: []string{"192.168.1.0/33"}, DstPort: []string{"80-70000"}}},
+		Action:        "DROP",
+		State:         util.ActiveState,
+	}
+	updateRuleBytes, _ := json.Marshal(updateRule)
+
+	getRequest, _ := http.NewRequest("PUT",
+		fmt.Sprintf(getOneTrafficRuleUrl, defaultAppInstanceId, trafficRuleId),
+		bytes.NewReader(updateRuleBytes))
+	getRequest.URL.RawQuery = fmt.Sprintf(appIdAndTrafficRuleIdQueryFormat, defaultAppInstanceId, trafficRuleId)
+	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
+
+	// Mock the response writer
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{} // Create http response header
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write",
+		[]byte("{\"title\":\"Request parameter error\",\"status\":14,\"detail\":\"invalid traffic filter\","+
+			"\"invalidParams\":[{\"param\":\"trafficFilter[0].srcAddress[0]\",\"reason\":\"invalid CIDR\"},"+
+			"{\"param\":\"trafficFilter[0].dstPort[0]\",\"reason\":\"port out of range(0-65535)\"}]}\n")).
+		Return(0, nil)
+	mockWriter.On("WriteHeader", 400)
+
+	// 23 is the order of the Traffic Rule put handler in the URLPattern
+	service.URLPatterns()[23].Func(mockWriter, getRequest)
+
+	assert.Equal(t, "400", responseHeader.Get(responseStatusHeader),
+		responseCheckFor400)
+
+	mockWriter.AssertExpectations(t)
+}
+
+// Update a traffic rule with more traffic filters than allowed
+func TestPutTrafficRuleTooManyTrafficFilters(t *testing.T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf(panicFormatString, r)
+		}
+	}()
+
+	service := Mp1Service{}
+
+	updateRule := dataplane.TrafficRule{
+		TrafficRuleID: trafficRuleId,
+		FilterType:    "FLOW",
+		Priority:      5,
+		TrafficFilter: make([]dataplane.TrafficFilter, 17),
+		Action:        "DROP",
+		State:         util.ActiveState,
+	}
+	for i := range updateRule.TrafficFilter {
+		updateRule.TrafficFilter[i].DstPort = []string{strconv.Itoa(8000 + i)}
+	}
+	updateRuleBytes, _ := json.Marshal(updateRule)
+
+	getRequest, _ := http.NewRequest("PUT",
+		fmt.Sprintf(getOneTrafficRuleUrl, defaultAppInstanceId, trafficRuleId),
+		bytes.NewReader(updateRuleBytes))
+	getRequest.URL.RawQuery = fmt.Sprintf(appIdAndTrafficRuleIdQueryFormat, defaultAppInstanceId, trafficRuleId)
+	getRequest.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
+
+	// Mock the response writer
+	mockWriter := &mockHttpWriter{}
+	responseHeader := http.Header{} // Create http response header
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write", mock.Anything).Return(0, nil)
+	mockWriter.On("WriteHeader", 400)
+
+	routeFunc(t, &service, http.MethodPut, util.TrafficRulesPath+util.TrafficRuleIdPath)(mockWriter, getRequest)
+
+	assert.Equal(t, "400", responseHeader.Get(responseStatusHeader),
+		responseCheckFor400)
+	assert.Contains(t, string(mockWriter.response), "'TrafficFilter' failed on the 'max' tag")
+
+	mockWriter.AssertExpectations(t)
+}
@@ -18,13 +18,16 @@
 package event
 
 import (
-	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
+	mepmetrics "mepserver/common/metrics"
 	"mepserver/common/models"
+	"net/http"
 	"strconv"
 	"strings"
+	"time"
+	"tracing"
 
 	"github.com/apache/servicecomb-service-center/pkg/log"
 	"github.com/apache/servicecomb-service-center/pkg/util"
@@ -46,7 +49,7 @@ const ConsumeIDLength = 16
 
 //InstanceEtsiEventHandler notification handler
 type InstanceEtsiEventHandler struct {
-	tlsCfg *tls.Config
+	transport http.RoundTripper
 }
 
 //Type event handler type
@@ -177,7 +180,9 @@ func (h *InstanceEtsiEventHandler) sendMsg(notificationInfo models.ServiceAvaila
 		return
 	}
 
-	_, err = util2.SendPostRequest(callBackURI, notificationInfoJSON, h.tlsCfg)
+	start := time.Now()
+	_, err = util2.SendPostRequestWithTransport(callBackURI, notificationInfoJSON, h.transport)
+	mepmetrics.ObserveNotification(mepmetrics.NotificationServiceAvailability, start, err)
 	if err != nil {
 		log.Error("Failed to send notification.", nil)
 	}
@@ -274,5 +279,5 @@ func NewInstanceEtsiEventHandler() *InstanceEtsiEventHandler {
 	if err != nil {
 		return nil
 	}
-	return &InstanceEtsiEventHandler{config}
+	return &InstanceEtsiEventHandler{tracing.NewTransport(&http.Transport{TLSClientConfig: config})}
 }
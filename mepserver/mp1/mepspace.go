@@ -25,6 +25,8 @@ import (
 	"golang.org/x/net/context"
 
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
+	"tracing"
 )
 
 // MepSpace base mep bus structure
@@ -41,6 +43,7 @@ type MepSpace struct {
 	SubscribeId   string          `json:"subscribeId"`
 	DNSRuleId     string          `json:"dnsRuleId"`
 	TrafficRuleId string          `json:"trafficRuleId"`
+	TaskId        string          `json:"taskId"`
 	Flag          bool            `json:"flag"`
 
 	QueryParam url.Values `json:"queryParam"`
@@ -49,15 +52,24 @@ type MepSpace struct {
 	CoreRsp     interface{}     `json:"coreRsp"`
 	HttPErrInf  *proto.Response `json:"httpErrInf"`
 	HttPRsp     interface{}     `json:"httpRsp"`
+	HttpStatus  int             `json:"httpStatus"`
 }
 
 // NewWorkSpace new a work space
 func NewWorkSpace(w http.ResponseWriter, r *http.Request) *MepSpace {
 	var plan = MepSpace{
 		W: w,
-		R: r,
+		R: tracing.Begin(audit.Begin(r)),
 	}
 
 	plan.Init()
 	return &plan
 }
+
+// TraceContext returns the trace context of the request, the steps are traced as the children of the request span
+func (m *MepSpace) TraceContext() context.Context {
+	if m.R == nil {
+		return context.Background()
+	}
+	return m.R.Context()
+}
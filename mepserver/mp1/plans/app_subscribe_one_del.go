@@ -27,6 +27,7 @@ import (
 	"github.com/apache/servicecomb-service-center/server/plugin/pkg/registry"
 
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
 	"mepserver/common/util"
 )
 
@@ -72,6 +73,7 @@ func (t *DelOneSubscribe) OnRequest(data string) workspace.TaskCode {
 		t.SetFirstErrorCode(util.SubscriptionNotFound, "subscription not exist")
 		return workspace.TaskFinish
 	}
+	audit.SetBefore(t.R.Context(), resp.Kvs[0].Value)
 
 	opts = []registry.PluginOp{
 		registry.OpDel(registry.WithStrKey(appSubKeyPath)),
@@ -78,12 +78,23 @@ func (t *GetOneSubscribe) OnRequest(data string) workspace.TaskCode {
 
 	var jsonErr error
 	selfPath := t.R.URL.Path[len(util.RootPath):]
-	if t.SubscribeType == util.SerAvailabilityNotificationSubscription {
+	switch t.SubscribeType {
+	case util.SerAvailabilityNotificationSubscription:
 		sub := &models.SerAvailabilityNotificationSubscription{}
 		jsonErr = json.Unmarshal(resp.Kvs[0].Value, sub)
 		sub.Links.Self.Href = selfPath
 		t.HttpRsp = sub
-	} else {
+	case util.CellChangeSubscription:
+		sub := &models.CellChangeSubscription{}
+		jsonErr = json.Unmarshal(resp.Kvs[0].Value, sub)
+		sub.Links.Self.Href = selfPath
+		t.HttpRsp = sub
+	case util.UserTrackingSubscription:
+		sub := &models.UserTrackingSubscriptionBody{}
+		jsonErr = json.Unmarshal(resp.Kvs[0].Value, sub)
+		sub.UserTrackingSubscription.ResourceURL = selfPath
+		t.HttpRsp = sub
+	default:
 		sub := &models.AppTerminationNotificationSubscription{}
 		jsonErr = json.Unmarshal(resp.Kvs[0].Value, sub)
 		sub.Links.Self.Href = selfPath
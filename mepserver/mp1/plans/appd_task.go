@@ -0,0 +1,149 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package plans implements mep server api plans
+package plans
+
+import (
+	"context"
+	"fmt"
+	"mepserver/common/appd"
+	"mepserver/common/models"
+	"mepserver/mm5/task"
+	"net/http"
+	"strings"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+	"github.com/apache/servicecomb-service-center/pkg/util"
+
+	"mepserver/common/arch/workspace"
+	meputil "mepserver/common/util"
+)
+
+// stageRuleUpdate stages the modified app configuration as a new appd task and hands it over to the sync worker
+func stageRuleUpdate(appDCommon *appd.AppDCommon, worker *task.Worker, appInstanceId string,
+	appDConfig *models.AppDConfig) (taskId string, code workspace.ErrCode, msg string) {
+
+	if worker == nil {
+		log.Errorf(nil, "Appd sync worker not initialized.")
+		return "", meputil.SerErrFailBase, "sync worker not available"
+	}
+
+	// Check if any other ongoing operation for this AppInstance Id in the system, the check and the staging are
+	// atomic across the mm5 and the mp1 requests
+	worker.LockStaging()
+	defer worker.UnlockStaging()
+	if appDCommon.IsAnyOngoingOperationExist(appInstanceId) {
+		log.Errorf(nil, "App instance has other operation in progress.")
+		return "", meputil.ForbiddenOperation, "app instance has other operation in progress"
+	}
+
+	// Rule update on mp1 is a modification of the whole app configuration
+	appDConfig.Operation = http.MethodPut
+	taskId = meputil.GenerateUniqueId()
+
+	code, msg = appDCommon.StageNewTask(appInstanceId, taskId, appDConfig)
+	if code != 0 {
+		return "", code, msg
+	}
+
+	worker.StartNewTask(appDConfig.AppName, appInstanceId, taskId)
+	return taskId, 0, ""
+}
+
+// setTaskHeaders sets the task id and the task status location in the response header
+func setTaskHeaders(w http.ResponseWriter, appInstanceId string, taskId string) {
+	location := strings.Replace(meputil.AppTasksPath, ":appInstanceId", appInstanceId, 1) + "/" + taskId
+	w.Header().Set(meputil.XTaskId, taskId)
+	w.Header().Set("Location", location)
+}
+
+// DecodeAppTaskReq step to decode the app task status request
+type DecodeAppTaskReq struct {
+	workspace.TaskBase
+	R             *http.Request   `json:"r,in"`
+	Ctx           context.Context `json:"ctx,out"`
+	AppInstanceId string          `json:"appInstanceId,out"`
+	TaskId        string          `json:"taskId,out"`
+}
+
+// OnRequest handles the app task status request decoding
+func (t *DecodeAppTaskReq) OnRequest(data string) workspace.TaskCode {
+	err := t.getParam(t.R)
+	if err != nil {
+		log.Error("Parameters validation failed.", err)
+	}
+	return workspace.TaskFinish
+}
+
+func (t *DecodeAppTaskReq) getParam(r *http.Request) error {
+	query, _ := meputil.GetHTTPTags(r)
+
+	var err error
+
+	t.AppInstanceId = query.Get(meputil.AppInstanceIdStr)
+	if len(t.AppInstanceId) == 0 {
+		err = fmt.Errorf("invalid app instance id")
+		t.SetFirstErrorCode(meputil.AuthorizationValidateErr, err.Error())
+		return err
+	}
+	if err = meputil.ValidateAppInstanceIdWithHeader(t.AppInstanceId, r); err != nil {
+		log.Error("Validate X-AppinstanceId failed.", err)
+		t.SetFirstErrorCode(meputil.AuthorizationValidateErr, err.Error())
+		return err
+	}
+
+	t.TaskId = query.Get(":taskId")
+	err = meputil.ValidateUUID(t.TaskId)
+	if err != nil {
+		t.SetFirstErrorCode(meputil.RequestParamErr, "taskId validation failed, invalid uuid")
+		return err
+	}
+
+	t.Ctx = util.SetTargetDomainProject(r.Context(), r.Header.Get("X-Domain-Name"), query.Get(":project"))
+	return nil
+}
+
+// AppTaskStatusGet step to read the status of a rule update task of the app instance
+type AppTaskStatusGet struct {
+	workspace.TaskBase
+	appd.AppDCommon
+	AppInstanceId string      `json:"appInstanceId,in"`
+	TaskId        string      `json:"taskId,in"`
+	HttpRsp       interface{} `json:"httpRsp,out"`
+}
+
+// OnRequest handles the app task status query
+func (t *AppTaskStatusGet) OnRequest(data string) workspace.TaskCode {
+	log.Debugf("Query request arrived to fetch task status for appId %s and taskId %s.", t.AppInstanceId,
+		t.TaskId)
+
+	taskProgress, errCode, msg := t.GetTaskProgress(t.TaskId)
+	if errCode != 0 {
+		t.SetFirstErrorCode(errCode, msg)
+		return workspace.TaskFinish
+	}
+
+	// Task of other app instances are not visible over mp1
+	if taskProgress.AppInstanceId != t.AppInstanceId {
+		log.Errorf(nil, "Task(%s) doesn't belong to the app instance(%s).", t.TaskId, t.AppInstanceId)
+		t.SetFirstErrorCode(meputil.SubscriptionNotFound, "task not found")
+		return workspace.TaskFinish
+	}
+
+	t.HttpRsp = *taskProgress
+	return workspace.TaskFinish
+}
@@ -23,17 +23,19 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"mepserver/common/appd"
 	"mepserver/common/extif/dataplane"
 	"mepserver/common/models"
+	"mepserver/mm5/task"
 	"net/http"
 
 	"github.com/apache/servicecomb-service-center/pkg/log"
 	"github.com/apache/servicecomb-service-center/pkg/util"
 
 	"mepserver/common/extif/backend"
-	"mepserver/common/extif/dns"
 
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
 	meputil "mepserver/common/util"
 )
 
@@ -155,26 +157,20 @@ func (t *DecodeDnsRestReq) getParam(r *http.Request) error {
 // DNSRuleUpdate step to handle dns rule update
 type DNSRuleUpdate struct {
 	workspace.TaskBase
+	appd.AppDCommon
 	R             *http.Request       `json:"r,in"`
 	W             http.ResponseWriter `json:"w,in"`
 	RestBody      interface{}         `json:"restBody,in"`
 	AppInstanceId string              `json:"appInstanceId,in"`
 	DNSRuleId     string              `json:"dnsRuleId,in"`
 	HttpRsp       interface{}         `json:"httpRsp,out"`
-	dnsAgent      dns.DNSAgent
-	dataPlane     dataplane.DataPlane
-	AppName       string
+	HttpStatus    int                 `json:"httpStatus,out"`
+	worker        *task.Worker
 }
 
-// WithDNSAgent inputs dns agent
-func (t *DNSRuleUpdate) WithDNSAgent(dnsAgent dns.DNSAgent) *DNSRuleUpdate {
-	t.dnsAgent = dnsAgent
-	return t
-}
-
-// WithDataPlane inputs data plane instance
-func (t *DNSRuleUpdate) WithDataPlane(dataPlane dataplane.DataPlane) *DNSRuleUpdate {
-	t.dataPlane = dataPlane
+// WithWorker inputs the appd sync worker instance
+func (t *DNSRuleUpdate) WithWorker(w *task.Worker) *DNSRuleUpdate {
+	t.worker = w
 	return t
 }
 
@@ -217,14 +213,13 @@ func (t *DNSRuleUpdate) OnRequest(data string) workspace.TaskCode {
 		return workspace.TaskFinish
 	}
 
-	t.AppName = appDInStore.AppName
-
 	dataOnStoreBytes, err := json.Marshal(dnsOnStore)
 	if err != nil {
 		log.Errorf(err, "Failed to parse the dns entry from data-store on update request.")
 		t.SetFirstErrorCode(meputil.OperateDataWithEtcdErr, "parse dns rules failed")
 		return workspace.TaskFinish
 	}
+	audit.SetBefore(t.R.Context(), dataOnStoreBytes)
 
 	// Check for E-Tags precondition. More details could be found here: https://tools.ietf.org/html/rfc7232#section-2.3
 	ifMatchTag := t.R.Header.Get("If-Match")
@@ -233,15 +228,11 @@ func (t *DNSRuleUpdate) OnRequest(data string) workspace.TaskCode {
 		return workspace.TaskFinish
 	}
 
-	errCode, errString := t.updateDnsRecordToRemoteServer(appDInStore, ruleIndex, dnsOnStore, dataOnStoreBytes)
-	if errCode > 0 {
-		t.SetFirstErrorCode(workspace.ErrCode(errCode), errString)
+	code, msg := t.stageDnsRuleUpdate(appDInStore, ruleIndex, dnsOnStore, dataOnStoreBytes)
+	if code != 0 {
+		t.SetFirstErrorCode(code, msg)
 		return workspace.TaskFinish
 	}
-
-	//if errCode == -1 {
-	//	return workspace.TaskFinish
-	//}
 	return workspace.TaskFinish
 }
 
@@ -266,9 +257,9 @@ func (t *DNSRuleUpdate) validateInputs(dnsConfigInput *dataplane.DNSRule,
 	return "", 0
 }
 
-// Update the dns modification request to the remote dns server
-func (t *DNSRuleUpdate) updateDnsRecordToRemoteServer(appDConfig models.AppDConfig, ruleIndex int,
-	dnsOnStore *dataplane.DNSRule, dataOnStoreBytes []byte) (int, string) {
+// Stage the dns modification request as an appd task, the sync worker applies it on the dns server and data-plane
+func (t *DNSRuleUpdate) stageDnsRuleUpdate(appDConfig models.AppDConfig, ruleIndex int,
+	dnsOnStore *dataplane.DNSRule, dataOnStoreBytes []byte) (workspace.ErrCode, string) {
 
 	// E-Tag check need to be done before parsing, hence added parsing here
 	dnsConfigInput, ok := t.RestBody.(*dataplane.DNSRule)
@@ -284,133 +275,30 @@ func (t *DNSRuleUpdate) updateDnsRecordToRemoteServer(appDConfig models.AppDConf
 	if dnsOnStore.State == dnsConfigInput.State {
 		t.W.Header().Set("ETag", meputil.GenerateStrongETag(dataOnStoreBytes))
 		t.HttpRsp = dnsOnStore
-		return -1, ""
+		return 0, ""
 	}
 
-	var err error
-	// Backing up state data for reconfigure in case of failure
-	oldState := dnsOnStore.State
-
 	dnsOnStore.State = dnsConfigInput.State
 	appDConfig.AppDNSRule[ruleIndex].State = dnsConfigInput.State
-	errCode, errString := t.updateDnsRecordOnDataStore(appDConfig)
-	if errCode != 0 {
-		return errCode, errString
-	}
-
-	rrType := meputil.RRTypeA
-	if dnsOnStore.IPAddressType == meputil.IPv6Type {
-		rrType = meputil.RRTypeAAAA
-	}
-
-	// Update the DNS server as per the new configurations
-	if dnsConfigInput.State == meputil.ActiveState {
-		err = t.dnsAgent.AddResourceRecord(dnsOnStore.DomainName, rrType, meputil.RRClassIN,
-			[]string{dnsOnStore.IPAddress}, dnsOnStore.TTL)
-	} else {
-		err = t.dnsAgent.DeleteResourceRecord(dnsOnStore.DomainName, rrType)
-	}
-	if err != nil {
-		log.Errorf(err, "Dns rule(app-id: %s, dns-rule-id: %s) update fail on dns server.",
-			t.AppInstanceId, t.DNSRuleId)
-
-		// Revert the update in the data store in failure case
-		appDConfig.AppDNSRule[ruleIndex].State = oldState
-		t.revertEntryFromDB(&appDConfig)
-
-		return meputil.RemoteServerErr, "failed to apply the dns modification"
-	}
-
-	appInfo := dataplane.ApplicationInfo{
-		Id:   t.AppInstanceId,
-		Name: t.AppName,
-	}
-
-	err = t.updateDNSToDataPlane(dnsConfigInput, dnsOnStore, appInfo, rrType)
-
+	stagedRuleBytes, err := json.Marshal(dnsOnStore)
 	if err != nil {
-		log.Errorf(err, "Dns rule(app-id: %s, dns-rule-id: %s) update fail on data-plane.",
-			t.AppInstanceId, t.DNSRuleId)
-		// Revert the entry in dns server
-		t.revertEntryFromDNSServer(dnsConfigInput.State, dnsOnStore.DomainName, rrType, dnsOnStore.IPAddress,
-			dnsOnStore.TTL)
-		// Revert the update in the data store in failure case
-		appDConfig.AppDNSRule[ruleIndex].State = oldState
-		t.revertEntryFromDB(&appDConfig)
-
-		return meputil.RemoteServerErr, "failed to apply the dns modification"
-	}
-
-	// State updated on dnsOnStore, so regenerate the byte array
-	dataOnStoreBytes, err = json.Marshal(dnsOnStore)
-	if err == nil {
-		t.W.Header().Set("ETag", meputil.GenerateStrongETag(dataOnStoreBytes))
+		log.Errorf(err, "Failed to parse the modified dns rule on update request.")
+		return meputil.ParseInfoErr, "internal error on data parsing"
 	}
 
-	t.HttpRsp = dnsOnStore
-
-	return 0, ""
-}
-
-func (t *DNSRuleUpdate) revertEntryFromDB(appDConfig *models.AppDConfig) {
-	errCode, _ := t.updateDnsRecordOnDataStore(*appDConfig)
-	if errCode != 0 {
-		log.Errorf(nil, "Failed to revert dns rule(app-id: %s, dns-rule-id: %s) update on data-store, "+
-			"this might lead to inconsistency.", t.AppInstanceId, t.DNSRuleId)
-	}
-}
-
-func (t *DNSRuleUpdate) revertEntryFromDNSServer(state, domainName, rrType, ipAddress string, ttl uint32) {
-	var err error
-	if state == meputil.ActiveState {
-		err = t.dnsAgent.DeleteResourceRecord(domainName, rrType)
-	} else {
-		err = t.dnsAgent.AddResourceRecord(domainName, rrType, meputil.RRClassIN,
-			[]string{ipAddress}, ttl)
+	taskId, code, msg := stageRuleUpdate(&t.AppDCommon, t.worker, t.AppInstanceId, &appDConfig)
+	if code != 0 {
+		return code, msg
 	}
-	if err != nil {
-		log.Errorf(nil, "Failed to revert dns rule(app-id: %s, dns-rule-id: %s) update on dns-server, "+
-			"this might lead to inconsistency.", t.AppInstanceId, t.DNSRuleId)
-	}
-}
 
-// Update the dns record to the data-store
-func (t *DNSRuleUpdate) updateDnsRecordOnDataStore(appDConfig models.AppDConfig) (int, string) {
-	updateJSON, err := json.Marshal(appDConfig)
-	if err != nil {
-		return meputil.ParseInfoErr, "output rule parse failed"
-	}
-	errCode := backend.PutRecord(meputil.AppDConfigKeyPath+t.AppInstanceId, updateJSON)
-	if errCode != 0 {
-		return errCode, "rule insertion failed"
-	}
+	log.Infof("Dns rule(app-id: %s, dns-rule-id: %s) update staged with task %s.", t.AppInstanceId,
+		t.DNSRuleId, taskId)
+	// The rule is not applied yet, the response refers to the task applying it
+	t.W.Header().Set("ETag", meputil.GenerateStrongETag(stagedRuleBytes))
+	setTaskHeaders(t.W, t.AppInstanceId, taskId)
+	t.HttpStatus = http.StatusAccepted
+	t.HttpRsp = t.GenerateTaskResponse(taskId, t.AppInstanceId, meputil.TaskStateProcessing, "0",
+		"Operation In progress")
 
 	return 0, ""
 }
-
-func (t *DNSRuleUpdate) updateDNSToDataPlane(dnsConfigInput *dataplane.DNSRule, dnsOnStore *dataplane.DNSRule,
-	appInfo dataplane.ApplicationInfo, rrType string) error {
-	var err error
-	if dnsConfigInput.State == meputil.ActiveState {
-		err = t.dataPlane.AddDNSRule(appInfo, t.DNSRuleId, dnsOnStore.DomainName,
-			dnsOnStore.IPAddressType, dnsOnStore.IPAddress, dnsOnStore.TTL)
-		if err != nil {
-			if err1 := t.dnsAgent.DeleteResourceRecord(dnsOnStore.DomainName, rrType); err1 != nil {
-				log.Errorf(err1, "Failed to revert the configuration(oper: delete, app-id: %s, "+
-					"dns-rule-id: %s) from dns-server, this might lead to data inconsistency.",
-					t.AppInstanceId, t.DNSRuleId)
-			}
-		}
-	} else {
-		err = t.dataPlane.DeleteDNSRule(appInfo, t.DNSRuleId)
-		if err != nil {
-			if err1 := t.dnsAgent.AddResourceRecord(dnsOnStore.DomainName, rrType, meputil.RRClassIN,
-				[]string{dnsOnStore.IPAddress}, dnsOnStore.TTL); err1 != nil {
-				log.Errorf(err1, "Failed to revert the configuration(oper: create, app-id: %s, "+
-					"dns-rule-id: %s) from dns-server, this might lead to data inconsistency.",
-					t.AppInstanceId, t.DNSRuleId)
-			}
-		}
-	}
-	return err
-}
@@ -23,6 +23,7 @@ import (
 	"errors"
 	"io/ioutil"
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
 	meputil "mepserver/common/util"
 	"net/http"
 	"strconv"
@@ -184,6 +185,8 @@ func (t *UpdateHeartbeat) OnRequest(data string) workspace.TaskCode {
 		t.SetFirstErrorCode(meputil.SerInstanceNotFound, "service instance id not found")
 		return workspace.TaskFinish
 	}
+	// the properties are updated in place, the hash is taken before
+	audit.SetBefore(t.Ctx, resp.Instance)
 	properties := resp.Instance.Properties
 	interval, err := strconv.Atoi(properties["livenessInterval"])
 	if err != nil {
@@ -0,0 +1,363 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package plans implements mep server api plans
+package plans
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+	"github.com/apache/servicecomb-service-center/pkg/util"
+
+	"mepserver/common/arch/workspace"
+	"mepserver/common/extif/platsvc"
+	"mepserver/common/models"
+	meputil "mepserver/common/util"
+)
+
+// DecodePlatformSvcReq step to decode the radio network information and location service requests
+type DecodePlatformSvcReq struct {
+	workspace.TaskBase
+	R             *http.Request   `json:"r,in"`
+	Ctx           context.Context `json:"ctx,out"`
+	AppInstanceId string          `json:"appInstanceId,out"`
+	SubscribeId   string          `json:"subscribeId,out"`
+	QueryParam    url.Values      `json:"queryParam,out"`
+	RestBody      interface{}     `json:"restBody,out"`
+}
+
+// OnRequest decodes the platform service request messages
+func (t *DecodePlatformSvcReq) OnRequest(data string) workspace.TaskCode {
+	log.Infof("Received message from ClientIP [%s] AppInstanceId [%s] Operation [%s] Resource [%s].",
+		meputil.GetClientIp(t.R), meputil.GetAppInstanceId(t.R), meputil.GetMethodFromReq(t.R),
+		meputil.GetHttpResourceInfo(t.R))
+
+	if err := t.getParam(t.R); err != nil {
+		log.Error("Parameters validation failed on platform service request.", err)
+		return workspace.TaskFinish
+	}
+	if err := t.parseBody(t.R); err != nil {
+		log.Error("Platform service request body parse failed.", err)
+	}
+	return workspace.TaskFinish
+}
+
+// WithBody set body and return DecodePlatformSvcReq
+func (t *DecodePlatformSvcReq) WithBody(body interface{}) *DecodePlatformSvcReq {
+	t.RestBody = body
+	return t
+}
+
+func (t *DecodePlatformSvcReq) getParam(r *http.Request) error {
+	query, _ := meputil.GetHTTPTags(r)
+
+	// the platform service apis are not scoped by the app instance path, hence it is taken from the header
+	t.AppInstanceId = r.Header.Get("X-AppinstanceID")
+	if err := meputil.ValidateUUID(t.AppInstanceId); err != nil || len(t.AppInstanceId) == 0 {
+		t.SetFirstErrorCode(meputil.AuthorizationValidateErr, "UnAuthorization to access the resource")
+		return errors.New("invalid app instance id in header")
+	}
+
+	t.SubscribeId = query.Get(":subscriptionId")
+	if err := meputil.ValidateUUID(t.SubscribeId); err != nil {
+		t.SetFirstErrorCode(meputil.RequestParamErr, "subscription ID validation failed, invalid uuid")
+		return err
+	}
+
+	t.QueryParam = query
+	t.Ctx = util.SetTargetDomainProject(r.Context(), r.Header.Get("X-Domain-Name"), query.Get(":project"))
+	return nil
+}
+
+func (t *DecodePlatformSvcReq) parseBody(r *http.Request) error {
+	if t.RestBody == nil {
+		return nil
+	}
+	msg, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		t.SetFirstErrorCode(meputil.SerErrFailBase, "read request body error")
+		return errors.New("read failed")
+	}
+	if len(msg) > meputil.RequestBodyLength {
+		t.SetFirstErrorCode(meputil.RequestParamErr, "request body too large")
+		return errors.New("request body too large")
+	}
+	if err = json.Unmarshal(msg, t.RestBody); err != nil {
+		t.SetFirstErrorCode(meputil.ParseInfoErr, "unmarshal request body error")
+		return errors.New("json unmarshalling failed")
+	}
+	if err = meputil.ValidateRestBody(t.RestBody); err != nil {
+		t.SetFirstErrorCode(meputil.RequestParamErr, "request param validation failed")
+		return err
+	}
+	return nil
+}
+
+// RabInfoGet step to query the radio access bearers
+type RabInfoGet struct {
+	workspace.TaskBase
+	AppInstanceId string      `json:"appInstanceId,in"`
+	QueryParam    url.Values  `json:"queryParam,in"`
+	HttpRsp       interface{} `json:"httpRsp,out"`
+	provider      platsvc.Provider
+}
+
+// WithProvider set the radio network information provider
+func (t *RabInfoGet) WithProvider(provider platsvc.Provider) *RabInfoGet {
+	t.provider = provider
+	return t
+}
+
+// OnRequest handles the rab info query
+func (t *RabInfoGet) OnRequest(data string) workspace.TaskCode {
+	plmn, cells, ues, err := readRadioNetwork(t.provider)
+	if err != nil {
+		log.Error("Read radio network from provider failed.", err)
+		t.SetFirstErrorCode(meputil.RemoteServerErr, "read radio network failed")
+		return workspace.TaskFinish
+	}
+
+	cellFilter := splitQueryValues(t.QueryParam, "cell_id")
+	ueFilter := append(splitQueryValues(t.QueryParam, "ue_ipv4_address"),
+		splitQueryValues(t.QueryParam, "ue_ipv6_address")...)
+
+	cellUserInfo := make([]models.CellUserInfo, 0, len(cells))
+	for _, cell := range cells {
+		if len(cellFilter) != 0 && !meputil.StringInList(cell.CellId, cellFilter) {
+			continue
+		}
+		ueInfo := make([]models.UeInfo, 0)
+		for _, ue := range ues {
+			if ue.CellId != cell.CellId || (len(ueFilter) != 0 && !meputil.StringInList(ue.Address, ueFilter)) {
+				continue
+			}
+			ueInfo = append(ueInfo, buildUeInfo(ue))
+		}
+		if len(ueInfo) == 0 && (len(ueFilter) != 0 || len(cellFilter) == 0) {
+			continue
+		}
+		cellUserInfo = append(cellUserInfo, models.CellUserInfo{Ecgi: BuildEcgi(plmn, cell.CellId), UeInfo: ueInfo})
+	}
+
+	t.HttpRsp = models.RabInfo{
+		AppInstanceId: t.AppInstanceId,
+		RequestId:     t.QueryParam.Get("request_id"),
+		CellUserInfo:  cellUserInfo,
+		TimeStamp:     BuildTimeStamp(time.Now()),
+	}
+	return workspace.TaskFinish
+}
+
+// PlmnInfoGet step to query the plmn information
+type PlmnInfoGet struct {
+	workspace.TaskBase
+	AppInstanceId string      `json:"appInstanceId,in"`
+	HttpRsp       interface{} `json:"httpRsp,out"`
+	provider      platsvc.Provider
+}
+
+// WithProvider set the radio network information provider
+func (t *PlmnInfoGet) WithProvider(provider platsvc.Provider) *PlmnInfoGet {
+	t.provider = provider
+	return t
+}
+
+// OnRequest handles the plmn info query
+func (t *PlmnInfoGet) OnRequest(data string) workspace.TaskCode {
+	plmn, _, _, err := readRadioNetwork(t.provider)
+	if err != nil {
+		log.Error("Read plmn from provider failed.", err)
+		t.SetFirstErrorCode(meputil.RemoteServerErr, "read plmn failed")
+		return workspace.TaskFinish
+	}
+	plmnList := make([]models.Plmn, 0, 1)
+	if len(plmn.Mcc) != 0 {
+		plmnList = append(plmnList, models.Plmn{Mcc: plmn.Mcc, Mnc: plmn.Mnc})
+	}
+	t.HttpRsp = models.PlmnInfo{AppInstanceId: t.AppInstanceId, Plmn: plmnList, TimeStamp: BuildTimeStamp(time.Now())}
+	return workspace.TaskFinish
+}
+
+// LocationUsersGet step to query the user locations
+type LocationUsersGet struct {
+	workspace.TaskBase
+	R          *http.Request `json:"r,in"`
+	QueryParam url.Values    `json:"queryParam,in"`
+	HttpRsp    interface{}   `json:"httpRsp,out"`
+	provider   platsvc.Provider
+}
+
+// WithProvider set the location provider
+func (t *LocationUsersGet) WithProvider(provider platsvc.Provider) *LocationUsersGet {
+	t.provider = provider
+	return t
+}
+
+// OnRequest handles the user location query
+func (t *LocationUsersGet) OnRequest(data string) workspace.TaskCode {
+	_, cells, ues, err := readRadioNetwork(t.provider)
+	if err != nil {
+		log.Error("Read user locations from provider failed.", err)
+		t.SetFirstErrorCode(meputil.RemoteServerErr, "read user locations failed")
+		return workspace.TaskFinish
+	}
+
+	zoneFilter := splitQueryValues(t.QueryParam, "zoneId")
+	apFilter := splitQueryValues(t.QueryParam, "accessPointId")
+	addressFilter := splitQueryValues(t.QueryParam, "address")
+	cellMap := make(map[string]platsvc.Cell, len(cells))
+	for _, cell := range cells {
+		cellMap[cell.CellId] = cell
+	}
+
+	selfPath := t.R.URL.Path[len(meputil.RootPath):]
+	now := BuildTimeStamp(time.Now())
+	users := make([]models.UserInfo, 0, len(ues))
+	for _, ue := range ues {
+		cell := cellMap[ue.CellId]
+		if (len(zoneFilter) != 0 && !meputil.StringInList(cell.ZoneId, zoneFilter)) ||
+			(len(apFilter) != 0 && !meputil.StringInList(cell.AccessPointId, apFilter)) ||
+			(len(addressFilter) != 0 && !meputil.StringInList(ue.Address, addressFilter)) {
+			continue
+		}
+		users = append(users, models.UserInfo{
+			Address:       ue.Address,
+			AccessPointId: cell.AccessPointId,
+			ZoneId:        cell.ZoneId,
+			ResourceURL:   selfPath + "?address=" + url.QueryEscape(ue.Address),
+			LocationInfo: models.LocationInfo{Latitude: []float32{ue.Latitude},
+				Longitude: []float32{ue.Longitude}, Shape: 2},
+			TimeStamp: now,
+		})
+	}
+	t.HttpRsp = models.UserListResponse{UserList: models.UserList{User: users, ResourceURL: selfPath}}
+	return workspace.TaskFinish
+}
+
+// LocationZonesGet step to query the zones
+type LocationZonesGet struct {
+	workspace.TaskBase
+	R        *http.Request `json:"r,in"`
+	HttpRsp  interface{}   `json:"httpRsp,out"`
+	provider platsvc.Provider
+}
+
+// WithProvider set the location provider
+func (t *LocationZonesGet) WithProvider(provider platsvc.Provider) *LocationZonesGet {
+	t.provider = provider
+	return t
+}
+
+// OnRequest handles the zone query
+func (t *LocationZonesGet) OnRequest(data string) workspace.TaskCode {
+	_, cells, ues, err := readRadioNetwork(t.provider)
+	if err != nil {
+		log.Error("Read zones from provider failed.", err)
+		t.SetFirstErrorCode(meputil.RemoteServerErr, "read zones failed")
+		return workspace.TaskFinish
+	}
+
+	selfPath := t.R.URL.Path[len(meputil.RootPath):]
+	zoneIndex := make(map[string]int)
+	cellZone := make(map[string]string, len(cells))
+	zones := make([]models.ZoneInfo, 0)
+	for _, cell := range cells {
+		cellZone[cell.CellId] = cell.ZoneId
+		index, found := zoneIndex[cell.ZoneId]
+		if !found {
+			index = len(zones)
+			zoneIndex[cell.ZoneId] = index
+			zones = append(zones, models.ZoneInfo{ZoneId: cell.ZoneId,
+				ResourceURL: selfPath + "/" + url.PathEscape(cell.ZoneId)})
+		}
+		zones[index].NumberOfAccessPoints++
+	}
+	for _, ue := range ues {
+		if index, found := zoneIndex[cellZone[ue.CellId]]; found {
+			zones[index].NumberOfUsers++
+		}
+	}
+	t.HttpRsp = models.ZoneListResponse{ZoneList: models.ZoneList{Zone: zones, ResourceURL: selfPath}}
+	return workspace.TaskFinish
+}
+
+func readRadioNetwork(provider platsvc.Provider) (platsvc.Plmn, []platsvc.Cell, []platsvc.UserEquipment, error) {
+	if provider == nil {
+		return platsvc.Plmn{}, nil, nil, errors.New("provider not initialized")
+	}
+	plmn, err := provider.GetPlmn()
+	if err != nil {
+		return platsvc.Plmn{}, nil, nil, err
+	}
+	cells, err := provider.GetCells()
+	if err != nil {
+		return platsvc.Plmn{}, nil, nil, err
+	}
+	ues, err := provider.GetUserEquipments()
+	if err != nil {
+		return platsvc.Plmn{}, nil, nil, err
+	}
+	return plmn, cells, ues, nil
+}
+
+// splitQueryValues supports both repeated and comma separated query parameters
+func splitQueryValues(query url.Values, key string) []string {
+	values := make([]string, 0)
+	for _, value := range query[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); len(item) != 0 {
+				values = append(values, item)
+			}
+		}
+	}
+	return values
+}
+
+func buildUeInfo(ue platsvc.UserEquipment) models.UeInfo {
+	erabInfo := make([]models.ErabInfo, 0, len(ue.Erabs))
+	for _, erab := range ue.Erabs {
+		erabInfo = append(erabInfo, models.ErabInfo{ErabId: erab.ErabId,
+			ErabQosParameters: models.ErabQosParameters{Qci: erab.Qci}})
+	}
+	return models.UeInfo{AssociateId: []models.AssociateId{BuildAssociateId(ue.Address)}, ErabInfo: erabInfo}
+}
+
+// BuildAssociateId generates the associate id of an user equipment address
+func BuildAssociateId(address string) models.AssociateId {
+	idType := models.AssociateIdUeIpv4
+	if meputil.FindIPAddressType(address) == meputil.IpTypeIpv6 {
+		idType = models.AssociateIdUeIpv6
+	}
+	return models.AssociateId{Type: idType, Value: address}
+}
+
+// BuildEcgi generates the E-UTRAN cell global identifier
+func BuildEcgi(plmn platsvc.Plmn, cellId string) models.Ecgi {
+	return models.Ecgi{CellId: cellId, Plmn: models.Plmn{Mcc: plmn.Mcc, Mnc: plmn.Mnc}}
+}
+
+// BuildTimeStamp converts the time to seconds and nano seconds
+func BuildTimeStamp(t time.Time) models.NtpTimeStamp {
+	return models.NtpTimeStamp{Seconds: int(t.Unix()), NanoSeconds: t.Nanosecond()}
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package plans implements mep server api plans
+package plans
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+	uuid "github.com/satori/go.uuid"
+
+	"mepserver/common/arch/workspace"
+	"mepserver/common/extif/backend"
+	"mepserver/common/models"
+	meputil "mepserver/common/util"
+)
+
+// PlatformSvcSubscribe step to handle the radio network information and location subscribe requests
+type PlatformSvcSubscribe struct {
+	workspace.TaskBase
+	W             http.ResponseWriter `json:"w,in"`
+	RestBody      interface{}         `json:"restBody,in"`
+	AppInstanceId string              `json:"appInstanceId,in"`
+	SubscribeId   string              `json:"subscribeId,out"`
+	HttpRsp       interface{}         `json:"httpRsp,out"`
+	SubscribeType string              `json:"subscribeType,out"`
+}
+
+// WithType set type and return PlatformSvcSubscribe
+func (t *PlatformSvcSubscribe) WithType(subType string) *PlatformSvcSubscribe {
+	t.SubscribeType = subType
+	return t
+}
+
+// OnRequest handles the platform service subscribe request
+func (t *PlatformSvcSubscribe) OnRequest(data string) workspace.TaskCode {
+	t.SubscribeId = uuid.NewV4().String()
+
+	var location string
+	switch sub := t.RestBody.(type) {
+	case *models.CellChangeSubscription:
+		location = fmt.Sprintf("%s/subscriptions/%s", meputil.MecRniPath, t.SubscribeId)
+		sub.Links = models.Links{Self: models.Self{Href: location}}
+		sub.FilterCriteriaAssocHo.AppInstanceId = t.AppInstanceId
+		t.HttpRsp = sub
+	case *models.UserTrackingSubscriptionBody:
+		location = fmt.Sprintf("%s/subscriptions/userTracking/%s", meputil.MecLocationPath, t.SubscribeId)
+		sub.UserTrackingSubscription.ResourceURL = location
+		t.HttpRsp = sub
+	default:
+		log.Error(meputil.ErrorRequestBodyMessage, nil)
+		t.SetFirstErrorCode(meputil.RequestParamErr, meputil.ErrorRequestBodyMessage)
+		return workspace.TaskFinish
+	}
+
+	subscribeJSON, err := json.Marshal(t.HttpRsp)
+	if err != nil {
+		log.Errorf(nil, "Can not marshal subscribe info.")
+		t.SetFirstErrorCode(meputil.ParseInfoErr, "marshal subscribe info error")
+		return workspace.TaskFinish
+	}
+	errCode := backend.PutRecord(meputil.GetSubscribeKeyPath(t.SubscribeType)+t.AppInstanceId+"/"+t.SubscribeId,
+		subscribeJSON)
+	if errCode != 0 {
+		log.Errorf(nil, "Subscription to etcd failed.")
+		t.SetFirstErrorCode(workspace.ErrCode(errCode), "put subscription to etcd failed")
+		return workspace.TaskFinish
+	}
+	t.W.Header().Set("Location", location)
+	log.Infof("Subscription(%s) of type %s created for app %s.", t.SubscribeId, t.SubscribeType, t.AppInstanceId)
+	return workspace.TaskFinish
+}
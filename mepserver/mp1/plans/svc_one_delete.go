@@ -26,6 +26,7 @@ import (
 	scerr "github.com/apache/servicecomb-service-center/server/error"
 
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
 	"mepserver/common/util"
 )
 
@@ -48,6 +49,9 @@ func (t *DeleteService) OnRequest(data string) workspace.TaskCode {
 	serviceID := t.ServiceId[:len(t.ServiceId)/2]
 	log.Debugf("Delete request arrived for service with serviceId %s.", serviceID)
 	instanceID := t.ServiceId[len(t.ServiceId)/2:]
+	if instance, err := util.GetServiceInstance(t.Ctx, t.ServiceId); err == nil {
+		audit.SetBefore(t.Ctx, instance)
+	}
 	req := &proto.UnregisterInstanceRequest{
 		ServiceId:  serviceID,
 		InstanceId: instanceID,
@@ -27,6 +27,8 @@ import (
 	"mepserver/common/models"
 
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
+	"mepserver/common/extif/apigw"
 	meputil "mepserver/common/util"
 )
 
@@ -39,6 +41,13 @@ type UpdateInstance struct {
 	RestBody      interface{}     `json:"restBody,in"`
 	HttpRsp       interface{}     `json:"httpRsp,out"`
 	AppInstanceId string          `json:"appInstanceId,in"`
+	apiGateway    apigw.APIGateway
+}
+
+// WithAPIGateway inputs the api gateway instance
+func (t *UpdateInstance) WithAPIGateway(apiGateway apigw.APIGateway) *UpdateInstance {
+	t.apiGateway = apiGateway
+	return t
 }
 
 // OnRequest handles service update request
@@ -60,9 +69,12 @@ func (t *UpdateInstance) OnRequest(data string) workspace.TaskCode {
 		t.SetFirstErrorCode(meputil.SerInstanceNotFound, "find service failed")
 		return workspace.TaskFinish
 	}
+	audit.SetBefore(t.Ctx, instance)
 
 	apiGwSerName := meputil.GetApiGwSerName(instance)
 
+	// the properties are updated in place, the api gateway services before the update are kept for the rollback
+	previousServices := apigw.ServicesFromProperties(instance.Properties)
 	copyInstanceRef := *instance
 	req := proto.RegisterInstanceRequest{
 		Instance: &copyInstanceRef,
@@ -75,10 +87,20 @@ func (t *UpdateInstance) OnRequest(data string) workspace.TaskCode {
 		req.Instance.Properties["liveness"] = fmt.Sprintf(meputil.LivenessPath, t.AppInstanceId,
 			instance.ServiceId+instance.InstanceId)
 	}
+	// the api gateway is updated before the registry, it is restored to the services before the update on failure
+	gateway := apigw.WithContext(t.apiGateway, t.TraceContext())
+	services := apigw.ServicesFromProperties(req.Instance.Properties)
+	err = apigw.RegisterServices(gateway, services, false)
+	if err != nil {
+		restoreServices(gateway, services, previousServices)
+		t.SetFirstErrorCode(meputil.RemoteServerErr, "api gateway update failed")
+		return workspace.TaskFinish
+	}
 	domainProject := util.ParseDomainProject(t.Ctx)
 	centerErr := svcutil.UpdateInstance(t.Ctx, domainProject, &copyInstanceRef)
 	if centerErr != nil {
 		log.Error("Update service failed.", nil)
+		restoreServices(gateway, services, previousServices)
 		t.SetFirstErrorCode(meputil.SerErrServiceUpdFailed, "update service failed")
 		return workspace.TaskFinish
 	}
@@ -86,6 +108,7 @@ func (t *UpdateInstance) OnRequest(data string) workspace.TaskCode {
 	err = meputil.RecordHeartbeat(t.Ctx, t.ServiceId)
 	if err != nil {
 		log.Error("Heartbeat update failed.", nil)
+		restoreServices(gateway, services, previousServices)
 		t.SetFirstErrorCode(meputil.SerErrServiceUpdFailed, "heartbeat failed")
 		return workspace.TaskFinish
 	}
@@ -93,3 +116,22 @@ func (t *UpdateInstance) OnRequest(data string) workspace.TaskCode {
 	t.HttpRsp = mp1Ser
 	return workspace.TaskFinish
 }
+
+// restoreServices re-applies the api gateway services before the update and removes the ones added by the update,
+// failures are logged
+func restoreServices(gateway apigw.APIGateway, services []apigw.Service, previousServices []apigw.Service) {
+	previous := make(map[string]bool, len(previousServices))
+	for _, service := range previousServices {
+		previous[service.Name] = true
+	}
+	added := make([]apigw.Service, 0)
+	for _, service := range services {
+		if !previous[service.Name] {
+			added = append(added, service)
+		}
+	}
+	apigw.UnregisterServices(gateway, added)
+	if err := apigw.RegisterServices(gateway, previousServices, false); err != nil {
+		log.Errorf(err, "API gateway services could not be restored after the failed update.")
+	}
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/apache/servicecomb-service-center/server/core/proto"
 
 	"mepserver/common/arch/workspace"
+	"mepserver/common/extif/apigw"
 	meputil "mepserver/common/util"
 )
 
@@ -223,6 +224,13 @@ type RegisterServiceInst struct {
 	InstanceId    string              `json:"instanceId,out"`
 	RestBody      interface{}         `json:"restBody,in"`
 	HttpRsp       interface{}         `json:"httpRsp,out"`
+	apiGateway    apigw.APIGateway
+}
+
+// WithAPIGateway inputs the api gateway instance
+func (t *RegisterServiceInst) WithAPIGateway(apiGateway apigw.APIGateway) *RegisterServiceInst {
+	t.apiGateway = apiGateway
+	return t
 }
 
 // OnRequest handles service instance registrations
@@ -265,6 +273,15 @@ func (t *RegisterServiceInst) OnRequest(data string) workspace.TaskCode {
 		t.SetFirstErrorCode(meputil.SerErrServiceInstanceFailed, "Status properties failed")
 		return workspace.TaskFinish
 	}
+
+	gateway := apigw.WithContext(t.apiGateway, t.TraceContext())
+	err = apigw.RegisterServices(gateway, apigw.ServicesFromProperties(req.Instance.Properties), true)
+	if err != nil {
+		log.Errorf(nil, "API gateway registration failed, removing the instance %s.", t.InstanceId)
+		t.unregisterInstance()
+		t.SetFirstErrorCode(meputil.RemoteServerErr, "api gateway registration failed")
+		return workspace.TaskFinish
+	}
 	// build response serviceComb use serviceId + InstanceId to mark a service instance
 	mp1SerId := t.ServiceId + t.InstanceId
 	serviceInfo.SerInstanceId = mp1SerId
@@ -275,14 +292,8 @@ func (t *RegisterServiceInst) OnRequest(data string) workspace.TaskCode {
 	_, err = json.Marshal(serviceInfo)
 	if err != nil {
 		log.Errorf(nil, "Service info encoding on registration failed.")
-		unResReq := &proto.UnregisterInstanceRequest{
-			ServiceId:  t.ServiceId,
-			InstanceId: t.InstanceId,
-		}
-		_, err := core.InstanceAPI.Unregister(t.Ctx, unResReq)
-		if err != nil {
-			log.Errorf(nil, "Service delete failed.")
-		}
+		t.unregisterInstance()
+		apigw.UnregisterServices(gateway, apigw.ServicesFromProperties(req.Instance.Properties))
 		t.SetFirstErrorCode(meputil.ParseInfoErr, "marshal service info failed")
 		return workspace.TaskFinish
 	}
@@ -290,6 +301,17 @@ func (t *RegisterServiceInst) OnRequest(data string) workspace.TaskCode {
 	return workspace.TaskFinish
 }
 
+func (t *RegisterServiceInst) unregisterInstance() {
+	unResReq := &proto.UnregisterInstanceRequest{
+		ServiceId:  t.ServiceId,
+		InstanceId: t.InstanceId,
+	}
+	_, err := core.InstanceAPI.Unregister(t.Ctx, unResReq)
+	if err != nil {
+		log.Errorf(nil, "Service delete failed.")
+	}
+}
+
 // RegisterLimit step to check the service registration limit
 type RegisterLimit struct {
 	workspace.TaskBase
@@ -23,6 +23,7 @@ import (
 	"fmt"
 	"github.com/apache/servicecomb-service-center/pkg/util"
 	"github.com/go-playground/validator/v10"
+	"mepserver/common/appd"
 	"mepserver/common/extif/dataplane"
 	"mepserver/common/models"
 	meputil "mepserver/common/util"
@@ -101,6 +102,13 @@ func (t *DecodeTrafficRestReq) parseBody(r *http.Request) error {
 		return verrs
 	}
 
+	if invalidParams := appd.NormalizeTrafficRule(trafficInPut, ""); len(invalidParams) != 0 {
+		log.Errorf(nil, "Traffic filter validation failed(%d invalid fields).", len(invalidParams))
+		t.SetInvalidParams(invalidParams)
+		t.SetFirstErrorCode(meputil.RequestParamErr, appd.InvalidTrafficFilter)
+		return fmt.Errorf(appd.InvalidTrafficFilter)
+	}
+
 	return nil
 }
 
@@ -19,8 +19,10 @@ package plans
 
 import (
 	"encoding/json"
+	"mepserver/common/appd"
 	"mepserver/common/extif/dataplane"
 	"mepserver/common/models"
+	"mepserver/mm5/task"
 	"net/http"
 	"reflect"
 
@@ -31,23 +33,26 @@ import (
 	"github.com/apache/servicecomb-service-center/pkg/log"
 
 	"mepserver/common/arch/workspace"
+	"mepserver/common/audit"
 )
 
 // TrafficRuleUpdate step to update the traffic rule
 type TrafficRuleUpdate struct {
 	workspace.TaskBase
+	appd.AppDCommon
 	R             *http.Request       `json:"r,in"`
 	W             http.ResponseWriter `json:"w,in"`
 	RestBody      interface{}         `json:"restBody,in"`
 	AppInstanceId string              `json:"appInstanceId,in"`
 	TrafficRuleId string              `json:"trafficRuleId,in"`
 	HttpRsp       interface{}         `json:"httpRsp,out"`
-	dataPlane     dataplane.DataPlane
+	HttpStatus    int                 `json:"httpStatus,out"`
+	worker        *task.Worker
 }
 
-// WithDataPlane inputs the data plane instance
-func (t *TrafficRuleUpdate) WithDataPlane(dataPlane dataplane.DataPlane) *TrafficRuleUpdate {
-	t.dataPlane = dataPlane
+// WithWorker inputs the appd sync worker instance
+func (t *TrafficRuleUpdate) WithWorker(w *task.Worker) *TrafficRuleUpdate {
+	t.worker = w
 	return t
 }
 
@@ -108,6 +113,7 @@ func (t *TrafficRuleUpdate) OnRequest(data string) workspace.TaskCode {
 		t.SetFirstErrorCode(meputil.ParseInfoErr, "internal error on data parsing")
 		return workspace.TaskFinish
 	}
+	audit.SetBefore(t.R.Context(), dataStoreEntryBytes)
 
 	// Check for E-Tags precondition. More details could be found here: https://tools.ietf.org/html/rfc7232#section-2.3
 	ifMatchTag := t.R.Header.Get("If-Match")
@@ -123,66 +129,28 @@ func (t *TrafficRuleUpdate) OnRequest(data string) workspace.TaskCode {
 		return workspace.TaskFinish
 	}
 
-	errCode, errString := t.applyTrafficRule(trafficRule, appDConfig, ruleIndex, appDConfigDB)
-	if errCode != 0 {
-		t.SetFirstErrorCode(workspace.ErrCode(errCode), errString)
-		return workspace.TaskFinish
-	}
-	return workspace.TaskFinish
-}
-
-func (t *TrafficRuleUpdate) applyTrafficRule(trafficRule *dataplane.TrafficRule, appDConfig models.AppDConfig,
-	ruleIndex int, appDConfigDB []byte) (int, string) {
-	trafficInPut, _ := t.RestBody.(*dataplane.TrafficRule)
-
 	trafficInPut.TrafficRuleID = trafficRule.TrafficRuleID
-
 	appDConfig.AppTrafficRule[ruleIndex] = *trafficInPut
-	updateJSON, err := json.Marshal(appDConfig)
+	stagedRuleBytes, err := json.Marshal(trafficInPut)
 	if err != nil {
-		log.Errorf(err, "Can not marshal the input traffic body.")
-		return meputil.ParseInfoErr, "can not marshal traffic info"
-	}
-
-	resultErr := backend.PutRecord(meputil.AppDConfigKeyPath+t.AppInstanceId, updateJSON)
-	if resultErr != 0 {
-		log.Errorf(nil, "Traffic rule(appId: %s, ruleId: %s) update on etcd failed, "+
-			"this will lead to data inconsistency.", t.AppInstanceId,
-			t.TrafficRuleId)
-		return meputil.OperateDataWithEtcdErr, "put traffic rule to etcd failed"
-	}
-
-	appInfo := dataplane.ApplicationInfo{
-		Id:   t.AppInstanceId,
-		Name: appDConfig.AppName,
-	}
-	if trafficInPut.State != trafficRule.State {
-		if trafficInPut.State == "ACTIVE" {
-			err = t.dataPlane.AddTrafficRule(appInfo, t.TrafficRuleId, trafficInPut.FilterType,
-				trafficInPut.Action, trafficInPut.Priority, trafficInPut.TrafficFilter)
-		} else {
-			err = t.dataPlane.DeleteTrafficRule(appInfo, t.TrafficRuleId)
-		}
-	} else if trafficInPut.State == "ACTIVE" {
-		err = t.dataPlane.SetTrafficRule(appInfo, t.TrafficRuleId, trafficInPut.FilterType,
-			trafficInPut.Action, trafficInPut.Priority, trafficInPut.TrafficFilter)
+		log.Errorf(err, "Traffic rule parse failed.")
+		t.SetFirstErrorCode(meputil.ParseInfoErr, "internal error on data parsing")
+		return workspace.TaskFinish
 	}
 
-	if err != nil {
-		log.Errorf(err, "Traffic rule(appId: %s, dnsRuleId: %s) update fail on server: %s.",
-			t.AppInstanceId, t.TrafficRuleId, err.Error())
-		t.SetFirstErrorCode(meputil.RemoteServerErr, "failed to apply configuration on data-plane")
-
-		resultErr := backend.PutRecord(meputil.AppDConfigKeyPath+t.AppInstanceId, appDConfigDB)
-		if resultErr != 0 {
-			log.Errorf(nil, "Traffic rule(appId: %s, ruleId: %s) update on etcd failed, "+
-				"this will lead to data inconsistency.", t.AppInstanceId,
-				t.TrafficRuleId)
-		}
-		return 0, ""
+	taskId, code, msg := stageRuleUpdate(&t.AppDCommon, t.worker, t.AppInstanceId, &appDConfig)
+	if code != 0 {
+		t.SetFirstErrorCode(code, msg)
+		return workspace.TaskFinish
 	}
 
-	t.W.Header().Set("ETag", meputil.GenerateStrongETag(updateJSON))
-	t.HttpRsp = trafficInPut
-	return 0, ""
+	log.Infof("Traffic rule(appId: %s, ruleId: %s) update staged with task %s.", t.AppInstanceId,
+		t.TrafficRuleId, taskId)
+	// The rule is not applied yet, the response refers to the task applying it
+	t.W.Header().Set("ETag", meputil.GenerateStrongETag(stagedRuleBytes))
+	setTaskHeaders(t.W, t.AppInstanceId, taskId)
+	t.HttpStatus = http.StatusAccepted
+	t.HttpRsp = t.GenerateTaskResponse(taskId, t.AppInstanceId, meputil.TaskStateProcessing, "0",
+		"Operation In progress")
+	return workspace.TaskFinish
 }
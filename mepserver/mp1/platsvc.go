@@ -0,0 +1,268 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package mp1 implements rest api route controller
+package mp1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"path"
+	"time"
+
+	"github.com/apache/servicecomb-service-center/pkg/log"
+	"github.com/apache/servicecomb-service-center/pkg/util"
+	"github.com/apache/servicecomb-service-center/server/core"
+	"github.com/apache/servicecomb-service-center/server/core/proto"
+
+	"mepserver/common/extif/backend"
+	"mepserver/common/extif/platsvc"
+	"mepserver/common/metrics"
+	"mepserver/common/models"
+	meputil "mepserver/common/util"
+	"mepserver/mp1/plans"
+	"tracing"
+)
+
+const platformSvcRegisterInterval = 5 * time.Second
+
+// platformSvcNotifier sends the radio network information and location notifications to the subscribers
+type platformSvcNotifier struct {
+	transport http.RoundTripper
+}
+
+func (n *platformSvcNotifier) onCellChange(event platsvc.CellChangeEvent) {
+	log.Infof("User equipment(%s) moved from cell %s to %s.", event.Ue.Address, event.SrcCell.CellId,
+		event.TrgCell.CellId)
+	if n.transport == nil {
+		tlsCfg, err := meputil.TLSConfig(meputil.ApiGwCaCertName, true)
+		if err != nil {
+			log.Error("Failed to create the tls configuration for platform service notification.", nil)
+			return
+		}
+		n.transport = tracing.NewTransport(&http.Transport{TLSClientConfig: tlsCfg})
+	}
+	n.notifyCellChange(event)
+	n.notifyZonalPresence(event)
+}
+
+func (n *platformSvcNotifier) notifyCellChange(event platsvc.CellChangeEvent) {
+	subscriptions, errCode := backend.GetRecordsWithCompleteKeyPath(meputil.RniSubKeyPath)
+	if errCode != 0 {
+		log.Errorf(nil, "Get cell change subscriptions from data-store failed.")
+		return
+	}
+	// the provider only reports the completed handovers
+	notification := models.CellChangeNotification{
+		NotificationType: "CellChangeNotification",
+		TimeStamp:        plans.BuildTimeStamp(event.TimeStamp),
+		HoStatus:         models.HoStatusCompleted,
+		AssociateId:      []models.AssociateId{plans.BuildAssociateId(event.Ue.Address)},
+	}
+	for key, value := range subscriptions {
+		sub := &models.CellChangeSubscription{}
+		if err := json.Unmarshal(value, sub); err != nil {
+			log.Warn("Cell change subscription parse failed, hence ignored.")
+			continue
+		}
+		if !isCellChangeInFilter(sub.FilterCriteriaAssocHo, event) {
+			continue
+		}
+		plmn := platsvc.Plmn{}
+		if len(sub.FilterCriteriaAssocHo.Ecgi) != 0 {
+			plmn = platsvc.Plmn{Mcc: sub.FilterCriteriaAssocHo.Ecgi[0].Plmn.Mcc,
+				Mnc: sub.FilterCriteriaAssocHo.Ecgi[0].Plmn.Mnc}
+		}
+		notification.SrcEcgi = plans.BuildEcgi(plmn, event.SrcCell.CellId)
+		notification.TrgEcgi = []models.Ecgi{plans.BuildEcgi(plmn, event.TrgCell.CellId)}
+		notification.Links.Subscription.Href = meputil.MecRniPath + "/subscriptions/" + path.Base(key)
+		n.send(sub.CallbackReference, notification)
+	}
+}
+
+func isCellChangeInFilter(filter models.FilterCriteriaAssocHo, event platsvc.CellChangeEvent) bool {
+	if len(filter.HoStatus) != 0 && !intInList(models.HoStatusCompleted, filter.HoStatus) {
+		return false
+	}
+	if len(filter.AssociateId) != 0 {
+		found := false
+		for _, associateId := range filter.AssociateId {
+			if associateId.Value == event.Ue.Address {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(filter.Ecgi) != 0 {
+		for _, ecgi := range filter.Ecgi {
+			if ecgi.CellId == event.SrcCell.CellId || ecgi.CellId == event.TrgCell.CellId {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (n *platformSvcNotifier) notifyZonalPresence(event platsvc.CellChangeEvent) {
+	subscriptions, errCode := backend.GetRecordsWithCompleteKeyPath(meputil.LocationSubKeyPath)
+	if errCode != 0 {
+		log.Errorf(nil, "Get user tracking subscriptions from data-store failed.")
+		return
+	}
+	notifications := buildZonalPresenceNotifications(event)
+	for key, value := range subscriptions {
+		sub := &models.UserTrackingSubscriptionBody{}
+		if err := json.Unmarshal(value, sub); err != nil {
+			log.Warn("User tracking subscription parse failed, hence ignored.")
+			continue
+		}
+		tracking := sub.UserTrackingSubscription
+		if tracking.Address != event.Ue.Address {
+			continue
+		}
+		for _, notification := range notifications {
+			if len(tracking.UserEventCriteria) != 0 &&
+				!meputil.StringInList(notification.UserEventType, tracking.UserEventCriteria) {
+				continue
+			}
+			notification.CallbackData = tracking.CallbackReference.CallbackData
+			notification.Link.Href = meputil.MecLocationPath + "/subscriptions/userTracking/" + path.Base(key)
+			n.send(tracking.CallbackReference.NotifyURL,
+				models.ZonalPresenceNotificationBody{ZonalPresenceNotification: notification})
+		}
+	}
+}
+
+// buildZonalPresenceNotifications generates leaving and entering events on zone change, otherwise transferring
+func buildZonalPresenceNotifications(event platsvc.CellChangeEvent) []models.ZonalPresenceNotification {
+	timeStamp := plans.BuildTimeStamp(event.TimeStamp)
+	if event.SrcCell.ZoneId == event.TrgCell.ZoneId {
+		if event.SrcCell.AccessPointId == event.TrgCell.AccessPointId {
+			return nil
+		}
+		return []models.ZonalPresenceNotification{{ZoneId: event.TrgCell.ZoneId, Address: event.Ue.Address,
+			UserEventType: models.UserEventTransferring, CurrentAccessPointId: event.TrgCell.AccessPointId,
+			PreviousAccessPointId: event.SrcCell.AccessPointId, TimeStamp: timeStamp}}
+	}
+	return []models.ZonalPresenceNotification{
+		{ZoneId: event.SrcCell.ZoneId, Address: event.Ue.Address, UserEventType: models.UserEventLeaving,
+			CurrentAccessPointId: event.TrgCell.AccessPointId, PreviousAccessPointId: event.SrcCell.AccessPointId,
+			TimeStamp: timeStamp},
+		{ZoneId: event.TrgCell.ZoneId, Address: event.Ue.Address, UserEventType: models.UserEventEntering,
+			CurrentAccessPointId: event.TrgCell.AccessPointId, PreviousAccessPointId: event.SrcCell.AccessPointId,
+			TimeStamp: timeStamp},
+	}
+}
+
+func (n *platformSvcNotifier) send(callbackURI string, notification interface{}) {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		log.Error("Marshal platform service notification failed.", nil)
+		return
+	}
+	start := time.Now()
+	_, err = meputil.SendPostRequestWithTransport(callbackURI, body, n.transport)
+	metrics.ObserveNotification(metrics.NotificationPlatformService, start, err)
+	if err != nil {
+		log.Error("Failed to send platform service notification.", nil)
+	}
+}
+
+func intInList(value int, list []int) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// registerPlatformServices registers the built-in services on the service registry, retries until succeeded
+func registerPlatformServices() {
+	services := map[string]string{
+		meputil.RniServiceName:      meputil.RootPath + meputil.MecRniPath,
+		meputil.LocationServiceName: meputil.RootPath + meputil.MecLocationPath,
+	}
+	ticker := time.NewTicker(platformSvcRegisterInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		for serName, serPath := range services {
+			if err := registerPlatformService(serName, serPath); err != nil {
+				log.Warnf("Register platform service %s failed, will retry.", serName)
+				continue
+			}
+			delete(services, serName)
+		}
+		if len(services) == 0 {
+			return
+		}
+	}
+}
+
+func registerPlatformService(serName string, serPath string) error {
+	resp, err := meputil.FindInstanceByKey(url.Values{})
+	if err == nil {
+		for _, instance := range resp.Instances {
+			if instance.Properties["serName"] == serName && instance.Properties["appInstanceId"] == "" {
+				log.Infof("Platform service %s is already registered.", serName)
+				return nil
+			}
+		}
+	} else if err.Error() != "null" {
+		return err
+	}
+
+	serviceInfo := &models.ServiceInfo{
+		SerName: serName,
+		SerCategory: models.CategoryRef{Href: models.GatewayURI(serPath), ID: serName, Name: serName,
+			Version: meputil.PlatformSvcVersion},
+		Version:           meputil.PlatformSvcVersion,
+		State:             meputil.ActiveState,
+		Serializer:        "JSON",
+		ScopeOfLocality:   "MEC_HOST",
+		ConsumedLocalOnly: true,
+		IsLocal:           true,
+	}
+	ctx := util.SetDomainProject(context.Background(), "default", "default")
+	serviceReq := &proto.CreateServiceRequest{}
+	serviceInfo.GenerateServiceRequest(serviceReq)
+	serviceResp, err := core.ServiceAPI.Create(ctx, serviceReq)
+	if err != nil || serviceResp.ServiceId == "" {
+		log.Errorf(err, "Create platform service %s failed.", serName)
+		return errors.New("service creation failed")
+	}
+
+	// the mep server is already routed by the api gateway, hence the endpoint is filled without gateway registration
+	instanceReq := &proto.RegisterInstanceRequest{}
+	serviceInfo.GenerateRegisterInstance(instanceReq, false, "")
+	instanceReq.Instance.ServiceId = serviceResp.ServiceId
+	instanceReq.Instance.Endpoints = []string{models.GatewayURI(serPath)}
+	instanceReq.Instance.Properties["endPointType"] = meputil.Uris
+	instanceResp, err := core.InstanceAPI.Register(ctx, instanceReq)
+	if err != nil || instanceResp.InstanceId == "" {
+		log.Errorf(err, "Register platform service instance %s failed.", serName)
+		return errors.New("instance registration failed")
+	}
+	log.Infof("Platform service %s registered(id: %s).", serName, serviceResp.ServiceId+instanceResp.InstanceId)
+	return nil
+}
@@ -0,0 +1,374 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mp1
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/agiledragon/gomonkey"
+	scbackend "github.com/apache/servicecomb-service-center/server/core/backend"
+	"github.com/apache/servicecomb-service-center/server/plugin/pkg/registry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"mepserver/common/config"
+	backendMemory "mepserver/common/extif/backend/memory"
+	"mepserver/common/extif/platsvc"
+	"mepserver/common/models"
+	"mepserver/common/util"
+)
+
+const (
+	platsvcCellId1   = "000a001"
+	platsvcCellId2   = "000a002"
+	platsvcUeAddress = "10.10.10.1"
+	platsvcCallback  = "http://127.0.0.1:9999/notify"
+)
+
+// fakeProvider serves the fixed radio network, err fails all the queries
+type fakeProvider struct {
+	err error
+}
+
+func (f *fakeProvider) InitProvider(*config.MepServerConfig) error {
+	return nil
+}
+
+func (f *fakeProvider) GetPlmn() (platsvc.Plmn, error) {
+	return platsvc.Plmn{Mcc: "460", Mnc: "01"}, f.err
+}
+
+func (f *fakeProvider) GetCells() ([]platsvc.Cell, error) {
+	return []platsvc.Cell{
+		{CellId: platsvcCellId1, ZoneId: "zone01", AccessPointId: "ap01"},
+		{CellId: platsvcCellId2, ZoneId: "zone02", AccessPointId: "ap02"},
+	}, f.err
+}
+
+func (f *fakeProvider) GetUserEquipments() ([]platsvc.UserEquipment, error) {
+	return []platsvc.UserEquipment{
+		{Address: platsvcUeAddress, CellId: platsvcCellId1, Latitude: 12.5, Longitude: 77.5,
+			Erabs: []platsvc.Erab{{ErabId: 1, Qci: 9}}},
+		{Address: "10.10.10.2", CellId: platsvcCellId2},
+	}, f.err
+}
+
+func (f *fakeProvider) SetEventHandler(platsvc.EventHandler) {
+}
+
+// usePlatformSvcStore keeps the subscriptions in memory
+func usePlatformSvcStore() *gomonkey.Patches {
+	store := backendMemory.NewRegistry()
+	return gomonkey.ApplyFunc(scbackend.Registry, func() registry.Registry {
+		return store
+	})
+}
+
+// servePlatformSvc runs the platform service route and returns the response status and body
+func servePlatformSvc(t *testing.T, service *Mp1Service, method string, routePath string, url string,
+	query string, body interface{}) (int, http.Header, []byte) {
+	var reqBody []byte
+	if body != nil {
+		var err error
+		reqBody, err = json.Marshal(body)
+		assert.NoError(t, err)
+	}
+	request := httptest.NewRequest(method, url, bytes.NewReader(reqBody))
+	request.URL.RawQuery = query
+	request.Header.Set(appInstanceIdHeader, defaultAppInstanceId)
+
+	mockWriter := &mockHttpWriterWithoutWrite{}
+	responseHeader := http.Header{}
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write").Return(0, nil)
+	mockWriter.On("WriteHeader", mock.Anything)
+	routeFunc(t, service, method, routePath)(mockWriter, request)
+
+	status, err := strconv.Atoi(responseHeader.Get(responseStatusHeader))
+	assert.NoError(t, err)
+	return status, responseHeader, mockWriter.response
+}
+
+func TestGetRabInfo(t *testing.T) {
+	service := &Mp1Service{platformSvc: &fakeProvider{}}
+	status, _, body := servePlatformSvc(t, service, http.MethodGet, util.RabInfoPath, util.RabInfoPath,
+		"cell_id="+platsvcCellId1+"&request_id=req01", nil)
+	assert.Equal(t, http.StatusOK, status, responseCheckFor200)
+
+	rabInfo := models.RabInfo{}
+	assert.NoError(t, json.Unmarshal(body, &rabInfo))
+	assert.Equal(t, defaultAppInstanceId, rabInfo.AppInstanceId)
+	assert.Equal(t, "req01", rabInfo.RequestId)
+	assert.Len(t, rabInfo.CellUserInfo, 1)
+	assert.Equal(t, models.Ecgi{CellId: platsvcCellId1, Plmn: models.Plmn{Mcc: "460", Mnc: "01"}},
+		rabInfo.CellUserInfo[0].Ecgi)
+	assert.Equal(t, []models.UeInfo{{
+		AssociateId: []models.AssociateId{{Type: models.AssociateIdUeIpv4, Value: platsvcUeAddress}},
+		ErabInfo:    []models.ErabInfo{{ErabId: 1, ErabQosParameters: models.ErabQosParameters{Qci: 9}}},
+	}}, rabInfo.CellUserInfo[0].UeInfo)
+}
+
+func TestGetRabInfoProviderFailed(t *testing.T) {
+	service := &Mp1Service{platformSvc: &fakeProvider{err: errors.New("provider not reachable")}}
+	status, _, _ := servePlatformSvc(t, service, http.MethodGet, util.RabInfoPath, util.RabInfoPath, "", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+func TestGetRabInfoInvalidAppInstanceId(t *testing.T) {
+	service := &Mp1Service{platformSvc: &fakeProvider{}}
+	request := httptest.NewRequest(http.MethodGet, util.RabInfoPath, nil)
+	mockWriter := &mockHttpWriterWithoutWrite{}
+	responseHeader := http.Header{}
+	mockWriter.On("Header").Return(responseHeader)
+	mockWriter.On("Write").Return(0, nil)
+	mockWriter.On("WriteHeader", http.StatusUnauthorized)
+	routeFunc(t, service, http.MethodGet, util.RabInfoPath)(mockWriter, request)
+	assert.Equal(t, "401", responseHeader.Get(responseStatusHeader))
+}
+
+func TestGetPlmnInfo(t *testing.T) {
+	service := &Mp1Service{platformSvc: &fakeProvider{}}
+	status, _, body := servePlatformSvc(t, service, http.MethodGet, util.PlmnInfoPath, util.PlmnInfoPath, "", nil)
+	assert.Equal(t, http.StatusOK, status, responseCheckFor200)
+
+	plmnInfo := models.PlmnInfo{}
+	assert.NoError(t, json.Unmarshal(body, &plmnInfo))
+	assert.Equal(t, []models.Plmn{{Mcc: "460", Mnc: "01"}}, plmnInfo.Plmn)
+}
+
+func TestGetLocationUsers(t *testing.T) {
+	service := &Mp1Service{platformSvc: &fakeProvider{}}
+	status, _, body := servePlatformSvc(t, service, http.MethodGet, util.LocationUsersPath, util.LocationUsersPath,
+		"zoneId=zone01", nil)
+	assert.Equal(t, http.StatusOK, status, responseCheckFor200)
+
+	users := models.UserListResponse{}
+	assert.NoError(t, json.Unmarshal(body, &users))
+	assert.Len(t, users.UserList.User, 1)
+	user := users.UserList.User[0]
+	assert.Equal(t, platsvcUeAddress, user.Address)
+	assert.Equal(t, "ap01", user.AccessPointId)
+	assert.Equal(t, models.LocationInfo{Latitude: []float32{12.5}, Longitude: []float32{77.5}, Shape: 2},
+		user.LocationInfo)
+	assert.Equal(t, util.MecLocationPath+"/queries/users?address="+platsvcUeAddress, user.ResourceURL)
+}
+
+func TestGetLocationZones(t *testing.T) {
+	service := &Mp1Service{platformSvc: &fakeProvider{}}
+	status, _, body := servePlatformSvc(t, service, http.MethodGet, util.LocationZonesPath, util.LocationZonesPath,
+		"", nil)
+	assert.Equal(t, http.StatusOK, status, responseCheckFor200)
+
+	zones := models.ZoneListResponse{}
+	assert.NoError(t, json.Unmarshal(body, &zones))
+	assert.Equal(t, []models.ZoneInfo{
+		{ZoneId: "zone01", NumberOfAccessPoints: 1, NumberOfUsers: 1,
+			ResourceURL: util.MecLocationPath + "/queries/zones/zone01"},
+		{ZoneId: "zone02", NumberOfAccessPoints: 1, NumberOfUsers: 1,
+			ResourceURL: util.MecLocationPath + "/queries/zones/zone02"},
+	}, zones.ZoneList.Zone)
+}
+
+func TestGetLocationZonesProviderFailed(t *testing.T) {
+	service := &Mp1Service{platformSvc: &fakeProvider{err: errors.New("provider not reachable")}}
+	status, _, _ := servePlatformSvc(t, service, http.MethodGet, util.LocationZonesPath, util.LocationZonesPath,
+		"", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+}
+
+func TestRniSubscribeAndUnsubscribe(t *testing.T) {
+	defer usePlatformSvcStore().Reset()
+	service := &Mp1Service{platformSvc: &fakeProvider{}}
+	subscription := models.CellChangeSubscription{
+		SubscriptionType:      util.CellChangeSubscription,
+		CallbackReference:     platsvcCallback,
+		FilterCriteriaAssocHo: models.FilterCriteriaAssocHo{HoStatus: []int{models.HoStatusCompleted}},
+	}
+	status, header, body := servePlatformSvc(t, service, http.MethodPost, util.RniSubscribePath,
+		util.RniSubscribePath, "", subscription)
+	assert.Equal(t, http.StatusCreated, status)
+	created := models.CellChangeSubscription{}
+	assert.NoError(t, json.Unmarshal(body, &created))
+	assert.Equal(t, defaultAppInstanceId, created.FilterCriteriaAssocHo.AppInstanceId)
+	location := header.Get("Location")
+	assert.Equal(t, created.Links.Self.Href, location)
+	subscriptionId := location[len(util.MecRniPath+"/subscriptions/"):]
+
+	subscriptionPath := util.RniSubscribePath + util.SubscriptionIdPath
+	subscriptionUrl := util.RniSubscribePath + "/" + subscriptionId
+	subscriptionQuery := ":subscriptionId=" + subscriptionId
+	status, _, body = servePlatformSvc(t, service, http.MethodGet, subscriptionPath, subscriptionUrl,
+		subscriptionQuery, nil)
+	assert.Equal(t, http.StatusOK, status, responseCheckFor200)
+	found := models.CellChangeSubscription{}
+	assert.NoError(t, json.Unmarshal(body, &found))
+	assert.Equal(t, platsvcCallback, found.CallbackReference)
+
+	status, _, _ = servePlatformSvc(t, service, http.MethodDelete, subscriptionPath, subscriptionUrl,
+		subscriptionQuery, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+	status, _, _ = servePlatformSvc(t, service, http.MethodGet, subscriptionPath, subscriptionUrl,
+		subscriptionQuery, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+	status, _, _ = servePlatformSvc(t, service, http.MethodDelete, subscriptionPath, subscriptionUrl,
+		subscriptionQuery, nil)
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestRniSubscribeInvalidBody(t *testing.T) {
+	defer usePlatformSvcStore().Reset()
+	service := &Mp1Service{platformSvc: &fakeProvider{}}
+	subscription := models.CellChangeSubscription{SubscriptionType: "AppTerminationNotificationSubscription",
+		CallbackReference: platsvcCallback}
+	status, _, _ := servePlatformSvc(t, service, http.MethodPost, util.RniSubscribePath, util.RniSubscribePath,
+		"", subscription)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestUserTrackingSubscribeAndUnsubscribe(t *testing.T) {
+	defer usePlatformSvcStore().Reset()
+	service := &Mp1Service{platformSvc: &fakeProvider{}}
+	subscription := models.UserTrackingSubscriptionBody{UserTrackingSubscription: models.UserTrackingSubscription{
+		CallbackReference: models.CallbackReference{NotifyURL: platsvcCallback, CallbackData: "data01"},
+		Address:           platsvcUeAddress,
+	}}
+	status, header, _ := servePlatformSvc(t, service, http.MethodPost, util.LocationUserTrackingPath,
+		util.LocationUserTrackingPath, "", subscription)
+	assert.Equal(t, http.StatusCreated, status)
+	location := header.Get("Location")
+	subscriptionId := location[len(util.MecLocationPath+"/subscriptions/userTracking/"):]
+
+	status, _, body := servePlatformSvc(t, service, http.MethodGet, util.LocationUserTrackingPath,
+		util.LocationUserTrackingPath, "", nil)
+	assert.Equal(t, http.StatusOK, status, responseCheckFor200)
+	assert.Contains(t, string(body), subscriptionId)
+
+	status, _, _ = servePlatformSvc(t, service, http.MethodDelete,
+		util.LocationUserTrackingPath+util.SubscriptionIdPath, util.LocationUserTrackingPath+"/"+subscriptionId,
+		":subscriptionId="+subscriptionId, nil)
+	assert.Equal(t, http.StatusNoContent, status)
+}
+
+func TestUserTrackingSubscribeInvalidAddress(t *testing.T) {
+	defer usePlatformSvcStore().Reset()
+	service := &Mp1Service{platformSvc: &fakeProvider{}}
+	subscription := models.UserTrackingSubscriptionBody{UserTrackingSubscription: models.UserTrackingSubscription{
+		CallbackReference: models.CallbackReference{NotifyURL: platsvcCallback},
+		Address:           "10.10.10",
+	}}
+	status, _, _ := servePlatformSvc(t, service, http.MethodPost, util.LocationUserTrackingPath,
+		util.LocationUserTrackingPath, "", subscription)
+	assert.Equal(t, http.StatusBadRequest, status)
+}
+
+// notificationReceiver keeps the notifications sent to the subscriber
+type notificationReceiver struct {
+	mutex  sync.Mutex
+	bodies [][]byte
+}
+
+func (n *notificationReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	n.mutex.Lock()
+	n.bodies = append(n.bodies, body)
+	n.mutex.Unlock()
+}
+
+func (n *notificationReceiver) received() [][]byte {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.bodies
+}
+
+func TestPlatformSvcNotification(t *testing.T) {
+	defer usePlatformSvcStore().Reset()
+	service := &Mp1Service{platformSvc: &fakeProvider{}}
+	rniReceiver := &notificationReceiver{}
+	rniSubscriber := httptest.NewServer(rniReceiver)
+	defer rniSubscriber.Close()
+	locationReceiver := &notificationReceiver{}
+	locationSubscriber := httptest.NewServer(locationReceiver)
+	defer locationSubscriber.Close()
+	// the closed subscriber fails the delivery, the other subscribers are still notified
+	closedSubscriber := httptest.NewServer(http.NotFoundHandler())
+	closedSubscriber.Close()
+
+	for _, callback := range []string{closedSubscriber.URL, rniSubscriber.URL} {
+		status, _, _ := servePlatformSvc(t, service, http.MethodPost, util.RniSubscribePath, util.RniSubscribePath, "",
+			models.CellChangeSubscription{SubscriptionType: util.CellChangeSubscription, CallbackReference: callback})
+		assert.Equal(t, http.StatusCreated, status)
+	}
+	// the subscription of the other cells is not notified
+	status, _, _ := servePlatformSvc(t, service, http.MethodPost, util.RniSubscribePath, util.RniSubscribePath, "",
+		models.CellChangeSubscription{SubscriptionType: util.CellChangeSubscription,
+			CallbackReference:     rniSubscriber.URL,
+			FilterCriteriaAssocHo: models.FilterCriteriaAssocHo{Ecgi: []models.Ecgi{{CellId: "000b001"}}}})
+	assert.Equal(t, http.StatusCreated, status)
+	status, _, _ = servePlatformSvc(t, service, http.MethodPost, util.LocationUserTrackingPath,
+		util.LocationUserTrackingPath, "", models.UserTrackingSubscriptionBody{
+			UserTrackingSubscription: models.UserTrackingSubscription{
+				CallbackReference: models.CallbackReference{NotifyURL: locationSubscriber.URL, CallbackData: "data01"},
+				Address:           platsvcUeAddress,
+				UserEventCriteria: []string{models.UserEventEntering},
+			}})
+	assert.Equal(t, http.StatusCreated, status)
+
+	notifier := &platformSvcNotifier{transport: http.DefaultTransport}
+	notifier.onCellChange(platsvc.CellChangeEvent{
+		Ue:        platsvc.UserEquipment{Address: platsvcUeAddress, CellId: platsvcCellId2},
+		SrcCell:   platsvc.Cell{CellId: platsvcCellId1, ZoneId: "zone01", AccessPointId: "ap01"},
+		TrgCell:   platsvc.Cell{CellId: platsvcCellId2, ZoneId: "zone02", AccessPointId: "ap02"},
+		TimeStamp: time.Unix(1600000000, 0),
+	})
+
+	assert.Len(t, rniReceiver.received(), 1)
+	cellChange := models.CellChangeNotification{}
+	assert.NoError(t, json.Unmarshal(rniReceiver.received()[0], &cellChange))
+	assert.Equal(t, "CellChangeNotification", cellChange.NotificationType)
+	assert.Equal(t, models.HoStatusCompleted, cellChange.HoStatus)
+	assert.Equal(t, platsvcCellId1, cellChange.SrcEcgi.CellId)
+	assert.Equal(t, platsvcCellId2, cellChange.TrgEcgi[0].CellId)
+	assert.Equal(t, []models.AssociateId{{Type: models.AssociateIdUeIpv4, Value: platsvcUeAddress}},
+		cellChange.AssociateId)
+	assert.Equal(t, 1600000000, cellChange.TimeStamp.Seconds)
+
+	// only the entering event is subscribed
+	assert.Len(t, locationReceiver.received(), 1)
+	zonalPresence := models.ZonalPresenceNotificationBody{}
+	assert.NoError(t, json.Unmarshal(locationReceiver.received()[0], &zonalPresence))
+	assert.Equal(t, models.ZonalPresenceNotification{
+		CallbackData:          "data01",
+		ZoneId:                "zone02",
+		Address:               platsvcUeAddress,
+		UserEventType:         models.UserEventEntering,
+		CurrentAccessPointId:  "ap02",
+		PreviousAccessPointId: "ap01",
+		TimeStamp:             models.NtpTimeStamp{Seconds: 1600000000},
+		Link: models.SerLinkType{Href: fmt.Sprintf("%s/subscriptions/userTracking/%s", util.MecLocationPath,
+			zonalPresence.ZonalPresenceNotification.Link.Href[len(util.MecLocationPath+"/subscriptions/userTracking/"):])},
+	}, zonalPresence.ZonalPresenceNotification)
+}
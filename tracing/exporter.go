@@ -0,0 +1,269 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	exportQueueSize     = 4096
+	exportBatchSize     = 256
+	exportInterval      = 5 * time.Second
+	exportTimeout       = 10 * time.Second
+	instrumentationName = "tracing"
+)
+
+// exporter sends the ended spans in batches to the otlp/http collector in the json encoding
+type exporter struct {
+	endpoint    string
+	serviceName string
+	client      *http.Client
+	queue       chan *Span
+	done        chan struct{}
+	stopped     chan struct{}
+	stopOnce    sync.Once
+}
+
+func newExporter(endpoint string, serviceName string) *exporter {
+	e := &exporter{
+		endpoint:    endpoint,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: exportTimeout},
+		queue:       make(chan *Span, exportQueueSize),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// enqueue queues the span for export, the span is dropped if the queue is full so the requests are never blocked
+// by a slow collector
+func (e *exporter) enqueue(span *Span) {
+	select {
+	case e.queue <- span:
+	default:
+		warnf("Tracing export queue is full, span dropped.")
+	}
+}
+
+func (e *exporter) run() {
+	defer close(e.stopped)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	batch := make([]*Span, 0, exportBatchSize)
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) < exportBatchSize {
+				continue
+			}
+		case <-ticker.C:
+			if len(batch) == 0 {
+				continue
+			}
+		case <-e.done:
+			e.flush(batch)
+			return
+		}
+		e.export(batch)
+		batch = make([]*Span, 0, exportBatchSize)
+	}
+}
+
+// flush exports the batch and the spans left in the queue
+func (e *exporter) flush(batch []*Span) {
+	for {
+		select {
+		case span := <-e.queue:
+			batch = append(batch, span)
+			if len(batch) < exportBatchSize {
+				continue
+			}
+		default:
+			if len(batch) != 0 {
+				e.export(batch)
+			}
+			return
+		}
+		e.export(batch)
+		batch = make([]*Span, 0, exportBatchSize)
+	}
+}
+
+// shutdown stops the export after the queued spans are exported, the error of the context is returned if they are
+// not exported in time
+func (e *exporter) shutdown(ctx context.Context) error {
+	e.stopOnce.Do(func() {
+		close(e.done)
+	})
+	select {
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *exporter) export(batch []*Span) {
+	if err := e.send(batch); err != nil {
+		warnf("Tracing export of %d spans failed(%s).", len(batch), err.Error())
+	}
+}
+
+func (e *exporter) send(spans []*Span) error {
+	body, err := json.Marshal(e.encode(spans))
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = ioutil.ReadAll(resp.Body)
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// otlp json request structures of the trace export service
+type otlpExportRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceId           string         `json:"traceId"`
+	SpanId            string         `json:"spanId"`
+	TraceState        string         `json:"traceState,omitempty"`
+	ParentSpanId      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              SpanKind       `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+func (e *exporter) encode(spans []*Span) *otlpExportRequest {
+	otlpSpans := make([]otlpSpan, 0, len(spans))
+	for _, span := range spans {
+		otlpSpans = append(otlpSpans, encodeSpan(span))
+	}
+	return &otlpExportRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource: otlpResource{Attributes: []otlpKeyValue{{Key: "service.name", Value: encodeValue(e.serviceName)}}},
+		ScopeSpans: []otlpScopeSpans{{
+			Scope: otlpScope{Name: instrumentationName},
+			Spans: otlpSpans,
+		}},
+	}}}
+}
+
+func encodeSpan(span *Span) otlpSpan {
+	span.mutex.Lock()
+	defer span.mutex.Unlock()
+	otlp := otlpSpan{
+		TraceId:           hex.EncodeToString(span.spanCtx.TraceId[:]),
+		SpanId:            hex.EncodeToString(span.spanCtx.SpanId[:]),
+		TraceState:        span.spanCtx.TraceState,
+		Name:              span.name,
+		Kind:              span.kind,
+		StartTimeUnixNano: strconv.FormatInt(span.start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.end.UnixNano(), 10),
+		Status:            otlpStatus{Code: span.statusCode, Message: span.statusMsg},
+	}
+	if span.parentSpanId != [8]byte{} {
+		otlp.ParentSpanId = hex.EncodeToString(span.parentSpanId[:])
+	}
+	keys := make([]string, 0, len(span.attributes))
+	for key := range span.attributes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		otlp.Attributes = append(otlp.Attributes, otlpKeyValue{Key: key, Value: encodeValue(span.attributes[key])})
+	}
+	return otlp
+}
+
+func encodeValue(value interface{}) otlpValue {
+	switch v := value.(type) {
+	case string:
+		return otlpValue{StringValue: &v}
+	case bool:
+		return otlpValue{BoolValue: &v}
+	case int:
+		intValue := strconv.Itoa(v)
+		return otlpValue{IntValue: &intValue}
+	case int64:
+		intValue := strconv.FormatInt(v, 10)
+		return otlpValue{IntValue: &intValue}
+	case float64:
+		return otlpValue{DoubleValue: &v}
+	default:
+		stringValue := fmt.Sprint(v)
+		return otlpValue{StringValue: &stringValue}
+	}
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+type serverSpanKey struct{}
+
+// Extract reads the w3c trace context of the remote caller from the headers into the context
+func Extract(ctx context.Context, header http.Header) context.Context {
+	spanCtx, err := ParseTraceParent(header.Get(TraceParentHeader))
+	if err != nil {
+		return ctx
+	}
+	spanCtx.TraceState = header.Get(TraceStateHeader)
+	return ContextWithRemoteParent(ctx, spanCtx)
+}
+
+// Inject writes the w3c trace context of the current span in the context to the headers
+func Inject(ctx context.Context, header http.Header) {
+	spanCtx, ok := SpanContextFromContext(ctx)
+	if !ok {
+		return
+	}
+	header.Set(TraceParentHeader, spanCtx.TraceParent())
+	if len(spanCtx.TraceState) != 0 {
+		header.Set(TraceStateHeader, spanCtx.TraceState)
+	}
+}
+
+// Begin starts the server span of the incoming request as the child of the caller span in the traceparent header,
+// the span is ended by Finish on the returned request
+func Begin(r *http.Request) *http.Request {
+	if r == nil || !Enabled() {
+		return r
+	}
+	ctx, span := StartSpan(Extract(r.Context(), r.Header), r.Method+" "+r.URL.Path, SpanKindServer)
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.target", r.URL.Path)
+	span.SetAttribute("net.peer.ip", r.RemoteAddr)
+	return r.WithContext(context.WithValue(ctx, serverSpanKey{}, span))
+}
+
+// Finish ends the server span of the request started by Begin with the response status code
+func Finish(r *http.Request, statusCode int) {
+	if r == nil {
+		return
+	}
+	span, ok := r.Context().Value(serverSpanKey{}).(*Span)
+	if !ok {
+		return
+	}
+	span.SetAttribute("http.status_code", statusCode)
+	if statusCode >= http.StatusInternalServerError {
+		span.SetError(http.StatusText(statusCode))
+	}
+	span.End(nil)
+}
+
+// transport traces the outbound requests in client spans and propagates the trace context to the server
+type transport struct {
+	base http.RoundTripper
+}
+
+// NewTransport wraps the round tripper to trace the outbound requests, the client span is the child of the span in
+// the request context
+func NewTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base}
+}
+
+// RoundTrip sends the request in a client span
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, span := StartSpan(req.Context(), req.Method+" "+req.URL.Host, SpanKindClient)
+	if span == nil {
+		return t.base.RoundTrip(req)
+	}
+	// the request must not be modified by the round tripper, the headers are set on a copy
+	outReq := req.Clone(ctx)
+	Inject(ctx, outReq.Header)
+	span.SetAttribute("http.method", req.Method)
+	span.SetAttribute("http.url", req.URL.Scheme+"://"+req.URL.Host+req.URL.Path)
+	resp, err := t.base.RoundTrip(outReq)
+	if err != nil {
+		span.End(err)
+		return resp, err
+	}
+	span.SetAttribute("http.status_code", resp.StatusCode)
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetError("status " + strconv.Itoa(resp.StatusCode))
+	}
+	span.End(nil)
+	return resp, nil
+}
+
+// statusRecorder records the response status code of the traced request
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+// WriteHeader records and writes the status code
+func (r *statusRecorder) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+	r.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Middleware traces the incoming requests of the handler in the server spans
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+		r = Begin(r)
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		Finish(r, recorder.statusCode)
+	})
+}
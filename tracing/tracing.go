@@ -0,0 +1,315 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package tracing implements the w3c trace context propagation and the span export to an otlp collector, it is
+// shared by the mep server, mepauth and the dns server
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	stdlog "log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+
+	DefaultServiceName = "unknown_service"
+	DefaultSampleRatio = 1.0
+
+	traceParentVersion = "00"
+	flagSampled        = 0x01
+)
+
+// SpanKind kind of the span, values are the same as the otlp span kinds
+type SpanKind int
+
+const (
+	SpanKindInternal SpanKind = 1
+	SpanKindServer   SpanKind = 2
+	SpanKindClient   SpanKind = 3
+)
+
+// statusError failed span status, value is the same as the otlp status code
+const statusError = 2
+
+type spanContextKey struct{}
+
+// SpanContext identifies the span in the trace, it is propagated in the traceparent header
+type SpanContext struct {
+	TraceId    [16]byte
+	SpanId     [8]byte
+	Sampled    bool
+	TraceState string
+}
+
+// IsValid checks both the trace id and the span id are set
+func (c SpanContext) IsValid() bool {
+	return c.TraceId != [16]byte{} && c.SpanId != [8]byte{}
+}
+
+// TraceParent formats the span context as the traceparent header value
+func (c SpanContext) TraceParent() string {
+	flags := 0
+	if c.Sampled {
+		flags = flagSampled
+	}
+	return fmt.Sprintf("%s-%s-%s-%02x", traceParentVersion, hex.EncodeToString(c.TraceId[:]),
+		hex.EncodeToString(c.SpanId[:]), flags)
+}
+
+// ParseTraceParent parses the traceparent header value, the future versions are read as the version 00
+func ParseTraceParent(value string) (SpanContext, error) {
+	spanCtx := SpanContext{}
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == traceParentVersion && len(parts) != 4) {
+		return spanCtx, fmt.Errorf("invalid traceparent format")
+	}
+	version, err := hex.DecodeString(parts[0])
+	if err != nil || len(version) != 1 {
+		return spanCtx, fmt.Errorf("invalid traceparent version")
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return spanCtx, fmt.Errorf("invalid traceparent field length")
+	}
+	if _, err = hex.Decode(spanCtx.TraceId[:], []byte(parts[1])); err != nil {
+		return spanCtx, fmt.Errorf("invalid trace id")
+	}
+	if _, err = hex.Decode(spanCtx.SpanId[:], []byte(parts[2])); err != nil {
+		return spanCtx, fmt.Errorf("invalid parent id")
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return spanCtx, fmt.Errorf("invalid trace flags")
+	}
+	if !spanCtx.IsValid() {
+		return spanCtx, fmt.Errorf("all zero trace id or parent id")
+	}
+	spanCtx.Sampled = flags[0]&flagSampled != 0
+	return spanCtx, nil
+}
+
+// Span holds a timed operation of the trace, a nil span is valid and records nothing
+type Span struct {
+	mutex        sync.Mutex
+	spanCtx      SpanContext
+	parentSpanId [8]byte
+	name         string
+	kind         SpanKind
+	start        time.Time
+	end          time.Time
+	attributes   map[string]interface{}
+	statusCode   int
+	statusMsg    string
+	ended        bool
+}
+
+// SpanContext returns the identifiers of the span
+func (s *Span) SpanContext() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.spanCtx
+}
+
+// SetAttribute adds an attribute to the span, string, bool, int and float values are supported
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.attributes == nil {
+		s.attributes = make(map[string]interface{})
+	}
+	s.attributes[key] = value
+}
+
+// SetError marks the span as failed with the message
+func (s *Span) SetError(msg string) {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.statusCode = statusError
+	s.statusMsg = msg
+	s.mutex.Unlock()
+}
+
+// End finishes the span and queues the sampled span for export, error marks the span as failed. Only the first
+// call takes effect
+func (s *Span) End(err error) {
+	if s == nil {
+		return
+	}
+	if err != nil {
+		s.SetError(err.Error())
+	}
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mutex.Unlock()
+	if s.spanCtx.Sampled {
+		getTracer().export(s)
+	}
+}
+
+// Options tracing options of the service, zero sample ratio samples all the traces
+type Options struct {
+	ServiceName string
+	Endpoint    string
+	SampleRatio float64
+}
+
+// tracer holds the tracing state selected by the options
+type tracer struct {
+	enabled     bool
+	sampleRatio float64
+	exporter    *exporter
+}
+
+var (
+	tracerMutex   sync.RWMutex
+	currentTracer = &tracer{}
+	warnf         = stdlog.Printf
+)
+
+// SetLogger sets the logger of the export failures, the standard logger is used by default
+func SetLogger(logger func(format string, args ...interface{})) {
+	if logger != nil {
+		warnf = logger
+	}
+}
+
+// Start enables the tracing, the spans are exported to the otlp/http traces endpoint. The calls while the tracing is
+// enabled take no effect
+func Start(options Options) {
+	serviceName := options.ServiceName
+	if len(serviceName) == 0 {
+		serviceName = DefaultServiceName
+	}
+	sampleRatio := options.SampleRatio
+	if sampleRatio == 0 {
+		sampleRatio = DefaultSampleRatio
+	}
+	tracerMutex.Lock()
+	defer tracerMutex.Unlock()
+	if currentTracer.enabled {
+		return
+	}
+	currentTracer = &tracer{
+		enabled:     true,
+		sampleRatio: sampleRatio,
+		exporter:    newExporter(options.Endpoint, serviceName),
+	}
+}
+
+// Shutdown disables the tracing and exports the queued spans, the spans ended later are dropped. The error of the
+// context is returned if the export does not complete in time
+func Shutdown(ctx context.Context) error {
+	tracerMutex.Lock()
+	t := currentTracer
+	currentTracer = &tracer{}
+	tracerMutex.Unlock()
+	if t.exporter == nil {
+		return nil
+	}
+	return t.exporter.shutdown(ctx)
+}
+
+// Enabled checks the tracing is enabled
+func Enabled() bool {
+	return getTracer().enabled
+}
+
+func getTracer() *tracer {
+	tracerMutex.RLock()
+	defer tracerMutex.RUnlock()
+	return currentTracer
+}
+
+func setTracer(t *tracer) {
+	tracerMutex.Lock()
+	currentTracer = t
+	tracerMutex.Unlock()
+}
+
+func (t *tracer) export(span *Span) {
+	if t.exporter != nil {
+		t.exporter.enqueue(span)
+	}
+}
+
+// sample decides whether the new trace is recorded, the lower bytes of the random trace id are used as the
+// sampling value as the w3c trace context recommends
+func (t *tracer) sample(traceId [16]byte) bool {
+	if t.sampleRatio >= 1 {
+		return true
+	}
+	var value uint64
+	for _, b := range traceId[8:] {
+		value = value<<8 | uint64(b)
+	}
+	return float64(value>>11)/float64(uint64(1)<<53) < t.sampleRatio
+}
+
+// StartSpan starts a new span as the child of the span in the context, a new trace is started if the context has
+// no span. The returned context carries the new span, the span is nil when the tracing is disabled
+func StartSpan(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	t := getTracer()
+	if !t.enabled {
+		return ctx, nil
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	span := &Span{name: name, kind: kind, start: time.Now()}
+	if parent, ok := SpanContextFromContext(ctx); ok {
+		span.spanCtx.TraceId = parent.TraceId
+		span.spanCtx.Sampled = parent.Sampled
+		span.spanCtx.TraceState = parent.TraceState
+		span.parentSpanId = parent.SpanId
+	} else {
+		_, _ = rand.Read(span.spanCtx.TraceId[:])
+		span.spanCtx.Sampled = t.sample(span.spanCtx.TraceId)
+	}
+	_, _ = rand.Read(span.spanCtx.SpanId[:])
+	return context.WithValue(ctx, spanContextKey{}, span.spanCtx), span
+}
+
+// ContextWithRemoteParent returns the context carrying the span context received from the remote caller
+func ContextWithRemoteParent(ctx context.Context, spanCtx SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, spanCtx)
+}
+
+// SpanContextFromContext reads the span context of the current span from the context
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	spanCtx, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return spanCtx, ok && spanCtx.IsValid()
+}
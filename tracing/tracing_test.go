@@ -0,0 +1,267 @@
+/*
+ * Copyright 2021 Huawei Technologies Co., Ltd.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func enableTestTracer() func() {
+	previous := getTracer()
+	setTracer(&tracer{enabled: true, sampleRatio: 1})
+	return func() {
+		setTracer(previous)
+	}
+}
+
+func TestParseTraceParent(t *testing.T) {
+	spanCtx, err := ParseTraceParent(traceParent)
+	if err != nil || !spanCtx.Sampled || spanCtx.TraceParent() != traceParent {
+		t.Fatalf("traceparent %s parsed to %+v, %v", traceParent, spanCtx, err)
+	}
+
+	for _, value := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	} {
+		if _, err = ParseTraceParent(value); err == nil {
+			t.Errorf("invalid traceparent %q accepted", value)
+		}
+	}
+	// future versions may carry more fields
+	if _, err = ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("future version rejected: %v", err)
+	}
+}
+
+func TestStartSpanDisabled(t *testing.T) {
+	ctx, span := StartSpan(context.Background(), "disabled", SpanKindInternal)
+	if span != nil {
+		t.Fatal("span started with the tracing disabled")
+	}
+	if _, ok := SpanContextFromContext(ctx); ok {
+		t.Fatal("span context set with the tracing disabled")
+	}
+	// nil span is a no-op
+	span.SetAttribute("key", "value")
+	span.End(errors.New("failure"))
+}
+
+func TestStartSpanChildOfRemoteParent(t *testing.T) {
+	defer enableTestTracer()()
+
+	header := http.Header{}
+	header.Set(TraceParentHeader, traceParent)
+	header.Set(TraceStateHeader, "vendor=value")
+	ctx, span := StartSpan(Extract(context.Background(), header), "child", SpanKindServer)
+	parent, _ := ParseTraceParent(traceParent)
+	if span.SpanContext().TraceId != parent.TraceId || span.parentSpanId != parent.SpanId ||
+		span.SpanContext().SpanId == parent.SpanId {
+		t.Fatalf("span %+v is not the child of %+v", span.SpanContext(), parent)
+	}
+
+	outHeader := http.Header{}
+	Inject(ctx, outHeader)
+	if outHeader.Get(TraceParentHeader) != span.SpanContext().TraceParent() ||
+		outHeader.Get(TraceStateHeader) != "vendor=value" {
+		t.Fatalf("trace context injected as %v", outHeader)
+	}
+}
+
+func TestSampleRatio(t *testing.T) {
+	never := &tracer{enabled: true, sampleRatio: 0.000001}
+	always := &tracer{enabled: true, sampleRatio: 1}
+	traceId := [16]byte{}
+	for i := range traceId {
+		traceId[i] = 0xff
+	}
+	if never.sample(traceId) || !always.sample(traceId) {
+		t.Fatal("sampling does not follow the ratio")
+	}
+}
+
+func TestTransportPropagatesTraceContext(t *testing.T) {
+	defer enableTestTracer()()
+
+	received := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get(TraceParentHeader)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	ctx, parent := StartSpan(context.Background(), "parent", SpanKindInternal)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	client := &http.Client{Transport: NewTransport(nil)}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	clientSpanCtx, err := ParseTraceParent(received)
+	if err != nil || clientSpanCtx.TraceId != parent.SpanContext().TraceId ||
+		clientSpanCtx.SpanId == parent.SpanContext().SpanId {
+		t.Fatalf("traceparent %q received for the parent %+v", received, parent.SpanContext())
+	}
+	if len(req.Header.Get(TraceParentHeader)) != 0 {
+		t.Fatal("request of the caller modified")
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	defer enableTestTracer()()
+
+	var spanCtx SpanContext
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		spanCtx, _ = SpanContextFromContext(r.Context())
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/mep/token", nil)
+	req.Header.Set(TraceParentHeader, traceParent)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	parent, _ := ParseTraceParent(traceParent)
+	if spanCtx.TraceId != parent.TraceId || spanCtx.SpanId == parent.SpanId {
+		t.Fatalf("server span %+v is not the child of %+v", spanCtx, parent)
+	}
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d written", recorder.Code)
+	}
+}
+
+// testCollector records the otlp export requests
+type testCollector struct {
+	mutex    sync.Mutex
+	requests []otlpExportRequest
+}
+
+func (c *testCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	request := otlpExportRequest{}
+	_ = json.Unmarshal(body, &request)
+	c.mutex.Lock()
+	c.requests = append(c.requests, request)
+	c.mutex.Unlock()
+}
+
+func (c *testCollector) spans() []otlpSpan {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	spans := make([]otlpSpan, 0)
+	for _, request := range c.requests {
+		for _, resourceSpans := range request.ResourceSpans {
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				spans = append(spans, scopeSpans.Spans...)
+			}
+		}
+	}
+	return spans
+}
+
+func TestExporterSend(t *testing.T) {
+	defer enableTestTracer()()
+
+	collector := &testCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	_, span := StartSpan(context.Background(), "operation", SpanKindClient)
+	span.SetAttribute("http.status_code", 500)
+	span.End(errors.New("failure"))
+
+	e := &exporter{endpoint: server.URL, serviceName: "mepserver", client: server.Client()}
+	if err := e.send([]*Span{span}); err != nil {
+		t.Fatal(err)
+	}
+	if len(collector.requests) != 1 ||
+		*collector.requests[0].ResourceSpans[0].Resource.Attributes[0].Value.StringValue != "mepserver" {
+		t.Fatalf("export requests %+v", collector.requests)
+	}
+	otlp := collector.spans()[0]
+	if otlp.Name != "operation" || otlp.Kind != SpanKindClient || otlp.ParentSpanId != "" ||
+		otlp.Status.Code != statusError || otlp.Status.Message != "failure" ||
+		*otlp.Attributes[0].Value.IntValue != "500" {
+		t.Fatalf("span exported as %+v", otlp)
+	}
+}
+
+func TestShutdownFlushesQueuedSpans(t *testing.T) {
+	collector := &testCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	previous := getTracer()
+	defer setTracer(previous)
+	setTracer(&tracer{enabled: true, sampleRatio: 1, exporter: newExporter(server.URL, "mepauth")})
+	for i := 0; i < exportBatchSize+1; i++ {
+		_, span := StartSpan(context.Background(), "operation", SpanKindInternal)
+		span.End(nil)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if spans := collector.spans(); len(spans) != exportBatchSize+1 {
+		t.Fatalf("%d spans exported on shutdown", len(spans))
+	}
+	if Enabled() {
+		t.Fatal("tracing enabled after shutdown")
+	}
+	// the tracing is already shut down
+	if err := Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStartAfterShutdown(t *testing.T) {
+	previous := getTracer()
+	defer setTracer(previous)
+
+	Start(Options{Endpoint: "http://127.0.0.1:1/v1/traces", SampleRatio: 0.5})
+	started := getTracer()
+	Start(Options{Endpoint: "http://127.0.0.1:1/v1/traces"})
+	if getTracer() != started || started.sampleRatio != 0.5 || started.exporter.serviceName != DefaultServiceName {
+		t.Fatalf("tracer %+v started", getTracer())
+	}
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	Start(Options{ServiceName: "dns-server", Endpoint: "http://127.0.0.1:1/v1/traces"})
+	if !Enabled() || getTracer().exporter.serviceName != "dns-server" {
+		t.Fatal("tracing not restarted after shutdown")
+	}
+	_ = Shutdown(context.Background())
+}